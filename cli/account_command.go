@@ -23,6 +23,7 @@ import (
 	"path/filepath"
 	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/choria-io/fisk"
@@ -43,6 +44,9 @@ type actCmd struct {
 	snapShotConsumers bool
 	force             bool
 	failOnWarn        bool
+	parallel          int
+	backupKV          bool
+	backupObj         bool
 
 	placementCluster string
 	placementTags    []string
@@ -63,14 +67,21 @@ func configureActCommand(app commandHost) {
 
 	report.Command("statistics", "Report on server statistics").Alias("stats").Alias("statsz").Action(c.reportServerStats)
 
+	configureAccountUsageCommand(report)
+	configureAccountImportsCommand(report)
+
 	backup := act.Command("backup", "Creates a backup of all  JetStream Streams over the NATS network").Alias("snapshot").Action(c.backupAction)
-	backup.Arg("target", "Directory to create the backup in").Required().StringVar(&c.backupDirectory)
+	backup.Arg("target", "Directory to create the backup in").StringVar(&c.backupDirectory)
+	backup.Flag("output", "Directory to create the backup in").Short('o').PlaceHolder("DIR").StringVar(&c.backupDirectory)
+	backup.Flag("kv", "Backs up all Key-Value buckets with a manifest instead of all Streams").UnNegatableBoolVar(&c.backupKV)
+	backup.Flag("obj", "Backs up all Object Store buckets with a manifest instead of all Streams").UnNegatableBoolVar(&c.backupObj)
 	backup.Flag("check", "Checks the Stream for health prior to backup").UnNegatableBoolVar(&c.healthCheck)
 	backup.Flag("consumers", "Enable or disable consumer backups").Default("true").BoolVar(&c.snapShotConsumers)
 	backup.Flag("force", "Perform backup without prompting").Short('f').UnNegatableBoolVar(&c.force)
 	backup.Flag("critical-warnings", "Treat warnings as failures").Short('w').UnNegatableBoolVar(&c.failOnWarn)
+	backup.Flag("parallel", "Number of Streams to back up concurrently").Default("1").PlaceHolder("N").IntVar(&c.parallel)
 
-	restore := act.Command("restore", "Restore an account backup over the NATS network").Action(c.restoreAction)
+	restore := mutating(act.Command("restore", "Restore an account backup over the NATS network").Action(c.restoreAction))
 	restore.Arg("directory", "The directory holding the account backup to restore").Required().ExistingDirVar(&c.backupDirectory)
 	restore.Flag("cluster", "Place the stream in a specific cluster").StringVar(&c.placementCluster)
 	restore.Flag("tag", "Place the stream on servers that has specific tags (pass multiple times)").StringsVar(&c.placementTags)
@@ -82,13 +93,21 @@ func init() {
 	registerCommand("account", 0, configureActCommand)
 }
 
-func (c *actCmd) backupAction(_ *fisk.ParseContext) error {
+func (c *actCmd) backupAction(pc *fisk.ParseContext) error {
 	var err error
 
+	if c.backupDirectory == "" {
+		return withExitCode(ExitValidation, fmt.Errorf("a target directory is required"))
+	}
+
+	if c.backupKV || c.backupObj {
+		return c.backupBuckets(pc)
+	}
+
 	_, mgr, err := prepareHelper("", natsOpts()...)
 	fisk.FatalIfError(err, "setup failed")
 
-	streams, missing, err := mgr.Streams(nil)
+	streams, missing, err := loadStreams(mgr, nil, c.parallel)
 	if err != nil {
 		return err
 	}
@@ -134,9 +153,15 @@ func (c *actCmd) backupAction(_ *fisk.ParseContext) error {
 
 	var errs []error
 	var warns []error
+	var mu sync.Mutex
+
+	parallelDo(c.parallel, len(streams), func(i int) {
+		s := streams[i]
+		err := backupStream(s, false, c.snapShotConsumers, c.healthCheck, filepath.Join(c.backupDirectory, s.Name()))
+
+		mu.Lock()
+		defer mu.Unlock()
 
-	for _, s := range streams {
-		err = backupStream(s, false, c.snapShotConsumers, c.healthCheck, filepath.Join(c.backupDirectory, s.Name()))
 		if errors.Is(err, jsm.ErrMemoryStreamNotSupported) {
 			fmt.Printf("Backup of %s failed: %v\n", s.Name(), err)
 			warns = append(warns, fmt.Errorf("%s: %w", s.Name(), err))
@@ -145,7 +170,7 @@ func (c *actCmd) backupAction(_ *fisk.ParseContext) error {
 			errs = append(errs, fmt.Errorf("%s: %s", s.Name(), err))
 		}
 		fmt.Println()
-	}
+	})
 
 	if len(warns) > 0 {
 		fmt.Printf("Backup Warnings: \n")
@@ -170,7 +195,40 @@ func (c *actCmd) backupAction(_ *fisk.ParseContext) error {
 	return nil
 }
 
+// backupBuckets backs up Key-Value and Object Store buckets by bucket name with a manifest that restore uses
+func (c *actCmd) backupBuckets(pc *fisk.ParseContext) error {
+	bc := &SrvBackupCmd{
+		directory:   c.backupDirectory,
+		healthCheck: c.healthCheck,
+		consumers:   c.snapShotConsumers,
+		force:       c.force,
+		parallel:    c.parallel,
+	}
+
+	if c.backupKV {
+		bc.kinds = append(bc.kinds, "kv")
+	}
+	if c.backupObj {
+		bc.kinds = append(bc.kinds, "object")
+	}
+
+	return bc.backupAction(pc)
+}
+
 func (c *actCmd) restoreAction(kp *fisk.ParseContext) error {
+	// bucket backups have a manifest describing the buckets
+	_, err := os.Stat(filepath.Join(c.backupDirectory, drManifestFile))
+	if err == nil {
+		bc := &SrvBackupCmd{
+			directory:        c.backupDirectory,
+			force:            true,
+			placementCluster: c.placementCluster,
+			placementTags:    c.placementTags,
+		}
+
+		return bc.restoreAction(kp)
+	}
+
 	_, mgr, err := prepareHelper("", natsOpts()...)
 	fisk.FatalIfError(err, "setup failed")
 	streams, err := mgr.StreamNames(nil)
@@ -0,0 +1,412 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/choria-io/fisk"
+	"github.com/fatih/color"
+	"github.com/nats-io/jwt/v2"
+	"github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats.go"
+)
+
+type ActImportsCmd struct {
+	account  string
+	dangling bool
+	json     bool
+
+	accounts map[string]*server.AccountInfo
+	system   string
+	servers  int
+}
+
+type accountExportReport struct {
+	Account   string   `json:"account"`
+	Name      string   `json:"name,omitempty"`
+	Type      string   `json:"type"`
+	Subject   string   `json:"subject"`
+	Private   bool     `json:"private"`
+	Response  string   `json:"response_type,omitempty"`
+	Importers []string `json:"importers,omitempty"`
+}
+
+type accountImportReport struct {
+	Account      string `json:"account"`
+	Type         string `json:"type"`
+	Subject      string `json:"subject"`
+	LocalSubject string `json:"local_subject,omitempty"`
+	From         string `json:"from"`
+	Problem      string `json:"problem,omitempty"`
+}
+
+func configureAccountImportsCommand(report *fisk.CmdClause) {
+	c := &ActImportsCmd{}
+
+	exports := report.Command("exports", "Report on Service and Stream exports of all accounts").Alias("export").Action(c.exportsAction)
+	exports.Flag("account", "Only report on exports from a specific account").StringVar(&c.account)
+	exports.Flag("json", "Produce JSON output").Short('j').UnNegatableBoolVar(&c.json)
+
+	imports := report.Command("imports", "Report on Service and Stream imports of all accounts and verifies they match an export").Alias("import").Action(c.importsAction)
+	imports.Flag("account", "Only report on imports into a specific account").StringVar(&c.account)
+	imports.Flag("dangling", "Only show imports that do not match an export").UnNegatableBoolVar(&c.dangling)
+	imports.Flag("json", "Produce JSON output").Short('j').UnNegatableBoolVar(&c.json)
+}
+
+// displayName is the account name tag when set, JWT based accounts are otherwise only known by public key
+func (c *ActImportsCmd) displayName(account string) string {
+	nfo, ok := c.accounts[account]
+	if ok && nfo != nil && nfo.NameTag != "" {
+		return nfo.NameTag
+	}
+
+	return account
+}
+
+func (c *ActImportsCmd) lookupAccount(nc *nats.Conn, account string) (*server.AccountInfo, error) {
+	nfo, ok := c.accounts[account]
+	if ok {
+		return nfo, nil
+	}
+
+	res, err := doReq(&server.AccountzEventOptions{AccountzOptions: server.AccountzOptions{Account: account}}, "$SYS.REQ.SERVER.PING.ACCOUNTZ", c.servers, nc)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, r := range res {
+		resp := struct {
+			Data  server.Accountz  `json:"data"`
+			Error *server.ApiError `json:"error"`
+		}{}
+
+		err = json.Unmarshal(r, &resp)
+		if err != nil {
+			return nil, err
+		}
+
+		if resp.Error != nil || resp.Data.Account == nil {
+			continue
+		}
+
+		nfo = resp.Data.Account
+		break
+	}
+
+	// nil is also cached so unknown accounts are only looked up once
+	c.accounts[account] = nfo
+
+	return nfo, nil
+}
+
+// gatherAccounts loads the details of every account known to any server, JWT based accounts are only
+// known once a server resolved them so exporting accounts are also looked up on demand
+func (c *ActImportsCmd) gatherAccounts(nc *nats.Conn) error {
+	c.accounts = map[string]*server.AccountInfo{}
+
+	res, err := doReq(&server.AccountzEventOptions{}, "$SYS.REQ.SERVER.PING.ACCOUNTZ", 0, nc)
+	if err != nil {
+		return err
+	}
+
+	c.servers = len(res)
+	names := map[string]bool{}
+	for _, r := range res {
+		resp := struct {
+			Data  server.Accountz  `json:"data"`
+			Error *server.ApiError `json:"error"`
+		}{}
+
+		err = json.Unmarshal(r, &resp)
+		if err != nil {
+			return err
+		}
+
+		if resp.Error != nil {
+			return fmt.Errorf("accountz request failed: %s", resp.Error.Description)
+		}
+
+		c.system = resp.Data.SystemAccount
+		for _, name := range resp.Data.Accounts {
+			names[name] = true
+		}
+	}
+
+	if len(names) == 0 {
+		return fmt.Errorf("no results received, ensure the account used has system privileges and appropriate permissions")
+	}
+
+	for name := range names {
+		if name == c.system {
+			continue
+		}
+
+		_, err = c.lookupAccount(nc, name)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (c *ActImportsCmd) sortedAccounts() []*server.AccountInfo {
+	var res []*server.AccountInfo
+	for _, nfo := range c.accounts {
+		if nfo != nil {
+			res = append(res, nfo)
+		}
+	}
+
+	sort.Slice(res, func(i, j int) bool {
+		return c.displayName(res[i].AccountName) < c.displayName(res[j].AccountName)
+	})
+
+	return res
+}
+
+// userImports are the imports configured by users, the server adds system account imports to every account
+func (c *ActImportsCmd) userImports(nfo *server.AccountInfo) []server.ExtImport {
+	var res []server.ExtImport
+	for _, imp := range nfo.Imports {
+		if imp.Account != c.system {
+			res = append(res, imp)
+		}
+	}
+
+	return res
+}
+
+// importExportSubject is the subject in the exporting account, the server reports service imports with the local subject
+// in Subject and the exported subject in To while stream imports have the exported subject in Subject
+func importExportSubject(imp server.ExtImport) jwt.Subject {
+	if imp.Type == jwt.Service && imp.To != "" {
+		return imp.To
+	}
+
+	return imp.Subject
+}
+
+// importLocalSubject is the subject the import is available on in the importing account
+func importLocalSubject(imp server.ExtImport) string {
+	switch {
+	case imp.Type == jwt.Service:
+		return string(imp.Subject)
+	case imp.LocalSubject != "":
+		return string(imp.LocalSubject)
+	default:
+		return string(imp.To)
+	}
+}
+
+// matchImport finds the export an import is bound to, the export is nil when no match was found
+func (c *ActImportsCmd) matchImport(nc *nats.Conn, importer string, imp server.ExtImport) (*server.ExtExport, string, error) {
+	exporter, err := c.lookupAccount(nc, imp.Account)
+	if err != nil {
+		return nil, "", err
+	}
+	if exporter == nil {
+		return nil, fmt.Sprintf("account %s not found", imp.Account), nil
+	}
+
+	subject := importExportSubject(imp)
+
+	var typeMismatch *server.ExtExport
+	for i, exp := range exporter.Exports {
+		if !subject.IsContainedIn(exp.Subject) {
+			continue
+		}
+
+		if exp.Type != imp.Type {
+			typeMismatch = &exporter.Exports[i]
+			continue
+		}
+
+		// the server lists every account allowed to import a private export, including those with activation tokens
+		approved := !exp.TokenReq && len(exp.ApprovedAccounts) == 0
+		for _, acct := range exp.ApprovedAccounts {
+			if acct == importer {
+				approved = true
+			}
+		}
+
+		problem := ""
+		switch {
+		case imp.Invalid:
+			problem = "rejected by the server"
+		case !approved:
+			problem = "private export without activation"
+		}
+
+		return &exporter.Exports[i], problem, nil
+	}
+
+	if typeMismatch != nil {
+		return nil, fmt.Sprintf("export of %s is a %s", typeMismatch.Subject, typeMismatch.Type), nil
+	}
+
+	return nil, fmt.Sprintf("no %s export matching %s", imp.Type, subject), nil
+}
+
+func (c *ActImportsCmd) exportsAction(_ *fisk.ParseContext) error {
+	nc, _, err := prepareHelper("", natsOpts()...)
+	if err != nil {
+		return err
+	}
+
+	err = c.gatherAccounts(nc)
+	if err != nil {
+		return err
+	}
+
+	var exports []*accountExportReport
+	found := map[*server.ExtExport]*accountExportReport{}
+
+	for _, nfo := range c.sortedAccounts() {
+		if c.account != "" && c.account != nfo.AccountName && c.account != nfo.NameTag {
+			continue
+		}
+
+		for i, exp := range nfo.Exports {
+			report := &accountExportReport{
+				Account: c.displayName(nfo.AccountName),
+				Name:    exp.Name,
+				Type:    exp.Type.String(),
+				Subject: string(exp.Subject),
+				Private: exp.TokenReq || len(exp.ApprovedAccounts) > 0,
+			}
+			if exp.IsService() {
+				report.Response = string(exp.ResponseType)
+			}
+
+			found[&nfo.Exports[i]] = report
+			exports = append(exports, report)
+		}
+	}
+
+	for _, nfo := range c.sortedAccounts() {
+		for _, imp := range c.userImports(nfo) {
+			exp, problem, err := c.matchImport(nc, nfo.AccountName, imp)
+			if err != nil {
+				return err
+			}
+			if exp == nil || problem != "" {
+				continue
+			}
+
+			report, ok := found[exp]
+			if ok {
+				report.Importers = append(report.Importers, c.displayName(nfo.AccountName))
+			}
+		}
+	}
+
+	if c.json {
+		return printJSON(exports)
+	}
+
+	if len(exports) == 0 {
+		fmt.Println("No exports found")
+		return nil
+	}
+
+	table := newTableWriter(fmt.Sprintf("%d Exports", len(exports)))
+	table.AddHeaders("Account", "Name", "Type", "Subject", "Private", "Response", "Importers")
+	for _, exp := range exports {
+		importers := strings.Join(exp.Importers, ", ")
+		if len(exp.Importers) == 0 {
+			importers = color.YellowString("none")
+		}
+
+		table.AddRow(exp.Account, exp.Name, exp.Type, exp.Subject, exp.Private, exp.Response, importers)
+	}
+	fmt.Print(table.Render())
+
+	return nil
+}
+
+func (c *ActImportsCmd) importsAction(_ *fisk.ParseContext) error {
+	nc, _, err := prepareHelper("", natsOpts()...)
+	if err != nil {
+		return err
+	}
+
+	err = c.gatherAccounts(nc)
+	if err != nil {
+		return err
+	}
+
+	var imports []*accountImportReport
+	dangling := 0
+
+	for _, nfo := range c.sortedAccounts() {
+		if c.account != "" && c.account != nfo.AccountName && c.account != nfo.NameTag {
+			continue
+		}
+
+		for _, imp := range c.userImports(nfo) {
+			_, problem, err := c.matchImport(nc, nfo.AccountName, imp)
+			if err != nil {
+				return err
+			}
+
+			if problem != "" {
+				dangling++
+			} else if c.dangling {
+				continue
+			}
+
+			imports = append(imports, &accountImportReport{
+				Account:      c.displayName(nfo.AccountName),
+				Type:         imp.Type.String(),
+				Subject:      string(importExportSubject(imp)),
+				LocalSubject: importLocalSubject(imp),
+				From:         c.displayName(imp.Account),
+				Problem:      problem,
+			})
+		}
+	}
+
+	if c.json {
+		return printJSON(imports)
+	}
+
+	if len(imports) == 0 {
+		fmt.Println("No imports found")
+		return nil
+	}
+
+	table := newTableWriter(fmt.Sprintf("%d Imports", len(imports)))
+	table.AddHeaders("Account", "Type", "Subject", "Local Subject", "From Account", "Status")
+	for _, imp := range imports {
+		status := "OK"
+		if imp.Problem != "" {
+			status = color.RedString(imp.Problem)
+		}
+
+		table.AddRow(imp.Account, imp.Type, imp.Subject, imp.LocalSubject, imp.From, status)
+	}
+	fmt.Print(table.Render())
+
+	if dangling > 0 {
+		fmt.Println()
+		fmt.Printf("%d imports do not match an export\n", dangling)
+	}
+
+	return nil
+}
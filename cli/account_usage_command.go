@@ -0,0 +1,313 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/choria-io/fisk"
+	"github.com/dustin/go-humanize"
+	"github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats.go"
+)
+
+type ActUsageCmd struct {
+	warn     int
+	warnOnly bool
+	json     bool
+	waitFor  int
+}
+
+// accountUsageValue is a used amount against a limit, a negative limit is unlimited and a nil limit unknown
+type accountUsageValue struct {
+	Used  int64  `json:"used"`
+	Limit *int64 `json:"limit,omitempty"`
+}
+
+type accountUsage struct {
+	Account     string            `json:"account"`
+	Connections accountUsageValue `json:"connections"`
+	Memory      accountUsageValue `json:"memory"`
+	Store       accountUsageValue `json:"storage"`
+	Streams     accountUsageValue `json:"streams"`
+	Consumers   accountUsageValue `json:"consumers"`
+	Warnings    []string          `json:"warnings,omitempty"`
+}
+
+func configureAccountUsageCommand(report *fisk.CmdClause) {
+	c := &ActUsageCmd{}
+
+	usage := report.Command("usage", "Report on resource usage against limits for all accounts").Alias("quota").Action(c.usageAction)
+	usage.Arg("expect", "Number of servers to expect").IntVar(&c.waitFor)
+	usage.Flag("warn", "Flag accounts using this percentage of any limit").Default("80").IntVar(&c.warn)
+	usage.Flag("warn-only", "Only show accounts that are close to their limits").UnNegatableBoolVar(&c.warnOnly)
+	usage.Flag("json", "Produce JSON output").Short('j').UnNegatableBoolVar(&c.json)
+}
+
+func (u accountUsageValue) percent() float64 {
+	if u.Limit == nil || *u.Limit <= 0 {
+		return 0
+	}
+
+	return float64(u.Used) / float64(*u.Limit) * 100
+}
+
+func (u accountUsageValue) render(format func(int64) string) string {
+	switch {
+	case u.Limit == nil:
+		return format(u.Used)
+	case *u.Limit < 0:
+		return fmt.Sprintf("%s / Unlimited", format(u.Used))
+	case *u.Limit == 0:
+		return fmt.Sprintf("%s / Disabled", format(u.Used))
+	default:
+		return fmt.Sprintf("%s / %s (%.0f%%)", format(u.Used), format(*u.Limit), u.percent())
+	}
+}
+
+func (c *ActUsageCmd) usageAction(_ *fisk.ParseContext) error {
+	nc, _, err := prepareHelper("", natsOpts()...)
+	if err != nil {
+		return err
+	}
+
+	usage := map[string]*accountUsage{}
+	get := func(name string) *accountUsage {
+		u, ok := usage[name]
+		if !ok {
+			u = &accountUsage{Account: name}
+			usage[name] = u
+		}
+		return u
+	}
+
+	err = c.gatherJetStreamUsage(nc, get)
+	if err != nil {
+		return err
+	}
+
+	err = c.gatherConnectionUsage(nc, get)
+	if err != nil {
+		return err
+	}
+
+	if len(usage) == 0 {
+		return fmt.Errorf("no results received, ensure the account used has system privileges and appropriate permissions")
+	}
+
+	var accounts []*accountUsage
+	for name, u := range usage {
+		err = c.gatherLimits(nc, name, u)
+		if err != nil {
+			return err
+		}
+
+		for _, check := range []struct {
+			name string
+			val  accountUsageValue
+		}{{"connections", u.Connections}, {"memory", u.Memory}, {"storage", u.Store}, {"streams", u.Streams}, {"consumers", u.Consumers}} {
+			if check.val.Limit != nil && *check.val.Limit > 0 && check.val.percent() >= float64(c.warn) {
+				u.Warnings = append(u.Warnings, fmt.Sprintf("%s at %.0f%%", check.name, check.val.percent()))
+			}
+		}
+
+		if c.warnOnly && len(u.Warnings) == 0 {
+			continue
+		}
+
+		accounts = append(accounts, u)
+	}
+
+	sort.Slice(accounts, func(i, j int) bool {
+		if len(accounts[i].Warnings) != len(accounts[j].Warnings) {
+			return len(accounts[i].Warnings) > len(accounts[j].Warnings)
+		}
+		return accounts[i].Account < accounts[j].Account
+	})
+
+	if c.json {
+		return printJSON(accounts)
+	}
+
+	if len(accounts) == 0 {
+		fmt.Printf("No accounts are using %d%% or more of their limits\n", c.warn)
+		return nil
+	}
+
+	comma := func(v int64) string { return humanize.Comma(v) }
+	bytes := func(v int64) string { return humanize.IBytes(uint64(v)) }
+
+	table := newTableWriter(fmt.Sprintf("Account Usage for %d Accounts", len(accounts)))
+	table.AddHeaders("Account", "Connections", "Memory", "File", "Streams", "Consumers", "Warnings")
+	for _, u := range accounts {
+		table.AddRow(
+			u.Account,
+			u.Connections.render(comma),
+			u.Memory.render(bytes),
+			u.Store.render(bytes),
+			u.Streams.render(comma),
+			u.Consumers.render(comma),
+			strings.Join(u.Warnings, ", "),
+		)
+	}
+	fmt.Print(table.Render())
+
+	return nil
+}
+
+func (c *ActUsageCmd) gatherJetStreamUsage(nc *nats.Conn, get func(string) *accountUsage) error {
+	req := &server.JszEventOptions{
+		JSzOptions:         server.JSzOptions{Accounts: true, Streams: true, Consumer: true, Limit: 10000},
+		EventFilterOptions: server.EventFilterOptions{Domain: opts.Config.JSDomain()},
+	}
+
+	res, err := doReq(req, "$SYS.REQ.SERVER.PING.JSZ", c.waitFor, nc)
+	if err != nil {
+		return err
+	}
+
+	streams := map[string]map[string]bool{}
+	consumers := map[string]map[string]bool{}
+
+	for _, r := range res {
+		resp := struct {
+			Data  server.JSInfo    `json:"data"`
+			Error *server.ApiError `json:"error"`
+		}{}
+
+		err = json.Unmarshal(r, &resp)
+		if err != nil {
+			return err
+		}
+
+		if resp.Error != nil {
+			return fmt.Errorf("jsz request failed: %s", resp.Error.Description)
+		}
+
+		for _, acct := range resp.Data.AccountDetails {
+			// name might be a JWT name tag, the id matches other APIs
+			name := acct.Id
+			if name == "" {
+				name = acct.Name
+			}
+			u := get(name)
+
+			// every server knows the cluster wide usage totals so we take the largest reported
+			if int64(acct.Memory) > u.Memory.Used {
+				u.Memory.Used = int64(acct.Memory)
+			}
+			if int64(acct.Store) > u.Store.Used {
+				u.Store.Used = int64(acct.Store)
+			}
+
+			if streams[name] == nil {
+				streams[name] = map[string]bool{}
+				consumers[name] = map[string]bool{}
+			}
+
+			for _, s := range acct.Streams {
+				streams[name][s.Name] = true
+				for _, cons := range s.Consumer {
+					consumers[name][s.Name+">"+cons.Name] = true
+				}
+			}
+		}
+	}
+
+	for name, s := range streams {
+		u := get(name)
+		u.Streams.Used = int64(len(s))
+		u.Consumers.Used = int64(len(consumers[name]))
+	}
+
+	return nil
+}
+
+func (c *ActUsageCmd) gatherConnectionUsage(nc *nats.Conn, get func(string) *accountUsage) error {
+	res, err := doReq(&server.AccountStatzOptions{IncludeUnused: true}, "$SYS.REQ.ACCOUNT.PING.STATZ", c.waitFor, nc)
+	if err != nil {
+		return err
+	}
+
+	act := &actCmd{}
+	for _, r := range res {
+		sz, err := act.parseAccountStatResp(r)
+		if err != nil {
+			return err
+		}
+
+		for _, stat := range sz.Stats.Accounts {
+			get(stat.Account).Connections.Used += int64(stat.Conns)
+		}
+	}
+
+	return nil
+}
+
+// gatherLimits looks up the account limits, only accounts defined using JWTs have limits that can be discovered
+func (c *ActUsageCmd) gatherLimits(nc *nats.Conn, account string, u *accountUsage) error {
+	res, err := doReq(&server.AccountzEventOptions{AccountzOptions: server.AccountzOptions{Account: account}}, "$SYS.REQ.SERVER.PING.ACCOUNTZ", 0, nc)
+	if err != nil {
+		return err
+	}
+
+	for _, r := range res {
+		resp := struct {
+			Data server.Accountz `json:"data"`
+		}{}
+
+		err = json.Unmarshal(r, &resp)
+		if err != nil {
+			return err
+		}
+
+		if resp.Data.Account == nil || resp.Data.Account.Claim == nil {
+			continue
+		}
+
+		limits := resp.Data.Account.Claim.Limits
+		u.Connections.Limit = &limits.Conn
+
+		mem, store, streams, consumers := limits.MemoryStorage, limits.DiskStorage, limits.Streams, limits.Consumer
+		if len(limits.JetStreamTieredLimits) > 0 {
+			mem, store, streams, consumers = 0, 0, 0, 0
+			sum := func(total *int64, v int64) {
+				if *total < 0 || v < 0 {
+					*total = -1
+					return
+				}
+				*total += v
+			}
+
+			for _, tier := range limits.JetStreamTieredLimits {
+				sum(&mem, tier.MemoryStorage)
+				sum(&store, tier.DiskStorage)
+				sum(&streams, tier.Streams)
+				sum(&consumers, tier.Consumer)
+			}
+		}
+
+		u.Memory.Limit = &mem
+		u.Store.Limit = &store
+		u.Streams.Limit = &streams
+		u.Consumers.Limit = &consumers
+
+		return nil
+	}
+
+	return nil
+}
@@ -0,0 +1,217 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/ghodss/yaml"
+	"github.com/nats-io/nats.go"
+)
+
+// Actions taken on anonymized fields and headers
+const (
+	anonymizeHash   = "hash"
+	anonymizeRedact = "redact"
+	anonymizeRemove = "remove"
+
+	anonymizeRedacted = "REDACTED"
+)
+
+// anonymizer hashes, redacts or removes JSON fields and headers of messages, hashes are keyed using the salt
+// so equal values hash the same way within an export without being reversible by guessing
+type anonymizer struct {
+	Salt    string           `json:"salt,omitempty"`
+	NonJSON string           `json:"non_json,omitempty"`
+	Rules   []*anonymizeRule `json:"rules"`
+}
+
+// anonymizeRule selects a JSON field by a dotted path, where * matches any key, or a header by name
+type anonymizeRule struct {
+	Field  string `json:"field,omitempty"`
+	Header string `json:"header,omitempty"`
+	Action string `json:"action,omitempty"`
+
+	path []string
+}
+
+func loadAnonymizer(file string) (*anonymizer, error) {
+	body, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	j, err := yaml.YAMLToJSON(body)
+	if err != nil {
+		return nil, fmt.Errorf("invalid anonymization rules %s: %w", file, err)
+	}
+
+	a := &anonymizer{}
+	err = json.Unmarshal(j, a)
+	if err != nil {
+		return nil, fmt.Errorf("invalid anonymization rules %s: %w", file, err)
+	}
+
+	err = a.validate()
+	if err != nil {
+		return nil, fmt.Errorf("invalid anonymization rules %s: %w", file, err)
+	}
+
+	return a, nil
+}
+
+func (a *anonymizer) validate() error {
+	if len(a.Rules) == 0 {
+		return fmt.Errorf("no rules defined")
+	}
+
+	switch a.NonJSON {
+	case "":
+		a.NonJSON = anonymizeRedact
+	case anonymizeRedact, "keep":
+	default:
+		return fmt.Errorf("non_json must be redact or keep")
+	}
+
+	for i, rule := range a.Rules {
+		if (rule.Field == "") == (rule.Header == "") {
+			return fmt.Errorf("rule %d: either field or header is required", i+1)
+		}
+
+		switch rule.Action {
+		case "":
+			rule.Action = anonymizeHash
+		case anonymizeHash, anonymizeRedact, anonymizeRemove:
+		default:
+			return fmt.Errorf("rule %d: action must be hash, redact or remove", i+1)
+		}
+
+		if rule.Field != "" {
+			rule.path = strings.Split(rule.Field, ".")
+		}
+	}
+
+	return nil
+}
+
+// hash produces a stable, keyed, hash of a value
+func (a *anonymizer) hash(v []byte) string {
+	h := hmac.New(sha256.New, []byte(a.Salt))
+	h.Write(v)
+
+	return "hash:" + hex.EncodeToString(h.Sum(nil))[:16]
+}
+
+// apply anonymizes the headers and JSON payload of msg in place
+func (a *anonymizer) apply(msg *nats.Msg) error {
+	for _, rule := range a.Rules {
+		if rule.Header == "" {
+			continue
+		}
+
+		for k, vals := range msg.Header {
+			if !strings.EqualFold(k, rule.Header) {
+				continue
+			}
+
+			switch rule.Action {
+			case anonymizeRemove:
+				delete(msg.Header, k)
+			case anonymizeRedact:
+				for i := range vals {
+					vals[i] = anonymizeRedacted
+				}
+			default:
+				for i := range vals {
+					vals[i] = a.hash([]byte(vals[i]))
+				}
+			}
+		}
+	}
+
+	hasFields := false
+	for _, rule := range a.Rules {
+		if rule.Field != "" {
+			hasFields = true
+		}
+	}
+	if !hasFields || len(msg.Data) == 0 {
+		return nil
+	}
+
+	var body any
+	dec := json.NewDecoder(bytes.NewReader(msg.Data))
+	dec.UseNumber()
+	err := dec.Decode(&body)
+	if err != nil {
+		if a.NonJSON == anonymizeRedact {
+			msg.Data = []byte(anonymizeRedacted)
+		}
+		return nil
+	}
+
+	for _, rule := range a.Rules {
+		if rule.Field != "" {
+			a.applyPath(body, rule.path, rule.Action)
+		}
+	}
+
+	msg.Data, err = json.Marshal(body)
+
+	return err
+}
+
+// applyPath walks path in v, arrays are walked into so fields of every element are anonymized
+func (a *anonymizer) applyPath(v any, path []string, action string) {
+	switch val := v.(type) {
+	case []any:
+		for _, e := range val {
+			a.applyPath(e, path, action)
+		}
+
+	case map[string]any:
+		for k, child := range val {
+			if path[0] != "*" && path[0] != k {
+				continue
+			}
+
+			if len(path) > 1 {
+				a.applyPath(child, path[1:], action)
+				continue
+			}
+
+			switch action {
+			case anonymizeRemove:
+				delete(val, k)
+			case anonymizeRedact:
+				val[k] = anonymizeRedacted
+			default:
+				// strings hash like headers so values can be correlated between them
+				s, ok := child.(string)
+				if !ok {
+					j, _ := json.Marshal(child)
+					s = string(j)
+				}
+				val[k] = a.hash([]byte(s))
+			}
+		}
+	}
+}
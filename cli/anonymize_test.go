@@ -0,0 +1,98 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/nats-io/nats.go"
+)
+
+func TestAnonymizer(t *testing.T) {
+	a := &anonymizer{
+		Salt: "s3cret",
+		Rules: []*anonymizeRule{
+			{Field: "user.email"},
+			{Field: "items.*.card", Action: anonymizeRedact},
+			{Field: "user.phone", Action: anonymizeRemove},
+			{Header: "x-user-email"},
+			{Header: "X-Session", Action: anonymizeRemove},
+		},
+	}
+	err := a.validate()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	msg := nats.NewMsg("orders")
+	msg.Header.Set("X-User-Email", "bob@example.net")
+	msg.Header.Set("X-Session", "abc")
+	msg.Header.Set("X-Other", "keep")
+	msg.Data = []byte(`{"user":{"email":"bob@example.net","phone":"123","id":10},"items":[{"x":{"card":"4111"}},{"y":{"card":"5500"}}],"total":10.50}`)
+
+	err = a.apply(msg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var body map[string]any
+	err = json.Unmarshal(msg.Data, &body)
+	if err != nil {
+		t.Fatalf("invalid JSON %q: %v", msg.Data, err)
+	}
+
+	user := body["user"].(map[string]any)
+	hashed := a.hash([]byte("bob@example.net"))
+	if user["email"] != hashed || msg.Header.Get("X-User-Email") != hashed {
+		t.Fatalf("expected email to be hashed consistently: %v %v", user["email"], msg.Header.Get("X-User-Email"))
+	}
+	if _, ok := user["phone"]; ok {
+		t.Fatalf("expected phone to be removed")
+	}
+	if user["id"] != float64(10) || body["total"] != 10.5 {
+		t.Fatalf("unexpected changes to other fields: %s", msg.Data)
+	}
+	for _, item := range body["items"].([]any) {
+		for _, v := range item.(map[string]any) {
+			if v.(map[string]any)["card"] != anonymizeRedacted {
+				t.Fatalf("expected card to be redacted: %s", msg.Data)
+			}
+		}
+	}
+	if msg.Header.Get("X-Session") != "" || msg.Header.Get("X-Other") != "keep" {
+		t.Fatalf("unexpected headers: %v", msg.Header)
+	}
+
+	msg.Data = []byte("not json")
+	err = a.apply(msg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(msg.Data) != anonymizeRedacted {
+		t.Fatalf("expected non JSON data to be redacted: %q", msg.Data)
+	}
+
+	for _, bad := range []*anonymizer{
+		{},
+		{Rules: []*anonymizeRule{{}}},
+		{Rules: []*anonymizeRule{{Field: "a", Header: "b"}}},
+		{Rules: []*anonymizeRule{{Field: "a", Action: "encrypt"}}},
+		{NonJSON: "drop", Rules: []*anonymizeRule{{Field: "a"}}},
+	} {
+		if bad.validate() == nil {
+			t.Fatalf("expected an error for %+v", bad)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nats-io/jsm.go/api"
+	"github.com/nats-io/nats.go"
+	"golang.org/x/time/rate"
+)
+
+// Reports and bulk operations make their JetStream API requests using apiCall so that a client side
+// rate limit can be applied and requests failing due to transient problems are retried
+var (
+	apiLimiter     *rate.Limiter
+	apiLimiterRate string
+	apiLimiterMu   sync.Mutex
+)
+
+// parseAPIRate parses rates like 50/s, 600/m or 10000/h, a number without unit is per second
+func parseAPIRate(s string) (rate.Limit, error) {
+	count, unit, _ := strings.Cut(strings.TrimSpace(s), "/")
+
+	n, err := strconv.ParseFloat(count, 64)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid rate %q, expected a rate like 50/s", s)
+	}
+
+	var per time.Duration
+	switch unit {
+	case "", "s":
+		per = time.Second
+	case "m":
+		per = time.Minute
+	case "h":
+		per = time.Hour
+	default:
+		return 0, fmt.Errorf("invalid rate %q, the unit must be s, m or h", s)
+	}
+
+	return rate.Limit(n / per.Seconds()), nil
+}
+
+// apiRateLimiter is the limiter for the rate set using --api-rate, nil when no rate is set
+func apiRateLimiter() (*rate.Limiter, error) {
+	apiLimiterMu.Lock()
+	defer apiLimiterMu.Unlock()
+
+	if opts.APIRate == "" {
+		return nil, nil
+	}
+
+	if apiLimiter == nil || apiLimiterRate != opts.APIRate {
+		limit, err := parseAPIRate(opts.APIRate)
+		if err != nil {
+			return nil, err
+		}
+
+		apiLimiter = rate.NewLimiter(limit, 1)
+		apiLimiterRate = opts.APIRate
+	}
+
+	return apiLimiter, nil
+}
+
+// isTransientAPIError determines if a request failed due to a problem that could be resolved by retrying it
+func isTransientAPIError(err error) bool {
+	if errors.Is(err, nats.ErrTimeout) || errors.Is(err, context.DeadlineExceeded) || errors.Is(err, nats.ErrNoResponders) {
+		return true
+	}
+
+	var apiErr api.ApiError
+	if errors.As(err, &apiErr) {
+		return apiErr.Code == 503 || apiErr.Code == 429
+	}
+
+	return false
+}
+
+// apiCall makes a JetStream API request using cb, waiting for the rate set using --api-rate and retrying transient
+// failures --retries times, doubling --retry-backoff between attempts
+func apiCall(cb func() error) error {
+	limiter, err := apiRateLimiter()
+	if err != nil {
+		return err
+	}
+
+	backoff := opts.RetryBackoff
+
+	for attempt := 0; ; attempt++ {
+		if limiter != nil {
+			err = limiter.Wait(ctx)
+			if err != nil {
+				return err
+			}
+		}
+
+		err = cb()
+		if err == nil || attempt >= opts.Retries || !isTransientAPIError(err) {
+			return err
+		}
+
+		if opts.Trace {
+			log.Printf("Retrying API request in %v after attempt %d failed: %v", backoff, attempt+1, err)
+		}
+
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return err
+		}
+
+		backoff *= 2
+	}
+}
@@ -0,0 +1,86 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/nats-io/jsm.go/api"
+	"github.com/nats-io/nats.go"
+	"golang.org/x/time/rate"
+)
+
+func TestParseAPIRate(t *testing.T) {
+	for s, expect := range map[string]rate.Limit{"50/s": 50, "50": 50, "120/m": 2, "3600/h": 1} {
+		limit, err := parseAPIRate(s)
+		checkErr(t, err, "parse %q failed", s)
+		if limit != expect {
+			t.Fatalf("expected %q to be %v got %v", s, expect, limit)
+		}
+	}
+
+	for _, s := range []string{"", "x/s", "-1/s", "5/d"} {
+		_, err := parseAPIRate(s)
+		if err == nil {
+			t.Fatalf("expected %q to fail", s)
+		}
+	}
+}
+
+func TestAPICall(t *testing.T) {
+	origOpts, origCtx := opts, ctx
+	defer func() { opts, ctx = origOpts, origCtx }()
+
+	opts = &Options{Retries: 2, RetryBackoff: time.Millisecond}
+	ctx = context.Background()
+
+	calls := 0
+	err := apiCall(func() error {
+		calls++
+		return nats.ErrTimeout
+	})
+	if !errors.Is(err, nats.ErrTimeout) || calls != 3 {
+		t.Fatalf("expected 3 calls and a timeout, got %d calls and %v", calls, err)
+	}
+
+	calls = 0
+	err = apiCall(func() error {
+		calls++
+		if calls == 1 {
+			return api.ApiError{Code: 503}
+		}
+		return nil
+	})
+	if err != nil || calls != 2 {
+		t.Fatalf("expected success after a retry, got %d calls and %v", calls, err)
+	}
+
+	calls = 0
+	err = apiCall(func() error {
+		calls++
+		return api.ApiError{Code: 404, Description: "stream not found"}
+	})
+	if err == nil || calls != 1 {
+		t.Fatalf("expected permanent failures to not be retried, got %d calls", calls)
+	}
+
+	opts.APIRate = "nope"
+	err = apiCall(func() error { return nil })
+	if err == nil {
+		t.Fatalf("expected an invalid rate to fail")
+	}
+}
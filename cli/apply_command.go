@@ -0,0 +1,178 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/choria-io/fisk"
+)
+
+type applyCmd struct {
+	files  []string
+	prune  bool
+	dryRun bool
+	force  bool
+}
+
+func configureApplyCommand(app commandHost) {
+	c := &applyCmd{}
+
+	help := `Creates, updates and optionally deletes JetStream assets to match manifests
+
+Manifests are YAML or JSON documents describing Streams, Consumers,
+Key-Value and Object Store buckets, multiple YAML documents can be
+placed in a file separated by ---:
+
+  kind: Stream
+  name: ORDERS
+  spec:
+    subjects: [ORDERS.*]
+    storage: file
+    num_replicas: 3
+  ---
+  kind: Consumer
+  stream: ORDERS
+  name: NEW
+  spec:
+    filter_subject: ORDERS.new
+    ack_policy: explicit
+
+Specs for Streams and Consumers are the same as their JSON
+configuration, settings not in the spec keep their current value.
+
+Pruning deletes assets of the kinds found in the manifests that are
+not described in them and requires --force, internal Streams starting
+with $ or GOVERNOR_ and mirrors are never pruned.
+`
+
+	apply := mutating(app.Command("apply", help).Action(c.applyAction))
+	apply.Flag("file", "Manifest file or directory of manifests to apply").Short('f').Required().PlaceHolder("PATH").ExistingFilesOrDirsVar(&c.files)
+	apply.Flag("prune", "Deletes assets of the kinds found in the manifests that are not described in them, requires --force").UnNegatableBoolVar(&c.prune)
+	apply.Flag("dry-run", "Only shows the changes that would be made").UnNegatableBoolVar(&c.dryRun)
+	apply.Flag("force", "Apply changes without prompting").UnNegatableBoolVar(&c.force)
+	addCheat("apply", apply)
+}
+
+func init() {
+	registerCommand("apply", 1, configureApplyCommand)
+}
+
+func (c *applyCmd) applyAction(_ *fisk.ParseContext) error {
+	manifests, err := loadManifests(c.files)
+	if err != nil {
+		return withExitCode(ExitValidation, err)
+	}
+
+	if len(manifests) == 0 {
+		return withExitCode(ExitValidation, fmt.Errorf("no manifests found"))
+	}
+
+	_, mgr, err := prepareHelper("", natsOpts()...)
+	if err != nil {
+		return err
+	}
+
+	changes, err := planManifests(mgr, manifests, c.prune)
+	if err != nil {
+		return err
+	}
+
+	if len(changes) == 0 {
+		fmt.Printf("No changes, %d assets match their manifests\n", len(manifests))
+		return nil
+	}
+
+	plan := renderManifestChanges(changes)
+	fmt.Print(plan)
+
+	deletes := manifestDeletions(changes)
+	if len(deletes) > 0 {
+		fmt.Println()
+		fmt.Printf("Pruning deletes %d assets:\n\n", len(deletes))
+		for _, asset := range deletes {
+			fmt.Printf("  %s\n", asset)
+		}
+	}
+
+	if c.dryRun {
+		return nil
+	}
+
+	if len(deletes) > 0 && !c.force {
+		return withExitCode(ExitValidation, fmt.Errorf("refusing to delete %d assets without --force", len(deletes)))
+	}
+
+	if !c.force {
+		fmt.Println()
+		ok, err := askConfirmation(fmt.Sprintf("Apply %d changes", len(changes)), false)
+		if err != nil {
+			return err
+		}
+
+		if !ok {
+			return nil
+		}
+	}
+
+	auditConfigChange(plan)
+
+	fmt.Println()
+	for _, change := range changes {
+		err = change.apply()
+		if err != nil {
+			return fmt.Errorf("could not %s %s %s: %w", change.Operation, change.Kind, change.Name, err)
+		}
+
+		fmt.Printf("%s %s %s\n", manifestOperationDone[change.Operation], change.Kind, change.Name)
+	}
+
+	return nil
+}
+
+var manifestOperationDone = map[string]string{"create": "Created", "update": "Updated", "delete": "Deleted"}
+
+// renderManifestChanges shows a summary of changes followed by each change and its configuration difference
+func renderManifestChanges(changes []*manifestChange) string {
+	buf := bytes.NewBuffer([]byte{})
+	counts := map[string]int{}
+	for _, change := range changes {
+		counts[change.Operation]++
+	}
+
+	fmt.Fprintf(buf, "Plan: %d to create, %d to update, %d to delete\n\n", counts["create"], counts["update"], counts["delete"])
+
+	for _, change := range changes {
+		marker := "~"
+		switch change.Operation {
+		case "create":
+			marker = "+"
+		case "delete":
+			marker = "-"
+		}
+
+		fmt.Fprintf(buf, "%s %s %s %s\n", marker, change.Operation, change.Kind, change.Name)
+		if change.Diff != "" {
+			fmt.Fprintln(buf)
+			for _, line := range strings.Split(strings.TrimRight(change.Diff, "\n"), "\n") {
+				fmt.Fprintf(buf, "    %s\n", line)
+			}
+			fmt.Fprintln(buf)
+		}
+	}
+
+	return buf.String()
+}
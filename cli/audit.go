@@ -0,0 +1,269 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"os/user"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/choria-io/fisk"
+	"github.com/nats-io/nats.go"
+)
+
+// auditRecord describes a mutating action taken using the CLI
+type auditRecord struct {
+	Time      time.Time         `json:"time"`
+	Command   string            `json:"command"`
+	Arguments []string          `json:"arguments"`
+	Targets   map[string]string `json:"targets,omitempty"`
+	Diff      string            `json:"diff,omitempty"`
+	User      string            `json:"user"`
+	Host      string            `json:"host"`
+	Context   string            `json:"context,omitempty"`
+	Server    string            `json:"server,omitempty"`
+	Success   bool              `json:"success"`
+	Error     string            `json:"error,omitempty"`
+}
+
+var (
+	auditPending *auditRecord
+	auditMu      sync.Mutex
+	auditOnce    sync.Once
+
+	// auditSecretFlags are flags whose values are not recorded in the audit log
+	auditSecretFlags = []string{"--password", "--token", "--auth-password", "--auth-token"}
+
+	// commandEffects records how commands affect the systems they connect to by full command name, commands not
+	// listed only read
+	commandEffects   = map[string]commandEffect{}
+	commandEffectsMu sync.Mutex
+)
+
+// commandEffect describes how a command affects the systems it connects to
+type commandEffect int
+
+const (
+	// commandReads only reads from the system
+	commandReads commandEffect = iota
+	// commandPublishes publishes messages or requests that could cause services to make changes
+	commandPublishes
+	// commandMutates modifies assets, servers or messages
+	commandMutates
+)
+
+func markCommand(cmd *fisk.CmdClause, effect commandEffect) *fisk.CmdClause {
+	commandEffectsMu.Lock()
+	commandEffects[cmd.FullCommand()] = effect
+	commandEffectsMu.Unlock()
+
+	return cmd
+}
+
+// mutating marks cmd as modifying assets, servers or messages, these commands are audited and refused on read-only contexts
+func mutating(cmd *fisk.CmdClause) *fisk.CmdClause {
+	return markCommand(cmd, commandMutates)
+}
+
+// publishing marks cmd as publishing messages or requests, these are treated as mutating and need confirmation to run against many contexts
+func publishing(cmd *fisk.CmdClause) *fisk.CmdClause {
+	return markCommand(cmd, commandPublishes)
+}
+
+func commandEffectOf(name string) commandEffect {
+	commandEffectsMu.Lock()
+	defer commandEffectsMu.Unlock()
+
+	return commandEffects[name]
+}
+
+// isMutatingCommand determines if a command modifies assets or servers or publishes messages, local context management is not audited
+func isMutatingCommand(name string) bool {
+	return commandEffectOf(name) != commandReads
+}
+
+// isPublishingCommand determines if a command publishes messages or requests
+func isPublishingCommand(name string) bool {
+	return commandEffectOf(name) == commandPublishes
+}
+
+// redactArguments removes passwords and tokens from command line arguments
+func redactArguments(args []string) []string {
+	res := make([]string, len(args))
+
+	for i, arg := range args {
+		res[i] = arg
+
+		for _, f := range auditSecretFlags {
+			switch {
+			case strings.HasPrefix(arg, f+"="):
+				res[i] = f + "=[redacted]"
+			case i > 0 && args[i-1] == f:
+				res[i] = "[redacted]"
+			}
+		}
+
+		if strings.Contains(arg, "://") {
+			u, err := url.Parse(arg)
+			if err == nil && u.User != nil {
+				res[i] = u.Redacted()
+			}
+		}
+	}
+
+	return res
+}
+
+// startAudit prepares a record for the selected command when auditing is enabled and the command is mutating
+func startAudit(pc *fisk.ParseContext, args []string) {
+	auditMu.Lock()
+	auditOnce = sync.Once{}
+	auditMu.Unlock()
+
+	if (opts.AuditFile == "" && opts.AuditSubject == "") || pc == nil || pc.SelectedCommand == nil {
+		return
+	}
+
+	name := pc.SelectedCommand.FullCommand()
+	if !isMutatingCommand(name) {
+		return
+	}
+
+	rec := &auditRecord{
+		Time:      time.Now().UTC(),
+		Command:   name,
+		Arguments: redactArguments(args),
+		Targets:   map[string]string{},
+	}
+
+	for _, e := range pc.Elements {
+		arg, ok := e.Clause.(*fisk.ArgClause)
+		if ok && e.Value != nil {
+			rec.Targets[arg.Model().Name] = *e.Value
+		}
+	}
+
+	u, err := user.Current()
+	if err == nil {
+		rec.User = u.Username
+	}
+	rec.Host, _ = os.Hostname()
+
+	auditMu.Lock()
+	auditPending = rec
+	auditMu.Unlock()
+}
+
+// auditConfigChange records the configuration difference made by the command being audited
+func auditConfigChange(diff string) {
+	auditMu.Lock()
+	defer auditMu.Unlock()
+
+	if auditPending != nil {
+		auditPending.Diff = diff
+	}
+}
+
+// finishAudit completes the audit record with the outcome of the command and writes it to the audit destinations
+func finishAudit(cmdErr error) {
+	auditOnce.Do(func() {
+		auditMu.Lock()
+		rec := auditPending
+		auditPending = nil
+		auditMu.Unlock()
+
+		if rec == nil {
+			return
+		}
+
+		rec.Success = cmdErr == nil
+		if cmdErr != nil {
+			rec.Error = cmdErr.Error()
+		}
+
+		if opts.Config != nil {
+			rec.Context = opts.Config.Name
+			if u, err := url.Parse(opts.Config.ServerURL()); err == nil && u.User != nil {
+				rec.Server = u.Redacted()
+			} else {
+				rec.Server = opts.Config.ServerURL()
+			}
+		}
+		if rec.Context == "" {
+			rec.Context = opts.CfgCtx
+		}
+
+		err := writeAuditRecord(rec)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Could not record audit log entry: %v\n", err)
+		}
+	})
+}
+
+func writeAuditRecord(rec *auditRecord) error {
+	j, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+
+	if opts.AuditFile != "" {
+		f, err := os.OpenFile(opts.AuditFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+		if err != nil {
+			return err
+		}
+
+		_, err = f.Write(append(j, '\n'))
+		f.Close()
+		if err != nil {
+			return err
+		}
+	}
+
+	if opts.AuditSubject != "" {
+		nc := opts.Conn
+		if nc == nil || nc.IsClosed() {
+			if opts.Config == nil {
+				err = loadContext()
+				if err != nil {
+					return err
+				}
+			}
+
+			nc, err = nats.Connect(opts.Config.ServerURL(), natsOpts()...)
+			if err != nil {
+				return err
+			}
+			defer nc.Close()
+		}
+
+		err = nc.Publish(opts.AuditSubject, j)
+		if err != nil {
+			return err
+		}
+
+		timeout := opts.Timeout
+		if timeout == 0 {
+			timeout = 5 * time.Second
+		}
+
+		return nc.FlushTimeout(timeout)
+	}
+
+	return nil
+}
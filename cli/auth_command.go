@@ -0,0 +1,471 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"fmt"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/choria-io/fisk"
+	"github.com/ghodss/yaml"
+	"github.com/nats-io/jwt/v2"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nkeys"
+)
+
+const (
+	authCalloutSubject    = "$SYS.REQ.USER.AUTH"
+	authCalloutXKeyHeader = "Nats-Server-Xkey"
+
+	// servers deny clients publishing to the callout subject so tests are sent on this one
+	authCalloutTestSubject = "nats.auth.callout.test"
+)
+
+type authCmd struct {
+	rulesFile  string
+	issuerSeed string
+	xkeySeed   string
+	subject    string
+	testSubj   string
+	queue      string
+	user       string
+	password   string
+	token      string
+	serviceKey string
+	json       bool
+
+	rules  *authCalloutRules
+	issuer nkeys.KeyPair
+	xkp    nkeys.KeyPair
+}
+
+// authCalloutRules maps users to the accounts and permissions issued by the callout service
+type authCalloutRules struct {
+	Users []*authCalloutUser `json:"users"`
+}
+
+type authCalloutUser struct {
+	User        string          `json:"user,omitempty"`
+	Password    string          `json:"password,omitempty"`
+	Token       string          `json:"token,omitempty"`
+	Account     string          `json:"account"`
+	Name        string          `json:"name,omitempty"`
+	Expires     string          `json:"expires,omitempty"`
+	Permissions jwt.Permissions `json:"permissions,omitempty"`
+}
+
+func configureAuthCommand(app commandHost) {
+	c := &authCmd{}
+
+	help := `Auth Callout development utilities
+
+The serve command issues users based on a rules file like:
+
+  users:
+    - user: alice
+      password: s3cret
+      account: APP
+      expires: 1h
+      permissions:
+        pub:
+          allow: ["orders.>"]
+        sub:
+          allow: ["_INBOX.>"]
+    - token: s3cr3t-t0k3n
+      account: APP
+
+The issuer seed is the account NKey seed set as the auth callout issuer
+in the server configuration.
+
+Servers do not allow clients to publish to $SYS.REQ.USER.AUTH so the test
+command sends its requests to nats.auth.callout.test which serve also
+listens on.
+`
+
+	auth := app.Command("auth", help)
+	addCheat("auth", auth)
+
+	callout := auth.Command("callout", "Auth Callout service development harness")
+
+	serve := publishing(callout.Command("serve", "Runs a reference Auth Callout service issuing users based on a rules file").Action(c.serveAction))
+	serve.Flag("rules", "YAML file mapping users to accounts and permissions").Required().ExistingFileVar(&c.rulesFile)
+	serve.Flag("issuer", "File holding the account NKey seed used to sign responses").Required().PlaceHolder("FILE").StringVar(&c.issuerSeed)
+	serve.Flag("xkey", "File holding the curve NKey seed used to decrypt requests when encryption is enabled").PlaceHolder("FILE").StringVar(&c.xkeySeed)
+	serve.Flag("subject", "The subject to listen on").Default(authCalloutSubject).StringVar(&c.subject)
+	serve.Flag("test-subject", "Also answers test requests sent on this subject").Default(authCalloutTestSubject).StringVar(&c.testSubj)
+	serve.Flag("queue", "Queue group to join").Default("auth_callout").StringVar(&c.queue)
+
+	test := publishing(callout.Command("test", "Sends a synthetic authorization request to an Auth Callout service and shows the response").Action(c.testAction))
+	test.Flag("auth-user", "The user name to authorize").StringVar(&c.user)
+	test.Flag("auth-password", "The password to authorize").StringVar(&c.password)
+	test.Flag("auth-token", "The token to authorize").StringVar(&c.token)
+	test.Flag("xkey", "The public curve NKey of the service to encrypt the request").PlaceHolder("KEY").StringVar(&c.serviceKey)
+	test.Flag("subject", "The subject the service listens on for test requests").Default(authCalloutTestSubject).StringVar(&c.subject)
+	test.Flag("json", "Produce JSON output").Short('j').UnNegatableBoolVar(&c.json)
+}
+
+func init() {
+	registerCommand("auth", 10, configureAuthCommand)
+}
+
+func loadNKeySeed(file string) (nkeys.KeyPair, error) {
+	seed, err := readContextFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	// curve seeds are not supported by ParseDecoratedNKey
+	if bytes.HasPrefix(bytes.TrimSpace(seed), []byte("SX")) {
+		return nkeys.FromCurveSeed(bytes.TrimSpace(seed))
+	}
+
+	return nkeys.ParseDecoratedNKey(seed)
+}
+
+func (c *authCmd) loadRules() error {
+	rj, err := os.ReadFile(c.rulesFile)
+	if err != nil {
+		return err
+	}
+
+	c.rules = &authCalloutRules{}
+	err = yaml.Unmarshal(rj, c.rules)
+	if err != nil {
+		return fmt.Errorf("invalid rules file: %w", err)
+	}
+
+	for i, u := range c.rules.Users {
+		if u.User == "" && u.Token == "" {
+			return fmt.Errorf("rule %d requires a user or token", i+1)
+		}
+		if u.Account == "" {
+			return fmt.Errorf("rule %d requires an account", i+1)
+		}
+		if u.Expires != "" {
+			_, err = parseDurationString(u.Expires)
+			if err != nil {
+				return fmt.Errorf("rule %d has an invalid expires: %w", i+1, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+func secureEqual(a string, b string) bool {
+	return subtle.ConstantTimeCompare([]byte(a), []byte(b)) == 1
+}
+
+// matchUser finds the rule matching the connect options of the request
+func (c *authCmd) matchUser(req *jwt.AuthorizationRequestClaims) *authCalloutUser {
+	opts := req.ConnectOptions
+
+	for _, u := range c.rules.Users {
+		switch {
+		case u.Token != "":
+			if opts.Token != "" && secureEqual(u.Token, opts.Token) {
+				return u
+			}
+		case u.User == opts.Username:
+			if u.Password == "" || secureEqual(u.Password, opts.Password) {
+				return u
+			}
+		}
+	}
+
+	return nil
+}
+
+func (c *authCmd) respond(msg *nats.Msg, req *jwt.AuthorizationRequestClaims, serverXKey string, rule *authCalloutUser, errMsg string) error {
+	resp := jwt.NewAuthorizationResponseClaims(req.UserNkey)
+	resp.Audience = req.Server.ID
+
+	if rule != nil {
+		uc := jwt.NewUserClaims(req.UserNkey)
+		uc.Audience = rule.Account
+		uc.Name = rule.Name
+		uc.Permissions = rule.Permissions
+		if rule.Expires != "" {
+			expires, _ := parseDurationString(rule.Expires)
+			uc.Expires = time.Now().Add(expires).Unix()
+		}
+
+		ujwt, err := uc.Encode(c.issuer)
+		if err != nil {
+			return err
+		}
+		resp.Jwt = ujwt
+	} else {
+		resp.Error = errMsg
+	}
+
+	token, err := resp.Encode(c.issuer)
+	if err != nil {
+		return err
+	}
+
+	data := []byte(token)
+	if serverXKey != "" {
+		data, err = c.xkp.Seal(data, serverXKey)
+		if err != nil {
+			return err
+		}
+	}
+
+	return msg.Respond(data)
+}
+
+func (c *authCmd) handleRequest(msg *nats.Msg) error {
+	data := msg.Data
+	serverXKey := msg.Header.Get(authCalloutXKeyHeader)
+
+	if serverXKey != "" {
+		if c.xkp == nil {
+			return fmt.Errorf("received an encrypted request but no xkey was configured")
+		}
+
+		var err error
+		data, err = c.xkp.Open(data, serverXKey)
+		if err != nil {
+			return fmt.Errorf("could not decrypt request: %w", err)
+		}
+	}
+
+	req, err := jwt.DecodeAuthorizationRequestClaims(string(data))
+	if err != nil {
+		return fmt.Errorf("invalid request: %w", err)
+	}
+
+	vr := jwt.CreateValidationResults()
+	req.Validate(vr)
+	if len(vr.Errors()) > 0 {
+		return fmt.Errorf("invalid request: %v", vr.Errors()[0])
+	}
+
+	client := req.ConnectOptions.Username
+	if client == "" && req.ConnectOptions.Token != "" {
+		client = "token"
+	}
+
+	rule := c.matchUser(req)
+	if rule == nil {
+		log.Printf("Denied %q connecting from %s to %s", client, req.ClientInformation.Host, req.Server.Name)
+		return c.respond(msg, req, serverXKey, nil, "not authorized")
+	}
+
+	log.Printf("Authorized %q connecting from %s to %s in account %s", client, req.ClientInformation.Host, req.Server.Name, rule.Account)
+
+	return c.respond(msg, req, serverXKey, rule, "")
+}
+
+func (c *authCmd) serveAction(_ *fisk.ParseContext) error {
+	err := c.loadRules()
+	if err != nil {
+		return err
+	}
+
+	c.issuer, err = loadNKeySeed(c.issuerSeed)
+	if err != nil {
+		return fmt.Errorf("invalid issuer seed: %w", err)
+	}
+
+	if c.xkeySeed != "" {
+		c.xkp, err = loadNKeySeed(c.xkeySeed)
+		if err != nil {
+			return fmt.Errorf("invalid xkey seed: %w", err)
+		}
+	}
+
+	nc, _, err := prepareHelper("", natsOpts()...)
+	if err != nil {
+		return err
+	}
+
+	handler := func(msg *nats.Msg) {
+		err := c.handleRequest(msg)
+		if err != nil {
+			log.Printf("Could not handle request: %v", err)
+		}
+	}
+
+	for _, subj := range []string{c.subject, c.testSubj} {
+		if subj == "" {
+			continue
+		}
+
+		_, err = nc.QueueSubscribe(subj, c.queue, handler)
+		if err != nil {
+			return err
+		}
+	}
+
+	pk, _ := c.issuer.PublicKey()
+	log.Printf("Auth Callout service issuing %d users as %s listening on %q", len(c.rules.Users), pk, c.subject)
+
+	ic := make(chan os.Signal, 1)
+	signal.Notify(ic, os.Interrupt)
+	<-ic
+
+	log.Printf("Draining...")
+
+	return nc.Drain()
+}
+
+func (c *authCmd) testAction(_ *fisk.ParseContext) error {
+	nc, _, err := prepareHelper("", natsOpts()...)
+	if err != nil {
+		return err
+	}
+
+	server, err := nkeys.CreateServer()
+	if err != nil {
+		return err
+	}
+	serverID, _ := server.PublicKey()
+
+	user, err := nkeys.CreateUser()
+	if err != nil {
+		return err
+	}
+	userKey, _ := user.PublicKey()
+
+	req := jwt.NewAuthorizationRequestClaims(serverID)
+	req.Audience = "nats-authorization-request"
+	req.UserNkey = userKey
+	req.Server = jwt.ServerID{Name: "nats-cli-auth-test", ID: serverID, Host: "127.0.0.1"}
+	req.ClientInformation = jwt.ClientInformation{Host: "127.0.0.1", ID: 1, Kind: "Client", Type: "nats", Name: "NATS CLI Auth Callout Test"}
+	req.ConnectOptions = jwt.ConnectOptions{Username: c.user, Password: c.password, Token: c.token, Lang: "go", Version: nats.Version, Protocol: 1}
+	req.Expires = time.Now().Add(opts.Timeout).Unix()
+
+	var xkp nkeys.KeyPair
+	if c.serviceKey != "" {
+		xkp, err = nkeys.CreateCurveKeys()
+		if err != nil {
+			return err
+		}
+		req.Server.XKey, _ = xkp.PublicKey()
+	}
+
+	token, err := req.Encode(server)
+	if err != nil {
+		return err
+	}
+
+	msg := nats.NewMsg(c.subject)
+	msg.Data = []byte(token)
+	if xkp != nil {
+		msg.Data, err = xkp.Seal(msg.Data, c.serviceKey)
+		if err != nil {
+			return err
+		}
+		msg.Header.Set(authCalloutXKeyHeader, req.Server.XKey)
+	}
+
+	res, err := nc.RequestMsg(msg, opts.Timeout)
+	if err != nil {
+		return fmt.Errorf("no response from the Auth Callout service on %s: %w", c.subject, err)
+	}
+
+	data := res.Data
+	if xkp != nil && !bytes.HasPrefix(data, []byte("eyJ")) {
+		data, err = xkp.Open(data, c.serviceKey)
+		if err != nil {
+			return fmt.Errorf("could not decrypt response: %w", err)
+		}
+	}
+
+	resp, err := jwt.DecodeAuthorizationResponseClaims(string(data))
+	if err != nil {
+		return fmt.Errorf("invalid response: %w", err)
+	}
+
+	var problems []string
+	vr := jwt.CreateValidationResults()
+	resp.Validate(vr)
+	for _, issue := range vr.Issues {
+		problems = append(problems, issue.Description)
+	}
+	if resp.Subject != userKey {
+		problems = append(problems, "response is not for the requested user")
+	}
+	if resp.Audience != serverID {
+		problems = append(problems, "response is not for the requesting server")
+	}
+
+	var uc *jwt.UserClaims
+	if resp.Jwt != "" {
+		uc, err = jwt.DecodeUserClaims(resp.Jwt)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("invalid user JWT: %v", err))
+		} else if uc.Subject != userKey {
+			problems = append(problems, "user JWT is not for the requested user")
+		}
+	}
+
+	if c.json {
+		printJSON(map[string]any{
+			"response": resp,
+			"user":     uc,
+			"problems": problems,
+		})
+	} else {
+		fmt.Printf("Auth Callout response from %s\n\n", resp.Issuer)
+		if resp.IssuerAccount != "" {
+			fmt.Printf("   Issuer Account: %s\n", resp.IssuerAccount)
+		}
+
+		if resp.Error != "" {
+			fmt.Printf("           Result: Denied\n")
+			fmt.Printf("            Error: %s\n", resp.Error)
+		} else if uc != nil {
+			fmt.Printf("           Result: Authorized\n")
+			fmt.Printf("          Account: %s\n", uc.Audience)
+			if uc.Name != "" {
+				fmt.Printf("             Name: %s\n", uc.Name)
+			}
+			fmt.Printf("           Issuer: %s\n", uc.Issuer)
+			if uc.Expires > 0 {
+				fmt.Printf("          Expires: %s\n", time.Unix(uc.Expires, 0).Format(time.RFC3339))
+			}
+			showPermission := func(kind string, p jwt.Permission) {
+				if len(p.Allow) > 0 {
+					fmt.Printf("%17s: %s\n", kind+" Allow", p.Allow)
+				}
+				if len(p.Deny) > 0 {
+					fmt.Printf("%17s: %s\n", kind+" Deny", p.Deny)
+				}
+			}
+			showPermission("Publish", uc.Pub)
+			showPermission("Subscribe", uc.Sub)
+		}
+
+		if len(problems) > 0 {
+			fmt.Println()
+			fmt.Println("Problems:")
+			for _, p := range problems {
+				fmt.Printf("  %s\n", p)
+			}
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("the response would be rejected by the server")
+	}
+
+	return nil
+}
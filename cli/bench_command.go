@@ -63,8 +63,10 @@ type benchCmd struct {
 	multiSubjectMax      int
 	deDuplication        bool
 	deDuplicationWindow  time.Duration
-	retries              int
 	retriesUsed          bool
+	consumerMatrix       bool
+	matrixAcks           []string
+	matrixBatches        []int
 }
 
 const (
@@ -101,6 +103,10 @@ JetStream durable pull and push consumers:
 
   nats bench benchsubject --js --sub 5 --push
 
+JetStream consumer comparison of ordered, push and pull consumers:
+
+  nats bench benchsubject --js --consumer-matrix --msgs 100000
+
 JetStream KV put and get:
 
   nats bench benchsubject --kv --pub 1
@@ -109,7 +115,7 @@ JetStream KV put and get:
 
 Remember to use --no-progress to measure performance more accurately
 `
-	bench := app.Command("bench", "Benchmark utility").Action(c.bench)
+	bench := mutating(app.Command("bench", "Benchmark utility").Action(c.bench))
 	if !opts.NoCheats {
 		bench.CheatFile(fs, "bench", "cheats/bench.md")
 	}
@@ -144,9 +150,11 @@ Remember to use --no-progress to measure performance more accurately
 	bench.Flag("history", "History depth for the bucket in KV mode").Default("1").Uint8Var(&c.history)
 	bench.Flag("multisubject", "Multi-subject mode, each message is published on a subject that includes the publisher's message sequence number as a token").UnNegatableBoolVar(&c.multiSubject)
 	bench.Flag("multisubjectmax", "The maximum number of subjects to use in multi-subject mode (0 means no max)").Default("0").IntVar(&c.multiSubjectMax)
-	bench.Flag("retries", "The maximum number of retries in JS operations").Default("3").IntVar(&c.retries)
 	bench.Flag("dedup", "Sets a message id in the header to use JS Publish de-duplication").Default("false").UnNegatableBoolVar(&c.deDuplication)
 	bench.Flag("dedupwindow", "Sets the duration of the stream's deduplication functionality").Default("2m").DurationVar(&c.deDuplicationWindow)
+	bench.Flag("consumer-matrix", "Consumes the same messages using ordered, push and pull consumers with varying ack policies and batch sizes, comparing their performance").UnNegatableBoolVar(&c.consumerMatrix)
+	bench.Flag("matrix-acks", "Ack policies to compare in consumer matrix mode").Default("none", "all", "explicit").EnumsVar(&c.matrixAcks, "none", "all", "explicit")
+	bench.Flag("matrix-batches", "Batch sizes to compare in consumer matrix mode, the fetch size for pull consumers and max ack pending for push consumers").Default("1", "100", "1000").IntsVar(&c.matrixBatches)
 }
 
 func init() {
@@ -163,6 +171,12 @@ func (c *benchCmd) bench(_ *fisk.ParseContext) error {
 		log.Fatal("Can not parse or invalid the value specified for the message size: %s", c.msgSizeString)
 	}
 	c.msgSize = int(msgSize)
+	if c.consumerMatrix {
+		if !c.js {
+			return fmt.Errorf("consumer matrix mode requires --js")
+		}
+		return c.runConsumerMatrix()
+	}
 	if c.js && c.numSubs > 0 && c.pull {
 		log.Print("JetStream durable pull consumer mode, subscriber(s) will explicitly acknowledge the consumption of messages")
 	}
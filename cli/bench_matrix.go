@@ -0,0 +1,308 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/dustin/go-humanize"
+	"github.com/nats-io/nats.go"
+)
+
+const benchMatrixConsumerName = "natscli-bench-matrix"
+
+// benchMatrixRun is a single consumer configuration measured in consumer matrix mode
+type benchMatrixRun struct {
+	kind      string
+	ackPolicy nats.AckPolicy
+	batch     int
+	received  int
+	duration  time.Duration
+	err       error
+}
+
+func (r *benchMatrixRun) rate() float64 {
+	if r.duration <= 0 {
+		return 0
+	}
+
+	return float64(r.received) / r.duration.Seconds()
+}
+
+func benchMatrixAckPolicy(policy string) nats.AckPolicy {
+	switch policy {
+	case "none":
+		return nats.AckNonePolicy
+	case "all":
+		return nats.AckAllPolicy
+	default:
+		return nats.AckExplicitPolicy
+	}
+}
+
+// benchMatrixRuns lists the configurations to measure, ordered consumers are always unacknowledged and unbatched
+func (c *benchCmd) benchMatrixRuns() []*benchMatrixRun {
+	runs := []*benchMatrixRun{{kind: "ordered", ackPolicy: nats.AckNonePolicy}}
+
+	for _, kind := range []string{"push", "pull"} {
+		for _, policy := range c.matrixAcks {
+			for _, batch := range c.matrixBatches {
+				runs = append(runs, &benchMatrixRun{kind: kind, ackPolicy: benchMatrixAckPolicy(policy), batch: batch})
+			}
+		}
+	}
+
+	return runs
+}
+
+// benchMatrixAck acknowledges msg according to the ack policy, with AckAll only the last message of a batch is acknowledged
+func benchMatrixAck(msg *nats.Msg, policy nats.AckPolicy, last bool) error {
+	switch {
+	case policy == nats.AckExplicitPolicy, policy == nats.AckAllPolicy && last:
+		return msg.Ack()
+	default:
+		return nil
+	}
+}
+
+// fillMatrixStream makes sure the stream holds at least the number of messages being consumed
+func (c *benchCmd) fillMatrixStream(js nats.JetStreamContext) error {
+	info, err := js.StreamInfo(c.streamName)
+	if err != nil {
+		return err
+	}
+
+	if info.State.Msgs >= uint64(c.numMsg) {
+		return nil
+	}
+
+	missing := c.numMsg - int(info.State.Msgs)
+	log.Printf("Publishing %s messages to stream %s", humanize.Comma(int64(missing)), c.streamName)
+
+	msg := make([]byte, c.msgSize)
+	for i := 0; i < missing; i++ {
+		_, err = js.PublishAsync(getPublishSubject(c, i), msg)
+		if err != nil {
+			return err
+		}
+
+		if (i+1)%c.pubBatch == 0 || i == missing-1 {
+			select {
+			case <-js.PublishAsyncComplete():
+			case <-time.After(c.jsTimeout):
+				return fmt.Errorf("timeout waiting for publish acknowledgements")
+			}
+		}
+	}
+
+	return nil
+}
+
+func (c *benchCmd) runMatrixConsumer(nc *nats.Conn, js nats.JetStreamContext, run *benchMatrixRun) error {
+	done := make(chan struct{})
+	progress := make(chan struct{}, 1)
+	var ackErr error
+
+	record := func(msg *nats.Msg, last bool) {
+		if run.received >= c.numMsg {
+			return
+		}
+		run.received++
+
+		err := benchMatrixAck(msg, run.ackPolicy, last || run.received == c.numMsg)
+		if err != nil && ackErr == nil {
+			ackErr = err
+		}
+
+		if run.received == c.numMsg {
+			close(done)
+			return
+		}
+
+		select {
+		case progress <- struct{}{}:
+		default:
+		}
+	}
+
+	handler := func(msg *nats.Msg) {
+		record(msg, run.batch > 0 && (run.received+1)%run.batch == 0)
+	}
+
+	if run.kind != "ordered" {
+		cfg := &nats.ConsumerConfig{
+			Durable:       benchMatrixConsumerName,
+			DeliverPolicy: nats.DeliverAllPolicy,
+			AckPolicy:     run.ackPolicy,
+			ReplayPolicy:  nats.ReplayInstantPolicy,
+		}
+		if run.ackPolicy != nats.AckNonePolicy {
+			cfg.MaxAckPending = run.batch
+			if run.kind == "pull" && cfg.MaxAckPending < 10000 {
+				cfg.MaxAckPending = 10000
+			}
+		}
+		if run.kind == "push" {
+			cfg.DeliverSubject = nc.NewRespInbox()
+		}
+
+		_, err := js.AddConsumer(c.streamName, cfg)
+		if err != nil {
+			return err
+		}
+		defer js.DeleteConsumer(c.streamName, benchMatrixConsumerName)
+	}
+
+	start := time.Now()
+
+	var sub *nats.Subscription
+	var err error
+
+	switch run.kind {
+	case "ordered":
+		sub, err = js.Subscribe("", handler, nats.BindStream(c.streamName), nats.OrderedConsumer())
+	case "push":
+		sub, err = js.Subscribe("", handler, nats.Bind(c.streamName, benchMatrixConsumerName), nats.ManualAck())
+	case "pull":
+		sub, err = js.PullSubscribe("", benchMatrixConsumerName, nats.Bind(c.streamName, benchMatrixConsumerName))
+	}
+	if err != nil {
+		return err
+	}
+	defer sub.Unsubscribe()
+
+	if run.kind == "pull" {
+		for run.received < c.numMsg {
+			msgs, err := sub.Fetch(min(run.batch, c.numMsg-run.received), nats.MaxWait(c.jsTimeout))
+			if err != nil {
+				return err
+			}
+
+			for i, msg := range msgs {
+				record(msg, i == len(msgs)-1)
+			}
+		}
+	} else {
+		err = sub.SetPendingLimits(-1, -1)
+		if err != nil {
+			return err
+		}
+
+	wait:
+		for {
+			select {
+			case <-done:
+				break wait
+			case <-progress:
+			case <-time.After(c.jsTimeout):
+				return fmt.Errorf("timeout after receiving %d messages", run.received)
+			}
+		}
+	}
+
+	run.duration = time.Since(start)
+
+	return ackErr
+}
+
+// runConsumerMatrix consumes the same workload using ordered, push and pull consumers with varying ack policies and batch sizes
+func (c *benchCmd) runConsumerMatrix() error {
+	if c.streamName == DefaultStreamName {
+		maxBytes, err := parseStringAsBytes(c.streamMaxBytesString)
+		if err != nil || maxBytes <= 0 {
+			return fmt.Errorf("can not parse or invalid the value specified for the max stream size: %s", c.streamMaxBytesString)
+		}
+		c.streamMaxBytes = maxBytes
+	}
+
+	for _, batch := range c.matrixBatches {
+		if batch <= 0 {
+			return fmt.Errorf("matrix batch sizes should be greater than 0")
+		}
+	}
+
+	nc, err := nats.Connect(opts.Config.ServerURL(), natsOpts()...)
+	if err != nil {
+		return fmt.Errorf("nats connection failed: %w", err)
+	}
+	defer nc.Close()
+
+	js, err := nc.JetStream(nats.MaxWait(c.jsTimeout))
+	if err != nil {
+		return err
+	}
+
+	if c.streamName == DefaultStreamName {
+		storage := nats.MemoryStorage
+		if c.storage == "file" {
+			storage = nats.FileStorage
+		}
+
+		_, err = js.AddStream(&nats.StreamConfig{Name: c.streamName, Subjects: []string{getSubscribeSubject(c)}, Retention: nats.LimitsPolicy, Discard: nats.DiscardNew, Storage: storage, Replicas: c.replicas, MaxBytes: c.streamMaxBytes, Duplicates: c.deDuplicationWindow})
+		if err != nil {
+			return fmt.Errorf("%v. If you want to delete and re-define the stream use `nats stream delete %s`", err, c.streamName)
+		}
+	}
+
+	if c.purge {
+		log.Printf("Purging the stream")
+		err = js.PurgeStream(c.streamName)
+		if err != nil {
+			return fmt.Errorf("error purging stream %s: %w", c.streamName, err)
+		}
+	}
+
+	err = c.fillMatrixStream(js)
+	if err != nil {
+		return fmt.Errorf("could not prepare stream %s: %w", c.streamName, err)
+	}
+
+	runs := c.benchMatrixRuns()
+	log.Printf("Starting JetStream consumer matrix benchmark [stream=%s, msgs=%s, msgsize=%s, runs=%d]", c.streamName, humanize.Comma(int64(c.numMsg)), humanize.IBytes(uint64(c.msgSize)), len(runs))
+
+	var fastest float64
+	for _, run := range runs {
+		run.err = c.runMatrixConsumer(nc, js, run)
+		if run.err == nil && run.rate() > fastest {
+			fastest = run.rate()
+		}
+	}
+
+	table := newTableWriter(fmt.Sprintf("Consumer comparison consuming %s %s messages from %s", humanize.Comma(int64(c.numMsg)), humanize.IBytes(uint64(c.msgSize)), c.streamName))
+	table.AddHeaders("Consumer", "Ack Policy", "Batch", "Duration", "Msgs/sec", "Throughput", "Relative")
+	for _, run := range runs {
+		batch := "-"
+		if run.batch > 0 {
+			batch = strconv.Itoa(run.batch)
+		}
+
+		if run.err != nil {
+			table.AddRow(run.kind, run.ackPolicy.String(), batch, "failed", run.err.Error(), "", "")
+			continue
+		}
+
+		table.AddRow(run.kind, run.ackPolicy.String(), batch,
+			humanizeDuration(run.duration),
+			humanize.Comma(int64(run.rate())),
+			fmt.Sprintf("%s/sec", humanize.IBytes(uint64(run.rate()*float64(c.msgSize)))),
+			fmt.Sprintf("%.0f%%", run.rate()/fastest*100))
+	}
+
+	fmt.Println()
+	fmt.Println(table.Render())
+
+	return nil
+}
@@ -0,0 +1,44 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+func TestBenchMatrixRuns(t *testing.T) {
+	c := &benchCmd{matrixAcks: []string{"none", "explicit"}, matrixBatches: []int{1, 100}}
+
+	runs := c.benchMatrixRuns()
+	if len(runs) != 9 {
+		t.Fatalf("expected 9 runs got %d", len(runs))
+	}
+
+	if runs[0].kind != "ordered" || runs[0].ackPolicy != nats.AckNonePolicy || runs[0].batch != 0 {
+		t.Fatalf("invalid ordered run: %#v", runs[0])
+	}
+
+	last := runs[len(runs)-1]
+	if last.kind != "pull" || last.ackPolicy != nats.AckExplicitPolicy || last.batch != 100 {
+		t.Fatalf("invalid last run: %#v", last)
+	}
+
+	run := &benchMatrixRun{received: 1000, duration: 2 * time.Second}
+	if run.rate() != 500 {
+		t.Fatalf("invalid rate %f", run.rate())
+	}
+}
@@ -59,7 +59,7 @@ var (
 
 	// These are persisted by contexts, as properties thereof.
 	// So don't include NATS_CONTEXT in this list.
-	overrideEnvVars = []string{"NATS_URL", "NATS_USER", "NATS_PASSWORD", "NATS_CREDS", "NATS_NKEY", "NATS_CERT", "NATS_KEY", "NATS_CA", "NATS_TIMEOUT", "NATS_SOCKS_PROXY", "NATS_COLOR"}
+	overrideEnvVars = []string{"NATS_URL", "NATS_USER", "NATS_PASSWORD", "NATS_CREDS", "NATS_NKEY", "NATS_CERT", "NATS_KEY", "NATS_CA", "NATS_TIMEOUT", "NATS_SOCKS_PROXY", "NATS_PROXY", "NATS_COLOR"}
 )
 
 func registerCommand(name string, order int, c func(app commandHost)) {
@@ -114,10 +114,34 @@ type Options struct {
 	NoCheats bool
 	// PrometheusNamespace is the namespace to use for prometheus format output in server check
 	PrometheusNamespace string
-	// SocksProxy is a SOCKS5 proxy to use for NATS connections
+	// SocksProxy is a SOCKS5 or HTTP proxy to use for NATS connections
 	SocksProxy string
 	// ColorScheme influence table colors and more based on ValidStyles()
 	ColorScheme string
+	// OutputFormat selects the output for commands that support it, one of text, wide, narrow, json or yaml
+	OutputFormat string
+	// JSONFilter is a jq style filter applied to JSON output
+	JSONFilter string
+	// OutputTemplate is a Go template used to render data that would otherwise be rendered as JSON
+	OutputTemplate string
+	// Quiet suppresses standard output so only the exit code reflects the outcome
+	Quiet bool
+	// NoPager disables sending long output through a pager
+	NoPager bool
+	// OtelEndpoint is the OTLP HTTP endpoint to send traces of commands and API calls to
+	OtelEndpoint string
+	// AuditFile is a file that records mutating actions taken using the CLI
+	AuditFile string
+	// AuditSubject is a subject mutating actions taken using the CLI are published to
+	AuditSubject string
+	// APIRate limits the rate of JetStream API requests made by reports and bulk operations, like 50/s
+	APIRate string
+	// Retries is how many times JetStream API requests made by reports and bulk operations are retried on transient failures
+	Retries int
+	// RetryBackoff is the time to wait before the first retry, doubling for every following retry
+	RetryBackoff time.Duration
+	// EachContext is a pattern selecting contexts to run a read-only command against
+	EachContext string
 }
 
 // SkipContexts used during tests
@@ -202,12 +226,16 @@ func ConfigureInCommand(cmd *fisk.CmdClause, cliOpts *Options, prepare bool, dis
 
 // ConfigureInApp attaches the cli commands to app, prepare will load the context on demand and should be true unless override nats,
 // manager and js context is given in a custom PreAction in the caller.  Disable is a list of command names to skip.
+//
+// Executables called nats-<name> found on PATH or in the plugins directory of the nats configuration are added as commands
 func ConfigureInApp(app *fisk.Application, cliOpts *Options, prepare bool, disable ...string) (*Options, error) {
 	err := commonConfigure(app, cliOpts, disable...)
 	if err != nil {
 		return nil, err
 	}
 
+	configurePlugins(app)
+
 	if prepare {
 		app.PreAction(preAction)
 	}
@@ -0,0 +1,226 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/choria-io/fisk"
+	"github.com/nats-io/jsm.go/natscontext"
+	"github.com/nats-io/nats.go"
+)
+
+const (
+	// completionCacheTTL is how long names retrieved from the server are reused by completions
+	completionCacheTTL = 30 * time.Second
+
+	// completionTimeout limits how long completions wait for the server so the shell stays responsive
+	completionTimeout = 2 * time.Second
+)
+
+var fishCompletionScript = `function __complete_nats
+    set -l args (commandline -opc)
+    set -e args[1]
+    nats --completion-bash $args (commandline -ct)
+end
+
+complete -c nats -f -a '(__complete_nats)'
+`
+
+// configureCompletion adds hints to global flags and the fish completion script as those are not known when commands are configured
+func configureCompletion(app *fisk.Application) {
+	if f := app.GetFlag("context"); f != nil {
+		f.HintAction(contextNamesHint)
+	}
+
+	if app.GetFlag("completion-script-fish") == nil {
+		app.Flag("completion-script-fish", "Generate completion script for fish.").Hidden().PreAction(func(_ *fisk.ParseContext) error {
+			fmt.Print(fishCompletionScript)
+			os.Exit(0)
+			return nil
+		}).UnNegatableBool()
+	}
+}
+
+// completionCacheFile is the file caching a list of names retrieved from the connected server
+func completionCacheFile(kind string, scope string) (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+
+	var server, user, domain string
+	if opts.Config != nil {
+		server = opts.Config.ServerURL()
+		user = opts.Config.User() + opts.Config.Creds() + opts.Config.NKey() + opts.Config.Token()
+		domain = opts.Config.JSDomain()
+	}
+
+	key := sha256.Sum256([]byte(strings.Join([]string{server, user, domain, kind, scope}, "\x00")))
+
+	return filepath.Join(dir, "nats", "completion", fmt.Sprintf("%x.json", key[:16])), nil
+}
+
+// cachedCompletion returns names from the completion cache, calling lookup and caching the result when the cache expired
+func cachedCompletion(kind string, scope string, lookup func() ([]string, error)) []string {
+	file, err := completionCacheFile(kind, scope)
+	if err == nil {
+		stat, err := os.Stat(file)
+		if err == nil && time.Since(stat.ModTime()) < completionCacheTTL {
+			var names []string
+			body, err := os.ReadFile(file)
+			if err == nil && json.Unmarshal(body, &names) == nil {
+				return names
+			}
+		}
+	}
+
+	if opts.Timeout == 0 || opts.Timeout > completionTimeout {
+		timeout := opts.Timeout
+		opts.Timeout = completionTimeout
+		defer func() { opts.Timeout = timeout }()
+	}
+
+	names, err := lookup()
+	if err != nil {
+		return nil
+	}
+	sort.Strings(names)
+
+	if file != "" {
+		body, err := json.Marshal(names)
+		if err == nil && os.MkdirAll(filepath.Dir(file), 0700) == nil {
+			os.WriteFile(file, body, 0600)
+		}
+	}
+
+	return names
+}
+
+// completionConn connects quietly for completions, connection problems are not reported as the shell would show them as completions
+func completionConn() []nats.Option {
+	return append(natsOpts(), nats.Timeout(completionTimeout), nats.MaxReconnects(0), nats.NoCallbacksAfterClientClose(), nats.ErrorHandler(func(*nats.Conn, *nats.Subscription, error) {}))
+}
+
+func streamNamesHint() []string {
+	return cachedCompletion("streams", "", func() ([]string, error) {
+		_, mgr, err := prepareHelper("", completionConn()...)
+		if err != nil {
+			return nil, err
+		}
+
+		return mgr.StreamNames(nil)
+	})
+}
+
+// consumerNamesHint completes the names of Consumers on the Stream that was already given on the command line
+func consumerNamesHint(stream *string) fisk.HintAction {
+	return func() []string {
+		if *stream == "" {
+			return nil
+		}
+
+		return cachedCompletion("consumers", *stream, func() ([]string, error) {
+			_, mgr, err := prepareHelper("", completionConn()...)
+			if err != nil {
+				return nil, err
+			}
+
+			return mgr.ConsumerNames(*stream)
+		})
+	}
+}
+
+// bucketNamesHint completes the names of buckets by listing the Streams backing them
+func bucketNamesHint(prefix string) fisk.HintAction {
+	return func() []string {
+		return cachedCompletion("buckets", prefix, func() ([]string, error) {
+			_, mgr, err := prepareHelper("", completionConn()...)
+			if err != nil {
+				return nil, err
+			}
+
+			streams, err := mgr.StreamNames(nil)
+			if err != nil {
+				return nil, err
+			}
+
+			var names []string
+			for _, s := range streams {
+				if strings.HasPrefix(s, prefix) {
+					names = append(names, strings.TrimPrefix(s, prefix))
+				}
+			}
+
+			return names, nil
+		})
+	}
+}
+
+func kvBucketNamesHint() []string {
+	return bucketNamesHint("KV_")()
+}
+
+func objBucketNamesHint() []string {
+	return bucketNamesHint("OBJ_")()
+}
+
+// objectNamesHint completes the names of objects in the bucket that was already given on the command line
+func objectNamesHint(bucket *string) fisk.HintAction {
+	return func() []string {
+		if *bucket == "" {
+			return nil
+		}
+
+		return cachedCompletion("objects", *bucket, func() ([]string, error) {
+			_, _, err := prepareHelper("", completionConn()...)
+			if err != nil {
+				return nil, err
+			}
+
+			_, js, err := prepareJSHelper()
+			if err != nil {
+				return nil, err
+			}
+
+			obj, err := js.ObjectStore(*bucket)
+			if err != nil {
+				return nil, err
+			}
+
+			list, err := obj.List()
+			if err != nil {
+				return nil, err
+			}
+
+			var names []string
+			for _, o := range list {
+				names = append(names, o.Name)
+			}
+
+			return names, nil
+		})
+	}
+}
+
+func contextNamesHint() []string {
+	return natscontext.KnownContexts()
+}
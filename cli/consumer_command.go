@@ -21,18 +21,23 @@ import (
 	"math"
 	"math/rand"
 	"os"
+	"os/signal"
 	"regexp"
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/AlecAivazis/survey/v2"
+	"github.com/HdrHistogram/hdrhistogram-go"
 	"github.com/choria-io/fisk"
 	"github.com/dustin/go-humanize"
 	"github.com/google/go-cmp/cmp"
 	"github.com/nats-io/jsm.go/api"
+	"github.com/nats-io/jsm.go/api/jetstream/metric"
 	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nkeys"
 
 	"github.com/nats-io/jsm.go"
 )
@@ -42,6 +47,8 @@ type consumerCmd struct {
 	stream         string
 	json           bool
 	listNames      bool
+	listStates     bool
+	workers        int
 	force          bool
 	ack            bool
 	ackSetByUser   bool
@@ -52,6 +59,11 @@ type consumerCmd struct {
 	outFile        string
 	showAll        bool
 	acceptDefaults bool
+	resumeFile     string
+	decryptKey     string
+	decrypter      nkeys.KeyPair
+	replaySpeed    string
+	pacer          *replayPacer
 
 	selectedConsumer *jsm.Consumer
 
@@ -70,8 +82,13 @@ type consumerCmd struct {
 	pullCount           int
 	replayPolicy        string
 	reportLeaderDistrib bool
+	reportColumns       []string
+	reportParallel      int
+	pushGateway         string
+	pushJob             string
 	samplePct           int
 	startPolicy         string
+	startAtSubjectLast  bool
 	validateOnly        bool
 	description         string
 	inactiveThreshold   time.Duration
@@ -91,6 +108,9 @@ type consumerCmd struct {
 	metadataIsSet       bool
 	metadata            map[string]string
 
+	sampleDuration time.Duration
+	sampleSubjects bool
+
 	dryRun bool
 	mgr    *jsm.Manager
 	nc     *nats.Conn
@@ -109,7 +129,8 @@ func configureConsumerCommand(app commandHost) {
 		f.Flag("backoff-min", "The shortest backoff period that will be generated").PlaceHolder("MIN").Default("1m").DurationVar(&c.backoffMin)
 		f.Flag("backoff-max", "The longest backoff period that will be generated").PlaceHolder("MAX").Default("20m").DurationVar(&c.backoffMax)
 		if !edit {
-			f.Flag("deliver", "Start policy (all, new, last, subject, 1h, msg sequence)").PlaceHolder("POLICY").StringVar(&c.startPolicy)
+			f.Flag("deliver", "Start policy (all, new, last, subject, 1h, 2024-01-01T00:00:00Z, msg sequence)").PlaceHolder("POLICY").StringVar(&c.startPolicy)
+			f.Flag("start-at-subject-last", "Alias for --deliver subject, starts with the last message for every subject matching --filter which is required").UnNegatableBoolVar(&c.startAtSubjectLast)
 			f.Flag("deliver-group", "Delivers push messages only to subscriptions matching this group").Default("_unset_").PlaceHolder("GROUP").StringVar(&c.deliveryGroup)
 		}
 		f.Flag("description", "Sets a contextual description for the consumer").StringVar(&c.description)
@@ -151,23 +172,31 @@ func configureConsumerCommand(app commandHost) {
 	cons.Flag("all", "Operate on all streams including system ones").Short('a').UnNegatableBoolVar(&c.showAll)
 
 	consLs := cons.Command("ls", "List known Consumers").Alias("list").Action(c.lsAction)
-	consLs.Arg("stream", "Stream name").StringVar(&c.stream)
-	consLs.Flag("json", "Produce JSON output").Short('j').UnNegatableBoolVar(&c.json)
+	consLs.Arg("stream", "Stream name").HintAction(streamNamesHint).StringVar(&c.stream)
+	addJSONOutputFlag(consLs, &c.json)
+	pagedCommand(consLs)
 	consLs.Flag("names", "Show just the consumer names").Short('n').UnNegatableBoolVar(&c.listNames)
+	consLs.Flag("states", "Fetch the full state of every consumer").UnNegatableBoolVar(&c.listStates)
+	addParallelFlag(consLs, &c.workers)
 
 	conReport := cons.Command("report", "Reports on Consumer statistics").Action(c.reportAction)
-	conReport.Arg("stream", "Stream name").StringVar(&c.stream)
+	pagedCommand(conReport)
+	conReport.Arg("stream", "Stream name").HintAction(streamNamesHint).StringVar(&c.stream)
 	conReport.Flag("raw", "Show un-formatted numbers").Short('r').UnNegatableBoolVar(&c.raw)
 	conReport.Flag("leaders", "Show details about the leaders").Short('l').UnNegatableBoolVar(&c.reportLeaderDistrib)
+	conReport.Flag("columns", "Show only specific columns, comma separated").PlaceHolder("COLUMNS").StringsVar(&c.reportColumns)
+	addJSONOutputFlag(conReport, &c.json)
+	addParallelFlag(conReport, &c.reportParallel)
+	addPushGatewayFlags(conReport, &c.pushGateway, &c.pushJob)
 
 	consInfo := cons.Command("info", "Consumer information").Alias("nfo").Action(c.infoAction)
-	consInfo.Arg("stream", "Stream name").StringVar(&c.stream)
-	consInfo.Arg("consumer", "Consumer name").StringVar(&c.consumer)
-	consInfo.Flag("json", "Produce JSON output").Short('j').UnNegatableBoolVar(&c.json)
+	consInfo.Arg("stream", "Stream name").HintAction(streamNamesHint).StringVar(&c.stream)
+	consInfo.Arg("consumer", "Consumer name").HintAction(consumerNamesHint(&c.stream)).StringVar(&c.consumer)
+	addJSONOutputFlag(consInfo, &c.json)
 	consInfo.Flag("no-select", "Do not select consumers from a list").Default("false").UnNegatableBoolVar(&c.force)
 
-	consAdd := cons.Command("add", "Creates a new Consumer").Alias("create").Alias("new").Action(c.createAction)
-	consAdd.Arg("stream", "Stream name").StringVar(&c.stream)
+	consAdd := mutating(cons.Command("add", "Creates a new Consumer").Alias("create").Alias("new").Action(c.createAction))
+	consAdd.Arg("stream", "Stream name").HintAction(streamNamesHint).StringVar(&c.stream)
 	consAdd.Arg("consumer", "Consumer name").StringVar(&c.consumer)
 	consAdd.Flag("config", "JSON file to read configuration from").ExistingFileVar(&c.inputFile)
 	consAdd.Flag("validate", "Only validates the configuration against the official Schema").UnNegatableBoolVar(&c.validateOnly)
@@ -175,45 +204,58 @@ func configureConsumerCommand(app commandHost) {
 	addCreateFlags(consAdd, false)
 	consAdd.Flag("defaults", "Accept default values for all prompts").UnNegatableBoolVar(&c.acceptDefaults)
 
-	edit := cons.Command("edit", "Edits the configuration of a consumer").Alias("update").Action(c.editAction)
-	edit.Arg("stream", "Stream name").StringVar(&c.stream)
-	edit.Arg("consumer", "Consumer name").StringVar(&c.consumer)
+	edit := mutating(cons.Command("edit", "Edits the configuration of a consumer").Alias("update").Action(c.editAction))
+	edit.Arg("stream", "Stream name").HintAction(streamNamesHint).StringVar(&c.stream)
+	edit.Arg("consumer", "Consumer name").HintAction(consumerNamesHint(&c.stream)).StringVar(&c.consumer)
 	edit.Flag("config", "JSON file to read configuration from").ExistingFileVar(&c.inputFile)
 	edit.Flag("force", "Force removal without prompting").Short('f').UnNegatableBoolVar(&c.force)
 	edit.Flag("dry-run", "Only shows differences, do not edit the stream").UnNegatableBoolVar(&c.dryRun)
 	addCreateFlags(edit, true)
 
-	consRm := cons.Command("rm", "Removes a Consumer").Alias("delete").Alias("del").Action(c.rmAction)
-	consRm.Arg("stream", "Stream name").StringVar(&c.stream)
-	consRm.Arg("consumer", "Consumer name").StringVar(&c.consumer)
+	consRm := mutating(cons.Command("rm", "Removes a Consumer").Alias("delete").Alias("del").Action(c.rmAction))
+	consRm.Arg("stream", "Stream name").HintAction(streamNamesHint).StringVar(&c.stream)
+	consRm.Arg("consumer", "Consumer name").HintAction(consumerNamesHint(&c.stream)).StringVar(&c.consumer)
 	consRm.Flag("force", "Force removal without prompting").Short('f').UnNegatableBoolVar(&c.force)
 
-	consCp := cons.Command("copy", "Creates a new Consumer based on the configuration of another").Alias("cp").Action(c.cpAction)
-	consCp.Arg("stream", "Stream name").Required().StringVar(&c.stream)
-	consCp.Arg("source", "Source Consumer name").Required().StringVar(&c.consumer)
+	consCp := mutating(cons.Command("copy", "Creates a new Consumer based on the configuration of another").Alias("cp").Action(c.cpAction))
+	consCp.Arg("stream", "Stream name").HintAction(streamNamesHint).Required().StringVar(&c.stream)
+	consCp.Arg("source", "Source Consumer name").HintAction(consumerNamesHint(&c.stream)).Required().StringVar(&c.consumer)
 	consCp.Arg("destination", "Destination Consumer name").Required().StringVar(&c.destination)
 	addCreateFlags(consCp, false)
 
-	consNext := cons.Command("next", "Retrieves messages from Pull Consumers without interactive prompts").Action(c.nextAction)
-	consNext.Arg("stream", "Stream name").Required().StringVar(&c.stream)
-	consNext.Arg("consumer", "Consumer name").Required().StringVar(&c.consumer)
+	consNext := mutating(cons.Command("next", "Retrieves messages from Pull Consumers without interactive prompts").Action(c.nextAction))
+	consNext.Arg("stream", "Stream name").HintAction(streamNamesHint).Required().StringVar(&c.stream)
+	consNext.Arg("consumer", "Consumer name").HintAction(consumerNamesHint(&c.stream)).Required().StringVar(&c.consumer)
 	consNext.Flag("ack", "Acknowledge received message").Default("true").IsSetByUser(&c.ackSetByUser).BoolVar(&c.ack)
 	consNext.Flag("term", "Terms the message").Default("false").UnNegatableBoolVar(&c.term)
 	consNext.Flag("raw", "Show only the message").Short('r').UnNegatableBoolVar(&c.raw)
 	consNext.Flag("wait", "Wait up to this period to acknowledge messages").DurationVar(&c.ackWait)
 	consNext.Flag("count", "Number of messages to try to fetch from the pull consumer").Default("1").IntVar(&c.pullCount)
 
-	consSub := cons.Command("sub", "Retrieves messages from Consumers").Action(c.subAction)
-	consSub.Arg("stream", "Stream name").StringVar(&c.stream)
-	consSub.Arg("consumer", "Consumer name").StringVar(&c.consumer)
+	consSub := mutating(cons.Command("sub", "Retrieves messages from Consumers").Action(c.subAction))
+	consSub.Arg("stream", "Stream name").HintAction(streamNamesHint).StringVar(&c.stream)
+	consSub.Arg("consumer", "Consumer name").HintAction(consumerNamesHint(&c.stream)).StringVar(&c.consumer)
 	consSub.Flag("ack", "Acknowledge received message").Default("true").BoolVar(&c.ack)
 	consSub.Flag("raw", "Show only the message").Short('r').UnNegatableBoolVar(&c.raw)
 	consSub.Flag("deliver-group", "Deliver group of the consumer").StringVar(&c.deliveryGroup)
+	consSub.Flag("resume-file", "Records the last processed Stream sequence in a file and resumes from it on restart using an ordered consumer").PlaceHolder("FILE").StringVar(&c.resumeFile)
+	consSub.Flag("decrypt-key", "Decrypts encrypted payloads using a curve NKey seed, given directly or in a file, defaults to the key of the context").PlaceHolder("KEY").StringVar(&c.decryptKey)
+	consSub.Flag("speed", "Handles messages at a multiple of the rate they were stored at, like 2x or 0.5x").PlaceHolder("SPEED").StringVar(&c.replaySpeed)
+
+	consSample := cons.Command("sample", "Analyzes acknowledgement samples published by Consumers with sampling enabled").Action(c.sampleAction)
+	consSample.Arg("stream", "Stream name").HintAction(streamNamesHint).StringVar(&c.stream)
+	consSample.Arg("consumer", "Consumer name").HintAction(consumerNamesHint(&c.stream)).StringVar(&c.consumer)
+	consSample.Flag("duration", "How long to collect samples for").Default("5m").DurationVar(&c.sampleDuration)
+	consSample.Flag("subjects", "Break down samples by message subject, requires reading sampled messages from the Stream").UnNegatableBoolVar(&c.sampleSubjects)
+	addJSONOutputFlag(consSample, &c.json)
 
 	conCluster := cons.Command("cluster", "Manages a clustered Consumer").Alias("c")
-	conClusterDown := conCluster.Command("step-down", "Force a new leader election by standing down the current leader").Alias("elect").Alias("down").Alias("d").Action(c.leaderStandDown)
-	conClusterDown.Arg("stream", "Stream to act on").StringVar(&c.stream)
-	conClusterDown.Arg("consumer", "Consumer to act on").StringVar(&c.consumer)
+	conClusterDown := mutating(conCluster.Command("step-down", "Force a new leader election by standing down the current leader").Alias("elect").Alias("down").Alias("d").Action(c.leaderStandDown))
+	conClusterDown.Arg("stream", "Stream to act on").HintAction(streamNamesHint).StringVar(&c.stream)
+	conClusterDown.Arg("consumer", "Consumer to act on").HintAction(consumerNamesHint(&c.stream)).StringVar(&c.consumer)
+
+	configureConsumerRedriveCommand(cons)
+	configureConsumerPreviewCommand(cons)
 }
 
 func init() {
@@ -403,6 +445,7 @@ func (c *consumerCmd) editAction(pc *fisk.ParseContext) error {
 	if c.dryRun {
 		os.Exit(1)
 	}
+	auditConfigChange(diff)
 
 	if !c.force {
 		ok, err := askConfirmation(fmt.Sprintf("Really edit Consumer %s > %s", c.stream, c.consumer), false)
@@ -484,6 +527,10 @@ func (c *consumerCmd) lsAction(pc *fisk.ParseContext) error {
 	stream, err := c.mgr.LoadStream(c.stream)
 	fisk.FatalIfError(err, "could not load Consumers")
 
+	if c.listStates && !c.listNames {
+		return c.lsStates(stream)
+	}
+
 	consumers, err := stream.ConsumerNames()
 	fisk.FatalIfError(err, "could not load Consumers")
 
@@ -516,6 +563,57 @@ func (c *consumerCmd) lsAction(pc *fisk.ParseContext) error {
 	return nil
 }
 
+// lsStates lists the state of every consumer, fetched concurrently when --parallel is above 1
+func (c *consumerCmd) lsStates(stream *jsm.Stream) error {
+	consumers, missing, err := loadConsumers(c.mgr, stream.Name(), c.workers)
+	if err != nil {
+		return fmt.Errorf("could not load Consumers: %w", err)
+	}
+
+	sort.Slice(consumers, func(i, j int) bool { return consumers[i].Name() < consumers[j].Name() })
+
+	states := make([]api.ConsumerInfo, 0, len(consumers))
+	for _, cons := range consumers {
+		state, err := cons.LatestState()
+		if err != nil {
+			missing = append(missing, cons.Name())
+			continue
+		}
+		states = append(states, state)
+	}
+
+	if c.json {
+		return printJSON(states)
+	}
+
+	if len(states) == 0 {
+		fmt.Println("No Consumers defined")
+	} else {
+		table := newTableWriter(fmt.Sprintf("Consumers for Stream %s", stream.Name()))
+		table.AddHeaders("Name", "Mode", "Created", "Ack Pending", "Redelivered", "Unprocessed", "Waiting", "Last Delivery", "Cluster")
+		for _, state := range states {
+			mode := "Push"
+			if state.Config.DeliverSubject == "" {
+				mode = "Pull"
+			}
+
+			lastDelivery := ""
+			if state.Delivered.Last != nil {
+				lastDelivery = humanizeDuration(time.Since(*state.Delivered.Last))
+			}
+
+			table.AddRow(state.Name, mode, state.Created.Local().Format(time.RFC3339), humanize.Comma(int64(state.NumAckPending)), humanize.Comma(int64(state.NumRedelivered)), humanize.Comma(int64(state.NumPending)), state.NumWaiting, lastDelivery, renderCluster(state.Cluster))
+		}
+		fmt.Println(table.Render())
+	}
+
+	if len(missing) > 0 {
+		fmt.Printf("\nWARNING: could not load the state of %d Consumers: %s\n", len(missing), strings.Join(missing, ", "))
+	}
+
+	return nil
+}
+
 func (c *consumerCmd) showConsumer(consumer *jsm.Consumer) {
 	config := consumer.Configuration()
 	state, err := consumer.LatestState()
@@ -552,7 +650,8 @@ func (c *consumerCmd) renderBackoff(bo []time.Duration) string {
 
 func (c *consumerCmd) showInfo(config api.ConsumerConfig, state api.ConsumerInfo) {
 	if c.json {
-		printJSON(state)
+		err := printJSON(state)
+		fisk.FatalIfError(err, "could not display info")
 		return
 	}
 
@@ -780,6 +879,38 @@ func (c *consumerCmd) defaultConsumer() *api.ConsumerConfig {
 	}
 }
 
+// resolveStartAtSubjectLast turns --start-at-subject-last into the equivalent --deliver subject start policy,
+// filtered indicates the Consumer has filter subjects without which the policy can not be used
+func (c *consumerCmd) resolveStartAtSubjectLast(filtered bool) error {
+	if !c.startAtSubjectLast {
+		return nil
+	}
+
+	if !filtered {
+		return fmt.Errorf("--start-at-subject-last requires --filter")
+	}
+
+	if c.startPolicy != "" && c.startPolicy != "subject" && c.startPolicy != "last_per_subject" {
+		return fmt.Errorf("--start-at-subject-last can not be used with --deliver %s", c.startPolicy)
+	}
+
+	c.startPolicy = "subject"
+
+	return nil
+}
+
+// parseStartTime parses absolute start times given as RFC3339 timestamps or dates
+func parseStartTime(policy string) (time.Time, bool) {
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02"} {
+		t, err := time.Parse(layout, policy)
+		if err == nil {
+			return t.UTC(), true
+		}
+	}
+
+	return time.Time{}, false
+}
+
 func (c *consumerCmd) setStartPolicy(cfg *api.ConsumerConfig, policy string) {
 	if policy == "" {
 		return
@@ -797,9 +928,12 @@ func (c *consumerCmd) setStartPolicy(cfg *api.ConsumerConfig, policy string) {
 		seq, _ := strconv.Atoi(policy)
 		cfg.DeliverPolicy = api.DeliverByStartSequence
 		cfg.OptStartSeq = uint64(seq)
+	} else if t, ok := parseStartTime(policy); ok {
+		cfg.DeliverPolicy = api.DeliverByStartTime
+		cfg.OptStartTime = &t
 	} else {
 		d, err := parseDurationString(policy)
-		fisk.FatalIfError(err, "could not parse starting delta")
+		fisk.FatalIfError(err, "could not parse starting delta or time")
 		t := time.Now().UTC().Add(-d)
 		cfg.DeliverPolicy = api.DeliverByStartTime
 		cfg.OptStartTime = &t
@@ -822,6 +956,9 @@ func (c *consumerCmd) cpAction(pc *fisk.ParseContext) (err error) {
 		cfg.SampleFrequency = c.sampleFreqFromInt(c.samplePct)
 	}
 
+	err = c.resolveStartAtSubjectLast(len(c.filterSubjects) > 0 || cfg.FilterSubject != "" || len(cfg.FilterSubjects) > 0)
+	fisk.FatalIfError(err, "invalid start policy")
+
 	if c.startPolicy != "" {
 		c.setStartPolicy(&cfg, c.startPolicy)
 	}
@@ -926,7 +1063,9 @@ func (c *consumerCmd) cpAction(pc *fisk.ParseContext) (err error) {
 	}
 
 	consumer, err := c.mgr.NewConsumerFromDefault(c.stream, cfg)
-	fisk.FatalIfError(err, "Consumer creation failed")
+	if err != nil {
+		return fmt.Errorf("Consumer creation failed: %w", err)
+	}
 
 	if cfg.Durable == "" {
 		return nil
@@ -1001,6 +1140,8 @@ func (c *consumerCmd) prepareConfig(pc *fisk.ParseContext) (cfg *api.ConsumerCon
 		return cfg, err
 	}
 
+	version := c.connectedServerVersion()
+
 	if c.consumer == "" && !c.ephemeral {
 		err = askOne(&survey.Input{
 			Message: "Consumer name",
@@ -1070,10 +1211,15 @@ func (c *consumerCmd) prepareConfig(pc *fisk.ParseContext) (cfg *api.ConsumerCon
 		cfg.DeliverGroup = ""
 	}
 
+	err = c.resolveStartAtSubjectLast(len(c.filterSubjects) > 0)
+	if err != nil {
+		return nil, err
+	}
+
 	if c.startPolicy == "" {
 		err = askOne(&survey.Input{
-			Message: "Start policy (all, new, last, subject, 1h, msg sequence)",
-			Help:    "This controls how the Consumer starts out, does it make all messages available, only the latest, latest per subject, ones after a certain time or time sequence. Settable using --deliver",
+			Message: "Start policy (all, new, last, subject, 1h, 2024-01-01T00:00:00Z, msg sequence)",
+			Help:    "This controls how the Consumer starts out, does it make all messages available, only the latest, latest per subject, ones after a certain time given as a duration or RFC3339 timestamp or time sequence. Settable using --deliver and --start-at-subject-last",
 			Default: "all",
 		}, &c.startPolicy, survey.WithValidator(survey.Required))
 		fisk.FatalIfError(err, "could not request start policy")
@@ -1147,6 +1293,16 @@ func (c *consumerCmd) prepareConfig(pc *fisk.ParseContext) (cfg *api.ConsumerCon
 	}
 
 	switch {
+	case len(c.filterSubjects) == 0 && !c.acceptDefaults && serverSupports(version, featureMultipleFilters):
+		subs := ""
+		err = askOne(&survey.Input{
+			Message: "Filter Stream by subjects, separated by spaces or commas (blank for all)",
+			Default: "",
+			Help:    "Stream can consume more than one subject - or a wildcard - this allows you to filter out just some subjects from all the ones entering the Stream for delivery to the Consumer. Settable using --filter",
+		}, &subs)
+		fisk.FatalIfError(err, "could not ask for filtering subjects")
+		c.filterSubjects = splitCLISubjects([]string{subs})
+
 	case len(c.filterSubjects) == 0 && !c.acceptDefaults:
 		sub := ""
 		err = askOne(&survey.Input{
@@ -1156,6 +1312,10 @@ func (c *consumerCmd) prepareConfig(pc *fisk.ParseContext) (cfg *api.ConsumerCon
 		}, &sub)
 		fisk.FatalIfError(err, "could not ask for filtering subject")
 		c.filterSubjects = []string{sub}
+
+	case len(c.filterSubjects) > 1 && !serverSupports(version, featureMultipleFilters):
+		// older servers ignore the filters and deliver all messages
+		return nil, unsupportedFeatureError(version, featureMultipleFilters)
 	}
 
 	switch {
@@ -1279,9 +1439,46 @@ func (c *consumerCmd) prepareConfig(pc *fisk.ParseContext) (cfg *api.ConsumerCon
 		cfg.Metadata = c.metadata
 	}
 
+	c.checkServerFeatures(version, cfg)
+
 	return cfg, nil
 }
 
+// connectedServerVersion connects to find the server version when the configuration will be submitted,
+// an empty version is returned when not connected and all features are assumed to be supported
+func (c *consumerCmd) connectedServerVersion() string {
+	if c.validateOnly || c.outFile != "" {
+		return ""
+	}
+
+	if c.nc == nil {
+		nc, mgr, err := prepareHelper("", natsOpts()...)
+		if err != nil {
+			return ""
+		}
+		c.nc, c.mgr = nc, mgr
+	}
+
+	return serverVersion(c.nc)
+}
+
+// checkServerFeatures clears settings the connected server does not support, warning that they will be ignored
+func (c *consumerCmd) checkServerFeatures(version string, cfg *api.ConsumerConfig) {
+	if len(cfg.Metadata) > 0 && !serverSupports(version, featureMetadata) {
+		warnUnsupportedFeature(version, featureMetadata)
+		cfg.Metadata = nil
+	}
+
+	if cfg.InactiveThreshold > 0 && cfg.Durable != "" && !serverSupports(version, featureDurableInactive) {
+		warnUnsupportedFeature(version, featureDurableInactive)
+	}
+
+	if cfg.MaxRequestMaxBytes > 0 && !serverSupports(version, featurePullMaxBytes) {
+		warnUnsupportedFeature(version, featurePullMaxBytes)
+		cfg.MaxRequestMaxBytes = 0
+	}
+}
+
 func (c *consumerCmd) askBackoffPolicy() error {
 	ok, err := askConfirmation("Add a Retry Backoff Policy", false)
 	if err != nil {
@@ -1391,6 +1588,8 @@ func (c *consumerCmd) createAction(pc *fisk.ParseContext) (err error) {
 
 	c.connectAndSetup(true, false)
 
+	auditConfigChange(cmp.Diff(api.ConsumerConfig{}, *cfg))
+
 	created, err := c.mgr.NewConsumerFromDefault(c.stream, *cfg)
 	fisk.FatalIfError(err, "Consumer creation failed")
 
@@ -1440,6 +1639,14 @@ func (c *consumerCmd) getNextMsgDirect(stream string, consumer string) error {
 		fatalIfNotPull()
 	}
 
+	err = decryptPayload(msg, c.decrypter)
+	if err == nil {
+		err = decompressPayload(msg)
+	}
+	if err != nil && !c.raw {
+		log.Printf("Message on %s: %s", msg.Subject, err)
+	}
+
 	if !c.raw {
 		info, err := jsm.ParseJSMsgMetadata(msg)
 		if err != nil {
@@ -1540,6 +1747,14 @@ func (c *consumerCmd) subscribeConsumer(consumer *jsm.Consumer) (err error) {
 
 		fisk.FatalIfError(err, "could not parse JetStream metadata: '%s'", m.Reply)
 
+		err = decryptPayload(m, c.decrypter)
+		if err == nil {
+			err = decompressPayload(m)
+		}
+		if err != nil && !c.raw {
+			log.Printf("Message on %s: %s", m.Subject, err)
+		}
+
 		if !c.raw {
 			now := time.Now().Format("15:04:05")
 
@@ -1585,6 +1800,10 @@ func (c *consumerCmd) subscribeConsumer(consumer *jsm.Consumer) (err error) {
 		}
 	}
 
+	if c.pacer != nil {
+		handler = c.pacer.pace(handler)
+	}
+
 	if consumer.DeliverGroup() == "" {
 		_, err = c.nc.Subscribe(consumer.DeliverySubject(), handler)
 	} else {
@@ -1598,6 +1817,109 @@ func (c *consumerCmd) subscribeConsumer(consumer *jsm.Consumer) (err error) {
 	return nil
 }
 
+// resumeConsumer reads the stream using an ordered consumer matching the filter of consumer, starting
+// after the position in the resume file or after the ack floor of consumer when no position is known
+func (c *consumerCmd) resumeConsumer(consumer *jsm.Consumer) error {
+	pos, err := loadResumePosition(c.resumeFile)
+	if err != nil {
+		return err
+	}
+
+	if pos.Stream != "" && pos.Stream != consumer.StreamName() {
+		return fmt.Errorf("resume file %s records a position in Stream %s", c.resumeFile, pos.Stream)
+	}
+
+	start := pos.Sequence + 1
+	if pos.Sequence == 0 {
+		state, err := consumer.LatestState()
+		if err != nil {
+			return err
+		}
+		start = state.AckFloor.Stream + 1
+	}
+
+	js, err := c.nc.JetStream()
+	if err != nil {
+		return err
+	}
+
+	subj := consumer.FilterSubject()
+	if subj == "" {
+		subj = ">"
+	}
+
+	if !c.raw {
+		fmt.Printf("Resuming Stream %s using the filter of Consumer %s starting at sequence %d\n\n", consumer.StreamName(), consumer.Name(), start)
+	}
+
+	handler := func(m *nats.Msg) {
+		info, err := jsm.ParseJSMsgMetadata(m)
+		if err != nil {
+			log.Printf("Could not parse JetStream metadata: '%s': %s", m.Reply, err)
+			return
+		}
+
+		err = decryptPayload(m, c.decrypter)
+		if err == nil {
+			err = decompressPayload(m)
+		}
+		if err != nil && !c.raw {
+			log.Printf("Message on %s: %s", m.Subject, err)
+		}
+
+		if c.raw {
+			fmt.Println(string(m.Data))
+		} else {
+			fmt.Printf("[%s] subj: %s / str seq: %d / pending: %s\n", time.Now().Format("15:04:05"), m.Subject, info.StreamSequence(), humanize.Comma(int64(info.Pending())))
+			prettyPrintMsg(m, false, "")
+		}
+
+		pos.update(info, c.resumeFile)
+	}
+
+	if c.pacer != nil {
+		handler = c.pacer.pace(handler)
+	}
+
+	_, err = js.Subscribe(subj, handler, nats.BindStream(consumer.StreamName()), nats.OrderedConsumer(), nats.StartSequence(start))
+	if err != nil {
+		return err
+	}
+
+	<-ctx.Done()
+
+	return nil
+}
+
+// setupReplaySpeed prepares pacing messages using their stored time, consumers replaying at the original
+// rate are already paced by the server so can only be slowed down
+func (c *consumerCmd) setupReplaySpeed(consumer *jsm.Consumer) error {
+	var err error
+	c.pacer, err = newReplayPacer(c.replaySpeed)
+	if err != nil {
+		return err
+	}
+
+	if consumer.IsPullMode() && c.resumeFile == "" {
+		return fmt.Errorf("--speed requires a Push Consumer or --resume-file")
+	}
+
+	if consumer.ReplayPolicy() == api.ReplayOriginal && c.pacer.speed > 1 && c.resumeFile == "" {
+		return fmt.Errorf("consumer %s replays messages at their original rate, speeds above 1x require a Consumer with instant replay", consumer.Name())
+	}
+
+	// without acknowledgements or flow control the server does not wait for slowly paced messages to be handled
+	if c.pacer.speed < 1 && c.resumeFile == "" && consumer.AckPolicy() == api.AckNone && !consumer.FlowControl() {
+		return fmt.Errorf("consumer %s does not use acknowledgements or flow control, speeds below 1x require one of them or --resume-file", consumer.Name())
+	}
+
+	if c.ack && c.pacer.speed < 1 && !c.raw {
+		log.Printf("WARNING: messages waiting longer than the Ack Wait of %v will be redelivered", consumer.AckWait())
+	}
+
+	return nil
+}
+
 func (c *consumerCmd) subAction(_ *fisk.ParseContext) error {
 	c.connectAndSetup(true, true, nats.UseOldRequestStyle())
 
@@ -1608,7 +1930,21 @@ func (c *consumerCmd) subAction(_ *fisk.ParseContext) error {
 		c.ack = false
 	}
 
+	c.decrypter, err = loadPayloadDecryptKey(c.decryptKey)
+	if err != nil {
+		return withExitCode(ExitValidation, fmt.Errorf("invalid decryption key: %w", err))
+	}
+
+	if c.replaySpeed != "" {
+		err = c.setupReplaySpeed(consumer)
+		if err != nil {
+			return withExitCode(ExitValidation, err)
+		}
+	}
+
 	switch {
+	case c.resumeFile != "":
+		return c.resumeConsumer(consumer)
 	case consumer.IsPullMode():
 		return c.getNextMsgDirect(consumer.StreamName(), consumer.Name())
 	case consumer.IsPushMode():
@@ -1656,6 +1992,28 @@ func (c *consumerCmd) connectAndSetup(askStream bool, askConsumer bool, opts ...
 	}
 }
 
+// consumerReportEntry is the structured form of a row in the consumer report
+type consumerReportEntry struct {
+	Name           string           `json:"name"`
+	Mode           string           `json:"mode"`
+	AckPolicy      string           `json:"ack_policy"`
+	AckWait        time.Duration    `json:"ack_wait"`
+	NumAckPending  int              `json:"num_ack_pending"`
+	NumRedelivered int              `json:"num_redelivered"`
+	NumPending     uint64           `json:"num_pending"`
+	AckFloor       uint64           `json:"ack_floor_stream_seq"`
+	Cluster        *api.ClusterInfo `json:"cluster,omitempty"`
+	Filter         string           `json:"filter_subject,omitempty"`
+	LastDelivery   *time.Time       `json:"last_delivery,omitempty"`
+}
+
+// consumerReport is the structured form of the consumer report
+type consumerReport struct {
+	Stream       string                 `json:"stream"`
+	Consumers    []*consumerReportEntry `json:"consumers"`
+	Inaccessible []string               `json:"inaccessible,omitempty"`
+}
+
 func (c *consumerCmd) reportAction(_ *fisk.ParseContext) error {
 	c.connectAndSetup(true, false)
 
@@ -1671,13 +2029,31 @@ func (c *consumerCmd) reportAction(_ *fisk.ParseContext) error {
 
 	leaders := make(map[string]*raftLeader)
 
+	pending := newPushGauge("consumer", "pending", "Number of messages the consumer has not yet delivered", "stream", "consumer")
+	ackPending := newPushGauge("consumer", "ack_pending", "Number of delivered messages awaiting acknowledgement", "stream", "consumer")
+	redelivered := newPushGauge("consumer", "redelivered", "Number of messages that were redelivered", "stream", "consumer")
+
 	table := newTableWriter(fmt.Sprintf("Consumer report for %s with %s consumers", c.stream, humanize.Comma(int64(ss.Consumers))))
-	table.AddHeaders("Consumer", "Mode", "Ack Policy", "Ack Wait", "Ack Pending", "Redelivered", "Unprocessed", "Ack Floor", "Cluster")
-	missing, err := s.EachConsumer(func(cons *jsm.Consumer) {
+	table.AddHeaders("Consumer", "Mode", "Ack Policy", "Ack Wait", "Ack Pending", "Redelivered", "Unprocessed", "Ack Floor", "Cluster", "Filter Subject", "Last Delivery")
+	table.WideColumns("Filter Subject", "Last Delivery")
+	table.NarrowColumns("Consumer", "Ack Pending", "Redelivered", "Unprocessed")
+	err = table.SelectColumns(c.reportColumns...)
+	if err != nil {
+		return err
+	}
+
+	consumers, missing, err := loadConsumers(c.mgr, s.Name(), c.reportParallel)
+	if err != nil {
+		return err
+	}
+
+	report := &consumerReport{Stream: s.Name(), Consumers: []*consumerReportEntry{}}
+
+	for _, cons := range consumers {
 		cs, err := cons.LatestState()
 		if err != nil {
 			log.Printf("Could not obtain consumer state for %s: %s", cons.Name(), err)
-			return
+			continue
 		}
 
 		mode := "Push"
@@ -1695,8 +2071,36 @@ func (c *consumerCmd) reportAction(_ *fisk.ParseContext) error {
 			}
 		}
 
+		filter := cons.FilterSubject()
+		if len(cons.FilterSubjects()) > 0 {
+			filter = strings.Join(cons.FilterSubjects(), ", ")
+		}
+
+		pending.WithLabelValues(c.stream, cons.Name()).Set(float64(cs.NumPending))
+		ackPending.WithLabelValues(c.stream, cons.Name()).Set(float64(cs.NumAckPending))
+		redelivered.WithLabelValues(c.stream, cons.Name()).Set(float64(cs.NumRedelivered))
+
+		report.Consumers = append(report.Consumers, &consumerReportEntry{
+			Name:           cons.Name(),
+			Mode:           mode,
+			AckPolicy:      cons.AckPolicy().String(),
+			AckWait:        cons.AckWait(),
+			NumAckPending:  cs.NumAckPending,
+			NumRedelivered: cs.NumRedelivered,
+			NumPending:     cs.NumPending,
+			AckFloor:       cs.AckFloor.Stream,
+			Cluster:        cs.Cluster,
+			Filter:         filter,
+			LastDelivery:   cs.Delivered.Last,
+		})
+
+		lastDelivery := "never"
+		if cs.Delivered.Last != nil {
+			lastDelivery = humanizeDuration(time.Since(*cs.Delivered.Last))
+		}
+
 		if c.raw {
-			table.AddRow(cons.Name(), mode, cons.AckPolicy().String(), cons.AckWait(), cs.NumAckPending, cs.NumRedelivered, cs.NumPending, cs.AckFloor.Stream, renderCluster(cs.Cluster))
+			table.AddRow(cons.Name(), mode, cons.AckPolicy().String(), cons.AckWait(), cs.NumAckPending, cs.NumRedelivered, cs.NumPending, cs.AckFloor.Stream, renderCluster(cs.Cluster), filter, lastDelivery)
 		} else {
 			unprocessed := "0"
 			if cs.NumPending > 0 {
@@ -1707,11 +2111,21 @@ func (c *consumerCmd) reportAction(_ *fisk.ParseContext) error {
 				unprocessed = fmt.Sprintf("%s / %0.0f%%", humanize.Comma(int64(cs.NumPending)), upct)
 			}
 
-			table.AddRow(cons.Name(), mode, cons.AckPolicy().String(), humanizeDuration(cons.AckWait()), humanize.Comma(int64(cs.NumAckPending)), humanize.Comma(int64(cs.NumRedelivered)), unprocessed, humanize.Comma(int64(cs.AckFloor.Stream)), renderCluster(cs.Cluster))
+			table.AddRow(cons.Name(), mode, cons.AckPolicy().String(), humanizeDuration(cons.AckWait()), humanize.Comma(int64(cs.NumAckPending)), humanize.Comma(int64(cs.NumRedelivered)), unprocessed, humanize.Comma(int64(cs.AckFloor.Stream)), renderCluster(cs.Cluster), filter, lastDelivery)
+		}
+	}
+
+	if c.pushGateway != "" {
+		err = pushMetrics(c.pushGateway, c.pushJob, pending, ackPending, redelivered)
+		if err != nil {
+			return err
 		}
-	})
-	if err != nil {
-		return err
+	}
+
+	if c.json {
+		sort.Strings(missing)
+		report.Inaccessible = missing
+		return printJSON(report)
 	}
 
 	fmt.Println(table.Render())
@@ -1745,3 +2159,197 @@ func (c *consumerCmd) renderMissing(out io.Writer, missing []string) {
 		fmt.Fprint(out, table.Render())
 	}
 }
+
+type consumerSampleStats struct {
+	Subject     string        `json:"subject,omitempty"`
+	Samples     int           `json:"samples"`
+	Redelivered int           `json:"redelivered"`
+	Deliveries  uint64        `json:"deliveries"`
+	Min         time.Duration `json:"min"`
+	P50         time.Duration `json:"p50"`
+	P90         time.Duration `json:"p90"`
+	P99         time.Duration `json:"p99"`
+	Max         time.Duration `json:"max"`
+
+	delays []int64
+}
+
+func (s *consumerSampleStats) add(delay int64, deliveries uint64) {
+	s.Samples++
+	s.Deliveries += deliveries
+	if deliveries > 1 {
+		s.Redelivered++
+	}
+	s.delays = append(s.delays, delay)
+}
+
+func (s *consumerSampleStats) calculate() {
+	if len(s.delays) == 0 {
+		return
+	}
+
+	highest := int64(2)
+	for _, d := range s.delays {
+		if d > highest {
+			highest = d
+		}
+	}
+
+	h := hdrhistogram.New(1, highest, 3)
+	for _, d := range s.delays {
+		h.RecordValue(d)
+	}
+
+	s.Min = time.Duration(h.Min())
+	s.P50 = time.Duration(h.ValueAtQuantile(50))
+	s.P90 = time.Duration(h.ValueAtQuantile(90))
+	s.P99 = time.Duration(h.ValueAtQuantile(99))
+	s.Max = time.Duration(h.Max())
+}
+
+func (s *consumerSampleStats) redeliveryRate() float64 {
+	if s.Samples == 0 {
+		return 0
+	}
+
+	return float64(s.Redelivered) / float64(s.Samples) * 100
+}
+
+func (c *consumerCmd) sampleAction(_ *fisk.ParseContext) error {
+	c.connectAndSetup(true, true)
+
+	cfg := c.selectedConsumer.Configuration()
+	if cfg.SampleFrequency == "" {
+		return fmt.Errorf("consumer %s > %s does not have acknowledgement sampling enabled, enable it using 'nats consumer edit %s %s --sample 100'", c.stream, c.consumer, c.stream, c.consumer)
+	}
+
+	var stream *jsm.Stream
+	if c.sampleSubjects {
+		var err error
+		stream, err = c.mgr.LoadStream(c.stream)
+		if err != nil {
+			return err
+		}
+	}
+
+	timeout, cancel := context.WithTimeout(ctx, c.sampleDuration)
+	defer cancel()
+
+	ic := make(chan os.Signal, 1)
+	signal.Notify(ic, os.Interrupt)
+	defer signal.Stop(ic)
+	go func() {
+		select {
+		case <-ic:
+			cancel()
+		case <-timeout.Done():
+		}
+	}()
+
+	var mu sync.Mutex
+	total := &consumerSampleStats{}
+	subjects := map[string]*consumerSampleStats{}
+
+	subj := fmt.Sprintf("%s.%s.%s", jsm.EventSubject(api.JSMetricConsumerAckPre, opts.Config.JSEventPrefix()), c.stream, c.consumer)
+	sub, err := c.nc.Subscribe(subj, func(m *nats.Msg) {
+		sample := metric.ConsumerAckMetricV1{}
+		err := json.Unmarshal(m.Data, &sample)
+		if err != nil {
+			log.Printf("Invalid acknowledgement sample: %s", err)
+			return
+		}
+
+		var subject string
+		if stream != nil {
+			msg, err := stream.ReadMessage(sample.StreamSeq)
+			if err == nil {
+				subject = msg.Subject
+			} else {
+				subject = "unknown"
+			}
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		total.add(sample.Delay, sample.Deliveries)
+		if subject != "" {
+			ss, ok := subjects[subject]
+			if !ok {
+				ss = &consumerSampleStats{Subject: subject}
+				subjects[subject] = ss
+			}
+			ss.add(sample.Delay, sample.Deliveries)
+		}
+
+		if !c.json {
+			fmt.Printf("\rCollected %s samples", humanize.Comma(int64(total.Samples)))
+		}
+	})
+	if err != nil {
+		return err
+	}
+	defer sub.Unsubscribe()
+
+	if !c.json {
+		fmt.Printf("Collecting %s sampled acknowledgements on %s for %v, press ^C to report early\n", cfg.SampleFrequency, subj, c.sampleDuration)
+	}
+
+	<-timeout.Done()
+	sub.Unsubscribe()
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	total.calculate()
+	var bySubject []*consumerSampleStats
+	for _, ss := range subjects {
+		ss.calculate()
+		bySubject = append(bySubject, ss)
+	}
+	sort.Slice(bySubject, func(i, j int) bool {
+		return bySubject[i].P99 > bySubject[j].P99
+	})
+
+	if c.json {
+		return printJSON(map[string]any{
+			"stream":     c.stream,
+			"consumer":   c.consumer,
+			"total":      total,
+			"by_subject": bySubject,
+		})
+	}
+
+	fmt.Println()
+	fmt.Println()
+
+	if total.Samples == 0 {
+		fmt.Println("No acknowledgement samples were received")
+		return nil
+	}
+
+	avgDeliveries := float64(total.Deliveries) / float64(total.Samples)
+
+	fmt.Printf("Acknowledgement samples for %s > %s\n\n", c.stream, c.consumer)
+	fmt.Printf("            Samples: %s\n", humanize.Comma(int64(total.Samples)))
+	fmt.Printf("        Redelivered: %s (%.1f%%)\n", humanize.Comma(int64(total.Redelivered)), total.redeliveryRate())
+	fmt.Printf(" Average Deliveries: %.2f\n", avgDeliveries)
+	fmt.Printf("    Minimum Latency: %v\n", total.Min)
+	fmt.Printf("                p50: %v\n", total.P50)
+	fmt.Printf("                p90: %v\n", total.P90)
+	fmt.Printf("                p99: %v\n", total.P99)
+	fmt.Printf("    Maximum Latency: %v\n", total.Max)
+
+	if len(bySubject) > 0 {
+		fmt.Println()
+
+		table := newTableWriter("Acknowledgement latency by subject")
+		table.AddHeaders("Subject", "Samples", "Redelivered", "p50", "p90", "p99", "Max")
+		for _, ss := range bySubject {
+			table.AddRow(ss.Subject, humanize.Comma(int64(ss.Samples)), fmt.Sprintf("%.1f%%", ss.redeliveryRate()), ss.P50, ss.P90, ss.P99, ss.Max)
+		}
+		fmt.Print(table.Render())
+	}
+
+	return nil
+}
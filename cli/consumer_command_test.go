@@ -0,0 +1,69 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nats-io/jsm.go/api"
+)
+
+func TestConsumerSetStartPolicy(t *testing.T) {
+	c := &consumerCmd{}
+
+	cfg := &api.ConsumerConfig{}
+	c.setStartPolicy(cfg, "2024-01-01T10:00:00+02:00")
+	if cfg.DeliverPolicy != api.DeliverByStartTime {
+		t.Fatalf("expected start time policy got %v", cfg.DeliverPolicy)
+	}
+	if !cfg.OptStartTime.Equal(time.Date(2024, 1, 1, 8, 0, 0, 0, time.UTC)) {
+		t.Fatalf("invalid start time %v", cfg.OptStartTime)
+	}
+
+	cfg = &api.ConsumerConfig{}
+	c.setStartPolicy(cfg, "2024-01-01")
+	if !cfg.OptStartTime.Equal(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("invalid start time %v", cfg.OptStartTime)
+	}
+
+	cfg = &api.ConsumerConfig{}
+	c.setStartPolicy(cfg, "1h")
+	if cfg.DeliverPolicy != api.DeliverByStartTime || time.Since(*cfg.OptStartTime).Round(time.Minute) != time.Hour {
+		t.Fatalf("invalid relative start time %v", cfg.OptStartTime)
+	}
+
+	cfg = &api.ConsumerConfig{}
+	c.setStartPolicy(cfg, "10")
+	if cfg.DeliverPolicy != api.DeliverByStartSequence || cfg.OptStartSeq != 10 {
+		t.Fatalf("invalid sequence policy %v %d", cfg.DeliverPolicy, cfg.OptStartSeq)
+	}
+}
+
+func TestConsumerResolveStartAtSubjectLast(t *testing.T) {
+	c := &consumerCmd{startAtSubjectLast: true}
+	if err := c.resolveStartAtSubjectLast(true); err != nil || c.startPolicy != "subject" {
+		t.Fatalf("expected subject policy got %q: %v", c.startPolicy, err)
+	}
+
+	c = &consumerCmd{startAtSubjectLast: true, startPolicy: "all"}
+	if err := c.resolveStartAtSubjectLast(true); err == nil {
+		t.Fatalf("expected conflicting policies to fail")
+	}
+
+	c = &consumerCmd{startAtSubjectLast: true}
+	if err := c.resolveStartAtSubjectLast(false); err == nil {
+		t.Fatalf("expected unfiltered consumers to fail")
+	}
+}
@@ -0,0 +1,184 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"fmt"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/choria-io/fisk"
+	"github.com/dustin/go-humanize"
+	"github.com/nats-io/jsm.go"
+	"github.com/nats-io/jsm.go/api"
+	"github.com/nats-io/nats.go"
+)
+
+type consumerPreviewCmd struct {
+	stream  string
+	deliver string
+	filters []string
+	json    bool
+}
+
+// consumerStartPreview is where a consumer with a specific start policy would start reading a stream
+type consumerStartPreview struct {
+	Stream        string     `json:"stream"`
+	DeliverPolicy string     `json:"deliver_policy"`
+	Filters       []string   `json:"filters,omitempty"`
+	Pending       uint64     `json:"pending"`
+	StartSequence uint64     `json:"start_sequence,omitempty"`
+	StartTime     *time.Time `json:"start_time,omitempty"`
+	StartSubject  string     `json:"start_subject,omitempty"`
+	StreamLastSeq uint64     `json:"stream_last_sequence"`
+	StreamMsgs    uint64     `json:"stream_messages"`
+}
+
+func configureConsumerPreviewCommand(cons *fisk.CmdClause) {
+	c := &consumerPreviewCmd{}
+
+	help := `Shows where a new Consumer would start reading a Stream
+
+Creates a short lived ephemeral Consumer using the start policy and
+filters, reports the first message it would receive and how many
+messages would be pending, and removes it again without acknowledging
+any message.
+
+Use this to check --deliver settings before creating Consumers that
+would start with a big backlog.
+`
+
+	preview := cons.Command("preview-start", help).Action(c.previewAction)
+	preview.Arg("stream", "The Stream the Consumer would read").Required().HintAction(streamNamesHint).StringVar(&c.stream)
+	preview.Flag("deliver", "Start policy (all, new, last, subject, 1h, msg sequence)").Default("all").PlaceHolder("POLICY").StringVar(&c.deliver)
+	preview.Flag("filter", "Filter subjects of the Consumer (pass multiple times)").PlaceHolder("SUBJECT").StringsVar(&c.filters)
+	addJSONOutputFlag(preview, &c.json)
+}
+
+func (c *consumerPreviewCmd) previewAction(_ *fisk.ParseContext) error {
+	_, mgr, err := prepareHelper("", append(natsOpts(), nats.UseOldRequestStyle())...)
+	if err != nil {
+		return err
+	}
+
+	str, err := mgr.LoadStream(c.stream)
+	if err != nil {
+		return fmt.Errorf("could not load Stream %s: %w", c.stream, err)
+	}
+
+	state, err := str.State()
+	if err != nil {
+		return err
+	}
+
+	cfg := api.ConsumerConfig{
+		Description: "Start preview by nats consumer preview-start",
+		AckPolicy:   api.AckExplicit,
+		AckWait:     time.Minute,
+		HeadersOnly: true,
+	}
+	(&consumerCmd{}).setStartPolicy(&cfg, c.deliver)
+
+	switch len(c.filters) {
+	case 0:
+	case 1:
+		cfg.FilterSubject = c.filters[0]
+	default:
+		cfg.FilterSubjects = c.filters
+	}
+
+	// interrupts cancel the context so the preview consumer is removed by its deferred delete
+	ctx, cancel := signal.NotifyContext(ctx, syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT)
+	defer cancel()
+
+	cons, err := newTempConsumer(mgr, str.Name(), cfg)
+	if err != nil {
+		return fmt.Errorf("could not create preview Consumer: %w", err)
+	}
+	defer deleteTempConsumer(cons)
+
+	nfo, err := cons.LatestState()
+	if err != nil {
+		return err
+	}
+
+	preview := &consumerStartPreview{
+		Stream:        str.Name(),
+		DeliverPolicy: cfg.DeliverPolicy.String(),
+		Filters:       c.filters,
+		Pending:       nfo.NumPending,
+		StreamLastSeq: state.LastSeq,
+		StreamMsgs:    state.Msgs,
+	}
+
+	if preview.Pending > 0 {
+		msg, err := cons.NextMsg()
+		if err != nil {
+			return fmt.Errorf("could not read the first message: %w", err)
+		}
+
+		meta, err := jsm.ParseJSMsgMetadata(msg)
+		if err != nil {
+			return err
+		}
+
+		ts := meta.TimeStamp()
+		preview.StartSequence = meta.StreamSequence()
+		preview.StartTime = &ts
+		preview.StartSubject = msg.Subject
+	}
+
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	if c.json {
+		return printJSON(preview)
+	}
+
+	c.render(preview)
+
+	return nil
+}
+
+func (c *consumerPreviewCmd) render(p *consumerStartPreview) {
+	filters := "all subjects"
+	if len(p.Filters) > 0 {
+		filters = strings.Join(p.Filters, ", ")
+	}
+
+	fmt.Printf("A Consumer on Stream %s with deliver policy %s reading %s would start:\n\n", p.Stream, p.DeliverPolicy, filters)
+
+	if p.Pending == 0 {
+		fmt.Println("        Start: with the next message stored in the Stream")
+		fmt.Println("      Pending: 0 messages")
+		fmt.Println()
+		fmt.Printf("The Stream holds %s messages up to sequence %s, none would be delivered\n", humanize.Comma(int64(p.StreamMsgs)), humanize.Comma(int64(p.StreamLastSeq)))
+		return
+	}
+
+	fmt.Printf("     Sequence: %s\n", humanize.Comma(int64(p.StartSequence)))
+	fmt.Printf("      Subject: %s\n", p.StartSubject)
+	fmt.Printf("     Received: %s (%s ago)\n", p.StartTime.Local().Format(time.RFC3339), humanizeDuration(time.Since(*p.StartTime).Round(time.Second)))
+	fmt.Printf("      Pending: %s messages\n", humanize.Comma(int64(p.Pending)))
+	fmt.Println()
+
+	pct := 0.0
+	if p.StreamMsgs > 0 {
+		pct = float64(p.Pending) / float64(p.StreamMsgs) * 100
+	}
+	fmt.Printf("The initial backlog is %.1f%% of the %s messages in the Stream\n", pct, humanize.Comma(int64(p.StreamMsgs)))
+}
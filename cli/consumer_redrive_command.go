@@ -0,0 +1,348 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/choria-io/fisk"
+	"github.com/dustin/go-humanize"
+	"github.com/nats-io/jsm.go"
+	"github.com/nats-io/nats.go"
+)
+
+type consumerRedriveCmd struct {
+	stream     string
+	consumer   string
+	since      string
+	subject    string
+	advisories []string
+	terminated bool
+	dryRun     bool
+	force      bool
+
+	nc  *nats.Conn
+	mgr *jsm.Manager
+	js  nats.JetStreamContext
+}
+
+func configureConsumerRedriveCommand(cons *fisk.CmdClause) {
+	c := &consumerRedriveCmd{}
+
+	help := `Republishes messages that failed processing by a Consumer
+
+Messages that exceeded the maximum deliveries of the Consumer, or were
+terminated by clients, are found using the advisories published about
+them. The advisories have to be stored in a Stream, like one created
+using nats dlq setup, and the messages have to still be held in the
+Stream of the Consumer.
+`
+
+	redrive := mutating(cons.Command("redrive", help).Action(c.redriveAction))
+	redrive.Arg("stream", "The Stream the Consumer reads").HintAction(streamNamesHint).Required().StringVar(&c.stream)
+	redrive.Arg("consumer", "The Consumer that failed to process messages").HintAction(consumerNamesHint(&c.stream)).Required().StringVar(&c.consumer)
+	redrive.Flag("since", "Only redrive messages that failed in this period like 1h").PlaceHolder("DURATION").StringVar(&c.since)
+	redrive.Flag("subject", "Republish to this subject rather than the original subject of messages").PlaceHolder("SUBJECT").StringVar(&c.subject)
+	redrive.Flag("advisories", "Stream holding the advisories, found automatically by default (pass multiple times)").PlaceHolder("STREAM").StringsVar(&c.advisories)
+	redrive.Flag("terminated", "Include messages terminated by clients").Default("true").BoolVar(&c.terminated)
+	redrive.Flag("dry-run", "Lists the messages that would be republished").UnNegatableBoolVar(&c.dryRun)
+	redrive.Flag("force", "Redrive without prompting").Short('f').UnNegatableBoolVar(&c.force)
+}
+
+func (c *consumerRedriveCmd) advisorySubjects() []string {
+	subjects := []string{fmt.Sprintf("%s.%s.%s", dlqMaxDeliveriesSubject, c.stream, c.consumer)}
+	if c.terminated {
+		subjects = append(subjects, fmt.Sprintf("%s.%s.%s", dlqTerminatedSubject, c.stream, c.consumer))
+	}
+
+	return subjects
+}
+
+// advisoryStreams finds the Streams storing advisories about the Consumer
+func (c *consumerRedriveCmd) advisoryStreams() ([]string, error) {
+	if len(c.advisories) > 0 {
+		return c.advisories, nil
+	}
+
+	found := map[string]bool{}
+	for _, subj := range c.advisorySubjects() {
+		names, err := c.mgr.StreamNames(&jsm.StreamNamesFilter{Subject: subj})
+		if err != nil {
+			return nil, err
+		}
+
+		for _, name := range names {
+			found[name] = true
+		}
+	}
+
+	var streams []string
+	for name := range found {
+		streams = append(streams, name)
+	}
+	sort.Strings(streams)
+
+	return streams, nil
+}
+
+// readAdvisories reads the advisories about the Consumer stored in stream since start, a zero start reads all
+func (c *consumerRedriveCmd) readAdvisories(stream string, subject string, start time.Time) ([]*dlqEntry, error) {
+	sopts := []nats.SubOpt{nats.OrderedConsumer(), nats.BindStream(stream)}
+	if start.IsZero() {
+		sopts = append(sopts, nats.DeliverAll())
+	} else {
+		sopts = append(sopts, nats.StartTime(start))
+	}
+
+	sub, err := c.js.SubscribeSync(subject, sopts...)
+	if err != nil {
+		return nil, fmt.Errorf("could not read advisories from %s: %w", stream, err)
+	}
+	defer sub.Unsubscribe()
+
+	nfo, err := sub.ConsumerInfo()
+	if err != nil {
+		return nil, err
+	}
+
+	// messages might have been pushed to the subscription already
+	var entries []*dlqEntry
+	if nfo.NumPending == 0 && nfo.Delivered.Consumer == 0 {
+		return entries, nil
+	}
+
+	for {
+		msg, err := sub.NextMsg(opts.Timeout)
+		if err != nil {
+			return nil, fmt.Errorf("could not read advisories from %s: %w", stream, err)
+		}
+
+		meta, err := msg.Metadata()
+		if err != nil {
+			return nil, err
+		}
+
+		var adv struct {
+			Type       string    `json:"type"`
+			Time       time.Time `json:"timestamp"`
+			Stream     string    `json:"stream"`
+			Consumer   string    `json:"consumer"`
+			StreamSeq  uint64    `json:"stream_seq"`
+			Deliveries uint64    `json:"deliveries"`
+		}
+		err = json.Unmarshal(msg.Data, &adv)
+		if err != nil {
+			return nil, fmt.Errorf("invalid advisory in message %d of %s: %w", meta.Sequence.Stream, stream, err)
+		}
+
+		entry := &dlqEntry{
+			Sequence:   meta.Sequence.Stream,
+			Reason:     "max deliveries",
+			Time:       adv.Time,
+			Stream:     adv.Stream,
+			Consumer:   adv.Consumer,
+			StreamSeq:  adv.StreamSeq,
+			Deliveries: adv.Deliveries,
+		}
+		if adv.Type == "io.nats.jetstream.advisory.v1.terminated" {
+			entry.Reason = "terminated"
+		}
+		entries = append(entries, entry)
+
+		if meta.NumPending == 0 {
+			return entries, nil
+		}
+	}
+}
+
+// entries finds the failed messages of the Consumer, a message that failed several times is only included once
+func (c *consumerRedriveCmd) entries() ([]*dlqEntry, error) {
+	var start time.Time
+	if c.since != "" {
+		since, err := parseDurationString(c.since)
+		if err != nil {
+			return nil, withExitCode(ExitValidation, fmt.Errorf("invalid since duration: %w", err))
+		}
+		start = time.Now().Add(-since)
+	}
+
+	streams, err := c.advisoryStreams()
+	if err != nil {
+		return nil, err
+	}
+	if len(streams) == 0 {
+		return nil, withExitCode(ExitNotFound, fmt.Errorf("no Stream stores advisories for Consumer %s > %s, create one using nats dlq setup", c.stream, c.consumer))
+	}
+
+	found := map[uint64]*dlqEntry{}
+	for _, stream := range streams {
+		for _, subj := range c.advisorySubjects() {
+			entries, err := c.readAdvisories(stream, subj, start)
+			if err != nil {
+				return nil, err
+			}
+
+			for _, e := range entries {
+				// the latest failure of a message is kept
+				if prev, ok := found[e.StreamSeq]; !ok || e.Time.After(prev.Time) {
+					found[e.StreamSeq] = e
+				}
+			}
+		}
+	}
+
+	str, err := c.mgr.LoadStream(c.stream)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []*dlqEntry
+	for _, e := range found {
+		e.Message, err = str.ReadMessage(e.StreamSeq)
+		if err != nil && !jsm.IsNatsError(err, 10037) {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].StreamSeq < entries[j].StreamSeq })
+
+	return entries, nil
+}
+
+func (c *consumerRedriveCmd) redriveAction(_ *fisk.ParseContext) error {
+	var err error
+
+	c.nc, c.mgr, err = prepareHelper("", natsOpts()...)
+	if err != nil {
+		return err
+	}
+
+	_, c.js, err = prepareJSHelper()
+	if err != nil {
+		return err
+	}
+
+	entries, err := c.entries()
+	if err != nil {
+		return err
+	}
+
+	if len(entries) == 0 {
+		fmt.Printf("No failed messages found for Consumer %s > %s\n", c.stream, c.consumer)
+		return nil
+	}
+
+	if c.dryRun {
+		table := newTableWriter(fmt.Sprintf("%d failed messages for Consumer %s > %s", len(entries), c.stream, c.consumer))
+		table.AddHeaders("Stream Sequence", "Time", "Reason", "Deliveries", "Subject", "Size")
+		for _, e := range entries {
+			subject, size := "message not found", ""
+			if e.Message != nil {
+				subject = e.Message.Subject
+				size = humanize.IBytes(uint64(len(e.Message.Data)))
+			}
+
+			table.AddRow(e.StreamSeq, e.Time.Local().Format("2006-01-02 15:04:05"), e.Reason, e.Deliveries, subject, size)
+		}
+		fmt.Println(table.Render())
+
+		return nil
+	}
+
+	if !c.force {
+		target := "their original subjects"
+		if c.subject != "" {
+			target = c.subject
+		}
+
+		ok, err := askConfirmation(fmt.Sprintf("Really republish %d messages to %s", len(entries), target), false)
+		if err != nil {
+			return err
+		}
+
+		if !ok {
+			return nil
+		}
+	}
+
+	// messages are republished using JetStream unless they are sent to a subject that is not stored in a Stream
+	jetstream := true
+	if c.subject != "" {
+		streams, err := c.mgr.StreamNames(&jsm.StreamNamesFilter{Subject: c.subject})
+		if err != nil {
+			return err
+		}
+		jetstream = len(streams) > 0
+	}
+
+	redriven, missing := 0, 0
+	for _, e := range entries {
+		if e.Message == nil {
+			missing++
+			continue
+		}
+
+		msg := nats.NewMsg(e.Message.Subject)
+		if c.subject != "" {
+			msg.Subject = c.subject
+		}
+		msg.Data = e.Message.Data
+		if len(e.Message.Header) > 0 {
+			hdr, err := decodeHeadersMsg(e.Message.Header)
+			if err != nil {
+				return fmt.Errorf("could not parse headers of message %d in %s: %w", e.StreamSeq, e.Stream, err)
+			}
+			msg.Header = hdr
+		}
+		for _, h := range migrateHeaders {
+			msg.Header.Del(h)
+		}
+
+		if jetstream {
+			ack, err := c.js.PublishMsg(msg)
+			if err != nil {
+				return fmt.Errorf("could not republish message %d to %s: %w", e.StreamSeq, msg.Subject, err)
+			}
+
+			if opts.Trace {
+				log.Printf("Republished %s > %d to %s > %d", e.Stream, e.StreamSeq, ack.Stream, ack.Sequence)
+			}
+		} else {
+			err = c.nc.PublishMsg(msg)
+			if err != nil {
+				return fmt.Errorf("could not republish message %d to %s: %w", e.StreamSeq, msg.Subject, err)
+			}
+		}
+
+		redriven++
+	}
+
+	if !jetstream {
+		err = c.nc.FlushTimeout(opts.Timeout)
+		if err != nil {
+			return err
+		}
+	}
+
+	fmt.Printf("Republished %d failed messages of Consumer %s > %s\n", redriven, c.stream, c.consumer)
+	if missing > 0 {
+		fmt.Printf("%d messages are no longer stored in %s and could not be republished\n", missing, c.stream)
+	}
+
+	return nil
+}
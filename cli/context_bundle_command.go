@@ -0,0 +1,399 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/choria-io/fisk"
+	"github.com/nats-io/jsm.go/natscontext"
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/scrypt"
+)
+
+const (
+	ctxBundleFormat      = "io.nats.cli.context_bundle.v1"
+	ctxBundlePasswordEnv = "NATS_CONTEXT_BUNDLE_PASSWORD"
+)
+
+// the context settings that refer to files that can be embedded in a bundle
+var ctxBundleFileKeys = []string{"creds", "nkey", "cert", "key", "ca"}
+
+type ctxBundleCmd struct {
+	names   []string
+	all     bool
+	output  string
+	input   string
+	encrypt bool
+	embed   bool
+	force   bool
+}
+
+// ctxBundle is the file written by context export, Data holds the encrypted Contexts when Encrypted is set
+type ctxBundle struct {
+	Format    string              `json:"format"`
+	Created   time.Time           `json:"created"`
+	Encrypted bool                `json:"encrypted"`
+	Salt      []byte              `json:"salt,omitempty"`
+	Nonce     []byte              `json:"nonce,omitempty"`
+	Data      []byte              `json:"data,omitempty"`
+	Contexts  []*ctxBundleContext `json:"contexts,omitempty"`
+}
+
+type ctxBundleContext struct {
+	Name     string            `json:"name"`
+	Settings map[string]any    `json:"settings"`
+	Files    map[string][]byte `json:"files,omitempty"`
+}
+
+func configureCtxBundleCommand(context *fisk.CmdClause) {
+	c := &ctxBundleCmd{}
+
+	export := context.Command("export", "Exports contexts to a bundle that can be shared with others").Action(c.exportAction)
+	export.Arg("names", "The contexts to export").HintAction(contextNamesHint).StringsVar(&c.names)
+	export.Flag("all", "Export all known contexts").Short('a').UnNegatableBoolVar(&c.all)
+	export.Flag("output", "The file to write the bundle to").Short('o').Required().StringVar(&c.output)
+	export.Flag("encrypt", fmt.Sprintf("Encrypt the bundle using a password read from %s or prompted for", ctxBundlePasswordEnv)).UnNegatableBoolVar(&c.encrypt)
+	export.Flag("embed-creds", "Embed credentials, NKeys and certificates referenced by the contexts").UnNegatableBoolVar(&c.embed)
+	export.Flag("force", "Overwrite the output file without prompting").Short('f').UnNegatableBoolVar(&c.force)
+
+	imp := context.Command("import", "Imports contexts from a bundle created using export").Action(c.importAction)
+	imp.Arg("file", "The bundle to import").Required().ExistingFileVar(&c.input)
+	imp.Arg("names", "Only import specific contexts from the bundle").StringsVar(&c.names)
+	imp.Flag("force", "Overwrite existing contexts without prompting").Short('f').UnNegatableBoolVar(&c.force)
+}
+
+func (c *ctxBundleCmd) password(confirm bool) ([]byte, error) {
+	pass := os.Getenv(ctxBundlePasswordEnv)
+	if pass != "" {
+		return []byte(pass), nil
+	}
+
+	err := askOne(&survey.Password{Message: "Bundle password", Help: fmt.Sprintf("The password used to encrypt the context bundle, can also be set using %s", ctxBundlePasswordEnv)}, &pass, survey.WithValidator(survey.Required))
+	if err != nil {
+		return nil, fmt.Errorf("could not read password: %w", err)
+	}
+
+	if confirm {
+		var again string
+		err = askOne(&survey.Password{Message: "Reenter password", Help: "Enter the same password again"}, &again)
+		if err != nil {
+			return nil, fmt.Errorf("could not read password: %w", err)
+		}
+		if pass != again {
+			return nil, fmt.Errorf("passwords do not match")
+		}
+	}
+
+	return []byte(pass), nil
+}
+
+func ctxBundleKey(password []byte, salt []byte) ([]byte, error) {
+	return scrypt.Key(password, salt, 1<<15, 8, 1, chacha20poly1305.KeySize)
+}
+
+func (c *ctxBundleCmd) seal(bundle *ctxBundle) error {
+	password, err := c.password(true)
+	if err != nil {
+		return err
+	}
+
+	bundle.Salt = make([]byte, 16)
+	_, err = rand.Read(bundle.Salt)
+	if err != nil {
+		return err
+	}
+
+	key, err := ctxBundleKey(password, bundle.Salt)
+	if err != nil {
+		return err
+	}
+
+	aead, err := chacha20poly1305.NewX(key)
+	if err != nil {
+		return err
+	}
+
+	bundle.Nonce = make([]byte, aead.NonceSize())
+	_, err = rand.Read(bundle.Nonce)
+	if err != nil {
+		return err
+	}
+
+	contexts, err := json.Marshal(bundle.Contexts)
+	if err != nil {
+		return err
+	}
+
+	bundle.Data = aead.Seal(nil, bundle.Nonce, contexts, []byte(bundle.Format))
+	bundle.Encrypted = true
+	bundle.Contexts = nil
+
+	return nil
+}
+
+func (c *ctxBundleCmd) open(bundle *ctxBundle) error {
+	password, err := c.password(false)
+	if err != nil {
+		return err
+	}
+
+	key, err := ctxBundleKey(password, bundle.Salt)
+	if err != nil {
+		return err
+	}
+
+	aead, err := chacha20poly1305.NewX(key)
+	if err != nil {
+		return err
+	}
+
+	contexts, err := aead.Open(nil, bundle.Nonce, bundle.Data, []byte(bundle.Format))
+	if err != nil {
+		return fmt.Errorf("could not decrypt bundle, incorrect password or corrupt bundle")
+	}
+
+	return json.Unmarshal(contexts, &bundle.Contexts)
+}
+
+func (c *ctxBundleCmd) exportContext(name string) (*ctxBundleContext, error) {
+	path, err := natscontext.ContextPath(name)
+	if err != nil {
+		return nil, err
+	}
+
+	cj, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read context %s: %w", name, err)
+	}
+
+	exported := &ctxBundleContext{Name: name, Settings: map[string]any{}, Files: map[string][]byte{}}
+	err = json.Unmarshal(cj, &exported.Settings)
+	if err != nil {
+		return nil, fmt.Errorf("invalid context %s: %w", name, err)
+	}
+
+	if !c.embed {
+		return exported, nil
+	}
+
+	for _, key := range ctxBundleFileKeys {
+		file, _ := exported.Settings[key].(string)
+		if file == "" || isSecretReference(file) {
+			continue
+		}
+
+		exported.Files[key], err = os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("could not embed %s for context %s: %w", key, name, err)
+		}
+	}
+
+	return exported, nil
+}
+
+func (c *ctxBundleCmd) exportAction(_ *fisk.ParseContext) error {
+	names := c.names
+	if c.all {
+		names = natscontext.KnownContexts()
+	}
+	if len(names) == 0 {
+		return fmt.Errorf("specify contexts to export or use --all")
+	}
+
+	if !c.force {
+		exist, _ := fileAccessible(c.output)
+		if exist {
+			ok, err := askConfirmation(fmt.Sprintf("Overwrite existing file %s", c.output), false)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				return nil
+			}
+		}
+	}
+
+	bundle := &ctxBundle{Format: ctxBundleFormat, Created: time.Now().UTC()}
+	embedded := 0
+	for _, name := range names {
+		if !natscontext.IsKnown(name) {
+			return fmt.Errorf("unknown context %q", name)
+		}
+
+		exported, err := c.exportContext(name)
+		if err != nil {
+			return err
+		}
+
+		embedded += len(exported.Files)
+		bundle.Contexts = append(bundle.Contexts, exported)
+	}
+
+	if embedded > 0 && !c.encrypt {
+		fmt.Printf("WARNING: the bundle holds %d embedded credentials but is not encrypted\n\n", embedded)
+	}
+
+	if c.encrypt {
+		err := c.seal(bundle)
+		if err != nil {
+			return err
+		}
+	}
+
+	bj, err := json.MarshalIndent(bundle, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	err = os.WriteFile(c.output, bj, 0600)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Exported %d contexts with %d embedded files to %s\n", len(names), embedded, c.output)
+
+	return nil
+}
+
+// importContext saves a context from the bundle, embedded files are stored in a directory alongside the context
+func (c *ctxBundleCmd) importContext(imported *ctxBundleContext) error {
+	path, err := natscontext.ContextPath(imported.Name)
+	if err != nil {
+		return err
+	}
+
+	settings := imported.Settings
+	if settings == nil {
+		settings = map[string]any{}
+	}
+
+	if len(imported.Files) > 0 {
+		dir := filepath.Join(filepath.Dir(path), imported.Name)
+		err = os.MkdirAll(dir, 0700)
+		if err != nil {
+			return err
+		}
+
+		for _, key := range ctxBundleFileKeys {
+			contents, ok := imported.Files[key]
+			if !ok {
+				continue
+			}
+
+			file := filepath.Join(dir, key)
+			if orig, ok := settings[key].(string); ok && filepath.Ext(orig) != "" {
+				file += filepath.Ext(orig)
+			}
+
+			err = os.WriteFile(file, contents, 0600)
+			if err != nil {
+				return err
+			}
+
+			settings[key] = file
+		}
+	}
+
+	cj, err := json.MarshalIndent(settings, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(filepath.Dir(path), 0700)
+	if err != nil {
+		return err
+	}
+
+	err = os.WriteFile(path, cj, 0600)
+	if err != nil {
+		return err
+	}
+
+	_, err = natscontext.New(imported.Name, true)
+	if err != nil {
+		return fmt.Errorf("imported context %s is not valid: %w", imported.Name, err)
+	}
+
+	return nil
+}
+
+func (c *ctxBundleCmd) importAction(_ *fisk.ParseContext) error {
+	bj, err := os.ReadFile(c.input)
+	if err != nil {
+		return err
+	}
+
+	bundle := &ctxBundle{}
+	err = json.Unmarshal(bj, bundle)
+	if err != nil {
+		return fmt.Errorf("invalid context bundle: %w", err)
+	}
+
+	if bundle.Format != ctxBundleFormat {
+		return fmt.Errorf("unsupported context bundle format %q", bundle.Format)
+	}
+
+	if bundle.Encrypted {
+		err = c.open(bundle)
+		if err != nil {
+			return err
+		}
+	}
+
+	wanted := map[string]bool{}
+	for _, name := range c.names {
+		wanted[name] = true
+	}
+
+	sort.Slice(bundle.Contexts, func(i, j int) bool {
+		return bundle.Contexts[i].Name < bundle.Contexts[j].Name
+	})
+
+	imported := 0
+	for _, ctx := range bundle.Contexts {
+		if len(wanted) > 0 && !wanted[ctx.Name] {
+			continue
+		}
+
+		if natscontext.IsKnown(ctx.Name) && !c.force {
+			ok, err := askConfirmation(fmt.Sprintf("Overwrite existing context %s", ctx.Name), false)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				continue
+			}
+		}
+
+		err = c.importContext(ctx)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("Imported context %s\n", ctx.Name)
+		imported++
+	}
+
+	fmt.Printf("\nImported %d contexts from %s\n", imported, c.input)
+
+	return nil
+}
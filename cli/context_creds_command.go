@@ -0,0 +1,265 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"fmt"
+	iofs "io/fs"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/choria-io/fisk"
+	"github.com/nats-io/jsm.go/natscontext"
+)
+
+type ctxCredsCmd struct {
+	dir         string
+	prefix      string
+	server      string
+	description string
+	force       bool
+	prune       bool
+	dryRun      bool
+}
+
+// ctxCredsEntry is a credentials file found in a nsc style creds tree
+type ctxCredsEntry struct {
+	name  string
+	creds string
+}
+
+func configureCtxCredsCommand(context *fisk.CmdClause) {
+	c := &ctxCredsCmd{}
+
+	addFlags := func(cmd *fisk.CmdClause) {
+		cmd.Flag("prefix", "Prefix to add to the names of generated contexts").StringVar(&c.prefix)
+		cmd.Flag("context-server", "The server URL to set in generated contexts, defaults to --server").PlaceHolder("URL").StringVar(&c.server)
+		cmd.Flag("description", "Set a friendly description for generated contexts").StringVar(&c.description)
+		cmd.Flag("dry-run", "Only show which contexts would be changed").UnNegatableBoolVar(&c.dryRun)
+	}
+
+	newCmd := context.Command("new", "Creates contexts for every user in a nsc credentials directory").Action(c.newAction)
+	newCmd.Flag("from-creds-dir", "Directory holding credentials in <operator>/<account>/<user>.creds layout").PlaceHolder("DIR").Required().ExistingDirVar(&c.dir)
+	newCmd.Flag("force", "Overwrite the credentials and server of existing contexts").Short('f').UnNegatableBoolVar(&c.force)
+	addFlags(newCmd)
+
+	sync := context.Command("sync-creds", "Creates, updates and optionally removes contexts to match a nsc credentials directory").Action(c.syncAction)
+	sync.Arg("dir", "Directory holding credentials in <operator>/<account>/<user>.creds layout").Required().ExistingDirVar(&c.dir)
+	sync.Flag("prune", "Remove contexts using credentials from the directory that no longer exist").UnNegatableBoolVar(&c.prune)
+	addFlags(sync)
+}
+
+// findCreds walks the credentials directory, contexts are named after the path of the creds file
+// so ~/.nkeys/creds/O/A/u.creds becomes O_A_u
+func (c *ctxCredsCmd) findCreds() ([]*ctxCredsEntry, error) {
+	dir, err := filepath.Abs(c.dir)
+	if err != nil {
+		return nil, err
+	}
+	c.dir = dir
+
+	var found []*ctxCredsEntry
+	err = filepath.WalkDir(dir, func(path string, d iofs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || filepath.Ext(path) != ".creds" {
+			return nil
+		}
+
+		rel, err := filepath.Rel(dir, strings.TrimSuffix(path, ".creds"))
+		if err != nil {
+			return err
+		}
+
+		name := c.prefix + strings.Join(strings.Split(filepath.ToSlash(rel), "/"), "_")
+		found = append(found, &ctxCredsEntry{name: name, creds: path})
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(found, func(i, j int) bool { return found[i].name < found[j].name })
+
+	return found, nil
+}
+
+// saveCredsContext creates or updates a context keeping all settings other than the server and credentials
+func (c *ctxCredsCmd) saveCredsContext(entry *ctxCredsEntry) error {
+	known := natscontext.IsKnown(entry.name)
+
+	var guard *contextGuard
+	if known {
+		extends, err := contextExtends(entry.name)
+		if err != nil {
+			return err
+		}
+		if extends != "" {
+			return fmt.Errorf("context %q extends %q, saving it would remove the inherited settings", entry.name, extends)
+		}
+
+		guard, err = storedContextGuard(entry.name)
+		if err != nil {
+			return err
+		}
+	}
+
+	server := c.server
+	if server == "" {
+		server = opts.Servers
+	}
+
+	config, err := natscontext.New(entry.name, known,
+		natscontext.WithServerURL(server),
+		natscontext.WithCreds(entry.creds),
+		natscontext.WithDescription(c.description),
+	)
+	if err != nil {
+		return err
+	}
+
+	err = config.Save(entry.name)
+	if err != nil {
+		return err
+	}
+
+	return restoreContextGuard(entry.name, guard)
+}
+
+// managedContexts finds contexts using credentials from within the credentials directory
+func (c *ctxCredsCmd) managedContexts() map[string]string {
+	managed := map[string]string{}
+
+	for _, name := range natscontext.KnownContexts() {
+		path, err := natscontext.ContextPath(name)
+		if err != nil {
+			continue
+		}
+
+		settings, err := readContextSettings(path)
+		if err != nil {
+			continue
+		}
+
+		creds, _ := settings["creds"].(string)
+		if creds != "" && strings.HasPrefix(creds, c.dir+string(filepath.Separator)) {
+			managed[name] = creds
+		}
+	}
+
+	return managed
+}
+
+func (c *ctxCredsCmd) newAction(_ *fisk.ParseContext) error {
+	entries, err := c.findCreds()
+	if err != nil {
+		return err
+	}
+	if len(entries) == 0 {
+		return fmt.Errorf("no credentials found in %s", c.dir)
+	}
+
+	created, skipped := 0, 0
+	for _, entry := range entries {
+		if natscontext.IsKnown(entry.name) && !c.force {
+			fmt.Printf("Skipping existing context %s\n", entry.name)
+			skipped++
+			continue
+		}
+
+		if c.dryRun {
+			fmt.Printf("Would save context %s using %s\n", entry.name, entry.creds)
+			continue
+		}
+
+		err = c.saveCredsContext(entry)
+		if err != nil {
+			return fmt.Errorf("could not save context %s: %w", entry.name, err)
+		}
+
+		fmt.Printf("Saved context %s using %s\n", entry.name, entry.creds)
+		created++
+	}
+
+	if !c.dryRun {
+		fmt.Printf("\nSaved %d contexts, skipped %d existing contexts\n", created, skipped)
+	}
+
+	return nil
+}
+
+func (c *ctxCredsCmd) syncAction(_ *fisk.ParseContext) error {
+	entries, err := c.findCreds()
+	if err != nil {
+		return err
+	}
+
+	managed := c.managedContexts()
+	current := map[string]bool{}
+
+	saved, removed := 0, 0
+	for _, entry := range entries {
+		current[entry.name] = true
+
+		if managed[entry.name] == entry.creds && c.server == "" && c.description == "" {
+			continue
+		}
+
+		if c.dryRun {
+			fmt.Printf("Would save context %s using %s\n", entry.name, entry.creds)
+			continue
+		}
+
+		err = c.saveCredsContext(entry)
+		if err != nil {
+			return fmt.Errorf("could not save context %s: %w", entry.name, err)
+		}
+
+		fmt.Printf("Saved context %s using %s\n", entry.name, entry.creds)
+		saved++
+	}
+
+	var stale []string
+	for name := range managed {
+		if !current[name] {
+			stale = append(stale, name)
+		}
+	}
+	sort.Strings(stale)
+
+	for _, name := range stale {
+		switch {
+		case !c.prune:
+			fmt.Printf("Context %s uses credentials %s that no longer exist, use --prune to remove it\n", name, managed[name])
+		case c.dryRun:
+			fmt.Printf("Would remove context %s\n", name)
+		default:
+			err = natscontext.DeleteContext(name)
+			if err != nil {
+				return fmt.Errorf("could not remove context %s: %w", name, err)
+			}
+			fmt.Printf("Removed context %s\n", name)
+			removed++
+		}
+	}
+
+	if !c.dryRun {
+		fmt.Printf("\nSaved %d contexts, removed %d contexts, %d contexts were up to date\n", saved, removed, len(entries)-saved)
+	}
+
+	return nil
+}
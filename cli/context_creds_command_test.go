@@ -0,0 +1,133 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/nats-io/jsm.go/natscontext"
+)
+
+func writeTestCreds(t *testing.T, dir string, files ...string) {
+	t.Helper()
+
+	for _, f := range files {
+		path := filepath.Join(dir, f)
+		err := os.MkdirAll(filepath.Dir(path), 0700)
+		if err != nil {
+			t.Fatalf("could not create creds directory: %v", err)
+		}
+
+		err = os.WriteFile(path, []byte("creds"), 0600)
+		if err != nil {
+			t.Fatalf("could not write creds: %v", err)
+		}
+	}
+}
+
+func TestCtxCredsFindCreds(t *testing.T) {
+	dir := t.TempDir()
+	writeTestCreds(t, dir, "O/A/u1.creds", "O/A/u2.creds", "O/B/admin.creds", "O/A/notes.txt", "O/A/u1.nk")
+
+	c := &ctxCredsCmd{dir: dir, prefix: "dev_"}
+	found, err := c.findCreds()
+	if err != nil {
+		t.Fatalf("find failed: %v", err)
+	}
+
+	var names []string
+	for _, entry := range found {
+		names = append(names, entry.name)
+	}
+
+	expect := []string{"dev_O_A_u1", "dev_O_A_u2", "dev_O_B_admin"}
+	if !reflect.DeepEqual(names, expect) {
+		t.Fatalf("expected %v got %v", expect, names)
+	}
+
+	if found[2].creds != filepath.Join(dir, "O", "B", "admin.creds") {
+		t.Fatalf("invalid creds path %q", found[2].creds)
+	}
+}
+
+func TestCtxCredsSync(t *testing.T) {
+	cfg := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", cfg)
+
+	err := os.MkdirAll(filepath.Join(cfg, "nats", "context"), 0700)
+	if err != nil {
+		t.Fatalf("could not create context directory: %v", err)
+	}
+	writeTestContext(t, cfg, "other", `{"url":"nats://other:4222","creds":"/elsewhere/u.creds"}`)
+
+	defer func(o *Options) { opts = o }(opts)
+	opts = &Options{Servers: "nats://localhost:4222"}
+
+	dir := t.TempDir()
+	writeTestCreds(t, dir, "O/A/u1.creds", "O/A/u2.creds")
+
+	c := &ctxCredsCmd{dir: dir}
+	err = c.syncAction(nil)
+	if err != nil {
+		t.Fatalf("sync failed: %v", err)
+	}
+
+	managed := c.managedContexts()
+	expect := map[string]string{
+		"O_A_u1": filepath.Join(c.dir, "O", "A", "u1.creds"),
+		"O_A_u2": filepath.Join(c.dir, "O", "A", "u2.creds"),
+	}
+	if !reflect.DeepEqual(managed, expect) {
+		t.Fatalf("expected %v got %v", expect, managed)
+	}
+
+	err = os.Remove(filepath.Join(dir, "O", "A", "u2.creds"))
+	if err != nil {
+		t.Fatalf("could not remove creds: %v", err)
+	}
+
+	// without --prune stale contexts are only reported
+	err = c.syncAction(nil)
+	if err != nil {
+		t.Fatalf("sync failed: %v", err)
+	}
+	if !natscontext.IsKnown("O_A_u2") {
+		t.Fatalf("expected stale context to be kept without prune")
+	}
+
+	c.prune = true
+	c.dryRun = true
+	err = c.syncAction(nil)
+	if err != nil {
+		t.Fatalf("sync failed: %v", err)
+	}
+	if !natscontext.IsKnown("O_A_u2") {
+		t.Fatalf("expected stale context to be kept during a dry run")
+	}
+
+	c.dryRun = false
+	err = c.syncAction(nil)
+	if err != nil {
+		t.Fatalf("sync failed: %v", err)
+	}
+	if natscontext.IsKnown("O_A_u2") {
+		t.Fatalf("expected stale context to be pruned")
+	}
+	if !natscontext.IsKnown("O_A_u1") || !natscontext.IsKnown("other") {
+		t.Fatalf("expected current and unmanaged contexts to be kept")
+	}
+}
@@ -0,0 +1,170 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/choria-io/fisk"
+	"github.com/nats-io/jsm.go/natscontext"
+)
+
+const (
+	// contextProtectConfirm requires typing the context name before running commands that make changes
+	contextProtectConfirm = "confirm"
+	// contextConfirmEnv confirms running commands that make changes against the protected context it names without prompting
+	contextConfirmEnv = "NATS_CONFIRM_CONTEXT"
+)
+
+// contextGuard holds the guard-rail settings of a context that limit which commands can be run against it,
+// like other context settings they can be inherited from contexts being extended
+type contextGuard struct {
+	ReadOnly  bool   `json:"readonly,omitempty"`
+	Protected string `json:"protected,omitempty"`
+}
+
+func parseContextGuard(settings map[string]any) (*contextGuard, error) {
+	guard := &contextGuard{}
+	guard.ReadOnly, _ = settings["readonly"].(bool)
+	guard.Protected, _ = settings["protected"].(string)
+
+	if guard.Protected != "" && guard.Protected != contextProtectConfirm {
+		return nil, fmt.Errorf("invalid protected setting %q, only %q is supported", guard.Protected, contextProtectConfirm)
+	}
+
+	return guard, nil
+}
+
+// loadContextGuard loads the guard settings of a context name or file, the selected context is used when name is empty
+func loadContextGuard(name string) (string, *contextGuard, error) {
+	path := name
+
+	if exist, _ := fileAccessible(name); !exist || !strings.HasSuffix(name, ".json") {
+		if name == "" {
+			name = natscontext.SelectedContext()
+		}
+
+		if name == "" || !natscontext.IsKnown(name) {
+			return name, &contextGuard{}, nil
+		}
+
+		var err error
+		path, err = natscontext.ContextPath(name)
+		if err != nil {
+			return name, nil, err
+		}
+	}
+
+	settings, err := resolveContextSettings(path, nil)
+	if err != nil {
+		return name, nil, err
+	}
+
+	guard, err := parseContextGuard(settings)
+
+	return name, guard, err
+}
+
+// checkContextGuard refuses or confirms commands that make changes against read-only or protected contexts
+func checkContextGuard(pc *fisk.ParseContext) error {
+	if SkipContexts || pc == nil || pc.SelectedCommand == nil {
+		return nil
+	}
+
+	cmd := pc.SelectedCommand.FullCommand()
+	if !isMutatingCommand(cmd) {
+		return nil
+	}
+
+	name, guard, err := loadContextGuard(opts.CfgCtx)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case guard.ReadOnly:
+		return withExitCode(ExitValidation, fmt.Errorf("context %q is read-only, %q makes changes", name, cmd))
+
+	case guard.Protected == contextProtectConfirm:
+		if os.Getenv(contextConfirmEnv) == name {
+			return nil
+		}
+
+		if !isTerminal() {
+			return withExitCode(ExitValidation, fmt.Errorf("context %q is protected, set %s=%s to run %q without a terminal", name, contextConfirmEnv, name, cmd))
+		}
+
+		var answer string
+		err = askOne(&survey.Input{Message: fmt.Sprintf("Context %q is protected, type its name to run %q", name, cmd)}, &answer)
+		if err != nil {
+			return err
+		}
+
+		if strings.TrimSpace(answer) != name {
+			return withExitCode(ExitValidation, fmt.Errorf("confirmation did not match context %q, not running %q", name, cmd))
+		}
+	}
+
+	return nil
+}
+
+// restoreContextGuard stores guard settings in a context file, used after natscontext saved a context as it does not retain them
+func restoreContextGuard(name string, guard *contextGuard) error {
+	if guard == nil || (!guard.ReadOnly && guard.Protected == "") {
+		return nil
+	}
+
+	path, err := natscontext.ContextPath(name)
+	if err != nil {
+		return err
+	}
+
+	settings, err := readContextSettings(path)
+	if err != nil {
+		return err
+	}
+
+	if guard.ReadOnly {
+		settings["readonly"] = true
+	}
+	if guard.Protected != "" {
+		settings["protected"] = guard.Protected
+	}
+
+	j, err := json.MarshalIndent(settings, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, j, 0600)
+}
+
+// storedContextGuard is the guard settings stored in a context file without those inherited from other contexts
+func storedContextGuard(name string) (*contextGuard, error) {
+	path, err := natscontext.ContextPath(name)
+	if err != nil {
+		return nil, err
+	}
+
+	settings, err := readContextSettings(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseContextGuard(settings)
+}
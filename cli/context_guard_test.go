@@ -0,0 +1,111 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadContextGuard(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", dir)
+
+	err := os.MkdirAll(filepath.Join(dir, "nats", "context"), 0700)
+	if err != nil {
+		t.Fatalf("could not create context directory: %v", err)
+	}
+
+	writeTestContext(t, dir, "prod", `{"url":"nats://prod:4222","readonly":true}`)
+	writeTestContext(t, dir, "prod-admin", `{"extend":"prod","readonly":false,"protected":"confirm"}`)
+	writeTestContext(t, dir, "invalid", `{"protected":"always"}`)
+
+	_, guard, err := loadContextGuard("prod")
+	if err != nil || !guard.ReadOnly || guard.Protected != "" {
+		t.Fatalf("unexpected guard for prod: %+v: %v", guard, err)
+	}
+
+	_, guard, err = loadContextGuard("prod-admin")
+	if err != nil || guard.ReadOnly || guard.Protected != contextProtectConfirm {
+		t.Fatalf("unexpected guard for prod-admin: %+v: %v", guard, err)
+	}
+
+	_, guard, err = loadContextGuard("unknown")
+	if err != nil || guard.ReadOnly || guard.Protected != "" {
+		t.Fatalf("unexpected guard for unknown context: %+v: %v", guard, err)
+	}
+
+	_, _, err = loadContextGuard("invalid")
+	if err == nil {
+		t.Fatalf("expected invalid protected setting to fail")
+	}
+
+	writeTestContext(t, dir, "prod-admin", `{"extend":"prod"}`)
+
+	err = restoreContextGuard("prod-admin", &contextGuard{ReadOnly: true})
+	if err != nil {
+		t.Fatalf("could not restore guard: %v", err)
+	}
+
+	guard, err = storedContextGuard("prod-admin")
+	if err != nil || !guard.ReadOnly || guard.Protected != "" {
+		t.Fatalf("unexpected stored guard for prod-admin: %+v: %v", guard, err)
+	}
+}
+
+func TestCheckContextGuard(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", dir)
+
+	err := os.MkdirAll(filepath.Join(dir, "nats", "context"), 0700)
+	if err != nil {
+		t.Fatalf("could not create context directory: %v", err)
+	}
+
+	writeTestContext(t, dir, "prod", `{"url":"nats://prod:4222","readonly":true}`)
+
+	app := newTestApp(t)
+	opts.CfgCtx = "prod"
+
+	for _, args := range [][]string{
+		{"pub", "orders.new", "body"},
+		{"request", "orders.get", "body"},
+		{"server", "connection", "kick", "10"},
+		{"schedule", "run"},
+		{"kv", "lock", "LOCKS", "job", "--", "true"},
+		{"election", "run", "job", "--", "true"},
+		{"consumer", "next", "ORDERS", "NEW"},
+		{"stream", "rm", "ORDERS"},
+	} {
+		pc, err := app.ParseContext(args)
+		if err != nil {
+			t.Fatalf("could not parse %v: %v", args, err)
+		}
+
+		if checkContextGuard(pc) == nil {
+			t.Fatalf("expected %v to be refused on a read-only context", args)
+		}
+	}
+
+	pc, err := app.ParseContext([]string{"stream", "info", "ORDERS"})
+	if err != nil {
+		t.Fatalf("could not parse: %v", err)
+	}
+
+	err = checkContextGuard(pc)
+	if err != nil {
+		t.Fatalf("expected stream info to be allowed on a read-only context: %v", err)
+	}
+}
@@ -0,0 +1,282 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/nats-io/jsm.go/natscontext"
+)
+
+// Contexts can set extend to the name of another context whose settings they inherit, settings set in the
+// extending context take precedence. String settings can refer to environment variables using ${VAR} or
+// ${VAR:-default}, these are resolved after merging when the context is loaded.
+const contextExtendKey = "extend"
+
+var contextEnvPattern = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)(:-([^}]*))?\}`)
+
+// loadResolvedContext loads a context like natscontext.New while resolving inheritance and environment variables,
+// the selected context is loaded when name is empty
+func loadResolvedContext(name string, ctxOpts ...natscontext.Option) (*natscontext.Context, error) {
+	if name == "" {
+		name = natscontext.SelectedContext()
+	}
+
+	if name == "" || !natscontext.IsKnown(name) {
+		return natscontext.New(name, true, ctxOpts...)
+	}
+
+	path, err := natscontext.ContextPath(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return loadResolvedContextFile(path, ctxOpts...)
+}
+
+// resolvedContext holds the settings of a resolved context using the names natscontext stores them as
+type resolvedContext struct {
+	Description   string `json:"description"`
+	URL           string `json:"url"`
+	SocksProxy    string `json:"socks_proxy"`
+	Token         string `json:"token"`
+	User          string `json:"user"`
+	Password      string `json:"password"`
+	Creds         string `json:"creds"`
+	NKey          string `json:"nkey"`
+	Cert          string `json:"cert"`
+	Key           string `json:"key"`
+	CA            string `json:"ca"`
+	NSCLookup     string `json:"nsc"`
+	JSDomain      string `json:"jetstream_domain"`
+	JSAPIPrefix   string `json:"jetstream_api_prefix"`
+	JSEventPrefix string `json:"jetstream_event_prefix"`
+	InboxPrefix   string `json:"inbox_prefix"`
+	UserJwt       string `json:"user_jwt"`
+	ColorScheme   string `json:"color_scheme"`
+}
+
+// loadResolvedContextFile loads a context like natscontext.NewFromFile while resolving inheritance and environment variables,
+// resolved contexts are built in memory so expanded secrets are never written to disk
+func loadResolvedContextFile(path string, ctxOpts ...natscontext.Option) (*natscontext.Context, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil || !contextNeedsResolving(raw) {
+		return natscontext.NewFromFile(path, ctxOpts...)
+	}
+
+	settings, err := resolveContextSettings(path, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	j, err := json.Marshal(settings)
+	if err != nil {
+		return nil, err
+	}
+
+	var resolved resolvedContext
+	err = json.Unmarshal(j, &resolved)
+	if err != nil {
+		return nil, fmt.Errorf("invalid context %s: %w", path, err)
+	}
+
+	// like natscontext credentials and servers found using nsc apply only when not set in the context
+	resolvedOpts, err := nscContextOptions(resolved.NSCLookup)
+	if err != nil {
+		return nil, err
+	}
+
+	resolvedOpts = append(resolvedOpts,
+		natscontext.WithDescription(resolved.Description),
+		natscontext.WithServerURL(resolved.URL),
+		natscontext.WithSocksProxy(resolved.SocksProxy),
+		natscontext.WithToken(resolved.Token),
+		natscontext.WithUser(resolved.User),
+		natscontext.WithPassword(resolved.Password),
+		natscontext.WithCreds(resolved.Creds),
+		natscontext.WithNKey(resolved.NKey),
+		natscontext.WithCertificate(resolved.Cert),
+		natscontext.WithKey(resolved.Key),
+		natscontext.WithCA(resolved.CA),
+		natscontext.WithNscUrl(resolved.NSCLookup),
+		natscontext.WithJSDomain(resolved.JSDomain),
+		natscontext.WithJSAPIPrefix(resolved.JSAPIPrefix),
+		natscontext.WithJSEventPrefix(resolved.JSEventPrefix),
+		natscontext.WithInboxPrefix(resolved.InboxPrefix),
+		natscontext.WithUserJWT(resolved.UserJwt),
+		natscontext.WithColorScheme(resolved.ColorScheme),
+	)
+
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+
+	return natscontext.New(name, false, append(resolvedOpts, ctxOpts...)...)
+}
+
+// nscContextOptions looks up the credentials and servers of a nsc://<operator>/<account>/<user> url the same way natscontext does
+func nscContextOptions(lookup string) ([]natscontext.Option, error) {
+	if lookup == "" {
+		return nil, nil
+	}
+
+	path, err := exec.LookPath("nsc")
+	if err != nil {
+		return nil, fmt.Errorf("cannot find 'nsc' in user path")
+	}
+
+	out, err := exec.Command(path, "generate", "profile", lookup).CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("nsc invoke failed: %s", string(out))
+	}
+
+	var profile struct {
+		UserCreds string `json:"user_creds"`
+		Operator  struct {
+			Service []string `json:"service"`
+		} `json:"operator"`
+	}
+
+	err = json.Unmarshal(out, &profile)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse nsc output: %s", err)
+	}
+
+	return []natscontext.Option{
+		natscontext.WithCreds(profile.UserCreds),
+		natscontext.WithServerURL(strings.Join(profile.Operator.Service, ",")),
+	}, nil
+}
+
+func contextNeedsResolving(raw []byte) bool {
+	return bytes.Contains(raw, []byte(`"`+contextExtendKey+`"`)) || bytes.Contains(raw, []byte("${"))
+}
+
+// contextExtends is the name of the context the named context extends, empty when it does not extend another
+func contextExtends(name string) (string, error) {
+	path, err := natscontext.ContextPath(name)
+	if err != nil {
+		return "", err
+	}
+
+	settings, err := readContextSettings(path)
+	if err != nil {
+		return "", err
+	}
+
+	base, _ := settings[contextExtendKey].(string)
+
+	return base, nil
+}
+
+func readContextSettings(path string) (map[string]any, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	settings := map[string]any{}
+	err = json.Unmarshal(raw, &settings)
+	if err != nil {
+		return nil, fmt.Errorf("invalid context %s: %w", path, err)
+	}
+
+	return settings, nil
+}
+
+// resolveContextSettings reads the settings in path merged over those of the contexts it extends and resolves
+// environment variables, seen holds the names of extending contexts to detect loops
+func resolveContextSettings(path string, seen []string) (map[string]any, error) {
+	settings, err := readContextSettings(path)
+	if err != nil {
+		return nil, err
+	}
+
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	base, _ := settings[contextExtendKey].(string)
+	delete(settings, contextExtendKey)
+
+	if base != "" {
+		for _, s := range append(seen, name) {
+			if s == base {
+				return nil, fmt.Errorf("context %s extends %s creating a loop", name, base)
+			}
+		}
+
+		if !natscontext.IsKnown(base) {
+			return nil, fmt.Errorf("context %s extends unknown context %q", name, base)
+		}
+
+		bpath, err := natscontext.ContextPath(base)
+		if err != nil {
+			return nil, err
+		}
+
+		merged, err := resolveContextSettings(bpath, append(seen, name))
+		if err != nil {
+			return nil, err
+		}
+
+		for k, v := range settings {
+			if s, ok := v.(string); ok && s == "" {
+				continue
+			}
+			merged[k] = v
+		}
+
+		settings = merged
+	}
+
+	for k, v := range settings {
+		s, ok := v.(string)
+		if !ok {
+			continue
+		}
+
+		settings[k], err = expandContextValue(s)
+		if err != nil {
+			return nil, fmt.Errorf("context %s setting %s: %w", name, k, err)
+		}
+	}
+
+	return settings, nil
+}
+
+// expandContextValue replaces ${VAR} and ${VAR:-default} references with values from the environment
+func expandContextValue(v string) (string, error) {
+	var err error
+
+	res := contextEnvPattern.ReplaceAllStringFunc(v, func(ref string) string {
+		m := contextEnvPattern.FindStringSubmatch(ref)
+
+		val, ok := os.LookupEnv(m[1])
+		switch {
+		case ok && val != "":
+			return val
+		case m[2] != "":
+			return m[3]
+		case !ok && err == nil:
+			err = fmt.Errorf("environment variable %s is not set", m[1])
+		}
+
+		return val
+	})
+
+	return res, err
+}
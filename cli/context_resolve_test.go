@@ -0,0 +1,103 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeTestContext(t *testing.T, dir string, name string, content string) {
+	t.Helper()
+
+	err := os.WriteFile(filepath.Join(dir, "nats", "context", name+".json"), []byte(content), 0600)
+	if err != nil {
+		t.Fatalf("could not write context: %v", err)
+	}
+}
+
+func TestExpandContextValue(t *testing.T) {
+	t.Setenv("CTX_TEST_SET", "value")
+	t.Setenv("CTX_TEST_EMPTY", "")
+
+	for _, tc := range []struct {
+		in     string
+		expect string
+		err    bool
+	}{
+		{"nats://${CTX_TEST_SET}:4222", "nats://value:4222", false},
+		{"${CTX_TEST_UNSET:-default}", "default", false},
+		{"${CTX_TEST_EMPTY:-default}", "default", false},
+		{"${CTX_TEST_EMPTY}", "", false},
+		{"$CTX_TEST_SET", "$CTX_TEST_SET", false},
+		{"${CTX_TEST_UNSET}", "", true},
+	} {
+		res, err := expandContextValue(tc.in)
+		if tc.err != (err != nil) {
+			t.Fatalf("expandContextValue(%q) returned error %v", tc.in, err)
+		}
+		if res != tc.expect {
+			t.Fatalf("expandContextValue(%q) returned %q expected %q", tc.in, res, tc.expect)
+		}
+	}
+}
+
+func TestLoadResolvedContext(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", dir)
+	t.Setenv("CTX_TEST_CREDS", "/ci/user.creds")
+	t.Setenv("CTX_TEST_PASSWORD", "s3cret")
+
+	tmp := t.TempDir()
+	t.Setenv("TMPDIR", tmp)
+
+	err := os.MkdirAll(filepath.Join(dir, "nats", "context"), 0700)
+	if err != nil {
+		t.Fatalf("could not create context directory: %v", err)
+	}
+
+	writeTestContext(t, dir, "base", `{"url":"nats://base:4222","description":"base","jetstream_domain":"hub"}`)
+	writeTestContext(t, dir, "ci", `{"extend":"base","description":"","creds":"${CTX_TEST_CREDS}","password":"${CTX_TEST_PASSWORD}","jetstream_domain":"leaf"}`)
+	writeTestContext(t, dir, "loop", `{"extend":"loop"}`)
+
+	cfg, err := loadResolvedContext("ci")
+	if err != nil {
+		t.Fatalf("could not load context: %v", err)
+	}
+
+	if cfg.Name != "ci" || cfg.ServerURL() != "nats://base:4222" || cfg.Description() != "base" || cfg.Creds() != "/ci/user.creds" || cfg.JSDomain() != "leaf" {
+		t.Fatalf("unexpected resolved context: %s %s %s %s %s", cfg.Name, cfg.ServerURL(), cfg.Description(), cfg.Creds(), cfg.JSDomain())
+	}
+
+	if cfg.Password() != "s3cret" {
+		t.Fatalf("expected the password to be expanded, got %q", cfg.Password())
+	}
+
+	// expanded secrets must never be written to disk
+	written, err := os.ReadDir(tmp)
+	if err != nil || len(written) > 0 {
+		t.Fatalf("expected no temporary files, found %d: %v", len(written), err)
+	}
+
+	extends, err := contextExtends("ci")
+	if err != nil || extends != "base" {
+		t.Fatalf("expected ci to extend base, got %q: %v", extends, err)
+	}
+
+	_, err = loadResolvedContext("loop")
+	if err == nil {
+		t.Fatalf("expected extending loop to fail")
+	}
+}
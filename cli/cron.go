@@ -0,0 +1,191 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cronSchedule is a parsed schedule in the standard 5 field cron format or an @every interval
+type cronSchedule struct {
+	minute uint64
+	hour   uint64
+	dom    uint64
+	month  uint64
+	dow    uint64
+
+	// restricted day fields, when both are restricted either has to match
+	domRestricted bool
+	dowRestricted bool
+
+	every time.Duration
+}
+
+var cronAliases = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// parseCronSpec parses a schedule like */5 * * * *, an alias like @hourly or an interval like @every 10s
+func parseCronSpec(spec string) (*cronSchedule, error) {
+	spec = strings.TrimSpace(spec)
+
+	if strings.HasPrefix(spec, "@every ") {
+		every, err := parseDurationString(strings.TrimSpace(strings.TrimPrefix(spec, "@every ")))
+		if err != nil {
+			return nil, fmt.Errorf("invalid interval in %q: %w", spec, err)
+		}
+		if every < time.Second {
+			return nil, fmt.Errorf("interval in %q must be at least 1s", spec)
+		}
+
+		return &cronSchedule{every: every}, nil
+	}
+
+	if alias, ok := cronAliases[spec]; ok {
+		spec = alias
+	}
+
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("invalid schedule %q: expected 5 fields", spec)
+	}
+
+	var err error
+	s := &cronSchedule{
+		domRestricted: fields[2] != "*",
+		dowRestricted: fields[4] != "*",
+	}
+
+	for i, f := range []struct {
+		bits *uint64
+		min  int
+		max  int
+	}{
+		{&s.minute, 0, 59},
+		{&s.hour, 0, 23},
+		{&s.dom, 1, 31},
+		{&s.month, 1, 12},
+		{&s.dow, 0, 7},
+	} {
+		*f.bits, err = parseCronField(fields[i], f.min, f.max)
+		if err != nil {
+			return nil, fmt.Errorf("invalid schedule %q: %w", spec, err)
+		}
+	}
+
+	// 7 is an alternative for sunday
+	if s.dow&(1<<7) > 0 {
+		s.dow |= 1
+	}
+
+	return s, nil
+}
+
+// parseCronField parses a comma separated list of values, ranges and steps like 1,5-10,*/15
+func parseCronField(field string, min int, max int) (uint64, error) {
+	var bits uint64
+
+	for _, part := range strings.Split(field, ",") {
+		rng, stepStr, hasStep := strings.Cut(part, "/")
+
+		step := 1
+		if hasStep {
+			var err error
+			step, err = strconv.Atoi(stepStr)
+			if err != nil || step < 1 {
+				return 0, fmt.Errorf("invalid step in %q", part)
+			}
+		}
+
+		start, end := min, max
+		switch {
+		case rng == "*":
+		case strings.Contains(rng, "-"):
+			from, to, _ := strings.Cut(rng, "-")
+			var err1, err2 error
+			start, err1 = strconv.Atoi(from)
+			end, err2 = strconv.Atoi(to)
+			if err1 != nil || err2 != nil {
+				return 0, fmt.Errorf("invalid range %q", part)
+			}
+		default:
+			v, err := strconv.Atoi(rng)
+			if err != nil {
+				return 0, fmt.Errorf("invalid value %q", part)
+			}
+			start = v
+			end = v
+			if hasStep {
+				end = max
+			}
+		}
+
+		if start < min || end > max || start > end {
+			return 0, fmt.Errorf("%q is outside the range %d-%d", part, min, max)
+		}
+
+		for i := start; i <= end; i += step {
+			bits |= 1 << uint(i)
+		}
+	}
+
+	return bits, nil
+}
+
+func (s *cronSchedule) dayMatches(t time.Time) bool {
+	dom := s.dom&(1<<uint(t.Day())) > 0
+	dow := s.dow&(1<<uint(t.Weekday())) > 0
+
+	if s.domRestricted && s.dowRestricted {
+		return dom || dow
+	}
+
+	return dom && dow
+}
+
+// next finds the first time after t matching the schedule, a zero time is returned when nothing matches within 5 years
+func (s *cronSchedule) next(t time.Time) time.Time {
+	if s.every > 0 {
+		return t.Add(s.every)
+	}
+
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+
+	for t.Before(limit) {
+		switch {
+		case s.month&(1<<uint(t.Month())) == 0:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+		case !s.dayMatches(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+		case s.hour&(1<<uint(t.Hour())) == 0:
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+		case s.minute&(1<<uint(t.Minute())) == 0:
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+
+	return time.Time{}
+}
@@ -0,0 +1,222 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/choria-io/fisk"
+	"github.com/ghodss/yaml"
+	"github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats.go"
+)
+
+type cronCmd struct {
+	spec      string
+	subject   string
+	payload   string
+	hdrs      []string
+	file      string
+	jetStream bool
+	list      bool
+
+	nc *nats.Conn
+	js nats.JetStreamContext
+}
+
+// cronEntry is a scheduled publish, entries are given on the command line or in a YAML file holding a list of them
+type cronEntry struct {
+	Name     string        `json:"name"`
+	Spec     string        `json:"spec"`
+	Subject  string        `json:"subject"`
+	Payload  string        `json:"payload"`
+	Headers  []string      `json:"headers"`
+	Schedule *cronSchedule `json:"-"`
+	Next     time.Time     `json:"-"`
+	Count    int           `json:"-"`
+}
+
+func configureCronCommand(app commandHost) {
+	c := &cronCmd{}
+
+	help := `Publishes messages on a schedule
+
+Schedules use the standard 5 field cron format of minute, hour, day of
+month, month and day of week like */5 * * * *, aliases like @hourly and
+@daily or intervals like @every 30s. Times are in the local time zone.
+
+Payloads and headers may use the same templates as nats pub, like
+{{ TimeStamp }} and {{ Count }}.
+
+Many schedules can be given in a YAML file holding a list of entries
+with name, spec, subject, payload and headers keys.
+`
+
+	cron := publishing(app.Command("cron", help).Action(c.cronAction))
+	cron.Flag("spec", "The schedule to publish on").PlaceHolder("SCHEDULE").StringVar(&c.spec)
+	cron.Flag("subject", "The subject to publish to").StringVar(&c.subject)
+	cron.Flag("payload-template", "The payload to publish").PlaceHolder("TEMPLATE").StringVar(&c.payload)
+	cron.Flag("header", "Adds headers to the message").Short('H').StringsVar(&c.hdrs)
+	cron.Flag("file", "YAML file holding a list of schedules").PlaceHolder("FILE").ExistingFileVar(&c.file)
+	cron.Flag("jetstream", "Publish using JetStream and wait for acknowledgements").UnNegatableBoolVar(&c.jetStream)
+	cron.Flag("list", "List the schedules and their next run times without publishing").UnNegatableBoolVar(&c.list)
+	addCheat("cron", cron)
+}
+
+func init() {
+	registerCommand("cron", 5, configureCronCommand)
+}
+
+func (c *cronCmd) loadEntries() ([]*cronEntry, error) {
+	var entries []*cronEntry
+
+	if c.file != "" {
+		body, err := os.ReadFile(c.file)
+		if err != nil {
+			return nil, err
+		}
+
+		j, err := yaml.YAMLToJSON(body)
+		if err != nil {
+			return nil, fmt.Errorf("invalid schedules file %s: %w", c.file, err)
+		}
+
+		err = json.Unmarshal(j, &entries)
+		if err != nil {
+			return nil, fmt.Errorf("invalid schedules file %s: %w", c.file, err)
+		}
+	}
+
+	if c.spec != "" || c.subject != "" {
+		entries = append(entries, &cronEntry{Spec: c.spec, Subject: c.subject, Payload: c.payload, Headers: c.hdrs})
+	}
+
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("no schedules given, use --spec and --subject or --file")
+	}
+
+	now := time.Now()
+	for i, e := range entries {
+		if e.Name == "" {
+			e.Name = fmt.Sprintf("%d", i+1)
+		}
+
+		if !server.IsValidLiteralSubject(e.Subject) {
+			return nil, fmt.Errorf("schedule %s: invalid subject %q", e.Name, e.Subject)
+		}
+
+		var err error
+		e.Schedule, err = parseCronSpec(e.Spec)
+		if err != nil {
+			return nil, fmt.Errorf("schedule %s: %w", e.Name, err)
+		}
+
+		e.Next = e.Schedule.next(now)
+		if e.Next.IsZero() {
+			return nil, fmt.Errorf("schedule %s: %q never runs", e.Name, e.Spec)
+		}
+	}
+
+	return entries, nil
+}
+
+func (c *cronCmd) publish(e *cronEntry) error {
+	e.Count++
+
+	body, err := pubReplyBodyTemplate(e.Payload, "", e.Count)
+	if err != nil {
+		return fmt.Errorf("could not parse payload template: %w", err)
+	}
+
+	msg := nats.NewMsg(e.Subject)
+	msg.Data = body
+	err = parseStringsToMsgHeader(e.Headers, e.Count, msg)
+	if err != nil {
+		return err
+	}
+
+	if c.jetStream {
+		_, err = c.js.PublishMsg(msg)
+		return err
+	}
+
+	err = c.nc.PublishMsg(msg)
+	if err != nil {
+		return err
+	}
+
+	return c.nc.FlushTimeout(opts.Timeout)
+}
+
+func (c *cronCmd) cronAction(_ *fisk.ParseContext) error {
+	entries, err := c.loadEntries()
+	if err != nil {
+		return withExitCode(ExitValidation, err)
+	}
+
+	if c.list {
+		table := newTableWriter("Schedules")
+		table.AddHeaders("Name", "Schedule", "Subject", "Next Run")
+		for _, e := range entries {
+			table.AddRow(e.Name, e.Spec, e.Subject, e.Next.Format(time.RFC1123))
+		}
+		fmt.Println(table.Render())
+
+		return nil
+	}
+
+	c.nc, err = newNatsConn("", natsOpts()...)
+	if err != nil {
+		return err
+	}
+	defer c.nc.Close()
+
+	if c.jetStream {
+		_, c.js, err = prepareJSHelper()
+		if err != nil {
+			return err
+		}
+	}
+
+	log.Printf("Publishing %d schedules", len(entries))
+
+	for {
+		next := entries[0]
+		for _, e := range entries[1:] {
+			if e.Next.Before(next.Next) {
+				next = e
+			}
+		}
+
+		timer := time.NewTimer(time.Until(next.Next))
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return nil
+		}
+
+		err = c.publish(next)
+		if err != nil {
+			log.Printf("Schedule %s: publishing to %s failed: %v", next.Name, next.Subject, err)
+		} else if opts.Trace {
+			log.Printf("Schedule %s: published message %d to %s", next.Name, next.Count, next.Subject)
+		}
+
+		next.Next = next.Schedule.next(time.Now())
+	}
+}
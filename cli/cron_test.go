@@ -0,0 +1,59 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCronSchedule(t *testing.T) {
+	// a wednesday
+	start := time.Date(2023, 5, 10, 10, 2, 30, 0, time.UTC)
+
+	for spec, expect := range map[string]time.Time{
+		"*/5 * * * *":     time.Date(2023, 5, 10, 10, 5, 0, 0, time.UTC),
+		"* * * * *":       time.Date(2023, 5, 10, 10, 3, 0, 0, time.UTC),
+		"0 9 * * *":       time.Date(2023, 5, 11, 9, 0, 0, 0, time.UTC),
+		"30 8-17/2 * * *": time.Date(2023, 5, 10, 10, 30, 0, 0, time.UTC),
+		"0 0 * * 0":       time.Date(2023, 5, 14, 0, 0, 0, 0, time.UTC),
+		"0 0 * * 7":       time.Date(2023, 5, 14, 0, 0, 0, 0, time.UTC),
+		"0 0 1,15 * *":    time.Date(2023, 5, 15, 0, 0, 0, 0, time.UTC),
+		"0 0 1 * 5":       time.Date(2023, 5, 12, 0, 0, 0, 0, time.UTC),
+		"0 0 29 2 *":      time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC),
+		"@hourly":         time.Date(2023, 5, 10, 11, 0, 0, 0, time.UTC),
+		"@every 10s":      time.Date(2023, 5, 10, 10, 2, 40, 0, time.UTC),
+	} {
+		s, err := parseCronSpec(spec)
+		checkErr(t, err, "parse %q failed", spec)
+
+		next := s.next(start)
+		if !next.Equal(expect) {
+			t.Fatalf("expected %q to run at %v got %v", spec, expect, next)
+		}
+	}
+
+	for _, spec := range []string{"", "* * * *", "60 * * * *", "* 24 * * *", "0 0 0 * *", "*/0 * * * *", "5-1 * * * *", "x * * * *", "@every 10ms"} {
+		_, err := parseCronSpec(spec)
+		if err == nil {
+			t.Fatalf("expected %q to fail", spec)
+		}
+	}
+
+	s, err := parseCronSpec("0 0 31 2 *")
+	checkErr(t, err, "parse failed")
+	if !s.next(start).IsZero() {
+		t.Fatalf("expected impossible schedule to never run")
+	}
+}
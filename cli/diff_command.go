@@ -0,0 +1,74 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"fmt"
+
+	"github.com/choria-io/fisk"
+)
+
+type diffCmd struct {
+	files []string
+	prune bool
+}
+
+func configureDiffCommand(app commandHost) {
+	c := &diffCmd{}
+
+	help := `Shows differences between JetStream assets and their manifests
+
+Manifests are in the format used by nats apply, nothing is changed.
+Exits with code 5 when any asset differs from its manifest.
+`
+
+	diff := app.Command("diff", help).Action(c.diffAction)
+	diff.Flag("file", "Manifest file or directory of manifests to compare").Short('f').Required().PlaceHolder("PATH").ExistingFilesOrDirsVar(&c.files)
+	diff.Flag("prune", "Also reports assets of the kinds found in the manifests that are not described in them").UnNegatableBoolVar(&c.prune)
+	addCheat("diff", diff)
+}
+
+func init() {
+	registerCommand("diff", 6, configureDiffCommand)
+}
+
+func (c *diffCmd) diffAction(_ *fisk.ParseContext) error {
+	manifests, err := loadManifests(c.files)
+	if err != nil {
+		return withExitCode(ExitValidation, err)
+	}
+
+	if len(manifests) == 0 {
+		return withExitCode(ExitValidation, fmt.Errorf("no manifests found"))
+	}
+
+	_, mgr, err := prepareHelper("", natsOpts()...)
+	if err != nil {
+		return err
+	}
+
+	changes, err := planManifests(mgr, manifests, c.prune)
+	if err != nil {
+		return err
+	}
+
+	if len(changes) == 0 {
+		fmt.Printf("No differences, %d assets match their manifests\n", len(manifests))
+		return nil
+	}
+
+	fmt.Print(renderManifestChanges(changes))
+
+	return withExitCode(ExitThreshold, fmt.Errorf("%d assets differ from their manifests", len(changes)))
+}
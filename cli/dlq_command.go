@@ -0,0 +1,354 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/choria-io/fisk"
+	"github.com/dustin/go-humanize"
+	"github.com/nats-io/jsm.go"
+	"github.com/nats-io/jsm.go/api"
+	"github.com/nats-io/nats.go"
+)
+
+type dlqCmd struct {
+	stream   string
+	consumer string
+	dlq      string
+	to       string
+	maxAge   string
+	replicas int
+	memory   bool
+	keep     bool
+	force    bool
+	json     bool
+
+	mgr *jsm.Manager
+	js  nats.JetStreamContext
+}
+
+// dlqEntry is an advisory stored in a dead letter queue along with the message it refers to
+type dlqEntry struct {
+	Sequence   uint64         `json:"sequence"`
+	Reason     string         `json:"reason"`
+	Time       time.Time      `json:"time"`
+	Stream     string         `json:"stream"`
+	Consumer   string         `json:"consumer"`
+	StreamSeq  uint64         `json:"stream_seq"`
+	Deliveries uint64         `json:"deliveries"`
+	Message    *api.StoredMsg `json:"message,omitempty"`
+}
+
+const (
+	dlqMaxDeliveriesSubject = "$JS.EVENT.ADVISORY.CONSUMER.MAX_DELIVERIES"
+	dlqTerminatedSubject    = "$JS.EVENT.ADVISORY.CONSUMER.MSG_TERMINATED"
+)
+
+func configureDLQCommand(app commandHost) {
+	c := &dlqCmd{}
+
+	help := `Manages dead letter queues for Consumers
+
+A dead letter queue is a Stream holding the advisories published when
+messages exceed the maximum deliveries of a Consumer or are terminated
+by clients. The messages they refer to are read from the Stream of the
+Consumer, which should retain them, when listing or redriving the queue.
+`
+
+	dlq := app.Command("dlq", help)
+	addCheat("dlq", dlq)
+
+	setup := mutating(dlq.Command("setup", "Creates a dead letter queue for a Consumer").Action(c.setupAction))
+	setup.Arg("stream", "The Stream the Consumer reads").HintAction(streamNamesHint).Required().StringVar(&c.stream)
+	setup.Arg("consumer", "The Consumer to capture failed messages for").HintAction(consumerNamesHint(&c.stream)).Required().StringVar(&c.consumer)
+	setup.Flag("name", "The name of the dead letter queue Stream").PlaceHolder("NAME").StringVar(&c.dlq)
+	setup.Flag("max-age", "How long to keep entries in the queue").Default("1y").PlaceHolder("DURATION").StringVar(&c.maxAge)
+	setup.Flag("replicas", "Number of replicas for the queue").Default("1").IntVar(&c.replicas)
+	setup.Flag("memory", "Store the queue in memory").UnNegatableBoolVar(&c.memory)
+
+	ls := dlq.Command("ls", "Lists the entries in a dead letter queue").Alias("list").Action(c.lsAction)
+	ls.Arg("dlq", "The dead letter queue Stream").HintAction(streamNamesHint).Required().StringVar(&c.dlq)
+	addJSONOutputFlag(ls, &c.json)
+
+	redrive := mutating(dlq.Command("redrive", "Republishes the messages in a dead letter queue").Action(c.redriveAction))
+	redrive.Arg("dlq", "The dead letter queue Stream").HintAction(streamNamesHint).Required().StringVar(&c.dlq)
+	redrive.Flag("to", "The Stream to republish messages to, defaults to their original Stream").PlaceHolder("STREAM").StringVar(&c.to)
+	redrive.Flag("keep", "Keeps entries in the queue after republishing their messages").UnNegatableBoolVar(&c.keep)
+	redrive.Flag("force", "Redrive without prompting").UnNegatableBoolVar(&c.force)
+}
+
+func init() {
+	registerCommand("dlq", 6, configureDLQCommand)
+}
+
+func (c *dlqCmd) connect() error {
+	var err error
+
+	_, c.mgr, err = prepareHelper("", natsOpts()...)
+	if err != nil {
+		return err
+	}
+
+	_, c.js, err = prepareJSHelper()
+
+	return err
+}
+
+func (c *dlqCmd) setupAction(_ *fisk.ParseContext) error {
+	err := c.connect()
+	if err != nil {
+		return err
+	}
+
+	cons, err := c.mgr.LoadConsumer(c.stream, c.consumer)
+	if err != nil {
+		return fmt.Errorf("could not load Consumer %s > %s: %w", c.stream, c.consumer, err)
+	}
+
+	if c.dlq == "" {
+		c.dlq = fmt.Sprintf("DLQ_%s_%s", c.stream, c.consumer)
+	}
+
+	maxAge, err := parseDurationString(c.maxAge)
+	if err != nil {
+		return withExitCode(ExitValidation, fmt.Errorf("invalid max age: %w", err))
+	}
+
+	storage := jsm.FileStorage()
+	if c.memory {
+		storage = jsm.MemoryStorage()
+	}
+
+	_, err = c.mgr.NewStream(c.dlq,
+		jsm.Subjects(fmt.Sprintf("%s.%s.%s", dlqMaxDeliveriesSubject, c.stream, c.consumer), fmt.Sprintf("%s.%s.%s", dlqTerminatedSubject, c.stream, c.consumer)),
+		jsm.StreamDescription(fmt.Sprintf("Dead letter queue for Consumer %s > %s", c.stream, c.consumer)),
+		jsm.MaxAge(maxAge),
+		jsm.Replicas(c.replicas),
+		storage,
+	)
+	if err != nil {
+		return fmt.Errorf("could not create dead letter queue %s: %w", c.dlq, err)
+	}
+
+	fmt.Printf("Created dead letter queue %s for Consumer %s > %s\n", c.dlq, c.stream, c.consumer)
+	if cons.MaxDeliver() == -1 {
+		fmt.Println()
+		fmt.Printf("WARNING: Consumer %s has unlimited deliveries, only terminated messages will be captured\n", c.consumer)
+	}
+
+	return nil
+}
+
+// entries reads all advisories in the dead letter queue and loads the messages they refer to
+func (c *dlqCmd) entries() ([]*dlqEntry, *jsm.Stream, error) {
+	str, err := c.mgr.LoadStream(c.dlq)
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not load dead letter queue %s: %w", c.dlq, err)
+	}
+
+	nfo, err := str.Information()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var entries []*dlqEntry
+	if nfo.State.Msgs == 0 {
+		return entries, str, nil
+	}
+
+	sources := map[string]*jsm.Stream{}
+
+	for seq := nfo.State.FirstSeq; seq <= nfo.State.LastSeq; seq++ {
+		msg, err := str.ReadMessage(seq)
+		if err != nil {
+			if jsm.IsNatsError(err, 10037) {
+				continue
+			}
+			return nil, nil, err
+		}
+
+		var adv struct {
+			Type       string    `json:"type"`
+			Time       time.Time `json:"timestamp"`
+			Stream     string    `json:"stream"`
+			Consumer   string    `json:"consumer"`
+			StreamSeq  uint64    `json:"stream_seq"`
+			Deliveries uint64    `json:"deliveries"`
+		}
+		err = json.Unmarshal(msg.Data, &adv)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid advisory in message %d: %w", seq, err)
+		}
+
+		entry := &dlqEntry{
+			Sequence:   seq,
+			Reason:     "max deliveries",
+			Time:       adv.Time,
+			Stream:     adv.Stream,
+			Consumer:   adv.Consumer,
+			StreamSeq:  adv.StreamSeq,
+			Deliveries: adv.Deliveries,
+		}
+		if adv.Type == "io.nats.jetstream.advisory.v1.terminated" {
+			entry.Reason = "terminated"
+		}
+
+		source, ok := sources[adv.Stream]
+		if !ok {
+			source, err = c.mgr.LoadStream(adv.Stream)
+			if err != nil && !jsm.IsNatsError(err, 10059) {
+				return nil, nil, err
+			}
+			sources[adv.Stream] = source
+		}
+
+		if source != nil {
+			entry.Message, err = source.ReadMessage(adv.StreamSeq)
+			if err != nil && !jsm.IsNatsError(err, 10037) {
+				return nil, nil, err
+			}
+		}
+
+		entries = append(entries, entry)
+	}
+
+	return entries, str, nil
+}
+
+func (c *dlqCmd) lsAction(_ *fisk.ParseContext) error {
+	err := c.connect()
+	if err != nil {
+		return err
+	}
+
+	entries, _, err := c.entries()
+	if err != nil {
+		return err
+	}
+
+	if c.json {
+		return printJSON(entries)
+	}
+
+	if len(entries) == 0 {
+		fmt.Printf("No entries in dead letter queue %s\n", c.dlq)
+		return nil
+	}
+
+	table := newTableWriter(fmt.Sprintf("%d entries in dead letter queue %s", len(entries), c.dlq))
+	table.AddHeaders("Entry", "Time", "Reason", "Consumer", "Stream Sequence", "Deliveries", "Subject", "Size")
+	for _, e := range entries {
+		subject, size := "message not found", ""
+		if e.Message != nil {
+			subject = e.Message.Subject
+			size = humanize.IBytes(uint64(len(e.Message.Data)))
+		}
+
+		table.AddRow(e.Sequence, e.Time.Local().Format("2006-01-02 15:04:05"), e.Reason, fmt.Sprintf("%s > %s", e.Stream, e.Consumer), e.StreamSeq, e.Deliveries, subject, size)
+	}
+
+	fmt.Println(table.Render())
+
+	return nil
+}
+
+func (c *dlqCmd) redriveAction(_ *fisk.ParseContext) error {
+	err := c.connect()
+	if err != nil {
+		return err
+	}
+
+	entries, str, err := c.entries()
+	if err != nil {
+		return err
+	}
+
+	if len(entries) == 0 {
+		fmt.Printf("No entries in dead letter queue %s\n", c.dlq)
+		return nil
+	}
+
+	if !c.force {
+		target := "their original Streams"
+		if c.to != "" {
+			target = "Stream " + c.to
+		}
+
+		ok, err := askConfirmation(fmt.Sprintf("Really republish %d messages to %s", len(entries), target), false)
+		if err != nil {
+			return err
+		}
+
+		if !ok {
+			return nil
+		}
+	}
+
+	redriven, missing := 0, 0
+	for _, e := range entries {
+		if e.Message == nil {
+			missing++
+			continue
+		}
+
+		target := e.Stream
+		if c.to != "" {
+			target = c.to
+		}
+
+		msg := nats.NewMsg(e.Message.Subject)
+		msg.Data = e.Message.Data
+		if len(e.Message.Header) > 0 {
+			hdr, err := decodeHeadersMsg(e.Message.Header)
+			if err != nil {
+				return fmt.Errorf("could not parse headers of message %d in %s: %w", e.StreamSeq, e.Stream, err)
+			}
+			msg.Header = hdr
+		}
+		for _, h := range migrateHeaders {
+			msg.Header.Del(h)
+		}
+
+		ack, err := c.js.PublishMsg(msg)
+		if err != nil {
+			return fmt.Errorf("could not republish message %d from %s to %s: %w", e.StreamSeq, e.Stream, target, err)
+		}
+		if ack.Stream != target {
+			return fmt.Errorf("message %d from %s was stored in Stream %s rather than %s", e.StreamSeq, e.Stream, ack.Stream, target)
+		}
+
+		if opts.Trace {
+			log.Printf("Republished %s > %d to %s > %d", e.Stream, e.StreamSeq, ack.Stream, ack.Sequence)
+		}
+
+		if !c.keep {
+			err = str.DeleteMessage(e.Sequence)
+			if err != nil {
+				return fmt.Errorf("could not remove entry %d from %s: %w", e.Sequence, c.dlq, err)
+			}
+		}
+
+		redriven++
+	}
+
+	fmt.Printf("Republished %d messages from dead letter queue %s\n", redriven, c.dlq)
+	if missing > 0 {
+		fmt.Printf("%d entries refer to messages that are no longer stored and were left in the queue\n", missing)
+	}
+
+	return nil
+}
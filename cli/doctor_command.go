@@ -0,0 +1,474 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/choria-io/fisk"
+	"github.com/dustin/go-humanize"
+	"github.com/fatih/color"
+	"github.com/nats-io/jsm.go"
+	"github.com/nats-io/jsm.go/api"
+	"github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats.go"
+)
+
+type doctorCmd struct {
+	expireWarn time.Duration
+	maxSkew    time.Duration
+	json       bool
+
+	findings []*doctorFinding
+}
+
+// doctorFinding is a problem, or a confirmation that a check passed, found by nats doctor
+type doctorFinding struct {
+	Severity string `json:"severity"`
+	Check    string `json:"check"`
+	Message  string `json:"message"`
+}
+
+const (
+	doctorCritical = "critical"
+	doctorWarning  = "warning"
+	doctorInfo     = "info"
+	doctorOK       = "ok"
+
+	// doctorMinServerVersion is the oldest server version supporting all features used by the CLI
+	doctorMinServerVersion = "2.9.0"
+)
+
+var doctorSeverities = map[string]int{doctorCritical: 0, doctorWarning: 1, doctorInfo: 2, doctorOK: 3}
+
+func configureDoctorCommand(app commandHost) {
+	c := &doctorCmd{}
+
+	help := `Diagnoses common problems with the environment and the connected cluster
+
+Checks the selected context and its credentials, connectivity, TLS,
+server versions and clocks, JetStream availability and usage and the
+configuration of Streams and Consumers, listing the problems found with
+the most severe first.
+
+Some checks need access to the system account and are skipped without it.
+`
+
+	doctor := app.Command("doctor", help).Action(c.doctorAction)
+	doctor.Flag("expire-warn", "Warn about credentials and certificates expiring within this period").Default("720h").PlaceHolder("DURATION").DurationVar(&c.expireWarn)
+	doctor.Flag("max-skew", "Warn when server clocks differ from the local clock by more than this").Default("1s").PlaceHolder("DURATION").DurationVar(&c.maxSkew)
+	addJSONOutputFlag(doctor, &c.json)
+	addCheat("doctor", doctor)
+}
+
+func init() {
+	registerCommand("doctor", 6, configureDoctorCommand)
+}
+
+func (c *doctorCmd) add(severity string, check string, format string, a ...any) {
+	c.findings = append(c.findings, &doctorFinding{Severity: severity, Check: check, Message: fmt.Sprintf(format, a...)})
+}
+
+func (c *doctorCmd) doctorAction(_ *fisk.ParseContext) error {
+	c.checkContext()
+
+	nc, mgr, err := c.checkConnection()
+	if err == nil {
+		c.checkTLS(nc)
+		c.checkServers(nc)
+		if c.checkJetStream(mgr) {
+			c.checkAssets(nc, mgr)
+		}
+	}
+
+	sort.SliceStable(c.findings, func(i, j int) bool {
+		return doctorSeverities[c.findings[i].Severity] < doctorSeverities[c.findings[j].Severity]
+	})
+
+	critical := 0
+	for _, f := range c.findings {
+		if f.Severity == doctorCritical {
+			critical++
+		}
+	}
+
+	if c.json {
+		err = printJSON(c.findings)
+		if err != nil {
+			return err
+		}
+	} else {
+		c.render()
+	}
+
+	if critical > 0 {
+		return withExitCode(ExitThreshold, fmt.Errorf("%d critical problems found", critical))
+	}
+
+	return nil
+}
+
+func (c *doctorCmd) render() {
+	table := newTableWriter("NATS Doctor")
+	table.AddHeaders("Severity", "Check", "Finding")
+
+	counts := map[string]int{}
+	for _, f := range c.findings {
+		counts[f.Severity]++
+
+		severity := f.Severity
+		switch f.Severity {
+		case doctorCritical:
+			severity = color.RedString("CRITICAL")
+		case doctorWarning:
+			severity = color.YellowString("WARNING")
+		case doctorInfo:
+			severity = color.CyanString("INFO")
+		case doctorOK:
+			severity = color.GreenString("OK")
+		}
+
+		table.AddRow(severity, f.Check, f.Message)
+	}
+
+	fmt.Println(table.Render())
+	fmt.Printf("%d critical, %d warnings, %d informational, %d checks passed\n", counts[doctorCritical], counts[doctorWarning], counts[doctorInfo], counts[doctorOK])
+}
+
+func (c *doctorCmd) checkContext() {
+	nctx := opts.Config
+	if nctx == nil || nctx.Name == "" {
+		c.add(doctorInfo, "Context", "No context selected, connecting using command line options and defaults")
+		return
+	}
+
+	c.add(doctorOK, "Context", "Using context %s", nctx.Name)
+
+	var audits []*ctxCredentialAudit
+	audit := &ctxCommand{}
+	if nctx.Creds() != "" {
+		a := &ctxCredentialAudit{Kind: "credentials", File: nctx.Creds()}
+		audit.auditCredsFile(a)
+		audits = append(audits, a)
+	}
+	if nctx.Certificate() != "" {
+		a := &ctxCredentialAudit{Kind: "certificate", File: nctx.Certificate()}
+		audit.auditCertFile(a)
+		audits = append(audits, a)
+	}
+	if nctx.CA() != "" {
+		a := &ctxCredentialAudit{Kind: "ca", File: nctx.CA()}
+		audit.auditCertFile(a)
+		audits = append(audits, a)
+	}
+
+	for _, a := range audits {
+		switch {
+		case a.Error != "":
+			c.add(doctorCritical, "Credentials", "Could not read %s %s: %s", a.Kind, a.File, a.Error)
+		case a.Expires == nil:
+			c.add(doctorOK, "Credentials", "The %s in %s does not expire", a.Kind, a.File)
+		case a.Expires.Before(time.Now()):
+			c.add(doctorCritical, "Credentials", "The %s in %s expired %s", a.Kind, a.File, humanize.Time(*a.Expires))
+		case a.Expires.Before(time.Now().Add(c.expireWarn)):
+			c.add(doctorWarning, "Credentials", "The %s in %s expires %s", a.Kind, a.File, humanize.Time(*a.Expires))
+		default:
+			c.add(doctorOK, "Credentials", "The %s in %s expires %s", a.Kind, a.File, humanize.Time(*a.Expires))
+		}
+	}
+}
+
+func (c *doctorCmd) checkConnection() (*nats.Conn, *jsm.Manager, error) {
+	start := time.Now()
+	nc, mgr, err := prepareHelper("", natsOpts()...)
+	if err != nil {
+		c.add(doctorCritical, "Connection", "Could not connect: %v", err)
+		return nil, nil, err
+	}
+
+	c.add(doctorOK, "Connection", "Connected to %s in %v", nc.ConnectedUrlRedacted(), time.Since(start).Round(time.Millisecond))
+
+	rtt, err := nc.RTT()
+	switch {
+	case err != nil:
+		c.add(doctorWarning, "Connection", "Could not measure the round trip time: %v", err)
+	case rtt > 250*time.Millisecond:
+		c.add(doctorWarning, "Connection", "Round trip time to the server is %v, commands will be slow", rtt.Round(time.Millisecond))
+	}
+
+	return nc, mgr, nil
+}
+
+func (c *doctorCmd) checkTLS(nc *nats.Conn) {
+	state, err := nc.TLSConnectionState()
+	if err != nil {
+		u, err := url.Parse(nc.ConnectedUrl())
+		local := err == nil && isLoopbackHost(u.Hostname())
+
+		switch {
+		case local:
+			c.add(doctorInfo, "TLS", "The connection is not encrypted")
+		case opts.Password != "" || opts.Username != "" || opts.Config != nil && (opts.Config.Password() != "" || opts.Config.Token() != ""):
+			c.add(doctorWarning, "TLS", "The connection is not encrypted, passwords and tokens are sent in plain text")
+		default:
+			c.add(doctorWarning, "TLS", "The connection to a remote server is not encrypted")
+		}
+		return
+	}
+
+	if len(state.PeerCertificates) == 0 {
+		c.add(doctorOK, "TLS", "The connection is encrypted")
+		return
+	}
+
+	cert := state.PeerCertificates[0]
+	switch {
+	case cert.NotAfter.Before(time.Now()):
+		c.add(doctorCritical, "TLS", "The server certificate for %s expired %s", cert.Subject.CommonName, humanize.Time(cert.NotAfter))
+	case cert.NotAfter.Before(time.Now().Add(c.expireWarn)):
+		c.add(doctorWarning, "TLS", "The server certificate for %s expires %s", cert.Subject.CommonName, humanize.Time(cert.NotAfter))
+	default:
+		c.add(doctorOK, "TLS", "The connection is encrypted, the server certificate expires %s", humanize.Time(cert.NotAfter))
+	}
+}
+
+func isLoopbackHost(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+func (c *doctorCmd) checkServers(nc *nats.Conn) {
+	version := nc.ConnectedServerVersion()
+	if !serverMinVersion(version, 2, 9, 0) {
+		c.add(doctorWarning, "Servers", "The connected server runs version %s, some features need version %s or newer", version, doctorMinServerVersion)
+	} else {
+		c.add(doctorOK, "Servers", "The connected server runs version %s", version)
+	}
+
+	var stats []*server.ServerStatsMsg
+	var received []time.Time
+
+	err := doReqAsync(nil, "$SYS.REQ.SERVER.PING", 0, nc, func(data []byte) {
+		ssm := &server.ServerStatsMsg{}
+		if json.Unmarshal(data, ssm) == nil {
+			stats = append(stats, ssm)
+			received = append(received, time.Now())
+		}
+	})
+	if err != nil || len(stats) == 0 {
+		c.add(doctorInfo, "Servers", "The system account is not available, cluster wide checks were skipped")
+		return
+	}
+
+	versions := map[string][]string{}
+	for i, s := range stats {
+		versions[s.Server.Version] = append(versions[s.Server.Version], s.Server.Name)
+
+		if !serverMinVersion(s.Server.Version, 2, 9, 0) {
+			c.add(doctorWarning, "Servers", "Server %s runs version %s, some features need version %s or newer", s.Server.Name, s.Server.Version, doctorMinServerVersion)
+		}
+
+		skew := received[i].Sub(s.Server.Time)
+		if skew < 0 {
+			skew = -skew
+		}
+		if skew > c.maxSkew {
+			c.add(doctorWarning, "Clocks", "The clock of server %s differs from the local clock by %v", s.Server.Name, skew.Round(time.Millisecond))
+		}
+	}
+
+	if len(versions) > 1 {
+		var list []string
+		for v, servers := range versions {
+			list = append(list, fmt.Sprintf("%s on %d servers", v, len(servers)))
+		}
+		sort.Strings(list)
+		c.add(doctorWarning, "Servers", "Servers run different versions: %s", strings.Join(list, ", "))
+	} else {
+		c.add(doctorOK, "Servers", "All %d servers run version %s", len(stats), stats[0].Server.Version)
+	}
+}
+
+// checkJetStream checks JetStream is available and within its limits, returns false when JetStream is not available
+func (c *doctorCmd) checkJetStream(mgr *jsm.Manager) bool {
+	info, err := mgr.JetStreamAccountInfo()
+	switch {
+	case errors.Is(err, nats.ErrNoResponders) || errors.Is(err, nats.ErrJetStreamNotEnabled) || jsm.IsNatsError(err, 10039):
+		c.add(doctorInfo, "JetStream", "JetStream is not enabled for this account")
+		return false
+	case err != nil:
+		c.add(doctorCritical, "JetStream", "Could not retrieve JetStream account information: %v", err)
+		return false
+	}
+
+	c.add(doctorOK, "JetStream", "JetStream is enabled with %s Streams and %s Consumers", humanize.Comma(int64(info.Streams)), humanize.Comma(int64(info.Consumers)))
+
+	usage := func(what string, used float64, limit float64, format func(float64) string) {
+		if limit <= 0 {
+			return
+		}
+
+		pct := used / limit * 100
+		switch {
+		case pct >= 90:
+			c.add(doctorCritical, "JetStream", "%s usage is at %.0f%% of the account limit (%s of %s)", what, pct, format(used), format(limit))
+		case pct >= 75:
+			c.add(doctorWarning, "JetStream", "%s usage is at %.0f%% of the account limit (%s of %s)", what, pct, format(used), format(limit))
+		}
+	}
+
+	bytes := func(v float64) string { return humanize.IBytes(uint64(v)) }
+	count := func(v float64) string { return humanize.Comma(int64(v)) }
+
+	tiers := map[string]api.JetStreamTier{"": info.JetStreamTier}
+	for name, tier := range info.Tiers {
+		tiers[name] = tier
+	}
+
+	for name, tier := range tiers {
+		prefix := ""
+		if name != "" {
+			prefix = fmt.Sprintf("Tier %s ", name)
+		}
+
+		usage(prefix+"Memory", float64(tier.Memory), float64(tier.Limits.MaxMemory), bytes)
+		usage(prefix+"Storage", float64(tier.Store), float64(tier.Limits.MaxStore), bytes)
+		usage(prefix+"Stream", float64(tier.Streams), float64(tier.Limits.MaxStreams), count)
+		usage(prefix+"Consumer", float64(tier.Consumers), float64(tier.Limits.MaxConsumers), count)
+	}
+
+	if info.API.Total > 0 && info.API.Errors*10 > info.API.Total {
+		c.add(doctorWarning, "JetStream", "%s of %s API requests made by this account failed", humanize.Comma(int64(info.API.Errors)), humanize.Comma(int64(info.API.Total)))
+	}
+
+	return true
+}
+
+// summarizeNames lists names, limited to a few followed by how many were left out
+func summarizeNames(names []string) string {
+	sort.Strings(names)
+	if len(names) <= 5 {
+		return strings.Join(names, ", ")
+	}
+
+	return fmt.Sprintf("%s and %d more", strings.Join(names[:5], ", "), len(names)-5)
+}
+
+func (c *doctorCmd) checkAssets(nc *nats.Conn, mgr *jsm.Manager) {
+	streams, missing, err := loadStreams(mgr, nil, 1)
+	if err != nil {
+		c.add(doctorCritical, "Streams", "Could not list Streams: %v", err)
+		return
+	}
+	if len(missing) > 0 {
+		c.add(doctorCritical, "Streams", "Could not load information for %d Streams: %s", len(missing), summarizeNames(missing))
+	}
+
+	clustered := nc.ConnectedClusterName() != ""
+
+	var single, leaderless, lagging, unbounded []string
+	for _, s := range streams {
+		info, err := s.LatestInformation()
+		if err != nil {
+			continue
+		}
+
+		if clustered && info.Config.Replicas == 1 && !jsm.IsInternalStream(s.Name()) {
+			single = append(single, s.Name())
+		}
+
+		if info.Cluster != nil && info.Config.Replicas > 1 {
+			if info.Cluster.Leader == "" {
+				leaderless = append(leaderless, s.Name())
+			}
+
+			for _, r := range info.Cluster.Replicas {
+				if !r.Current || r.Offline {
+					lagging = append(lagging, s.Name())
+					break
+				}
+			}
+		}
+
+		cfg := info.Config
+		if cfg.Retention == api.LimitsPolicy && cfg.MaxAge == 0 && cfg.MaxBytes <= 0 && cfg.MaxMsgs <= 0 && cfg.MaxMsgsPer <= 0 {
+			unbounded = append(unbounded, s.Name())
+		}
+
+		if cfg.Retention == api.WorkQueuePolicy {
+			c.checkWorkQueue(mgr, s.Name())
+		}
+	}
+
+	if len(leaderless) > 0 {
+		c.add(doctorCritical, "Streams", "%d Streams have no leader: %s", len(leaderless), summarizeNames(leaderless))
+	}
+	if len(lagging) > 0 {
+		c.add(doctorWarning, "Streams", "%d Streams have replicas that are offline or not current: %s", len(lagging), summarizeNames(lagging))
+	}
+	if len(single) > 0 {
+		c.add(doctorWarning, "Streams", "%d Streams in a cluster have a single replica and will be unavailable when their server is down: %s", len(single), summarizeNames(single))
+	}
+	if len(unbounded) > 0 {
+		c.add(doctorInfo, "Streams", "%d Streams have no limits and will grow until the account or server limits are reached: %s", len(unbounded), summarizeNames(unbounded))
+	}
+
+	if len(streams) > 0 && len(leaderless)+len(lagging)+len(single) == 0 {
+		c.add(doctorOK, "Streams", "%d Streams are healthy", len(streams))
+	}
+}
+
+// checkWorkQueue finds Consumers on a work queue Stream with overlapping filters, these would receive the same messages
+func (c *doctorCmd) checkWorkQueue(mgr *jsm.Manager, stream string) {
+	consumers, _, err := loadConsumers(mgr, stream, 1)
+	if err != nil {
+		c.add(doctorWarning, "Consumers", "Could not list Consumers of work queue %s: %v", stream, err)
+		return
+	}
+
+	filters := func(cons *jsm.Consumer) []string {
+		if len(cons.FilterSubjects()) > 0 {
+			return cons.FilterSubjects()
+		}
+		if cons.FilterSubject() != "" {
+			return []string{cons.FilterSubject()}
+		}
+		return []string{">"}
+	}
+
+	for i := 0; i < len(consumers); i++ {
+		for j := i + 1; j < len(consumers); j++ {
+		compare:
+			for _, a := range filters(consumers[i]) {
+				for _, b := range filters(consumers[j]) {
+					if server.SubjectsCollide(a, b) {
+						c.add(doctorWarning, "Consumers", "Consumers %s and %s on work queue %s have overlapping filters %s and %s", consumers[i].Name(), consumers[j].Name(), stream, a, b)
+						break compare
+					}
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,365 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/choria-io/fisk"
+	"github.com/nats-io/jsm.go/natscontext"
+)
+
+const (
+	// eachContextEnv is set for commands started by --each-context, tables are then exported rather than rendered
+	eachContextEnv = "NATS_CLI_EACH_CONTEXT"
+	// eachContextTableMarker prefixes lines holding exported tables
+	eachContextTableMarker = "\x1enats-table:"
+)
+
+// eachContextTable is a table exported by a command started by --each-context
+type eachContextTable struct {
+	Title   string     `json:"title"`
+	Headers []string   `json:"headers"`
+	Rows    [][]string `json:"rows"`
+}
+
+// eachContextResult is the output of a command run against a single context
+type eachContextResult struct {
+	context  string
+	stdout   []byte
+	stderr   []byte
+	exitCode int
+}
+
+func eachContextChild() bool {
+	return os.Getenv(eachContextEnv) != ""
+}
+
+// export renders the visible columns of the table as a single line for merging by --each-context
+func (t *tbl) export() string {
+	visible := t.visibleColumns()
+
+	var idx []int
+	for i, h := range t.headers {
+		for _, v := range visible {
+			if columnMatch(h, v) {
+				idx = append(idx, i)
+				break
+			}
+		}
+	}
+
+	exp := eachContextTable{Title: t.title, Rows: [][]string{}}
+	for _, i := range idx {
+		exp.Headers = append(exp.Headers, t.headers[i])
+	}
+
+	for _, row := range t.rows {
+		r := make([]string, len(idx))
+		for j, i := range idx {
+			if i < len(row) {
+				r[j] = row[i]
+			}
+		}
+		exp.Rows = append(exp.Rows, r)
+	}
+
+	j, _ := json.Marshal(exp)
+
+	return eachContextTableMarker + string(j)
+}
+
+// eachContextArgs extracts the --each-context flag from args
+func eachContextArgs(args []string) (string, []string, bool) {
+	for i, arg := range args {
+		switch {
+		case arg == "--":
+			return "", args, false
+		case arg == "--each-context" && i+1 < len(args):
+			rest := append(append([]string{}, args[:i]...), args[i+2:]...)
+			return args[i+1], rest, true
+		case strings.HasPrefix(arg, "--each-context="):
+			rest := append(append([]string{}, args[:i]...), args[i+1:]...)
+			return strings.TrimPrefix(arg, "--each-context="), rest, true
+		}
+	}
+
+	return "", args, false
+}
+
+// matchingContexts finds the known contexts matching a shell pattern
+func matchingContexts(pattern string) ([]string, error) {
+	var matched []string
+	for _, name := range natscontext.KnownContexts() {
+		ok, err := path.Match(pattern, name)
+		if err != nil {
+			return nil, fmt.Errorf("invalid context pattern %q: %w", pattern, err)
+		}
+		if ok {
+			matched = append(matched, name)
+		}
+	}
+
+	sort.Strings(matched)
+
+	return matched, nil
+}
+
+// runEachContext runs the command in args against every context matching pattern concurrently and merges the output
+func runEachContext(app *fisk.Application, pattern string, args []string) int {
+	fail := func(code int, format string, a ...any) int {
+		app.Errorf(format, a...)
+		return code
+	}
+
+	if eachContextChild() {
+		return fail(ExitUsage, "--each-context cannot be nested")
+	}
+
+	pc, err := app.ParseContext(args)
+	if err != nil {
+		return fail(ExitUsage, "%v", err)
+	}
+	if pc.SelectedCommand == nil {
+		return fail(ExitUsage, "a command is required when using --each-context")
+	}
+
+	for _, e := range pc.Elements {
+		f, ok := e.Clause.(*fisk.FlagClause)
+		if ok && (f.Model().Name == "context" || f.Model().Name == "no-context") {
+			return fail(ExitUsage, "--%s cannot be combined with --each-context", f.Model().Name)
+		}
+	}
+
+	cmd := pc.SelectedCommand.FullCommand()
+	if isMutatingCommand(cmd) && !isPublishingCommand(cmd) {
+		return fail(ExitUsage, "only read-only commands can be run using --each-context, %q makes changes", cmd)
+	}
+
+	contexts, err := matchingContexts(pattern)
+	if err != nil {
+		return fail(ExitUsage, "%v", err)
+	}
+	if len(contexts) == 0 {
+		return fail(ExitNotFound, "no contexts match %q", pattern)
+	}
+
+	if isPublishingCommand(cmd) {
+		err = confirmEachContextPublish(cmd, contexts)
+		if err != nil {
+			return fail(ExitValidation, "%v", err)
+		}
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		return fail(ExitUsage, "could not determine the nats executable: %v", err)
+	}
+
+	results := make([]*eachContextResult, len(contexts))
+	wg := sync.WaitGroup{}
+
+	for i, name := range contexts {
+		wg.Add(1)
+		go func(i int, name string) {
+			defer wg.Done()
+
+			var stdout, stderr bytes.Buffer
+			c := exec.Command(exe, append([]string{"--context", name}, args...)...)
+			c.Env = append(os.Environ(), eachContextEnv+"=1", "NATS_NO_PAGER=1")
+			c.Stdout = &stdout
+			c.Stderr = &stderr
+
+			res := &eachContextResult{context: name}
+			err := c.Run()
+			if err != nil {
+				var exitErr *exec.ExitError
+				if errors.As(err, &exitErr) {
+					res.exitCode = exitErr.ExitCode()
+				} else {
+					res.exitCode = ExitUsage
+					fmt.Fprintf(&stderr, "could not run command: %v\n", err)
+				}
+			}
+			res.stdout = stdout.Bytes()
+			res.stderr = stderr.Bytes()
+			results[i] = res
+		}(i, name)
+	}
+
+	wg.Wait()
+
+	mergeEachContextOutput(results)
+
+	for _, res := range results {
+		if res.exitCode != ExitOK {
+			return res.exitCode
+		}
+	}
+
+	return ExitOK
+}
+
+// confirmEachContextPublish asks before publishing messages or requests to every matching context
+func confirmEachContextPublish(cmd string, contexts []string) error {
+	if !isTerminal() {
+		return fmt.Errorf("%q publishes messages, it can only be run using --each-context from a terminal", cmd)
+	}
+
+	ok, err := askConfirmation(fmt.Sprintf("Really run %q publishing to %d contexts: %s", cmd, len(contexts), strings.Join(contexts, ", ")), false)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("not running %q against %d contexts", cmd, len(contexts))
+	}
+
+	return nil
+}
+
+// mergeEachContextOutput combines the output of all contexts, JSON documents are merged into a
+// list, tables with the same columns are merged with a Context column and other output is prefixed
+func mergeEachContextOutput(results []*eachContextResult) {
+	for _, res := range results {
+		for _, line := range strings.Split(strings.TrimRight(string(res.stderr), "\n"), "\n") {
+			if line != "" {
+				fmt.Fprintf(os.Stderr, "%s: %s\n", res.context, line)
+			}
+		}
+	}
+
+	merged, ok := mergeEachContextJSON(results)
+	if ok {
+		j, err := json.MarshalIndent(merged, "", "  ")
+		if err == nil {
+			fmt.Println(string(j))
+			return
+		}
+	}
+
+	var tables []*eachContextTable
+	var rows [][][]string
+
+	for _, res := range results {
+		scanner := bufio.NewScanner(bytes.NewReader(res.stdout))
+		scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+
+		for scanner.Scan() {
+			line := scanner.Text()
+
+			if !strings.HasPrefix(line, eachContextTableMarker) {
+				if strings.TrimSpace(line) != "" {
+					fmt.Printf("%s: %s\n", res.context, line)
+				}
+				continue
+			}
+
+			t := &eachContextTable{}
+			err := json.Unmarshal([]byte(strings.TrimPrefix(line, eachContextTableMarker)), t)
+			if err != nil {
+				continue
+			}
+
+			idx := -1
+			for i, known := range tables {
+				if known.Title == t.Title && strings.Join(known.Headers, "\x00") == strings.Join(t.Headers, "\x00") {
+					idx = i
+					break
+				}
+			}
+			if idx == -1 {
+				tables = append(tables, t)
+				rows = append(rows, nil)
+				idx = len(tables) - 1
+			}
+
+			for _, row := range t.Rows {
+				rows[idx] = append(rows[idx], append([]string{res.context}, row...))
+			}
+		}
+	}
+
+	for i, t := range tables {
+		if i == 0 {
+			fmt.Println()
+		}
+
+		table := newTableWriter(t.Title)
+		hdrs := []any{"Context"}
+		for _, h := range t.Headers {
+			hdrs = append(hdrs, h)
+		}
+		table.AddHeaders(hdrs...)
+
+		for _, row := range rows[i] {
+			r := make([]any, len(row))
+			for j, v := range row {
+				r[j] = v
+			}
+			table.AddRow(r...)
+		}
+
+		fmt.Println(table.Render())
+	}
+}
+
+// mergeEachContextJSON merges JSON output into a list, objects get a context key and other values are wrapped
+func mergeEachContextJSON(results []*eachContextResult) ([]any, bool) {
+	merged := []any{}
+	found := false
+
+	for _, res := range results {
+		body := bytes.TrimSpace(res.stdout)
+		if len(body) == 0 {
+			if res.exitCode == ExitOK {
+				return nil, false
+			}
+			continue
+		}
+
+		var doc any
+		err := json.Unmarshal(body, &doc)
+		if err != nil {
+			return nil, false
+		}
+		found = true
+
+		items := []any{doc}
+		if list, ok := doc.([]any); ok {
+			items = list
+		}
+
+		for _, item := range items {
+			obj, ok := item.(map[string]any)
+			if ok {
+				obj["context"] = res.context
+				merged = append(merged, obj)
+			} else {
+				merged = append(merged, map[string]any{"context": res.context, "data": item})
+			}
+		}
+	}
+
+	return merged, found
+}
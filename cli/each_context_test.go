@@ -0,0 +1,63 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestEachContextArgs(t *testing.T) {
+	for _, tc := range []struct {
+		args    []string
+		pattern string
+		rest    []string
+		found   bool
+	}{
+		{[]string{"stream", "report"}, "", []string{"stream", "report"}, false},
+		{[]string{"--each-context", "prod-*", "stream", "report"}, "prod-*", []string{"stream", "report"}, true},
+		{[]string{"stream", "report", "--each-context=prod-*", "--json"}, "prod-*", []string{"stream", "report", "--json"}, true},
+		{[]string{"run", "--", "--each-context", "x"}, "", []string{"run", "--", "--each-context", "x"}, false},
+	} {
+		pattern, rest, found := eachContextArgs(tc.args)
+		if pattern != tc.pattern || found != tc.found || !reflect.DeepEqual(rest, tc.rest) {
+			t.Fatalf("eachContextArgs(%v) returned %q %v %v", tc.args, pattern, rest, found)
+		}
+	}
+}
+
+func TestMergeEachContextJSON(t *testing.T) {
+	merged, ok := mergeEachContextJSON([]*eachContextResult{
+		{context: "a", stdout: []byte(`{"name":"ORDERS"}`)},
+		{context: "b", stdout: []byte(`[{"name":"ORDERS"},"x"]`)},
+		{context: "c", exitCode: ExitConnection},
+	})
+	if !ok {
+		t.Fatalf("expected JSON output to merge")
+	}
+
+	expect := []any{
+		map[string]any{"name": "ORDERS", "context": "a"},
+		map[string]any{"name": "ORDERS", "context": "b"},
+		map[string]any{"context": "b", "data": "x"},
+	}
+	if !reflect.DeepEqual(merged, expect) {
+		t.Fatalf("unexpected merge result: %#v", merged)
+	}
+
+	_, ok = mergeEachContextJSON([]*eachContextResult{{context: "a", stdout: []byte("DD\nWQ\n")}})
+	if ok {
+		t.Fatalf("expected text output not to merge")
+	}
+}
@@ -0,0 +1,158 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/choria-io/fisk"
+)
+
+type electionCmd struct {
+	bucket   string
+	key      string
+	ttl      time.Duration
+	command  []string
+	json     bool
+	campaign time.Duration
+}
+
+// electionStatus is the current leader of an election
+type electionStatus struct {
+	Bucket  string     `json:"bucket"`
+	Key     string     `json:"key"`
+	Leader  string     `json:"leader,omitempty"`
+	Since   *time.Time `json:"since,omitempty"`
+	Renewed *time.Time `json:"renewed,omitempty"`
+}
+
+func configureElectionCommand(app commandHost) {
+	c := &electionCmd{}
+
+	help := `Leader election using a Key-Value bucket
+
+Candidates campaign for leadership by taking a lease stored in a key,
+the leader runs the command and renews the lease while it runs. Other
+candidates keep campaigning and take over once the leader resigns or
+its lease expires after the TTL.
+
+The leader resigns once the command exits and exits using the exit code
+of the command. When leadership is lost the command is terminated and
+the candidate campaigns again.
+`
+
+	election := app.Command("election", help)
+	addCheat("election", election)
+
+	run := mutating(election.Command("run", "Campaigns for leadership and runs a command while leader").Action(c.runAction))
+	run.Arg("bucket", "The bucket holding the election").Required().HintAction(kvBucketNamesHint).StringVar(&c.bucket)
+	run.Arg("key", "The key to campaign for").Required().StringVar(&c.key)
+	run.Arg("command", "The command to run while leader").Required().StringsVar(&c.command)
+	run.Flag("ttl", "How long leadership is kept without being renewed").Default("30s").DurationVar(&c.ttl)
+	run.Flag("campaign", "How often to campaign while not leader").Default("1s").DurationVar(&c.campaign)
+
+	status := election.Command("status", "Shows the current leader").Alias("info").Action(c.statusAction)
+	status.Arg("bucket", "The bucket holding the election").Required().HintAction(kvBucketNamesHint).StringVar(&c.bucket)
+	status.Arg("key", "The key of the election").Required().StringVar(&c.key)
+	addJSONOutputFlag(status, &c.json)
+}
+
+func init() {
+	registerCommand("election", 6, configureElectionCommand)
+}
+
+func (c *electionCmd) runAction(_ *fisk.ParseContext) error {
+	keeper, err := newKVLeaseKeeper(c.bucket, c.key, c.ttl)
+	if err != nil {
+		return err
+	}
+
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(sigs)
+
+	ticker := time.NewTicker(c.campaign)
+	defer ticker.Stop()
+
+	campaigning := false
+
+	for {
+		leader, err := keeper.tryAcquire()
+		switch {
+		case err != nil:
+			log.Printf("Campaign for %s > %s failed: %v", c.bucket, c.key, err)
+
+		case leader == nil:
+			log.Printf("Became leader of %s > %s, running %s", c.bucket, c.key, c.command[0])
+
+			lost, err := keeper.run(c.command)
+			if !lost {
+				log.Printf("Resigned leadership of %s > %s", c.bucket, c.key)
+				return err
+			}
+			campaigning = false
+
+		case !campaigning:
+			log.Printf("Campaigning for %s > %s, current leader is %s", c.bucket, c.key, leader.Owner)
+			campaigning = true
+		}
+
+		select {
+		case <-ticker.C:
+		case <-sigs:
+			return nil
+		}
+	}
+}
+
+func (c *electionCmd) statusAction(_ *fisk.ParseContext) error {
+	keeper, err := newKVLeaseKeeper(c.bucket, c.key, time.Second)
+	if err != nil {
+		return err
+	}
+
+	leader, entry, err := keeper.current()
+	if err != nil {
+		return err
+	}
+
+	status := &electionStatus{Bucket: c.bucket, Key: c.key}
+	if leader != nil {
+		renewed := entry.Created()
+		status.Leader = leader.Owner
+		status.Since = &leader.Acquired
+		status.Renewed = &renewed
+	}
+
+	if c.json {
+		return printJSON(status)
+	}
+
+	if leader == nil {
+		fmt.Printf("%s > %s has no leader\n", c.bucket, c.key)
+		return nil
+	}
+
+	fmt.Printf("Leader of %s > %s:\n\n", c.bucket, c.key)
+	fmt.Printf("   Leader: %s\n", status.Leader)
+	fmt.Printf("    Since: %s (%s)\n", status.Since.Local().Format(time.RFC3339), humanizeDuration(time.Since(*status.Since).Round(time.Second)))
+	fmt.Printf("  Renewed: %s ago\n", humanizeDuration(time.Since(*status.Renewed).Round(time.Millisecond)))
+	fmt.Printf("  Expires: in %s\n", humanizeDuration(time.Until(status.Renewed.Add(leader.TTL)).Round(time.Millisecond)))
+
+	return nil
+}
@@ -14,15 +14,21 @@
 package cli
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"regexp"
 	"strings"
 	"sync"
+	"time"
 
+	"github.com/antonmedv/expr"
+	"github.com/antonmedv/expr/vm"
 	"github.com/choria-io/fisk"
 	"github.com/nats-io/jsm.go"
 	"github.com/nats-io/jsm.go/api"
+	jsadvisory "github.com/nats-io/jsm.go/api/jetstream/advisory"
 	"github.com/nats-io/nats.go"
 )
 
@@ -34,12 +40,26 @@ type eventsCmd struct {
 	bodyF   string
 	bodyFRe *regexp.Regexp
 
+	filterExpr    string
+	filterProgram *vm.Program
+	outputFile    string
+	output        *os.File
+	storeStream   string
+	storePrefix   string
+	js            nats.JetStreamContext
+
 	showJsMetrics        bool
 	showJsAdvisories     bool
+	showJsAudit          bool
 	showServerAdvisories bool
+	srvAdvisoriesSet     bool
 	showAll              bool
 	extraSubjects        []string
 
+	auditAccount string
+	auditUser    string
+	auditAsset   string
+
 	sync.Mutex
 }
 
@@ -55,19 +75,149 @@ func configureEventsCommand(app commandHost) {
 	events.Flag("filter", "Filter across the entire event using regular expressions").Default(".").StringVar(&c.bodyF)
 	events.Flag("js-metric", "Shows JetStream metric events (false)").UnNegatableBoolVar(&c.showJsMetrics)
 	events.Flag("js-advisory", "Shows advisory events (false)").UnNegatableBoolVar(&c.showJsAdvisories)
-	events.Flag("srv-advisory", "Shows NATS Server advisories (true)").Default("true").BoolVar(&c.showServerAdvisories)
+	events.Flag("js-audit", "Shows JetStream API audit events (false)").UnNegatableBoolVar(&c.showJsAudit)
+	events.Flag("audit-account", "Only show JetStream API audit events for a specific account").PlaceHolder("ACCOUNT").StringVar(&c.auditAccount)
+	events.Flag("audit-user", "Only show JetStream API audit events for a specific user").PlaceHolder("USER").StringVar(&c.auditUser)
+	events.Flag("audit-asset", "Only show JetStream API audit events for a specific Stream or Consumer").PlaceHolder("NAME").StringVar(&c.auditAsset)
+	events.Flag("srv-advisory", "Shows NATS Server advisories (true)").Default("true").IsSetByUser(&c.srvAdvisoriesSet).BoolVar(&c.showServerAdvisories)
 	events.Flag("subjects", "Show Advisories and Metrics received on specific subjects").PlaceHolder("SUBJECTS").StringsVar(&c.extraSubjects)
+	events.Flag("filter-expr", "Only show events matching an expression over type, account, subject and event").PlaceHolder("EXPR").StringVar(&c.filterExpr)
+	events.Flag("output", "Appends matching events to a file as newline delimited JSON").PlaceHolder("FILE").StringVar(&c.outputFile)
+	events.Flag("store-stream", "Stores matching events in a JetStream Stream, created if it does not exist").PlaceHolder("STREAM").StringVar(&c.storeStream)
+	events.Flag("store-prefix", "Subject prefix used when storing events in the Stream").Default("events").StringVar(&c.storePrefix)
 }
 
 func init() {
 	registerCommand("events", 7, configureEventsCommand)
 }
 
+// storedEvent is the format events are written to the output file in
+type storedEvent struct {
+	Time    time.Time       `json:"time"`
+	Subject string          `json:"subject"`
+	Type    string          `json:"type"`
+	Account string          `json:"account,omitempty"`
+	Event   json.RawMessage `json:"event"`
+}
+
+// eventAccount finds the account an event relates to in either the subject or the client information
+func eventAccount(subject string, event map[string]any) string {
+	parts := strings.Split(subject, ".")
+	if len(parts) > 3 && parts[0] == "$SYS" && parts[1] == "ACCOUNT" {
+		return parts[2]
+	}
+
+	client, ok := event["client"].(map[string]any)
+	if ok {
+		acc, _ := client["acc"].(string)
+		return acc
+	}
+
+	acc, _ := event["account"].(string)
+	return acc
+}
+
+func (c *eventsCmd) matchAndStore(m *nats.Msg) (bool, error) {
+	kind, _ := api.SchemaTypeForMessage(m.Data)
+
+	event := map[string]any{}
+	json.Unmarshal(m.Data, &event)
+	account := eventAccount(m.Subject, event)
+
+	if c.filterProgram != nil {
+		res, err := expr.Run(c.filterProgram, map[string]any{
+			"type":    kind,
+			"account": account,
+			"subject": m.Subject,
+			"event":   event,
+		})
+		if err != nil {
+			return false, fmt.Errorf("filter expression failed: %s", err)
+		}
+
+		match, ok := res.(bool)
+		if !ok || !match {
+			return false, nil
+		}
+	}
+
+	if c.output != nil {
+		j, err := json.Marshal(storedEvent{Time: time.Now().UTC(), Subject: m.Subject, Type: kind, Account: account, Event: m.Data})
+		if err != nil {
+			return true, err
+		}
+
+		c.Lock()
+		_, err = fmt.Fprintln(c.output, string(j))
+		c.Unlock()
+		if err != nil {
+			return true, fmt.Errorf("could not write to %s: %s", c.outputFile, err)
+		}
+	}
+
+	if c.js != nil {
+		msg := nats.NewMsg(fmt.Sprintf("%s.%s", c.storePrefix, strings.TrimPrefix(m.Subject, "$")))
+		msg.Data = m.Data
+		msg.Header.Set("Nats-Event-Subject", m.Subject)
+		if kind != "" {
+			msg.Header.Set("Nats-Event-Type", kind)
+		}
+		if account != "" {
+			msg.Header.Set("Nats-Event-Account", account)
+		}
+
+		_, err := c.js.PublishMsg(msg)
+		if err != nil {
+			return true, fmt.Errorf("could not store event in %s: %s", c.storeStream, err)
+		}
+	}
+
+	return true, nil
+}
+
+func (c *eventsCmd) prepareStore(mgr *jsm.Manager) error {
+	if c.outputFile != "" {
+		var err error
+		c.output, err = os.OpenFile(c.outputFile, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+		if err != nil {
+			return err
+		}
+	}
+
+	if c.storeStream == "" {
+		return nil
+	}
+
+	known, err := mgr.IsKnownStream(c.storeStream)
+	if err != nil {
+		return err
+	}
+
+	if !known {
+		c.Printf("Creating Stream %s capturing %s.>\n", c.storeStream, c.storePrefix)
+		_, err = mgr.NewStream(c.storeStream, jsm.Subjects(c.storePrefix+".>"), jsm.FileStorage())
+		if err != nil {
+			return fmt.Errorf("could not create stream %s: %s", c.storeStream, err)
+		}
+	}
+
+	_, c.js, err = prepareJSHelper()
+	return err
+}
+
 func (c *eventsCmd) handleNATSEvent(m *nats.Msg) {
 	if !c.bodyFRe.MatchString(strings.ToUpper(string(m.Data))) {
 		return
 	}
 
+	match, err := c.matchAndStore(m)
+	if err != nil {
+		log.Printf("%s", err)
+	}
+	if !match {
+		return
+	}
+
 	if c.json && !c.ce {
 		fmt.Println(string(m.Data))
 		return
@@ -112,13 +262,47 @@ func (c *eventsCmd) handleNATSEvent(m *nats.Msg) {
 		return nil
 	}
 
-	err := handle()
+	err = handle()
 	if err != nil {
 		fmt.Printf("Event error: %s\n\n", err)
 		fmt.Println(leftPad(string(m.Data), 10))
 	}
 }
 
+// handleAuditEvent applies the account, user and asset filters to JetStream API audit events
+func (c *eventsCmd) handleAuditEvent(m *nats.Msg) {
+	audit := jsadvisory.JetStreamAPIAuditV1{}
+	err := json.Unmarshal(m.Data, &audit)
+	if err != nil {
+		log.Printf("Invalid JetStream API audit event: %s", err)
+		return
+	}
+
+	if c.auditAccount != "" && audit.Client.Account != c.auditAccount {
+		return
+	}
+
+	if c.auditUser != "" && audit.Client.User != c.auditUser {
+		return
+	}
+
+	// assets are tokens in the API subject like $JS.API.CONSUMER.INFO.ORDERS.NEW
+	if c.auditAsset != "" {
+		found := false
+		for _, token := range strings.Split(audit.Subject, ".") {
+			if token == c.auditAsset {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return
+		}
+	}
+
+	c.handleNATSEvent(m)
+}
+
 func (c *eventsCmd) Printf(f string, arg ...any) {
 	if !c.json {
 		fmt.Printf(f, arg...)
@@ -130,23 +314,63 @@ func (c *eventsCmd) eventsAction(_ *fisk.ParseContext) error {
 		c.json = true
 	}
 
-	nc, _, err := prepareHelper("", natsOpts()...)
+	nc, mgr, err := prepareHelper("", natsOpts()...)
 	fisk.FatalIfError(err, "setup failed")
 
 	c.bodyFRe, err = regexp.Compile(strings.ToUpper(c.bodyF))
 	fisk.FatalIfError(err, "invalid body regular expression")
 
-	if !c.showAll && !c.showJsAdvisories && !c.showJsMetrics && !c.showServerAdvisories && len(c.extraSubjects) == 0 {
+	if c.filterExpr != "" {
+		c.filterProgram, err = expr.Compile(c.filterExpr, expr.AsBool(), expr.AllowUndefinedVariables())
+		fisk.FatalIfError(err, "invalid filter expression")
+	}
+
+	if c.storeStream != "" && c.storePrefix == "" {
+		return errors.New("a subject prefix is required when storing events")
+	}
+
+	err = c.prepareStore(mgr)
+	if err != nil {
+		return err
+	}
+	if c.output != nil {
+		defer c.output.Close()
+	}
+
+	if c.auditAccount != "" || c.auditUser != "" || c.auditAsset != "" {
+		c.showJsAudit = true
+	}
+
+	// when only auditing we do not also want the default server advisories
+	if c.showJsAudit && !c.srvAdvisoriesSet && !c.showAll {
+		c.showServerAdvisories = false
+	}
+
+	if !c.showAll && !c.showJsAdvisories && !c.showJsAudit && !c.showJsMetrics && !c.showServerAdvisories && len(c.extraSubjects) == 0 {
 		return fmt.Errorf("no events were chosen")
 	}
 
+	auditSubject := jsm.EventSubject(api.JSAuditAdvisory, opts.Config.JSEventPrefix())
+
 	if c.showJsAdvisories || c.showAll {
 		c.Printf("Listening for Advisories on %s.>\n", jsm.EventSubject(api.JSAdvisoryPrefix, opts.Config.JSEventPrefix()))
 		nc.Subscribe(fmt.Sprintf("%s.>", jsm.EventSubject(api.JSAdvisoryPrefix, opts.Config.JSEventPrefix())), func(m *nats.Msg) {
+			// audit events are handled by their own subscription
+			if c.showJsAudit && m.Subject == auditSubject {
+				return
+			}
+
 			c.handleNATSEvent(m)
 		})
 	}
 
+	if c.showJsAudit {
+		c.Printf("Listening for JetStream API audit events on %s\n", auditSubject)
+		nc.Subscribe(auditSubject, func(m *nats.Msg) {
+			c.handleAuditEvent(m)
+		})
+	}
+
 	if c.showJsMetrics || c.showAll {
 		c.Printf("Listening for Metrics on %s.>\n", jsm.EventSubject(api.JSMetricPrefix, opts.Config.JSEventPrefix()))
 		nc.Subscribe(fmt.Sprintf("%s.>", jsm.EventSubject(api.JSMetricPrefix, opts.Config.JSEventPrefix())), func(m *nats.Msg) {
@@ -0,0 +1,260 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/choria-io/fisk"
+	"github.com/nats-io/jsm.go/api"
+	"github.com/nats-io/nats.go"
+)
+
+// Exit codes used when running commands using Run, scripts can branch on these rather than parse errors.
+// Commands implementing Nagios compatible checks like server check keep using the Nagios exit codes.
+const (
+	// ExitOK indicates success
+	ExitOK = 0
+	// ExitUsage indicates invalid usage or a failure without a more specific code
+	ExitUsage = 1
+	// ExitConnection indicates the NATS Server could not be reached or the connection failed
+	ExitConnection = 2
+	// ExitNotFound indicates a requested item like a Stream, Consumer or Key does not exist
+	ExitNotFound = 3
+	// ExitValidation indicates invalid configuration or input was rejected
+	ExitValidation = 4
+	// ExitThreshold indicates a health or threshold check did not pass
+	ExitThreshold = 5
+)
+
+// exitError is an error that should terminate the process with a specific exit code
+type exitError struct {
+	code int
+	err  error
+}
+
+func (e *exitError) Error() string { return e.err.Error() }
+func (e *exitError) Unwrap() error { return e.err }
+
+// withExitCode annotates err with a specific exit code
+func withExitCode(code int, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	return &exitError{code: code, err: err}
+}
+
+// ExitCode determines the exit code for err based on the documented exit code scheme
+func ExitCode(err error) int {
+	if err == nil {
+		return ExitOK
+	}
+
+	var ee *exitError
+	if errors.As(err, &ee) {
+		return ee.code
+	}
+
+	var jsErr nats.JetStreamError
+	if errors.As(err, &jsErr) && jsErr.APIError() != nil {
+		if code := exitCodeForAPIStatus(jsErr.APIError().Code); code != ExitUsage {
+			return code
+		}
+	}
+
+	var apiErr api.ApiError
+	if errors.As(err, &apiErr) {
+		if code := exitCodeForAPIStatus(apiErr.Code); code != ExitUsage {
+			return code
+		}
+	}
+
+	var apiErrP *api.ApiError
+	if errors.As(err, &apiErrP) && apiErrP != nil {
+		if code := exitCodeForAPIStatus(apiErrP.Code); code != ExitUsage {
+			return code
+		}
+	}
+
+	var netErr net.Error
+	switch {
+	case errors.Is(err, nats.ErrNoServers), errors.Is(err, nats.ErrConnectionClosed), errors.Is(err, nats.ErrAuthorization),
+		errors.Is(err, nats.ErrTimeout), errors.Is(err, nats.ErrNoResponders), errors.Is(err, nats.ErrJetStreamNotEnabled),
+		errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr):
+		return ExitConnection
+
+	case errors.Is(err, nats.ErrStreamNotFound), errors.Is(err, nats.ErrConsumerNotFound), errors.Is(err, nats.ErrKeyNotFound),
+		errors.Is(err, nats.ErrBucketNotFound), errors.Is(err, nats.ErrObjectNotFound), errors.Is(err, os.ErrNotExist):
+		return ExitNotFound
+	}
+
+	return exitCodeForMessage(err.Error())
+}
+
+func exitCodeForAPIStatus(status int) int {
+	switch status {
+	case 404:
+		return ExitNotFound
+	case 400:
+		return ExitValidation
+	case 503:
+		return ExitConnection
+	default:
+		return ExitUsage
+	}
+}
+
+// exitCodeForMessage classifies errors that are only available as text, like those logged by fisk.FatalIfError
+func exitCodeForMessage(msg string) int {
+	msg = strings.ToLower(msg)
+
+	switch {
+	case strings.Contains(msg, "no servers available"), strings.Contains(msg, "connection refused"),
+		strings.Contains(msg, "authorization violation"), strings.Contains(msg, "i/o timeout"),
+		strings.Contains(msg, "nats: timeout"), strings.Contains(msg, "no responders"),
+		strings.Contains(msg, "jetstream not enabled"):
+		return ExitConnection
+
+	case strings.Contains(msg, "not found"), strings.Contains(msg, "does not exist"), strings.Contains(msg, "no such file"):
+		return ExitNotFound
+
+	case strings.Contains(msg, "validation failed"), strings.Contains(msg, "invalid configuration"):
+		return ExitValidation
+	}
+
+	return ExitUsage
+}
+
+// errorRecorder passes errors written by fisk through to w while keeping the last one to determine the exit code
+type errorRecorder struct {
+	w    io.Writer
+	last string
+	mu   sync.Mutex
+}
+
+func (r *errorRecorder) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	r.last = string(p)
+	r.mu.Unlock()
+
+	return r.w.Write(p)
+}
+
+func (r *errorRecorder) lastMessage() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.last
+}
+
+func (r *errorRecorder) exitCode() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return exitCodeForMessage(r.last)
+}
+
+// Run parses args and executes the selected command, terminating the process with an exit code
+// from the documented exit code scheme when the command fails
+func Run(app *fisk.Application, args []string) {
+	app.PreAction(func(pc *fisk.ParseContext) error {
+		err := setupTracing(pc)
+		if err != nil {
+			return err
+		}
+
+		startAudit(pc, args)
+
+		err = checkContextGuard(pc)
+		if err != nil {
+			return err
+		}
+
+		return setupQuietOutput()
+	})
+
+	// commands that fail using fisk.FatalIfError terminate the process from the global application
+	recorder := &errorRecorder{w: os.Stderr}
+	fisk.CommandLine.ErrorWriter(recorder)
+	fisk.CommandLine.Terminate(func(code int) {
+		stopPager()
+		if code == 0 {
+			finishAudit(nil)
+			stopTracing(nil)
+			os.Exit(ExitOK)
+		}
+
+		cmdErr := errors.New(strings.TrimSpace(recorder.lastMessage()))
+		finishAudit(cmdErr)
+		stopTracing(cmdErr)
+		os.Exit(recorder.exitCode())
+	})
+
+	configureCompletion(app)
+	args = pluginArgs(app, args)
+
+	if pattern, rest, ok := eachContextArgs(args); ok {
+		os.Exit(runEachContext(app, pattern, rest))
+	}
+
+	_, err := app.Parse(args)
+	stopPager()
+	finishAudit(err)
+	stopTracing(err)
+	if err == nil {
+		return
+	}
+
+	// no command was run, parse again to show the usage related to the error
+	if isUsageError(err) {
+		app.MustParseWithUsage(args)
+		os.Exit(ExitUsage)
+	}
+
+	app.Errorf("%v", err)
+	os.Exit(ExitCode(err))
+}
+
+func isUsageError(err error) bool {
+	for _, uerr := range []error{fisk.ErrSubCommandRequired, fisk.ErrExpectedKnownCommand, fisk.ErrCommandNotSpecified, fisk.ErrRequiredArgument, fisk.ErrRequiredFlag, fisk.ErrUnknownLongFlag, fisk.ErrUnknownShortFlag, fisk.ErrExpectedFlagArgument, fisk.ErrFlagCannotRepeat, fisk.ErrUnexpectedArgument} {
+		if errors.Is(err, uerr) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// setupQuietOutput discards standard output when --quiet is set, structured output is kept as it is requested explicitly
+func setupQuietOutput() error {
+	if !opts.Quiet || structuredOutput() {
+		return nil
+	}
+
+	devNull, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+	if err != nil {
+		return err
+	}
+
+	os.Stdout = devNull
+
+	return nil
+}
@@ -0,0 +1,45 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/nats-io/jsm.go/api"
+	"github.com/nats-io/nats.go"
+)
+
+func TestExitCode(t *testing.T) {
+	for _, tc := range []struct {
+		err    error
+		expect int
+	}{
+		{nil, ExitOK},
+		{errors.New("something failed"), ExitUsage},
+		{fmt.Errorf("setup failed: %w", nats.ErrNoServers), ExitConnection},
+		{fmt.Errorf("could not load: %w", nats.ErrStreamNotFound), ExitNotFound},
+		{nats.ErrKeyNotFound, ExitNotFound},
+		{fmt.Errorf("could not create Stream: %w", api.ApiError{Code: 400, ErrCode: 10065, Description: "subjects overlap with an existing stream"}), ExitValidation},
+		{api.ApiError{Code: 404, ErrCode: 10059, Description: "stream not found"}, ExitNotFound},
+		{withExitCode(ExitThreshold, errors.New("unhealthy")), ExitThreshold},
+		{errors.New("Validation Failed: invalid subjects"), ExitValidation},
+	} {
+		code := ExitCode(tc.err)
+		if code != tc.expect {
+			t.Fatalf("expected exit code %d for %v got %d", tc.expect, tc.err, code)
+		}
+	}
+}
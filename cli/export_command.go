@@ -0,0 +1,136 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/choria-io/fisk"
+	"github.com/ghodss/yaml"
+)
+
+type exportCmd struct {
+	all      bool
+	streams  []string
+	kvs      []string
+	objects  []string
+	output   string
+	json     bool
+	parallel int
+}
+
+func configureExportCommand(app commandHost) {
+	c := &exportCmd{}
+
+	help := `Exports the configuration of JetStream assets as manifests
+
+Each Stream, durable Consumer, Key-Value and Object Store bucket is
+written to its own file in the format used by nats apply.
+`
+
+	export := app.Command("export", help).Action(c.exportAction)
+	export.Flag("all", "Exports all Streams, Consumers and buckets").UnNegatableBoolVar(&c.all)
+	export.Flag("stream", "Exports a Stream and its Consumers (pass multiple times)").PlaceHolder("STREAM").StringsVar(&c.streams)
+	export.Flag("kv", "Exports a Key-Value bucket (pass multiple times)").PlaceHolder("BUCKET").StringsVar(&c.kvs)
+	export.Flag("object", "Exports an Object Store bucket (pass multiple times)").PlaceHolder("BUCKET").StringsVar(&c.objects)
+	export.Flag("output", "Directory to write manifests to").Required().PlaceHolder("DIR").StringVar(&c.output)
+	export.Flag("json", "Writes manifests in JSON format rather than YAML").UnNegatableBoolVar(&c.json)
+	addParallelFlag(export, &c.parallel)
+	addCheat("export", export)
+}
+
+func init() {
+	registerCommand("export", 6, configureExportCommand)
+}
+
+func (c *exportCmd) exportAction(_ *fisk.ParseContext) error {
+	if c.all {
+		c.streams, c.kvs, c.objects = []string{}, []string{}, []string{}
+	}
+
+	if c.streams == nil && c.kvs == nil && c.objects == nil {
+		return withExitCode(ExitValidation, fmt.Errorf("select assets to export using --all, --stream, --kv or --object"))
+	}
+
+	_, mgr, err := prepareHelper("", natsOpts()...)
+	if err != nil {
+		return err
+	}
+
+	manifests, err := exportManifests(mgr, c.streams, c.kvs, c.objects, c.parallel)
+	if err != nil {
+		return err
+	}
+
+	if len(manifests) == 0 {
+		fmt.Println("No assets found to export")
+		return nil
+	}
+
+	err = os.MkdirAll(c.output, 0700)
+	if err != nil {
+		return err
+	}
+
+	for _, m := range manifests {
+		file, body, err := c.renderManifest(m)
+		if err != nil {
+			return fmt.Errorf("could not export %s: %w", m.id(), err)
+		}
+
+		err = os.WriteFile(filepath.Join(c.output, file), body, 0600)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("Exported %s to %s\n", m.id(), filepath.Join(c.output, file))
+	}
+
+	return nil
+}
+
+// renderManifest determines the file name for a manifest and its content, asset names can not hold
+// path separators or dots so the names are unique
+func (c *exportCmd) renderManifest(m *manifest) (string, []byte, error) {
+	var name string
+	switch m.Kind {
+	case manifestStream:
+		name = "stream_" + m.Name
+	case manifestConsumer:
+		name = fmt.Sprintf("consumer_%s.%s", m.Stream, m.Name)
+	case manifestKeyValue:
+		name = "kv_" + m.Name
+	case manifestObjectStore:
+		name = "object_" + m.Name
+	}
+
+	j, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return "", nil, err
+	}
+
+	if c.json {
+		return name + ".json", append(j, '\n'), nil
+	}
+
+	y, err := yaml.JSONToYAML(j)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return name + ".yaml", y, nil
+}
@@ -0,0 +1,364 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/choria-io/fisk"
+	"github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats.go"
+)
+
+type gatewayCmd struct {
+	listen   string
+	allow    []string
+	token    string
+	maxBody  int64
+	readOnly bool
+
+	nc *nats.Conn
+}
+
+// gatewayMsg is a message delivered to HTTP clients by the subscribe endpoint
+type gatewayMsg struct {
+	Subject  string      `json:"subject"`
+	Reply    string      `json:"reply,omitempty"`
+	Header   nats.Header `json:"headers,omitempty"`
+	Data     string      `json:"data"`
+	Encoding string      `json:"encoding,omitempty"`
+}
+
+func configureGatewayCommand(app commandHost) {
+	c := &gatewayCmd{}
+
+	help := `HTTP to NATS gateway
+
+Serves a small HTTP API interacting with NATS using the selected context,
+allowing systems that only speak HTTP, and quick tests using curl, to
+publish, send requests and subscribe:
+
+   POST /publish/SUBJECT     publishes the request body
+   GET  /request/SUBJECT     sends a request, POST sends the body, and
+                             responds with the reply
+   GET  /subscribe/SUBJECT   streams messages as Server-Sent Events
+
+Headers are added to messages using header=Name:Value query parameters,
+requests wait for a reply for the duration in the timeout parameter and
+subscriptions join a queue group given in the queue parameter.
+`
+
+	gw := app.Command("gateway", help)
+	addCheat("gateway", gw)
+
+	serve := publishing(gw.Command("serve", "Serves the HTTP gateway").Action(c.serveAction))
+	serve.Flag("listen", "The address to listen on").Default("localhost:8080").StringVar(&c.listen)
+	serve.Flag("allow", "Only allow access to subjects matching these patterns (pass multiple times)").PlaceHolder("SUBJECT").StringsVar(&c.allow)
+	serve.Flag("token", "Require HTTP clients to send this bearer token").Envar("NATS_GATEWAY_TOKEN").PlaceHolder("TOKEN").StringVar(&c.token)
+	serve.Flag("max-body", "Maximum size of request bodies in bytes").Default("1048576").Int64Var(&c.maxBody)
+	serve.Flag("read-only", "Only allow subscribing").UnNegatableBoolVar(&c.readOnly)
+}
+
+func init() {
+	registerCommand("gateway", 8, configureGatewayCommand)
+}
+
+// subjectIsSubset determines if every subject matched by subject is also matched by filter
+func subjectIsSubset(subject string, filter string) bool {
+	stoks := strings.Split(subject, ".")
+	ftoks := strings.Split(filter, ".")
+
+	for i, ft := range ftoks {
+		if i >= len(stoks) {
+			return false
+		}
+
+		st := stoks[i]
+		switch {
+		case ft == ">":
+			return true
+		case ft == "*":
+			if st == ">" {
+				return false
+			}
+		case st != ft:
+			return false
+		}
+	}
+
+	return len(stoks) == len(ftoks)
+}
+
+func (c *gatewayCmd) allowed(subject string) bool {
+	if len(c.allow) == 0 {
+		return true
+	}
+
+	for _, filter := range c.allow {
+		if subjectIsSubset(subject, filter) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (c *gatewayCmd) httpError(w http.ResponseWriter, code int, format string, a ...any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(map[string]string{"error": fmt.Sprintf(format, a...)})
+}
+
+// handler wraps endpoints with authentication, subject validation and access logging
+func (c *gatewayCmd) handler(prefix string, wildcards bool, methods []string, cb func(w http.ResponseWriter, r *http.Request, subject string)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if opts.Trace {
+			log.Printf("%s %s %s", r.RemoteAddr, r.Method, r.URL.Path)
+		}
+
+		if c.token != "" {
+			auth := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if subtle.ConstantTimeCompare([]byte(auth), []byte(c.token)) != 1 {
+				c.httpError(w, http.StatusUnauthorized, "invalid token")
+				return
+			}
+		}
+
+		methodOk := false
+		for _, m := range methods {
+			if r.Method == m {
+				methodOk = true
+			}
+		}
+		if !methodOk {
+			w.Header().Set("Allow", strings.Join(methods, ", "))
+			c.httpError(w, http.StatusMethodNotAllowed, "method %s not allowed", r.Method)
+			return
+		}
+
+		subject := strings.TrimPrefix(r.URL.Path, prefix)
+		valid := server.IsValidLiteralSubject(subject)
+		if wildcards {
+			valid = server.IsValidSubject(subject)
+		}
+		if !valid {
+			c.httpError(w, http.StatusBadRequest, "invalid subject %q", subject)
+			return
+		}
+
+		if !c.allowed(subject) {
+			c.httpError(w, http.StatusForbidden, "access to subject %s is not allowed", subject)
+			return
+		}
+
+		cb(w, r, subject)
+	}
+}
+
+// prepareMsg creates a message from the HTTP request body and header query parameters
+func (c *gatewayCmd) prepareMsg(w http.ResponseWriter, r *http.Request, subject string) (*nats.Msg, error) {
+	msg := nats.NewMsg(subject)
+
+	if r.Body != nil {
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, c.maxBody))
+		if err != nil {
+			return nil, err
+		}
+		msg.Data = body
+	}
+
+	err := parseStringsToMsgHeader(r.URL.Query()["header"], 0, msg)
+	if err != nil {
+		return nil, err
+	}
+
+	return msg, nil
+}
+
+func (c *gatewayCmd) publishHandler(w http.ResponseWriter, r *http.Request, subject string) {
+	msg, err := c.prepareMsg(w, r, subject)
+	if err != nil {
+		c.httpError(w, http.StatusBadRequest, "%v", err)
+		return
+	}
+
+	err = c.nc.PublishMsg(msg)
+	if err == nil {
+		err = c.nc.FlushTimeout(opts.Timeout)
+	}
+	if err != nil {
+		c.httpError(w, http.StatusBadGateway, "publish failed: %v", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]any{"subject": subject, "bytes": len(msg.Data)})
+}
+
+func (c *gatewayCmd) requestHandler(w http.ResponseWriter, r *http.Request, subject string) {
+	timeout := opts.Timeout
+	if t := r.URL.Query().Get("timeout"); t != "" {
+		var err error
+		timeout, err = parseDurationString(t)
+		if err != nil {
+			c.httpError(w, http.StatusBadRequest, "invalid timeout: %v", err)
+			return
+		}
+	}
+
+	msg, err := c.prepareMsg(w, r, subject)
+	if err != nil {
+		c.httpError(w, http.StatusBadRequest, "%v", err)
+		return
+	}
+
+	rctx, cancel := context.WithTimeout(r.Context(), timeout)
+	defer cancel()
+
+	res, err := c.nc.RequestMsgWithContext(rctx, msg)
+	switch {
+	case errors.Is(err, nats.ErrNoResponders):
+		c.httpError(w, http.StatusServiceUnavailable, "no responders available for %s", subject)
+		return
+	case errors.Is(err, context.DeadlineExceeded) || errors.Is(err, nats.ErrTimeout):
+		c.httpError(w, http.StatusGatewayTimeout, "no reply received within %v", timeout)
+		return
+	case err != nil:
+		c.httpError(w, http.StatusBadGateway, "request failed: %v", err)
+		return
+	}
+
+	for h, vals := range res.Header {
+		for _, v := range vals {
+			w.Header().Add("Nats-Header-"+h, v)
+		}
+	}
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Write(res.Data)
+}
+
+func (c *gatewayCmd) subscribeHandler(w http.ResponseWriter, r *http.Request, subject string) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		c.httpError(w, http.StatusInternalServerError, "streaming is not supported")
+		return
+	}
+
+	msgs := make(chan *nats.Msg, 1000)
+	sub, err := c.nc.ChanQueueSubscribe(subject, r.URL.Query().Get("queue"), msgs)
+	if err != nil {
+		c.httpError(w, http.StatusBadGateway, "subscribe failed: %v", err)
+		return
+	}
+	defer sub.Unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	keepalive := time.NewTicker(30 * time.Second)
+	defer keepalive.Stop()
+
+	for id := 1; ; id++ {
+		select {
+		case m := <-msgs:
+			gm := gatewayMsg{Subject: m.Subject, Reply: m.Reply, Header: m.Header}
+			if utf8.Valid(m.Data) {
+				gm.Data = string(m.Data)
+			} else {
+				gm.Data = base64.StdEncoding.EncodeToString(m.Data)
+				gm.Encoding = "base64"
+			}
+
+			j, err := json.Marshal(gm)
+			if err != nil {
+				continue
+			}
+
+			_, err = fmt.Fprintf(w, "id: %d\nevent: message\ndata: %s\n\n", id, j)
+			if err != nil {
+				return
+			}
+			flusher.Flush()
+
+		case <-keepalive.C:
+			_, err = fmt.Fprint(w, ": keepalive\n\n")
+			if err != nil {
+				return
+			}
+			flusher.Flush()
+
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+func (c *gatewayCmd) serveAction(_ *fisk.ParseContext) error {
+	for _, filter := range c.allow {
+		if !server.IsValidSubject(filter) {
+			return withExitCode(ExitValidation, fmt.Errorf("invalid allowed subject %q", filter))
+		}
+	}
+
+	var err error
+	c.nc, err = newNatsConn("", natsOpts()...)
+	if err != nil {
+		return err
+	}
+	defer c.nc.Close()
+
+	mux := http.NewServeMux()
+	if !c.readOnly {
+		mux.HandleFunc("/publish/", c.handler("/publish/", false, []string{http.MethodPost, http.MethodPut}, c.publishHandler))
+		mux.HandleFunc("/request/", c.handler("/request/", false, []string{http.MethodGet, http.MethodPost}, c.requestHandler))
+	}
+	mux.HandleFunc("/subscribe/", c.handler("/subscribe/", true, []string{http.MethodGet}, c.subscribeHandler))
+
+	srv := &http.Server{Addr: c.listen, Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+
+	go func() {
+		<-ctx.Done()
+		sctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		srv.Shutdown(sctx)
+	}()
+
+	log.Printf("Serving the NATS gateway for %s on http://%s", c.nc.ConnectedUrlRedacted(), c.listen)
+	host, _, _ := net.SplitHostPort(c.listen)
+	if c.token == "" && !isLoopbackHost(host) {
+		log.Printf("WARNING: the gateway is listening on a non local address without requiring a token")
+	}
+
+	err = srv.ListenAndServe()
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+
+	return err
+}
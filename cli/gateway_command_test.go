@@ -0,0 +1,43 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"testing"
+)
+
+func TestSubjectIsSubset(t *testing.T) {
+	for _, tc := range []struct {
+		subject string
+		filter  string
+		expect  bool
+	}{
+		{"orders.new", "orders.>", true},
+		{"orders.>", "orders.>", true},
+		{"orders.*", "orders.>", true},
+		{"orders.new", "orders.*", true},
+		{"orders.*", "orders.*", true},
+		{"orders.>", "orders.*", false},
+		{"orders.new.eu", "orders.*", false},
+		{"orders", "orders.>", false},
+		{"orders.new", "orders.new", true},
+		{"orders.new", "orders.old", false},
+		{"orders.new", ">", true},
+		{"*.new", "orders.new", false},
+	} {
+		if subjectIsSubset(tc.subject, tc.filter) != tc.expect {
+			t.Fatalf("expected %s subset of %s to be %v", tc.subject, tc.filter, tc.expect)
+		}
+	}
+}
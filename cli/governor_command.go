@@ -0,0 +1,318 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"fmt"
+	"os/signal"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/choria-io/fisk"
+	"github.com/nats-io/jsm.go"
+	"github.com/nats-io/jsm.go/api"
+)
+
+const (
+	governorStreamPrefix = "GOVERNOR_"
+
+	// governorWaitHeader is set by holders that record how long they waited to obtain their slot
+	governorWaitHeader = "Nats-Governor-Wait"
+)
+
+type governorCmd struct {
+	name  string
+	slot  uint64
+	watch time.Duration
+	force bool
+	json  bool
+}
+
+// governorSlot is a slot held in a governor, the slot is the sequence of the message the holder stored to obtain it
+type governorSlot struct {
+	Slot     uint64        `json:"slot"`
+	Holder   string        `json:"holder"`
+	Acquired time.Time     `json:"acquired"`
+	Held     time.Duration `json:"held"`
+	Waited   time.Duration `json:"waited,omitempty"`
+}
+
+// governorState is the configuration of a governor and the slots currently held
+type governorState struct {
+	Name        string          `json:"name"`
+	Stream      string          `json:"stream"`
+	Limit       int64           `json:"limit"`
+	Timeout     time.Duration   `json:"timeout"`
+	Slots       []*governorSlot `json:"slots"`
+	LongestWait time.Duration   `json:"longest_wait"`
+	AverageWait time.Duration   `json:"average_wait"`
+}
+
+func configureGovernorCommand(app commandHost) {
+	c := &governorCmd{}
+
+	help := `Inspects and manages governors limiting concurrent execution
+
+A governor is a Stream named GOVERNOR_<name> that holds at most one
+message per slot, holders store a message containing their name to
+obtain a slot and remove it to release the slot. Slots of holders that
+crashed are released after the governor timeout.
+`
+
+	gov := app.Command("governor", help)
+	addCheat("governor", gov)
+
+	report := gov.Command("report", "Reports slot usage, the longest holders and their wait times").Action(c.reportAction)
+	report.Arg("name", "The name of the governor").Required().StringVar(&c.name)
+	report.Flag("watch", "Refresh the report at this interval until interrupted").PlaceHolder("INTERVAL").DurationVar(&c.watch)
+	addJSONOutputFlag(report, &c.json)
+
+	evict := mutating(gov.Command("evict", "Forcibly releases a slot held in a governor").Action(c.evictAction))
+	evict.Arg("name", "The name of the governor").Required().StringVar(&c.name)
+	evict.Arg("slot", "The slot to release as shown in the report").Required().Uint64Var(&c.slot)
+	evict.Flag("force", "Act without confirmation").Short('f').UnNegatableBoolVar(&c.force)
+}
+
+func init() {
+	registerCommand("governor", 6, configureGovernorCommand)
+}
+
+func governorStreamName(name string) (string, error) {
+	if name == "" || strings.ContainsAny(name, ".*> \t") {
+		return "", withExitCode(ExitValidation, fmt.Errorf("invalid governor name %q", name))
+	}
+
+	return governorStreamPrefix + name, nil
+}
+
+// newGovernorSlot parses a message stored to obtain a slot
+func newGovernorSlot(msg *api.StoredMsg, now time.Time) *governorSlot {
+	slot := &governorSlot{
+		Slot:     msg.Sequence,
+		Holder:   string(msg.Data),
+		Acquired: msg.Time,
+		Held:     now.Sub(msg.Time),
+	}
+
+	if len(msg.Header) > 0 {
+		hdr, err := decodeHeadersMsg(msg.Header)
+		if err == nil {
+			slot.Waited, _ = time.ParseDuration(hdr.Get(governorWaitHeader))
+		}
+	}
+
+	return slot
+}
+
+// summarize sorts the slots longest held first and calculates the wait times of their holders
+func (s *governorState) summarize() {
+	sort.SliceStable(s.Slots, func(i, j int) bool {
+		return s.Slots[i].Held > s.Slots[j].Held
+	})
+
+	s.LongestWait = 0
+	s.AverageWait = 0
+	if len(s.Slots) == 0 {
+		return
+	}
+
+	var total time.Duration
+	for _, slot := range s.Slots {
+		total += slot.Waited
+		if slot.Waited > s.LongestWait {
+			s.LongestWait = slot.Waited
+		}
+	}
+	s.AverageWait = total / time.Duration(len(s.Slots))
+}
+
+func (c *governorCmd) loadStream() (*jsm.Stream, error) {
+	stream, err := governorStreamName(c.name)
+	if err != nil {
+		return nil, err
+	}
+
+	_, mgr, err := prepareHelper("", natsOpts()...)
+	if err != nil {
+		return nil, err
+	}
+
+	known, err := mgr.IsKnownStream(stream)
+	if err != nil {
+		return nil, err
+	}
+	if !known {
+		return nil, withExitCode(ExitNotFound, fmt.Errorf("unknown governor %s", c.name))
+	}
+
+	return mgr.LoadStream(stream)
+}
+
+// governorState loads the slots held in the governor stored in str
+func (c *governorCmd) governorState(str *jsm.Stream) (*governorState, error) {
+	cfg := str.Configuration()
+	state := &governorState{
+		Name:    c.name,
+		Stream:  str.Name(),
+		Limit:   cfg.MaxMsgs,
+		Timeout: cfg.MaxAge,
+		Slots:   []*governorSlot{},
+	}
+
+	nfo, err := str.State(api.JSApiStreamInfoRequest{DeletedDetails: true})
+	if err != nil {
+		return nil, err
+	}
+
+	if nfo.Msgs > 0 {
+		released := make(map[uint64]bool, len(nfo.Deleted))
+		for _, seq := range nfo.Deleted {
+			released[seq] = true
+		}
+
+		now := time.Now()
+		for seq := nfo.FirstSeq; seq <= nfo.LastSeq && uint64(len(state.Slots)) < nfo.Msgs; seq++ {
+			if released[seq] {
+				continue
+			}
+
+			msg, err := str.ReadMessage(seq)
+			if err != nil {
+				// the slot was released or timed out since the state was loaded
+				continue
+			}
+
+			state.Slots = append(state.Slots, newGovernorSlot(msg, now))
+		}
+	}
+
+	state.summarize()
+
+	return state, nil
+}
+
+func (c *governorCmd) renderReport(state *governorState) {
+	fmt.Printf("Governor %s: %d of %d slots used, %d free\n", state.Name, len(state.Slots), state.Limit, state.Limit-int64(len(state.Slots)))
+	fmt.Println()
+
+	if len(state.Slots) == 0 {
+		fmt.Println("No slots are held")
+		return
+	}
+
+	fmt.Printf("Longest Holder: %s for %s\n", state.Slots[0].Holder, humanizeDuration(state.Slots[0].Held.Round(time.Second)))
+	fmt.Printf("  Longest Wait: %s\n", humanizeDuration(state.LongestWait.Round(time.Millisecond)))
+	fmt.Printf("  Average Wait: %s\n", humanizeDuration(state.AverageWait.Round(time.Millisecond)))
+	fmt.Println()
+
+	table := newTableWriter(fmt.Sprintf("Slots held in Governor %s", state.Name))
+	table.AddHeaders("Slot", "Holder", "Acquired", "Held", "Waited", "Expires")
+	for _, slot := range state.Slots {
+		expires := "never"
+		if state.Timeout > 0 {
+			expires = humanizeDuration((state.Timeout - slot.Held).Round(time.Second))
+		}
+
+		table.AddRow(
+			slot.Slot,
+			slot.Holder,
+			slot.Acquired.Local().Format(time.RFC3339),
+			humanizeDuration(slot.Held.Round(time.Second)),
+			humanizeDuration(slot.Waited.Round(time.Millisecond)),
+			expires)
+	}
+	fmt.Println(table.Render())
+}
+
+func (c *governorCmd) reportAction(_ *fisk.ParseContext) error {
+	str, err := c.loadStream()
+	if err != nil {
+		return err
+	}
+
+	report := func() error {
+		state, err := c.governorState(str)
+		if err != nil {
+			return err
+		}
+
+		if c.json {
+			return printJSON(state)
+		}
+
+		c.renderReport(state)
+
+		return nil
+	}
+
+	if c.watch <= 0 {
+		return report()
+	}
+
+	ctx, cancel := signal.NotifyContext(ctx, syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	ticker := time.NewTicker(c.watch)
+	defer ticker.Stop()
+
+	for {
+		clearScreen()
+
+		err := report()
+		if err != nil {
+			return err
+		}
+
+		fmt.Println()
+		fmt.Printf("Updated %s, refreshing every %v\n", time.Now().Format("15:04:05"), c.watch)
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+func (c *governorCmd) evictAction(_ *fisk.ParseContext) error {
+	str, err := c.loadStream()
+	if err != nil {
+		return err
+	}
+
+	msg, err := str.ReadMessage(c.slot)
+	if err != nil {
+		return withExitCode(ExitNotFound, fmt.Errorf("slot %d is not held in governor %s", c.slot, c.name))
+	}
+
+	if !c.force {
+		ok, err := askConfirmation(fmt.Sprintf("Really release slot %d held by %s since %s", c.slot, msg.Data, msg.Time.Local().Format(time.RFC3339)), false)
+		fisk.FatalIfError(err, "could not obtain confirmation")
+
+		if !ok {
+			return nil
+		}
+	}
+
+	err = str.DeleteMessage(c.slot)
+	if err != nil {
+		return fmt.Errorf("could not release slot %d: %w", c.slot, err)
+	}
+
+	fmt.Printf("Released slot %d held by %s in Governor %s\n", c.slot, msg.Data, c.name)
+
+	return nil
+}
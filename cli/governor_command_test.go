@@ -0,0 +1,80 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/nats-io/jsm.go/api"
+)
+
+func TestGovernorStreamName(t *testing.T) {
+	stream, err := governorStreamName("cron")
+	assertNoError(t, err)
+	if stream != "GOVERNOR_cron" {
+		t.Fatalf("invalid stream name %q", stream)
+	}
+
+	for _, name := range []string{"", "cron.daily", "cron*", "cron>", "cron daily"} {
+		_, err = governorStreamName(name)
+		if err == nil {
+			t.Fatalf("expected %q to be invalid", name)
+		}
+	}
+}
+
+func TestGovernorStateSummarize(t *testing.T) {
+	now := time.Now()
+	slot := func(seq uint64, held time.Duration, waited string) *governorSlot {
+		msg := &api.StoredMsg{Sequence: seq, Data: []byte(fmt.Sprintf("host%d", seq)), Time: now.Add(-held)}
+		if waited != "" {
+			msg.Header = []byte(fmt.Sprintf("NATS/1.0\r\n%s: %s\r\n\r\n", governorWaitHeader, waited))
+		}
+
+		return newGovernorSlot(msg, now)
+	}
+
+	state := &governorState{Slots: []*governorSlot{
+		slot(3, time.Minute, "1s"),
+		slot(1, time.Hour, "3s"),
+		slot(2, time.Second, ""),
+	}}
+	state.summarize()
+
+	for i, expect := range []uint64{1, 3, 2} {
+		if state.Slots[i].Slot != expect {
+			t.Fatalf("expected slot %d at %d got %d", expect, i, state.Slots[i].Slot)
+		}
+	}
+	if state.Slots[0].Holder != "host1" || state.Slots[0].Held != time.Hour {
+		t.Fatalf("invalid longest holder %+v", state.Slots[0])
+	}
+	if state.Slots[2].Waited != 0 {
+		t.Fatalf("expected no wait without a header")
+	}
+	if state.LongestWait != 3*time.Second {
+		t.Fatalf("invalid longest wait %v", state.LongestWait)
+	}
+	if state.AverageWait != 4*time.Second/3 {
+		t.Fatalf("invalid average wait %v", state.AverageWait)
+	}
+
+	state = &governorState{}
+	state.summarize()
+	if state.LongestWait != 0 || state.AverageWait != 0 {
+		t.Fatalf("expected no waits without slots")
+	}
+}
@@ -0,0 +1,250 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/choria-io/fisk"
+	"github.com/dustin/go-humanize"
+	"github.com/nats-io/nats.go"
+)
+
+type kvCloneCmd struct {
+	source              string
+	dest                string
+	history             uint
+	context             string
+	replicas            uint
+	storage             string
+	description         string
+	maxBucketSizeString string
+	force               bool
+}
+
+func configureKVCloneCommand(kv *fisk.CmdClause) {
+	c := &kvCloneCmd{}
+
+	help := `Creates a copy of a bucket and its values
+
+The new bucket is created with the settings of the source bucket unless
+overridden, republish, mirror and source settings are not copied. Only
+current values are copied unless --history is given in which case up to
+that many historic values are copied for every key.
+
+Revisions and creation times of values are not preserved.
+
+  nats kv clone CONFIG CONFIG_STAGING --to-context staging
+`
+
+	clone := mutating(kv.Command("clone", help).Alias("cp").Action(c.cloneAction))
+	clone.Arg("source", "The bucket to copy").Required().HintAction(kvBucketNamesHint).StringVar(&c.source)
+	clone.Arg("destination", "The bucket to create").Required().StringVar(&c.dest)
+	clone.Flag("history", "How many historic values to copy per key").Default("1").UintVar(&c.history)
+	clone.Flag("to-context", "Creates the bucket using a different context").HintAction(contextNamesHint).PlaceHolder("NAME").StringVar(&c.context)
+	clone.Flag("replicas", "Overrides the number of replicas of the new bucket").UintVar(&c.replicas)
+	clone.Flag("storage", "Overrides the storage backend of the new bucket (file, memory)").EnumVar(&c.storage, "file", "f", "memory", "m")
+	clone.Flag("description", "Overrides the description of the new bucket").StringVar(&c.description)
+	clone.Flag("max-bucket-size", "Overrides the maximum size of the new bucket").PlaceHolder("BYTES").StringVar(&c.maxBucketSizeString)
+	clone.Flag("force", "Act without confirmation").Short('f').UnNegatableBoolVar(&c.force)
+}
+
+func (c *kvCloneCmd) cloneAction(_ *fisk.ParseContext) error {
+	if c.history == 0 || c.history > nats.KeyValueMaxHistory {
+		return withExitCode(ExitValidation, fmt.Errorf("history has to be between 1 and %d", nats.KeyValueMaxHistory))
+	}
+
+	if c.source == c.dest && c.context == "" {
+		return withExitCode(ExitValidation, fmt.Errorf("source and destination buckets cannot be the same"))
+	}
+
+	_, sjs, err := prepareJSHelper()
+	if err != nil {
+		return err
+	}
+
+	src, err := sjs.KeyValue(c.source)
+	if err != nil {
+		return fmt.Errorf("could not load bucket %s: %w", c.source, err)
+	}
+
+	cfg, err := c.destinationConfig(src)
+	if err != nil {
+		return err
+	}
+
+	djs := sjs
+	if c.context != "" {
+		nc, _, js, err := prepareContextHelper(c.context)
+		if err != nil {
+			return err
+		}
+		defer nc.Close()
+
+		djs = js
+	}
+
+	_, err = djs.KeyValue(c.dest)
+	switch {
+	case err == nil:
+		return fmt.Errorf("bucket %s already exist", c.dest)
+	case !errors.Is(err, nats.ErrBucketNotFound):
+		return err
+	}
+
+	keys, err := src.Keys()
+	if err != nil && !errors.Is(err, nats.ErrNoKeysFound) {
+		return err
+	}
+
+	if !c.force {
+		target := "the current context"
+		if c.context != "" {
+			target = "context " + c.context
+		}
+
+		ok, err := askConfirmation(fmt.Sprintf("Really clone %s with %s keys to %s in %s", c.source, humanize.Comma(int64(len(keys))), c.dest, target), false)
+		if err != nil {
+			return err
+		}
+
+		if !ok {
+			return nil
+		}
+	}
+
+	dest, err := djs.CreateKeyValue(cfg)
+	if err != nil {
+		return err
+	}
+
+	var copied int
+	for _, key := range keys {
+		n, err := c.copyKey(src, dest, key)
+		if err != nil {
+			return fmt.Errorf("copying key %s failed: %w", key, err)
+		}
+		copied += n
+	}
+
+	fmt.Printf("Cloned %s to %s copying %s values for %s keys\n", c.source, c.dest, humanize.Comma(int64(copied)), humanize.Comma(int64(len(keys))))
+
+	return nil
+}
+
+// destinationConfig creates the configuration for the new bucket from the source bucket and overrides
+func (c *kvCloneCmd) destinationConfig(src nats.KeyValue) (*nats.KeyValueConfig, error) {
+	status, err := src.Status()
+	if err != nil {
+		return nil, err
+	}
+
+	bs, ok := status.(*nats.KeyValueBucketStatus)
+	if !ok {
+		return nil, fmt.Errorf("unsupported bucket backend %s", status.BackingStore())
+	}
+
+	scfg := bs.StreamInfo().Config
+
+	cfg := &nats.KeyValueConfig{
+		Bucket:       c.dest,
+		Description:  scfg.Description,
+		MaxValueSize: scfg.MaxMsgSize,
+		History:      uint8(scfg.MaxMsgsPerSubject),
+		TTL:          scfg.MaxAge,
+		MaxBytes:     scfg.MaxBytes,
+		Storage:      scfg.Storage,
+		Replicas:     scfg.Replicas,
+		Placement:    scfg.Placement,
+	}
+
+	// the placement of the source rarely makes sense in another account or cluster
+	if c.context != "" {
+		cfg.Placement = nil
+	}
+
+	if uint8(c.history) > cfg.History {
+		cfg.History = uint8(c.history)
+	}
+
+	if c.replicas > 0 {
+		cfg.Replicas = int(c.replicas)
+	}
+
+	if c.storage != "" {
+		cfg.Storage = nats.FileStorage
+		if strings.HasPrefix(c.storage, "m") {
+			cfg.Storage = nats.MemoryStorage
+		}
+	}
+
+	if c.description != "" {
+		cfg.Description = c.description
+	}
+
+	if c.maxBucketSizeString != "" {
+		cfg.MaxBytes, err = parseStringAsBytes(c.maxBucketSizeString)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return cfg, nil
+}
+
+// copyKey stores up to history values of key in dest in their original order, returns the number of values copied
+func (c *kvCloneCmd) copyKey(src nats.KeyValue, dest nats.KeyValue, key string) (int, error) {
+	var entries []nats.KeyValueEntry
+
+	if c.history == 1 {
+		entry, err := src.Get(key)
+		if errors.Is(err, nats.ErrKeyNotFound) {
+			return 0, nil
+		}
+		if err != nil {
+			return 0, err
+		}
+		entries = append(entries, entry)
+	} else {
+		hist, err := src.History(key)
+		if err != nil {
+			return 0, err
+		}
+		if len(hist) > int(c.history) {
+			hist = hist[len(hist)-int(c.history):]
+		}
+		entries = hist
+	}
+
+	copied := 0
+	for _, entry := range entries {
+		var err error
+
+		switch entry.Operation() {
+		case nats.KeyValuePut:
+			_, err = dest.Put(key, entry.Value())
+		case nats.KeyValueDelete, nats.KeyValuePurge:
+			err = dest.Delete(key)
+		}
+		if err != nil {
+			return copied, err
+		}
+
+		copied++
+	}
+
+	return copied, nil
+}
@@ -14,6 +14,7 @@
 package cli
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
 	"io"
@@ -27,6 +28,7 @@ import (
 	"github.com/choria-io/fisk"
 	"github.com/dustin/go-humanize"
 	"github.com/fatih/color"
+	"github.com/google/go-cmp/cmp"
 	"github.com/nats-io/jsm.go"
 	"github.com/nats-io/nats.go"
 )
@@ -57,6 +59,8 @@ type kvCommand struct {
 	repubHeadersOnly      bool
 	mirror                string
 	mirrorDomain          string
+	json                  bool
+	revertTime            string
 }
 
 func configureKVCommand(app commandHost) {
@@ -71,7 +75,7 @@ for an indefinite period or a per-bucket configured TTL.
 	kv := app.Command("kv", help)
 	addCheat("kv", kv)
 
-	add := kv.Command("add", "Adds a new KV Store Bucket").Alias("new").Action(c.addAction)
+	add := mutating(kv.Command("add", "Adds a new KV Store Bucket").Alias("new").Action(c.addAction))
 	add.Arg("bucket", "The bucket to act on").Required().StringVar(&c.bucket)
 	add.Flag("history", "How many historic values to keep per key").Default("1").Uint64Var(&c.history)
 	add.Flag("ttl", "How long to keep values for").DurationVar(&c.ttl)
@@ -90,64 +94,72 @@ for an indefinite period or a per-bucket configured TTL.
 
 	add.PreAction(c.parseLimitStrings)
 
-	put := kv.Command("put", "Puts a value into a key").Action(c.putAction)
-	put.Arg("bucket", "The bucket to act on").Required().StringVar(&c.bucket)
+	put := mutating(kv.Command("put", "Puts a value into a key").Action(c.putAction))
+	put.Arg("bucket", "The bucket to act on").HintAction(kvBucketNamesHint).Required().StringVar(&c.bucket)
 	put.Arg("key", "The key to act on").Required().StringVar(&c.key)
 	put.Arg("value", "The value to store, when empty reads STDIN").StringVar(&c.val)
 
 	get := kv.Command("get", "Gets a value for a key").Action(c.getAction)
-	get.Arg("bucket", "The bucket to act on").Required().StringVar(&c.bucket)
+	get.Arg("bucket", "The bucket to act on").HintAction(kvBucketNamesHint).Required().StringVar(&c.bucket)
 	get.Arg("key", "The key to act on").Required().StringVar(&c.key)
 	get.Flag("revision", "Gets a specific revision").Uint64Var(&c.revision)
 	get.Flag("raw", "Show only the value string").UnNegatableBoolVar(&c.raw)
 
-	create := kv.Command("create", "Puts a value into a key only if the key is new or it's last operation was a delete").Action(c.createAction)
-	create.Arg("bucket", "The bucket to act on").Required().StringVar(&c.bucket)
+	create := mutating(kv.Command("create", "Puts a value into a key only if the key is new or it's last operation was a delete").Action(c.createAction))
+	create.Arg("bucket", "The bucket to act on").HintAction(kvBucketNamesHint).Required().StringVar(&c.bucket)
 	create.Arg("key", "The key to act on").Required().StringVar(&c.key)
 	create.Arg("value", "The value to store, when empty reads STDIN").StringVar(&c.val)
 
-	update := kv.Command("update", "Updates a key with a new value if the previous value matches the given revision").Action(c.updateAction)
-	update.Arg("bucket", "The bucket to act on").Required().StringVar(&c.bucket)
+	update := mutating(kv.Command("update", "Updates a key with a new value if the previous value matches the given revision").Action(c.updateAction))
+	update.Arg("bucket", "The bucket to act on").HintAction(kvBucketNamesHint).Required().StringVar(&c.bucket)
 	update.Arg("key", "The key to act on").Required().StringVar(&c.key)
 	update.Arg("value", "The value to store, when empty reads STDIN").StringVar(&c.val)
 	update.Arg("revision", "The revision of the previous value in the bucket").Uint64Var(&c.revision)
 
-	del := kv.Command("del", "Deletes a key or the entire bucket").Alias("rm").Action(c.deleteAction)
-	del.Arg("bucket", "The bucket to act on").Required().StringVar(&c.bucket)
+	del := mutating(kv.Command("del", "Deletes a key or the entire bucket").Alias("rm").Action(c.deleteAction))
+	del.Arg("bucket", "The bucket to act on").HintAction(kvBucketNamesHint).Required().StringVar(&c.bucket)
 	del.Arg("key", "The key to act on").StringVar(&c.key)
 	del.Flag("force", "Act without confirmation").Short('f').UnNegatableBoolVar(&c.force)
 
-	purge := kv.Command("purge", "Deletes a key from the bucket, clearing history before creating a delete marker").Action(c.purgeAction)
-	purge.Arg("bucket", "The bucket to act on").Required().StringVar(&c.bucket)
+	purge := mutating(kv.Command("purge", "Deletes a key from the bucket, clearing history before creating a delete marker").Action(c.purgeAction))
+	purge.Arg("bucket", "The bucket to act on").HintAction(kvBucketNamesHint).Required().StringVar(&c.bucket)
 	purge.Arg("key", "The key to act on").Required().StringVar(&c.key)
 	purge.Flag("force", "Act without confirmation").Short('f').UnNegatableBoolVar(&c.force)
 
 	history := kv.Command("history", "Shows the full history for a key").Action(c.historyAction)
-	history.Arg("bucket", "The bucket to act on").Required().StringVar(&c.bucket)
+	history.Arg("bucket", "The bucket to act on").HintAction(kvBucketNamesHint).Required().StringVar(&c.bucket)
 	history.Arg("key", "The key to act on").Required().StringVar(&c.key)
 
-	revert := kv.Command("revert", "Reverts a value to a previous revision using put").Action(c.revertAction)
-	revert.Arg("bucket", "The bucket to act on").Required().StringVar(&c.bucket)
+	revert := mutating(kv.Command("revert", "Reverts a value to a previous revision using put").Action(c.revertAction))
+	revert.Arg("bucket", "The bucket to act on").HintAction(kvBucketNamesHint).Required().StringVar(&c.bucket)
 	revert.Arg("key", "The key to act on").Required().StringVar(&c.key)
-	revert.Arg("revision", "The revision to revert to").Required().Uint64Var(&c.revision)
+	revert.Arg("revision", "The revision to revert to").Uint64Var(&c.revision)
+	revert.Flag("revision", "The revision to revert to").PlaceHolder("REVISION").Uint64Var(&c.revision)
+	revert.Flag("to-time", "Reverts to the value the key had at a RFC3339 time or a duration ago").PlaceHolder("TIME").StringVar(&c.revertTime)
 	revert.Flag("force", "Force reverting without prompting").BoolVar(&c.force)
 
 	status := kv.Command("info", "View the status of a KV store").Alias("view").Alias("status").Action(c.infoAction)
-	status.Arg("bucket", "The bucket to act on").StringVar(&c.bucket)
+	status.Arg("bucket", "The bucket to act on").HintAction(kvBucketNamesHint).StringVar(&c.bucket)
+	addJSONOutputFlag(status, &c.json)
 
 	watch := kv.Command("watch", "Watch the bucket or a specific key for updated").Action(c.watchAction)
-	watch.Arg("bucket", "The bucket to act on").Required().StringVar(&c.bucket)
+	watch.Arg("bucket", "The bucket to act on").HintAction(kvBucketNamesHint).Required().StringVar(&c.bucket)
 	watch.Arg("key", "The key to act on").Default(">").StringVar(&c.key)
 
 	ls := kv.Command("ls", "List available buckets or the keys in a bucket").Alias("list").Action(c.lsAction)
-	ls.Arg("bucket", "The bucket to list the keys").StringVar(&c.bucket)
+	pagedCommand(ls)
+	ls.Arg("bucket", "The bucket to list the keys").HintAction(kvBucketNamesHint).StringVar(&c.bucket)
 	ls.Flag("names", "Show just the bucket names").Short('n').UnNegatableBoolVar(&c.listNames)
 	ls.Flag("verbose", "Show detailed info about the key").Short('v').UnNegatableBoolVar(&c.lsVerbose)
 	ls.Flag("display-value", "Display value in verbose output (has no effect without 'verbose')").UnNegatableBoolVar(&c.lsVerboseDisplayValue)
+	addJSONOutputFlag(ls, &c.json)
 
-	rmHistory := kv.Command("compact", "Reclaim space used by deleted keys").Action(c.compactAction)
-	rmHistory.Arg("bucket", "The bucket to act on").Required().StringVar(&c.bucket)
+	rmHistory := mutating(kv.Command("compact", "Reclaim space used by deleted keys").Action(c.compactAction))
+	rmHistory.Arg("bucket", "The bucket to act on").HintAction(kvBucketNamesHint).Required().StringVar(&c.bucket)
 	rmHistory.Flag("force", "Act without confirmation").Short('f').UnNegatableBoolVar(&c.force)
+
+	configureKVCloneCommand(kv)
+	configureKVLockCommand(kv)
 }
 
 func init() {
@@ -207,6 +219,9 @@ func (c *kvCommand) lsBucketKeys() error {
 	keys, err := kv.Keys()
 	if err != nil {
 		if err == nats.ErrNoKeysFound {
+			if c.json {
+				return printJSON([]string{})
+			}
 			fmt.Println("No keys found in bucket")
 			return nil
 		}
@@ -214,6 +229,10 @@ func (c *kvCommand) lsBucketKeys() error {
 		return fmt.Errorf("unable to fetch keys in bucket: %s", err)
 	}
 
+	if c.json {
+		return printJSON(keys)
+	}
+
 	if c.lsVerbose {
 		if err := c.displayKeyInfo(kv, keys); err != nil {
 			return fmt.Errorf("unable to display key info: %s", err)
@@ -282,6 +301,16 @@ func (c *kvCommand) lsBuckets() error {
 		return err
 	}
 
+	if c.json {
+		names := []string{}
+		for _, s := range found {
+			names = append(names, strings.TrimPrefix(s.Name(), "KV_"))
+		}
+		sort.Strings(names)
+
+		return printJSON(names)
+	}
+
 	if len(found) == 0 {
 		fmt.Println("No Key-Value buckets found")
 		return nil
@@ -314,25 +343,94 @@ func (c *kvCommand) lsBuckets() error {
 	return nil
 }
 
+// revisionAt finds the last revision of the key stored at or before t
+func (c *kvCommand) revisionAt(store nats.KeyValue, t time.Time) (nats.KeyValueEntry, error) {
+	history, err := store.History(c.key)
+	if err != nil {
+		return nil, err
+	}
+
+	var found nats.KeyValueEntry
+	for _, r := range history {
+		if r.Created().After(t) {
+			break
+		}
+		found = r
+	}
+
+	if found == nil {
+		return nil, withExitCode(ExitNotFound, fmt.Errorf("key %s has no revision before %s", c.key, t.Format(time.RFC3339)))
+	}
+
+	return found, nil
+}
+
 func (c *kvCommand) revertAction(pc *fisk.ParseContext) error {
+	if (c.revision == 0) == (c.revertTime == "") {
+		return withExitCode(ExitValidation, fmt.Errorf("either a revision or --to-time is required"))
+	}
+
 	_, _, store, err := c.loadBucket()
 	if err != nil {
 		return err
 	}
 
-	rev, err := store.GetRevision(c.key, c.revision)
+	var rev nats.KeyValueEntry
+	if c.revertTime != "" {
+		t, perr := time.Parse(time.RFC3339, c.revertTime)
+		if perr != nil {
+			d, perr := parseDurationString(c.revertTime)
+			if perr != nil {
+				return withExitCode(ExitValidation, fmt.Errorf("invalid time %q, expected a RFC3339 time or a duration like 1h", c.revertTime))
+			}
+			t = time.Now().Add(-d)
+		}
+
+		rev, err = c.revisionAt(store, t)
+	} else {
+		rev, err = store.GetRevision(c.key, c.revision)
+	}
 	if err != nil {
 		return err
 	}
 
+	if rev.Operation() != nats.KeyValuePut {
+		return fmt.Errorf("revision %d of %s is a %s operation and has no value to revert to", rev.Revision(), c.key, rev.Operation())
+	}
+
+	var current []byte
+	cur, err := store.Get(c.key)
+	switch {
+	case errors.Is(err, nats.ErrKeyNotFound):
+	case err != nil:
+		return err
+	default:
+		current = cur.Value()
+		if cur.Revision() == rev.Revision() || bytes.Equal(current, rev.Value()) {
+			fmt.Printf("%s already has the value of revision %d\n", c.key, rev.Revision())
+			return nil
+		}
+	}
+
 	if !c.force {
-		val := base64IfNotPrintable(rev.Value())
-		if len(val) > 40 {
-			val = fmt.Sprintf("%s...%s", val[0:15], val[len(val)-15:])
+		fmt.Printf("Reverting %s > %s to revision %d created %s\n\n", c.bucket, c.key, rev.Revision(), rev.Created().Format(time.RFC3339))
+
+		printable := func(v []byte) bool {
+			return isPrintable(strings.NewReplacer("\n", "", "\t", "").Replace(string(v)))
+		}
+
+		if printable(current) && printable(rev.Value()) {
+			fmt.Printf("Differences (-current +revision %d):\n%s\n", rev.Revision(), cmp.Diff(strings.Split(string(current), "\n"), strings.Split(string(rev.Value()), "\n")))
+		} else {
+			val := base64IfNotPrintable(rev.Value())
+			if len(val) > 40 {
+				val = fmt.Sprintf("%s...%s", val[0:15], val[len(val)-15:])
+			}
+
+			fmt.Printf("Revision: %d\n\n%v\n\n", rev.Revision(), val)
 		}
 
-		fmt.Printf("Revision: %d\n\n%v\n\n", rev.Revision(), val)
-		ok, err := askConfirmation(fmt.Sprintf("Really revert to revision %d", c.revision), false)
+		ok, err := askConfirmation(fmt.Sprintf("Really revert to revision %d", rev.Revision()), false)
 		fisk.FatalIfError(err, "could not obtain confirmation")
 		if !ok {
 			return nil
@@ -726,6 +824,10 @@ func (c *kvCommand) showStatus(store nats.KeyValue) error {
 		nfo = status.(*nats.KeyValueBucketStatus).StreamInfo()
 	}
 
+	if c.json {
+		return printJSON(nfo)
+	}
+
 	if nfo == nil {
 		fmt.Printf("Information for Key-Value Store Bucket %s\n", status.Bucket())
 	} else {
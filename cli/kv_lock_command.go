@@ -0,0 +1,271 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/choria-io/fisk"
+	"github.com/nats-io/nats.go"
+)
+
+type kvLockCmd struct {
+	bucket  string
+	key     string
+	ttl     time.Duration
+	wait    time.Duration
+	command []string
+}
+
+// kvLease is the value stored in a key while a lock is held, the lease expires ttl after the last revision was stored
+type kvLease struct {
+	Owner    string        `json:"owner"`
+	TTL      time.Duration `json:"ttl"`
+	Acquired time.Time     `json:"acquired"`
+}
+
+func (l *kvLease) expired(stored time.Time, now time.Time) bool {
+	return stored.Add(l.TTL).Before(now)
+}
+
+// kvLeaseKeeper acquires, renews and releases a lease stored in a key
+type kvLeaseKeeper struct {
+	store  nats.KeyValue
+	bucket string
+	key    string
+	ttl    time.Duration
+	lease  []byte
+	rev    uint64
+}
+
+func newKVLeaseKeeper(bucket string, key string, ttl time.Duration) (*kvLeaseKeeper, error) {
+	if ttl < time.Second {
+		return nil, withExitCode(ExitValidation, fmt.Errorf("ttl has to be at least 1s"))
+	}
+
+	_, js, err := prepareJSHelper()
+	if err != nil {
+		return nil, err
+	}
+
+	store, err := js.KeyValue(bucket)
+	if err != nil {
+		return nil, err
+	}
+
+	return &kvLeaseKeeper{store: store, bucket: bucket, key: key, ttl: ttl}, nil
+}
+
+// current loads the lease stored in the key, expired leases and keys without leases return nil
+func (k *kvLeaseKeeper) current() (*kvLease, nats.KeyValueEntry, error) {
+	entry, err := k.store.Get(k.key)
+	if errors.Is(err, nats.ErrKeyNotFound) {
+		return nil, nil, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	held := &kvLease{}
+	err = json.Unmarshal(entry.Value(), held)
+	if err != nil || held.expired(entry.Created(), time.Now()) {
+		return nil, entry, nil
+	}
+
+	return held, entry, nil
+}
+
+// tryAcquire makes a single attempt to obtain the lease, taking over expired leases, and returns the lease of the holder on failure
+func (k *kvLeaseKeeper) tryAcquire() (*kvLease, error) {
+	host, _ := os.Hostname()
+	lease, err := json.Marshal(&kvLease{
+		Owner:    fmt.Sprintf("%s:%d", host, os.Getpid()),
+		TTL:      k.ttl,
+		Acquired: time.Now().UTC(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	k.lease = lease
+
+	for {
+		rev, err := k.store.Create(k.key, k.lease)
+		if err == nil {
+			k.rev = rev
+			return nil, nil
+		}
+		if !errors.Is(err, nats.ErrKeyExists) {
+			return nil, err
+		}
+
+		held, entry, err := k.current()
+		if err != nil {
+			return nil, err
+		}
+		if entry == nil {
+			continue
+		}
+		if held != nil {
+			return held, nil
+		}
+
+		rev, err = k.store.Update(k.key, k.lease, entry.Revision())
+		if err == nil {
+			k.rev = rev
+			return nil, nil
+		}
+	}
+}
+
+// acquire obtains the lease waiting up to wait for the holder to release it
+func (k *kvLeaseKeeper) acquire(wait time.Duration) error {
+	deadline := time.Now().Add(wait)
+
+	for {
+		held, err := k.tryAcquire()
+		if err != nil {
+			return err
+		}
+		if held == nil {
+			return nil
+		}
+
+		if time.Now().After(deadline) {
+			return withExitCode(ExitThreshold, fmt.Errorf("%s > %s is locked by %s since %s", k.bucket, k.key, held.Owner, held.Acquired.Local().Format(time.RFC3339)))
+		}
+
+		time.Sleep(250 * time.Millisecond)
+	}
+}
+
+func (k *kvLeaseKeeper) renew() error {
+	rev, err := k.store.Update(k.key, k.lease, k.rev)
+	if err != nil {
+		return err
+	}
+	k.rev = rev
+
+	return nil
+}
+
+func (k *kvLeaseKeeper) release() {
+	err := k.store.Delete(k.key, nats.LastRevision(k.rev))
+	if err != nil {
+		log.Printf("Could not release %s > %s: %v", k.bucket, k.key, err)
+	}
+}
+
+// run executes command while renewing the lease, the command is terminated when the lease is lost
+func (k *kvLeaseKeeper) run(command []string) (lost bool, err error) {
+	cmd := exec.Command(command[0], command[1:]...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.Env = append(os.Environ(), fmt.Sprintf("NATS_LOCK_BUCKET=%s", k.bucket), fmt.Sprintf("NATS_LOCK_KEY=%s", k.key))
+
+	// the command receives signals from the terminal directly, we keep running to release the lease
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(sigs)
+
+	err = cmd.Start()
+	if err != nil {
+		k.release()
+		return false, fmt.Errorf("could not run %s: %w", command[0], err)
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+
+	ticker := time.NewTicker(k.ttl / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			err = k.renew()
+			if err != nil {
+				log.Printf("Lost %s > %s, terminating %s: %v", k.bucket, k.key, command[0], err)
+				cmd.Process.Kill()
+				<-done
+				return true, fmt.Errorf("lost %s > %s: %w", k.bucket, k.key, err)
+			}
+
+		case sig := <-sigs:
+			cmd.Process.Signal(sig)
+
+		case err = <-done:
+			k.release()
+
+			var exitErr *exec.ExitError
+			if errors.As(err, &exitErr) {
+				return false, withExitCode(exitErr.ExitCode(), fmt.Errorf("%s failed: %w", command[0], err))
+			}
+
+			return false, err
+		}
+	}
+}
+
+func configureKVLockCommand(kv *fisk.CmdClause) {
+	c := &kvLockCmd{}
+
+	help := `Runs a command while holding a lock on a key
+
+The lock is a lease stored in the key that is renewed while the command
+runs and removed once it completes, other instances wait for the lock
+or fail when it is held. Leases of crashed holders expire after the TTL
+since they were last renewed. Clocks of the hosts competing for a lock
+should be synchronized.
+
+Exits with code 5 when the lock is held by another instance, otherwise
+with the exit code of the command.
+
+  nats kv lock LOCKS nightly-report --ttl 30s -- ./report.sh --daily
+`
+
+	lock := mutating(kv.Command("lock", help).Action(c.lockAction))
+	lock.Arg("bucket", "The bucket holding the lock").Required().HintAction(kvBucketNamesHint).StringVar(&c.bucket)
+	lock.Arg("key", "The key to lock").Required().StringVar(&c.key)
+	lock.Arg("command", "The command to run while holding the lock").Required().StringsVar(&c.command)
+	lock.Flag("ttl", "How long the lock is held without being renewed").Default("30s").DurationVar(&c.ttl)
+	lock.Flag("wait", "How long to wait for the lock when it is held").Default("0s").DurationVar(&c.wait)
+}
+
+func (c *kvLockCmd) lockAction(_ *fisk.ParseContext) error {
+	keeper, err := newKVLeaseKeeper(c.bucket, c.key, c.ttl)
+	if err != nil {
+		return err
+	}
+
+	err = keeper.acquire(c.wait)
+	if err != nil {
+		return err
+	}
+
+	if opts.Trace {
+		log.Printf("Acquired lock %s > %s at revision %d", c.bucket, c.key, keeper.rev)
+	}
+
+	_, err = keeper.run(c.command)
+
+	return err
+}
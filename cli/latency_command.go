@@ -39,6 +39,11 @@ type latencyCmd struct {
 	testDuration  time.Duration
 	histFile      string
 	numPubs       int
+	matrix        bool
+	matrixServers []string
+	matrixProbes  int
+	matrixJSON    bool
+	probeTimeout  time.Duration
 }
 
 func configureLatencyCommand(app commandHost) {
@@ -46,11 +51,16 @@ func configureLatencyCommand(app commandHost) {
 
 	latency := app.Command("latency", "Perform latency tests between two NATS servers").Alias("lat").Action(c.latencyAction)
 	addCheat("latency", latency)
-	latency.Flag("server-b", "The second server to to subscribe on").Required().StringVar(&c.serverB)
+	latency.Flag("server-b", "The second server to to subscribe on").StringVar(&c.serverB)
 	latency.Flag("size", "Message size").Default("8").IntVar(&c.msgSize)
 	latency.Flag("rate", "Rate of messages per second").Default("1000").IntVar(&c.targetPubRate)
 	latency.Flag("duration", "Test duration").Default("5s").DurationVar(&c.testDuration)
 	latency.Flag("histogram", "Output file to store the histogram in").StringVar(&c.histFile)
+	latency.Flag("matrix", "Probe request latency and loss between every pair of servers").UnNegatableBoolVar(&c.matrix)
+	latency.Flag("matrix-server", "Server URL to include in the matrix, discovered using system requests when not set (pass multiple times)").PlaceHolder("URL").StringsVar(&c.matrixServers)
+	latency.Flag("probes", "Number of requests to send between each pair of servers in matrix mode").Default("100").IntVar(&c.matrixProbes)
+	latency.Flag("probe-timeout", "How long to wait for each matrix probe response before considering it lost").Default("1s").DurationVar(&c.probeTimeout)
+	addJSONOutputFlag(latency, &c.matrixJSON)
 }
 
 func init() {
@@ -58,6 +68,14 @@ func init() {
 }
 
 func (c *latencyCmd) latencyAction(_ *fisk.ParseContext) error {
+	if c.matrix {
+		return c.matrixAction()
+	}
+
+	if c.serverB == "" {
+		return withExitCode(ExitValidation, fmt.Errorf("--server-b is required unless --matrix is set"))
+	}
+
 	start := time.Now()
 	c.numPubs = int(c.testDuration/time.Second) * c.targetPubRate
 
@@ -0,0 +1,288 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"time"
+
+	"github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats.go"
+)
+
+// latencyNode is a connection pinned to a single server used as requester and responder in matrix mode
+type latencyNode struct {
+	Name    string `json:"name"`
+	Cluster string `json:"cluster,omitempty"`
+	URL     string `json:"url"`
+	nc      *nats.Conn
+	subject string
+}
+
+func (n *latencyNode) label() string {
+	if n.Cluster == "" {
+		return n.Name
+	}
+	return fmt.Sprintf("%s (%s)", n.Name, n.Cluster)
+}
+
+type latencyPairResult struct {
+	From   string        `json:"from"`
+	To     string        `json:"to"`
+	Sent   int           `json:"sent"`
+	Lost   int           `json:"lost"`
+	Min    time.Duration `json:"min"`
+	Median time.Duration `json:"median"`
+	Max    time.Duration `json:"max"`
+}
+
+func (r *latencyPairResult) lossPct() float64 {
+	if r.Sent == 0 {
+		return 0
+	}
+	return float64(r.Lost) / float64(r.Sent) * 100
+}
+
+// discoverMatrixURLs finds the client listener of every server using VARZ, requires system access
+func (c *latencyCmd) discoverMatrixURLs() ([]string, error) {
+	nc, err := newNatsConn("", natsOpts()...)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := doReq(server.VarzEventOptions{}, "$SYS.REQ.SERVER.PING.VARZ", 0, nc)
+	if errors.Is(err, nats.ErrNoResponders) || (err == nil && len(res) == 0) {
+		return nil, fmt.Errorf("no VARZ responses received, ensure the account used has system privileges or pass servers using --matrix-server")
+	} else if err != nil {
+		return nil, err
+	}
+
+	connectedHost := ""
+	if u, err := url.Parse(nc.ConnectedUrl()); err == nil {
+		connectedHost = u.Hostname()
+	}
+
+	var urls []string
+	for _, r := range res {
+		resp := struct {
+			Data  server.Varz      `json:"data"`
+			Error *server.ApiError `json:"error"`
+		}{}
+
+		err = json.Unmarshal(r, &resp)
+		if err != nil {
+			return nil, err
+		}
+		if resp.Error != nil {
+			return nil, fmt.Errorf("varz request failed: %s", resp.Error.Description)
+		}
+
+		fallback := ""
+		if resp.Data.ID == nc.ConnectedServerId() {
+			fallback = connectedHost
+		}
+
+		addr := tlsListenerAddress(resp.Data.Host, resp.Data.Port, fallback)
+		if addr == "" {
+			log.Printf("Skipping %s: client listener on %s:%d is not reachable, pass it using --matrix-server", resp.Data.Name, resp.Data.Host, resp.Data.Port)
+			continue
+		}
+
+		urls = append(urls, "nats://"+addr)
+	}
+
+	return urls, nil
+}
+
+// connectMatrixNodes connects to every url without randomization and sets up a responder on each
+func (c *latencyCmd) connectMatrixNodes(urls []string) ([]*latencyNode, error) {
+	var nodes []*latencyNode
+
+	for _, u := range urls {
+		// reset to not use the stored conn or context
+		opts.Conn = nil
+
+		nc, err := newNatsConn(u, append(natsOpts(), nats.DontRandomize(), nats.NoReconnect())...)
+		if err != nil {
+			return nodes, fmt.Errorf("connecting to %s failed: %v", u, err)
+		}
+
+		node := &latencyNode{
+			Name:    nc.ConnectedServerName(),
+			Cluster: nc.ConnectedClusterName(),
+			URL:     u,
+			nc:      nc,
+			subject: nc.NewRespInbox(),
+		}
+		nodes = append(nodes, node)
+
+		_, err = nc.Subscribe(node.subject, func(m *nats.Msg) {
+			m.Respond(m.Data)
+		})
+		if err != nil {
+			return nodes, fmt.Errorf("subscribing on %s failed: %v", node.Name, err)
+		}
+
+		err = nc.Flush()
+		if err != nil {
+			return nodes, err
+		}
+	}
+
+	sort.Slice(nodes, func(i, j int) bool {
+		if nodes[i].Cluster != nodes[j].Cluster {
+			return nodes[i].Cluster < nodes[j].Cluster
+		}
+		return nodes[i].Name < nodes[j].Name
+	})
+
+	return nodes, nil
+}
+
+// probePair sends requests from one node to the responder on another and records round trip times
+func (c *latencyCmd) probePair(from *latencyNode, to *latencyNode) (*latencyPairResult, error) {
+	result := &latencyPairResult{From: from.Name, To: to.Name}
+
+	// interest that never propagates means the pair can not communicate at all
+	err := c.waitForRoute(from.nc, to.nc)
+	if err != nil {
+		result.Sent = c.matrixProbes
+		result.Lost = c.matrixProbes
+		return result, nil
+	}
+
+	data := make([]byte, c.msgSize)
+	delay := time.Second / time.Duration(c.targetPubRate)
+
+	var durations []time.Duration
+	for i := 0; i < c.matrixProbes; i++ {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+
+		result.Sent++
+
+		start := time.Now()
+		_, err := from.nc.Request(to.subject, data, c.probeTimeout)
+		if err != nil {
+			result.Lost++
+			continue
+		}
+
+		rtt := time.Since(start)
+		durations = append(durations, rtt)
+
+		if rtt < delay {
+			time.Sleep(delay - rtt)
+		}
+	}
+
+	if len(durations) == 0 {
+		return result, nil
+	}
+
+	sort.Slice(durations, func(i, j int) bool { return durations[i] < durations[j] })
+	result.Min = durations[0]
+	result.Max = durations[len(durations)-1]
+	result.Median, _ = c.getMedian(durations)
+
+	return result, nil
+}
+
+func (c *latencyCmd) matrixAction() error {
+	if c.targetPubRate <= 0 {
+		return fmt.Errorf("rate must be greater than 0")
+	}
+	if c.msgSize < 0 {
+		return fmt.Errorf("message size can not be negative")
+	}
+
+	urls := c.matrixServers
+	if len(urls) == 0 {
+		var err error
+		urls, err = c.discoverMatrixURLs()
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(urls) < 2 {
+		return fmt.Errorf("at least 2 servers are required for a latency matrix, found %d", len(urls))
+	}
+
+	nodes, err := c.connectMatrixNodes(urls)
+	defer func() {
+		for _, n := range nodes {
+			n.nc.Close()
+		}
+	}()
+	if err != nil {
+		return err
+	}
+
+	if !c.matrixJSON {
+		log.Printf("Probing latency between %d servers using %d requests of %d bytes per pair", len(nodes), c.matrixProbes, c.msgSize)
+	}
+
+	results := make([][]*latencyPairResult, len(nodes))
+	var flat []*latencyPairResult
+	for i, from := range nodes {
+		results[i] = make([]*latencyPairResult, len(nodes))
+		for j, to := range nodes {
+			res, err := c.probePair(from, to)
+			if err != nil {
+				return fmt.Errorf("probing %s > %s failed: %v", from.Name, to.Name, err)
+			}
+			results[i][j] = res
+			flat = append(flat, res)
+		}
+	}
+
+	if c.matrixJSON {
+		return printJSON(map[string]any{
+			"servers": nodes,
+			"results": flat,
+		})
+	}
+
+	headers := []any{"Requester \\ Responder"}
+	for _, n := range nodes {
+		headers = append(headers, n.Name)
+	}
+
+	table := newTableWriter("Median Request Latency and Loss")
+	table.AddHeaders(headers...)
+	for i, from := range nodes {
+		row := []any{from.label()}
+		for j := range nodes {
+			res := results[i][j]
+			switch {
+			case res.Lost == res.Sent:
+				row = append(row, "unreachable")
+			case res.Lost > 0:
+				row = append(row, fmt.Sprintf("%v (%.1f%% loss)", c.fmtDur(res.Median), res.lossPct()))
+			default:
+				row = append(row, c.fmtDur(res.Median).String())
+			}
+		}
+		table.AddRow(row...)
+	}
+	fmt.Println(table.Render())
+
+	return nil
+}
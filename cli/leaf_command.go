@@ -0,0 +1,276 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"bufio"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/choria-io/fisk"
+	"github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nkeys"
+	"github.com/nats-io/nuid"
+)
+
+type leafCmd struct {
+	config string
+	json   bool
+}
+
+type leafTestCheck struct {
+	Check  string `json:"check"`
+	OK     bool   `json:"ok"`
+	Detail string `json:"detail,omitempty"`
+}
+
+type leafTestResult struct {
+	Account string           `json:"account"`
+	URL     string           `json:"url"`
+	Checks  []*leafTestCheck `json:"checks"`
+}
+
+func configureLeafCommand(app commandHost) {
+	c := &leafCmd{}
+
+	leaf := app.Command("leafnode", "Leafnode connection utilities").Alias("leaf")
+	addCheat("leaf", leaf)
+
+	test := leaf.Command("test", "Validates the remotes in a leafnode configuration by connecting to them").Action(c.testAction)
+	test.Arg("config", "The NATS Server configuration holding the leafnode remotes").Required().ExistingFileVar(&c.config)
+	test.Flag("json", "Produce JSON output").Short('j').UnNegatableBoolVar(&c.json)
+}
+
+func init() {
+	registerCommand("leafnode", 10, configureLeafCommand)
+}
+
+func (r *leafTestResult) pass(check string, format string, a ...any) {
+	r.Checks = append(r.Checks, &leafTestCheck{Check: check, OK: true, Detail: fmt.Sprintf(format, a...)})
+}
+
+func (r *leafTestResult) fail(check string, format string, a ...any) *leafTestResult {
+	r.Checks = append(r.Checks, &leafTestCheck{Check: check, Detail: fmt.Sprintf(format, a...)})
+	return r
+}
+
+func (c *leafCmd) testAction(_ *fisk.ParseContext) error {
+	sopts, err := server.ProcessConfigFile(c.config)
+	if err != nil {
+		return fmt.Errorf("invalid configuration: %w", err)
+	}
+
+	if len(sopts.LeafNode.Remotes) == 0 {
+		return fmt.Errorf("no leafnode remotes found in %s", c.config)
+	}
+
+	var results []*leafTestResult
+	failed := 0
+
+	for _, remote := range sopts.LeafNode.Remotes {
+		for _, u := range remote.URLs {
+			result := c.testRemote(remote, u)
+			for _, check := range result.Checks {
+				if !check.OK {
+					failed++
+					break
+				}
+			}
+			results = append(results, result)
+		}
+	}
+
+	if c.json {
+		printJSON(results)
+	} else {
+		for _, result := range results {
+			table := newTableWriter(fmt.Sprintf("Leafnode remote %s for account %s", result.URL, result.Account))
+			table.AddHeaders("Check", "Result", "Detail")
+			for _, check := range result.Checks {
+				status := "OK"
+				if !check.OK {
+					status = "FAILED"
+				}
+				table.AddRow(check.Check, status, check.Detail)
+			}
+			fmt.Print(table.Render())
+			fmt.Println()
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d leafnode remotes failed validation", failed, len(results))
+	}
+
+	return nil
+}
+
+func (c *leafCmd) loadCredentials(file string) (string, nkeys.KeyPair, error) {
+	creds, err := os.ReadFile(file)
+	if err != nil {
+		return "", nil, err
+	}
+
+	jwt, err := nkeys.ParseDecoratedJWT(creds)
+	if err != nil {
+		return "", nil, fmt.Errorf("invalid credentials in %s: %w", file, err)
+	}
+
+	kp, err := nkeys.ParseDecoratedNKey(creds)
+	if err != nil {
+		return "", nil, fmt.Errorf("invalid credentials in %s: %w", file, err)
+	}
+
+	return jwt, kp, nil
+}
+
+// testRemote dials a remote the same way a leafnode would and performs the CONNECT handshake
+func (c *leafCmd) testRemote(remote *server.RemoteLeafOpts, u *url.URL) *leafTestResult {
+	account := remote.LocalAccount
+	if account == "" {
+		account = server.DEFAULT_GLOBAL_ACCOUNT
+	}
+
+	redacted := *u
+	if _, ok := redacted.User.Password(); ok {
+		redacted.User = url.UserPassword(redacted.User.Username(), "xxxxx")
+	}
+
+	result := &leafTestResult{Account: account, URL: redacted.String()}
+
+	var jwt string
+	var kp nkeys.KeyPair
+	if remote.Credentials != "" {
+		var err error
+		jwt, kp, err = c.loadCredentials(remote.Credentials)
+		if err != nil {
+			return result.fail("Credentials", "%v", err)
+		}
+
+		pk, _ := kp.PublicKey()
+		result.pass("Credentials", "user %s", pk)
+	}
+
+	if u.Scheme == "ws" || u.Scheme == "wss" {
+		return result.fail("Connect", "websocket remotes are not supported by this test")
+	}
+
+	start := time.Now()
+	conn, err := net.DialTimeout("tcp", u.Host, opts.Timeout)
+	if err != nil {
+		return result.fail("Connect", "%v", err)
+	}
+	defer conn.Close()
+	result.pass("Connect", "connected to %s in %v", conn.RemoteAddr(), time.Since(start).Round(time.Millisecond))
+
+	conn.SetDeadline(time.Now().Add(opts.Timeout))
+	rdr := bufio.NewReader(conn)
+
+	line, err := rdr.ReadString('\n')
+	if err != nil {
+		return result.fail("Server INFO", "did not receive INFO: %v", err)
+	}
+	if !strings.HasPrefix(line, "INFO ") {
+		return result.fail("Server INFO", "unexpected protocol line: %s", strings.TrimSpace(line))
+	}
+
+	info := server.Info{}
+	err = json.Unmarshal([]byte(strings.TrimPrefix(strings.TrimSpace(line), "INFO ")), &info)
+	if err != nil {
+		return result.fail("Server INFO", "invalid INFO: %v", err)
+	}
+	result.pass("Server INFO", "%s version %s", info.Name, info.Version)
+
+	useTLS := info.TLSRequired || remote.TLS || u.Scheme == "tls"
+	if useTLS {
+		tlsc := &tls.Config{MinVersion: tls.VersionTLS12}
+		if remote.TLSConfig != nil {
+			tlsc = remote.TLSConfig.Clone()
+		}
+		if tlsc.ServerName == "" {
+			tlsc.ServerName = u.Hostname()
+		}
+
+		tconn := tls.Client(conn, tlsc)
+		err = tconn.Handshake()
+		if err != nil {
+			return result.fail("TLS", "%v", err)
+		}
+
+		state := tconn.ConnectionState()
+		if len(state.PeerCertificates) > 0 {
+			cert := state.PeerCertificates[0]
+			result.pass("TLS", "%s expires in %s", cert.Subject.CommonName, humanizeDuration(time.Until(cert.NotAfter)))
+		} else {
+			result.pass("TLS", "")
+		}
+
+		conn = tconn
+		rdr = bufio.NewReader(conn)
+	}
+
+	connect := map[string]any{
+		"tls_required": useTLS,
+		"server_id":    nuid.Next(),
+		"name":         "nats-cli-leaf-test",
+		"headers":      true,
+	}
+	if jwt != "" {
+		sig, err := kp.Sign([]byte(info.Nonce))
+		if err != nil {
+			return result.fail("Authentication", "could not sign nonce: %v", err)
+		}
+		connect["jwt"] = jwt
+		connect["sig"] = base64.RawURLEncoding.EncodeToString(sig)
+	}
+	if u.User != nil {
+		connect["user"] = u.User.Username()
+		if pass, ok := u.User.Password(); ok {
+			connect["pass"] = pass
+		}
+	}
+
+	cj, err := json.Marshal(connect)
+	if err != nil {
+		return result.fail("Authentication", "%v", err)
+	}
+
+	_, err = fmt.Fprintf(conn, "CONNECT %s\r\nPING\r\n", cj)
+	if err != nil {
+		return result.fail("Authentication", "%v", err)
+	}
+
+	for {
+		line, err = rdr.ReadString('\n')
+		if err != nil {
+			return result.fail("Authentication", "no response to CONNECT: %v", err)
+		}
+
+		line = strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(line, "-ERR"):
+			return result.fail("Authentication", "%s", strings.Trim(strings.TrimPrefix(line, "-ERR "), "'"))
+		case line == "PONG":
+			result.pass("Authentication", "accepted by %s", info.Name)
+			return result
+		}
+	}
+}
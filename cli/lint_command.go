@@ -0,0 +1,413 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/choria-io/fisk"
+	"github.com/fatih/color"
+	"github.com/ghodss/yaml"
+	"github.com/nats-io/jsm.go"
+	"github.com/nats-io/jsm.go/api"
+)
+
+type lintCmd struct {
+	rulesFile string
+	json      bool
+	strict    bool
+	internal  bool
+
+	findings []*lintFinding
+}
+
+// Assets and severities supported by lint rules
+const (
+	lintStream   = "stream"
+	lintConsumer = "consumer"
+
+	lintError   = "error"
+	lintWarning = "warning"
+)
+
+// lintRules is the rules file for nats lint
+type lintRules struct {
+	Rules []*lintRule `json:"rules"`
+}
+
+// lintRule checks a field of every stream or consumer against a pattern or requires it to be set
+type lintRule struct {
+	Name     string `json:"name"`
+	Asset    string `json:"asset"`
+	Field    string `json:"field"`
+	Pattern  string `json:"pattern,omitempty"`
+	Required bool   `json:"required,omitempty"`
+	Severity string `json:"severity,omitempty"`
+	Fix      string `json:"fix,omitempty"`
+
+	re *regexp.Regexp
+}
+
+// lintFinding is a field of an asset that violates a rule
+type lintFinding struct {
+	Severity   string `json:"severity"`
+	Rule       string `json:"rule"`
+	Asset      string `json:"asset"`
+	Stream     string `json:"stream"`
+	Consumer   string `json:"consumer,omitempty"`
+	Field      string `json:"field"`
+	Value      string `json:"value,omitempty"`
+	Message    string `json:"message"`
+	Suggestion string `json:"suggestion,omitempty"`
+}
+
+var lintSeparators = regexp.MustCompile(`[-_\s]+`)
+
+func configureLintCommand(app commandHost) {
+	c := &lintCmd{}
+
+	help := `Checks Streams and Consumers against naming and configuration rules
+
+Rules are read from a YAML file and applied to every Stream and Consumer
+in the account, each rule checks a field against a regular expression or
+requires it to be set:
+
+  rules:
+    - name: stream names are upper case
+      asset: stream
+      field: name
+      pattern: '^[A-Z][A-Z0-9_]*$'
+    - name: streams have an owner
+      asset: stream
+      field: metadata.owner
+      required: true
+    - name: consumers have descriptions
+      asset: consumer
+      field: description
+      required: true
+      severity: warning
+
+Supported fields are name, description, metadata.KEY and subjects for
+Streams or filter_subject for Consumers, every subject has to match the
+pattern. Rules may set a fix to show instead of the suggested change.
+
+Violations of error rules, and of warning rules when using --strict,
+exit with code 5 making this suitable for use in CI pipelines.
+`
+
+	lint := app.Command("lint", help).Action(c.lintAction)
+	lint.Flag("rules", "The rules file to check against").Required().PlaceHolder("FILE").ExistingFileVar(&c.rulesFile)
+	lint.Flag("strict", "Fail on warnings as well as errors").UnNegatableBoolVar(&c.strict)
+	lint.Flag("internal", "Include KV, Object Store and MQTT Streams").UnNegatableBoolVar(&c.internal)
+	addJSONOutputFlag(lint, &c.json)
+	addCheat("lint", lint)
+}
+
+func init() {
+	registerCommand("lint", 10, configureLintCommand)
+}
+
+func loadLintRules(file string) (*lintRules, error) {
+	body, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	j, err := yaml.YAMLToJSON(body)
+	if err != nil {
+		return nil, err
+	}
+
+	rules := &lintRules{}
+	err = json.Unmarshal(j, rules)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(rules.Rules) == 0 {
+		return nil, fmt.Errorf("no rules defined")
+	}
+
+	for i, rule := range rules.Rules {
+		if rule.Name == "" {
+			rule.Name = fmt.Sprintf("rule %d", i+1)
+		}
+
+		switch rule.Asset {
+		case lintStream:
+			if rule.Field == "filter_subject" {
+				return nil, fmt.Errorf("%s: field %s is not supported for streams", rule.Name, rule.Field)
+			}
+		case lintConsumer:
+			if rule.Field == "subjects" {
+				return nil, fmt.Errorf("%s: field %s is not supported for consumers", rule.Name, rule.Field)
+			}
+		default:
+			return nil, fmt.Errorf("%s: asset must be stream or consumer", rule.Name)
+		}
+
+		switch {
+		case rule.Field == "name", rule.Field == "description", rule.Field == "subjects", rule.Field == "filter_subject":
+		case strings.HasPrefix(rule.Field, "metadata.") && len(rule.Field) > len("metadata."):
+		default:
+			return nil, fmt.Errorf("%s: unknown field %q", rule.Name, rule.Field)
+		}
+
+		switch rule.Severity {
+		case "":
+			rule.Severity = lintError
+		case lintError, lintWarning:
+		default:
+			return nil, fmt.Errorf("%s: severity must be error or warning", rule.Name)
+		}
+
+		if rule.Pattern == "" && !rule.Required {
+			return nil, fmt.Errorf("%s: a pattern or required is needed", rule.Name)
+		}
+
+		if rule.Pattern != "" {
+			rule.re, err = regexp.Compile(rule.Pattern)
+			if err != nil {
+				return nil, fmt.Errorf("%s: invalid pattern: %w", rule.Name, err)
+			}
+		}
+	}
+
+	return rules, nil
+}
+
+// lintFieldValues extracts the values of a field, fields that are not set have no values
+func lintFieldValues(field string, name string, description string, metadata map[string]string, subjects []string) []string {
+	switch {
+	case field == "name":
+		return []string{name}
+	case field == "description" && description != "":
+		return []string{description}
+	case field == "subjects", field == "filter_subject":
+		return subjects
+	case strings.HasPrefix(field, "metadata."):
+		v, ok := metadata[strings.TrimPrefix(field, "metadata.")]
+		if ok {
+			return []string{v}
+		}
+	}
+
+	return nil
+}
+
+// lintSuggestName attempts to find a name matching re by changing the case and separators of value
+func lintSuggestName(value string, re *regexp.Regexp) string {
+	for _, sep := range []string{"", "_", "-"} {
+		candidate := value
+		if sep != "" {
+			candidate = lintSeparators.ReplaceAllString(value, sep)
+		}
+
+		for _, c := range []string{candidate, strings.ToUpper(candidate), strings.ToLower(candidate)} {
+			if c != value && re.MatchString(c) {
+				return c
+			}
+		}
+	}
+
+	return ""
+}
+
+// suggest gives the change that would satisfy rule for an asset
+func (c *lintCmd) suggest(rule *lintRule, f *lintFinding) string {
+	if rule.Fix != "" {
+		return rule.Fix
+	}
+
+	edit := fmt.Sprintf("nats stream edit %s", f.Stream)
+	if f.Asset == lintConsumer {
+		edit = fmt.Sprintf("nats consumer edit %s %s", f.Stream, f.Consumer)
+	}
+
+	switch {
+	case f.Value == "" && rule.Field == "description":
+		return fmt.Sprintf("%s --description \"...\"", edit)
+	case f.Value == "" && strings.HasPrefix(rule.Field, "metadata."):
+		return fmt.Sprintf("%s --metadata %s=...", edit, strings.TrimPrefix(rule.Field, "metadata."))
+	case rule.Field == "name" && rule.re != nil:
+		name := lintSuggestName(f.Value, rule.re)
+		if name != "" {
+			return fmt.Sprintf("recreate as %s", name)
+		}
+	case (rule.Field == "subjects" || rule.Field == "filter_subject") && rule.re != nil:
+		subject := lintSuggestName(f.Value, rule.re)
+		if subject != "" {
+			return fmt.Sprintf("use %s", subject)
+		}
+	}
+
+	return ""
+}
+
+func (c *lintCmd) check(rule *lintRule, f lintFinding, values []string) {
+	add := func(value string, format string, a ...any) {
+		finding := f
+		finding.Severity = rule.Severity
+		finding.Rule = rule.Name
+		finding.Field = rule.Field
+		finding.Value = value
+		finding.Message = fmt.Sprintf(format, a...)
+		finding.Suggestion = c.suggest(rule, &finding)
+		c.findings = append(c.findings, &finding)
+	}
+
+	if len(values) == 0 {
+		if rule.Required {
+			add("", "%s is not set", rule.Field)
+		}
+		return
+	}
+
+	if rule.re == nil {
+		return
+	}
+
+	for _, v := range values {
+		if !rule.re.MatchString(v) {
+			add(v, "%s %q does not match %s", rule.Field, v, rule.Pattern)
+		}
+	}
+}
+
+func (c *lintCmd) lintStream(rules []*lintRule, cfg api.StreamConfig) {
+	for _, rule := range rules {
+		if rule.Asset != lintStream {
+			continue
+		}
+
+		values := lintFieldValues(rule.Field, cfg.Name, cfg.Description, cfg.Metadata, cfg.Subjects)
+		c.check(rule, lintFinding{Asset: lintStream, Stream: cfg.Name}, values)
+	}
+}
+
+func (c *lintCmd) lintConsumer(rules []*lintRule, stream string, cfg api.ConsumerConfig) {
+	subjects := cfg.FilterSubjects
+	if cfg.FilterSubject != "" {
+		subjects = append([]string{cfg.FilterSubject}, subjects...)
+	}
+
+	for _, rule := range rules {
+		if rule.Asset != lintConsumer {
+			continue
+		}
+
+		name := cfg.Durable
+		if cfg.Name != "" {
+			name = cfg.Name
+		}
+
+		values := lintFieldValues(rule.Field, name, cfg.Description, cfg.Metadata, subjects)
+		c.check(rule, lintFinding{Asset: lintConsumer, Stream: stream, Consumer: name}, values)
+	}
+}
+
+func (c *lintCmd) lintAction(_ *fisk.ParseContext) error {
+	rules, err := loadLintRules(c.rulesFile)
+	if err != nil {
+		return withExitCode(ExitValidation, fmt.Errorf("invalid rules in %s: %w", c.rulesFile, err))
+	}
+
+	_, mgr, err := prepareHelper("", natsOpts()...)
+	if err != nil {
+		return err
+	}
+
+	streams, missing, err := loadStreams(mgr, nil, 1)
+	if err != nil {
+		return err
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("could not obtain stream information for %d streams", len(missing))
+	}
+
+	checked := 0
+	for _, s := range streams {
+		if !c.internal && jsm.IsInternalStream(s.Name()) {
+			continue
+		}
+
+		checked++
+		c.lintStream(rules.Rules, s.Configuration())
+
+		consumers, _, err := loadConsumers(mgr, s.Name(), 1)
+		if err != nil {
+			return err
+		}
+
+		for _, cons := range consumers {
+			checked++
+			c.lintConsumer(rules.Rules, s.Name(), cons.Configuration())
+		}
+	}
+
+	failed := 0
+	for _, f := range c.findings {
+		if f.Severity == lintError || c.strict {
+			failed++
+		}
+	}
+
+	if c.json {
+		err = printJSON(c.findings)
+		if err != nil {
+			return err
+		}
+	} else {
+		c.render(checked)
+	}
+
+	if failed > 0 {
+		return withExitCode(ExitThreshold, fmt.Errorf("%d rule violations found", failed))
+	}
+
+	return nil
+}
+
+func (c *lintCmd) render(checked int) {
+	if len(c.findings) == 0 {
+		fmt.Printf("Checked %d Streams and Consumers, no rule violations found\n", checked)
+		return
+	}
+
+	table := newTableWriter("Rule Violations")
+	table.AddHeaders("Severity", "Asset", "Rule", "Problem", "Suggestion")
+
+	errs := 0
+	for _, f := range c.findings {
+		asset := fmt.Sprintf("Stream %s", f.Stream)
+		if f.Asset == lintConsumer {
+			asset = fmt.Sprintf("Consumer %s > %s", f.Stream, f.Consumer)
+		}
+
+		severity := color.YellowString("WARNING")
+		if f.Severity == lintError {
+			errs++
+			severity = color.RedString("ERROR")
+		}
+
+		table.AddRow(severity, asset, f.Rule, f.Message, f.Suggestion)
+	}
+
+	fmt.Println(table.Render())
+	fmt.Printf("Checked %d Streams and Consumers, %d errors, %d warnings\n", checked, errs, len(c.findings)-errs)
+}
@@ -0,0 +1,81 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+)
+
+func TestLoadLintRules(t *testing.T) {
+	dir := t.TempDir()
+
+	write := func(body string) string {
+		t.Helper()
+		f := filepath.Join(dir, "lint.yaml")
+		err := os.WriteFile(f, []byte(body), 0600)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return f
+	}
+
+	rules, err := loadLintRules(write("rules:\n  - asset: stream\n    field: name\n    pattern: '^[A-Z]+$'\n  - asset: consumer\n    field: metadata.owner\n    required: true\n    severity: warning\n"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if rules.Rules[0].Name != "rule 1" || rules.Rules[0].Severity != lintError || rules.Rules[0].re == nil {
+		t.Fatalf("unexpected rule: %+v", rules.Rules[0])
+	}
+	if rules.Rules[1].Severity != lintWarning || rules.Rules[1].re != nil {
+		t.Fatalf("unexpected rule: %+v", rules.Rules[1])
+	}
+
+	for _, body := range []string{
+		"rules: []\n",
+		"rules:\n  - asset: kv\n    field: name\n    required: true\n",
+		"rules:\n  - asset: stream\n    field: owner\n    required: true\n",
+		"rules:\n  - asset: stream\n    field: metadata.\n    required: true\n",
+		"rules:\n  - asset: stream\n    field: filter_subject\n    required: true\n",
+		"rules:\n  - asset: consumer\n    field: subjects\n    required: true\n",
+		"rules:\n  - asset: stream\n    field: name\n",
+		"rules:\n  - asset: stream\n    field: name\n    pattern: '['\n",
+		"rules:\n  - asset: stream\n    field: name\n    required: true\n    severity: fatal\n",
+	} {
+		_, err = loadLintRules(write(body))
+		if err == nil {
+			t.Fatalf("expected an error for %q", body)
+		}
+	}
+}
+
+func TestLintSuggestName(t *testing.T) {
+	for _, tc := range []struct {
+		value   string
+		pattern string
+		expect  string
+	}{
+		{"orders", "^[A-Z][A-Z0-9_]*$", "ORDERS"},
+		{"new-orders", "^[A-Z][A-Z0-9_]*$", "NEW_ORDERS"},
+		{"NEW_ORDERS", "^[a-z][a-z0-9-]*$", "new-orders"},
+		{"Orders", "^[0-9]+$", ""},
+	} {
+		got := lintSuggestName(tc.value, regexp.MustCompile(tc.pattern))
+		if got != tc.expect {
+			t.Fatalf("expected %q for %q got %q", tc.expect, tc.value, got)
+		}
+	}
+}
@@ -0,0 +1,756 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ghodss/yaml"
+	"github.com/google/go-cmp/cmp"
+	"github.com/nats-io/jsm.go"
+	"github.com/nats-io/jsm.go/api"
+	"github.com/nats-io/nats.go"
+)
+
+// Kinds of assets that can be described in manifests
+const (
+	manifestStream      = "Stream"
+	manifestConsumer    = "Consumer"
+	manifestKeyValue    = "KeyValue"
+	manifestObjectStore = "ObjectStore"
+)
+
+// manifest describes the desired configuration of a JetStream asset, fields not set in
+// the spec are not managed and keep their current value
+type manifest struct {
+	Kind   string          `json:"kind"`
+	Name   string          `json:"name"`
+	Stream string          `json:"stream,omitempty"`
+	Spec   json.RawMessage `json:"spec"`
+
+	file string
+}
+
+// bucketSpec is the manifest spec for Key-Value and Object Store buckets
+type bucketSpec struct {
+	Description  string          `json:"description,omitempty"`
+	History      int64           `json:"history,omitempty"`
+	TTL          time.Duration   `json:"ttl,omitempty"`
+	MaxValueSize int32           `json:"max_value_size,omitempty"`
+	MaxBytes     int64           `json:"max_bytes"`
+	Storage      api.StorageType `json:"storage"`
+	Replicas     int             `json:"replicas"`
+	Placement    *api.Placement  `json:"placement,omitempty"`
+}
+
+// manifestChange is a change needed to make the live configuration match a manifest
+type manifestChange struct {
+	Operation string
+	Kind      string
+	Name      string
+	Diff      string
+	apply     func() error
+}
+
+func (m *manifest) id() string {
+	if m.Kind == manifestConsumer {
+		return fmt.Sprintf("%s %s > %s", m.Kind, m.Stream, m.Name)
+	}
+
+	return fmt.Sprintf("%s %s", m.Kind, m.Name)
+}
+
+var manifestSeparator = regexp.MustCompile(`(?m)^---\s*$`)
+
+// loadManifests loads all manifests from files and directories, directories are searched for .yaml, .yml and .json files
+func loadManifests(paths []string) ([]*manifest, error) {
+	var files []string
+
+	for _, p := range paths {
+		nfo, err := os.Stat(p)
+		if err != nil {
+			return nil, err
+		}
+
+		if !nfo.IsDir() {
+			files = append(files, p)
+			continue
+		}
+
+		err = filepath.Walk(p, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+
+			switch strings.ToLower(filepath.Ext(path)) {
+			case ".yaml", ".yml", ".json":
+				if !info.IsDir() {
+					files = append(files, path)
+				}
+			}
+
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var manifests []*manifest
+	seen := map[string]string{}
+
+	for _, file := range files {
+		body, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, doc := range manifestSeparator.Split(string(body), -1) {
+			if strings.TrimSpace(doc) == "" {
+				continue
+			}
+
+			m, err := parseManifest([]byte(doc))
+			if err != nil {
+				return nil, fmt.Errorf("invalid manifest in %s: %w", file, err)
+			}
+			m.file = file
+
+			if prev, ok := seen[m.id()]; ok {
+				return nil, fmt.Errorf("%s is defined in both %s and %s", m.id(), prev, file)
+			}
+			seen[m.id()] = file
+
+			manifests = append(manifests, m)
+		}
+	}
+
+	return manifests, nil
+}
+
+func parseManifest(doc []byte) (*manifest, error) {
+	j, err := yaml.YAMLToJSON(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &manifest{}
+	err = json.Unmarshal(j, m)
+	if err != nil {
+		return nil, err
+	}
+
+	switch m.Kind {
+	case manifestStream, manifestKeyValue, manifestObjectStore:
+	case manifestConsumer:
+		if m.Stream == "" {
+			return nil, fmt.Errorf("consumer %q requires a stream", m.Name)
+		}
+	default:
+		return nil, fmt.Errorf("unknown kind %q, valid kinds are %s", m.Kind, strings.Join([]string{manifestStream, manifestConsumer, manifestKeyValue, manifestObjectStore}, ", "))
+	}
+
+	if m.Name == "" {
+		return nil, fmt.Errorf("%s requires a name", m.Kind)
+	}
+
+	if len(m.Spec) == 0 || bytes.Equal(m.Spec, []byte("null")) {
+		m.Spec = json.RawMessage("{}")
+	}
+
+	return m, nil
+}
+
+// configDiff shows the difference between two configurations, subject lists that only differ in ordering are considered equal
+func configDiff(live any, desired any) string {
+	sorter := cmp.Transformer("Sort", func(in []string) []string {
+		out := append([]string(nil), in...)
+		sort.Strings(out)
+		return out
+	})
+
+	return cmp.Diff(live, desired, sorter)
+}
+
+// planManifests determines the changes needed for the live configuration to match manifests, when prune is set
+// assets of the kinds found in the manifests that are not described are removed
+func planManifests(mgr *jsm.Manager, manifests []*manifest, prune bool) ([]*manifestChange, error) {
+	var changes []*manifestChange
+	kinds := map[string]bool{}
+	declared := map[string]bool{}
+
+	// changes are planned in dependency order so streams exist before their consumers
+	order := map[string]int{manifestStream: 0, manifestKeyValue: 1, manifestObjectStore: 2, manifestConsumer: 3}
+	sorted := append([]*manifest(nil), manifests...)
+	sort.SliceStable(sorted, func(i, j int) bool { return order[sorted[i].Kind] < order[sorted[j].Kind] })
+
+	for _, m := range sorted {
+		kinds[m.Kind] = true
+		declared[m.id()] = true
+
+		var change *manifestChange
+		var err error
+
+		switch m.Kind {
+		case manifestStream:
+			change, err = planStreamManifest(mgr, m)
+		case manifestConsumer:
+			change, err = planConsumerManifest(mgr, m)
+		case manifestKeyValue, manifestObjectStore:
+			change, err = planBucketManifest(mgr, m)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("could not plan %s: %w", m.id(), err)
+		}
+
+		if change != nil {
+			changes = append(changes, change)
+		}
+	}
+
+	if !prune {
+		return changes, nil
+	}
+
+	pruned, err := planPrune(mgr, manifests, kinds, declared)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(pruned, changes...), nil
+}
+
+func planStreamManifest(mgr *jsm.Manager, m *manifest) (*manifestChange, error) {
+	known, err := mgr.IsKnownStream(m.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	if !known {
+		cfg := jsm.DefaultStream
+		cfg.MaxAge = 0
+		err = json.Unmarshal(m.Spec, &cfg)
+		if err != nil {
+			return nil, err
+		}
+		cfg.Name = m.Name
+
+		return &manifestChange{Operation: "create", Kind: m.Kind, Name: m.Name, apply: func() error {
+			_, err := mgr.NewStreamFromDefault(cfg.Name, cfg)
+			return err
+		}}, nil
+	}
+
+	str, err := mgr.LoadStream(m.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := str.Configuration()
+	err = json.Unmarshal(m.Spec, &cfg)
+	if err != nil {
+		return nil, err
+	}
+	cfg.Name = m.Name
+
+	diff := configDiff(str.Configuration(), cfg)
+	if diff == "" {
+		return nil, nil
+	}
+
+	return &manifestChange{Operation: "update", Kind: m.Kind, Name: m.Name, Diff: diff, apply: func() error {
+		return str.UpdateConfiguration(cfg)
+	}}, nil
+}
+
+func planConsumerManifest(mgr *jsm.Manager, m *manifest) (*manifestChange, error) {
+	name := fmt.Sprintf("%s > %s", m.Stream, m.Name)
+
+	known, err := mgr.IsKnownStream(m.Stream)
+	if err != nil {
+		return nil, err
+	}
+
+	if known {
+		known, err = mgr.IsKnownConsumer(m.Stream, m.Name)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if !known {
+		cfg := jsm.DefaultConsumer
+		err = json.Unmarshal(m.Spec, &cfg)
+		if err != nil {
+			return nil, err
+		}
+		cfg.Durable = m.Name
+		cfg.Name = ""
+
+		return &manifestChange{Operation: "create", Kind: m.Kind, Name: name, apply: func() error {
+			_, err := mgr.NewConsumerFromDefault(m.Stream, cfg)
+			return err
+		}}, nil
+	}
+
+	cons, err := mgr.LoadConsumer(m.Stream, m.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := cons.Configuration()
+	err = json.Unmarshal(m.Spec, &cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	diff := configDiff(cons.Configuration(), cfg)
+	if diff == "" {
+		return nil, nil
+	}
+
+	return &manifestChange{Operation: "update", Kind: m.Kind, Name: name, Diff: diff, apply: func() error {
+		_, err := mgr.NewConsumerFromDefault(m.Stream, cfg)
+		return err
+	}}, nil
+}
+
+// bucketStreamName is the name of the stream holding the data for a Key-Value or Object Store bucket
+func bucketStreamName(kind string, bucket string) string {
+	if kind == manifestKeyValue {
+		return "KV_" + bucket
+	}
+
+	return "OBJ_" + bucket
+}
+
+// bucketSpecFromStream extracts the bucket configuration from the configuration of its stream
+func bucketSpecFromStream(kind string, cfg api.StreamConfig) bucketSpec {
+	spec := bucketSpec{
+		Description: cfg.Description,
+		TTL:         cfg.MaxAge,
+		MaxBytes:    cfg.MaxBytes,
+		Storage:     cfg.Storage,
+		Replicas:    cfg.Replicas,
+		Placement:   cfg.Placement,
+	}
+
+	if kind == manifestKeyValue {
+		spec.History = cfg.MaxMsgsPer
+		spec.MaxValueSize = cfg.MaxMsgSize
+	}
+
+	return spec
+}
+
+func planBucketManifest(mgr *jsm.Manager, m *manifest) (*manifestChange, error) {
+	stream := bucketStreamName(m.Kind, m.Name)
+
+	known, err := mgr.IsKnownStream(stream)
+	if err != nil {
+		return nil, err
+	}
+
+	if !known {
+		spec := bucketSpec{History: 1, MaxValueSize: -1, MaxBytes: -1, Replicas: 1}
+		err = json.Unmarshal(m.Spec, &spec)
+		if err != nil {
+			return nil, err
+		}
+
+		return &manifestChange{Operation: "create", Kind: m.Kind, Name: m.Name, apply: func() error {
+			return createBucket(mgr, m.Kind, m.Name, spec)
+		}}, nil
+	}
+
+	str, err := mgr.LoadStream(stream)
+	if err != nil {
+		return nil, err
+	}
+
+	live := bucketSpecFromStream(m.Kind, str.Configuration())
+	spec := live
+	err = json.Unmarshal(m.Spec, &spec)
+	if err != nil {
+		return nil, err
+	}
+
+	diff := configDiff(live, spec)
+	if diff == "" {
+		return nil, nil
+	}
+
+	return &manifestChange{Operation: "update", Kind: m.Kind, Name: m.Name, Diff: diff, apply: func() error {
+		cfg := str.Configuration()
+		cfg.Description = spec.Description
+		cfg.MaxAge = spec.TTL
+		cfg.MaxBytes = spec.MaxBytes
+		cfg.Storage = spec.Storage
+		cfg.Replicas = spec.Replicas
+		cfg.Placement = spec.Placement
+		if m.Kind == manifestKeyValue {
+			cfg.MaxMsgsPer = spec.History
+			cfg.MaxMsgSize = spec.MaxValueSize
+		}
+
+		return str.UpdateConfiguration(cfg)
+	}}, nil
+}
+
+func createBucket(mgr *jsm.Manager, kind string, name string, spec bucketSpec) error {
+	js, err := mgr.NatsConn().JetStream()
+	if err != nil {
+		return err
+	}
+
+	var placement *nats.Placement
+	if spec.Placement != nil {
+		placement = &nats.Placement{Cluster: spec.Placement.Cluster, Tags: spec.Placement.Tags}
+	}
+
+	storage := nats.FileStorage
+	if spec.Storage == api.MemoryStorage {
+		storage = nats.MemoryStorage
+	}
+
+	if kind == manifestKeyValue {
+		if spec.History < 1 || spec.History > 64 {
+			return fmt.Errorf("history must be between 1 and 64")
+		}
+
+		_, err = js.CreateKeyValue(&nats.KeyValueConfig{
+			Bucket:       name,
+			Description:  spec.Description,
+			MaxValueSize: spec.MaxValueSize,
+			History:      uint8(spec.History),
+			TTL:          spec.TTL,
+			MaxBytes:     spec.MaxBytes,
+			Storage:      storage,
+			Replicas:     spec.Replicas,
+			Placement:    placement,
+		})
+
+		return err
+	}
+
+	_, err = js.CreateObjectStore(&nats.ObjectStoreConfig{
+		Bucket:      name,
+		Description: spec.Description,
+		TTL:         spec.TTL,
+		MaxBytes:    spec.MaxBytes,
+		Storage:     storage,
+		Replicas:    spec.Replicas,
+		Placement:   placement,
+	})
+
+	return err
+}
+
+// manifestUnmanagedStreamPrefixes are prefixes of Streams created by the server and other tools, they are never pruned
+var manifestUnmanagedStreamPrefixes = []string{"$", "GOVERNOR_"}
+
+func isUnmanagedStream(stream string) bool {
+	for _, prefix := range manifestUnmanagedStreamPrefixes {
+		if strings.HasPrefix(stream, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// manifestDeletions lists the assets deleted by changes
+func manifestDeletions(changes []*manifestChange) []string {
+	var deletes []string
+	for _, change := range changes {
+		if change.Operation == "delete" {
+			deletes = append(deletes, fmt.Sprintf("%s %s", change.Kind, change.Name))
+		}
+	}
+
+	return deletes
+}
+
+// planPrune plans the removal of assets of kinds that are managed by manifests but are not described in any of them
+func planPrune(mgr *jsm.Manager, manifests []*manifest, kinds map[string]bool, declared map[string]bool) ([]*manifestChange, error) {
+	var changes []*manifestChange
+
+	names, err := mgr.StreamNames(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	deleteStream := func(kind string, name string, stream string) *manifestChange {
+		return &manifestChange{Operation: "delete", Kind: kind, Name: name, apply: func() error {
+			str, err := mgr.LoadStream(stream)
+			if err != nil {
+				return err
+			}
+			return str.Delete()
+		}}
+	}
+
+	var streams []*manifestChange
+	for _, stream := range names {
+		if isUnmanagedStream(stream) {
+			continue
+		}
+
+		switch {
+		case strings.HasPrefix(stream, "KV_"):
+			name := strings.TrimPrefix(stream, "KV_")
+			if kinds[manifestKeyValue] && !declared[manifestKeyValue+" "+name] {
+				streams = append(streams, deleteStream(manifestKeyValue, name, stream))
+			}
+		case strings.HasPrefix(stream, "OBJ_"):
+			name := strings.TrimPrefix(stream, "OBJ_")
+			if kinds[manifestObjectStore] && !declared[manifestObjectStore+" "+name] {
+				streams = append(streams, deleteStream(manifestObjectStore, name, stream))
+			}
+		default:
+			if !kinds[manifestStream] || declared[manifestStream+" "+stream] {
+				continue
+			}
+
+			str, err := mgr.LoadStream(stream)
+			if err != nil {
+				return nil, err
+			}
+
+			// mirrors are usually maintained by other tools replicating data between accounts or domains
+			if str.IsMirror() {
+				continue
+			}
+
+			streams = append(streams, deleteStream(manifestStream, stream, stream))
+		}
+	}
+
+	// consumers are only pruned from streams that have consumers described in the manifests
+	if kinds[manifestConsumer] {
+		managed := map[string]bool{}
+		for _, m := range manifests {
+			if m.Kind == manifestConsumer {
+				managed[m.Stream] = true
+			}
+		}
+
+		for stream := range managed {
+			known, err := mgr.IsKnownStream(stream)
+			if err != nil {
+				return nil, err
+			}
+			if !known {
+				continue
+			}
+
+			consumers, err := mgr.ConsumerNames(stream)
+			if err != nil {
+				return nil, err
+			}
+
+			for _, consumer := range consumers {
+				if declared[fmt.Sprintf("%s %s > %s", manifestConsumer, stream, consumer)] {
+					continue
+				}
+
+				cons, err := mgr.LoadConsumer(stream, consumer)
+				if err != nil {
+					return nil, err
+				}
+
+				// ephemeral consumers belong to running clients
+				if cons.IsEphemeral() {
+					continue
+				}
+
+				changes = append(changes, &manifestChange{Operation: "delete", Kind: manifestConsumer, Name: fmt.Sprintf("%s > %s", stream, consumer), apply: cons.Delete})
+			}
+		}
+
+		sort.Slice(changes, func(i, j int) bool { return changes[i].Name < changes[j].Name })
+	}
+
+	// consumers are removed before streams
+	return append(changes, streams...), nil
+}
+
+// newManifest creates a manifest for an asset holding spec, keys in omit are removed from the spec
+func newManifest(kind string, name string, stream string, spec any, omit ...string) (*manifest, error) {
+	j, err := json.Marshal(spec)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(omit) > 0 {
+		fields := map[string]any{}
+		err = json.Unmarshal(j, &fields)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, k := range omit {
+			delete(fields, k)
+		}
+
+		j, err = json.Marshal(fields)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &manifest{Kind: kind, Name: name, Stream: stream, Spec: j}, nil
+}
+
+// exportManifests creates manifests for the live configuration of streams and their durable consumers and of buckets,
+// empty name lists select all assets of that kind while nil lists select none, up to workers streams are loaded concurrently
+func exportManifests(mgr *jsm.Manager, streams []string, kvs []string, objects []string, workers int) ([]*manifest, error) {
+	var names []string
+	err := apiCall(func() (err error) {
+		names, err = mgr.StreamNames(nil)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(names)
+
+	selected := func(list []string, name string) bool {
+		if list == nil {
+			return false
+		}
+
+		for _, n := range list {
+			if n == name {
+				return true
+			}
+		}
+
+		return len(list) == 0
+	}
+
+	found := make([][]*manifest, len(names))
+	errs := make([]error, len(names))
+
+	parallelDo(workers, len(names), func(i int) {
+		stream := names[i]
+
+		switch {
+		case strings.HasPrefix(stream, "KV_"):
+			if selected(kvs, strings.TrimPrefix(stream, "KV_")) {
+				found[i], errs[i] = exportBucketManifest(mgr, manifestKeyValue, stream)
+			}
+
+		case strings.HasPrefix(stream, "OBJ_"):
+			if selected(objects, strings.TrimPrefix(stream, "OBJ_")) {
+				found[i], errs[i] = exportBucketManifest(mgr, manifestObjectStore, stream)
+			}
+
+		default:
+			if selected(streams, stream) {
+				found[i], errs[i] = exportStreamManifests(mgr, stream)
+			}
+		}
+	})
+
+	var manifests []*manifest
+	for i := range names {
+		if errs[i] != nil {
+			return nil, errs[i]
+		}
+		manifests = append(manifests, found[i]...)
+	}
+
+	return manifests, nil
+}
+
+// exportBucketManifest creates the manifest for a Key-Value or Object Store bucket stored in stream
+func exportBucketManifest(mgr *jsm.Manager, kind string, stream string) ([]*manifest, error) {
+	var str *jsm.Stream
+	err := apiCall(func() (err error) {
+		str, err = mgr.LoadStream(stream)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	name := strings.TrimPrefix(strings.TrimPrefix(stream, "KV_"), "OBJ_")
+	m, err := newManifest(kind, name, "", bucketSpecFromStream(kind, str.Configuration()))
+	if err != nil {
+		return nil, err
+	}
+
+	return []*manifest{m}, nil
+}
+
+// exportStreamManifests creates manifests for a stream and its durable consumers
+func exportStreamManifests(mgr *jsm.Manager, stream string) ([]*manifest, error) {
+	var str *jsm.Stream
+	err := apiCall(func() (err error) {
+		str, err = mgr.LoadStream(stream)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	m, err := newManifest(manifestStream, stream, "", str.Configuration(), "name")
+	if err != nil {
+		return nil, err
+	}
+	manifests := []*manifest{m}
+
+	var consumers []string
+	err = apiCall(func() (err error) {
+		consumers, err = str.ConsumerNames()
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(consumers)
+
+	for _, consumer := range consumers {
+		var cons *jsm.Consumer
+		err := apiCall(func() (err error) {
+			cons, err = str.LoadConsumer(consumer)
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		// ephemeral consumers belong to running clients
+		if cons.IsEphemeral() {
+			continue
+		}
+
+		m, err := newManifest(manifestConsumer, consumer, stream, cons.Configuration(), "name", "durable_name")
+		if err != nil {
+			return nil, err
+		}
+		manifests = append(manifests, m)
+	}
+
+	return manifests, nil
+}
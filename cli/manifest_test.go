@@ -0,0 +1,108 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseManifest(t *testing.T) {
+	_, err := parseManifest([]byte("kind: Bogus\nname: X\n"))
+	if err == nil {
+		t.Fatalf("expected an error for unknown kinds")
+	}
+
+	_, err = parseManifest([]byte("kind: Consumer\nname: X\n"))
+	if err == nil {
+		t.Fatalf("expected an error for consumers without a stream")
+	}
+
+	_, err = parseManifest([]byte("kind: Stream\n"))
+	if err == nil {
+		t.Fatalf("expected an error for manifests without a name")
+	}
+
+	m, err := parseManifest([]byte("kind: Stream\nname: ORDERS\n"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(m.Spec) != "{}" {
+		t.Fatalf("expected an empty spec, got %q", m.Spec)
+	}
+}
+
+func TestLoadManifests(t *testing.T) {
+	dir := t.TempDir()
+
+	err := os.WriteFile(filepath.Join(dir, "orders.yaml"), []byte("kind: Stream\nname: ORDERS\nspec:\n  subjects: [ORDERS.*]\n---\nkind: Consumer\nstream: ORDERS\nname: NEW\n"), 0600)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	err = os.WriteFile(filepath.Join(dir, "config.json"), []byte(`{"kind": "KeyValue", "name": "CONFIG", "spec": {"history": 5}}`), 0600)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	err = os.WriteFile(filepath.Join(dir, "README.md"), []byte("not a manifest"), 0600)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	manifests, err := loadManifests([]string{dir})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(manifests) != 3 {
+		t.Fatalf("expected 3 manifests, got %d", len(manifests))
+	}
+
+	err = os.WriteFile(filepath.Join(dir, "dupe.yml"), []byte("kind: Stream\nname: ORDERS\n"), 0600)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	_, err = loadManifests([]string{dir})
+	if err == nil {
+		t.Fatalf("expected an error for duplicate manifests")
+	}
+}
+
+func TestIsUnmanagedStream(t *testing.T) {
+	for stream, expect := range map[string]bool{
+		"ORDERS":         false,
+		"KV_CONFIG":      false,
+		"$MQTT_msgs":     true,
+		"$MQTT_sess":     true,
+		"GOVERNOR_cron":  true,
+		"ORDERS_$BACKUP": false,
+	} {
+		if isUnmanagedStream(stream) != expect {
+			t.Fatalf("expected %q unmanaged to be %v", stream, expect)
+		}
+	}
+}
+
+func TestManifestDeletions(t *testing.T) {
+	deletes := manifestDeletions([]*manifestChange{
+		{Operation: "create", Kind: manifestStream, Name: "ORDERS"},
+		{Operation: "delete", Kind: manifestConsumer, Name: "ORDERS > OLD"},
+		{Operation: "update", Kind: manifestKeyValue, Name: "CONFIG"},
+		{Operation: "delete", Kind: manifestStream, Name: "ARCHIVE"},
+	})
+
+	if len(deletes) != 2 || deletes[0] != "Consumer ORDERS > OLD" || deletes[1] != "Stream ARCHIVE" {
+		t.Fatalf("invalid deletions %v", deletes)
+	}
+}
@@ -57,6 +57,9 @@ func configureMicroCommand(app commandHost) {
 
 	ping := mc.Command("ping", "Sends a ping to all services").Action(c.pingAction)
 	ping.Arg("service", "Service to show").StringVar(&c.name)
+
+	configureMicroProbeCommand(mc)
+	configureMicroServeCommand(mc)
 }
 
 func init() {
@@ -223,8 +226,7 @@ func (c *microCmd) statsAction(_ *fisk.ParseContext) error {
 	})
 
 	if c.showJSON {
-		printJSON(stats)
-		return nil
+		return printJSON(stats)
 	}
 
 	table := newTableWriter(fmt.Sprintf("%s Service Statistics", c.name))
@@ -347,8 +349,7 @@ func (c *microCmd) listAction(_ *fisk.ParseContext) error {
 	}
 
 	if c.showJSON {
-		printJSON(nfos)
-		return nil
+		return printJSON(nfos)
 	}
 
 	if len(nfos) == 0 {
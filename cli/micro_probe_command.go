@@ -0,0 +1,347 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/HdrHistogram/hdrhistogram-go"
+	"github.com/choria-io/fisk"
+	"github.com/dustin/go-humanize"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/micro"
+	"github.com/nats-io/natscli/monitor"
+	"golang.org/x/time/rate"
+)
+
+type microProbeCmd struct {
+	microCmd
+
+	endpoints   []string
+	payload     string
+	rate        string
+	duration    time.Duration
+	concurrency int
+	json        bool
+	check       bool
+
+	latencyWarn time.Duration
+	latencyCrit time.Duration
+	errorsWarn  float64
+	errorsCrit  float64
+	minRequests int
+}
+
+// microProbeResult is the outcome of probing a single endpoint
+type microProbeResult struct {
+	Endpoint  string        `json:"endpoint"`
+	Subject   string        `json:"subject"`
+	Requests  int           `json:"requests"`
+	Errors    int           `json:"errors"`
+	Timeouts  int           `json:"timeouts"`
+	ErrorRate float64       `json:"error_rate"`
+	Rate      float64       `json:"rate"`
+	P50       time.Duration `json:"p50"`
+	P90       time.Duration `json:"p90"`
+	P99       time.Duration `json:"p99"`
+	Max       time.Duration `json:"max"`
+	LastError string        `json:"last_error,omitempty"`
+
+	latencies []int64
+	mu        sync.Mutex
+}
+
+func configureMicroProbeCommand(mc *fisk.CmdClause) {
+	c := &microProbeCmd{}
+
+	help := `Load tests the endpoints of a Micro service
+
+Sends requests to every endpoint of a discovered service at a fixed rate
+per endpoint and reports latency percentiles and error rates.
+
+Endpoints with wildcard subjects are skipped. Using --check the results
+are compared to thresholds and rendered like nats server check for use
+in health gating.
+`
+
+	probe := publishing(mc.Command("probe", help).Action(c.probeAction))
+	probe.Arg("service", "Service to probe").Required().StringVar(&c.name)
+	probe.Arg("id", "Probe a specific instance to discover endpoints").StringVar(&c.id)
+	probe.Flag("endpoint", "Only probe specific endpoints (pass multiple times)").PlaceHolder("NAME").StringsVar(&c.endpoints)
+	probe.Flag("payload", "The payload to send, supports the same templates as nats request").PlaceHolder("TEMPLATE").StringVar(&c.payload)
+	probe.Flag("rate", "The request rate per endpoint like 100/s").Default("10/s").StringVar(&c.rate)
+	probe.Flag("duration", "How long to probe for").Default("10s").DurationVar(&c.duration)
+	probe.Flag("concurrency", "Maximum outstanding requests per endpoint").Default("10").IntVar(&c.concurrency)
+	probe.Flag("json", "Produce JSON output").Short('j').UnNegatableBoolVar(&c.json)
+	probe.Flag("check", "Compare results to thresholds and exit like a monitoring check").UnNegatableBoolVar(&c.check)
+	probe.Flag("latency-warn", "Warning threshold for the 99th percentile latency").Default("500ms").DurationVar(&c.latencyWarn)
+	probe.Flag("latency-critical", "Critical threshold for the 99th percentile latency").Default("1s").DurationVar(&c.latencyCrit)
+	probe.Flag("errors-warn", "Warning threshold for the percentage of failed requests").Default("1").Float64Var(&c.errorsWarn)
+	probe.Flag("errors-critical", "Critical threshold for the percentage of failed requests").Default("5").Float64Var(&c.errorsCrit)
+	probe.Flag("min-requests", "Critical when fewer requests than this completed for an endpoint").Default("1").IntVar(&c.minRequests)
+}
+
+func (r *microProbeResult) record(latency time.Duration, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.Requests++
+	if err != nil {
+		r.Errors++
+		if errors.Is(err, nats.ErrTimeout) || errors.Is(err, context.DeadlineExceeded) {
+			r.Timeouts++
+		}
+		r.LastError = err.Error()
+		return
+	}
+
+	r.latencies = append(r.latencies, int64(latency))
+}
+
+func (r *microProbeResult) calculate(elapsed time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.Requests > 0 {
+		r.ErrorRate = float64(r.Errors) / float64(r.Requests) * 100
+	}
+	if elapsed > 0 {
+		r.Rate = float64(r.Requests) / elapsed.Seconds()
+	}
+
+	if len(r.latencies) == 0 {
+		return
+	}
+
+	highest := int64(2)
+	for _, l := range r.latencies {
+		if l > highest {
+			highest = l
+		}
+	}
+
+	h := hdrhistogram.New(1, highest, 3)
+	for _, l := range r.latencies {
+		h.RecordValue(l)
+	}
+
+	r.P50 = time.Duration(h.ValueAtQuantile(50))
+	r.P90 = time.Duration(h.ValueAtQuantile(90))
+	r.P99 = time.Duration(h.ValueAtQuantile(99))
+	r.Max = time.Duration(h.Max())
+}
+
+// discoverEndpoints finds the endpoints of the service that can be probed
+func (c *microProbeCmd) discoverEndpoints(nc *nats.Conn) ([]*microProbeResult, error) {
+	stats, err := c.getInstanceStats(nc, c.name, c.id)
+	if err != nil {
+		return nil, err
+	}
+
+	wanted := map[string]bool{}
+	for _, e := range c.endpoints {
+		wanted[e] = true
+	}
+
+	var results []*microProbeResult
+	for _, e := range stats.Endpoints {
+		name := e.Name
+		if name == "" {
+			name = "default"
+		}
+
+		if len(wanted) > 0 && !wanted[name] {
+			continue
+		}
+
+		if strings.ContainsAny(e.Subject, "*>") {
+			if !c.json && !c.check {
+				log.Printf("Skipping endpoint %s with wildcard subject %s", name, e.Subject)
+			}
+			continue
+		}
+
+		results = append(results, &microProbeResult{Endpoint: name, Subject: e.Subject})
+	}
+
+	sort.Slice(results, func(i, j int) bool { return results[i].Endpoint < results[j].Endpoint })
+
+	return results, nil
+}
+
+func (c *microProbeCmd) probeEndpoint(pctx context.Context, nc *nats.Conn, limiter *rate.Limiter, result *microProbeResult) {
+	var wg sync.WaitGroup
+	var ctr int
+	var mu sync.Mutex
+
+	for i := 0; i < c.concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for {
+				if limiter.Wait(pctx) != nil {
+					return
+				}
+
+				mu.Lock()
+				ctr++
+				seq := ctr
+				mu.Unlock()
+
+				body, err := pubReplyBodyTemplate(c.payload, "", seq)
+				if err != nil {
+					result.record(0, err)
+					continue
+				}
+
+				msg := nats.NewMsg(result.Subject)
+				msg.Data = body
+
+				rctx, cancel := context.WithTimeout(pctx, opts.Timeout)
+				start := time.Now()
+				res, err := nc.RequestMsgWithContext(rctx, msg)
+				latency := time.Since(start)
+				cancel()
+
+				// requests still outstanding when probing ends are not counted
+				if pctx.Err() != nil {
+					return
+				}
+
+				if err == nil && res.Header.Get(micro.ErrorHeader) != "" {
+					err = fmt.Errorf("%s: %s", res.Header.Get(micro.ErrorCodeHeader), res.Header.Get(micro.ErrorHeader))
+				}
+
+				result.record(latency, err)
+			}
+		}()
+	}
+
+	wg.Wait()
+}
+
+func (c *microProbeCmd) probeAction(_ *fisk.ParseContext) error {
+	limit, err := parseAPIRate(c.rate)
+	if err != nil {
+		return withExitCode(ExitValidation, err)
+	}
+	if c.concurrency < 1 {
+		return withExitCode(ExitValidation, fmt.Errorf("concurrency must be at least 1"))
+	}
+
+	nc, _, err := prepareHelper("", natsOpts()...)
+	if err != nil {
+		return fmt.Errorf("setup failed: %v", err)
+	}
+
+	results, err := c.discoverEndpoints(nc)
+	if err != nil {
+		if c.check {
+			check := &monitor.Result{Name: c.name, Check: "micro", OutFile: checkRenderOutFile, PushGateway: checkPushGateway, PushJob: checkPushJob, NameSpace: opts.PrometheusNamespace, RenderFormat: checkRenderFormat}
+			check.Critical("discovery failed: %v", err)
+			check.GenericExit()
+		}
+		return err
+	}
+	if len(results) == 0 {
+		return withExitCode(ExitNotFound, fmt.Errorf("no endpoints of %s can be probed", c.name))
+	}
+
+	if !c.json && !c.check {
+		log.Printf("Probing %d endpoints of %s at %s per endpoint for %v", len(results), c.name, c.rate, c.duration)
+	}
+
+	pctx, cancel := context.WithTimeout(ctx, c.duration)
+	defer cancel()
+
+	start := time.Now()
+	var wg sync.WaitGroup
+	for _, result := range results {
+		wg.Add(1)
+		go func(result *microProbeResult) {
+			defer wg.Done()
+			c.probeEndpoint(pctx, nc, rate.NewLimiter(limit, 1), result)
+		}(result)
+	}
+	wg.Wait()
+
+	elapsed := time.Since(start)
+	for _, result := range results {
+		result.calculate(elapsed)
+	}
+
+	switch {
+	case c.check:
+		c.renderCheck(results)
+	case c.json:
+		return printJSON(results)
+	default:
+		c.renderResults(results, elapsed)
+	}
+
+	return nil
+}
+
+func (c *microProbeCmd) renderResults(results []*microProbeResult, elapsed time.Duration) {
+	table := newTableWriter(fmt.Sprintf("Probe results for %s over %v", c.name, elapsed.Round(time.Millisecond)))
+	table.AddHeaders("Endpoint", "Subject", "Requests", "Rate", "Errors", "Timeouts", "Error %", "P50", "P90", "P99", "Max")
+	for _, r := range results {
+		table.AddRow(r.Endpoint, r.Subject, humanize.Comma(int64(r.Requests)), fmt.Sprintf("%.1f/s", r.Rate), humanize.Comma(int64(r.Errors)), humanize.Comma(int64(r.Timeouts)), fmt.Sprintf("%.2f", r.ErrorRate), humanizeDuration(r.P50), humanizeDuration(r.P90), humanizeDuration(r.P99), humanizeDuration(r.Max))
+	}
+	fmt.Println(table.Render())
+
+	for _, r := range results {
+		if r.LastError != "" {
+			fmt.Printf("Last error for %s: %s\n", r.Endpoint, r.LastError)
+		}
+	}
+}
+
+func (c *microProbeCmd) renderCheck(results []*microProbeResult) {
+	check := &monitor.Result{Name: c.name, Check: "micro", OutFile: checkRenderOutFile, PushGateway: checkPushGateway, PushJob: checkPushJob, NameSpace: opts.PrometheusNamespace, RenderFormat: checkRenderFormat}
+	defer check.GenericExit()
+
+	for _, r := range results {
+		check.Pd(
+			&monitor.PerfDataItem{Name: r.Endpoint + "_p99", Value: r.P99.Seconds(), Warn: c.latencyWarn.Seconds(), Crit: c.latencyCrit.Seconds(), Unit: "s", Help: "The 99th percentile request latency of the endpoint"},
+			&monitor.PerfDataItem{Name: r.Endpoint + "_error_rate", Value: r.ErrorRate, Warn: c.errorsWarn, Crit: c.errorsCrit, Unit: "%", Help: "The percentage of failed requests to the endpoint"},
+			&monitor.PerfDataItem{Name: r.Endpoint + "_requests", Value: float64(r.Requests), Help: "The number of requests sent to the endpoint"},
+		)
+
+		switch {
+		case r.Requests < c.minRequests:
+			check.Critical("%s completed %d requests", r.Endpoint, r.Requests)
+		case r.ErrorRate >= c.errorsCrit:
+			check.Critical("%s error rate %.2f%%", r.Endpoint, r.ErrorRate)
+		case r.ErrorRate >= c.errorsWarn:
+			check.Warn("%s error rate %.2f%%", r.Endpoint, r.ErrorRate)
+		}
+
+		switch {
+		case r.P99 >= c.latencyCrit:
+			check.Critical("%s p99 latency %v", r.Endpoint, r.P99)
+		case r.P99 >= c.latencyWarn:
+			check.Warn("%s p99 latency %v", r.Endpoint, r.P99)
+		}
+	}
+
+	check.Ok("%d endpoints probed", len(results))
+}
@@ -0,0 +1,245 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"github.com/choria-io/fisk"
+	"github.com/ghodss/yaml"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/micro"
+)
+
+type microServeCmd struct {
+	file      string
+	instances int
+}
+
+// microMockService is the YAML definition of a mock service
+type microMockService struct {
+	Name        string               `json:"name"`
+	Version     string               `json:"version"`
+	Description string               `json:"description"`
+	Metadata    map[string]string    `json:"metadata"`
+	Endpoints   []*microMockEndpoint `json:"endpoints"`
+}
+
+// microMockEndpoint is an endpoint of a mock service, responses may use the same templates as nats reply
+type microMockEndpoint struct {
+	Name             string            `json:"name"`
+	Subject          string            `json:"subject"`
+	Response         string            `json:"response"`
+	Headers          map[string]string `json:"headers"`
+	Latency          string            `json:"latency"`
+	Jitter           string            `json:"jitter"`
+	ErrorRate        float64           `json:"error_rate"`
+	ErrorCode        string            `json:"error_code"`
+	ErrorDescription string            `json:"error_description"`
+	Metadata         map[string]string `json:"metadata"`
+
+	latency time.Duration
+	jitter  time.Duration
+	count   int64
+}
+
+func configureMicroServeCommand(mc *fisk.CmdClause) {
+	c := &microServeCmd{}
+
+	help := `Serves a mock Micro service defined in a YAML file
+
+The service responds to discovery, stats and ping requests like a real
+service, allowing clients and dashboards to be developed before the real
+service exists. An example definition:
+
+   name: orders
+   version: 1.0.0
+   description: Mock orders service
+   endpoints:
+     - name: get
+       subject: orders.get
+       response: '{"id": "{{ ID }}", "request": {{ Request }}}'
+       latency: 10ms
+       jitter: 5ms
+     - name: create
+       subject: orders.create
+       response: '{"created": true}'
+       error_rate: 10
+       error_code: "500"
+       error_description: simulated failure
+
+Responses may use the same templates as nats reply. Requests to an
+endpoint are handled one at a time, latency delays the response and
+error_rate is the percentage of requests failing with the given error.
+`
+
+	serve := publishing(mc.Command("serve", help).Action(c.serveAction))
+	serve.Arg("file", "The YAML service definition").Required().ExistingFileVar(&c.file)
+	serve.Flag("instances", "Number of service instances to run").Default("1").IntVar(&c.instances)
+}
+
+func loadMicroMockService(file string) (*microMockService, error) {
+	body, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	j, err := yaml.YAMLToJSON(body)
+	if err != nil {
+		return nil, fmt.Errorf("invalid service definition %s: %w", file, err)
+	}
+
+	svc := &microMockService{}
+	err = json.Unmarshal(j, svc)
+	if err != nil {
+		return nil, fmt.Errorf("invalid service definition %s: %w", file, err)
+	}
+
+	if svc.Name == "" {
+		return nil, fmt.Errorf("invalid service definition %s: name is required", file)
+	}
+	if svc.Version == "" {
+		svc.Version = "0.0.1"
+	}
+	if len(svc.Endpoints) == 0 {
+		return nil, fmt.Errorf("invalid service definition %s: no endpoints defined", file)
+	}
+
+	for i, e := range svc.Endpoints {
+		if e.Name == "" {
+			return nil, fmt.Errorf("invalid service definition %s: endpoint %d has no name", file, i+1)
+		}
+		if e.Subject == "" {
+			e.Subject = e.Name
+		}
+		if e.ErrorRate < 0 || e.ErrorRate > 100 {
+			return nil, fmt.Errorf("invalid service definition %s: error_rate of endpoint %s must be between 0 and 100", file, e.Name)
+		}
+		if e.ErrorRate > 0 && e.ErrorCode == "" {
+			e.ErrorCode = "500"
+		}
+		if e.ErrorRate > 0 && e.ErrorDescription == "" {
+			e.ErrorDescription = "simulated error"
+		}
+
+		if e.Latency != "" {
+			e.latency, err = parseDurationString(e.Latency)
+			if err != nil {
+				return nil, fmt.Errorf("invalid service definition %s: invalid latency for endpoint %s: %w", file, e.Name, err)
+			}
+		}
+		if e.Jitter != "" {
+			e.jitter, err = parseDurationString(e.Jitter)
+			if err != nil {
+				return nil, fmt.Errorf("invalid service definition %s: invalid jitter for endpoint %s: %w", file, e.Name, err)
+			}
+		}
+	}
+
+	return svc, nil
+}
+
+func (e *microMockEndpoint) handle(req micro.Request) {
+	delay := e.latency
+	if e.jitter > 0 {
+		delay += time.Duration(rand.Int63n(int64(e.jitter)))
+	}
+	if delay > 0 {
+		time.Sleep(delay)
+	}
+
+	if e.ErrorRate > 0 && rand.Float64()*100 < e.ErrorRate {
+		req.Error(e.ErrorCode, e.ErrorDescription, nil)
+		return
+	}
+
+	ctr := atomic.AddInt64(&e.count, 1)
+	body, err := pubReplyBodyTemplate(e.Response, string(req.Data()), int(ctr))
+	if err != nil {
+		log.Printf("Could not parse response template for endpoint %s: %s", e.Name, err)
+		req.Error("500", "invalid response template", nil)
+		return
+	}
+
+	if opts.Trace {
+		log.Printf("Endpoint %s received %d bytes on %s", e.Name, len(req.Data()), req.Subject())
+	}
+
+	if len(e.Headers) == 0 {
+		req.Respond(body)
+		return
+	}
+
+	hdr := micro.Headers{}
+	for k, v := range e.Headers {
+		nats.Header(hdr).Set(k, v)
+	}
+	req.Respond(body, micro.WithHeaders(hdr))
+}
+
+func (c *microServeCmd) serveAction(_ *fisk.ParseContext) error {
+	def, err := loadMicroMockService(c.file)
+	if err != nil {
+		return withExitCode(ExitValidation, err)
+	}
+	if c.instances < 1 {
+		return withExitCode(ExitValidation, fmt.Errorf("at least one instance is required"))
+	}
+
+	nc, err := newNatsConn("", natsOpts()...)
+	if err != nil {
+		return err
+	}
+	defer nc.Close()
+
+	var services []micro.Service
+	for i := 0; i < c.instances; i++ {
+		svc, err := micro.AddService(nc, micro.Config{
+			Name:        def.Name,
+			Version:     def.Version,
+			Description: def.Description,
+			Metadata:    def.Metadata,
+		})
+		if err != nil {
+			return fmt.Errorf("could not start service %s: %w", def.Name, err)
+		}
+		services = append(services, svc)
+
+		for _, e := range def.Endpoints {
+			err = svc.AddEndpoint(e.Name, micro.HandlerFunc(e.handle), micro.WithEndpointSubject(e.Subject), micro.WithEndpointMetadata(e.Metadata))
+			if err != nil {
+				return fmt.Errorf("could not add endpoint %s: %w", e.Name, err)
+			}
+		}
+
+		log.Printf("Serving mock service %s version %s with ID %s", def.Name, def.Version, svc.Info().ID)
+	}
+
+	for _, e := range def.Endpoints {
+		log.Printf("  Endpoint %s on %s", e.Name, e.Subject)
+	}
+
+	<-ctx.Done()
+
+	for _, svc := range services {
+		svc.Stop()
+	}
+
+	return nil
+}
@@ -0,0 +1,391 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/choria-io/fisk"
+	"github.com/dustin/go-humanize"
+	"github.com/nats-io/jsm.go"
+	"github.com/nats-io/jsm.go/api"
+	"github.com/nats-io/nats.go"
+)
+
+type migrateCmd struct {
+	fromContext   string
+	toContext     string
+	streams       []string
+	kvs           []string
+	objects       []string
+	withData      bool
+	withConsumers bool
+	noPlacement   bool
+	dryRun        bool
+	force         bool
+}
+
+// migrateAsset is a stream, or the stream backing a bucket, selected for migration
+type migrateAsset struct {
+	kind   string
+	name   string
+	stream *jsm.Stream
+
+	action    string
+	copied    uint64
+	expected  uint64
+	target    uint64
+	consumers int
+	problem   string
+}
+
+// migrateHeaders are headers not copied with messages as they would cause the target to reject or remove messages
+var migrateHeaders = []string{api.JSMsgId, api.JSExpectedStream, api.JSExpectedLastSeq, api.JSExpectedLastSubjSeq, api.JSExpectedLastMsgId, api.JSRollup}
+
+func configureMigrateCommand(app commandHost) {
+	c := &migrateCmd{}
+
+	help := `Migrates JetStream assets between clusters
+
+Streams, Key-Value and Object Store buckets are created on the target
+using the configuration they have on the source, optionally followed
+by their durable Consumers and their data.
+
+Data is copied by reading all messages from the source and publishing
+them to the target, message timestamps and sequences are not preserved.
+Streams that mirror or source other Streams receive their data from
+their origin and are not copied. Consumer delivery state is not migrated.
+
+When no Streams or buckets are selected all are migrated, patterns
+are shell style globs like 'ORDERS_*'.
+`
+
+	migrate := mutating(app.Command("migrate", help).Action(c.migrateAction))
+	migrate.Flag("from-context", "The context to migrate assets from").HintAction(contextNamesHint).Required().PlaceHolder("NAME").StringVar(&c.fromContext)
+	migrate.Flag("to-context", "The context to migrate assets to").HintAction(contextNamesHint).Required().PlaceHolder("NAME").StringVar(&c.toContext)
+	migrate.Flag("streams", "Migrates Streams matching a pattern (pass multiple times)").PlaceHolder("PATTERN").StringsVar(&c.streams)
+	migrate.Flag("kv", "Migrates Key-Value buckets matching a pattern (pass multiple times)").PlaceHolder("PATTERN").StringsVar(&c.kvs)
+	migrate.Flag("objects", "Migrates Object Store buckets matching a pattern (pass multiple times)").PlaceHolder("PATTERN").StringsVar(&c.objects)
+	migrate.Flag("with-data", "Copies messages to the target").UnNegatableBoolVar(&c.withData)
+	migrate.Flag("with-consumers", "Creates durable Consumers on the target").UnNegatableBoolVar(&c.withConsumers)
+	migrate.Flag("no-placement", "Removes placement directives from configurations").UnNegatableBoolVar(&c.noPlacement)
+	migrate.Flag("dry-run", "Only shows the assets that would be migrated").UnNegatableBoolVar(&c.dryRun)
+	migrate.Flag("force", "Migrate without prompting").UnNegatableBoolVar(&c.force)
+	addCheat("migrate", migrate)
+}
+
+func init() {
+	registerCommand("migrate", 11, configureMigrateCommand)
+}
+
+func (c *migrateCmd) migrateAction(_ *fisk.ParseContext) error {
+	if c.fromContext == c.toContext {
+		return withExitCode(ExitValidation, fmt.Errorf("source and target contexts cannot be the same"))
+	}
+
+	for _, p := range append(append(append([]string{}, c.streams...), c.kvs...), c.objects...) {
+		_, err := path.Match(p, "")
+		if err != nil {
+			return withExitCode(ExitValidation, fmt.Errorf("invalid pattern %q: %w", p, err))
+		}
+	}
+
+	snc, smgr, sjs, err := prepareContextHelper(c.fromContext)
+	if err != nil {
+		return err
+	}
+	defer snc.Close()
+
+	tnc, tmgr, tjs, err := prepareContextHelper(c.toContext)
+	if err != nil {
+		return err
+	}
+	defer tnc.Close()
+
+	assets, err := c.selectAssets(smgr)
+	if err != nil {
+		return err
+	}
+
+	if len(assets) == 0 {
+		fmt.Println("No Streams or buckets matched")
+		return nil
+	}
+
+	fmt.Printf("Migrating %d assets from context %s to %s:\n\n", len(assets), c.fromContext, c.toContext)
+	for _, a := range assets {
+		nfo, err := a.stream.LatestInformation()
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("  %s %s with %s messages\n", a.kind, a.name, humanize.Comma(int64(nfo.State.Msgs)))
+	}
+	fmt.Println()
+
+	if c.dryRun {
+		return nil
+	}
+
+	if !c.force {
+		ok, err := askConfirmation(fmt.Sprintf("Really migrate %d assets to context %s", len(assets), c.toContext), false)
+		if err != nil {
+			return err
+		}
+
+		if !ok {
+			return nil
+		}
+	}
+
+	for _, a := range assets {
+		err = c.migrateAsset(a, sjs, tmgr, tjs)
+		if err != nil {
+			a.problem = err.Error()
+		}
+	}
+
+	return c.renderResults(assets)
+}
+
+// selectAssets finds the streams and buckets on the source that match the selection patterns
+func (c *migrateCmd) selectAssets(mgr *jsm.Manager) ([]*migrateAsset, error) {
+	all := len(c.streams) == 0 && len(c.kvs) == 0 && len(c.objects) == 0
+
+	matches := func(patterns []string, name string) bool {
+		if all {
+			return true
+		}
+
+		for _, p := range patterns {
+			if ok, _ := path.Match(p, name); ok {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	var assets []*migrateAsset
+	_, err := mgr.EachStream(nil, func(s *jsm.Stream) {
+		name := s.Name()
+
+		switch {
+		case strings.HasPrefix(name, "KV_"):
+			if matches(c.kvs, strings.TrimPrefix(name, "KV_")) {
+				assets = append(assets, &migrateAsset{kind: manifestKeyValue, name: strings.TrimPrefix(name, "KV_"), stream: s})
+			}
+		case strings.HasPrefix(name, "OBJ_"):
+			if matches(c.objects, strings.TrimPrefix(name, "OBJ_")) {
+				assets = append(assets, &migrateAsset{kind: manifestObjectStore, name: strings.TrimPrefix(name, "OBJ_"), stream: s})
+			}
+		default:
+			if matches(c.streams, name) {
+				assets = append(assets, &migrateAsset{kind: manifestStream, name: name, stream: s})
+			}
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return assets, nil
+}
+
+func (c *migrateCmd) migrateAsset(a *migrateAsset, sjs nats.JetStreamContext, tmgr *jsm.Manager, tjs nats.JetStreamContext) error {
+	cfg := a.stream.Configuration()
+	sealed := cfg.Sealed
+	cfg.Sealed = false
+	if c.noPlacement {
+		cfg.Placement = nil
+	}
+
+	known, err := tmgr.IsKnownStream(cfg.Name)
+	if err != nil {
+		return err
+	}
+
+	var target *jsm.Stream
+	if known {
+		a.action = "exists"
+		target, err = tmgr.LoadStream(cfg.Name)
+	} else {
+		a.action = "created"
+		target, err = tmgr.NewStreamFromDefault(cfg.Name, cfg)
+	}
+	if err != nil {
+		return err
+	}
+
+	copyData := c.withData && !known && cfg.Mirror == nil && len(cfg.Sources) == 0
+	if copyData {
+		err = c.copyMessages(a, sjs, tjs)
+		if err != nil {
+			return fmt.Errorf("copying messages failed: %w", err)
+		}
+	}
+
+	if c.withConsumers {
+		err = c.migrateConsumers(a, tmgr)
+		if err != nil {
+			return err
+		}
+	}
+
+	if sealed && !known {
+		cfg.Sealed = true
+		err = target.UpdateConfiguration(cfg)
+		if err != nil {
+			return fmt.Errorf("sealing failed: %w", err)
+		}
+	}
+
+	nfo, err := target.Information()
+	if err != nil {
+		return err
+	}
+	a.target = nfo.State.Msgs
+
+	if copyData && a.target != a.expected {
+		return fmt.Errorf("expected %d messages on the target", a.expected)
+	}
+
+	return nil
+}
+
+// copyMessages reads all messages from the source stream using an ordered consumer and publishes them to the target
+func (c *migrateCmd) copyMessages(a *migrateAsset, sjs nats.JetStreamContext, tjs nats.JetStreamContext) error {
+	nfo, err := a.stream.Information()
+	if err != nil {
+		return err
+	}
+
+	a.expected = nfo.State.Msgs
+	if a.expected == 0 {
+		return nil
+	}
+
+	sub, err := sjs.SubscribeSync("", nats.BindStream(a.stream.Name()), nats.OrderedConsumer(), nats.DeliverAll())
+	if err != nil {
+		return err
+	}
+	defer sub.Unsubscribe()
+
+	timeout := opts.Timeout
+	if timeout < 5*time.Second {
+		timeout = 5 * time.Second
+	}
+
+	for {
+		msg, err := sub.NextMsg(timeout)
+		if err != nil {
+			return err
+		}
+
+		meta, err := msg.Metadata()
+		if err != nil {
+			return err
+		}
+
+		out := nats.NewMsg(msg.Subject)
+		out.Data = msg.Data
+		for k, v := range msg.Header {
+			out.Header[k] = v
+		}
+		for _, h := range migrateHeaders {
+			out.Header.Del(h)
+		}
+
+		_, err = tjs.PublishMsgAsync(out)
+		if err != nil {
+			return err
+		}
+		a.copied++
+
+		// messages published after the copy started are not copied
+		if meta.NumPending == 0 || meta.Sequence.Stream >= nfo.State.LastSeq {
+			break
+		}
+	}
+
+	select {
+	case <-tjs.PublishAsyncComplete():
+	case <-time.After(timeout):
+		return fmt.Errorf("timeout waiting for messages to be stored")
+	}
+
+	a.expected = a.copied
+
+	return nil
+}
+
+func (c *migrateCmd) migrateConsumers(a *migrateAsset, tmgr *jsm.Manager) error {
+	names, err := a.stream.ConsumerNames()
+	if err != nil {
+		return err
+	}
+
+	for _, name := range names {
+		cons, err := a.stream.LoadConsumer(name)
+		if err != nil {
+			return err
+		}
+
+		// ephemeral consumers belong to running clients
+		if cons.IsEphemeral() {
+			continue
+		}
+
+		known, err := tmgr.IsKnownConsumer(a.stream.Name(), name)
+		if err != nil {
+			return err
+		}
+
+		if !known {
+			_, err = tmgr.NewConsumerFromDefault(a.stream.Name(), cons.Configuration())
+			if err != nil {
+				return fmt.Errorf("could not create Consumer %s: %w", name, err)
+			}
+		}
+
+		a.consumers++
+	}
+
+	return nil
+}
+
+func (c *migrateCmd) renderResults(assets []*migrateAsset) error {
+	table := newTableWriter(fmt.Sprintf("Migration from %s to %s", c.fromContext, c.toContext))
+	table.AddHeaders("Kind", "Name", "Action", "Copied", "Target Messages", "Consumers", "Problem")
+
+	failed := 0
+	for _, a := range assets {
+		if a.problem != "" {
+			failed++
+		}
+
+		table.AddRow(a.kind, a.name, a.action, humanize.Comma(int64(a.copied)), humanize.Comma(int64(a.target)), a.consumers, a.problem)
+	}
+
+	fmt.Println(table.Render())
+
+	if failed > 0 {
+		return withExitCode(ExitThreshold, fmt.Errorf("%d of %d assets failed to migrate", failed, len(assets)))
+	}
+
+	return nil
+}
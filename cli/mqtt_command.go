@@ -0,0 +1,355 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/choria-io/fisk"
+	"github.com/dustin/go-humanize"
+	"github.com/nats-io/jsm.go"
+	"github.com/nats-io/jsm.go/api"
+)
+
+// These are the streams the NATS Server uses to persist MQTT state
+const (
+	mqttSessStream     = "$MQTT_sess"
+	mqttMsgsStream     = "$MQTT_msgs"
+	mqttRetainedStream = "$MQTT_rmsgs"
+	mqttQoS2InStream   = "$MQTT_qos2in"
+	mqttQoS2InPrefix   = "$MQTT.qos2.in."
+)
+
+type mqttCmd struct {
+	clientID string
+	force    bool
+	json     bool
+	limit    int
+}
+
+// mqttSession is the session state persisted by the server along with some information we derive
+type mqttSession struct {
+	Origin      string                         `json:"origin,omitempty"`
+	ID          string                         `json:"id"`
+	Clean       bool                           `json:"clean,omitempty"`
+	Subs        map[string]byte                `json:"subs,omitempty"`
+	Cons        map[string]*api.ConsumerConfig `json:"cons,omitempty"`
+	Updated     time.Time                      `json:"updated"`
+	QoS2Pending int                            `json:"qos2_pending"`
+
+	subject string
+	hash    string
+}
+
+type mqttRetainedMessage struct {
+	Origin  string `json:"origin,omitempty"`
+	Subject string `json:"subject,omitempty"`
+	Topic   string `json:"topic,omitempty"`
+	Msg     []byte `json:"msg,omitempty"`
+	Flags   byte   `json:"flags,omitempty"`
+	Source  string `json:"source,omitempty"`
+}
+
+func configureMQTTCommand(app commandHost) {
+	c := &mqttCmd{}
+
+	mqtt := app.Command("mqtt", "Inspect MQTT state stored in JetStream")
+	addCheat("mqtt", mqtt)
+
+	sessions := mqtt.Command("sessions", "Manage MQTT client sessions").Alias("session").Alias("sess")
+
+	ls := sessions.Command("ls", "List MQTT client sessions").Alias("list").Action(c.sessionsListAction)
+	ls.Flag("json", "Produce JSON output").Short('j').UnNegatableBoolVar(&c.json)
+
+	rm := mutating(sessions.Command("rm", "Removes an MQTT client session and its state").Alias("delete").Action(c.sessionsRemoveAction))
+	rm.Arg("client", "The MQTT Client ID to remove").Required().StringVar(&c.clientID)
+	rm.Flag("force", "Force removal without prompting").Short('f').UnNegatableBoolVar(&c.force)
+
+	retained := mqtt.Command("retained", "Inspect retained MQTT messages").Alias("ret")
+
+	rls := retained.Command("ls", "List retained messages").Alias("list").Action(c.retainedListAction)
+	rls.Flag("limit", "Maximum number of retained messages to show").Default("1000").IntVar(&c.limit)
+	rls.Flag("json", "Produce JSON output").Short('j').UnNegatableBoolVar(&c.json)
+}
+
+func init() {
+	registerCommand("mqtt", 10, configureMQTTCommand)
+}
+
+func (c *mqttCmd) loadStream(mgr *jsm.Manager, name string) (*jsm.Stream, error) {
+	known, err := mgr.IsKnownStream(name)
+	if err != nil {
+		return nil, err
+	}
+	if !known {
+		return nil, fmt.Errorf("stream %s not found, ensure MQTT is enabled and has been used in this account", name)
+	}
+
+	return mgr.LoadStream(name)
+}
+
+// qos2Pending counts incoming QoS 2 messages awaiting PUBREL per session hash, only newer servers store these
+func (c *mqttCmd) qos2Pending(mgr *jsm.Manager) (map[string]int, error) {
+	pending := map[string]int{}
+
+	known, err := mgr.IsKnownStream(mqttQoS2InStream)
+	if err != nil || !known {
+		return pending, err
+	}
+
+	stream, err := mgr.LoadStream(mqttQoS2InStream)
+	if err != nil {
+		return nil, err
+	}
+
+	nfo, err := stream.Information(api.JSApiStreamInfoRequest{SubjectsFilter: mqttQoS2InPrefix + ">"})
+	if err != nil {
+		return nil, err
+	}
+
+	// subjects are $MQTT.qos2.in.<session hash>.<packet id>
+	for subj, cnt := range nfo.State.Subjects {
+		parts := strings.Split(strings.TrimPrefix(subj, mqttQoS2InPrefix), ".")
+		pending[parts[0]] += int(cnt)
+	}
+
+	return pending, nil
+}
+
+func (c *mqttCmd) loadSessions(mgr *jsm.Manager) ([]*mqttSession, error) {
+	stream, err := c.loadStream(mgr, mqttSessStream)
+	if err != nil {
+		return nil, err
+	}
+
+	nfo, err := stream.Information(api.JSApiStreamInfoRequest{SubjectsFilter: ">"})
+	if err != nil {
+		return nil, err
+	}
+
+	pending, err := c.qos2Pending(mgr)
+	if err != nil {
+		return nil, err
+	}
+
+	var sessions []*mqttSession
+	for subj := range nfo.State.Subjects {
+		msg, err := stream.ReadLastMessageForSubject(subj)
+		if err != nil {
+			return nil, fmt.Errorf("could not load session %s: %w", subj, err)
+		}
+
+		sess := &mqttSession{}
+		err = json.Unmarshal(msg.Data, sess)
+		if err != nil {
+			return nil, fmt.Errorf("invalid session %s: %w", subj, err)
+		}
+
+		parts := strings.Split(subj, ".")
+		sess.subject = subj
+		sess.hash = parts[len(parts)-1]
+		sess.Updated = msg.Time
+		sess.QoS2Pending = pending[sess.hash]
+
+		sessions = append(sessions, sess)
+	}
+
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].ID < sessions[j].ID
+	})
+
+	return sessions, nil
+}
+
+func (c *mqttCmd) sessionsListAction(_ *fisk.ParseContext) error {
+	_, mgr, err := prepareHelper("", natsOpts()...)
+	if err != nil {
+		return err
+	}
+
+	sessions, err := c.loadSessions(mgr)
+	if err != nil {
+		return err
+	}
+
+	if c.json {
+		return printJSON(sessions)
+	}
+
+	if len(sessions) == 0 {
+		fmt.Println("No MQTT sessions found")
+		return nil
+	}
+
+	table := newTableWriter(fmt.Sprintf("%d MQTT Sessions", len(sessions)))
+	table.AddHeaders("Client ID", "Clean", "Subscriptions", "Consumers", "QoS 2 Pending", "Origin", "Updated")
+	for _, s := range sessions {
+		table.AddRow(s.ID, s.Clean, len(s.Subs), len(s.Cons), s.QoS2Pending, s.Origin, humanizeDuration(time.Since(s.Updated))+" ago")
+	}
+	fmt.Print(table.Render())
+
+	return nil
+}
+
+func (c *mqttCmd) sessionsRemoveAction(_ *fisk.ParseContext) error {
+	_, mgr, err := prepareHelper("", natsOpts()...)
+	if err != nil {
+		return err
+	}
+
+	sessions, err := c.loadSessions(mgr)
+	if err != nil {
+		return err
+	}
+
+	var sess *mqttSession
+	for _, s := range sessions {
+		if s.ID == c.clientID {
+			sess = s
+			break
+		}
+	}
+	if sess == nil {
+		return fmt.Errorf("no session found for client %q", c.clientID)
+	}
+
+	fmt.Printf("Session %s has %d subscriptions, %d consumers and %d pending QoS 2 messages\n", sess.ID, len(sess.Subs), len(sess.Cons), sess.QoS2Pending)
+	fmt.Println("The client should be disconnected before its session is removed")
+	fmt.Println()
+
+	if !c.force {
+		ok, err := askConfirmation(fmt.Sprintf("Really remove the session for client %s", sess.ID), false)
+		fisk.FatalIfError(err, "could not obtain confirmation")
+
+		if !ok {
+			return nil
+		}
+	}
+
+	for _, cfg := range sess.Cons {
+		if cfg == nil || cfg.Durable == "" {
+			continue
+		}
+
+		cons, err := mgr.LoadConsumer(mqttMsgsStream, cfg.Durable)
+		if jsm.IsNatsError(err, 10014) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("could not load consumer %s: %w", cfg.Durable, err)
+		}
+
+		err = cons.Delete()
+		if err != nil {
+			return fmt.Errorf("could not remove consumer %s: %w", cfg.Durable, err)
+		}
+	}
+
+	if sess.QoS2Pending > 0 {
+		stream, err := mgr.LoadStream(mqttQoS2InStream)
+		if err != nil {
+			return err
+		}
+
+		err = stream.Purge(&api.JSApiStreamPurgeRequest{Subject: fmt.Sprintf("%s%s.>", mqttQoS2InPrefix, sess.hash)})
+		if err != nil {
+			return fmt.Errorf("could not remove QoS 2 state: %w", err)
+		}
+	}
+
+	stream, err := mgr.LoadStream(mqttSessStream)
+	if err != nil {
+		return err
+	}
+
+	err = stream.Purge(&api.JSApiStreamPurgeRequest{Subject: sess.subject})
+	if err != nil {
+		return fmt.Errorf("could not remove session: %w", err)
+	}
+
+	fmt.Printf("Removed session for client %s\n", sess.ID)
+
+	return nil
+}
+
+func (c *mqttCmd) retainedListAction(_ *fisk.ParseContext) error {
+	_, mgr, err := prepareHelper("", natsOpts()...)
+	if err != nil {
+		return err
+	}
+
+	stream, err := c.loadStream(mgr, mqttRetainedStream)
+	if err != nil {
+		return err
+	}
+
+	state, err := stream.LatestState()
+	if err != nil {
+		return err
+	}
+
+	type retained struct {
+		Topic   string    `json:"topic"`
+		Size    int       `json:"size"`
+		QoS     int       `json:"qos"`
+		Origin  string    `json:"origin,omitempty"`
+		Time    time.Time `json:"time"`
+		Message []byte    `json:"message,omitempty"`
+	}
+
+	var msgs []*retained
+	for seq := state.FirstSeq; seq <= state.LastSeq && state.Msgs > 0 && len(msgs) < c.limit; seq++ {
+		msg, err := stream.ReadMessage(seq)
+		if jsm.IsNatsError(err, 10037) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		rm := &mqttRetainedMessage{}
+		err = json.Unmarshal(msg.Data, rm)
+		if err != nil {
+			return fmt.Errorf("invalid retained message %d: %w", seq, err)
+		}
+
+		msgs = append(msgs, &retained{Topic: rm.Topic, Size: len(rm.Msg), QoS: int(rm.Flags>>1) & 0x3, Origin: rm.Origin, Time: msg.Time, Message: rm.Msg})
+	}
+
+	sort.Slice(msgs, func(i, j int) bool {
+		return msgs[i].Topic < msgs[j].Topic
+	})
+
+	if c.json {
+		return printJSON(msgs)
+	}
+
+	if len(msgs) == 0 {
+		fmt.Println("No retained messages found")
+		return nil
+	}
+
+	table := newTableWriter(fmt.Sprintf("%d Retained Messages", len(msgs)))
+	table.AddHeaders("Topic", "Size", "QoS", "Origin", "Stored")
+	for _, m := range msgs {
+		table.AddRow(m.Topic, humanize.IBytes(uint64(m.Size)), m.QoS, m.Origin, humanizeDuration(time.Since(m.Time))+" ago")
+	}
+	fmt.Print(table.Render())
+
+	return nil
+}
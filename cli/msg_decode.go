@@ -0,0 +1,175 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/dustin/go-humanize"
+	"github.com/nats-io/nats.go"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// msgDecoders are the formats message bodies can be decoded from
+var msgDecoders = []string{"json", "proto", "hex"}
+
+// decodeMsgBody renders data in a structured format, proto data is decoded without a schema showing field numbers and wire types
+func decodeMsgBody(data []byte, format string) (string, error) {
+	switch format {
+	case "json":
+		out := &bytes.Buffer{}
+		err := json.Indent(out, bytes.TrimSpace(data), "", "  ")
+		if err != nil {
+			return "", fmt.Errorf("invalid JSON: %w", err)
+		}
+		return out.String(), nil
+
+	case "proto":
+		out := &strings.Builder{}
+		err := decodeProtoWire(out, data, "")
+		if err != nil {
+			return "", fmt.Errorf("invalid protobuf: %w", err)
+		}
+		return strings.TrimSuffix(out.String(), "\n"), nil
+
+	case "hex":
+		return strings.TrimSuffix(hex.Dump(data), "\n"), nil
+
+	default:
+		return "", fmt.Errorf("unknown decoder %q, valid decoders are %s", format, strings.Join(msgDecoders, ", "))
+	}
+}
+
+func decodeProtoWire(out *strings.Builder, data []byte, indent string) error {
+	for len(data) > 0 {
+		num, typ, n := protowire.ConsumeTag(data)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		data = data[n:]
+
+		switch typ {
+		case protowire.VarintType:
+			v, n := protowire.ConsumeVarint(data)
+			if n < 0 {
+				return protowire.ParseError(n)
+			}
+			fmt.Fprintf(out, "%s%d: %d (varint)\n", indent, num, v)
+			data = data[n:]
+
+		case protowire.Fixed32Type:
+			v, n := protowire.ConsumeFixed32(data)
+			if n < 0 {
+				return protowire.ParseError(n)
+			}
+			fmt.Fprintf(out, "%s%d: %d (fixed32, float %g)\n", indent, num, v, math.Float32frombits(v))
+			data = data[n:]
+
+		case protowire.Fixed64Type:
+			v, n := protowire.ConsumeFixed64(data)
+			if n < 0 {
+				return protowire.ParseError(n)
+			}
+			fmt.Fprintf(out, "%s%d: %d (fixed64, double %g)\n", indent, num, v, math.Float64frombits(v))
+			data = data[n:]
+
+		case protowire.BytesType:
+			v, n := protowire.ConsumeBytes(data)
+			if n < 0 {
+				return protowire.ParseError(n)
+			}
+			data = data[n:]
+
+			// length delimited fields are strings, bytes or embedded messages, we can only guess which
+			nested := &strings.Builder{}
+			switch {
+			case len(v) > 0 && isPrintable(string(v)):
+				fmt.Fprintf(out, "%s%d: %q\n", indent, num, v)
+			case len(v) > 0 && decodeProtoWire(nested, v, indent+"  ") == nil:
+				fmt.Fprintf(out, "%s%d: {\n%s%s}\n", indent, num, nested.String(), indent)
+			default:
+				fmt.Fprintf(out, "%s%d: %s (bytes)\n", indent, num, hex.EncodeToString(v))
+			}
+
+		default:
+			return fmt.Errorf("unsupported wire type %d for field %d", typ, num)
+		}
+	}
+
+	return nil
+}
+
+// msgHeaderSize is the size of headers as encoded on the wire
+func msgHeaderSize(hdr nats.Header) int {
+	if len(hdr) == 0 {
+		return 0
+	}
+
+	size := len("NATS/1.0\r\n\r\n")
+	for k, vals := range hdr {
+		for _, v := range vals {
+			size += len(k) + len(": ") + len(v) + len("\r\n")
+		}
+	}
+
+	return size
+}
+
+// msgAnnotations describes the age and size of a message for stream get and view
+func msgAnnotations(received time.Time, data int, headers int, showAge bool, showSize bool) string {
+	var parts []string
+
+	if showAge {
+		parts = append(parts, fmt.Sprintf("Age: %s", humanizeDuration(time.Since(received).Round(time.Second))))
+	}
+
+	if showSize {
+		size := fmt.Sprintf("Size: %s", humanize.IBytes(uint64(data+headers)))
+		if headers > 0 {
+			size = fmt.Sprintf("%s (%s data, %s headers)", size, humanize.IBytes(uint64(data)), humanize.IBytes(uint64(headers)))
+		}
+		parts = append(parts, size)
+	}
+
+	return strings.Join(parts, " ")
+}
+
+// outPutDecodedMSGBody shows a message body after translating it and decoding it using format
+func outPutDecodedMSGBody(data []byte, filter, format, subject, stream string) {
+	if format == "" || len(data) == 0 {
+		outPutMSGBody(data, filter, subject, stream)
+		return
+	}
+
+	data, err := filterDataThroughCmd(data, filter, subject, stream)
+	if err != nil {
+		fmt.Printf("%q\nError while translating msg body: %s\n\n", data, err.Error())
+		return
+	}
+
+	out, err := decodeMsgBody(data, format)
+	if err != nil {
+		fmt.Printf("%q\nError while decoding msg body: %s\n\n", data, err.Error())
+		return
+	}
+
+	fmt.Println(out)
+	fmt.Println()
+}
@@ -0,0 +1,82 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"testing"
+
+	"github.com/nats-io/nats.go"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+func TestDecodeMsgBody(t *testing.T) {
+	out, err := decodeMsgBody([]byte(`{"a":1,"b":[true]}`), "json")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if out != "{\n  \"a\": 1,\n  \"b\": [\n    true\n  ]\n}" {
+		t.Fatalf("unexpected json: %q", out)
+	}
+
+	_, err = decodeMsgBody([]byte("not json"), "json")
+	if err == nil {
+		t.Fatalf("expected invalid json to fail")
+	}
+
+	var inner []byte
+	inner = protowire.AppendTag(inner, 1, protowire.VarintType)
+	inner = protowire.AppendVarint(inner, 300)
+
+	var msg []byte
+	msg = protowire.AppendTag(msg, 1, protowire.BytesType)
+	msg = protowire.AppendString(msg, "hello")
+	msg = protowire.AppendTag(msg, 2, protowire.BytesType)
+	msg = protowire.AppendBytes(msg, inner)
+	msg = protowire.AppendTag(msg, 3, protowire.VarintType)
+	msg = protowire.AppendVarint(msg, 7)
+
+	out, err = decodeMsgBody(msg, "proto")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expect := "1: \"hello\"\n2: {\n  1: 300 (varint)\n}\n3: 7 (varint)"
+	if out != expect {
+		t.Fatalf("unexpected proto:\n%s\nexpected:\n%s", out, expect)
+	}
+
+	_, err = decodeMsgBody([]byte{0x0a, 0x05, 'h'}, "proto")
+	if err == nil {
+		t.Fatalf("expected truncated proto to fail")
+	}
+
+	out, err = decodeMsgBody([]byte("hi"), "hex")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if out != "00000000  68 69                                             |hi|" {
+		t.Fatalf("unexpected hex: %q", out)
+	}
+}
+
+func TestMsgHeaderSize(t *testing.T) {
+	if msgHeaderSize(nil) != 0 {
+		t.Fatalf("expected no size for empty headers")
+	}
+
+	hdr := nats.Header{}
+	hdr.Add("A", "1")
+	if size := msgHeaderSize(hdr); size != len("NATS/1.0\r\nA: 1\r\n\r\n") {
+		t.Fatalf("unexpected size %d", size)
+	}
+}
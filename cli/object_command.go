@@ -45,6 +45,7 @@ type objCommand struct {
 	placementTags       []string
 	maxBucketSize       int64
 	maxBucketSizeString string
+	json                bool
 
 	description string
 	replicas    uint
@@ -65,7 +66,7 @@ NOTE: This is an experimental feature.
 	obj := app.Command("object", help).Alias("obj")
 	addCheat("obj", obj)
 
-	add := obj.Command("add", "Adds a new Object Store Bucket").Action(c.addAction)
+	add := mutating(obj.Command("add", "Adds a new Object Store Bucket").Action(c.addAction))
 	add.Arg("bucket", "The bucket to act on").Required().StringVar(&c.bucket)
 	add.Flag("ttl", "How long to keep objects for").DurationVar(&c.ttl)
 	add.Flag("replicas", "How many replicas of the data to store").Default("1").UintVar(&c.replicas)
@@ -76,8 +77,8 @@ NOTE: This is an experimental feature.
 	add.Flag("cluster", "Place the store on a specific cluster").StringVar(&c.placementCluster)
 	add.PreAction(c.parseLimitStrings)
 
-	put := obj.Command("put", "Puts a file into the store").Action(c.putAction)
-	put.Arg("bucket", "The bucket to act on").Required().StringVar(&c.bucket)
+	put := mutating(obj.Command("put", "Puts a file into the store").Action(c.putAction))
+	put.Arg("bucket", "The bucket to act on").HintAction(objBucketNamesHint).Required().StringVar(&c.bucket)
 	put.Arg("file", "The file to put").ExistingFileVar(&c.file)
 	put.Flag("name", "Override the name supplied to the object store").StringVar(&c.overrideName)
 	put.Flag("description", "Sets an optional description for the object").StringVar(&c.description)
@@ -85,32 +86,37 @@ NOTE: This is an experimental feature.
 	put.Flag("progress", "Disable progress bars").Default("true").BoolVar(&c.progress)
 	put.Flag("force", "Act without confirmation").Short('f').UnNegatableBoolVar(&c.force)
 
-	del := obj.Command("del", "Deletes a file or bucket from the store").Action(c.delAction).Alias("rm")
-	del.Arg("bucket", "The bucket to act on").Required().StringVar(&c.bucket)
-	del.Arg("file", "The file to retrieve").StringVar(&c.file)
+	del := mutating(obj.Command("del", "Deletes a file or bucket from the store").Action(c.delAction).Alias("rm"))
+	del.Arg("bucket", "The bucket to act on").HintAction(objBucketNamesHint).Required().StringVar(&c.bucket)
+	del.Arg("file", "The file to retrieve").HintAction(objectNamesHint(&c.bucket)).StringVar(&c.file)
 	del.Flag("force", "Act without confirmation").Short('f').UnNegatableBoolVar(&c.force)
 
 	get := obj.Command("get", "Retrieves a file from the store").Action(c.getAction)
-	get.Arg("bucket", "The bucket to act on").Required().StringVar(&c.bucket)
-	get.Arg("file", "The file to retrieve").Required().StringVar(&c.file)
+	get.Arg("bucket", "The bucket to act on").HintAction(objBucketNamesHint).Required().StringVar(&c.bucket)
+	get.Arg("file", "The file to retrieve").HintAction(objectNamesHint(&c.bucket)).Required().StringVar(&c.file)
 	get.Flag("output", "Override the output file name").Short('O').StringVar(&c.overrideName)
 	get.Flag("progress", "Disable progress bars").Default("true").BoolVar(&c.progress)
 	get.Flag("force", "Act without confirmation").Short('f').UnNegatableBoolVar(&c.force)
 
 	info := obj.Command("info", "Get information about a bucket or object").Alias("show").Alias("i").Action(c.infoAction)
-	info.Arg("bucket", "The bucket to act on").StringVar(&c.bucket)
-	info.Arg("file", "The file to retrieve").StringVar(&c.file)
+	info.Arg("bucket", "The bucket to act on").HintAction(objBucketNamesHint).StringVar(&c.bucket)
+	info.Arg("file", "The file to retrieve").HintAction(objectNamesHint(&c.bucket)).StringVar(&c.file)
+	addJSONOutputFlag(info, &c.json)
 
 	ls := obj.Command("ls", "List buckets or contents of a specific bucket").Action(c.lsAction)
-	ls.Arg("bucket", "The bucket to act on").StringVar(&c.bucket)
+	pagedCommand(ls)
+	ls.Arg("bucket", "The bucket to act on").HintAction(objBucketNamesHint).StringVar(&c.bucket)
 	ls.Flag("names", "When listing buckets, show just the bucket names").Short('n').UnNegatableBoolVar(&c.listNames)
+	addJSONOutputFlag(ls, &c.json)
 
-	seal := obj.Command("seal", "Seals a bucket preventing further updates").Action(c.sealAction)
-	seal.Arg("bucket", "The bucket to act on").Required().StringVar(&c.bucket)
+	seal := mutating(obj.Command("seal", "Seals a bucket preventing further updates").Action(c.sealAction))
+	seal.Arg("bucket", "The bucket to act on").HintAction(objBucketNamesHint).Required().StringVar(&c.bucket)
 	seal.Flag("force", "Force sealing without prompting").Short('f').UnNegatableBoolVar(&c.force)
 
 	watch := obj.Command("watch", "Watch a bucket for changes").Action(c.watchAction)
-	watch.Arg("bucket", "The bucket to act on").Required().StringVar(&c.bucket)
+	watch.Arg("bucket", "The bucket to act on").HintAction(objBucketNamesHint).Required().StringVar(&c.bucket)
+
+	configureObjectGCCommand(obj)
 }
 
 func init() {
@@ -249,6 +255,10 @@ func (c *objCommand) infoAction(_ *fisk.ParseContext) error {
 		return err
 	}
 
+	if c.json {
+		return printJSON(nfo)
+	}
+
 	c.showObjectInfo(nfo)
 
 	return nil
@@ -265,6 +275,10 @@ func (c *objCommand) showBucketInfo(store nats.ObjectStore) error {
 		nfo = status.(*nats.ObjectBucketStatus).StreamInfo()
 	}
 
+	if c.json {
+		return printJSON(nfo)
+	}
+
 	if nfo == nil {
 		fmt.Printf("Information for Object Store Bucket %s\n", status.Bucket())
 	} else {
@@ -356,6 +370,16 @@ func (c *objCommand) listBuckets() error {
 		return err
 	}
 
+	if c.json {
+		names := []string{}
+		for _, s := range found {
+			names = append(names, strings.TrimPrefix(s.Name(), "OBJ_"))
+		}
+		sort.Strings(names)
+
+		return printJSON(names)
+	}
+
 	if len(found) == 0 {
 		fmt.Println("No Object Store buckets found")
 		return nil
@@ -399,10 +423,17 @@ func (c *objCommand) lsAction(_ *fisk.ParseContext) error {
 	}
 
 	contents, err := obj.List()
-	if err != nil {
+	if err != nil && err != nats.ErrNoObjectsFound {
 		return err
 	}
 
+	if c.json {
+		if contents == nil {
+			contents = []*nats.ObjectInfo{}
+		}
+		return printJSON(contents)
+	}
+
 	if len(contents) == 0 {
 		fmt.Println("No entries found")
 		return nil
@@ -0,0 +1,179 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/choria-io/fisk"
+	"github.com/dustin/go-humanize"
+	"github.com/nats-io/jsm.go/api"
+	"github.com/nats-io/nats.go"
+)
+
+type objGCCmd struct {
+	bucket string
+	minAge time.Duration
+	dryRun bool
+	force  bool
+}
+
+// objOrphan is a set of chunks stored in a bucket that no object refers to
+type objOrphan struct {
+	nuid     string
+	subject  string
+	chunks   uint64
+	lastSeen time.Time
+}
+
+func configureObjectGCCommand(obj *fisk.CmdClause) {
+	c := &objGCCmd{}
+
+	help := `Removes chunks that are not referenced by any object
+
+Interrupted puts and deletes that did not complete leave chunks behind
+that are not visible in the bucket but still consume space.
+
+Chunks written more recently than --min-age are kept as they might
+belong to a put that is still in progress.
+`
+
+	gc := mutating(obj.Command("gc", help).Action(c.gcAction))
+	gc.Arg("bucket", "The bucket to act on").HintAction(objBucketNamesHint).Required().StringVar(&c.bucket)
+	gc.Flag("min-age", "Only removes chunks last written longer ago than this").Default("1h").DurationVar(&c.minAge)
+	gc.Flag("dry-run", "Only reports orphaned chunks").UnNegatableBoolVar(&c.dryRun)
+	gc.Flag("force", "Act without confirmation").Short('f').UnNegatableBoolVar(&c.force)
+}
+
+func (c *objGCCmd) gcAction(_ *fisk.ParseContext) error {
+	_, js, err := prepareJSHelper()
+	if err != nil {
+		return err
+	}
+
+	_, mgr, err := prepareHelper("", natsOpts()...)
+	if err != nil {
+		return err
+	}
+
+	obs, err := js.ObjectStore(c.bucket)
+	if err != nil {
+		return err
+	}
+
+	stream, err := mgr.LoadStream("OBJ_" + c.bucket)
+	if err != nil {
+		return err
+	}
+
+	if stream.Sealed() || !stream.PurgeAllowed() {
+		return fmt.Errorf("bucket %s does not allow removing data", c.bucket)
+	}
+
+	objects, err := obs.List()
+	if err != nil && err != nats.ErrNoObjectsFound {
+		return err
+	}
+
+	referenced := make(map[string]struct{}, len(objects))
+	for _, o := range objects {
+		referenced[o.NUID] = struct{}{}
+	}
+
+	chunkPrefix := fmt.Sprintf("$O.%s.C.", c.bucket)
+	nfo, err := stream.Information(api.JSApiStreamInfoRequest{SubjectsFilter: chunkPrefix + ">"})
+	if err != nil {
+		return err
+	}
+
+	var orphans []*objOrphan
+	var recent int
+	var chunks uint64
+	for subj, cnt := range nfo.State.Subjects {
+		nuid := strings.TrimPrefix(subj, chunkPrefix)
+		if _, ok := referenced[nuid]; ok {
+			continue
+		}
+
+		last, err := stream.ReadLastMessageForSubject(subj)
+		if err != nil {
+			return fmt.Errorf("could not load chunk %s: %w", subj, err)
+		}
+
+		if time.Since(last.Time) < c.minAge {
+			recent++
+			continue
+		}
+
+		orphans = append(orphans, &objOrphan{nuid: nuid, subject: subj, chunks: cnt, lastSeen: last.Time})
+		chunks += cnt
+	}
+
+	if recent > 0 {
+		fmt.Printf("Skipping %d unreferenced objects written within the last %v\n\n", recent, c.minAge)
+	}
+
+	if len(orphans) == 0 {
+		fmt.Printf("No orphaned chunks found in bucket %s\n", c.bucket)
+		return nil
+	}
+
+	sort.Slice(orphans, func(i, j int) bool { return orphans[i].lastSeen.Before(orphans[j].lastSeen) })
+
+	table := newTableWriter(fmt.Sprintf("Orphaned chunks in bucket %s", c.bucket))
+	table.AddHeaders("NUID", "Chunks", "Last Written")
+	for _, o := range orphans {
+		table.AddRow(o.nuid, humanize.Comma(int64(o.chunks)), fmt.Sprintf("%s ago", humanizeDuration(time.Since(o.lastSeen))))
+	}
+	fmt.Println(table.Render())
+
+	if c.dryRun {
+		return nil
+	}
+
+	if !c.force {
+		ok, err := askConfirmation(fmt.Sprintf("Really remove %s orphaned chunks from bucket %s", humanize.Comma(int64(chunks)), c.bucket), false)
+		if err != nil {
+			return err
+		}
+
+		if !ok {
+			return nil
+		}
+	}
+
+	for _, o := range orphans {
+		err = stream.Purge(&api.JSApiStreamPurgeRequest{Subject: o.subject})
+		if err != nil {
+			return fmt.Errorf("could not remove chunks %s: %w", o.subject, err)
+		}
+	}
+
+	after, err := stream.LatestInformation()
+	if err != nil {
+		return err
+	}
+
+	var reclaimed uint64
+	if after.State.Bytes < nfo.State.Bytes {
+		reclaimed = nfo.State.Bytes - after.State.Bytes
+	}
+
+	fmt.Printf("Removed %s chunks of %d orphaned objects reclaiming %s\n", humanize.Comma(int64(chunks)), len(orphans), humanize.IBytes(reclaimed))
+
+	return nil
+}
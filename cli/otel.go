@@ -0,0 +1,380 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/choria-io/fisk"
+	"github.com/nats-io/nats.go"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// When an OpenTelemetry endpoint is configured every command is traced in a span and every request
+// made on NATS connections, like JetStream and system API calls, is traced in a child span
+var (
+	tracer       trace.Tracer
+	tracerCtx    context.Context
+	tracerRoot   trace.Span
+	tracerProv   *sdktrace.TracerProvider
+	tracerMu     sync.Mutex
+	tracerOnce   sync.Once
+	tracePending = map[string]trace.Span{}
+)
+
+// setupTracing starts the span for the command being run when tracing is enabled
+func setupTracing(pc *fisk.ParseContext) error {
+	if opts.OtelEndpoint == "" {
+		return nil
+	}
+
+	u, err := url.Parse(opts.OtelEndpoint)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid OpenTelemetry endpoint %q, must be a http or https URL", opts.OtelEndpoint)
+	}
+
+	eopts := []otlptracehttp.Option{otlptracehttp.WithEndpoint(u.Host)}
+	if u.Scheme == "http" {
+		eopts = append(eopts, otlptracehttp.WithInsecure())
+	}
+	if u.Path != "" && u.Path != "/" {
+		eopts = append(eopts, otlptracehttp.WithURLPath(u.Path))
+	}
+
+	exporter, err := otlptracehttp.New(context.Background(), eopts...)
+	if err != nil {
+		return fmt.Errorf("could not create OpenTelemetry exporter: %w", err)
+	}
+
+	res := resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceName("nats-cli"), semconv.ServiceVersion(Version))
+
+	otel.SetErrorHandler(otel.ErrorHandlerFunc(func(err error) {
+		log.Printf("OpenTelemetry error: %v", err)
+	}))
+
+	tracerMu.Lock()
+	defer tracerMu.Unlock()
+
+	tracerProv = sdktrace.NewTracerProvider(sdktrace.WithBatcher(exporter), sdktrace.WithResource(res))
+	tracer = tracerProv.Tracer("github.com/nats-io/natscli")
+
+	name := "nats"
+	if pc != nil && pc.SelectedCommand != nil {
+		name = "nats " + pc.SelectedCommand.FullCommand()
+	}
+
+	tracerCtx, tracerRoot = tracer.Start(context.Background(), name, trace.WithSpanKind(trace.SpanKindClient))
+
+	return nil
+}
+
+// stopTracing ends the command span, recording err, and sends all spans to the collector
+func stopTracing(err error) {
+	tracerOnce.Do(func() {
+		tracerMu.Lock()
+		defer tracerMu.Unlock()
+
+		if tracerProv == nil {
+			return
+		}
+
+		for subj, span := range tracePending {
+			span.SetStatus(codes.Error, "no response received")
+			span.End()
+			delete(tracePending, subj)
+		}
+
+		if err != nil {
+			tracerRoot.RecordError(err)
+			tracerRoot.SetStatus(codes.Error, err.Error())
+		}
+		tracerRoot.End()
+
+		sctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		tracerProv.Shutdown(sctx)
+	})
+}
+
+func tracingEnabled() bool {
+	tracerMu.Lock()
+	defer tracerMu.Unlock()
+
+	return tracerProv != nil
+}
+
+// traceRequest starts a span for a request published to subject, the span ends when a response is received on reply
+func traceRequest(subject string, reply string, size int) {
+	tracerMu.Lock()
+	defer tracerMu.Unlock()
+
+	if tracerProv == nil {
+		return
+	}
+
+	_, span := tracer.Start(tracerCtx, subject, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(
+		semconv.MessagingSystem("nats"),
+		semconv.MessagingDestinationName(subject),
+		semconv.MessagingMessagePayloadSizeBytes(size),
+	))
+
+	tracePending[reply] = span
+}
+
+// traceResponse ends the span for the request waiting on subject, if any
+func traceResponse(subject string, hdr []byte, data []byte) {
+	tracerMu.Lock()
+	defer tracerMu.Unlock()
+
+	span, ok := tracePending[subject]
+	if !ok {
+		return
+	}
+	delete(tracePending, subject)
+
+	// status only responses like no responders are sent as headers only messages
+	if bytes.HasPrefix(hdr, []byte("NATS/1.0 ")) && len(data) == 0 {
+		status := strings.TrimSpace(strings.SplitN(string(hdr), "\r\n", 2)[0][9:])
+		span.SetAttributes(attribute.String("nats.status", status))
+		span.SetStatus(codes.Error, status)
+		span.End()
+		return
+	}
+
+	var resp struct {
+		Error *struct {
+			Code        int    `json:"code"`
+			ErrCode     int    `json:"err_code"`
+			Description string `json:"description"`
+		} `json:"error"`
+	}
+
+	if json.Unmarshal(data, &resp) == nil && resp.Error != nil {
+		span.SetAttributes(attribute.Int("nats.api.error_code", resp.Error.Code), attribute.Int("nats.api.err_code", resp.Error.ErrCode))
+		span.SetStatus(codes.Error, resp.Error.Description)
+	}
+
+	span.End()
+}
+
+// tracingOption is a nats.Option that traces requests made on the connection
+func tracingOption() nats.Option {
+	return func(o *nats.Options) error {
+		for _, s := range append(o.Servers, o.Url) {
+			// websocket connections are framed so the protocol can not be traced
+			if strings.HasPrefix(s, "ws://") || strings.HasPrefix(s, "wss://") {
+				return nil
+			}
+		}
+
+		o.CustomDialer = &tracingDialer{next: o.CustomDialer, opts: o}
+
+		return nil
+	}
+}
+
+// tracingDialer connects to the server and, when required, does the TLS handshake itself so that
+// the decrypted NATS protocol can be observed
+type tracingDialer struct {
+	next nats.CustomDialer
+	opts *nats.Options
+	tls  bool
+}
+
+func (d *tracingDialer) SkipTLSHandshake() bool { return d.tls }
+
+func (d *tracingDialer) Dial(network, address string) (net.Conn, error) {
+	var conn net.Conn
+	var err error
+
+	if d.next != nil {
+		conn, err = d.next.Dial(network, address)
+	} else {
+		conn, err = (&net.Dialer{Timeout: d.opts.Timeout}).Dial(network, address)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	conn.SetReadDeadline(time.Now().Add(d.opts.Timeout))
+	rdr := bufio.NewReader(conn)
+	line, err := rdr.ReadBytes('\n')
+	conn.SetReadDeadline(time.Time{})
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	var info struct {
+		TLSRequired bool `json:"tls_required"`
+	}
+	json.Unmarshal(bytes.TrimPrefix(bytes.TrimSpace(line), []byte("INFO ")), &info)
+
+	d.tls = info.TLSRequired || d.opts.Secure
+	for _, s := range d.opts.Servers {
+		if strings.HasPrefix(s, "tls://") {
+			d.tls = true
+		}
+	}
+
+	if !d.tls {
+		return newTracingConn(conn, io.MultiReader(bytes.NewReader(line), rdr)), nil
+	}
+
+	tlsc := &tls.Config{MinVersion: tls.VersionTLS12}
+	if d.opts.TLSConfig != nil {
+		tlsc = d.opts.TLSConfig.Clone()
+	}
+	if tlsc.ServerName == "" {
+		tlsc.ServerName, _, _ = net.SplitHostPort(address)
+	}
+
+	tconn := tls.Client(&bufferedConn{Conn: conn, rdr: rdr}, tlsc)
+	err = tconn.Handshake()
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return newTracingConn(tconn, io.MultiReader(bytes.NewReader(line), tconn)), nil
+}
+
+// tracingConn observes the NATS protocol in both directions to trace requests and their responses
+type tracingConn struct {
+	net.Conn
+	rdr io.Reader
+	in  *protoParser
+	out *protoParser
+}
+
+func newTracingConn(conn net.Conn, rdr io.Reader) *tracingConn {
+	return &tracingConn{
+		Conn: conn,
+		rdr:  rdr,
+		out: &protoParser{op: func(args []string, payload []byte, hsize int) {
+			// PUB subject reply size and HPUB subject reply hsize size
+			if (args[0] == "PUB" && len(args) == 4) || (args[0] == "HPUB" && len(args) == 5) {
+				traceRequest(args[1], args[2], len(payload))
+			}
+		}},
+		in: &protoParser{op: func(args []string, payload []byte, hsize int) {
+			if args[0] == "MSG" || args[0] == "HMSG" {
+				traceResponse(args[1], payload[:hsize], payload[hsize:])
+			}
+		}},
+	}
+}
+
+func (c *tracingConn) Read(b []byte) (int, error) {
+	n, err := c.rdr.Read(b)
+	c.in.feed(b[:n])
+
+	return n, err
+}
+
+func (c *tracingConn) Write(b []byte) (int, error) {
+	c.out.feed(b)
+
+	return c.Conn.Write(b)
+}
+
+// protoParser splits a stream of NATS protocol into operations and their payloads
+type protoParser struct {
+	buf     []byte
+	args    []string
+	hsize   int
+	pending int
+	op      func(args []string, payload []byte, hsize int)
+}
+
+func (p *protoParser) feed(b []byte) {
+	p.buf = append(p.buf, b...)
+
+	for {
+		if p.args != nil {
+			// the payload is followed by \r\n
+			if len(p.buf) < p.pending+2 {
+				return
+			}
+
+			p.op(p.args, p.buf[:p.pending], p.hsize)
+			p.buf = p.buf[p.pending+2:]
+			p.args = nil
+		} else {
+			idx := bytes.Index(p.buf, []byte("\r\n"))
+			if idx == -1 {
+				return
+			}
+
+			p.parseLine(string(p.buf[:idx]))
+			p.buf = p.buf[idx+2:]
+		}
+
+		// release the buffer once drained to avoid holding on to large payloads
+		if len(p.buf) == 0 {
+			p.buf = nil
+		}
+	}
+}
+
+func (p *protoParser) parseLine(line string) {
+	args := strings.Fields(line)
+	if len(args) == 0 {
+		return
+	}
+	args[0] = strings.ToUpper(args[0])
+
+	switch args[0] {
+	case "PUB", "MSG":
+		size, err := strconv.Atoi(args[len(args)-1])
+		if err != nil {
+			return
+		}
+		p.args, p.pending, p.hsize = args, size, 0
+
+	case "HPUB", "HMSG":
+		if len(args) < 4 {
+			return
+		}
+		hsize, herr := strconv.Atoi(args[len(args)-2])
+		size, err := strconv.Atoi(args[len(args)-1])
+		if err != nil || herr != nil || hsize > size {
+			return
+		}
+		p.args, p.pending, p.hsize = args, size, hsize
+
+	default:
+		p.op(args, nil, 0)
+	}
+}
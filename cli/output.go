@@ -0,0 +1,158 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"text/template"
+
+	"github.com/choria-io/fisk"
+	"github.com/ghodss/yaml"
+	"github.com/itchyny/gojq"
+)
+
+// toJSON renders d as JSON or, when selected using --output-format, as YAML. When a
+// filter is set using --filter-json only the results of the filter are rendered and
+// when a template is set using --template d is rendered using the template
+func toJSON(d any) (string, error) {
+	switch {
+	case opts.OutputTemplate != "" && opts.JSONFilter != "":
+		return "", fmt.Errorf("--template and --filter-json cannot be used together")
+	case opts.OutputTemplate != "":
+		return renderTemplate(d, opts.OutputTemplate)
+	case opts.JSONFilter != "":
+		return filterJSON(d, opts.JSONFilter)
+	}
+
+	return marshalOutput(d)
+}
+
+func printJSON(d any) error {
+	j, err := toJSON(d)
+	if err != nil {
+		return err
+	}
+
+	startPager()
+	fmt.Println(j)
+
+	return nil
+}
+
+func marshalOutput(d any) (string, error) {
+	j, err := json.MarshalIndent(d, "", "  ")
+	if err != nil {
+		return "", err
+	}
+
+	if opts.OutputFormat == "yaml" {
+		j, err = yaml.JSONToYAML(j)
+		if err != nil {
+			return "", err
+		}
+		j = bytes.TrimSuffix(j, []byte("\n"))
+	}
+
+	return string(j), nil
+}
+
+// filterJSON applies a jq style filter to d, strings are rendered without quotes to ease use in scripts
+func filterJSON(d any, filter string) (string, error) {
+	query, err := gojq.Parse(filter)
+	if err != nil {
+		return "", fmt.Errorf("invalid filter %q: %w", filter, err)
+	}
+
+	// the query only operates on basic types so we round trip d via JSON
+	j, err := json.Marshal(d)
+	if err != nil {
+		return "", err
+	}
+
+	var input any
+	err = json.Unmarshal(j, &input)
+	if err != nil {
+		return "", err
+	}
+
+	var out []string
+	iter := query.Run(input)
+	for {
+		v, ok := iter.Next()
+		if !ok {
+			break
+		}
+
+		switch r := v.(type) {
+		case error:
+			return "", fmt.Errorf("filter %q failed: %w", filter, r)
+		case string:
+			out = append(out, r)
+		default:
+			res, err := marshalOutput(r)
+			if err != nil {
+				return "", err
+			}
+			out = append(out, res)
+		}
+	}
+
+	return strings.Join(out, "\n"), nil
+}
+
+// renderTemplate renders d using a Go template, d is the same data that would be rendered as JSON
+func renderTemplate(d any, body string) (string, error) {
+	funcs := template.FuncMap{
+		"json": func(v any) (string, error) {
+			j, err := json.Marshal(v)
+			return string(j), err
+		},
+		"join": func(v []string, sep string) string {
+			return strings.Join(v, sep)
+		},
+	}
+
+	tpl, err := template.New("output").Funcs(funcs).Parse(body)
+	if err != nil {
+		return "", fmt.Errorf("invalid template: %w", err)
+	}
+
+	var b bytes.Buffer
+	err = tpl.Execute(&b, d)
+	if err != nil {
+		return "", fmt.Errorf("could not render template: %w", err)
+	}
+
+	return strings.TrimSuffix(b.String(), "\n"), nil
+}
+
+// structuredOutput determines if structured output was selected using --output-format, --filter-json or --template
+func structuredOutput() bool {
+	return opts.OutputFormat == "json" || opts.OutputFormat == "yaml" || opts.JSONFilter != "" || opts.OutputTemplate != ""
+}
+
+// addJSONOutputFlag adds the --json flag to cmd, the flag is also set when structured output
+// was selected using --output-format, --filter-json or --template so commands only need to consider the flag
+func addJSONOutputFlag(cmd *fisk.CmdClause, json *bool) {
+	cmd.Flag("json", "Produce JSON output").Short('j').UnNegatableBoolVar(json)
+	cmd.PreAction(func(_ *fisk.ParseContext) error {
+		if structuredOutput() {
+			*json = true
+		}
+		return nil
+	})
+}
@@ -0,0 +1,79 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"testing"
+
+	"github.com/nats-io/jsm.go/api"
+)
+
+func TestFilterJSON(t *testing.T) {
+	info := api.StreamInfo{Config: api.StreamConfig{Name: "ORDERS", Subjects: []string{"ORDERS.new", "ORDERS.shipped"}, MaxMsgs: 10}}
+
+	for _, tc := range []struct {
+		filter string
+		expect string
+	}{
+		{".config.name", "ORDERS"},
+		{".config.max_msgs", "10"},
+		{".config.subjects[]", "ORDERS.new\nORDERS.shipped"},
+		{".config | {name}", "{\n  \"name\": \"ORDERS\"\n}"},
+	} {
+		res, err := filterJSON(info, tc.filter)
+		assertNoError(t, err)
+		if res != tc.expect {
+			t.Fatalf("filter %q produced %q expected %q", tc.filter, res, tc.expect)
+		}
+	}
+
+	_, err := filterJSON(info, ".config |")
+	if err == nil {
+		t.Fatalf("expected an error for an invalid filter")
+	}
+}
+
+func TestRenderTemplate(t *testing.T) {
+	info := api.StreamInfo{Config: api.StreamConfig{Name: "ORDERS", Subjects: []string{"ORDERS.new", "ORDERS.shipped"}}, State: api.StreamState{Msgs: 10}}
+
+	res, err := renderTemplate(info, "{{.State.Msgs}} {{.Config.Name}} {{join .Config.Subjects \",\"}}\n")
+	assertNoError(t, err)
+	if res != "10 ORDERS ORDERS.new,ORDERS.shipped" {
+		t.Fatalf("invalid result: %q", res)
+	}
+
+	_, err = renderTemplate(info, "{{.Missing}}")
+	if err == nil {
+		t.Fatalf("expected an error for an unknown field")
+	}
+}
+
+func TestMarshalOutputReports(t *testing.T) {
+	defer func(o *Options) { opts = o }(opts)
+	opts = &Options{OutputFormat: "yaml"}
+
+	res, err := marshalOutput(streamReport{Streams: []streamStat{{Name: "ORDERS", Msgs: 10, Storage: "File"}}, Inaccessible: []string{"X"}})
+	assertNoError(t, err)
+	expect := "inaccessible:\n- X\nstreams:\n- bytes: 0\n  consumers: 0\n  deleted: 0\n  messages: 10\n  name: ORDERS\n  storage: File"
+	if res != expect {
+		t.Fatalf("invalid stream report yaml: %q", res)
+	}
+
+	res, err = marshalOutput(consumerReport{Stream: "ORDERS", Consumers: []*consumerReportEntry{{Name: "C1", Mode: "Pull", NumPending: 5}}})
+	assertNoError(t, err)
+	expect = "consumers:\n- ack_floor_stream_seq: 0\n  ack_policy: \"\"\n  ack_wait: 0\n  mode: Pull\n  name: C1\n  num_ack_pending: 0\n  num_pending: 5\n  num_redelivered: 0\nstream: ORDERS"
+	if res != expect {
+		t.Fatalf("invalid consumer report yaml: %q", res)
+	}
+}
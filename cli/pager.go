@@ -0,0 +1,158 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"os"
+	"os/exec"
+	"sync"
+
+	"github.com/choria-io/fisk"
+	"github.com/google/shlex"
+	"github.com/mattn/go-isatty"
+)
+
+// Long output of commands marked using pagedCommand is sent through a pager like git does, the pager
+// is started when the shared output helpers first render output so that any prompts happen before paging
+var (
+	pagerWanted bool
+	pager       *exec.Cmd
+	pagerStdout *os.File
+	pagerDone   chan struct{}
+	pagerMu     sync.Mutex
+)
+
+// pagedCommand sends the output of cmd through a pager, skip can disable paging for example when watching
+func pagedCommand(cmd *fisk.CmdClause, skip ...func() bool) {
+	cmd.PreAction(func(_ *fisk.ParseContext) error {
+		for _, s := range skip {
+			if s() {
+				return nil
+			}
+		}
+
+		pagerMu.Lock()
+		pagerWanted = true
+		pagerMu.Unlock()
+
+		return nil
+	})
+}
+
+func pagerCommand() []string {
+	pc := os.Getenv("NATS_PAGER")
+	if pc == "" {
+		pc = os.Getenv("PAGER")
+	}
+	if pc == "" {
+		pc = "less"
+	}
+
+	parts, err := shlex.Split(pc)
+	if err != nil || len(parts) == 0 || parts[0] == "cat" {
+		return nil
+	}
+
+	return parts
+}
+
+// startPager starts the pager when the current command is paged and output is to a terminal
+func startPager() {
+	pagerMu.Lock()
+	defer pagerMu.Unlock()
+
+	if !pagerWanted || pager != nil || opts.NoPager || structuredOutput() || !isatty.IsTerminal(os.Stdout.Fd()) {
+		return
+	}
+
+	parts := pagerCommand()
+	if parts == nil {
+		return
+	}
+
+	rdr, wrtr, err := os.Pipe()
+	if err != nil {
+		return
+	}
+
+	cmd := exec.Command(parts[0], parts[1:]...)
+	cmd.Stdin = rdr
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.Env = os.Environ()
+	if os.Getenv("LESS") == "" {
+		// quit when the output fits the screen, keep colors and do not clear the screen
+		cmd.Env = append(cmd.Env, "LESS=FRX")
+	}
+
+	err = cmd.Start()
+	if err != nil {
+		rdr.Close()
+		wrtr.Close()
+		return
+	}
+	rdr.Close()
+
+	pager = cmd
+	pagerStdout = os.Stdout
+	pagerDone = make(chan struct{})
+	os.Stdout = wrtr
+
+	go func(done chan struct{}) {
+		cmd.Wait()
+		close(done)
+	}(pagerDone)
+}
+
+// pagerActive determines if output is currently being sent to a pager
+func pagerActive() bool {
+	pagerMu.Lock()
+	defer pagerMu.Unlock()
+
+	return pager != nil
+}
+
+// pagerClosed determines if the user closed the pager before all output was shown
+func pagerClosed() bool {
+	pagerMu.Lock()
+	defer pagerMu.Unlock()
+
+	if pager == nil {
+		return false
+	}
+
+	select {
+	case <-pagerDone:
+		return true
+	default:
+		return false
+	}
+}
+
+// stopPager waits for the user to close the pager and restores standard output
+func stopPager() {
+	pagerMu.Lock()
+	defer pagerMu.Unlock()
+
+	pagerWanted = false
+
+	if pager == nil {
+		return
+	}
+
+	os.Stdout.Close()
+	os.Stdout = pagerStdout
+	<-pagerDone
+	pager = nil
+}
@@ -0,0 +1,88 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/nats-io/nats.go"
+)
+
+// payloadEncodingHeader indicates how a payload was compressed, compressed payloads are decompressed when consuming
+const payloadEncodingHeader = "Content-Encoding"
+
+// payloadCompressions are the supported values for --compress
+var payloadCompressions = []string{"zstd"}
+
+var (
+	zstdEncoder *zstd.Encoder
+	zstdDecoder *zstd.Decoder
+	zstdOnce    sync.Once
+	zstdErr     error
+)
+
+func zstdCodecs() (*zstd.Encoder, *zstd.Decoder, error) {
+	zstdOnce.Do(func() {
+		zstdEncoder, zstdErr = zstd.NewWriter(nil)
+		if zstdErr != nil {
+			return
+		}
+		zstdDecoder, zstdErr = zstd.NewReader(nil)
+	})
+
+	return zstdEncoder, zstdDecoder, zstdErr
+}
+
+// compressPayload compresses the payload of msg in place using algorithm and sets the encoding header
+func compressPayload(msg *nats.Msg, algorithm string) error {
+	switch algorithm {
+	case "":
+		return nil
+
+	case "zstd":
+		enc, _, err := zstdCodecs()
+		if err != nil {
+			return err
+		}
+
+		msg.Data = enc.EncodeAll(msg.Data, nil)
+		msg.Header.Set(payloadEncodingHeader, algorithm)
+
+		return nil
+
+	default:
+		return fmt.Errorf("unsupported compression %q", algorithm)
+	}
+}
+
+// decompressPayload decompresses the payload of msg in place when it was compressed using a supported algorithm
+func decompressPayload(msg *nats.Msg) error {
+	switch msg.Header.Get(payloadEncodingHeader) {
+	case "zstd":
+		_, dec, err := zstdCodecs()
+		if err != nil {
+			return err
+		}
+
+		data, err := dec.DecodeAll(msg.Data, nil)
+		if err != nil {
+			return fmt.Errorf("could not decompress payload: %w", err)
+		}
+		msg.Data = data
+	}
+
+	return nil
+}
@@ -0,0 +1,47 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/nats-io/nats.go"
+)
+
+func TestPayloadCompression(t *testing.T) {
+	body := bytes.Repeat([]byte(`{"name":"test"}`), 100)
+
+	msg := nats.NewMsg("test")
+	msg.Data = body
+	err := compressPayload(msg, "zstd")
+	checkErr(t, err, "compress failed")
+	if len(msg.Data) >= len(body) || msg.Header.Get(payloadEncodingHeader) != "zstd" {
+		t.Fatalf("payload was not compressed")
+	}
+
+	err = decompressPayload(msg)
+	checkErr(t, err, "decompress failed")
+	if !bytes.Equal(msg.Data, body) {
+		t.Fatalf("payload did not round trip")
+	}
+
+	msg = nats.NewMsg("test")
+	msg.Data = []byte("not compressed")
+	msg.Header.Set(payloadEncodingHeader, "zstd")
+	err = decompressPayload(msg)
+	if err == nil {
+		t.Fatalf("expected invalid payload to fail")
+	}
+}
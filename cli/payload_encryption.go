@@ -0,0 +1,170 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/nats-io/jsm.go/natscontext"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nkeys"
+)
+
+// Payloads are encrypted using curve NKeys, the same NaCl box based scheme the server uses for Auth Callout.
+// Every publisher uses a new sender key and sends its public key in payloadXKeyHeader, subscribers decrypt
+// using the seed matching the public key the message was encrypted for. Keys can be created using nk -gen curve
+const payloadXKeyHeader = "Nats-Cli-Sender-Xkey"
+
+// payloadKeyFile is the file holding the curve NKey seed a context uses to decrypt payloads
+func payloadKeyFile(context string) (string, error) {
+	path, err := natscontext.ContextPath(context)
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(filepath.Dir(path), context, "payload.xk"), nil
+}
+
+// parsePayloadKey parses a curve NKey public key or seed, or reads one from a file or secret reference,
+// a nil key pair is returned when key holds a public key
+func parsePayloadKey(key string) (string, nkeys.KeyPair, error) {
+	val := bytes.TrimSpace([]byte(key))
+
+	switch {
+	case nkeys.IsValidPublicCurveKey(string(val)):
+		return string(val), nil, nil
+
+	case bytes.HasPrefix(val, []byte("SX")):
+		kp, err := nkeys.FromCurveSeed(val)
+		if err != nil {
+			return "", nil, fmt.Errorf("invalid curve seed: %w", err)
+		}
+
+		pub, err := kp.PublicKey()
+		if err != nil {
+			return "", nil, err
+		}
+
+		return pub, kp, nil
+	}
+
+	contents, err := readContextFile(key)
+	if err != nil {
+		return "", nil, fmt.Errorf("key is not a curve NKey or a file holding one: %w", err)
+	}
+
+	val = bytes.TrimSpace(contents)
+	if !nkeys.IsValidPublicCurveKey(string(val)) && !bytes.HasPrefix(val, []byte("SX")) {
+		return "", nil, fmt.Errorf("%s does not hold a curve NKey", key)
+	}
+
+	return parsePayloadKey(string(val))
+}
+
+// loadPayloadDecryptKey loads the seed used to decrypt payloads from key, or from the selected context when key
+// is empty, nil is returned when no key is configured
+func loadPayloadDecryptKey(key string) (nkeys.KeyPair, error) {
+	if key == "" {
+		if opts.Config == nil || opts.Config.Name == "" {
+			return nil, nil
+		}
+
+		file, err := payloadKeyFile(opts.Config.Name)
+		if err != nil {
+			return nil, err
+		}
+
+		_, err = os.Stat(file)
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+
+		key = file
+	}
+
+	_, kp, err := parsePayloadKey(key)
+	if err != nil {
+		return nil, err
+	}
+	if kp == nil {
+		return nil, fmt.Errorf("decrypting requires a curve NKey seed, not a public key")
+	}
+
+	return kp, nil
+}
+
+// payloadEncrypter encrypts payloads for a recipient
+type payloadEncrypter struct {
+	recipient string
+	sender    nkeys.KeyPair
+	senderPub string
+}
+
+// newPayloadEncrypter creates an encrypter for the public key held in key, a seed can be given to encrypt for its own public key
+func newPayloadEncrypter(key string) (*payloadEncrypter, error) {
+	recipient, _, err := parsePayloadKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	sender, err := nkeys.CreateCurveKeys()
+	if err != nil {
+		return nil, err
+	}
+
+	senderPub, err := sender.PublicKey()
+	if err != nil {
+		return nil, err
+	}
+
+	return &payloadEncrypter{recipient: recipient, sender: sender, senderPub: senderPub}, nil
+}
+
+func (e *payloadEncrypter) encrypt(msg *nats.Msg) error {
+	data, err := e.sender.Seal(msg.Data, e.recipient)
+	if err != nil {
+		return fmt.Errorf("could not encrypt payload: %w", err)
+	}
+
+	msg.Data = data
+	msg.Header.Set(payloadXKeyHeader, e.senderPub)
+
+	return nil
+}
+
+// decryptPayload decrypts the payload of msg in place when it was encrypted, kp may be nil in which case
+// encrypted payloads fail to decrypt
+func decryptPayload(msg *nats.Msg, kp nkeys.KeyPair) error {
+	sender := msg.Header.Get(payloadXKeyHeader)
+	if sender == "" {
+		return nil
+	}
+
+	if kp == nil {
+		return fmt.Errorf("payload is encrypted but no decryption key is configured")
+	}
+
+	data, err := kp.Open(msg.Data, sender)
+	if err != nil {
+		return fmt.Errorf("could not decrypt payload: %w", err)
+	}
+
+	msg.Data = data
+
+	return nil
+}
@@ -0,0 +1,74 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nkeys"
+)
+
+func TestPayloadEncryption(t *testing.T) {
+	recipient, err := nkeys.CreateCurveKeys()
+	checkErr(t, err, "key creation failed")
+	seed, _ := recipient.Seed()
+	pub, _ := recipient.PublicKey()
+
+	seedFile := filepath.Join(t.TempDir(), "payload.xk")
+	err = os.WriteFile(seedFile, append(seed, '\n'), 0600)
+	checkErr(t, err, "write failed")
+
+	// encrypting for a seed uses its public key
+	for _, key := range []string{pub, string(seed), seedFile} {
+		enc, err := newPayloadEncrypter(key)
+		checkErr(t, err, "encrypter creation failed")
+
+		msg := nats.NewMsg("test")
+		msg.Data = []byte("secret")
+		err = enc.encrypt(msg)
+		checkErr(t, err, "encrypt failed")
+		if string(msg.Data) == "secret" || msg.Header.Get(payloadXKeyHeader) == "" {
+			t.Fatalf("message was not encrypted")
+		}
+
+		err = decryptPayload(msg, nil)
+		if err == nil {
+			t.Fatalf("expected decrypting without a key to fail")
+		}
+
+		kp, err := loadPayloadDecryptKey(seedFile)
+		checkErr(t, err, "loading seed failed")
+		err = decryptPayload(msg, kp)
+		checkErr(t, err, "decrypt failed")
+		if string(msg.Data) != "secret" {
+			t.Fatalf("expected secret got %q", msg.Data)
+		}
+	}
+
+	_, err = loadPayloadDecryptKey(pub)
+	if err == nil {
+		t.Fatalf("expected decrypting with a public key to fail")
+	}
+
+	msg := nats.NewMsg("test")
+	msg.Data = []byte("plain")
+	err = decryptPayload(msg, nil)
+	checkErr(t, err, "unencrypted message failed")
+	if string(msg.Data) != "plain" {
+		t.Fatalf("unencrypted message was modified")
+	}
+}
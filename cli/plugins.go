@@ -0,0 +1,280 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/user"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+
+	"github.com/choria-io/fisk"
+)
+
+// pluginPrefix is the prefix of executables that are exposed as nats sub commands
+const pluginPrefix = "nats-"
+
+// plugin is an external executable exposed as a nats sub command
+type plugin struct {
+	name string
+	path string
+	args []string
+}
+
+// plugins that were registered in the application, by name
+var plugins = map[string]*plugin{}
+
+// pluginsDir is the directory in the nats configuration directory holding plugins
+func pluginsDir() (string, error) {
+	parent := os.Getenv("XDG_CONFIG_HOME")
+	if parent != "" {
+		return filepath.Join(parent, "nats", "plugins"), nil
+	}
+
+	u, err := user.Current()
+	if err != nil {
+		return "", err
+	}
+
+	if u.HomeDir == "" {
+		return "", fmt.Errorf("cannot determine home directory")
+	}
+
+	return filepath.Join(u.HomeDir, ".config", "nats", "plugins"), nil
+}
+
+// findPlugins searches the plugins directory and PATH for nats-<name> executables, the first found for a name is used
+func findPlugins() map[string]string {
+	found := map[string]string{}
+
+	var dirs []string
+	dir, err := pluginsDir()
+	if err == nil {
+		dirs = append(dirs, dir)
+	}
+	dirs = append(dirs, filepath.SplitList(os.Getenv("PATH"))...)
+
+	for _, dir := range dirs {
+		if dir == "" {
+			continue
+		}
+
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+
+		for _, entry := range entries {
+			name, ok := pluginName(entry.Name())
+			if !ok {
+				continue
+			}
+
+			if _, ok := found[name]; ok {
+				continue
+			}
+
+			path := filepath.Join(dir, entry.Name())
+			if !isExecutable(path) {
+				continue
+			}
+
+			found[name] = path
+		}
+	}
+
+	return found
+}
+
+// pluginName extracts the command name from a plugin file name
+func pluginName(file string) (string, bool) {
+	if runtime.GOOS == "windows" {
+		if !strings.EqualFold(filepath.Ext(file), ".exe") {
+			return "", false
+		}
+		file = strings.TrimSuffix(file, filepath.Ext(file))
+	}
+
+	if !strings.HasPrefix(file, pluginPrefix) {
+		return "", false
+	}
+
+	name := strings.TrimPrefix(file, pluginPrefix)
+	if name == "" || strings.HasPrefix(name, "-") || strings.ContainsAny(name, " .") {
+		return "", false
+	}
+
+	return name, true
+}
+
+func isExecutable(path string) bool {
+	stat, err := os.Stat(path)
+	if err != nil || stat.IsDir() {
+		return false
+	}
+
+	if runtime.GOOS == "windows" {
+		return true
+	}
+
+	return stat.Mode().Perm()&0111 != 0
+}
+
+// configurePlugins adds a command for every plugin found that does not conflict with a built-in command
+func configurePlugins(app *fisk.Application) {
+	found := findPlugins()
+
+	var names []string
+	for name := range found {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if app.GetCommand(name) != nil {
+			continue
+		}
+
+		p := &plugin{name: name, path: found[name]}
+		cmd := app.Command(name, fmt.Sprintf("Runs the %s%s plugin from %s", pluginPrefix, name, p.path)).Action(p.runAction)
+		cmd.Arg("args", "Arguments to pass to the plugin").StringsVar(&p.args)
+		plugins[name] = p
+	}
+}
+
+// pluginArgs prevents flags following a plugin command being parsed by the CLI so they are passed to the plugin
+func pluginArgs(app *fisk.Application, args []string) []string {
+	if len(plugins) == 0 {
+		return args
+	}
+
+	// completions are handled by the CLI
+	for _, arg := range args {
+		if arg == "--completion-bash" {
+			return args
+		}
+	}
+
+	valueFlags := map[string]bool{}
+	for _, f := range app.Model().Flags {
+		if f.IsBoolFlag() {
+			continue
+		}
+
+		valueFlags["--"+f.Name] = true
+		if f.Short != 0 {
+			valueFlags["-"+string(f.Short)] = true
+		}
+	}
+
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+
+		switch {
+		case arg == "--":
+			return args
+		case strings.HasPrefix(arg, "-"):
+			if valueFlags[arg] {
+				i++
+			}
+			continue
+		}
+
+		if _, ok := plugins[arg]; !ok {
+			return args
+		}
+
+		if i+1 < len(args) && args[i+1] == "--" {
+			return args
+		}
+
+		res := append([]string{}, args[:i+1]...)
+		res = append(res, "--")
+		return append(res, args[i+1:]...)
+	}
+
+	return args
+}
+
+// pluginEnv is the environment for a plugin, holding the resolved connection settings
+func pluginEnv() []string {
+	env := os.Environ()
+
+	set := func(k string, v string) {
+		if v != "" {
+			env = append(env, k+"="+v)
+		}
+	}
+
+	if exe, err := os.Executable(); err == nil {
+		set("NATS_CLI", exe)
+	}
+	set("NATS_CLI_VERSION", Version)
+	set("NATS_TIMEOUT", opts.Timeout.String())
+
+	nctx := opts.Config
+	if nctx == nil {
+		return env
+	}
+
+	set("NATS_CONTEXT", nctx.Name)
+	set("NATS_URL", nctx.ServerURL())
+	if nctx.Token() != "" {
+		set("NATS_USER", nctx.Token())
+	} else {
+		set("NATS_USER", nctx.User())
+		set("NATS_PASSWORD", nctx.Password())
+	}
+	set("NATS_CREDS", nctx.Creds())
+	set("NATS_NKEY", nctx.NKey())
+	set("NATS_CERT", nctx.Certificate())
+	set("NATS_KEY", nctx.Key())
+	set("NATS_CA", nctx.CA())
+	set("NATS_SOCKS_PROXY", nctx.SocksProxy())
+	set("NATS_INBOX_PREFIX", nctx.InboxPrefix())
+	set("NATS_JS_API_PREFIX", nctx.JSAPIPrefix())
+	set("NATS_JS_EVENT_PREFIX", nctx.JSEventPrefix())
+	set("NATS_JS_DOMAIN", nctx.JSDomain())
+
+	return env
+}
+
+func (p *plugin) runAction(_ *fisk.ParseContext) error {
+	cmd := exec.Command(p.path, p.args...)
+	cmd.Env = pluginEnv()
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	if opts.Trace {
+		log.Printf(">>> Running plugin %s %s", p.path, strings.Join(p.args, " "))
+	}
+
+	err := cmd.Run()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && exitErr.ExitCode() > 0 {
+			return withExitCode(exitErr.ExitCode(), fmt.Errorf("plugin %s failed: %w", p.name, err))
+		}
+
+		return fmt.Errorf("could not run plugin %s: %w", p.name, err)
+	}
+
+	return nil
+}
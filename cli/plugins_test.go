@@ -0,0 +1,58 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/choria-io/fisk"
+)
+
+func TestPluginArgs(t *testing.T) {
+	app := fisk.New("nats", "test")
+	app.Flag("server", "").Short('s').String()
+	app.Flag("trace", "").Bool()
+	app.Command("stream", "")
+
+	orig := plugins
+	plugins = map[string]*plugin{"hello": {name: "hello"}}
+	defer func() { plugins = orig }()
+
+	for _, tc := range []struct {
+		args   []string
+		expect []string
+	}{
+		{[]string{"hello", "--foo", "bar"}, []string{"hello", "--", "--foo", "bar"}},
+		{[]string{"-s", "hello", "hello", "-x"}, []string{"-s", "hello", "hello", "--", "-x"}},
+		{[]string{"--server", "nats://localhost:4222", "--trace", "hello"}, []string{"--server", "nats://localhost:4222", "--trace", "hello", "--"}},
+		{[]string{"hello", "--", "-x"}, []string{"hello", "--", "-x"}},
+		{[]string{"stream", "hello", "--foo"}, []string{"stream", "hello", "--foo"}},
+		{[]string{"--", "hello"}, []string{"--", "hello"}},
+	} {
+		res := pluginArgs(app, tc.args)
+		if !reflect.DeepEqual(res, tc.expect) {
+			t.Fatalf("expected %v for %v got %v", tc.expect, tc.args, res)
+		}
+	}
+
+	if _, ok := pluginName("nats-server"); !ok {
+		t.Fatalf("expected nats-server to be a plugin name")
+	}
+	for _, n := range []string{"nats", "nats-", "nats--x", "natsx", "nats-x.sh"} {
+		if _, ok := pluginName(n); ok {
+			t.Fatalf("expected %q to not be a plugin name", n)
+		}
+	}
+}
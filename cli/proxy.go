@@ -0,0 +1,189 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"golang.org/x/net/proxy"
+)
+
+// proxyDialer connects via a SOCKS5 or HTTP CONNECT proxy, it is used for NATS connections
+// and any other connections the CLI makes to NATS Servers
+type proxyDialer struct {
+	url     *url.URL
+	timeout time.Duration
+}
+
+var _ nats.CustomDialer = (*proxyDialer)(nil)
+
+// bufferedConn is a connection where the proxy handshake might have read data meant for the client
+type bufferedConn struct {
+	net.Conn
+	rdr *bufio.Reader
+}
+
+func (c *bufferedConn) Read(b []byte) (int, error) {
+	return c.rdr.Read(b)
+}
+
+func newProxyDialer(proxyURL string, timeout time.Duration) (*proxyDialer, error) {
+	u, err := url.Parse(proxyURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy %q: %w", proxyURL, err)
+	}
+
+	switch u.Scheme {
+	case "socks5", "socks5h", "http", "https":
+	default:
+		return nil, fmt.Errorf("unsupported proxy scheme %q, supported schemes are socks5, http and https", u.Scheme)
+	}
+
+	if u.Host == "" {
+		return nil, fmt.Errorf("invalid proxy %q: no host", proxyURL)
+	}
+
+	return &proxyDialer{url: u, timeout: timeout}, nil
+}
+
+// selectedProxyDialer is the proxy dialer for the selected context, nil when no proxy is configured
+func selectedProxyDialer() (*proxyDialer, error) {
+	if opts.Config == nil || opts.Config.SocksProxy() == "" {
+		return nil, nil
+	}
+
+	return newProxyDialer(opts.Config.SocksProxy(), opts.Timeout)
+}
+
+// dialDirectOrProxy connects to address using the proxy of the selected context when set
+func dialDirectOrProxy(address string, timeout time.Duration) (net.Conn, error) {
+	pd, err := selectedProxyDialer()
+	if err != nil {
+		return nil, err
+	}
+
+	if pd == nil {
+		return net.DialTimeout("tcp", address, timeout)
+	}
+
+	pd.timeout = timeout
+
+	return pd.Dial("tcp", address)
+}
+
+func (d *proxyDialer) Dial(network, address string) (net.Conn, error) {
+	timeout := d.timeout
+	if timeout == 0 {
+		timeout = 5 * time.Second
+	}
+
+	dctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	return d.DialContext(dctx, network, address)
+}
+
+func (d *proxyDialer) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	switch d.url.Scheme {
+	case "http", "https":
+		return d.dialHTTP(ctx, address)
+	default:
+		dialer, err := proxy.FromURL(d.url, &net.Dialer{})
+		if err != nil {
+			return nil, err
+		}
+
+		cd, ok := dialer.(proxy.ContextDialer)
+		if !ok {
+			return dialer.Dial(network, address)
+		}
+
+		return cd.DialContext(ctx, network, address)
+	}
+}
+
+// dialHTTP establishes a tunnel using the HTTP CONNECT method
+func (d *proxyDialer) dialHTTP(ctx context.Context, address string) (net.Conn, error) {
+	host := d.url.Host
+	if d.url.Port() == "" {
+		if d.url.Scheme == "https" {
+			host = net.JoinHostPort(d.url.Hostname(), "443")
+		} else {
+			host = net.JoinHostPort(d.url.Hostname(), "80")
+		}
+	}
+
+	conn, err := (&net.Dialer{}).DialContext(ctx, "tcp", host)
+	if err != nil {
+		return nil, fmt.Errorf("could not connect to proxy %s: %w", d.url.Redacted(), err)
+	}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+		defer conn.SetDeadline(time.Time{})
+	}
+
+	if d.url.Scheme == "https" {
+		tconn := tls.Client(conn, &tls.Config{ServerName: d.url.Hostname(), MinVersion: tls.VersionTLS12})
+		err = tconn.HandshakeContext(ctx)
+		if err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("could not connect to proxy %s: %w", d.url.Redacted(), err)
+		}
+		conn = tconn
+	}
+
+	req := &http.Request{
+		Method: http.MethodConnect,
+		URL:    &url.URL{Opaque: address},
+		Host:   address,
+		Header: http.Header{},
+	}
+
+	if d.url.User != nil {
+		pass, _ := d.url.User.Password()
+		auth := base64.StdEncoding.EncodeToString([]byte(d.url.User.Username() + ":" + pass))
+		req.Header.Set("Proxy-Authorization", "Basic "+auth)
+	}
+
+	err = req.Write(conn)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	rdr := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(rdr, req)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("invalid response from proxy %s: %w", d.url.Redacted(), err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		conn.Close()
+		return nil, fmt.Errorf("proxy %s refused connection to %s: %s", d.url.Redacted(), address, resp.Status)
+	}
+
+	return &bufferedConn{Conn: conn, rdr: rdr}, nil
+}
@@ -14,14 +14,21 @@
 package cli
 
 import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"math"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/choria-io/fisk"
 	"github.com/gosuri/uiprogress"
+	"github.com/nats-io/jsm.go/api"
 	"github.com/nats-io/nats.go"
 	terminal "golang.org/x/term"
 )
@@ -33,12 +40,39 @@ type pubCmd struct {
 	replyTo      string
 	raw          bool
 	hdrs         []string
+	hdrFile      string
+	msgIDTmpl    string
 	cnt          int
 	sleep        time.Duration
 	replyCount   int
 	replyTimeout time.Duration
 	forceStdin   bool
 	translate    string
+	stdinLines   bool
+	stdinFramed  bool
+	replyWait    time.Duration
+	encryptKey   string
+	compress     string
+	encrypter    *payloadEncrypter
+
+	expectStream         string
+	expectLastSeq        uint64
+	expectLastSeqSet     bool
+	expectLastSubjSeq    uint64
+	expectLastSubjSeqSet bool
+
+	jetstream bool
+	verify    bool
+	retries   int
+	retryWait time.Duration
+	verified  pubVerifyStats
+}
+
+// pubVerifyStats counts the outcomes of JetStream publishes when verifying acknowledgements
+type pubVerifyStats struct {
+	acked     int
+	duplicate int
+	failed    int
 }
 
 func configurePubCommand(app commandHost) {
@@ -62,18 +96,50 @@ Available template functions are:
    Time             the current time
    ID               an unique ID
    Random(min, max) random string at least min long, at most max
+
+Headers may also be read from a file holding one "Key: Value" header per
+line, values in the file may use the same templates. Blank lines and
+lines starting with # are ignored.
+
+   nats pub orders.new --header-file headers.txt "{{ Random 10 100 }}"
+
+The --msg-id-template flag sets the JetStream deduplication header
+Nats-Msg-Id from a template, creating deterministic IDs allows repeated
+runs to be deduplicated by the Stream.
+
+   nats pub orders.new --count 10 --msg-id-template "order-{{ Count }}" "body"
+
+Combined with --verify every acknowledgement is checked and the number of
+acknowledged, duplicate and failed publishes is reported, publishes that
+find no Stream listening are retried according to --retries.
+
+   nats pub orders.new --count 10 --msg-id-template "order-{{ Count }}" --verify "body"
 `
 
-	pub := app.Command("publish", "Generic data publish utility").Alias("pub").Action(c.publish)
+	pub := publishing(app.Command("publish", "Generic data publish utility").Alias("pub").Action(c.publish))
 	addCheat("pub", pub)
 	pub.HelpLong(pubHelp)
 	pub.Arg("subject", "Subject to subscribe to").Required().StringVar(&c.subject)
 	pub.Arg("body", "Message body").Default("!nil!").StringVar(&c.body)
 	pub.Flag("reply", "Sets a custom reply to subject").StringVar(&c.replyTo)
 	pub.Flag("header", "Adds headers to the message").Short('H').StringsVar(&c.hdrs)
+	pub.Flag("header-file", "Adds headers read from a file holding one Key: Value header per line").PlaceHolder("FILE").ExistingFileVar(&c.hdrFile)
+	pub.Flag("msg-id-template", "Sets the Nats-Msg-Id header from a template, for deterministic deduplication IDs").PlaceHolder("TEMPLATE").StringVar(&c.msgIDTmpl)
 	pub.Flag("count", "Publish multiple messages").Default("1").IntVar(&c.cnt)
 	pub.Flag("sleep", "When publishing multiple messages, sleep between publishes").DurationVar(&c.sleep)
 	pub.Flag("force-stdin", "Force reading from stdin").UnNegatableBoolVar(&c.forceStdin)
+	pub.Flag("stdin-lines", "Publish every line read from STDIN as a message until EOF").UnNegatableBoolVar(&c.stdinLines)
+	pub.Flag("stdin-framed", "Publish every 4 byte big endian length prefixed frame read from STDIN as a message until EOF").UnNegatableBoolVar(&c.stdinFramed)
+	pub.Flag("reply-wait", "When publishing from STDIN, send requests and wait this long for a reply to print").PlaceHolder("DURATION").DurationVar(&c.replyWait)
+	pub.Flag("compress", "Compresses payloads, consumers using this tool decompress them automatically").PlaceHolder("ALGORITHM").EnumVar(&c.compress, payloadCompressions...)
+	pub.Flag("encrypt-key", "Encrypts payloads for a public curve NKey, given directly or in a file").PlaceHolder("KEY").StringVar(&c.encryptKey)
+	pub.Flag("expect-stream", "Requires the message to be stored in this Stream, reporting the acknowledgement").PlaceHolder("STREAM").StringVar(&c.expectStream)
+	pub.Flag("expect-last-seq", "Requires the last sequence of the Stream to be this, reporting the acknowledgement").PlaceHolder("SEQ").IsSetByUser(&c.expectLastSeqSet).Uint64Var(&c.expectLastSeq)
+	pub.Flag("expect-last-subject-seq", "Requires the last sequence for the subject to be this, reporting the acknowledgement").PlaceHolder("SEQ").IsSetByUser(&c.expectLastSubjSeqSet).Uint64Var(&c.expectLastSubjSeq)
+	pub.Flag("jetstream", "Publish to a Stream and wait for the acknowledgement").UnNegatableBoolVar(&c.jetstream)
+	pub.Flag("verify", "Verify the acknowledgement of every message, summarizing acknowledged, duplicate and failed publishes, implies --jetstream").UnNegatableBoolVar(&c.verify)
+	pub.Flag("ack-retries", "Number of times to retry JetStream publishes when no Stream is listening").Default("3").IntVar(&c.retries)
+	pub.Flag("retry-wait", "Time to wait between JetStream publish retries").Default("500ms").PlaceHolder("DURATION").DurationVar(&c.retryWait)
 
 	requestHelp := `Body and Header values of the messages may use Go templates to 
 create unique messages.
@@ -93,15 +159,29 @@ Available template functions are:
    Time             the current time
    ID               an unique ID
    Random(min, max) random string at least min long, at most max
+
+Headers may also be read from a file holding one "Key: Value" header per
+line, values in the file may use the same templates. Blank lines and
+lines starting with # are ignored.
+
+   nats request orders.new --header-file headers.txt "{{ Random 10 100 }}"
+
+The --msg-id-template flag sets the JetStream deduplication header
+Nats-Msg-Id from a template, creating deterministic IDs allows repeated
+runs to be deduplicated by the Stream.
+
+   nats request orders.new --count 10 --msg-id-template "order-{{ Count }}" "body"
 `
 
-	req := app.Command("request", "Generic request-reply request utility").Alias("req").Action(c.publish)
+	req := publishing(app.Command("request", "Generic request-reply request utility").Alias("req").Action(c.publish))
 	req.HelpLong(requestHelp)
 	req.Arg("subject", "Subject to subscribe to").Required().StringVar(&c.subject)
 	req.Arg("body", "Message body").Default("!nil!").StringVar(&c.body)
 	req.Flag("wait", "Wait for a reply from a service").Short('w').Default("true").Hidden().BoolVar(&c.req)
 	req.Flag("raw", "Show just the output received").Short('r').UnNegatableBoolVar(&c.raw)
 	req.Flag("header", "Adds headers to the message").Short('H').StringsVar(&c.hdrs)
+	req.Flag("header-file", "Adds headers read from a file holding one Key: Value header per line").PlaceHolder("FILE").ExistingFileVar(&c.hdrFile)
+	req.Flag("msg-id-template", "Sets the Nats-Msg-Id header from a template, for deterministic deduplication IDs").PlaceHolder("TEMPLATE").StringVar(&c.msgIDTmpl)
 	req.Flag("count", "Publish multiple messages").Default("1").IntVar(&c.cnt)
 	req.Flag("replies", "Wait for multiple replies from services. 0 waits until timeout").Default("1").IntVar(&c.replyCount)
 	req.Flag("reply-timeout", "Maximum timeout between incoming replies.").Default("300ms").DurationVar(&c.replyTimeout)
@@ -117,7 +197,144 @@ func (c *pubCmd) prepareMsg(body []byte, seq int) (*nats.Msg, error) {
 	msg.Reply = c.replyTo
 	msg.Data = body
 
-	return msg, parseStringsToMsgHeader(c.hdrs, seq, msg)
+	err := parseStringsToMsgHeader(c.hdrs, seq, msg)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.expectStream != "" {
+		msg.Header.Set(nats.ExpectedStreamHdr, c.expectStream)
+	}
+	if c.expectLastSeqSet {
+		msg.Header.Set(nats.ExpectedLastSeqHdr, strconv.FormatUint(c.expectLastSeq, 10))
+	}
+	if c.expectLastSubjSeqSet {
+		msg.Header.Set(nats.ExpectedLastSubjSeqHdr, strconv.FormatUint(c.expectLastSubjSeq, 10))
+	}
+
+	if c.msgIDTmpl != "" {
+		id, err := pubReplyBodyTemplate(c.msgIDTmpl, "", seq)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse message ID template: %w", err)
+		}
+		msg.Header.Set(nats.MsgIdHdr, string(id))
+	}
+
+	err = compressPayload(msg, c.compress)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.encrypter != nil {
+		err = c.encrypter.encrypt(msg)
+	}
+
+	return msg, err
+}
+
+// jsExpectations determines if the publish has JetStream expectations that require an acknowledgement
+func (c *pubCmd) jsExpectations() bool {
+	return c.expectStream != "" || c.expectLastSeqSet || c.expectLastSubjSeqSet
+}
+
+// parsePubAck parses a JetStream publish acknowledgement, rejections are returned as *api.ApiError
+func parsePubAck(data []byte) (*api.PubAck, error) {
+	var resp api.JSPubAckResponse
+	err := json.Unmarshal(data, &resp)
+	if err != nil {
+		return nil, fmt.Errorf("invalid publish acknowledgement: %w", err)
+	}
+
+	if resp.Error != nil {
+		return nil, resp.Error
+	}
+
+	if resp.Stream == "" {
+		return nil, fmt.Errorf("invalid publish acknowledgement: %q", data)
+	}
+
+	return &resp.PubAck, nil
+}
+
+// jsPublish publishes msg to a Stream and waits for the acknowledgement
+func jsPublish(nc *nats.Conn, msg *nats.Msg) (*api.PubAck, error) {
+	res, err := nc.RequestMsg(msg, opts.Timeout)
+	if errors.Is(err, nats.ErrNoResponders) {
+		return nil, fmt.Errorf("no Stream is listening on subject %s: %w", msg.Subject, err)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return parsePubAck(res.Data)
+}
+
+// jsPublishing determines if messages are published to a Stream waiting for acknowledgements
+func (c *pubCmd) jsPublishing() bool {
+	return c.jetstream || c.verify || c.jsExpectations()
+}
+
+// jsPublishWithRetry publishes msg to a Stream, retrying when no Stream is listening like during leader elections
+func (c *pubCmd) jsPublishWithRetry(nc *nats.Conn, msg *nats.Msg) (*api.PubAck, error) {
+	for try := 1; ; try++ {
+		ack, err := jsPublish(nc, msg)
+		if !errors.Is(err, nats.ErrNoResponders) || try > c.retries {
+			return ack, err
+		}
+
+		time.Sleep(c.retryWait)
+	}
+}
+
+// publishMsg publishes msg, returning the acknowledgement when publishing to a Stream
+func (c *pubCmd) publishMsg(nc *nats.Conn, msg *nats.Msg) (*api.PubAck, error) {
+	if !c.jsPublishing() {
+		return nil, nc.PublishMsg(msg)
+	}
+
+	ack, err := c.jsPublishWithRetry(nc, msg)
+	var apiErr *api.ApiError
+	switch {
+	case errors.As(err, &apiErr):
+		return nil, fmt.Errorf("publish rejected: %w", err)
+	case err != nil:
+		return nil, fmt.Errorf("publish failed: %w", err)
+	}
+
+	return ack, nil
+}
+
+// verifyAck records the outcome of a JetStream publish
+func (c *pubCmd) verifyAck(ack *api.PubAck, err error) {
+	switch {
+	case err != nil:
+		c.verified.failed++
+	case ack.Duplicate:
+		c.verified.duplicate++
+	default:
+		c.verified.acked++
+	}
+}
+
+// verifyResult is the verification summary, failing when any publish failed
+func (c *pubCmd) verifyResult() (string, error) {
+	v := c.verified
+	total := v.acked + v.duplicate + v.failed
+	summary := fmt.Sprintf("Verified %d publishes: %d acknowledged, %d duplicates, %d failed", total, v.acked, v.duplicate, v.failed)
+
+	if v.failed > 0 {
+		return summary, fmt.Errorf("%d of %d publishes failed", v.failed, total)
+	}
+
+	return summary, nil
+}
+
+func logPubAck(ack *api.PubAck, size int) {
+	if ack.Duplicate {
+		log.Printf("Published %d bytes, duplicate of Stream %s sequence %d", size, ack.Stream, ack.Sequence)
+	} else {
+		log.Printf("Published %d bytes, stored in Stream %s with sequence %d", size, ack.Stream, ack.Sequence)
+	}
 }
 
 func (c *pubCmd) doReq(nc *nats.Conn, progress *uiprogress.Bar) error {
@@ -236,6 +453,32 @@ func (c *pubCmd) publish(_ *fisk.ParseContext) error {
 	}
 	defer nc.Close()
 
+	if c.encryptKey != "" {
+		c.encrypter, err = newPayloadEncrypter(c.encryptKey)
+		if err != nil {
+			return withExitCode(ExitValidation, fmt.Errorf("invalid encryption key: %w", err))
+		}
+	}
+
+	if c.hdrFile != "" {
+		hdrs, err := readHeaderFile(c.hdrFile)
+		if err != nil {
+			return withExitCode(ExitValidation, fmt.Errorf("invalid header file: %w", err))
+		}
+		c.hdrs = append(hdrs, c.hdrs...)
+	}
+
+	if c.jsPublishing() && (c.replyTo != "" || c.replyWait > 0) {
+		return withExitCode(ExitValidation, fmt.Errorf("--reply and --reply-wait can not be used when publishing to a Stream"))
+	}
+	if c.retries < 0 {
+		return withExitCode(ExitValidation, fmt.Errorf("retries can not be negative"))
+	}
+
+	if c.stdinLines || c.stdinFramed {
+		return c.publishStdinStream(nc, os.Stdin)
+	}
+
 	if c.cnt < 1 {
 		c.cnt = math.MaxInt16
 	}
@@ -250,6 +493,7 @@ func (c *pubCmd) publish(_ *fisk.ParseContext) error {
 	}
 
 	var progress *uiprogress.Bar
+	stopProgress := func() {}
 	if c.cnt > 20 && !c.raw {
 		progressFormat := fmt.Sprintf("%%%dd / %%d", len(fmt.Sprintf("%d", c.cnt)))
 		progress = uiprogress.AddBar(c.cnt).PrependFunc(func(b *uiprogress.Bar) string {
@@ -260,7 +504,15 @@ func (c *pubCmd) publish(_ *fisk.ParseContext) error {
 		fmt.Println()
 		uiprogress.Start()
 		uiprogress.RefreshInterval = 100 * time.Millisecond
-		defer func() { uiprogress.Stop(); fmt.Println() }()
+		stopped := false
+		stopProgress = func() {
+			if !stopped {
+				stopped = true
+				uiprogress.Stop()
+				fmt.Println()
+			}
+		}
+		defer stopProgress()
 	}
 
 	if c.req || c.replyCount >= 1 {
@@ -278,27 +530,193 @@ func (c *pubCmd) publish(_ *fisk.ParseContext) error {
 			return err
 		}
 
-		err = nc.PublishMsg(msg)
-		if err != nil {
+		ack, err := c.publishMsg(nc, msg)
+		if err != nil && !c.verify {
 			return err
 		}
-		nc.Flush()
 
-		err = nc.LastError()
-		if err != nil {
-			return err
+		if c.verify {
+			c.verifyAck(ack, err)
+		}
+
+		if err == nil && ack == nil {
+			nc.Flush()
+
+			err = nc.LastError()
+			if err != nil {
+				return err
+			}
 		}
 
 		if c.cnt > 1 && c.sleep > 0 {
 			time.Sleep(c.sleep)
 		}
 
-		if progress == nil {
-			log.Printf("Published %d bytes to %q\n", len(body), c.subject)
-		} else {
+		switch {
+		case progress != nil:
 			progress.Incr()
+		case err != nil:
+			log.Printf("Message %d: %v", i, err)
+		case ack != nil:
+			logPubAck(ack, len(msg.Data))
+		default:
+			log.Printf("Published %d bytes to %q\n", len(body), c.subject)
 		}
 	}
 
+	if c.verify {
+		stopProgress()
+		summary, err := c.verifyResult()
+		log.Print(summary)
+
+		return err
+	}
+
 	return nil
 }
+
+// readHeaderFile reads headers in Key: Value format, one per line, skipping blank lines and comments
+func readHeaderFile(file string) ([]string, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var hdrs []string
+	scanner := bufio.NewScanner(f)
+	line := 0
+	for scanner.Scan() {
+		line++
+		hdr := strings.TrimSpace(scanner.Text())
+		if hdr == "" || strings.HasPrefix(hdr, "#") {
+			continue
+		}
+
+		if !strings.Contains(hdr, ":") {
+			return nil, fmt.Errorf("invalid header %q on line %d", hdr, line)
+		}
+
+		hdrs = append(hdrs, hdr)
+	}
+
+	return hdrs, scanner.Err()
+}
+
+// readFrame reads a single 4 byte big endian length prefixed frame
+func readFrame(r *bufio.Reader) ([]byte, error) {
+	var size uint32
+
+	err := binary.Read(r, binary.BigEndian, &size)
+	if err != nil {
+		return nil, err
+	}
+
+	frame := make([]byte, size)
+	_, err = io.ReadFull(r, frame)
+	if errors.Is(err, io.EOF) {
+		return nil, io.ErrUnexpectedEOF
+	}
+
+	return frame, err
+}
+
+// publishStdinStream publishes every line or frame found on r as a message until EOF
+func (c *pubCmd) publishStdinStream(nc *nats.Conn, r io.Reader) error {
+	if c.stdinLines && c.stdinFramed {
+		return fmt.Errorf("--stdin-lines and --stdin-framed are mutually exclusive")
+	}
+
+	var (
+		reader  = bufio.NewReader(r)
+		scanner *bufio.Scanner
+		cnt     int
+		size    int
+	)
+
+	if c.stdinLines {
+		scanner = bufio.NewScanner(reader)
+		scanner.Buffer(make([]byte, 0, 64*1024), 8*1024*1024)
+	}
+
+	next := func() ([]byte, error) {
+		if c.stdinFramed {
+			return readFrame(reader)
+		}
+
+		if !scanner.Scan() {
+			if scanner.Err() != nil {
+				return nil, scanner.Err()
+			}
+			return nil, io.EOF
+		}
+
+		return append([]byte{}, scanner.Bytes()...), nil
+	}
+
+	for {
+		body, err := next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		cnt++
+		size += len(body)
+
+		msg, err := c.prepareMsg(body, cnt)
+		if err != nil {
+			return err
+		}
+
+		if c.replyWait > 0 {
+			res, err := nc.RequestMsg(msg, c.replyWait)
+			switch {
+			case errors.Is(err, nats.ErrTimeout):
+				log.Printf("No reply received for message %d within %v", cnt, c.replyWait)
+			case errors.Is(err, nats.ErrNoResponders):
+				log.Printf("No responders are available for message %d", cnt)
+			case err != nil:
+				return err
+			default:
+				outPutMSGBody(res.Data, "", res.Subject, "")
+			}
+		} else {
+			ack, err := c.publishMsg(nc, msg)
+			if c.verify {
+				c.verifyAck(ack, err)
+			}
+			switch {
+			case err != nil && c.verify:
+				log.Printf("Message %d: %v", cnt, err)
+			case err != nil:
+				return err
+			case ack != nil && ack.Duplicate:
+				log.Printf("Message %d was a duplicate of Stream %s sequence %d", cnt, ack.Stream, ack.Sequence)
+			}
+		}
+
+		if c.sleep > 0 {
+			time.Sleep(c.sleep)
+		}
+	}
+
+	err := nc.Flush()
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Published %d messages totaling %d bytes to %q", cnt, size, c.subject)
+
+	err = nc.LastError()
+	if err != nil || !c.verify {
+		return err
+	}
+
+	summary, err := c.verifyResult()
+	log.Print(summary)
+
+	return err
+}
@@ -0,0 +1,187 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/nats-io/jsm.go/api"
+	"github.com/nats-io/nats.go"
+)
+
+func TestReadFrame(t *testing.T) {
+	input := bytes.NewReader([]byte{0, 0, 0, 5, 'h', 'e', 'l', 'l', 'o', 0, 0, 0, 0, 0, 0, 0, 3, 'a'})
+	r := bufio.NewReader(input)
+
+	frame, err := readFrame(r)
+	assertNoError(t, err)
+	if string(frame) != "hello" {
+		t.Fatalf("invalid frame: %q", frame)
+	}
+
+	frame, err = readFrame(r)
+	assertNoError(t, err)
+	if len(frame) != 0 {
+		t.Fatalf("expected empty frame: %q", frame)
+	}
+
+	_, err = readFrame(r)
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Fatalf("expected unexpected EOF, got: %v", err)
+	}
+
+	_, err = readFrame(r)
+	if !errors.Is(err, io.EOF) {
+		t.Fatalf("expected EOF, got: %v", err)
+	}
+}
+
+func TestReadHeaderFile(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "headers.txt")
+	err := os.WriteFile(file, []byte("# comment\nNats-Rollup: sub\n\n  X-Count: {{ Count }}  \n"), 0600)
+	assertNoError(t, err)
+
+	hdrs, err := readHeaderFile(file)
+	assertNoError(t, err)
+	if len(hdrs) != 2 || hdrs[0] != "Nats-Rollup: sub" || hdrs[1] != "X-Count: {{ Count }}" {
+		t.Fatalf("invalid headers: %#v", hdrs)
+	}
+
+	err = os.WriteFile(file, []byte("X-One: 1\ninvalid\n"), 0600)
+	assertNoError(t, err)
+	_, err = readHeaderFile(file)
+	if err == nil || err.Error() != `invalid header "invalid" on line 2` {
+		t.Fatalf("expected invalid header error, got: %v", err)
+	}
+}
+
+func TestPubPrepareMsgHeaders(t *testing.T) {
+	c := &pubCmd{
+		subject:   "test",
+		hdrs:      []string{"X-Count: {{ Count }}", "Nats-Msg-Id: ignored"},
+		msgIDTmpl: "order-{{ Count }}",
+	}
+
+	msg, err := c.prepareMsg([]byte("body"), 10)
+	assertNoError(t, err)
+
+	if msg.Header.Get("X-Count") != "10" {
+		t.Fatalf("invalid count header: %q", msg.Header.Get("X-Count"))
+	}
+
+	ids := msg.Header.Values(nats.MsgIdHdr)
+	if len(ids) != 1 || ids[0] != "order-10" {
+		t.Fatalf("invalid message id: %#v", ids)
+	}
+
+	c.msgIDTmpl = "{{ Invalid }}"
+	_, err = c.prepareMsg([]byte("body"), 1)
+	if err == nil {
+		t.Fatalf("expected template error")
+	}
+}
+
+func TestPubPrepareMsgExpectations(t *testing.T) {
+	c := &pubCmd{subject: "test"}
+	if c.jsExpectations() {
+		t.Fatalf("expected no expectations")
+	}
+
+	msg, err := c.prepareMsg(nil, 1)
+	assertNoError(t, err)
+	if len(msg.Header) != 0 {
+		t.Fatalf("expected no headers: %#v", msg.Header)
+	}
+
+	c.expectStream = "ORDERS"
+	c.expectLastSeqSet = true
+	c.expectLastSubjSeq = 10
+	c.expectLastSubjSeqSet = true
+	if !c.jsExpectations() {
+		t.Fatalf("expected expectations")
+	}
+
+	msg, err = c.prepareMsg(nil, 1)
+	assertNoError(t, err)
+	if msg.Header.Get(nats.ExpectedStreamHdr) != "ORDERS" {
+		t.Fatalf("invalid stream header: %#v", msg.Header)
+	}
+	if msg.Header.Get(nats.ExpectedLastSeqHdr) != "0" {
+		t.Fatalf("invalid last sequence header: %#v", msg.Header)
+	}
+	if msg.Header.Get(nats.ExpectedLastSubjSeqHdr) != "10" {
+		t.Fatalf("invalid last subject sequence header: %#v", msg.Header)
+	}
+}
+
+func TestParsePubAck(t *testing.T) {
+	ack, err := parsePubAck([]byte(`{"stream":"ORDERS","seq":10,"duplicate":true}`))
+	assertNoError(t, err)
+	if ack.Stream != "ORDERS" || ack.Sequence != 10 || !ack.Duplicate {
+		t.Fatalf("invalid ack: %#v", ack)
+	}
+
+	_, err = parsePubAck([]byte(`{"error":{"code":400,"err_code":10071,"description":"wrong last sequence: 5"}}`))
+	var apiErr *api.ApiError
+	if !errors.As(err, &apiErr) || apiErr.ErrCode != 10071 {
+		t.Fatalf("expected api error, got: %v", err)
+	}
+	if ExitCode(fmt.Errorf("publish rejected: %w", err)) != ExitValidation {
+		t.Fatalf("expected validation exit code")
+	}
+
+	_, err = parsePubAck([]byte(`{}`))
+	if err == nil {
+		t.Fatalf("expected invalid ack error")
+	}
+}
+
+func TestPubVerify(t *testing.T) {
+	c := &pubCmd{}
+	if c.jsPublishing() {
+		t.Fatalf("expected core publishing")
+	}
+
+	c.verify = true
+	if !c.jsPublishing() {
+		t.Fatalf("expected verify to publish to a stream")
+	}
+
+	c.verifyAck(&api.PubAck{Stream: "ORDERS", Sequence: 1}, nil)
+	c.verifyAck(&api.PubAck{Stream: "ORDERS", Sequence: 2}, nil)
+	c.verifyAck(&api.PubAck{Stream: "ORDERS", Sequence: 2, Duplicate: true}, nil)
+	summary, err := c.verifyResult()
+	assertNoError(t, err)
+	if summary != "Verified 3 publishes: 2 acknowledged, 1 duplicates, 0 failed" {
+		t.Fatalf("invalid summary: %q", summary)
+	}
+
+	c.verifyAck(nil, nats.ErrNoResponders)
+	if c.verified.acked != 2 || c.verified.duplicate != 1 || c.verified.failed != 1 {
+		t.Fatalf("invalid stats: %#v", c.verified)
+	}
+
+	_, err = c.verifyResult()
+	if err == nil || err.Error() != "1 of 4 publishes failed" {
+		t.Fatalf("expected failure, got: %v", err)
+	}
+}
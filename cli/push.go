@@ -0,0 +1,53 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"fmt"
+
+	"github.com/choria-io/fisk"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/push"
+)
+
+// addPushGatewayFlags adds the flags used to push metrics produced by cmd to a Prometheus Push Gateway
+func addPushGatewayFlags(cmd *fisk.CmdClause, url *string, job *string) {
+	cmd.Flag("push-gateway", "Pushes metrics to a Prometheus Push Gateway at this URL").PlaceHolder("URL").StringVar(url)
+	cmd.Flag("job", "The job name to use when pushing metrics").Default("nats-cli").StringVar(job)
+}
+
+// newPushGauge creates a gauge for metrics pushed by reports, all are in the nats namespace
+func newPushGauge(subsystem string, name string, help string, labels ...string) *prometheus.GaugeVec {
+	return prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "nats",
+		Subsystem: subsystem,
+		Name:      name,
+		Help:      help,
+	}, labels)
+}
+
+// pushMetrics adds collectors to the metrics for job in the Push Gateway at url, replacing metrics with the same names
+func pushMetrics(url string, job string, collectors ...prometheus.Collector) error {
+	pusher := push.New(url, job)
+	for _, c := range collectors {
+		pusher.Collector(c)
+	}
+
+	err := pusher.Add()
+	if err != nil {
+		return fmt.Errorf("could not push metrics to %s: %w", url, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,213 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/choria-io/fisk"
+	"github.com/nats-io/jsm.go"
+	"github.com/nats-io/jsm.go/api"
+	"github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats.go"
+)
+
+type replayCmd struct {
+	stream   string
+	filter   string
+	subject  string
+	prefix   string
+	speed    string
+	since    time.Duration
+	startSeq uint64
+	count    uint64
+}
+
+func configureReplayCommand(app commandHost) {
+	c := &replayCmd{}
+
+	help := `Replays messages stored in a Stream at a multiple of their original rate
+
+Messages are published in the order they were stored with the time
+between them scaled by --speed, 2x replays a recording in half the time
+and 0.5x takes twice as long. Messages are published to --subject or
+to their original subject below --prefix so they are not stored in the
+Stream being replayed again.
+
+Messages stored while replaying are not replayed.
+`
+
+	replay := publishing(app.Command("replay", help).Action(c.replayAction))
+	replay.Arg("stream", "The Stream to replay").Required().HintAction(streamNamesHint).StringVar(&c.stream)
+	replay.Flag("filter", "Only replay messages matching a subject").PlaceHolder("SUBJECT").StringVar(&c.filter)
+	replay.Flag("subject", "Publish all messages to this subject").StringVar(&c.subject)
+	replay.Flag("prefix", "Publish messages to their original subject below this prefix").StringVar(&c.prefix)
+	replay.Flag("speed", "Replays messages at a multiple of the rate they were stored at, like 2x or 0.5x").Default("1x").PlaceHolder("SPEED").StringVar(&c.speed)
+	replay.Flag("since", "Replays messages stored since a duration like 1h").DurationVar(&c.since)
+	replay.Flag("start-seq", "Replays messages starting at a Stream sequence").PlaceHolder("SEQUENCE").Uint64Var(&c.startSeq)
+	replay.Flag("count", "Stops after replaying a number of messages").Uint64Var(&c.count)
+	addCheat("replay", replay)
+}
+
+func init() {
+	registerCommand("replay", 12, configureReplayCommand)
+}
+
+// target is the subject to publish a message originally stored on subject to
+func (c *replayCmd) target(subject string) string {
+	if c.subject != "" {
+		return c.subject
+	}
+
+	return c.prefix + "." + subject
+}
+
+func (c *replayCmd) validate() error {
+	switch {
+	case c.subject == "" && c.prefix == "":
+		return fmt.Errorf("a subject to replay to is required, use --subject or --prefix")
+	case c.subject != "" && c.prefix != "":
+		return fmt.Errorf("--subject and --prefix can not be used together")
+	case c.subject != "" && !server.IsValidLiteralSubject(c.subject):
+		return fmt.Errorf("invalid subject %q", c.subject)
+	case c.prefix != "" && !server.IsValidLiteralSubject(c.prefix):
+		return fmt.Errorf("invalid prefix %q", c.prefix)
+	case c.since > 0 && c.startSeq > 0:
+		return fmt.Errorf("--since and --start-seq can not be used together")
+	}
+
+	return nil
+}
+
+func (c *replayCmd) replayAction(_ *fisk.ParseContext) error {
+	err := c.validate()
+	if err != nil {
+		return withExitCode(ExitValidation, err)
+	}
+
+	pacer, err := newReplayPacer(c.speed)
+	if err != nil {
+		return withExitCode(ExitValidation, err)
+	}
+
+	nc, mgr, err := prepareHelper("", natsOpts()...)
+	if err != nil {
+		return err
+	}
+
+	str, err := mgr.LoadStream(c.stream)
+	if err != nil {
+		return fmt.Errorf("could not load Stream %s: %w", c.stream, err)
+	}
+
+	if str.Retention() == api.WorkQueuePolicy {
+		return fmt.Errorf("work queue stream contents can not be replayed")
+	}
+
+	state, err := str.State()
+	if err != nil {
+		return err
+	}
+
+	if state.Msgs == 0 || c.startSeq > state.LastSeq {
+		fmt.Printf("No messages to replay in Stream %s\n", str.Name())
+		return nil
+	}
+
+	js, err := nc.JetStream()
+	if err != nil {
+		return err
+	}
+
+	sopts := []nats.SubOpt{nats.BindStream(str.Name()), nats.OrderedConsumer()}
+	switch {
+	case c.since > 0:
+		sopts = append(sopts, nats.StartTime(time.Now().Add(-c.since)))
+	case c.startSeq > 0:
+		sopts = append(sopts, nats.StartSequence(c.startSeq))
+	default:
+		sopts = append(sopts, nats.DeliverAll())
+	}
+
+	// messages are read one at a time from a synchronous ordered consumer, flow control stops the server
+	// from sending more than the client can hold while waiting to publish slowly paced messages
+	sub, err := js.SubscribeSync(c.filter, sopts...)
+	if err != nil {
+		return fmt.Errorf("could not read Stream %s: %w", str.Name(), err)
+	}
+	defer sub.Unsubscribe()
+
+	ctx, cancel := signal.NotifyContext(ctx, syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT)
+	defer cancel()
+
+	fmt.Printf("Replaying Stream %s at %gx, press ^C to end\n", str.Name(), pacer.speed)
+
+	var replayed uint64
+	started := time.Now()
+
+	for c.count == 0 || replayed < c.count {
+		// all messages being replayed are already stored so waiting longer than the timeout means none are left
+		timeout, cancelTimeout := context.WithTimeout(ctx, opts.Timeout)
+		msg, err := sub.NextMsgWithContext(timeout)
+		cancelTimeout()
+		if ctx.Err() != nil || errors.Is(err, context.DeadlineExceeded) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		info, err := jsm.ParseJSMsgMetadata(msg)
+		if err != nil {
+			return fmt.Errorf("could not parse JetStream metadata: '%s': %w", msg.Reply, err)
+		}
+
+		if info.StreamSequence() > state.LastSeq {
+			break
+		}
+
+		pacer.wait(ctx, info.TimeStamp())
+		if ctx.Err() != nil {
+			break
+		}
+
+		out := nats.NewMsg(c.target(msg.Subject))
+		out.Header = msg.Header
+		out.Data = msg.Data
+
+		err = nc.PublishMsg(out)
+		if err != nil {
+			return fmt.Errorf("could not publish message %d: %w", info.StreamSequence(), err)
+		}
+		replayed++
+
+		if info.Pending() == 0 || info.StreamSequence() == state.LastSeq {
+			break
+		}
+	}
+
+	err = nc.Flush()
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Replayed %d messages from Stream %s in %s\n", replayed, str.Name(), humanizeDuration(time.Since(started)))
+
+	return nil
+}
@@ -0,0 +1,45 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"testing"
+	"time"
+)
+
+func TestReplayCmdTarget(t *testing.T) {
+	c := &replayCmd{prefix: "replay"}
+	assertNoError(t, c.validate())
+	if target := c.target("orders.new"); target != "replay.orders.new" {
+		t.Fatalf("invalid prefixed target %q", target)
+	}
+
+	c = &replayCmd{subject: "test.orders"}
+	assertNoError(t, c.validate())
+	if target := c.target("orders.new"); target != "test.orders" {
+		t.Fatalf("invalid target %q", target)
+	}
+
+	for _, c := range []*replayCmd{
+		{},
+		{subject: "a", prefix: "b"},
+		{subject: "orders.*"},
+		{prefix: "replay.>"},
+		{prefix: "replay", since: time.Hour, startSeq: 10},
+	} {
+		if c.validate() == nil {
+			t.Fatalf("expected %+v to be invalid", c)
+		}
+	}
+}
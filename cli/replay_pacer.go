@@ -0,0 +1,121 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nats-io/jsm.go"
+	"github.com/nats-io/nats.go"
+)
+
+// replayPacerBacklog bounds how many received messages wait to be paced, once full the subscription handler blocks
+// so flow control and acknowledgements throttle the server rather than messages being dropped
+const replayPacerBacklog = 1024
+
+// replayPacer delays messages so they are handled at a multiple of the rate they were stored at
+type replayPacer struct {
+	speed   float64
+	first   time.Time
+	started time.Time
+}
+
+// parseReplaySpeed parses speeds like 2x, 0.5x or 2
+func parseReplaySpeed(speed string) (float64, error) {
+	s, err := strconv.ParseFloat(strings.TrimSuffix(strings.ToLower(strings.TrimSpace(speed)), "x"), 64)
+	if err != nil || s <= 0 {
+		return 0, fmt.Errorf("invalid speed %q, expected a positive multiple like 2x or 0.5x", speed)
+	}
+
+	return s, nil
+}
+
+func newReplayPacer(speed string) (*replayPacer, error) {
+	s, err := parseReplaySpeed(speed)
+	if err != nil {
+		return nil, err
+	}
+
+	return &replayPacer{speed: s}, nil
+}
+
+// delay is how long to wait at now before handling a message stored at stored, the first message is handled immediately
+// and messages stored out of order, like redeliveries, are not delayed
+func (p *replayPacer) delay(stored time.Time, now time.Time) time.Duration {
+	if p.first.IsZero() {
+		p.first = stored
+		p.started = now
+		return 0
+	}
+
+	if stored.Before(p.first) {
+		return 0
+	}
+
+	target := p.started.Add(time.Duration(float64(stored.Sub(p.first)) / p.speed))
+	if target.Before(now) {
+		return 0
+	}
+
+	return target.Sub(now)
+}
+
+// wait sleeps until the message stored at stored should be handled or ctx is done
+func (p *replayPacer) wait(ctx context.Context, stored time.Time) {
+	d := p.delay(stored, time.Now())
+	if d <= 0 {
+		return
+	}
+
+	select {
+	case <-time.After(d):
+	case <-ctx.Done():
+	}
+}
+
+// pace wraps handler so messages are handled in order by a separate goroutine at the pace of their stored time,
+// messages without JetStream metadata like flow control requests are handled as soon as they are reached
+func (p *replayPacer) pace(handler nats.MsgHandler) nats.MsgHandler {
+	q := make(chan *nats.Msg, replayPacerBacklog)
+	pctx := ctx
+	done := pctx.Done()
+
+	go func() {
+		for {
+			select {
+			case m := <-q:
+				info, err := jsm.ParseJSMsgMetadata(m)
+				if err == nil {
+					p.wait(pctx, info.TimeStamp())
+				}
+
+				handler(m)
+
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return func(m *nats.Msg) {
+		select {
+		case q <- m:
+		case <-done:
+		}
+	}
+}
@@ -0,0 +1,94 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+func TestParseReplaySpeed(t *testing.T) {
+	for in, expected := range map[string]float64{"2x": 2, "0.5x": 0.5, "3": 3, " 1.5X ": 1.5} {
+		s, err := parseReplaySpeed(in)
+		assertNoError(t, err)
+		if s != expected {
+			t.Fatalf("expected %q to be %f got %f", in, expected, s)
+		}
+	}
+
+	for _, in := range []string{"", "x", "0x", "-2x", "fast"} {
+		_, err := parseReplaySpeed(in)
+		if err == nil {
+			t.Fatalf("expected %q to fail", in)
+		}
+	}
+}
+
+func TestReplayPacerDelay(t *testing.T) {
+	stored := time.Unix(1000, 0)
+	now := time.Unix(5000, 0)
+
+	p := &replayPacer{speed: 2}
+	if d := p.delay(stored, now); d != 0 {
+		t.Fatalf("expected first message to not be delayed: %v", d)
+	}
+
+	if d := p.delay(stored.Add(10*time.Second), now); d != 5*time.Second {
+		t.Fatalf("expected 5s delay at 2x got %v", d)
+	}
+
+	if d := p.delay(stored.Add(10*time.Second), now.Add(8*time.Second)); d != 0 {
+		t.Fatalf("expected no delay when late got %v", d)
+	}
+
+	if d := p.delay(stored.Add(-time.Second), now); d != 0 {
+		t.Fatalf("expected no delay for out of order messages got %v", d)
+	}
+
+	p = &replayPacer{speed: 0.5}
+	p.delay(stored, now)
+	if d := p.delay(stored.Add(10*time.Second), now.Add(5*time.Second)); d != 15*time.Second {
+		t.Fatalf("expected 15s delay at 0.5x got %v", d)
+	}
+}
+
+func TestReplayPacerPace(t *testing.T) {
+	defer func(c context.Context) { ctx = c }(ctx)
+
+	var cancel context.CancelFunc
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+
+	handled := make(chan string, 3)
+	p := &replayPacer{speed: 1}
+	handler := p.pace(func(m *nats.Msg) { handled <- m.Subject })
+
+	for _, subj := range []string{"a", "b", "c"} {
+		handler(&nats.Msg{Subject: subj})
+	}
+
+	for _, expect := range []string{"a", "b", "c"} {
+		select {
+		case subj := <-handled:
+			if subj != expect {
+				t.Fatalf("expected %q got %q", expect, subj)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("timeout waiting for message %q", expect)
+		}
+	}
+}
@@ -76,7 +76,7 @@ Available template functions are:
    Random(min, max) random string at least min long, at most max
 `
 
-	act := app.Command("reply", "Generic service reply utility").Action(c.reply)
+	act := publishing(app.Command("reply", "Generic service reply utility").Action(c.reply))
 	act.HelpLong(help)
 	addCheat("reply", act)
 	act.Arg("subject", "Subject to subscribe to").Required().StringVar(&c.subject)
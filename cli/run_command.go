@@ -0,0 +1,323 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/choria-io/fisk"
+	"github.com/google/shlex"
+	"github.com/kballard/go-shellquote"
+)
+
+type runCmd struct {
+	file    string
+	vars    map[string]string
+	onError string
+	dryRun  bool
+	logFile string
+}
+
+// scriptCommand is a command parsed from a script with its variables expanded
+type scriptCommand struct {
+	line    int
+	args    []string
+	onError string
+}
+
+// runResult is the outcome of a command in a script, recorded in the results log
+type runResult struct {
+	Line     int           `json:"line"`
+	Command  string        `json:"command"`
+	Start    time.Time     `json:"start"`
+	Duration time.Duration `json:"duration"`
+	Success  bool          `json:"success"`
+	ExitCode int           `json:"exit_code"`
+	Error    string        `json:"error,omitempty"`
+	Ignored  bool          `json:"ignored,omitempty"`
+}
+
+var scriptVariable = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+func configureRunCommand(app commandHost) {
+	c := &runCmd{vars: map[string]string{}}
+
+	help := `Runs a script of nats commands over a single connection
+
+Scripts hold one command per line without the leading 'nats', blank
+lines and lines starting with # are ignored.
+
+  # defaults that can be overridden using --var
+  set STREAM=ORDERS
+  set REPLICAS=3
+
+  # continue or stop when a command fails, the default is --on-error
+  on-error stop
+
+  stream add ${STREAM} --subjects "${STREAM}.>" --replicas ${REPLICAS} --defaults
+
+  # failures of commands starting with - are ignored
+  -consumer rm ${STREAM} OLD --force
+
+Variables are referenced as ${NAME} and are set in the script, using
+--var or from the environment, values passed using --var take precedence.
+`
+
+	run := app.Command("run", help).Action(c.runAction)
+	run.Arg("file", "The script to run").Required().ExistingFileVar(&c.file)
+	run.Flag("var", "Sets a variable used by the script").Short('v').PlaceHolder("NAME=VALUE").StringMapVar(&c.vars)
+	run.Flag("on-error", "What to do when a command fails").Default("stop").EnumVar(&c.onError, "stop", "continue")
+	run.Flag("dry-run", "Shows the commands that would be run without running them").UnNegatableBoolVar(&c.dryRun)
+	run.Flag("log", "Appends the result of every command as JSON to a file").PlaceHolder("FILE").StringVar(&c.logFile)
+	addCheat("run", run)
+}
+
+func init() {
+	registerCommand("run", 14, configureRunCommand)
+}
+
+func (c *runCmd) runAction(_ *fisk.ParseContext) error {
+	f, err := os.Open(c.file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	commands, err := parseScript(f, c.vars, c.onError)
+	if err != nil {
+		return withExitCode(ExitValidation, fmt.Errorf("invalid script %s: %w", c.file, err))
+	}
+
+	if c.dryRun {
+		return c.showCommands(commands)
+	}
+
+	var log io.Writer
+	if c.logFile != "" {
+		lf, err := os.OpenFile(c.logFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+		if err != nil {
+			return err
+		}
+		defer lf.Close()
+		log = lf
+	}
+
+	// connects once, all commands reuse the connection
+	_, _, err = prepareHelper("", natsOpts()...)
+	if err != nil {
+		return err
+	}
+
+	var results []*runResult
+	var stopErr error
+
+	for _, cmd := range commands {
+		command := shellquote.Join(cmd.args...)
+		fmt.Printf(">>> %s\n", command)
+
+		res := &runResult{Line: cmd.line, Command: command, Start: time.Now().UTC()}
+		err := runEmbedded(newEmbeddedApp("run", "shell"), cmd.args)
+		res.Duration = time.Since(res.Start)
+		res.Success = err == nil
+		res.ExitCode = ExitCode(err)
+		if err != nil {
+			res.Error = err.Error()
+			res.Ignored = cmd.onError == "ignore"
+		}
+		results = append(results, res)
+
+		if log != nil {
+			j, err := json.Marshal(res)
+			if err == nil {
+				_, err = log.Write(append(j, '\n'))
+			}
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Could not write results log: %v\n", err)
+			}
+		}
+
+		fmt.Println()
+
+		if err != nil && cmd.onError == "stop" {
+			stopErr = fmt.Errorf("line %d: %s: %w", cmd.line, command, err)
+			break
+		}
+	}
+
+	return c.summarize(commands, results, stopErr)
+}
+
+func (c *runCmd) showCommands(commands []*scriptCommand) error {
+	var invalid int
+
+	table := newTableWriter(fmt.Sprintf("%d commands in %s", len(commands), c.file))
+	table.AddHeaders("Line", "Command", "On Error", "Problem")
+	for _, cmd := range commands {
+		problem := ""
+		_, err := newEmbeddedApp("run", "shell").ParseContext(cmd.args)
+		if err != nil {
+			problem = err.Error()
+			invalid++
+		}
+
+		table.AddRow(cmd.line, shellquote.Join(cmd.args...), cmd.onError, problem)
+	}
+	fmt.Println(table.Render())
+
+	if invalid > 0 {
+		return withExitCode(ExitValidation, fmt.Errorf("%d commands are not valid", invalid))
+	}
+
+	return nil
+}
+
+func (c *runCmd) summarize(commands []*scriptCommand, results []*runResult, stopErr error) error {
+	var failed, ignored int
+	for _, r := range results {
+		switch {
+		case r.Ignored:
+			ignored++
+		case !r.Success:
+			failed++
+		}
+	}
+
+	fmt.Printf("Ran %d of %d commands from %s, %d failed, %d failures ignored\n", len(results), len(commands), c.file, failed, ignored)
+
+	if failed+ignored > 0 {
+		fmt.Println()
+		table := newTableWriter("Failed commands")
+		table.AddHeaders("Line", "Command", "Exit Code", "Ignored", "Error")
+		for _, r := range results {
+			if !r.Success {
+				table.AddRow(r.Line, r.Command, r.ExitCode, r.Ignored, r.Error)
+			}
+		}
+		fmt.Println(table.Render())
+	}
+
+	if stopErr != nil {
+		return stopErr
+	}
+
+	if failed > 0 {
+		return withExitCode(ExitThreshold, fmt.Errorf("%d commands failed", failed))
+	}
+
+	return nil
+}
+
+// parseScript reads commands from a script, expanding variables from overrides, the script and the environment in that order
+func parseScript(r io.Reader, overrides map[string]string, onError string) ([]*scriptCommand, error) {
+	vars := map[string]string{}
+	var commands []*scriptCommand
+
+	expand := func(s string) (string, error) {
+		var missing []string
+
+		res := scriptVariable.ReplaceAllStringFunc(s, func(v string) string {
+			name := scriptVariable.FindStringSubmatch(v)[1]
+			if val, ok := overrides[name]; ok {
+				return val
+			}
+			if val, ok := vars[name]; ok {
+				return val
+			}
+			if val, ok := os.LookupEnv(name); ok {
+				return val
+			}
+
+			missing = append(missing, name)
+			return v
+		})
+
+		if len(missing) > 0 {
+			return "", fmt.Errorf("undefined variable %s", strings.Join(missing, ", "))
+		}
+
+		return res, nil
+	}
+
+	scanner := bufio.NewScanner(r)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		line, err := expand(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNo, err)
+		}
+
+		cmdOnError := onError
+		if strings.HasPrefix(line, "-") {
+			cmdOnError = "ignore"
+			line = strings.TrimSpace(strings.TrimPrefix(line, "-"))
+		}
+
+		args, err := shlex.Split(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNo, err)
+		}
+		if len(args) > 0 && args[0] == "nats" {
+			args = args[1:]
+		}
+		if len(args) == 0 {
+			return nil, fmt.Errorf("line %d: no command given", lineNo)
+		}
+
+		switch args[0] {
+		case "set":
+			if len(args) != 2 || !strings.Contains(args[1], "=") {
+				return nil, fmt.Errorf("line %d: set requires NAME=VALUE", lineNo)
+			}
+
+			parts := strings.SplitN(args[1], "=", 2)
+			if !scriptVariable.MatchString("${" + parts[0] + "}") {
+				return nil, fmt.Errorf("line %d: invalid variable name %q", lineNo, parts[0])
+			}
+			vars[parts[0]] = parts[1]
+
+		case "on-error":
+			if len(args) != 2 || (args[1] != "stop" && args[1] != "continue") {
+				return nil, fmt.Errorf("line %d: on-error requires stop or continue", lineNo)
+			}
+			onError = args[1]
+
+		default:
+			commands = append(commands, &scriptCommand{line: lineNo, args: args, onError: cmdOnError})
+		}
+	}
+
+	if scanner.Err() != nil {
+		return nil, scanner.Err()
+	}
+
+	if len(commands) == 0 {
+		return nil, fmt.Errorf("no commands found")
+	}
+
+	return commands, nil
+}
@@ -0,0 +1,53 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseScript(t *testing.T) {
+	script := `# comment
+set STREAM=ORDERS
+set SUBJECT=ORDERS.>
+
+nats stream add ${STREAM} --subjects "${SUBJECT}"
+-consumer rm ${STREAM} ${CONSUMER} -f
+on-error continue
+pub '$JS.API.INFO' ""
+`
+
+	cmds, err := parseScript(strings.NewReader(script), map[string]string{"STREAM": "X", "CONSUMER": "C"}, "stop")
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+
+	expect := []*scriptCommand{
+		{line: 5, args: []string{"stream", "add", "X", "--subjects", "ORDERS.>"}, onError: "stop"},
+		{line: 6, args: []string{"consumer", "rm", "X", "C", "-f"}, onError: "ignore"},
+		{line: 8, args: []string{"pub", "$JS.API.INFO", ""}, onError: "continue"},
+	}
+	if !reflect.DeepEqual(cmds, expect) {
+		t.Fatalf("unexpected commands: %#v", cmds)
+	}
+
+	for _, bad := range []string{"stream info ${MISSING_SCRIPT_VAR}", "set X", "on-error maybe", "# nothing"} {
+		_, err = parseScript(strings.NewReader(bad), nil, "stop")
+		if err == nil {
+			t.Fatalf("expected %q to fail", bad)
+		}
+	}
+}
@@ -0,0 +1,331 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/choria-io/fisk"
+	"github.com/nats-io/jsm.go"
+	"github.com/nats-io/jsm.go/api"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nuid"
+)
+
+// scheduleBucket is the Key-Value bucket holding jobs queued using --at
+const scheduleBucket = "nats-cli-schedule"
+
+// Actions that can be scheduled
+const (
+	scheduleStreamPurge = "stream purge"
+)
+
+type scheduleCmd struct {
+	id       string
+	interval time.Duration
+	once     bool
+	force    bool
+	json     bool
+}
+
+// scheduledJob is a change queued in the schedule bucket to be executed by nats schedule run once due
+type scheduledJob struct {
+	ID       string    `json:"id"`
+	Action   string    `json:"action"`
+	At       time.Time `json:"at"`
+	Created  time.Time `json:"created"`
+	Stream   string    `json:"stream"`
+	Subject  string    `json:"subject,omitempty"`
+	Sequence uint64    `json:"sequence,omitempty"`
+	Keep     uint64    `json:"keep,omitempty"`
+
+	revision uint64
+}
+
+func (j *scheduledJob) target() string {
+	switch {
+	case j.Subject != "":
+		return fmt.Sprintf("%s subject %s", j.Stream, j.Subject)
+	case j.Sequence > 0:
+		return fmt.Sprintf("%s up to sequence %d", j.Stream, j.Sequence)
+	case j.Keep > 0:
+		return fmt.Sprintf("%s keeping %d messages", j.Stream, j.Keep)
+	default:
+		return j.Stream
+	}
+}
+
+func configureScheduleCommand(app commandHost) {
+	c := &scheduleCmd{}
+
+	help := `Manages changes scheduled for maintenance windows
+
+Changes like stream purges can be scheduled using --at, they are stored
+in the nats-cli-schedule Key-Value bucket and executed once due by a
+long running nats schedule run, multiple runners can be used safely as
+every job is claimed by a single runner.
+`
+
+	schedule := app.Command("schedule", help)
+	addCheat("schedule", schedule)
+
+	run := mutating(schedule.Command("run", "Executes scheduled jobs as they become due").Action(c.runAction))
+	run.Flag("interval", "How often to check for due jobs").Default("10s").DurationVar(&c.interval)
+	run.Flag("once", "Executes jobs that are due and exits").UnNegatableBoolVar(&c.once)
+
+	ls := schedule.Command("ls", "Lists scheduled jobs").Alias("list").Action(c.lsAction)
+	addJSONOutputFlag(ls, &c.json)
+
+	rm := mutating(schedule.Command("rm", "Removes a scheduled job").Alias("delete").Alias("del").Action(c.rmAction))
+	rm.Arg("id", "The job to remove").Required().StringVar(&c.id)
+	rm.Flag("force", "Force removal without prompting").Short('f').UnNegatableBoolVar(&c.force)
+}
+
+func init() {
+	registerCommand("schedule", 15, configureScheduleCommand)
+}
+
+// parseScheduleTime parses an RFC3339 time or a duration from now
+func parseScheduleTime(at string, now time.Time) (time.Time, error) {
+	t, err := time.Parse(time.RFC3339, at)
+	if err == nil {
+		return t, nil
+	}
+
+	d, err := parseDurationString(at)
+	if err != nil || d <= 0 {
+		return time.Time{}, fmt.Errorf("invalid time %q, expected a RFC3339 time or a duration like 2h", at)
+	}
+
+	return now.Add(d), nil
+}
+
+func scheduleStore(create bool) (nats.KeyValue, error) {
+	return cliBucket(scheduleBucket, "Changes scheduled using nats --at", create)
+}
+
+// scheduleJob stores a job for later execution
+func scheduleJob(job *scheduledJob) error {
+	kv, err := scheduleStore(true)
+	if err != nil {
+		return fmt.Errorf("could not access the schedule bucket: %w", err)
+	}
+
+	job.ID = strings.ToLower(nuid.Next())
+	job.Created = time.Now().UTC()
+
+	j, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+
+	_, err = kv.Create(job.ID, j)
+	if err != nil {
+		return fmt.Errorf("could not store scheduled job: %w", err)
+	}
+
+	fmt.Printf("Scheduled %s of %s at %s as job %s\n", job.Action, job.target(), job.At.Local().Format(time.RFC1123), job.ID)
+	fmt.Println("Jobs are executed by a running 'nats schedule run'")
+
+	return nil
+}
+
+func scheduledJobs(kv nats.KeyValue) ([]*scheduledJob, error) {
+	keys, err := kv.Keys()
+	if errors.Is(err, nats.ErrNoKeysFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var jobs []*scheduledJob
+	for _, k := range keys {
+		entry, err := kv.Get(k)
+		if errors.Is(err, nats.ErrKeyNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		job := &scheduledJob{}
+		err = json.Unmarshal(entry.Value(), job)
+		if err != nil {
+			log.Printf("Skipping invalid job %s: %v", k, err)
+			continue
+		}
+		job.revision = entry.Revision()
+
+		jobs = append(jobs, job)
+	}
+
+	sort.Slice(jobs, func(i, j int) bool {
+		return jobs[i].At.Before(jobs[j].At)
+	})
+
+	return jobs, nil
+}
+
+func (c *scheduleCmd) lsAction(_ *fisk.ParseContext) error {
+	kv, err := scheduleStore(false)
+	if errors.Is(err, nats.ErrBucketNotFound) {
+		kv = nil
+	} else if err != nil {
+		return err
+	}
+
+	jobs := []*scheduledJob{}
+	if kv != nil {
+		found, err := scheduledJobs(kv)
+		if err != nil {
+			return err
+		}
+		jobs = append(jobs, found...)
+	}
+
+	if c.json {
+		return printJSON(jobs)
+	}
+
+	if len(jobs) == 0 {
+		fmt.Println("No jobs are scheduled")
+		return nil
+	}
+
+	table := newTableWriter(fmt.Sprintf("%d Scheduled Jobs", len(jobs)))
+	table.AddHeaders("ID", "Action", "Target", "Due", "In")
+	for _, job := range jobs {
+		in := "overdue"
+		if until := time.Until(job.At); until > 0 {
+			in = humanizeDuration(until.Round(time.Second))
+		}
+
+		table.AddRow(job.ID, job.Action, job.target(), job.At.Local().Format(time.RFC1123), in)
+	}
+	fmt.Println(table.Render())
+
+	return nil
+}
+
+func (c *scheduleCmd) rmAction(_ *fisk.ParseContext) error {
+	kv, err := scheduleStore(false)
+	if err != nil {
+		return fmt.Errorf("unknown job %s: %w", c.id, err)
+	}
+
+	_, err = kv.Get(c.id)
+	if err != nil {
+		return fmt.Errorf("unknown job %s: %w", c.id, err)
+	}
+
+	if !c.force {
+		ok, err := askConfirmation(fmt.Sprintf("Really remove scheduled job %s", c.id), false)
+		fisk.FatalIfError(err, "could not obtain confirmation")
+
+		if !ok {
+			return nil
+		}
+	}
+
+	return kv.Delete(c.id)
+}
+
+func (c *scheduleCmd) runAction(_ *fisk.ParseContext) error {
+	_, mgr, err := prepareHelper("", natsOpts()...)
+	if err != nil {
+		return err
+	}
+
+	kv, err := scheduleStore(true)
+	if err != nil {
+		return fmt.Errorf("could not access the schedule bucket: %w", err)
+	}
+
+	ic := make(chan os.Signal, 1)
+	signal.Notify(ic, os.Interrupt)
+	defer signal.Stop(ic)
+
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+
+	if !c.once {
+		log.Printf("Executing jobs scheduled in the %s bucket every %s", scheduleBucket, humanizeDuration(c.interval))
+	}
+
+	for {
+		jobs, err := scheduledJobs(kv)
+		if err != nil {
+			log.Printf("Could not load scheduled jobs: %v", err)
+		}
+
+		for _, job := range jobs {
+			if job.At.After(time.Now()) {
+				break
+			}
+
+			// claims the job, other runners that loaded the same revision fail to delete it
+			err = kv.Delete(job.ID, nats.LastRevision(job.revision))
+			if err != nil {
+				continue
+			}
+
+			err = c.execute(mgr, job)
+			if err != nil {
+				log.Printf("Job %s: %s of %s failed: %v", job.ID, job.Action, job.target(), err)
+			} else {
+				log.Printf("Job %s: %s of %s completed", job.ID, job.Action, job.target())
+			}
+		}
+
+		if c.once {
+			return nil
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ic:
+			return nil
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+func (c *scheduleCmd) execute(mgr *jsm.Manager, job *scheduledJob) error {
+	switch job.Action {
+	case scheduleStreamPurge:
+		stream, err := mgr.LoadStream(job.Stream)
+		if err != nil {
+			return err
+		}
+
+		var req *api.JSApiStreamPurgeRequest
+		if job.Subject != "" || job.Sequence > 0 || job.Keep > 0 {
+			req = &api.JSApiStreamPurgeRequest{Subject: job.Subject, Sequence: job.Sequence, Keep: job.Keep}
+		}
+
+		return stream.Purge(req)
+
+	default:
+		return fmt.Errorf("unsupported action %q", job.Action)
+	}
+}
@@ -0,0 +1,47 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseScheduleTime(t *testing.T) {
+	now := time.Date(2023, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	for _, tc := range []struct {
+		at     string
+		expect time.Time
+	}{
+		{"2023-06-02T02:00:00Z", time.Date(2023, 6, 2, 2, 0, 0, 0, time.UTC)},
+		{"90m", now.Add(90 * time.Minute)},
+		{"1d", now.Add(24 * time.Hour)},
+	} {
+		at, err := parseScheduleTime(tc.at, now)
+		if err != nil {
+			t.Fatalf("unexpected error for %q: %v", tc.at, err)
+		}
+		if !at.Equal(tc.expect) {
+			t.Fatalf("expected %q to be %v got %v", tc.at, tc.expect, at)
+		}
+	}
+
+	for _, at := range []string{"", "tomorrow", "-1h", "2023-06-02 02:00"} {
+		_, err := parseScheduleTime(at, now)
+		if err == nil {
+			t.Fatalf("expected %q to fail", at)
+		}
+	}
+}
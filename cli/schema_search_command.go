@@ -40,8 +40,7 @@ func (c *schemaSearchCmd) search(_ *fisk.ParseContext) error {
 	}
 
 	if c.json {
-		printJSON(found)
-		return nil
+		return printJSON(found)
 	}
 
 	if len(found) == 0 {
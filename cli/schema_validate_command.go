@@ -68,8 +68,7 @@ func (c *schemaValidateCmd) validate(_ *fisk.ParseContext) error {
 		if errs == nil {
 			errs = []string{}
 		}
-		printJSON(errs)
-		return nil
+		return printJSON(errs)
 	}
 
 	if ok {
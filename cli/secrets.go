@@ -0,0 +1,392 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/kballard/go-shellquote"
+	"github.com/nats-io/jsm.go/natscontext"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nkeys"
+)
+
+// Context fields holding credentials can refer to secrets held in external systems, these are
+// resolved when connecting and only ever kept in memory:
+//
+//	vault://secret/nats/prod#creds   a field in a HashiCorp Vault secret using VAULT_ADDR and VAULT_TOKEN
+//	awssm://nats-prod-creds#creds    an AWS Secrets Manager secret, optionally a field in a JSON secret
+//	cmd://pass show nats             the output of a command
+var secretProviders = map[string]func(string) ([]byte, error){
+	"vault://": resolveVaultSecret,
+	"awssm://": resolveAWSSecret,
+	"cmd://":   resolveCommandSecret,
+}
+
+var (
+	secretCache = map[string][]byte{}
+	secretMu    sync.Mutex
+)
+
+func isSecretReference(v string) bool {
+	for prefix := range secretProviders {
+		if strings.HasPrefix(v, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// resolveSecret resolves a secret reference, resolved secrets are cached for the life of the process
+func resolveSecret(ref string) ([]byte, error) {
+	secretMu.Lock()
+	defer secretMu.Unlock()
+
+	secret, ok := secretCache[ref]
+	if ok {
+		return secret, nil
+	}
+
+	for prefix, resolver := range secretProviders {
+		if !strings.HasPrefix(ref, prefix) {
+			continue
+		}
+
+		secret, err := resolver(strings.TrimPrefix(ref, prefix))
+		if err != nil {
+			return nil, fmt.Errorf("could not resolve secret %s: %w", ref, err)
+		}
+
+		secret = bytes.TrimSpace(secret)
+		if len(secret) == 0 {
+			return nil, fmt.Errorf("secret %s is empty", ref)
+		}
+
+		secretCache[ref] = secret
+
+		return secret, nil
+	}
+
+	return nil, fmt.Errorf("unsupported secret reference %s", ref)
+}
+
+// secretField picks a field from a secret holding a JSON object, a secret with only one field does not need it named
+func secretField(data map[string]any, field string) ([]byte, error) {
+	if field == "" {
+		if len(data) != 1 {
+			return nil, fmt.Errorf("secret has %d fields, select one using #field", len(data))
+		}
+
+		for k := range data {
+			field = k
+		}
+	}
+
+	val, ok := data[field]
+	if !ok {
+		return nil, fmt.Errorf("secret has no field %q", field)
+	}
+
+	switch v := val.(type) {
+	case string:
+		return []byte(v), nil
+	default:
+		return json.Marshal(v)
+	}
+}
+
+func splitSecretField(ref string) (string, string) {
+	path, field, _ := strings.Cut(ref, "#")
+	return path, field
+}
+
+func resolveCommandSecret(command string) ([]byte, error) {
+	parts, err := shellquote.Split(command)
+	if err != nil {
+		return nil, err
+	}
+	if len(parts) == 0 {
+		return nil, fmt.Errorf("no command given")
+	}
+
+	cmd := exec.Command(parts[0], parts[1:]...)
+	cmd.Stdin = os.Stdin
+	cmd.Stderr = os.Stderr
+
+	return cmd.Output()
+}
+
+func resolveAWSSecret(ref string) ([]byte, error) {
+	id, field := splitSecretField(ref)
+
+	out, err := exec.Command("aws", "secretsmanager", "get-secret-value", "--secret-id", id, "--query", "SecretString", "--output", "text").Output()
+	if err != nil {
+		var ee *exec.ExitError
+		if errors.As(err, &ee) {
+			return nil, fmt.Errorf("%s", bytes.TrimSpace(ee.Stderr))
+		}
+		return nil, err
+	}
+
+	if field == "" {
+		return out, nil
+	}
+
+	data := map[string]any{}
+	err = json.Unmarshal(out, &data)
+	if err != nil {
+		return nil, fmt.Errorf("secret is not a JSON object: %w", err)
+	}
+
+	return secretField(data, field)
+}
+
+func vaultToken() (string, error) {
+	token := os.Getenv("VAULT_TOKEN")
+	if token != "" {
+		return token, nil
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+
+	tf, err := os.ReadFile(filepath.Join(home, ".vault-token"))
+	if err != nil {
+		return "", fmt.Errorf("set VAULT_TOKEN or log into vault")
+	}
+
+	return strings.TrimSpace(string(tf)), nil
+}
+
+func vaultRead(addr string, token string, path string) (map[string]any, int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("%s/v1/%s", strings.TrimSuffix(addr, "/"), path), nil)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	req.Header.Set("X-Vault-Token", token)
+	if ns := os.Getenv("VAULT_NAMESPACE"); ns != "" {
+		req.Header.Set("X-Vault-Namespace", ns)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, resp.StatusCode, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, resp.StatusCode, fmt.Errorf("vault returned %s", resp.Status)
+	}
+
+	secret := struct {
+		Data map[string]any `json:"data"`
+	}{}
+	err = json.Unmarshal(body, &secret)
+	if err != nil {
+		return nil, resp.StatusCode, err
+	}
+
+	// KV version 2 nests the secret with its metadata
+	nested, ok := secret.Data["data"].(map[string]any)
+	_, meta := secret.Data["metadata"]
+	if ok && meta {
+		return nested, resp.StatusCode, nil
+	}
+
+	return secret.Data, resp.StatusCode, nil
+}
+
+func resolveVaultSecret(ref string) ([]byte, error) {
+	path, field := splitSecretField(strings.TrimPrefix(ref, "/"))
+
+	addr := os.Getenv("VAULT_ADDR")
+	if addr == "" {
+		addr = "https://127.0.0.1:8200"
+	}
+
+	token, err := vaultToken()
+	if err != nil {
+		return nil, err
+	}
+
+	data, status, err := vaultRead(addr, token, path)
+	if status == http.StatusNotFound {
+		// secret/nats/prod in a KV version 2 mount is read from secret/data/nats/prod
+		mount, rest, found := strings.Cut(path, "/")
+		if found && !strings.HasPrefix(rest, "data/") {
+			data, _, err = vaultRead(addr, token, mount+"/data/"+rest)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return secretField(data, field)
+}
+
+// contextHasSecrets determines if any credential in the context refers to an external secret
+func contextHasSecrets(cfg *natscontext.Context) bool {
+	for _, v := range []string{cfg.Password(), cfg.Creds(), cfg.NKey(), cfg.Token()} {
+		if isSecretReference(v) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// readContextFile reads a file referenced in a context, resolving external secrets when needed
+func readContextFile(file string) ([]byte, error) {
+	if isSecretReference(file) {
+		return resolveSecret(file)
+	}
+
+	return os.ReadFile(file)
+}
+
+// contextNATSOptions is like natscontext.Context#NATSOptions but supports credentials held in external secrets
+// and HTTP proxies in addition to SOCKS5 ones
+func contextNATSOptions(cfg *natscontext.Context) ([]nats.Option, error) {
+	var nopts []nats.Option
+	var err error
+
+	if contextHasSecrets(cfg) {
+		nopts, err = secretNATSOptions(cfg)
+	} else {
+		nopts, err = cfg.NATSOptions()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.SocksProxy() != "" {
+		pd, err := newProxyDialer(cfg.SocksProxy(), opts.Timeout)
+		if err != nil {
+			return nil, err
+		}
+		nopts = append(nopts, nats.SetCustomDialer(pd))
+	}
+
+	if tracingEnabled() {
+		nopts = append(nopts, tracingOption())
+	}
+
+	return nopts, nil
+}
+
+func secretNATSOptions(cfg *natscontext.Context) ([]nats.Option, error) {
+	resolve := func(v string) (string, error) {
+		if !isSecretReference(v) {
+			return v, nil
+		}
+
+		secret, err := resolveSecret(v)
+		return string(secret), err
+	}
+
+	var nopts []nats.Option
+
+	switch {
+	case cfg.User() != "":
+		password, err := resolve(cfg.Password())
+		if err != nil {
+			return nil, err
+		}
+		nopts = append(nopts, nats.UserInfo(cfg.User(), password))
+
+	case isSecretReference(cfg.Creds()):
+		creds, err := resolveSecret(cfg.Creds())
+		if err != nil {
+			return nil, err
+		}
+
+		jwt, err := nkeys.ParseDecoratedJWT(creds)
+		if err != nil {
+			return nil, fmt.Errorf("invalid credentials in %s: %w", cfg.Creds(), err)
+		}
+		kp, err := nkeys.ParseDecoratedNKey(creds)
+		if err != nil {
+			return nil, fmt.Errorf("invalid credentials in %s: %w", cfg.Creds(), err)
+		}
+
+		nopts = append(nopts, nats.UserJWT(func() (string, error) { return jwt, nil }, kp.Sign))
+
+	case cfg.Creds() != "":
+		nopts = append(nopts, nats.UserCredentials(cfg.Creds()))
+
+	case isSecretReference(cfg.NKey()):
+		seed, err := resolveSecret(cfg.NKey())
+		if err != nil {
+			return nil, err
+		}
+
+		kp, err := nkeys.ParseDecoratedNKey(seed)
+		if err != nil {
+			return nil, fmt.Errorf("invalid nkey seed in %s: %w", cfg.NKey(), err)
+		}
+		pk, err := kp.PublicKey()
+		if err != nil {
+			return nil, err
+		}
+
+		nopts = append(nopts, nats.Nkey(pk, kp.Sign))
+
+	case cfg.NKey() != "":
+		nko, err := nats.NkeyOptionFromSeed(cfg.NKey())
+		if err != nil {
+			return nil, err
+		}
+		nopts = append(nopts, nko)
+
+	case cfg.Token() != "":
+		token, err := resolve(cfg.Token())
+		if err != nil {
+			return nil, err
+		}
+		nopts = append(nopts, nats.Token(token))
+	}
+
+	if cfg.Certificate() != "" && cfg.Key() != "" {
+		nopts = append(nopts, nats.ClientCert(cfg.Certificate(), cfg.Key()))
+	}
+
+	if cfg.CA() != "" {
+		nopts = append(nopts, nats.RootCAs(cfg.CA()))
+	}
+
+	if cfg.InboxPrefix() != "" {
+		nopts = append(nopts, nats.CustomInboxPrefix(cfg.InboxPrefix()))
+	}
+
+	return nopts, nil
+}
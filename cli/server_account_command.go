@@ -21,13 +21,13 @@ func configureServerAccountCommand(srv *fisk.CmdClause) {
 	c := &srvAccountCommand{}
 
 	account := srv.Command("account", "Interact with accounts").Alias("acct")
-	account.Flag("json", "Produce JSON output").Short('j').UnNegatableBoolVar(&c.json)
+	addJSONOutputFlag(account, &c.json)
 
 	info := account.Command("info", "Shows information for an account").Alias("i").Action(c.infoAction)
 	info.Arg("account", "The name of the account to view").Required().StringVar(&c.account)
 	info.Flag("host", "Request information from a specific server").StringVar(&c.server)
 
-	purge := account.Command("purge", "Purge assets from JetStream clusters").Action(c.purgeAccount)
+	purge := mutating(account.Command("purge", "Purge assets from JetStream clusters").Action(c.purgeAccount))
 	purge.Arg("account", "The name of the account to purge").PlaceHolder("NAME").Required().StringVar(&c.account)
 	purge.Flag("force", "Perform the operation without prompting").Short('f').UnNegatableBoolVar(&c.force)
 }
@@ -119,8 +119,7 @@ func (c *srvAccountCommand) infoAction(_ *fisk.ParseContext) error {
 	nfo := account.Account
 
 	if c.json {
-		printJSON(nfo)
-		return nil
+		return printJSON(nfo)
 	}
 
 	fmt.Printf("Account information for account %s\n\n", nfo.AccountName)
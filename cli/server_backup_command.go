@@ -0,0 +1,457 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/choria-io/fisk"
+	"github.com/dustin/go-humanize"
+	"github.com/gosuri/uiprogress"
+	"github.com/nats-io/jsm.go"
+	"github.com/nats-io/jsm.go/api"
+)
+
+const drManifestFile = "manifest.json"
+
+type SrvBackupCmd struct {
+	directory        string
+	healthCheck      bool
+	consumers        bool
+	force            bool
+	showProgress     bool
+	parallel         int
+	placementCluster string
+	placementTags    []string
+
+	// kinds limits the backup to assets of these kinds, all assets are backed up when empty
+	kinds []string
+}
+
+// drManifest describes the contents of a disaster recovery bundle
+type drManifest struct {
+	Created time.Time                  `json:"created"`
+	Account *api.JetStreamAccountStats `json:"account,omitempty"`
+	Assets  []*drAsset                 `json:"assets"`
+}
+
+// drAsset is a single stream in the bundle, memory streams cannot be snapshot so only their configuration is kept
+type drAsset struct {
+	Name       string               `json:"name"`
+	Kind       string               `json:"kind"`
+	Bucket     string               `json:"bucket,omitempty"`
+	Directory  string               `json:"directory,omitempty"`
+	ConfigOnly bool                 `json:"config_only,omitempty"`
+	Config     api.StreamConfig     `json:"config"`
+	Consumers  []api.ConsumerConfig `json:"consumers,omitempty"`
+	Messages   uint64               `json:"messages"`
+	Bytes      uint64               `json:"bytes"`
+}
+
+func configureServerBackupCommand(srv *fisk.CmdClause) {
+	c := &SrvBackupCmd{}
+
+	backup := srv.Command("backup-all", "Creates a disaster recovery bundle of all Streams, Key-Value and Object Store buckets").Action(c.backupAction)
+	backup.Flag("output", "Directory to create the bundle in").Short('o').Required().StringVar(&c.directory)
+	backup.Flag("check", "Checks the Streams for health prior to backup").UnNegatableBoolVar(&c.healthCheck)
+	backup.Flag("consumers", "Include Consumer state in the backup").Default("true").BoolVar(&c.consumers)
+	backup.Flag("progress", "Enables or disables progress reporting using a progress bar").Default("true").BoolVar(&c.showProgress)
+	backup.Flag("force", "Perform backup without prompting").Short('f').UnNegatableBoolVar(&c.force)
+	backup.Flag("parallel", "Number of Streams to back up concurrently, disables progress bars when above 1").Default("1").PlaceHolder("N").IntVar(&c.parallel)
+
+	restore := mutating(srv.Command("restore-all", "Restores a disaster recovery bundle created using backup-all").Action(c.restoreAction))
+	restore.Arg("directory", "The directory holding the bundle").Required().ExistingDirVar(&c.directory)
+	restore.Flag("progress", "Enables or disables progress reporting using a progress bar").Default("true").BoolVar(&c.showProgress)
+	restore.Flag("cluster", "Place the Streams in a specific cluster").StringVar(&c.placementCluster)
+	restore.Flag("tag", "Place the Streams on servers that has specific tags (pass multiple times)").StringsVar(&c.placementTags)
+	restore.Flag("force", "Perform restore without prompting").Short('f').UnNegatableBoolVar(&c.force)
+}
+
+func drAssetKind(stream string) string {
+	switch {
+	case strings.HasPrefix(stream, "KV_"):
+		return "kv"
+	case strings.HasPrefix(stream, "OBJ_"):
+		return "object"
+	default:
+		return "stream"
+	}
+}
+
+// drAssetBucket is the bucket name of a Key-Value or Object Store stream
+func drAssetBucket(stream string) string {
+	switch drAssetKind(stream) {
+	case "kv":
+		return strings.TrimPrefix(stream, "KV_")
+	case "object":
+		return strings.TrimPrefix(stream, "OBJ_")
+	default:
+		return ""
+	}
+}
+
+// drAssetDirectory is the directory an asset is stored in, buckets are stored by kind and bucket name
+func drAssetDirectory(stream string) string {
+	switch bucket := drAssetBucket(stream); drAssetKind(stream) {
+	case "kv":
+		return filepath.Join("kv", bucket)
+	case "object":
+		return filepath.Join("object", bucket)
+	default:
+		return filepath.Join("streams", stream)
+	}
+}
+
+// drRestoreOrder orders assets so that streams are restored before any stream that mirrors or sources them
+func drRestoreOrder(assets []*drAsset) []*drAsset {
+	inBundle := map[string]bool{}
+	for _, a := range assets {
+		inBundle[a.Name] = true
+	}
+
+	deps := func(a *drAsset) []string {
+		var res []string
+		if a.Config.Mirror != nil && inBundle[a.Config.Mirror.Name] {
+			res = append(res, a.Config.Mirror.Name)
+		}
+		for _, s := range a.Config.Sources {
+			if s != nil && inBundle[s.Name] {
+				res = append(res, s.Name)
+			}
+		}
+		return res
+	}
+
+	var ordered []*drAsset
+	placed := map[string]bool{}
+	remaining := assets
+
+	for len(remaining) > 0 {
+		var next []*drAsset
+		for _, a := range remaining {
+			ready := true
+			for _, d := range deps(a) {
+				if !placed[d] {
+					ready = false
+					break
+				}
+			}
+
+			if ready {
+				ordered = append(ordered, a)
+				placed[a.Name] = true
+			} else {
+				next = append(next, a)
+			}
+		}
+
+		// circular sources, restore the rest in their original order
+		if len(next) == len(remaining) {
+			return append(ordered, next...)
+		}
+
+		remaining = next
+	}
+
+	return ordered
+}
+
+func (c *SrvBackupCmd) backupAction(_ *fisk.ParseContext) error {
+	_, mgr, err := prepareHelper("", natsOpts()...)
+	if err != nil {
+		return err
+	}
+
+	streams, missing, err := loadStreams(mgr, nil, c.parallel)
+	if err != nil {
+		return err
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("could not obtain stream information for %d streams", len(missing))
+	}
+
+	if len(c.kinds) > 0 {
+		var selected []*jsm.Stream
+		for _, s := range streams {
+			for _, k := range c.kinds {
+				if drAssetKind(s.Name()) == k {
+					selected = append(selected, s)
+				}
+			}
+		}
+		streams = selected
+	}
+
+	if len(streams) == 0 {
+		return fmt.Errorf("no streams found")
+	}
+
+	manifest := &drManifest{Created: time.Now().UTC()}
+	manifest.Account, err = mgr.JetStreamAccountInfo()
+	if err != nil {
+		return err
+	}
+
+	var totalSize uint64
+	kinds := map[string]int{}
+	for _, s := range streams {
+		state, _ := s.LatestState()
+		totalSize += state.Bytes
+		kinds[drAssetKind(s.Name())]++
+	}
+
+	fmt.Printf("Creating disaster recovery bundle in %s\n\n", c.directory)
+	if len(c.kinds) == 0 {
+		fmt.Printf("         Streams: %s\n", humanize.Comma(int64(kinds["stream"])))
+	}
+	fmt.Printf("Key-Value Stores: %s\n", humanize.Comma(int64(kinds["kv"])))
+	fmt.Printf("   Object Stores: %s\n", humanize.Comma(int64(kinds["object"])))
+	fmt.Printf("            Size: %s\n", humanize.IBytes(totalSize))
+	fmt.Println()
+
+	if !c.force {
+		ok, err := askConfirmation("Perform backup", false)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return nil
+		}
+	}
+
+	err = os.MkdirAll(c.directory, 0700)
+	if err != nil {
+		return err
+	}
+
+	// progress bars of concurrent backups would overwrite each other
+	showProgress := c.showProgress && c.parallel <= 1
+
+	var failed []string
+	var mu sync.Mutex
+	assets := make([]*drAsset, len(streams))
+
+	parallelDo(c.parallel, len(streams), func(i int) {
+		s := streams[i]
+		fmt.Printf("[%d/%d] Backing up %s %s\n", i+1, len(streams), drAssetKind(s.Name()), s.Name())
+
+		fail := func(format string, a ...any) {
+			mu.Lock()
+			failed = append(failed, s.Name())
+			mu.Unlock()
+			fmt.Printf(format, a...)
+		}
+
+		asset := &drAsset{Name: s.Name(), Kind: drAssetKind(s.Name()), Bucket: drAssetBucket(s.Name()), Config: s.Configuration()}
+		state, err := s.LatestState()
+		if err == nil {
+			asset.Messages = state.Msgs
+			asset.Bytes = state.Bytes
+		}
+
+		consumers, _, err := loadConsumers(mgr, s.Name(), 1)
+		if err != nil {
+			fail("Could not list consumers for %s: %s\n\n", s.Name(), err)
+			return
+		}
+
+		for _, cons := range consumers {
+			asset.Consumers = append(asset.Consumers, cons.Configuration())
+		}
+
+		asset.Directory = drAssetDirectory(s.Name())
+		err = os.MkdirAll(filepath.Dir(filepath.Join(c.directory, asset.Directory)), 0700)
+		if err != nil {
+			fail("Backup of %s failed: %s\n\n", s.Name(), err)
+			return
+		}
+
+		err = backupStream(s, showProgress, c.consumers, c.healthCheck, filepath.Join(c.directory, asset.Directory))
+		switch {
+		case errors.Is(err, jsm.ErrMemoryStreamNotSupported):
+			fmt.Printf("Memory based %s only has its configuration saved\n", s.Name())
+			asset.Directory = ""
+			asset.ConfigOnly = true
+		case err != nil:
+			fail("Backup of %s failed: %s\n\n", s.Name(), err)
+			return
+		}
+
+		assets[i] = asset
+		fmt.Println()
+	})
+
+	for _, asset := range assets {
+		if asset != nil {
+			manifest.Assets = append(manifest.Assets, asset)
+		}
+	}
+	sort.Strings(failed)
+
+	mj, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	err = os.WriteFile(filepath.Join(c.directory, drManifestFile), mj, 0600)
+	if err != nil {
+		return err
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("backup of %d streams failed: %s", len(failed), strings.Join(failed, ", "))
+	}
+
+	fmt.Printf("Created bundle of %d assets in %s\n", len(manifest.Assets), c.directory)
+
+	return nil
+}
+
+func (c *SrvBackupCmd) restoreAction(_ *fisk.ParseContext) error {
+	_, mgr, err := prepareHelper("", natsOpts()...)
+	if err != nil {
+		return err
+	}
+
+	mj, err := os.ReadFile(filepath.Join(c.directory, drManifestFile))
+	if err != nil {
+		return fmt.Errorf("invalid bundle: %w", err)
+	}
+
+	manifest := &drManifest{}
+	err = json.Unmarshal(mj, manifest)
+	if err != nil {
+		return fmt.Errorf("invalid manifest: %w", err)
+	}
+
+	assets := drRestoreOrder(manifest.Assets)
+
+	var existing []string
+	for _, a := range assets {
+		known, err := mgr.IsKnownStream(a.Name)
+		if err != nil {
+			return err
+		}
+		if known {
+			existing = append(existing, a.Name)
+		}
+	}
+	if len(existing) > 0 {
+		return fmt.Errorf("streams already exist: %s", strings.Join(existing, ", "))
+	}
+
+	fmt.Printf("Restoring %d assets from bundle created %s\n\n", len(assets), manifest.Created.Local().Format(time.RFC1123))
+
+	if !c.force {
+		ok, err := askConfirmation("Perform restore", false)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return nil
+		}
+	}
+
+	var failed []string
+	for i, a := range assets {
+		name := a.Name
+		if a.Bucket != "" {
+			name = a.Bucket
+		}
+		fmt.Printf("[%d/%d] Restoring %s %s\n", i+1, len(assets), a.Kind, name)
+
+		err = c.restoreAsset(mgr, a)
+		if err != nil {
+			failed = append(failed, a.Name)
+			fmt.Printf("Restore of %s failed: %s\n", a.Name, err)
+		}
+		fmt.Println()
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("restore of %d streams failed: %s", len(failed), strings.Join(failed, ", "))
+	}
+
+	fmt.Printf("Restored %d assets from %s\n", len(assets), c.directory)
+
+	return nil
+}
+
+func (c *SrvBackupCmd) restoreAsset(mgr *jsm.Manager, a *drAsset) error {
+	cfg := a.Config
+	if c.placementCluster != "" || len(c.placementTags) > 0 {
+		cfg.Placement = &api.Placement{Cluster: c.placementCluster, Tags: c.placementTags}
+	}
+
+	if a.ConfigOnly {
+		_, err := mgr.NewStreamFromDefault(a.Name, cfg)
+		if err != nil {
+			return err
+		}
+
+		for _, cc := range a.Consumers {
+			_, err = mgr.NewConsumerFromDefault(a.Name, cc)
+			if err != nil {
+				return fmt.Errorf("could not create consumer %s: %w", cc.Durable, err)
+			}
+		}
+
+		fmt.Printf("Created %s with %d consumers from configuration only\n", a.Name, len(a.Consumers))
+
+		return nil
+	}
+
+	var bar *uiprogress.Bar
+	var bps uint64
+
+	progress := uiprogress.New()
+	opts := []jsm.SnapshotOption{jsm.RestoreConfiguration(cfg)}
+	if c.showProgress {
+		progress.Start()
+		opts = append(opts, jsm.RestoreNotify(func(p jsm.RestoreProgress) {
+			bps = p.BytesPerSecond()
+			if bar == nil {
+				bar = progress.AddBar(p.ChunksToSend()).AppendCompleted().PrependFunc(func(b *uiprogress.Bar) string {
+					return humanize.IBytes(bps) + "/s"
+				})
+				bar.Width = progressWidth()
+			}
+			bar.Set(int(p.ChunksSent()))
+		}))
+	}
+
+	fp, state, err := mgr.RestoreSnapshotFromDirectory(ctx, a.Name, filepath.Join(c.directory, a.Directory), opts...)
+	if c.showProgress {
+		progress.Stop()
+	}
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Restored %s messages in %v\n", humanize.Comma(int64(state.Msgs)), fp.EndTime().Sub(fp.StartTime()).Round(time.Millisecond))
+
+	if state.Msgs != a.Messages {
+		fmt.Printf("Warning: expected %s messages but restored %s\n", humanize.Comma(int64(a.Messages)), humanize.Comma(int64(state.Msgs)))
+	}
+
+	return nil
+}
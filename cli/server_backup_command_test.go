@@ -0,0 +1,56 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"testing"
+
+	"github.com/nats-io/jsm.go/api"
+)
+
+func TestDRRestoreOrder(t *testing.T) {
+	asset := func(name string, mirror string, sources ...string) *drAsset {
+		a := &drAsset{Name: name, Config: api.StreamConfig{Name: name}}
+		if mirror != "" {
+			a.Config.Mirror = &api.StreamSource{Name: mirror}
+		}
+		for _, s := range sources {
+			a.Config.Sources = append(a.Config.Sources, &api.StreamSource{Name: s})
+		}
+		return a
+	}
+
+	names := func(assets []*drAsset) []string {
+		var res []string
+		for _, a := range assets {
+			res = append(res, a.Name)
+		}
+		return res
+	}
+
+	ordered := drRestoreOrder([]*drAsset{
+		asset("AGGREGATE", "", "ORDERS", "MIRROR"),
+		asset("MIRROR", "ORDERS"),
+		asset("ORDERS", ""),
+		asset("REMOTE", "", "ELSEWHERE"),
+	})
+	assertListEquals(t, names(ordered), "ORDERS", "REMOTE", "MIRROR", "AGGREGATE")
+
+	ordered = drRestoreOrder([]*drAsset{
+		asset("A", "", "B"),
+		asset("B", "", "A"),
+		asset("C", ""),
+	})
+	assertListEquals(t, names(ordered), "C", "A", "B")
+}
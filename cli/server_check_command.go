@@ -16,10 +16,17 @@ package cli
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"net/http"
 	"net/url"
+	"os"
+	"os/exec"
 	"regexp"
+	"sort"
 	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/choria-io/fisk"
@@ -63,6 +70,7 @@ type SrvCheckCmd struct {
 	jsReplicas            bool
 	jsReplicaSeenCritical time.Duration
 	jsReplicaLagCritical  uint64
+	jsDeep                bool
 
 	srvName        string
 	srvCPUWarn     int
@@ -90,6 +98,12 @@ type SrvCheckCmd struct {
 	kvValuesCrit int
 	kvValuesWarn int
 	kvKey        string
+
+	tlsExpireWarn time.Duration
+	tlsExpireCrit time.Duration
+	tlsAddresses  []string
+	tlsDiscover   bool
+	tlsRequire    bool
 }
 
 func configureServerCheckCommand(srv *fisk.CmdClause) {
@@ -99,6 +113,9 @@ func configureServerCheckCommand(srv *fisk.CmdClause) {
 	check.Flag("format", "Render the check in a specific format (nagios, json, prometheus, text)").Default("nagios").EnumVar(&checkRenderFormatText, "nagios", "json", "prometheus", "text")
 	check.Flag("namespace", "The prometheus namespace to use in output").Default(opts.PrometheusNamespace).StringVar(&opts.PrometheusNamespace)
 	check.Flag("outfile", "Save output to a file rather than STDOUT").StringVar(&checkRenderOutFile)
+	check.Flag("exporter", "Runs the check continuously and serves its Prometheus metrics on /metrics at this address").PlaceHolder("ADDRESS").StringVar(&checkExporterListen)
+	addPushGatewayFlags(check, &checkPushGateway, &checkPushJob)
+	check.Flag("exporter-interval", "How often to run the check in exporter mode").Default("30s").PlaceHolder("DURATION").DurationVar(&checkExporterInterval)
 	check.PreAction(c.parseRenderFormat)
 
 	conn := check.Command("connection", "Checks basic server connection").Alias("conn").Default().Action(c.checkConnection)
@@ -148,6 +165,7 @@ func configureServerCheckCommand(srv *fisk.CmdClause) {
 	js.Flag("replicas", "Checks if all streams have healthy replicas").Default("true").BoolVar(&c.jsReplicas)
 	js.Flag("replica-seen-critical", "Critical threshold for when a stream replica should have been seen, as a duration").Default("5s").DurationVar(&c.jsReplicaSeenCritical)
 	js.Flag("replica-lag-critical", "Critical threshold for how many operations behind a peer can be").Default("200").Uint64Var(&c.jsReplicaLagCritical)
+	js.Flag("deep", "Compares the configuration and state of every Stream and Consumer across all replicas, requires system access").UnNegatableBoolVar(&c.jsDeep)
 
 	serv := check.Command("server", "Checks a NATS Server health").Action(c.checkSrv)
 	serv.Flag("name", "Server name to require in the result").Required().StringVar(&c.srvName)
@@ -170,15 +188,35 @@ func configureServerCheckCommand(srv *fisk.CmdClause) {
 	kv.Flag("values-critical", "Critical threshold for number of values in the bucket").Default("-1").IntVar(&c.kvValuesCrit)
 	kv.Flag("values-warn", "Warning threshold for number of values in the bucket").Default("-1").IntVar(&c.kvValuesWarn)
 	kv.Flag("key", "Requires a key to have any non-delete value set").StringVar(&c.kvKey)
+
+	c.configureCheckTLS(check)
 }
 
 var (
 	checkRenderFormatText = "nagios"
 	checkRenderFormat     = monitor.NagiosFormat
 	checkRenderOutFile    = ""
+	checkExporterListen   = ""
+	checkExporterInterval time.Duration
+	checkPushGateway      = ""
+	checkPushJob          = ""
 )
 
+// checkExporterChildEnv is set when the exporter runs the check as a child process
+const checkExporterChildEnv = "NATS_CLI_CHECK_EXPORTER"
+
 func (c *SrvCheckCmd) parseRenderFormat(_ *fisk.ParseContext) error {
+	if os.Getenv(checkExporterChildEnv) != "" {
+		checkRenderFormat = monitor.PrometheusFormat
+		checkRenderOutFile = ""
+		checkPushGateway = ""
+		return nil
+	}
+
+	if checkExporterListen != "" {
+		return c.runExporter()
+	}
+
 	switch checkRenderFormatText {
 	case "prometheus":
 		checkRenderFormat = monitor.PrometheusFormat
@@ -191,6 +229,95 @@ func (c *SrvCheckCmd) parseRenderFormat(_ *fisk.ParseContext) error {
 	return nil
 }
 
+// checkExporterOutput interprets the result of a check child process, checks exit using the Nagios codes 1, 2
+// and 3 for warning, critical and unknown results which are completed checks whose metrics should be served
+func checkExporterOutput(out []byte, err error) ([]byte, error) {
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() >= 1 && exitErr.ExitCode() <= 3 && len(out) > 0 {
+		return out, nil
+	}
+
+	return out, err
+}
+
+// runExporter repeatedly runs the requested check as a child process, checks exit the
+// process once done, and serves the latest Prometheus formatted result
+func (c *SrvCheckCmd) runExporter() error {
+	if checkExporterInterval < time.Second {
+		return fmt.Errorf("exporter interval must be at least 1s")
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+
+	var (
+		mu      sync.Mutex
+		metrics []byte
+		lastErr error
+	)
+
+	run := func() {
+		cmd := exec.CommandContext(ctx, exe, os.Args[1:]...)
+		cmd.Env = append(os.Environ(), fmt.Sprintf("%s=1", checkExporterChildEnv))
+		cmd.Stderr = os.Stderr
+
+		out, err := checkExporterOutput(cmd.Output())
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		if err != nil {
+			log.Printf("Check failed: %s", err)
+			lastErr = err
+			return
+		}
+
+		metrics = out
+		lastErr = nil
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", func(w http.ResponseWriter, _ *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if lastErr != nil || metrics == nil {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			fmt.Fprintf(w, "check not completed: %v\n", lastErr)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		w.Write(metrics)
+	})
+
+	go func() {
+		run()
+
+		ticker := time.NewTicker(checkExporterInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				run()
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	log.Printf("Serving check metrics on http://%s/metrics, checking every %v", checkExporterListen, checkExporterInterval)
+
+	srv := &http.Server{Addr: checkExporterListen, Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	err = srv.ListenAndServe()
+	fisk.FatalIfError(err, "exporter failed")
+
+	return nil
+}
+
 func (c *SrvCheckCmd) checkKVStatusAndBucket(check *monitor.Result, nc *nats.Conn) {
 	js, err := nc.JetStream()
 	check.CriticalIfErr(err, "connection failed: %v", err)
@@ -261,7 +388,7 @@ func (c *SrvCheckCmd) checkKVStatusAndBucket(check *monitor.Result, nc *nats.Con
 }
 
 func (c *SrvCheckCmd) checkKV(_ *fisk.ParseContext) error {
-	check := &monitor.Result{Name: c.kvBucket, Check: "kv", OutFile: checkRenderOutFile, NameSpace: opts.PrometheusNamespace, RenderFormat: checkRenderFormat}
+	check := &monitor.Result{Name: c.kvBucket, Check: "kv", OutFile: checkRenderOutFile, PushGateway: checkPushGateway, PushJob: checkPushJob, NameSpace: opts.PrometheusNamespace, RenderFormat: checkRenderFormat}
 	defer check.GenericExit()
 
 	nc, _, err := prepareHelper("", natsOpts()...)
@@ -273,7 +400,7 @@ func (c *SrvCheckCmd) checkKV(_ *fisk.ParseContext) error {
 }
 
 func (c *SrvCheckCmd) checkSrv(_ *fisk.ParseContext) error {
-	check := &monitor.Result{Name: c.srvName, Check: "server", OutFile: checkRenderOutFile, NameSpace: opts.PrometheusNamespace, RenderFormat: checkRenderFormat}
+	check := &monitor.Result{Name: c.srvName, Check: "server", OutFile: checkRenderOutFile, PushGateway: checkPushGateway, PushJob: checkPushJob, NameSpace: opts.PrometheusNamespace, RenderFormat: checkRenderFormat}
 	defer check.GenericExit()
 
 	vz, err := c.fetchVarz()
@@ -431,12 +558,22 @@ func (c *SrvCheckCmd) fetchVarz() (*server.Varz, error) {
 }
 
 func (c *SrvCheckCmd) checkJS(_ *fisk.ParseContext) error {
-	check := &monitor.Result{Name: "JetStream", Check: "jetstream", OutFile: checkRenderOutFile, NameSpace: opts.PrometheusNamespace, RenderFormat: checkRenderFormat}
+	check := &monitor.Result{Name: "JetStream", Check: "jetstream", OutFile: checkRenderOutFile, PushGateway: checkPushGateway, PushJob: checkPushJob, NameSpace: opts.PrometheusNamespace, RenderFormat: checkRenderFormat}
 	defer check.GenericExit()
 
-	_, mgr, err := prepareHelper("", natsOpts()...)
+	nc, mgr, err := prepareHelper("", natsOpts()...)
 	check.CriticalIfErr(err, "connection failed: %s", err)
 
+	if c.jsDeep {
+		assets, err := c.fetchJSDeepReplicas(nc)
+		check.CriticalIfErr(err, "JSZ API request failed: %s", err)
+
+		err = c.checkJSDeepReplicas(check, assets)
+		check.CriticalIfErr(err, "JetStream consistency check failed: %s", err)
+
+		return nil
+	}
+
 	info, err := mgr.JetStreamAccountInfo()
 	check.CriticalIfErr(err, "JetStream not available: %s", err)
 
@@ -549,6 +686,157 @@ func (c *SrvCheckCmd) checkStreamClusterHealth(check *monitor.Result, info []*js
 	return nil
 }
 
+// jsDeepReplica is the view a single server has of a stream it hosts
+type jsDeepReplica struct {
+	server string
+	stream server.StreamDetail
+}
+
+// fetchJSDeepReplicas gathers every server's view of the streams it hosts keyed by account and stream name
+func (c *SrvCheckCmd) fetchJSDeepReplicas(nc *nats.Conn) (map[string][]*jsDeepReplica, error) {
+	req := &server.JszEventOptions{
+		JSzOptions:         server.JSzOptions{Accounts: true, Streams: true, Consumer: true, Config: true, Limit: 10000},
+		EventFilterOptions: server.EventFilterOptions{Domain: opts.Config.JSDomain()},
+	}
+
+	res, err := doReq(req, "$SYS.REQ.SERVER.PING.JSZ", 0, nc)
+	if err != nil {
+		return nil, err
+	}
+	if len(res) == 0 {
+		return nil, fmt.Errorf("no JSZ responses received, system privileges are required")
+	}
+
+	assets := map[string][]*jsDeepReplica{}
+	for _, r := range res {
+		resp := struct {
+			Data   server.JSInfo     `json:"data"`
+			Server server.ServerInfo `json:"server"`
+			Error  *server.ApiError  `json:"error"`
+		}{}
+
+		err = json.Unmarshal(r, &resp)
+		if err != nil {
+			return nil, err
+		}
+		if resp.Error != nil {
+			return nil, fmt.Errorf("%s: %s", resp.Server.Name, resp.Error.Description)
+		}
+
+		for _, acct := range resp.Data.AccountDetails {
+			for _, s := range acct.Streams {
+				key := fmt.Sprintf("%s > %s", acct.Name, s.Name)
+				assets[key] = append(assets[key], &jsDeepReplica{server: resp.Server.Name, stream: s})
+			}
+		}
+	}
+
+	return assets, nil
+}
+
+// checkJSDeepReplicas compares the replicas of every stream and their consumers, large differences in sequences
+// are allowed up to the replica lag threshold to accommodate streams that are actively being written to
+func (c *SrvCheckCmd) checkJSDeepReplicas(check *monitor.Result, assets map[string][]*jsDeepReplica) error {
+	var streamsCnt, consumersCnt, driftedStreams, driftedConsumers, missingReplicas int
+
+	diff := func(a, b uint64) uint64 {
+		if a > b {
+			return a - b
+		}
+		return b - a
+	}
+
+	var keys []string
+	for k := range assets {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		replicas := assets[key]
+		streamsCnt++
+
+		first := replicas[0]
+		if first.stream.Config != nil && len(replicas) != first.stream.Config.Replicas {
+			missingReplicas++
+			check.Critical("%s: found %d of %d replicas", key, len(replicas), first.stream.Config.Replicas)
+		}
+
+		var problems []string
+		fcfg, _ := json.Marshal(first.stream.Config)
+		for _, r := range replicas[1:] {
+			cfg, _ := json.Marshal(r.stream.Config)
+			if !bytes.Equal(fcfg, cfg) {
+				problems = append(problems, fmt.Sprintf("configuration differs between %s and %s", first.server, r.server))
+			}
+
+			fs, rs := first.stream.State, r.stream.State
+			switch {
+			case diff(fs.LastSeq, rs.LastSeq) > c.jsReplicaLagCritical:
+				problems = append(problems, fmt.Sprintf("last sequence %d on %s and %d on %s", fs.LastSeq, first.server, rs.LastSeq, r.server))
+			case fs.LastSeq == rs.LastSeq && fs.FirstSeq != rs.FirstSeq:
+				problems = append(problems, fmt.Sprintf("first sequence %d on %s and %d on %s", fs.FirstSeq, first.server, rs.FirstSeq, r.server))
+			case fs.LastSeq == rs.LastSeq && fs.Msgs != rs.Msgs:
+				problems = append(problems, fmt.Sprintf("%d messages on %s and %d on %s", fs.Msgs, first.server, rs.Msgs, r.server))
+			}
+		}
+		if len(problems) > 0 {
+			driftedStreams++
+			check.Critical("%s: %s", key, strings.Join(problems, ", "))
+		}
+
+		consumers := map[string][]*server.ConsumerInfo{}
+		servers := map[string][]string{}
+		for _, r := range replicas {
+			for _, cons := range r.stream.Consumer {
+				consumers[cons.Name] = append(consumers[cons.Name], cons)
+				servers[cons.Name] = append(servers[cons.Name], r.server)
+			}
+		}
+
+		var cnames []string
+		for name := range consumers {
+			cnames = append(cnames, name)
+		}
+		sort.Strings(cnames)
+
+		for _, name := range cnames {
+			consumersCnt++
+			infos := consumers[name]
+			first := infos[0]
+
+			var problems []string
+			for i, ci := range infos[1:] {
+				srv := servers[name][i+1]
+				switch {
+				case diff(first.Delivered.Stream, ci.Delivered.Stream) > c.jsReplicaLagCritical:
+					problems = append(problems, fmt.Sprintf("delivered %d on %s and %d on %s", first.Delivered.Stream, servers[name][0], ci.Delivered.Stream, srv))
+				case diff(first.AckFloor.Stream, ci.AckFloor.Stream) > c.jsReplicaLagCritical:
+					problems = append(problems, fmt.Sprintf("ack floor %d on %s and %d on %s", first.AckFloor.Stream, servers[name][0], ci.AckFloor.Stream, srv))
+				}
+			}
+			if len(problems) > 0 {
+				driftedConsumers++
+				check.Critical("%s > %s: %s", key, name, strings.Join(problems, ", "))
+			}
+		}
+	}
+
+	check.Pd(
+		&monitor.PerfDataItem{Name: "streams", Value: float64(streamsCnt), Help: "Streams checked for consistency"},
+		&monitor.PerfDataItem{Name: "consumers", Value: float64(consumersCnt), Help: "Consumers checked for consistency"},
+		&monitor.PerfDataItem{Name: "streams_drifted", Value: float64(driftedStreams), Help: "Streams where replicas do not agree"},
+		&monitor.PerfDataItem{Name: "consumers_drifted", Value: float64(driftedConsumers), Help: "Consumers where replicas do not agree"},
+		&monitor.PerfDataItem{Name: "replicas_missing", Value: float64(missingReplicas), Help: "Streams with fewer replicas than configured"},
+	)
+
+	if driftedStreams+driftedConsumers+missingReplicas == 0 {
+		check.Ok("%d Streams and %d Consumers consistent", streamsCnt, consumersCnt)
+	}
+
+	return nil
+}
+
 func (c *SrvCheckCmd) checkAccountInfo(check *monitor.Result, info *api.JetStreamAccountStats) error {
 	if info == nil {
 		return fmt.Errorf("invalid account status")
@@ -729,7 +1017,7 @@ func (c *SrvCheckCmd) checkClusterInfo(check *monitor.Result, ci *server.Cluster
 }
 
 func (c *SrvCheckCmd) checkStream(_ *fisk.ParseContext) error {
-	check := &monitor.Result{Name: c.sourcesStream, Check: "stream", OutFile: checkRenderOutFile, NameSpace: opts.PrometheusNamespace, RenderFormat: checkRenderFormat}
+	check := &monitor.Result{Name: c.sourcesStream, Check: "stream", OutFile: checkRenderOutFile, PushGateway: checkPushGateway, PushJob: checkPushJob, NameSpace: opts.PrometheusNamespace, RenderFormat: checkRenderFormat}
 	defer check.GenericExit()
 
 	_, mgr, err := prepareHelper("", natsOpts()...)
@@ -916,7 +1204,7 @@ func (c *SrvCheckCmd) checkStreamMessage(mgr *jsm.Manager, check *monitor.Result
 }
 
 func (c *SrvCheckCmd) checkMsg(_ *fisk.ParseContext) error {
-	check := &monitor.Result{Name: "Stream Message", Check: "message", OutFile: checkRenderOutFile, NameSpace: opts.PrometheusNamespace, RenderFormat: checkRenderFormat}
+	check := &monitor.Result{Name: "Stream Message", Check: "message", OutFile: checkRenderOutFile, PushGateway: checkPushGateway, PushJob: checkPushJob, NameSpace: opts.PrometheusNamespace, RenderFormat: checkRenderFormat}
 	defer check.GenericExit()
 
 	_, mgr, err := prepareHelper("", natsOpts()...)
@@ -926,7 +1214,7 @@ func (c *SrvCheckCmd) checkMsg(_ *fisk.ParseContext) error {
 }
 
 func (c *SrvCheckCmd) checkConnection(_ *fisk.ParseContext) error {
-	check := &monitor.Result{Name: "Connection", Check: "connections", OutFile: checkRenderOutFile, NameSpace: opts.PrometheusNamespace, RenderFormat: checkRenderFormat}
+	check := &monitor.Result{Name: "Connection", Check: "connections", OutFile: checkRenderOutFile, PushGateway: checkPushGateway, PushJob: checkPushJob, NameSpace: opts.PrometheusNamespace, RenderFormat: checkRenderFormat}
 	defer check.GenericExit()
 
 	connStart := time.Now()
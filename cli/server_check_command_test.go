@@ -16,6 +16,7 @@ package cli
 import (
 	"fmt"
 	"os"
+	"os/exec"
 	"strconv"
 	"strings"
 	"testing"
@@ -893,3 +894,63 @@ func TestCheckJSZ(t *testing.T) {
 			"1 lagged more than 10 ops")
 	})
 }
+
+func TestCheckJSDeepReplicas(t *testing.T) {
+	cmd := &SrvCheckCmd{jsReplicaLagCritical: 10}
+
+	replica := func(srv string, replicas int, last uint64, msgs uint64, ackFloor uint64) *jsDeepReplica {
+		return &jsDeepReplica{
+			server: srv,
+			stream: server.StreamDetail{
+				Name:   "ORDERS",
+				Config: &server.StreamConfig{Name: "ORDERS", Replicas: replicas},
+				State:  server.StreamState{FirstSeq: 1, LastSeq: last, Msgs: msgs},
+				Consumer: []*server.ConsumerInfo{
+					{Name: "C1", AckFloor: server.SequenceInfo{Stream: ackFloor}, Delivered: server.SequenceInfo{Stream: ackFloor}},
+				},
+			},
+		}
+	}
+
+	t.Run("consistent", func(t *testing.T) {
+		check := &monitor.Result{}
+		assets := map[string][]*jsDeepReplica{
+			"A > ORDERS": {replica("n1", 3, 100, 100, 50), replica("n2", 3, 95, 95, 48), replica("n3", 3, 100, 100, 50)},
+		}
+		assertNoError(t, cmd.checkJSDeepReplicas(check, assets))
+		assertListIsEmpty(t, check.Criticals)
+		assertHasPDItem(t, check, "streams=1", "consumers=1", "streams_drifted=0", "consumers_drifted=0", "replicas_missing=0")
+	})
+
+	t.Run("drifted", func(t *testing.T) {
+		check := &monitor.Result{}
+		assets := map[string][]*jsDeepReplica{
+			"A > ORDERS": {replica("n1", 3, 100, 100, 50), replica("n2", 3, 100, 90, 20)},
+		}
+		assertNoError(t, cmd.checkJSDeepReplicas(check, assets))
+		assertListEquals(t, check.Criticals,
+			"A > ORDERS: found 2 of 3 replicas",
+			"A > ORDERS: 100 messages on n1 and 90 on n2",
+			"A > ORDERS > C1: delivered 50 on n1 and 20 on n2")
+		assertHasPDItem(t, check, "streams_drifted=1", "consumers_drifted=1", "replicas_missing=1")
+	})
+}
+
+func TestCheckExporterOutput(t *testing.T) {
+	for code, completed := range map[int]bool{0: true, 1: true, 2: true, 3: true, 4: false} {
+		out, err := checkExporterOutput(exec.Command("sh", "-c", fmt.Sprintf("echo nats_server_check_status 1; exit %d", code)).Output())
+		if completed {
+			assertNoError(t, err)
+			if !strings.Contains(string(out), "nats_server_check_status") {
+				t.Fatalf("expected metrics for exit code %d got %q", code, out)
+			}
+		} else if err == nil {
+			t.Fatalf("expected exit code %d to fail", code)
+		}
+	}
+
+	_, err := checkExporterOutput(exec.Command("sh", "-c", "exit 2").Output())
+	if err == nil {
+		t.Fatalf("expected a check without output to fail")
+	}
+}
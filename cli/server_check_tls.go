@@ -0,0 +1,434 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"bufio"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/choria-io/fisk"
+	"github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/natscli/monitor"
+	"golang.org/x/crypto/ocsp"
+)
+
+// tlsEndpoint is a listener of a server that should be checked
+type tlsEndpoint struct {
+	Server   string
+	Listener string
+	Address  string
+	// Direct listeners start TLS immediately while NATS protocol listeners send INFO first
+	Direct bool
+}
+
+type tlsProbeResult struct {
+	State     tls.ConnectionState
+	VerifyErr error
+	HandErr   error
+	Plain     bool
+}
+
+func (c *SrvCheckCmd) configureCheckTLS(check *fisk.CmdClause) {
+	tc := check.Command("tls", "Checks the TLS certificates of all client, route, gateway, leafnode and websocket listeners").Action(c.checkTLS)
+	tc.Flag("expire-warn", "Warning threshold for certificate expiry").Default("21d").PlaceHolder("DURATION").DurationVar(&c.tlsExpireWarn)
+	tc.Flag("expire-critical", "Critical threshold for certificate expiry").Default("7d").PlaceHolder("DURATION").DurationVar(&c.tlsExpireCrit)
+	tc.Flag("address", "Checks a specific host:port, wss:// and https:// addresses start TLS immediately (pass multiple times)").PlaceHolder("ADDRESS").StringsVar(&c.tlsAddresses)
+	tc.Flag("discover", "Discover the listeners of all servers, requires system access").Default("true").BoolVar(&c.tlsDiscover)
+	tc.Flag("require", "Critical when a listener does not offer TLS").UnNegatableBoolVar(&c.tlsRequire)
+}
+
+func tlsListenerAddress(host string, port int, fallback string) string {
+	if port <= 0 {
+		return ""
+	}
+
+	if host == "" || host == "0.0.0.0" || host == "::" || host == "[::]" {
+		if fallback == "" {
+			return ""
+		}
+		host = fallback
+	}
+
+	return net.JoinHostPort(strings.Trim(host, "[]"), strconv.Itoa(port))
+}
+
+// discoverTLSEndpoints finds the listeners of every server, listeners bound to all interfaces are only
+// reachable via the host we are connected to so those are only checked for the connected server
+func (c *SrvCheckCmd) discoverTLSEndpoints(nc *nats.Conn) ([]*tlsEndpoint, []string, error) {
+	res, err := doReq(server.VarzEventOptions{}, "$SYS.REQ.SERVER.PING.VARZ", 0, nc)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(res) == 0 {
+		return nil, nil, fmt.Errorf("no VARZ responses received, ensure the account used has system privileges")
+	}
+
+	connectedHost := ""
+	if u, err := url.Parse(nc.ConnectedUrl()); err == nil {
+		connectedHost = u.Hostname()
+	}
+
+	var endpoints []*tlsEndpoint
+	var skipped []string
+
+	for _, r := range res {
+		resp := struct {
+			Data  server.Varz      `json:"data"`
+			Error *server.ApiError `json:"error"`
+		}{}
+
+		err = json.Unmarshal(r, &resp)
+		if err != nil {
+			return nil, nil, err
+		}
+		if resp.Error != nil {
+			return nil, nil, fmt.Errorf("varz request failed: %s", resp.Error.Description)
+		}
+
+		vz := resp.Data
+		fallback := ""
+		if vz.ID == nc.ConnectedServerId() {
+			fallback = connectedHost
+		}
+
+		add := func(listener string, host string, port int, direct bool) {
+			if port <= 0 {
+				return
+			}
+
+			addr := tlsListenerAddress(host, port, fallback)
+			if addr == "" {
+				skipped = append(skipped, fmt.Sprintf("%s %s listener on %s:%d", vz.Name, listener, host, port))
+				return
+			}
+
+			endpoints = append(endpoints, &tlsEndpoint{Server: vz.Name, Listener: listener, Address: addr, Direct: direct})
+		}
+
+		add("client", vz.Host, vz.Port, false)
+		add("route", vz.Cluster.Host, vz.Cluster.Port, false)
+		add("gateway", vz.Gateway.Host, vz.Gateway.Port, false)
+		add("leafnode", vz.LeafNode.Host, vz.LeafNode.Port, false)
+		if !vz.Websocket.NoTLS {
+			add("websocket", vz.Websocket.Host, vz.Websocket.Port, true)
+		}
+	}
+
+	sort.Slice(endpoints, func(i, j int) bool {
+		if endpoints[i].Server == endpoints[j].Server {
+			return endpoints[i].Listener < endpoints[j].Listener
+		}
+		return endpoints[i].Server < endpoints[j].Server
+	})
+
+	return endpoints, skipped, nil
+}
+
+func (c *SrvCheckCmd) tlsClientConfig(host string, result *tlsProbeResult) (*tls.Config, error) {
+	tlsc := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		ServerName: host,
+		// verification is done after the handshake so that details are available for invalid certificates
+		InsecureSkipVerify: true,
+		VerifyConnection: func(cs tls.ConnectionState) error {
+			result.State = cs
+			return nil
+		},
+	}
+
+	if opts.Config != nil && opts.Config.Certificate() != "" && opts.Config.Key() != "" {
+		cert, err := tls.LoadX509KeyPair(opts.Config.Certificate(), opts.Config.Key())
+		if err != nil {
+			return nil, fmt.Errorf("could not load client certificate: %w", err)
+		}
+		tlsc.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsc, nil
+}
+
+func tlsRootCAs() (*x509.CertPool, error) {
+	if opts.Config == nil || opts.Config.CA() == "" {
+		return x509.SystemCertPool()
+	}
+
+	pem, err := os.ReadFile(opts.Config.CA())
+	if err != nil {
+		return nil, err
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no certificates found in %s", opts.Config.CA())
+	}
+
+	return pool, nil
+}
+
+func (c *SrvCheckCmd) probeTLS(ep *tlsEndpoint) (*tlsProbeResult, error) {
+	result := &tlsProbeResult{}
+
+	host, _, err := net.SplitHostPort(ep.Address)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := dialDirectOrProxy(ep.Address, opts.Timeout)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(opts.Timeout))
+
+	if !ep.Direct {
+		line, err := bufio.NewReader(conn).ReadString('\n')
+		if err != nil {
+			return nil, fmt.Errorf("did not receive INFO: %w", err)
+		}
+		if !strings.HasPrefix(line, "INFO ") {
+			return nil, fmt.Errorf("unexpected protocol line: %s", strings.TrimSpace(line))
+		}
+
+		info := server.Info{}
+		err = json.Unmarshal([]byte(strings.TrimPrefix(strings.TrimSpace(line), "INFO ")), &info)
+		if err != nil {
+			return nil, fmt.Errorf("invalid INFO: %w", err)
+		}
+
+		if !info.TLSRequired && !info.TLSAvailable {
+			result.Plain = true
+			return result, nil
+		}
+	}
+
+	tlsc, err := c.tlsClientConfig(host, result)
+	if err != nil {
+		return nil, err
+	}
+
+	tconn := tls.Client(conn, tlsc)
+	result.HandErr = tconn.Handshake()
+	if result.HandErr == nil {
+		result.State = tconn.ConnectionState()
+	}
+
+	if len(result.State.PeerCertificates) == 0 {
+		if result.HandErr != nil {
+			return nil, result.HandErr
+		}
+		return nil, fmt.Errorf("no certificates presented")
+	}
+
+	roots, err := tlsRootCAs()
+	if err != nil {
+		return nil, err
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, cert := range result.State.PeerCertificates[1:] {
+		intermediates.AddCert(cert)
+	}
+
+	_, result.VerifyErr = result.State.PeerCertificates[0].Verify(x509.VerifyOptions{
+		DNSName:       host,
+		Roots:         roots,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	})
+
+	return result, nil
+}
+
+func tlsVersionName(v uint16) string {
+	switch v {
+	case tls.VersionTLS10:
+		return "TLS 1.0"
+	case tls.VersionTLS11:
+		return "TLS 1.1"
+	case tls.VersionTLS12:
+		return "TLS 1.2"
+	case tls.VersionTLS13:
+		return "TLS 1.3"
+	default:
+		return fmt.Sprintf("TLS 0x%04x", v)
+	}
+}
+
+func tlsOCSPStatus(state tls.ConnectionState) string {
+	if len(state.OCSPResponse) == 0 {
+		return "not stapled"
+	}
+
+	var issuer *x509.Certificate
+	if len(state.PeerCertificates) > 1 {
+		issuer = state.PeerCertificates[1]
+	}
+
+	resp, err := ocsp.ParseResponse(state.OCSPResponse, issuer)
+	if err != nil {
+		return fmt.Sprintf("invalid: %v", err)
+	}
+
+	switch resp.Status {
+	case ocsp.Good:
+		return "good"
+	case ocsp.Revoked:
+		return "revoked"
+	default:
+		return "unknown"
+	}
+}
+
+func tlsCertNames(cert *x509.Certificate) string {
+	var sans []string
+	sans = append(sans, cert.DNSNames...)
+	for _, ip := range cert.IPAddresses {
+		sans = append(sans, ip.String())
+	}
+	for _, u := range cert.URIs {
+		sans = append(sans, u.String())
+	}
+	sans = append(sans, cert.EmailAddresses...)
+
+	return strings.Join(sans, ", ")
+}
+
+func (c *SrvCheckCmd) checkTLSEndpoint(check *monitor.Result, ep *tlsEndpoint) *time.Time {
+	name := fmt.Sprintf("%s %s %s", ep.Server, ep.Listener, ep.Address)
+
+	result, err := c.probeTLS(ep)
+	if err != nil {
+		check.Critical("%s: %v", name, err)
+		return nil
+	}
+
+	if result.Plain {
+		if c.tlsRequire {
+			check.Critical("%s: TLS not enabled", name)
+		} else {
+			check.Ok("%s: TLS not enabled", name)
+		}
+		return nil
+	}
+
+	leaf := result.State.PeerCertificates[0]
+	var chain []string
+	earliest := leaf.NotAfter
+	for _, cert := range result.State.PeerCertificates {
+		chain = append(chain, cert.Subject.CommonName)
+		if cert.NotAfter.Before(earliest) {
+			earliest = cert.NotAfter
+		}
+	}
+
+	until := time.Until(earliest)
+	switch {
+	case until <= 0:
+		check.Critical("%s: certificate expired %s ago", name, humanizeDuration(-until))
+	case until <= c.tlsExpireCrit:
+		check.Critical("%s: certificate expires in %s", name, humanizeDuration(until))
+	case until <= c.tlsExpireWarn:
+		check.Warn("%s: certificate expires in %s", name, humanizeDuration(until))
+	}
+
+	if result.VerifyErr != nil {
+		check.Warn("%s: certificate verification failed: %v", name, result.VerifyErr)
+	}
+
+	if result.HandErr != nil {
+		check.Warn("%s: handshake failed: %v", name, result.HandErr)
+	}
+
+	status := tlsOCSPStatus(result.State)
+	if status == "revoked" {
+		check.Critical("%s: certificate revoked according to stapled OCSP response", name)
+	}
+
+	check.Ok("%s: %s %s chain %s SANs %s expires %s OCSP %s", name,
+		tlsVersionName(result.State.Version),
+		tls.CipherSuiteName(result.State.CipherSuite),
+		strings.Join(chain, " > "),
+		tlsCertNames(leaf),
+		earliest.Format(time.RFC3339),
+		status)
+
+	return &earliest
+}
+
+func (c *SrvCheckCmd) checkTLS(_ *fisk.ParseContext) error {
+	check := &monitor.Result{Name: "TLS", Check: "tls", OutFile: checkRenderOutFile, PushGateway: checkPushGateway, PushJob: checkPushJob, NameSpace: opts.PrometheusNamespace, RenderFormat: checkRenderFormat}
+	defer check.GenericExit()
+
+	var endpoints []*tlsEndpoint
+
+	for _, addr := range c.tlsAddresses {
+		ep := &tlsEndpoint{Server: "custom", Listener: "address", Address: addr}
+		if u, err := url.Parse(addr); err == nil && u.Host != "" {
+			ep.Address = u.Host
+			ep.Direct = u.Scheme == "wss" || u.Scheme == "https"
+			if u.Port() == "" {
+				ep.Address = net.JoinHostPort(u.Hostname(), "443")
+			}
+		}
+		endpoints = append(endpoints, ep)
+	}
+
+	if c.tlsDiscover {
+		nc, _, err := prepareHelper("", natsOpts()...)
+		if check.CriticalIfErr(err, "connection failed: %v", err) {
+			return nil
+		}
+
+		discovered, skipped, err := c.discoverTLSEndpoints(nc)
+		if check.CriticalIfErr(err, "discovery failed: %v", err) {
+			return nil
+		}
+
+		for _, s := range skipped {
+			check.Warn("%s: cannot determine address, check it using --address", s)
+		}
+
+		endpoints = append(endpoints, discovered...)
+	}
+
+	if len(endpoints) == 0 {
+		check.Critical("no listeners to check")
+		return nil
+	}
+
+	var earliest *time.Time
+	for _, ep := range endpoints {
+		expires := c.checkTLSEndpoint(check, ep)
+		if expires != nil && (earliest == nil || expires.Before(*earliest)) {
+			earliest = expires
+		}
+	}
+
+	if earliest != nil {
+		check.Pd(&monitor.PerfDataItem{Name: "tls_expiry", Value: time.Until(*earliest).Seconds(), Warn: c.tlsExpireWarn.Seconds(), Crit: c.tlsExpireCrit.Seconds(), Unit: "s", Help: "Time till the first certificate expires"})
+	}
+
+	return nil
+}
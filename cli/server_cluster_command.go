@@ -17,12 +17,17 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/choria-io/fisk"
+	"github.com/dustin/go-humanize"
+	"github.com/fatih/color"
 	"github.com/nats-io/jsm.go/api"
 	"github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats.go"
 )
 
 type SrvClusterCmd struct {
@@ -30,6 +35,64 @@ type SrvClusterCmd struct {
 	force            bool
 	peer             string
 	placementCluster string
+	lagThreshold     uint64
+	waitFor          int
+	dryRun           bool
+}
+
+type raftAuditProblem struct {
+	Severity string `json:"severity"`
+	Account  string `json:"account,omitempty"`
+	Group    string `json:"group"`
+	Problem  string `json:"problem"`
+}
+
+type raftAuditReport struct {
+	server string
+	stream server.StreamDetail
+}
+
+// srvClusterMetaInfo is the meta group state seen by a server, pending proposals are only reported by newer servers
+type srvClusterMetaInfo struct {
+	server.MetaClusterInfo
+	Pending *int `json:"pending,omitempty"`
+}
+
+type srvClusterJSZResponse struct {
+	Data struct {
+		server.JSInfo
+		Meta *srvClusterMetaInfo `json:"meta_cluster,omitempty"`
+	} `json:"data"`
+	Server server.ServerInfo `json:"server"`
+}
+
+type metaReportPeer struct {
+	Name    string        `json:"name"`
+	ID      string        `json:"id"`
+	Leader  bool          `json:"leader"`
+	Current bool          `json:"current"`
+	Offline bool          `json:"offline"`
+	Active  time.Duration `json:"active"`
+	Lag     uint64        `json:"lag"`
+}
+
+type metaReportServer struct {
+	Name      string `json:"name"`
+	Cluster   string `json:"cluster"`
+	Leader    string `json:"leader"`
+	Pending   *int   `json:"pending,omitempty"`
+	Streams   int    `json:"streams"`
+	Consumers int    `json:"consumers"`
+	HAAssets  int    `json:"ha_assets"`
+}
+
+type metaReport struct {
+	Leader  string              `json:"leader"`
+	Size    int                 `json:"cluster_size"`
+	Online  int                 `json:"online"`
+	Quorum  bool                `json:"quorum"`
+	Peers   []*metaReportPeer   `json:"peers"`
+	Servers []*metaReportServer `json:"servers"`
 }
 
 func configureServerClusterCommand(srv *fisk.CmdClause) {
@@ -38,12 +101,213 @@ func configureServerClusterCommand(srv *fisk.CmdClause) {
 	raft := srv.Command("cluster", "Manage JetStream Clustering").Alias("r").Alias("raft")
 	raft.Flag("json", "Produce JSON output").Short('j').UnNegatableBoolVar(&c.json)
 
-	sd := raft.Command("step-down", "Force a new leader election by standing down the current meta leader").Alias("stepdown").Alias("sd").Alias("elect").Alias("down").Alias("d").Action(c.metaLeaderStandDown)
+	sd := mutating(raft.Command("step-down", "Force a new leader election by standing down the current meta leader").Alias("stepdown").Alias("sd").Alias("elect").Alias("down").Alias("d").Action(c.metaLeaderStandDown))
 	sd.Flag("cluster", "Request placement of the leader in a specific cluster").StringVar(&c.placementCluster)
 
-	rm := raft.Command("peer-remove", "Removes a server from a JetStream cluster").Alias("rm").Alias("pr").Action(c.metaPeerRemove)
+	rm := mutating(raft.Command("peer-remove", "Removes a server from a JetStream cluster").Alias("rm").Alias("pr").Action(c.metaPeerRemove))
 	rm.Arg("name", "The Server Name or ID to remove from the JetStream cluster").Required().StringVar(&c.peer)
 	rm.Flag("force", "Force removal without prompting").Short('f').UnNegatableBoolVar(&c.force)
+	rm.Flag("dry-run", "Only shows the impact of removing the peer").UnNegatableBoolVar(&c.dryRun)
+
+	meta := raft.Command("meta-report", "Reports on the JetStream meta group peers, leader, lag and pending proposals").Alias("meta").Action(c.metaReport)
+	meta.Arg("expect", "Number of servers to expect responses from").IntVar(&c.waitFor)
+
+	audit := raft.Command("audit", "Audits RAFT groups across all servers for leaderless, lagging and orphaned groups").Action(c.raftAudit)
+	audit.Arg("expect", "Number of servers to expect responses from").IntVar(&c.waitFor)
+	audit.Flag("lag", "Report replicas that are this many operations behind their leader").Default("1000").Uint64Var(&c.lagThreshold)
+}
+
+func (c *SrvClusterCmd) raftAudit(_ *fisk.ParseContext) error {
+	nc, _, err := prepareHelper("", natsOpts()...)
+	if err != nil {
+		return err
+	}
+
+	req := &server.JszEventOptions{
+		JSzOptions: server.JSzOptions{Accounts: true, Streams: true, Consumer: true, Config: true, RaftGroups: true, Limit: 10000},
+	}
+	res, err := doReq(req, "$SYS.REQ.SERVER.PING.JSZ", c.waitFor, nc)
+	if err != nil {
+		return err
+	}
+	if len(res) == 0 {
+		return fmt.Errorf("no results received, ensure the account used has system privileges and appropriate permissions")
+	}
+
+	type jszr struct {
+		Data   server.JSInfo     `json:"data"`
+		Server server.ServerInfo `json:"server"`
+	}
+
+	var (
+		problems []*raftAuditProblem
+		meta     *server.MetaClusterInfo
+		metaSize int
+		servers  = map[string]bool{}
+		streams  = map[string][]*raftAuditReport{}
+	)
+
+	problem := func(severity string, account string, group string, format string, a ...any) {
+		problems = append(problems, &raftAuditProblem{Severity: severity, Account: account, Group: group, Problem: fmt.Sprintf(format, a...)})
+	}
+
+	for _, r := range res {
+		resp := &jszr{}
+		err = json.Unmarshal(r, resp)
+		if err != nil {
+			return err
+		}
+
+		servers[resp.Server.Name] = true
+
+		if resp.Data.Meta != nil {
+			if resp.Data.Meta.Size > metaSize {
+				metaSize = resp.Data.Meta.Size
+			}
+			if resp.Data.Meta.Leader == resp.Server.Name {
+				meta = resp.Data.Meta
+			}
+		}
+
+		for _, acct := range resp.Data.AccountDetails {
+			for _, sd := range acct.Streams {
+				key := acct.Name + " > " + sd.Name
+				streams[key] = append(streams[key], &raftAuditReport{server: resp.Server.Name, stream: sd})
+			}
+		}
+	}
+
+	switch {
+	case meta == nil:
+		problem("critical", "", "_meta_", "no meta leader found, %d of %d servers responded", len(servers), metaSize)
+	default:
+		for _, p := range meta.Replicas {
+			switch {
+			case p.Offline:
+				problem("critical", "", "_meta_", "peer %s is offline", p.Name)
+			case !p.Current:
+				problem("warning", "", "_meta_", "peer %s is not current", p.Name)
+			}
+			if p.Lag > c.lagThreshold {
+				problem("warning", "", "_meta_", "peer %s is %s operations behind", p.Name, humanize.Comma(int64(p.Lag)))
+			}
+		}
+	}
+
+	auditGroup := func(account string, group string, ci *server.ClusterInfo) {
+		if ci == nil {
+			return
+		}
+		if ci.Leader == "" {
+			problem("critical", account, group, "has no leader")
+			return
+		}
+		for _, p := range ci.Replicas {
+			switch {
+			case p.Offline:
+				problem("critical", account, group, "replica %s is offline", p.Name)
+			case !p.Current:
+				problem("warning", account, group, "replica %s is not current", p.Name)
+			}
+			if p.Lag > c.lagThreshold {
+				problem("warning", account, group, "replica %s is %s operations behind", p.Name, humanize.Comma(int64(p.Lag)))
+			}
+		}
+	}
+
+	for key, reports := range streams {
+		parts := strings.SplitN(key, " > ", 2)
+		account, stream := parts[0], parts[1]
+
+		var leader *raftAuditReport
+		leaders := map[string]bool{}
+		for _, r := range reports {
+			if r.stream.Cluster == nil {
+				continue
+			}
+			leaders[r.stream.Cluster.Leader] = true
+			if r.stream.Cluster.Leader == r.server {
+				leader = r
+			}
+		}
+
+		if len(leaders) > 1 {
+			var names []string
+			for l := range leaders {
+				if l == "" {
+					l = "none"
+				}
+				names = append(names, l)
+			}
+			sort.Strings(names)
+			problem("critical", account, stream, "servers disagree on the leader: %s", strings.Join(names, ", "))
+		}
+
+		if leader == nil {
+			if reports[0].stream.Cluster != nil && reports[0].stream.Config != nil && reports[0].stream.Config.Replicas > 1 {
+				problem("critical", account, stream, "has no leader")
+			}
+			continue
+		}
+
+		auditGroup(account, stream, leader.stream.Cluster)
+
+		expected := map[string]bool{leader.server: true}
+		for _, p := range leader.stream.Cluster.Replicas {
+			expected[p.Name] = true
+		}
+
+		if leader.stream.Config != nil && len(expected) != leader.stream.Config.Replicas {
+			problem("warning", account, stream, "has %d peers but is configured for %d replicas", len(expected), leader.stream.Config.Replicas)
+		}
+
+		for _, r := range reports {
+			if !expected[r.server] {
+				problem("warning", account, stream, "orphaned raft group %s found on %s which is not a peer", r.stream.RaftGroup, r.server)
+			}
+		}
+
+		for _, cons := range leader.stream.Consumer {
+			if cons.Cluster == nil {
+				continue
+			}
+
+			group := stream + " > " + cons.Name
+			auditGroup(account, group, cons.Cluster)
+
+			if cons.Cluster.Leader != "" && !expected[cons.Cluster.Leader] {
+				problem("warning", account, group, "leader %s is not a peer of the stream", cons.Cluster.Leader)
+			}
+		}
+	}
+
+	sort.SliceStable(problems, func(i, j int) bool {
+		if problems[i].Severity != problems[j].Severity {
+			return problems[i].Severity == "critical"
+		}
+		if problems[i].Account != problems[j].Account {
+			return problems[i].Account < problems[j].Account
+		}
+		return problems[i].Group < problems[j].Group
+	})
+
+	if c.json {
+		return printJSON(problems)
+	}
+
+	if len(problems) == 0 {
+		fmt.Printf("No problems found in %s RAFT groups across %d servers\n", humanize.Comma(int64(len(streams))), len(servers))
+		return nil
+	}
+
+	table := newTableWriter(fmt.Sprintf("RAFT audit found %d problems across %d servers", len(problems), len(servers)))
+	table.AddHeaders("Severity", "Account", "Group", "Problem")
+	for _, p := range problems {
+		table.AddRow(p.Severity, p.Account, p.Group, p.Problem)
+	}
+	fmt.Print(table.Render())
+
+	return nil
 }
 
 func (c *SrvClusterCmd) metaPeerRemove(_ *fisk.ParseContext) error {
@@ -91,6 +355,44 @@ func (c *SrvClusterCmd) metaPeerRemove(_ *fisk.ParseContext) error {
 		return fmt.Errorf("did not find a replica named %s", c.peer)
 	}
 
+	online := 1
+	for _, r := range srv.Data.Meta.Replicas {
+		if !r.Offline {
+			online++
+		}
+	}
+	size := len(srv.Data.Meta.Replicas)
+	quorum := size/2 + 1
+
+	streams, consumers, err := c.peerAssets(nc, foundName)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Removing peer %s (%s) from the %d peer meta group led by %s\n\n", foundName, foundID, size+1, srv.Data.Meta.Leader)
+	fmt.Printf("   Online after removal: %d of %d peers, %d required for quorum\n", online, size, quorum)
+	fmt.Printf("       Affected Streams: %s\n", humanize.Comma(int64(len(streams))))
+	fmt.Printf("     Affected Consumers: %s\n", humanize.Comma(int64(consumers)))
+	fmt.Println()
+
+	if len(streams) > 0 {
+		table := newTableWriter(fmt.Sprintf("Streams with replicas on %s", foundName))
+		table.AddHeaders("Stream", "Replicas", "Leader")
+		for _, s := range streams {
+			table.AddRow(s[0], s[1], s[2])
+		}
+		fmt.Print(table.Render())
+		fmt.Println()
+	}
+
+	if online < quorum {
+		return fmt.Errorf("the meta group would not have quorum after removing %s", foundName)
+	}
+
+	if c.dryRun {
+		return nil
+	}
+
 	if !c.force {
 		fmt.Printf("Removing %s can not be reversed, data on this node will be inaccessible.\n\n", c.peer)
 
@@ -117,6 +419,190 @@ func (c *SrvClusterCmd) metaPeerRemove(_ *fisk.ParseContext) error {
 	return nil
 }
 
+// peerAssets finds the streams with replicas on a server and the number of consumers they hold
+func (c *SrvClusterCmd) peerAssets(nc *nats.Conn, name string) ([][3]string, int, error) {
+	req := &server.JszEventOptions{JSzOptions: server.JSzOptions{Accounts: true, Streams: true, Consumer: true, Config: true, Limit: 10000}}
+	res, err := doReq(req, "$SYS.REQ.SERVER.PING.JSZ", c.waitFor, nc)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	seen := map[string]bool{}
+	var streams [][3]string
+	consumers := 0
+
+	for _, r := range res {
+		resp := &srvClusterJSZResponse{}
+		err = json.Unmarshal(r, resp)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		for _, acct := range resp.Data.AccountDetails {
+			for _, sd := range acct.Streams {
+				key := acct.Name + " > " + sd.Name
+				if seen[key] || sd.Cluster == nil {
+					continue
+				}
+
+				onPeer := sd.Cluster.Leader == name
+				for _, p := range sd.Cluster.Replicas {
+					if p.Name == name {
+						onPeer = true
+					}
+				}
+				if !onPeer {
+					continue
+				}
+
+				seen[key] = true
+				replicas := len(sd.Cluster.Replicas) + 1
+				if sd.Config != nil {
+					replicas = sd.Config.Replicas
+				}
+				streams = append(streams, [3]string{key, strconv.Itoa(replicas), sd.Cluster.Leader})
+				consumers += len(sd.Consumer)
+			}
+		}
+	}
+
+	sort.Slice(streams, func(i, j int) bool { return streams[i][0] < streams[j][0] })
+
+	return streams, consumers, nil
+}
+
+func (c *SrvClusterCmd) metaReport(_ *fisk.ParseContext) error {
+	nc, _, err := prepareHelper("", natsOpts()...)
+	if err != nil {
+		return err
+	}
+
+	res, err := doReq(&server.JszEventOptions{}, "$SYS.REQ.SERVER.PING.JSZ", c.waitFor, nc)
+	if err != nil {
+		return err
+	}
+	if len(res) == 0 {
+		return fmt.Errorf("no results received, ensure the account used has system privileges and appropriate permissions")
+	}
+
+	report := &metaReport{}
+	var leader *srvClusterJSZResponse
+
+	for _, r := range res {
+		resp := &srvClusterJSZResponse{}
+		err = json.Unmarshal(r, resp)
+		if err != nil {
+			return err
+		}
+
+		if resp.Data.Meta == nil {
+			continue
+		}
+
+		if resp.Data.Meta.Leader == resp.Server.Name {
+			leader = resp
+		}
+
+		report.Servers = append(report.Servers, &metaReportServer{
+			Name:      resp.Server.Name,
+			Cluster:   resp.Server.Cluster,
+			Leader:    resp.Data.Meta.Leader,
+			Pending:   resp.Data.Meta.Pending,
+			Streams:   resp.Data.Streams,
+			Consumers: resp.Data.Consumers,
+			HAAssets:  resp.Data.HAAssets,
+		})
+	}
+
+	if len(report.Servers) == 0 {
+		return fmt.Errorf("no clustered JetStream servers responded")
+	}
+
+	sort.Slice(report.Servers, func(i, j int) bool { return report.Servers[i].Name < report.Servers[j].Name })
+
+	if leader != nil {
+		report.Leader = leader.Server.Name
+		report.Size = len(leader.Data.Meta.Replicas) + 1
+		report.Online = 1
+		report.Peers = append(report.Peers, &metaReportPeer{Name: leader.Server.Name, ID: leader.Data.Meta.Peer, Leader: true, Current: true})
+
+		for _, p := range leader.Data.Meta.Replicas {
+			if !p.Offline {
+				report.Online++
+			}
+			report.Peers = append(report.Peers, &metaReportPeer{Name: p.Name, ID: p.Peer, Current: p.Current, Offline: p.Offline, Active: p.Active, Lag: p.Lag})
+		}
+
+		report.Quorum = report.Online >= report.Size/2+1
+		sort.Slice(report.Peers[1:], func(i, j int) bool { return report.Peers[i+1].Name < report.Peers[j+1].Name })
+	}
+
+	if c.json {
+		return printJSON(report)
+	}
+
+	if leader == nil {
+		fmt.Printf("No meta leader found, %d servers responded\n\n", len(report.Servers))
+	} else {
+		quorum := color.GreenString("ok")
+		if !report.Quorum {
+			quorum = color.RedString("lost")
+		}
+
+		fmt.Printf("JetStream Meta Group led by %s\n\n", report.Leader)
+		fmt.Printf("          Peers: %d\n", report.Size)
+		fmt.Printf("   Online Peers: %d\n", report.Online)
+		fmt.Printf("         Quorum: %s, %d peers required\n", quorum, report.Size/2+1)
+		fmt.Println()
+
+		table := newTableWriter("Meta Group Peers")
+		table.AddHeaders("Name", "ID", "Leader", "Current", "Online", "Active", "Lag")
+		for _, p := range report.Peers {
+			isLeader := ""
+			if p.Leader {
+				isLeader = "yes"
+			}
+			current := "true"
+			if !p.Current {
+				current = color.YellowString("false")
+			}
+			online := "true"
+			if p.Offline {
+				online = color.RedString("false")
+			}
+			active := ""
+			if !p.Leader {
+				active = humanizeDuration(p.Active)
+			}
+			table.AddRow(p.Name, p.ID, isLeader, current, online, active, humanize.Comma(int64(p.Lag)))
+		}
+		fmt.Print(table.Render())
+		fmt.Println()
+	}
+
+	table := newTableWriter("Meta Group State by Server")
+	table.AddHeaders("Server", "Cluster", "Sees Leader", "Pending Proposals", "Streams", "Consumers", "HA Assets")
+	for _, s := range report.Servers {
+		seen := s.Leader
+		switch {
+		case seen == "":
+			seen = color.RedString("none")
+		case seen != report.Leader:
+			seen = color.RedString(seen)
+		}
+
+		pending := "unknown"
+		if s.Pending != nil {
+			pending = humanize.Comma(int64(*s.Pending))
+		}
+
+		table.AddRow(s.Name, s.Cluster, seen, pending, humanize.Comma(int64(s.Streams)), humanize.Comma(int64(s.Consumers)), humanize.Comma(int64(s.HAAssets)))
+	}
+	fmt.Print(table.Render())
+
+	return nil
+}
+
 func (c *SrvClusterCmd) metaLeaderStandDown(_ *fisk.ParseContext) error {
 	nc, mgr, err := prepareHelper("", natsOpts()...)
 	if err != nil {
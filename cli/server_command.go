@@ -18,8 +18,12 @@ func configureServerCommand(app commandHost) {
 	addCheat("server", srv)
 
 	configureServerAccountCommand(srv)
+	configureServerBackupCommand(srv)
 	configureServerCheckCommand(srv)
 	configureServerClusterCommand(srv)
+	configureServerConnectionCommand(srv)
+	configureServerDrainCommand(srv)
+	configureServerHealthCommand(srv)
 	configureServerInfoCommand(srv)
 	configureServerListCommand(srv)
 	configureServerMappingCommand(srv)
@@ -28,6 +32,7 @@ func configureServerCommand(app commandHost) {
 	configureServerReportCommand(srv)
 	configureServerRequestCommand(srv)
 	configureServerRunCommand(srv)
+	configureServerWatchCommand(srv)
 }
 
 func init() {
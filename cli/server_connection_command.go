@@ -0,0 +1,402 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/choria-io/fisk"
+	"github.com/dustin/go-humanize"
+	"github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats.go"
+)
+
+type SrvConnectionCmd struct {
+	cid     uint64
+	host    string
+	name    string
+	ip      string
+	account string
+	user    string
+	ldm     bool
+	force   bool
+	json    bool
+	waitFor int
+}
+
+// connectionKickRequest is the body of the KICK and LDM system requests
+type connectionKickRequest struct {
+	CID uint64 `json:"cid"`
+}
+
+func configureServerConnectionCommand(srv *fisk.CmdClause) {
+	c := &SrvConnectionCmd{}
+
+	conn := srv.Command("connection", "Inspect and disconnect client connections").Alias("conn").Alias("client")
+
+	addSearchFlags := func(cmd *fisk.CmdClause) {
+		cmd.Flag("host", "Limit the search to a specific server").StringVar(&c.host)
+		cmd.Flag("name", "Finds connections with client names matching a pattern").PlaceHolder("PATTERN").StringVar(&c.name)
+		cmd.Flag("ip", "Finds connections from a specific IP address").StringVar(&c.ip)
+		cmd.Flag("account", "Finds connections in a specific account").StringVar(&c.account)
+		cmd.Flag("conn-user", "Finds connections by a specific user").StringVar(&c.user)
+		cmd.Flag("expect", "Number of servers to expect responses from").IntVar(&c.waitFor)
+	}
+
+	find := conn.Command("find", "Finds connections by client name, IP, account or user").Alias("search").Alias("ls").Action(c.findAction)
+	addSearchFlags(find)
+	addJSONOutputFlag(find, &c.json)
+
+	info := conn.Command("info", "Shows detailed information about a connection").Alias("show").Action(c.infoAction)
+	info.Arg("cid", "The connection ID to inspect").Uint64Var(&c.cid)
+	addSearchFlags(info)
+	addJSONOutputFlag(info, &c.json)
+
+	kick := mutating(conn.Command("kick", "Disconnects connections, clients usually reconnect immediately").Action(c.kickAction))
+	kick.Arg("cid", "The connection ID to disconnect, requires --host when the ID is in use on several servers").Uint64Var(&c.cid)
+	addSearchFlags(kick)
+	kick.Flag("ldm", "Sends clients a Lame Duck Mode notification asking them to reconnect elsewhere rather than disconnecting them").UnNegatableBoolVar(&c.ldm)
+	kick.Flag("force", "Disconnect without prompting").Short('f').UnNegatableBoolVar(&c.force)
+}
+
+func (c *SrvConnectionCmd) searching() bool {
+	return c.cid > 0 || c.name != "" || c.ip != "" || c.account != "" || c.user != ""
+}
+
+// findConnections searches all servers for connections matching the search criteria
+func (c *SrvConnectionCmd) findConnections(nc *nats.Conn) ([]connInfo, error) {
+	if !c.searching() {
+		return nil, withExitCode(ExitValidation, fmt.Errorf("a connection ID, --name, --ip, --account or --user is required"))
+	}
+
+	if c.name != "" {
+		_, err := path.Match(c.name, "")
+		if err != nil {
+			return nil, withExitCode(ExitValidation, fmt.Errorf("invalid name pattern %q: %w", c.name, err))
+		}
+	}
+
+	var found []connInfo
+	offset := 0
+	limit := 1024
+
+	for {
+		req := &server.ConnzEventOptions{
+			ConnzOptions: server.ConnzOptions{
+				Subscriptions: true,
+				Username:      true,
+				CID:           c.cid,
+				Account:       c.account,
+				User:          c.user,
+				Offset:        offset,
+				Limit:         limit,
+			},
+			EventFilterOptions: server.EventFilterOptions{Name: c.host},
+		}
+
+		res, err := doReq(req, "$SYS.REQ.SERVER.PING.CONNZ", c.waitFor, nc)
+		if errors.Is(err, nats.ErrNoResponders) {
+			return nil, fmt.Errorf("server request failed, ensure the account used has system privileges and appropriate permissions")
+		} else if err != nil {
+			return nil, err
+		}
+
+		// servers page independently, those without more pages return empty pages for later offsets
+		more := false
+		for _, r := range res {
+			co, err := parseConnzResp(r)
+			if err != nil {
+				return nil, err
+			}
+
+			for _, conn := range co.Connz.Conns {
+				if c.ip != "" && conn.IP != c.ip {
+					continue
+				}
+				if c.name != "" {
+					if ok, _ := path.Match(c.name, conn.Name); !ok {
+						continue
+					}
+				}
+
+				found = append(found, connInfo{conn, co.ServerInfo})
+			}
+
+			if co.Connz.Offset+co.Connz.Limit < co.Connz.Total {
+				more = true
+			}
+		}
+
+		if !more || c.cid > 0 {
+			break
+		}
+
+		offset += limit
+	}
+
+	sort.Slice(found, func(i, j int) bool {
+		if found[i].Info.Name != found[j].Info.Name {
+			return found[i].Info.Name < found[j].Info.Name
+		}
+		return found[i].Cid < found[j].Cid
+	})
+
+	return found, nil
+}
+
+func (c *SrvConnectionCmd) renderConnections(conns []connInfo) {
+	table := newTableWriter(fmt.Sprintf("%d Connections", len(conns)))
+	table.AddHeaders("Server", "CID", "Name", "Account", "User", "IP", "Uptime", "Idle", "Subs", "Msgs In", "Msgs Out")
+	for _, conn := range conns {
+		table.AddRow(conn.Info.Name, conn.Cid, conn.Name, conn.Account, conn.AuthorizedUser, fmt.Sprintf("%s:%d", conn.IP, conn.Port), conn.Uptime, conn.Idle, conn.NumSubs, humanize.Comma(conn.InMsgs), humanize.Comma(conn.OutMsgs))
+	}
+	fmt.Println(table.Render())
+}
+
+func (c *SrvConnectionCmd) findAction(_ *fisk.ParseContext) error {
+	nc, _, err := prepareHelper("", natsOpts()...)
+	if err != nil {
+		return err
+	}
+
+	conns, err := c.findConnections(nc)
+	if err != nil {
+		return err
+	}
+
+	if c.json {
+		return printJSON(conns)
+	}
+
+	if len(conns) == 0 {
+		return withExitCode(ExitNotFound, fmt.Errorf("no connections found"))
+	}
+
+	c.renderConnections(conns)
+
+	return nil
+}
+
+func (c *SrvConnectionCmd) infoAction(_ *fisk.ParseContext) error {
+	nc, _, err := prepareHelper("", natsOpts()...)
+	if err != nil {
+		return err
+	}
+
+	conns, err := c.findConnections(nc)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case len(conns) == 0:
+		return withExitCode(ExitNotFound, fmt.Errorf("no connections found"))
+	case len(conns) > 1:
+		c.renderConnections(conns)
+		return withExitCode(ExitValidation, fmt.Errorf("%d connections matched, use --host or more specific search flags to select one", len(conns)))
+	}
+
+	conn := conns[0]
+
+	if c.json {
+		return printJSON(conn)
+	}
+
+	fmt.Printf("Information for connection %d on %s\n", conn.Cid, conn.Info.Name)
+	fmt.Println()
+	if conn.Name != "" {
+		fmt.Printf("                Name: %s\n", conn.Name)
+	}
+	fmt.Printf("              Server: %s (%s)\n", conn.Info.Name, conn.Info.ID)
+	if conn.Info.Cluster != "" {
+		fmt.Printf("             Cluster: %s\n", conn.Info.Cluster)
+	}
+	if conn.Account != "" {
+		fmt.Printf("             Account: %s\n", conn.Account)
+	}
+	if conn.AuthorizedUser != "" {
+		fmt.Printf("                User: %s\n", conn.AuthorizedUser)
+	}
+	if conn.NameTag != "" {
+		fmt.Printf("            Name Tag: %s\n", conn.NameTag)
+	}
+	if len(conn.Tags) > 0 {
+		fmt.Printf("                Tags: %s\n", strings.Join(conn.Tags, ", "))
+	}
+	fmt.Printf("             Address: %s:%d\n", conn.IP, conn.Port)
+	if conn.Kind != "" {
+		fmt.Printf("                Kind: %s %s\n", conn.Kind, conn.Type)
+	}
+	if conn.MQTTClient != "" {
+		fmt.Printf("      MQTT Client ID: %s\n", conn.MQTTClient)
+	}
+	if conn.Lang != "" {
+		fmt.Printf("             Library: %s %s\n", conn.Lang, conn.Version)
+	}
+	if conn.TLSVersion != "" {
+		fmt.Printf("                 TLS: %s %s\n", conn.TLSVersion, conn.TLSCipher)
+	}
+	fmt.Printf("           Connected: %s (%s)\n", conn.Start.Local().Format(time.RFC3339), conn.Uptime)
+	fmt.Printf("       Last Activity: %s (%s idle)\n", conn.LastActivity.Local().Format(time.RFC3339), conn.Idle)
+	if conn.RTT != "" {
+		fmt.Printf("                 RTT: %s\n", conn.RTT)
+	}
+	fmt.Printf("       Pending Bytes: %s\n", humanize.IBytes(uint64(conn.Pending)))
+	fmt.Printf("         Messages In: %s (%s)\n", humanize.Comma(conn.InMsgs), humanize.IBytes(uint64(conn.InBytes)))
+	fmt.Printf("        Messages Out: %s (%s)\n", humanize.Comma(conn.OutMsgs), humanize.IBytes(uint64(conn.OutBytes)))
+	fmt.Printf("       Subscriptions: %d\n", conn.NumSubs)
+
+	if len(conn.Subs) > 0 {
+		subs := conn.Subs
+		sort.Strings(subs)
+
+		fmt.Println()
+		fmt.Println("Subscriptions:")
+		fmt.Println()
+		for _, sub := range subs {
+			fmt.Printf("  %s\n", sub)
+		}
+	}
+
+	return nil
+}
+
+func (c *SrvConnectionCmd) kickAction(_ *fisk.ParseContext) error {
+	nc, _, err := prepareHelper("", natsOpts()...)
+	if err != nil {
+		return err
+	}
+
+	conns, err := c.findConnections(nc)
+	if err != nil {
+		return err
+	}
+
+	if len(conns) == 0 {
+		return withExitCode(ExitNotFound, fmt.Errorf("no connections found"))
+	}
+
+	err = c.checkKickServers(conns)
+	if err != nil {
+		c.renderConnections(conns)
+		return err
+	}
+
+	verb := "disconnect"
+	done := "Disconnected"
+	api := "KICK"
+	if c.ldm {
+		verb = "send Lame Duck Mode to"
+		done = "Sent Lame Duck Mode to"
+		api = "LDM"
+	}
+
+	if !c.force {
+		c.renderConnections(conns)
+
+		ok, err := askConfirmation(fmt.Sprintf("Really %s %d connections", verb, len(conns)), false)
+		if err != nil {
+			return err
+		}
+
+		if !ok {
+			return nil
+		}
+	}
+
+	failed := 0
+	for _, conn := range conns {
+		err = c.kickConnection(nc, api, conn)
+		if err != nil {
+			log.Printf("Could not %s connection %d on %s: %s", verb, conn.Cid, conn.Info.Name, err)
+			failed++
+			continue
+		}
+
+		fmt.Printf("%s connection %d (%s) on %s\n", done, conn.Cid, conn.Name, conn.Info.Name)
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d connections could not be handled", failed)
+	}
+
+	return nil
+}
+
+// checkKickServers refuses to kick a connection ID matched on several servers unless a server is selected using --host,
+// connection IDs are only unique within a single server
+func (c *SrvConnectionCmd) checkKickServers(conns []connInfo) error {
+	if c.cid == 0 || c.host != "" {
+		return nil
+	}
+
+	servers := map[string]struct{}{}
+	for _, conn := range conns {
+		servers[conn.Info.ID] = struct{}{}
+	}
+
+	if len(servers) > 1 {
+		return withExitCode(ExitValidation, fmt.Errorf("connection ID %d matched connections on %d servers, use --host to select the server", c.cid, len(servers)))
+	}
+
+	return nil
+}
+
+// kickConnection issues a KICK or LDM request to the server hosting a connection
+func (c *SrvConnectionCmd) kickConnection(nc *nats.Conn, api string, conn connInfo) error {
+	body, err := json.Marshal(connectionKickRequest{CID: conn.Cid})
+	if err != nil {
+		return err
+	}
+
+	subj := fmt.Sprintf("$SYS.REQ.SERVER.%s.%s", conn.Info.ID, api)
+
+	if opts.Trace {
+		log.Printf(">>> %s: %s", subj, string(body))
+	}
+
+	resp, err := nc.Request(subj, body, opts.Timeout)
+	if errors.Is(err, nats.ErrNoResponders) {
+		return fmt.Errorf("the server does not support the %s API or the account used lacks system privileges", api)
+	} else if err != nil {
+		return err
+	}
+
+	if opts.Trace {
+		log.Printf("<<< %q", resp.Data)
+	}
+
+	reqresp := map[string]json.RawMessage{}
+	err = json.Unmarshal(resp.Data, &reqresp)
+	if err != nil {
+		return err
+	}
+
+	errresp, ok := reqresp["error"]
+	if ok {
+		apiErr := server.ApiError{}
+		if json.Unmarshal(errresp, &apiErr) == nil && apiErr.Description != "" {
+			return errors.New(apiErr.Description)
+		}
+		return fmt.Errorf("invalid response received: %q", errresp)
+	}
+
+	return nil
+}
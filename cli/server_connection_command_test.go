@@ -0,0 +1,46 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"testing"
+
+	"github.com/nats-io/nats-server/v2/server"
+)
+
+func TestCheckKickServers(t *testing.T) {
+	conns := []connInfo{
+		{&server.ConnInfo{Cid: 10}, &server.ServerInfo{ID: "S1", Name: "n1"}},
+		{&server.ConnInfo{Cid: 10}, &server.ServerInfo{ID: "S2", Name: "n2"}},
+	}
+
+	c := &SrvConnectionCmd{cid: 10}
+	if c.checkKickServers(conns) == nil {
+		t.Fatalf("expected a connection ID on several servers to be refused")
+	}
+
+	if c.checkKickServers(conns[:1]) != nil {
+		t.Fatalf("expected a connection ID on a single server to be accepted")
+	}
+
+	c.host = "n1"
+	if c.checkKickServers(conns) != nil {
+		t.Fatalf("expected --host to select the server")
+	}
+
+	c = &SrvConnectionCmd{name: "worker*"}
+	if c.checkKickServers(conns) != nil {
+		t.Fatalf("expected searches without a connection ID to be accepted")
+	}
+}
@@ -0,0 +1,246 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/choria-io/fisk"
+	"github.com/nats-io/jsm.go"
+	"github.com/nats-io/jsm.go/api"
+)
+
+type SrvDrainCmd struct {
+	server    string
+	migrateR1 bool
+	wait      time.Duration
+	force     bool
+	dryRun    bool
+}
+
+func configureServerDrainCommand(srv *fisk.CmdClause) {
+	c := &SrvDrainCmd{}
+
+	drain := mutating(srv.Command("drain-assets", "Moves Stream and Consumer leaders away from a server in preparation for maintenance").Alias("evacuate").Action(c.drainAction))
+	drain.Arg("server", "The name of the server to move assets away from").Required().StringVar(&c.server)
+	drain.Flag("migrate-r1", "Migrate single replica Streams hosted on the server to other servers").UnNegatableBoolVar(&c.migrateR1)
+	drain.Flag("wait", "How long to wait for each asset to move").Default("1m").DurationVar(&c.wait)
+	drain.Flag("force", "Force the operation without prompting").Short('f').UnNegatableBoolVar(&c.force)
+	drain.Flag("dry-run", "Only show which assets would be moved").UnNegatableBoolVar(&c.dryRun)
+}
+
+func (c *SrvDrainCmd) drainAction(_ *fisk.ParseContext) error {
+	_, mgr, err := prepareHelper("", natsOpts()...)
+	if err != nil {
+		return err
+	}
+
+	var (
+		streamLeaders   []*jsm.Stream
+		consumerLeaders []*jsm.Consumer
+		singleReplicas  []*jsm.Stream
+	)
+
+	_, err = mgr.EachStream(nil, func(s *jsm.Stream) {
+		nfo, err := s.LatestInformation()
+		if err != nil || nfo.Cluster == nil || nfo.Cluster.Leader != c.server {
+			return
+		}
+
+		if s.Replicas() == 1 {
+			singleReplicas = append(singleReplicas, s)
+			return
+		}
+
+		streamLeaders = append(streamLeaders, s)
+
+		s.EachConsumer(func(cons *jsm.Consumer) {
+			cnfo, err := cons.LatestState()
+			if err != nil || cnfo.Cluster == nil || cnfo.Cluster.Leader != c.server {
+				return
+			}
+			consumerLeaders = append(consumerLeaders, cons)
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Server %s leads %d Streams and %d Consumers and hosts %d single replica Streams\n\n", c.server, len(streamLeaders), len(consumerLeaders), len(singleReplicas))
+
+	if len(streamLeaders)+len(consumerLeaders) == 0 && (!c.migrateR1 || len(singleReplicas) == 0) {
+		fmt.Println("Nothing to move")
+		return nil
+	}
+
+	if c.dryRun {
+		for _, s := range streamLeaders {
+			fmt.Printf("Would move leader of Stream %s\n", s.Name())
+		}
+		for _, cons := range consumerLeaders {
+			fmt.Printf("Would move leader of Consumer %s > %s\n", cons.StreamName(), cons.Name())
+		}
+		if c.migrateR1 {
+			for _, s := range singleReplicas {
+				fmt.Printf("Would migrate single replica Stream %s\n", s.Name())
+			}
+		}
+		return nil
+	}
+
+	if !c.force {
+		ok, err := askConfirmation(fmt.Sprintf("Really move assets away from %s", c.server), false)
+		fisk.FatalIfError(err, "could not obtain confirmation")
+
+		if !ok {
+			return nil
+		}
+	}
+
+	failed := 0
+
+	for _, s := range streamLeaders {
+		err = c.moveLeader(fmt.Sprintf("Stream %s", s.Name()), s.LeaderStepDown, func() (*api.ClusterInfo, error) {
+			nfo, err := s.LatestInformation()
+			if err != nil {
+				return nil, err
+			}
+			return nfo.Cluster, nil
+		})
+		if err != nil {
+			log.Printf("Could not move leader of Stream %s: %s", s.Name(), err)
+			failed++
+		}
+	}
+
+	for _, cons := range consumerLeaders {
+		err = c.moveLeader(fmt.Sprintf("Consumer %s > %s", cons.StreamName(), cons.Name()), cons.LeaderStepDown, func() (*api.ClusterInfo, error) {
+			nfo, err := cons.LatestState()
+			if err != nil {
+				return nil, err
+			}
+			return nfo.Cluster, nil
+		})
+		if err != nil {
+			log.Printf("Could not move leader of Consumer %s > %s: %s", cons.StreamName(), cons.Name(), err)
+			failed++
+		}
+	}
+
+	if c.migrateR1 {
+		for _, s := range singleReplicas {
+			err = c.migrateSingleReplica(s)
+			if err != nil {
+				log.Printf("Could not migrate Stream %s: %s", s.Name(), err)
+				failed++
+			}
+		}
+	} else if len(singleReplicas) > 0 {
+		log.Printf("%d single replica Streams remain on %s, use --migrate-r1 to move them", len(singleReplicas), c.server)
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d assets could not be moved away from %s", failed, c.server)
+	}
+
+	fmt.Printf("\nAll assets moved away from %s\n", c.server)
+
+	return nil
+}
+
+// waitForCluster polls cluster state until check passes or the wait time expires
+func (c *SrvDrainCmd) waitForCluster(state func() (*api.ClusterInfo, error), check func(*api.ClusterInfo) bool) error {
+	timeout := time.After(c.wait)
+	ticker := time.NewTicker(500 * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			ci, err := state()
+			if err != nil {
+				continue
+			}
+			if ci != nil && check(ci) {
+				return nil
+			}
+		case <-timeout:
+			return fmt.Errorf("timeout after %v", c.wait)
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func (c *SrvDrainCmd) moveLeader(name string, stepDown func() error, state func() (*api.ClusterInfo, error)) error {
+	log.Printf("Moving leader of %s", name)
+
+	err := stepDown()
+	if err != nil {
+		return err
+	}
+
+	return c.waitForCluster(state, func(ci *api.ClusterInfo) bool {
+		if ci.Leader == "" || ci.Leader == c.server {
+			return false
+		}
+
+		log.Printf("New leader for %s is %s", name, ci.Leader)
+		return true
+	})
+}
+
+// migrateSingleReplica scales a stream to 2 replicas, moves the leader to the new peer and scales back to 1
+// which removes the peer on the server being drained
+func (c *SrvDrainCmd) migrateSingleReplica(s *jsm.Stream) error {
+	log.Printf("Migrating single replica Stream %s", s.Name())
+
+	state := func() (*api.ClusterInfo, error) {
+		nfo, err := s.LatestInformation()
+		if err != nil {
+			return nil, err
+		}
+		return nfo.Cluster, nil
+	}
+
+	cfg := s.Configuration()
+	cfg.Replicas = 2
+	err := s.UpdateConfiguration(cfg)
+	if err != nil {
+		return err
+	}
+
+	err = c.waitForCluster(state, func(ci *api.ClusterInfo) bool {
+		return len(ci.Replicas) == 1 && ci.Replicas[0].Current
+	})
+	if err != nil {
+		return fmt.Errorf("new replica did not become current: %w", err)
+	}
+
+	err = c.moveLeader(fmt.Sprintf("Stream %s", s.Name()), s.LeaderStepDown, state)
+	if err != nil {
+		return err
+	}
+
+	cfg.Replicas = 1
+	err = s.UpdateConfiguration(cfg)
+	if err != nil {
+		return err
+	}
+
+	return c.waitForCluster(state, func(ci *api.ClusterInfo) bool {
+		return len(ci.Replicas) == 0 && ci.Leader != "" && ci.Leader != c.server
+	})
+}
@@ -0,0 +1,69 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"fmt"
+
+	"github.com/nats-io/nats.go"
+)
+
+// serverFeature is a Stream or Consumer setting that requires a minimum server version
+type serverFeature struct {
+	name  string
+	major int
+	minor int
+	patch int
+}
+
+var (
+	featureMultipleFilters   = serverFeature{"multiple filter subjects", 2, 10, 0}
+	featureMetadata          = serverFeature{"metadata", 2, 10, 0}
+	featureCompression       = serverFeature{"stream compression", 2, 10, 0}
+	featureDurableInactive   = serverFeature{"inactive thresholds for durable consumers", 2, 10, 0}
+	featurePullMaxBytes      = serverFeature{"pull request byte limits", 2, 9, 0}
+	featureDiscardNewPerSubj = serverFeature{"discard new per subject", 2, 9, 0}
+)
+
+func (f serverFeature) version() string {
+	return fmt.Sprintf("%d.%d.%d", f.major, f.minor, f.patch)
+}
+
+// serverVersion is the version of the connected server, empty when not connected
+func serverVersion(nc *nats.Conn) string {
+	if nc == nil {
+		return ""
+	}
+
+	return nc.ConnectedServerVersion()
+}
+
+// serverSupports determines if a server version supports a feature, unknown versions are assumed to support all features
+func serverSupports(version string, f serverFeature) bool {
+	if version == "" {
+		return true
+	}
+
+	return serverMinVersion(version, f.major, f.minor, f.patch)
+}
+
+// unsupportedFeatureError is used when a server would silently ignore a setting in a way that changes behavior
+func unsupportedFeatureError(version string, f serverFeature) error {
+	return fmt.Errorf("the connected server %s does not support %s, requires %s or newer", version, f.name, f.version())
+}
+
+// warnUnsupportedFeature warns that a setting will be ignored by the connected server
+func warnUnsupportedFeature(version string, f serverFeature) {
+	log.Printf("WARNING: the connected server %s does not support %s, requires %s or newer, the setting will be ignored", version, f.name, f.version())
+}
@@ -0,0 +1,164 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/choria-io/fisk"
+	"github.com/nats-io/nats-server/v2/server"
+)
+
+type SrvHealthCmd struct {
+	expect        int
+	jsEnabledOnly bool
+	jsServerOnly  bool
+	account       string
+	json          bool
+	pushGateway   string
+	pushJob       string
+}
+
+// srvHealthzRequest adds the account filter understood by newer servers to the healthz request
+type srvHealthzRequest struct {
+	server.HealthzOptions
+	server.EventFilterOptions
+	Account string `json:"account,omitempty"`
+}
+
+type srvHealthResult struct {
+	Server  string `json:"server"`
+	Cluster string `json:"cluster,omitempty"`
+	Status  string `json:"status"`
+	Error   string `json:"error,omitempty"`
+}
+
+func configureServerHealthCommand(srv *fisk.CmdClause) {
+	c := &SrvHealthCmd{}
+
+	health := srv.Command("health", "Checks the health of all servers, exit code reflects the worst result").Alias("healthz").Action(c.healthAction)
+	health.Arg("expect", "Number of servers to expect, missing servers are considered unhealthy").IntVar(&c.expect)
+	health.Flag("js-enabled-only", "Only check that JetStream is enabled, do not check streams and consumers").UnNegatableBoolVar(&c.jsEnabledOnly)
+	health.Flag("server-only", "Restricts the health check to the JetStream server only, do not check streams and consumers").UnNegatableBoolVar(&c.jsServerOnly)
+	health.Flag("account", "Restricts the health check to assets in a specific account").StringVar(&c.account)
+	health.Flag("json", "Produce JSON output").Short('j').UnNegatableBoolVar(&c.json)
+	addPushGatewayFlags(health, &c.pushGateway, &c.pushJob)
+}
+
+func (c *SrvHealthCmd) healthAction(_ *fisk.ParseContext) error {
+	nc, _, err := prepareHelper("", natsOpts()...)
+	if err != nil {
+		return err
+	}
+
+	req := &srvHealthzRequest{
+		HealthzOptions:     server.HealthzOptions{JSEnabledOnly: c.jsEnabledOnly, JSServerOnly: c.jsServerOnly},
+		EventFilterOptions: server.EventFilterOptions{Domain: opts.Config.JSDomain()},
+		Account:            c.account,
+	}
+
+	res, err := doReq(req, "$SYS.REQ.SERVER.PING.HEALTHZ", c.expect, nc)
+	if err != nil {
+		return err
+	}
+
+	var results []*srvHealthResult
+	unhealthy := 0
+
+	for _, r := range res {
+		resp := struct {
+			Server server.ServerInfo   `json:"server"`
+			Data   server.HealthStatus `json:"data"`
+			Error  *server.ApiError    `json:"error"`
+		}{}
+
+		err = json.Unmarshal(r, &resp)
+		if err != nil {
+			return err
+		}
+
+		result := &srvHealthResult{Server: resp.Server.Name, Cluster: resp.Server.Cluster, Status: resp.Data.Status, Error: resp.Data.Error}
+		if resp.Error != nil {
+			result.Status = "error"
+			result.Error = resp.Error.Description
+		}
+		if result.Status != "ok" {
+			unhealthy++
+		}
+
+		results = append(results, result)
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Cluster != results[j].Cluster {
+			return results[i].Cluster < results[j].Cluster
+		}
+		return results[i].Server < results[j].Server
+	})
+
+	missing := 0
+	if c.expect > len(results) {
+		missing = c.expect - len(results)
+	}
+
+	if c.json {
+		printJSON(results)
+	} else {
+		table := newTableWriter(fmt.Sprintf("Health of %d servers", len(results)))
+		table.AddHeaders("Server", "Cluster", "Status", "Error")
+		for _, r := range results {
+			table.AddRow(r.Server, r.Cluster, r.Status, r.Error)
+		}
+		fmt.Print(table.Render())
+
+		if missing > 0 {
+			fmt.Printf("\nMissing %d server(s)\n", missing)
+		}
+	}
+
+	if c.pushGateway != "" {
+		err = c.pushHealthMetrics(results, missing)
+		if err != nil {
+			return err
+		}
+	}
+
+	switch {
+	case unhealthy > 0:
+		os.Exit(ExitThreshold)
+	case missing > 0 || len(results) == 0:
+		os.Exit(ExitNotFound)
+	}
+
+	return nil
+}
+
+func (c *SrvHealthCmd) pushHealthMetrics(results []*srvHealthResult, missing int) error {
+	healthy := newPushGauge("server", "healthy", "Indicates if the server reported itself healthy", "server", "cluster")
+	missingServers := newPushGauge("server", "health_missing", "Number of expected servers that did not respond to the health check")
+
+	for _, r := range results {
+		v := 0.0
+		if r.Status == "ok" {
+			v = 1
+		}
+		healthy.WithLabelValues(r.Server, r.Cluster).Set(v)
+	}
+	missingServers.WithLabelValues().Set(float64(missing))
+
+	return pushMetrics(c.pushGateway, c.pushJob, healthy, missingServers)
+}
@@ -48,7 +48,8 @@ func configureServerListCommand(srv *fisk.CmdClause) {
 
 	ls := srv.Command("list", "List known servers").Alias("ls").Action(c.list)
 	ls.Arg("expect", "How many servers to expect").Uint32Var(&c.expect)
-	ls.Flag("json", "Produce JSON output").Short('j').UnNegatableBoolVar(&c.json)
+	addJSONOutputFlag(ls, &c.json)
+	pagedCommand(ls)
 	ls.Flag("sort", "Sort servers by a specific key (name,conns,subs,routes,gws,mem,cpu,slow,uptime,rtt").Default("rtt").EnumVar(&c.sort, strings.Split("name,conns,conn,subs,sub,routes,route,gw,mem,cpu,slow,uptime,rtt", ",")...)
 	ls.Flag("reverse", "Reverse sort servers").Short('R').UnNegatableBoolVar(&c.reverse)
 	ls.Flag("compact", "Compact server names").Default("true").BoolVar(&c.compact)
@@ -127,8 +128,7 @@ func (c *SrvLsCmd) list(_ *fisk.ParseContext) error {
 	}
 
 	if c.json {
-		printJSON(results)
-		return nil
+		return printJSON(results)
 	}
 
 	// we reverse sort by default now, setting reverse=true means
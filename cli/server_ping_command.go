@@ -31,9 +31,23 @@ import (
 )
 
 type SrvPingCmd struct {
-	expect uint32
-	graph  bool
-	showId bool
+	expect   uint32
+	graph    bool
+	showId   bool
+	interval time.Duration
+	count    int
+}
+
+type srvPingStats struct {
+	name     string
+	id       string
+	cluster  string
+	sent     int
+	received int
+	last     time.Duration
+	min      time.Duration
+	max      time.Duration
+	total    time.Duration
 }
 
 func configureServerPingCommand(srv *fisk.CmdClause) {
@@ -43,6 +57,8 @@ func configureServerPingCommand(srv *fisk.CmdClause) {
 	ls.Arg("expect", "How many servers to expect").Uint32Var(&c.expect)
 	ls.Flag("graph", "Produce a response distribution graph").UnNegatableBoolVar(&c.graph)
 	ls.Flag("id", "Include the Server ID in the output").UnNegatableBoolVar(&c.showId)
+	ls.Flag("interval", "Continuously ping servers at this interval showing RTT and loss statistics").PlaceHolder("DURATION").DurationVar(&c.interval)
+	ls.Flag("count", "Number of pings to send when pinging continuously, 0 for unlimited").IntVar(&c.count)
 }
 
 func (c *SrvPingCmd) ping(_ *fisk.ParseContext) error {
@@ -52,6 +68,10 @@ func (c *SrvPingCmd) ping(_ *fisk.ParseContext) error {
 	}
 	defer nc.Close()
 
+	if c.interval > 0 {
+		return c.continuousPing(nc)
+	}
+
 	ctx, cancel := context.WithTimeout(ctx, opts.Timeout)
 
 	seen := uint32(0)
@@ -124,6 +144,141 @@ func (c *SrvPingCmd) ping(_ *fisk.ParseContext) error {
 	return nil
 }
 
+// continuousPing pings all servers every interval and redraws a table of per server statistics,
+// servers that responded before but not in a round are counted as missed
+func (c *SrvPingCmd) continuousPing(nc *nats.Conn) error {
+	if c.interval < 500*time.Millisecond {
+		return fmt.Errorf("interval should be at least 500ms")
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	ic := make(chan os.Signal, 1)
+	signal.Notify(ic, os.Interrupt)
+	go func() {
+		select {
+		case <-ic:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	stats := map[string]*srvPingStats{}
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+
+	for round := 1; c.count == 0 || round <= c.count; round++ {
+		mu := sync.Mutex{}
+		seen := map[string]bool{}
+		start := time.Now()
+
+		err := doReqAsync(nil, "$SYS.REQ.SERVER.PING", int(c.expect), nc, func(data []byte) {
+			rtt := time.Since(start)
+
+			ssm := &server.ServerStatsMsg{}
+			err := json.Unmarshal(data, ssm)
+			if err != nil {
+				log.Printf("Could not decode response: %s", err)
+				return
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			s, ok := stats[ssm.Server.Name]
+			if !ok {
+				s = &srvPingStats{name: ssm.Server.Name, id: ssm.Server.ID, cluster: ssm.Server.Cluster, sent: round - 1, min: rtt}
+				stats[ssm.Server.Name] = s
+			}
+
+			seen[s.name] = true
+			s.received++
+			s.last = rtt
+			s.total += rtt
+			if rtt < s.min {
+				s.min = rtt
+			}
+			if rtt > s.max {
+				s.max = rtt
+			}
+		})
+		if err != nil {
+			return err
+		}
+
+		mu.Lock()
+		for _, s := range stats {
+			s.sent++
+			if !seen[s.name] {
+				s.last = 0
+			}
+		}
+		c.renderPingStats(stats, round)
+		mu.Unlock()
+
+		if c.count > 0 && round == c.count {
+			break
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return nil
+		}
+	}
+
+	return nil
+}
+
+func (c *SrvPingCmd) renderPingStats(stats map[string]*srvPingStats, round int) {
+	var list []*srvPingStats
+	for _, s := range stats {
+		list = append(list, s)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].cluster != list[j].cluster {
+			return list[i].cluster < list[j].cluster
+		}
+		return list[i].name < list[j].name
+	})
+
+	clearScreen()
+
+	table := newTableWriter(fmt.Sprintf("Server ping statistics after %d rounds every %v", round, c.interval))
+	hdrs := []any{"Server", "Cluster", "Sent", "Received", "Loss", "Last", "Min", "Avg", "Max"}
+	if c.showId {
+		hdrs = append([]any{"ID"}, hdrs...)
+	}
+	table.AddHeaders(hdrs...)
+
+	for _, s := range list {
+		last := "missed"
+		if s.last > 0 {
+			last = s.last.Round(time.Microsecond).String()
+		}
+
+		avg := time.Duration(0)
+		if s.received > 0 {
+			avg = s.total / time.Duration(s.received)
+		}
+
+		loss := float64(s.sent-s.received) / float64(s.sent) * 100
+
+		row := []any{s.name, s.cluster, s.sent, s.received, fmt.Sprintf("%.1f%%", loss), last, s.min.Round(time.Microsecond), avg.Round(time.Microsecond), s.max.Round(time.Microsecond)}
+		if c.showId {
+			row = append([]any{s.id}, row...)
+		}
+		table.AddRow(row...)
+	}
+
+	fmt.Print(table.Render())
+
+	if c.expect > 0 && uint32(len(list)) < c.expect {
+		fmt.Printf("\nMissing %d server(s)\n", c.expect-uint32(len(list)))
+	}
+}
+
 func (c *SrvPingCmd) summarize(times []float64) {
 	fmt.Println()
 	fmt.Println("---- ping statistics ----")
@@ -14,9 +14,17 @@
 package cli
 
 import (
+	"context"
+	"encoding/csv"
 	"encoding/json"
 	"fmt"
+	"math"
+	"os"
+	"os/signal"
 	"sort"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/choria-io/fisk"
 	"github.com/dustin/go-humanize"
@@ -38,6 +46,16 @@ type SrvReportCmd struct {
 	server  string
 	cluster string
 	tags    []string
+	watch   time.Duration
+	topBy   string
+	columns []string
+
+	sample   time.Duration
+	interval time.Duration
+	csvFile  string
+
+	api       bool
+	apiSample time.Duration
 }
 
 type srvReportAccountInfo struct {
@@ -71,22 +89,106 @@ func configureServerReportCommand(srv *fisk.CmdClause) {
 	conns.Flag("sort", "Sort by a specific property (in-bytes,out-bytes,in-msgs,out-msgs,uptime,cid,subs)").Default("subs").EnumVar(&c.sort, "in-bytes", "out-bytes", "in-msgs", "out-msgs", "uptime", "cid", "subs")
 	conns.Flag("top", "Limit results to the top results").Default("1000").IntVar(&c.topk)
 	conns.Flag("subject", "Limits responses only to those connections with matching subscription interest").StringVar(&c.subject)
-	conns.Flag("json", "Produce JSON output").Short('j').UnNegatableBoolVar(&c.json)
+	addJSONOutputFlag(conns, &c.json)
+	pagedCommand(conns, c.watching)
+	conns.Flag("by", "Show the top talkers by a specific property, aggregated per account and client IP (msgs,bytes,subs,pending)").PlaceHolder("PROPERTY").EnumVar(&c.topBy, "msgs", "bytes", "subs", "pending")
+	conns.Flag("watch", "Refresh the report at this interval until interrupted").PlaceHolder("INTERVAL").DurationVar(&c.watch)
+	conns.Flag("columns", "Show only specific columns, comma separated").PlaceHolder("COLUMNS").StringsVar(&c.columns)
 
 	acct := report.Command("accounts", "Report on account activity").Alias("acct").Action(c.reportAccount)
 	acct.Arg("account", "Account to produce a report for").StringVar(&c.account)
 	acct.Arg("limit", "Limit the responses to a certain amount of servers").IntVar(&c.waitFor)
 	addFilterOpts(acct)
-	acct.Flag("sort", "Sort by a specific property (in-bytes,out-bytes,in-msgs,out-msgs,conns,subs,uptime,cid)").Default("subs").EnumVar(&c.sort, "in-bytes", "out-bytes", "in-msgs", "out-msgs", "conns", "subs", "uptime", "cid")
+	acct.Flag("sort", "Sort by a specific property (in-bytes,out-bytes,in-msgs,out-msgs,conns,subs,uptime,cid,api,err)").Default("subs").EnumVar(&c.sort, "in-bytes", "out-bytes", "in-msgs", "out-msgs", "conns", "subs", "uptime", "cid", "api", "err")
 	acct.Flag("top", "Limit results to the top results").Default("1000").IntVar(&c.topk)
-	acct.Flag("json", "Produce JSON output").Short('j').UnNegatableBoolVar(&c.json)
+	acct.Flag("api", "Report on JetStream API request rates and errors per account").UnNegatableBoolVar(&c.api)
+	acct.Flag("api-sample", "How long to sample JetStream API requests for before the first report").Default("5s").DurationVar(&c.apiSample)
+	addJSONOutputFlag(acct, &c.json)
+	pagedCommand(acct, c.watching)
+	acct.Flag("watch", "Refresh the report at this interval until interrupted").PlaceHolder("INTERVAL").DurationVar(&c.watch)
 
 	jsz := report.Command("jetstream", "Report on JetStream activity").Alias("jsz").Alias("js").Action(c.reportJetStream)
 	jsz.Arg("limit", "Limit the responses to a certain amount of servers").IntVar(&c.waitFor)
 	addFilterOpts(jsz)
 	jsz.Flag("account", "Produce the report for a specific account").StringVar(&c.account)
-	jsz.Flag("sort", "Sort by a specific property (name,cluster,streams,consumers,msgs,mbytes,mem,file,api,err").Default("cluster").EnumVar(&c.sort, "name", "cluster", "streams", "consumers", "msgs", "mbytes", "bytes", "mem", "file", "store", "api", "err")
+	jsz.Flag("sort", "Sort by a specific property (name,cluster,streams,consumers,msgs,mbytes,memory,file,api,err").Default("cluster").EnumVar(&c.sort, "name", "cluster", "streams", "consumers", "msgs", "mbytes", "bytes", "mem", "memory", "file", "store", "api", "err")
 	jsz.Flag("compact", "Compact server names").Default("true").BoolVar(&c.compact)
+	addJSONOutputFlag(jsz, &c.json)
+	pagedCommand(jsz, c.watching)
+	jsz.Flag("watch", "Refresh the report at this interval until interrupted").PlaceHolder("INTERVAL").DurationVar(&c.watch)
+
+	gwz := report.Command("gateways", "Report on super cluster gateway connections").Alias("gateway").Alias("gwz").Alias("gw").Action(c.reportGateways)
+	gwz.Arg("limit", "Limit the responses to a certain amount of servers").IntVar(&c.waitFor)
+	addFilterOpts(gwz)
+	addJSONOutputFlag(gwz, &c.json)
+	gwz.Flag("watch", "Refresh the report at this interval until interrupted").PlaceHolder("INTERVAL").DurationVar(&c.watch)
+
+	leafz := report.Command("leafnodes", "Report on leafnode connections").Alias("leaf").Alias("leafz").Action(c.reportLeafs)
+	leafz.Arg("limit", "Limit the responses to a certain amount of servers").IntVar(&c.waitFor)
+	addFilterOpts(leafz)
+	leafz.Flag("account", "Limit report to a specific account").StringVar(&c.account)
+	leafz.Flag("sort", "Sort by a specific property (server,name,account,subs,in-bytes,out-bytes,in-msgs,out-msgs)").Default("server").EnumVar(&c.sort, "server", "name", "account", "subs", "in-bytes", "out-bytes", "in-msgs", "out-msgs")
+	addJSONOutputFlag(leafz, &c.json)
+	leafz.Flag("watch", "Refresh the report at this interval until interrupted").PlaceHolder("INTERVAL").DurationVar(&c.watch)
+
+	placement := report.Command("placement", "Report on the distribution of Stream and Consumer leaders and replicas").Alias("balance").Action(c.reportPlacement)
+	placement.Arg("limit", "Limit the responses to a certain amount of servers").IntVar(&c.waitFor)
+	addFilterOpts(placement)
+	addJSONOutputFlag(placement, &c.json)
+
+	slow := report.Command("slow-consumers", "Report on slow consumers by server, account and connection").Alias("slow").Action(c.reportSlowConsumers)
+	slow.Arg("limit", "Limit the responses to a certain amount of servers").IntVar(&c.waitFor)
+	addFilterOpts(slow)
+	slow.Flag("account", "Limit report to a specific account").StringVar(&c.account)
+	addJSONOutputFlag(slow, &c.json)
+	slow.Flag("watch", "Refresh the report at this interval until interrupted").PlaceHolder("INTERVAL").DurationVar(&c.watch)
+
+	resources := report.Command("resources", "Report on server resource usage, optionally sampled over time").Alias("res").Action(c.reportResources)
+	resources.Arg("limit", "Limit the responses to a certain amount of servers").IntVar(&c.waitFor)
+	addFilterOpts(resources)
+	resources.Flag("sample", "Sample resource usage for this long and report minimum, average and maximum values").PlaceHolder("DURATION").DurationVar(&c.sample)
+	resources.Flag("interval", "How often to sample resource usage").Default("5s").DurationVar(&c.interval)
+	resources.Flag("csv", "Writes every sample to a CSV file").PlaceHolder("FILE").StringVar(&c.csvFile)
+	addJSONOutputFlag(resources, &c.json)
+
+	mqtt := report.Command("mqtt", "Report on connected MQTT clients").Action(c.reportMQTT)
+	mqtt.Arg("limit", "Limit the responses to a certain amount of servers").IntVar(&c.waitFor)
+	addFilterOpts(mqtt)
+	mqtt.Flag("account", "Limit report to a specific account").StringVar(&c.account)
+	addJSONOutputFlag(mqtt, &c.json)
+}
+
+// watching determines if a report is being refreshed, watched reports are not paged
+func (c *SrvReportCmd) watching() bool {
+	return c.watch > 0
+}
+
+// watchReport calls report once or, when watching, repeatedly at the watch interval after clearing the screen
+func (c *SrvReportCmd) watchReport(report func() error) error {
+	if c.watch <= 0 {
+		return report()
+	}
+
+	ticker := time.NewTicker(c.watch)
+	defer ticker.Stop()
+
+	for {
+		clearScreen()
+
+		err := report()
+		if err != nil {
+			return err
+		}
+
+		fmt.Println()
+		fmt.Printf("Updated %s, refreshing every %v\n", time.Now().Format("15:04:05"), c.watch)
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return nil
+		}
+	}
 }
 
 func (c *SrvReportCmd) reportJetStream(_ *fisk.ParseContext) error {
@@ -95,6 +197,12 @@ func (c *SrvReportCmd) reportJetStream(_ *fisk.ParseContext) error {
 		return err
 	}
 
+	return c.watchReport(func() error {
+		return c.renderJetStream(nc)
+	})
+}
+
+func (c *SrvReportCmd) renderJetStream(nc *nats.Conn) error {
 	jszOpts := server.JSzOptions{}
 	if c.account != "" {
 		jszOpts.Account = c.account
@@ -157,7 +265,7 @@ func (c *SrvReportCmd) reportJetStream(_ *fisk.ParseContext) error {
 			return c.boolReverse(jszResponses[i].Data.Messages < jszResponses[j].Data.Messages)
 		case "mbytes", "bytes":
 			return c.boolReverse(jszResponses[i].Data.Bytes < jszResponses[j].Data.Bytes)
-		case "mem":
+		case "mem", "memory":
 			return c.boolReverse(jszResponses[i].Data.JetStreamStats.Memory < jszResponses[j].Data.JetStreamStats.Memory)
 		case "store", "file":
 			return c.boolReverse(jszResponses[i].Data.JetStreamStats.Store < jszResponses[j].Data.JetStreamStats.Store)
@@ -174,6 +282,10 @@ func (c *SrvReportCmd) reportJetStream(_ *fisk.ParseContext) error {
 		return fmt.Errorf("no results received, ensure the account used has system privileges and appropriate permissions")
 	}
 
+	if c.json {
+		return printJSON(jszResponses)
+	}
+
 	// here so it's after the sort
 	for _, js := range jszResponses {
 		names = append(names, js.Server.Name)
@@ -339,6 +451,16 @@ func (c *SrvReportCmd) reportAccount(_ *fisk.ParseContext) error {
 		return err
 	}
 
+	if c.api {
+		return c.reportAccountAPI(nc)
+	}
+
+	return c.watchReport(func() error {
+		return c.renderAccounts(nc)
+	})
+}
+
+func (c *SrvReportCmd) renderAccounts(nc *nats.Conn) error {
 	connz, err := c.getConnz(0, nc)
 	if err != nil {
 		return err
@@ -360,13 +482,12 @@ func (c *SrvReportCmd) reportAccount(_ *fisk.ParseContext) error {
 		}
 
 		if c.json {
-			printJSON(account)
-			return nil
+			return printJSON(account)
 		}
 
 		if len(account.ConnInfo) > 0 {
 			report := account.ConnInfo
-			c.renderConnections(report)
+			return c.renderConnections(report)
 		}
 		return nil
 	}
@@ -395,8 +516,7 @@ func (c *SrvReportCmd) reportAccount(_ *fisk.ParseContext) error {
 	})
 
 	if c.json {
-		printJSON(accounts)
-		return nil
+		return printJSON(accounts)
 	}
 
 	table := newTableWriter(fmt.Sprintf("%d Accounts Overview", len(accounts)))
@@ -447,6 +567,195 @@ func (c *SrvReportCmd) accountInfo(connz connzList) map[string]*srvReportAccount
 	return result
 }
 
+type srvReportAccountAPI struct {
+	Account     string  `json:"account"`
+	Total       uint64  `json:"total"`
+	Errors      uint64  `json:"errors"`
+	Rate        float64 `json:"rate"`
+	ErrorRate   float64 `json:"error_rate"`
+	ErrorsDelta uint64  `json:"errors_delta"`
+}
+
+type srvReportAPISample struct {
+	Time     time.Time
+	Accounts map[string]*srvReportAccountAPI
+	Inflight map[string]uint64
+}
+
+// sampleAccountAPI gathers the JetStream API totals per account across all servers and the API requests queued on every server
+func (c *SrvReportCmd) sampleAccountAPI(nc *nats.Conn) (*srvReportAPISample, error) {
+	jszOpts := server.JSzOptions{Accounts: true, Account: c.account}
+	res, err := doReq(&server.JszEventOptions{JSzOptions: jszOpts, EventFilterOptions: c.reqFilter()}, "$SYS.REQ.SERVER.PING.JSZ", c.waitFor, nc)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(res) == 0 {
+		return nil, fmt.Errorf("no results received, ensure the account used has system privileges and appropriate permissions")
+	}
+
+	sample := &srvReportAPISample{
+		Time:     time.Now(),
+		Accounts: map[string]*srvReportAccountAPI{},
+		Inflight: map[string]uint64{},
+	}
+
+	for _, r := range res {
+		resp := struct {
+			Data   server.JSInfo     `json:"data"`
+			Server server.ServerInfo `json:"server"`
+			Error  *server.ApiError  `json:"error"`
+		}{}
+
+		err = json.Unmarshal(r, &resp)
+		if err != nil {
+			return nil, err
+		}
+		if resp.Error != nil {
+			return nil, fmt.Errorf("%s: %s", resp.Server.Name, resp.Error.Description)
+		}
+
+		sample.Inflight[resp.Server.Name] = resp.Data.API.Inflight
+
+		for _, acct := range resp.Data.AccountDetails {
+			stats, ok := sample.Accounts[acct.Name]
+			if !ok {
+				stats = &srvReportAccountAPI{Account: acct.Name}
+				sample.Accounts[acct.Name] = stats
+			}
+
+			stats.Total += acct.API.Total
+			stats.Errors += acct.API.Errors
+		}
+	}
+
+	return sample, nil
+}
+
+// reportAccountAPI reports JetStream API request and error rates per account calculated between samples
+func (c *SrvReportCmd) reportAccountAPI(nc *nats.Conn) error {
+	var prev *srvReportAPISample
+
+	return c.watchReport(func() error {
+		var err error
+
+		if prev == nil {
+			prev, err = c.sampleAccountAPI(nc)
+			if err != nil {
+				return err
+			}
+
+			if !c.json {
+				fmt.Printf("Sampling JetStream API requests for %v\n", c.apiSample)
+			}
+
+			select {
+			case <-time.After(c.apiSample):
+			case <-ctx.Done():
+				return nil
+			}
+		}
+
+		cur, err := c.sampleAccountAPI(nc)
+		if err != nil {
+			return err
+		}
+
+		err = c.renderAccountAPI(prev, cur)
+		prev = cur
+
+		return err
+	})
+}
+
+func (c *SrvReportCmd) renderAccountAPI(prev *srvReportAPISample, cur *srvReportAPISample) error {
+	elapsed := cur.Time.Sub(prev.Time).Seconds()
+
+	// counters reset when servers restart, those are treated as no activity
+	delta := func(c uint64, p uint64) uint64 {
+		if c < p {
+			return 0
+		}
+		return c - p
+	}
+
+	var accounts []*srvReportAccountAPI
+	for name, stats := range cur.Accounts {
+		var pTotal, pErrors uint64
+		if p, ok := prev.Accounts[name]; ok {
+			pTotal, pErrors = p.Total, p.Errors
+		}
+
+		stats.ErrorsDelta = delta(stats.Errors, pErrors)
+		if elapsed > 0 {
+			stats.Rate = float64(delta(stats.Total, pTotal)) / elapsed
+			stats.ErrorRate = float64(stats.ErrorsDelta) / elapsed
+		}
+
+		accounts = append(accounts, stats)
+	}
+
+	sort.Slice(accounts, func(i, j int) bool {
+		switch c.sort {
+		case "err":
+			return c.boolReverse(accounts[i].ErrorRate < accounts[j].ErrorRate)
+		default:
+			return c.boolReverse(accounts[i].Rate < accounts[j].Rate)
+		}
+	})
+
+	if c.topk > 0 && len(accounts) > c.topk {
+		accounts = accounts[:c.topk]
+	}
+
+	if c.json {
+		return printJSON(map[string]any{
+			"sample_seconds": elapsed,
+			"accounts":       accounts,
+			"inflight":       cur.Inflight,
+		})
+	}
+
+	table := newTableWriter(fmt.Sprintf("JetStream API Requests by Account sampled over %v", time.Duration(elapsed*float64(time.Second)).Round(time.Second)))
+	table.AddHeaders("Account", "Requests/s", "Errors/s", "Errors", "Total Requests", "Total Errors")
+
+	var rate, errRate float64
+	for _, a := range accounts {
+		rate += a.Rate
+		errRate += a.ErrorRate
+
+		errs := humanize.Comma(int64(a.ErrorsDelta))
+		if a.ErrorsDelta > 0 {
+			errs = color.RedString(errs)
+		}
+
+		table.AddRow(a.Account, fmt.Sprintf("%.1f", a.Rate), fmt.Sprintf("%.1f", a.ErrorRate), errs, humanize.Comma(int64(a.Total)), humanize.Comma(int64(a.Errors)))
+	}
+	table.AddFooter("", fmt.Sprintf("%.1f", rate), fmt.Sprintf("%.1f", errRate), "", "", "")
+	fmt.Print(table.Render())
+
+	var queued []string
+	for name, inflight := range cur.Inflight {
+		if inflight > 0 {
+			queued = append(queued, name)
+		}
+	}
+
+	if len(queued) > 0 {
+		sort.Slice(queued, func(i, j int) bool { return cur.Inflight[queued[i]] > cur.Inflight[queued[j]] })
+
+		fmt.Println()
+		table = newTableWriter("Pending JetStream API Requests by Server")
+		table.AddHeaders("Server", "Pending")
+		for _, name := range queued {
+			table.AddRow(name, humanize.Comma(int64(cur.Inflight[name])))
+		}
+		fmt.Print(table.Render())
+	}
+
+	return nil
+}
+
 type connInfo struct {
 	*server.ConnInfo
 	Info *server.ServerInfo `json:"server"`
@@ -458,25 +767,108 @@ func (c *SrvReportCmd) reportConnections(_ *fisk.ParseContext) error {
 		return err
 	}
 
-	connz, err := c.getConnz(0, nc)
-	if err != nil {
-		return err
+	if c.topBy != "" {
+		c.sort = c.topBy
 	}
 
-	if len(connz) == 0 {
-		return fmt.Errorf("did not get results from any servers")
-	}
+	return c.watchReport(func() error {
+		connz, err := c.getConnz(0, nc)
+		if err != nil {
+			return err
+		}
 
-	conns := connz.flatConnInfo()
+		if len(connz) == 0 {
+			return fmt.Errorf("did not get results from any servers")
+		}
+
+		conns := connz.flatConnInfo()
+
+		if c.json {
+			return printJSON(conns)
+		}
+
+		err = c.renderConnections(conns)
+		if err != nil {
+			return err
+		}
+
+		if c.topBy != "" {
+			c.renderTopTalkers(conns)
+		}
 
-	if c.json {
-		printJSON(conns)
 		return nil
+	})
+}
+
+type connTalker struct {
+	name        string
+	connections int
+	msgs        int64
+	bytes       int64
+	subs        int64
+	pending     int64
+}
+
+func (t *connTalker) value(by string) int64 {
+	switch by {
+	case "msgs":
+		return t.msgs
+	case "bytes":
+		return t.bytes
+	case "pending":
+		return t.pending
+	default:
+		return t.subs
 	}
+}
 
-	c.renderConnections(conns)
+// renderTopTalkers aggregates connections by account and by client IP ranked by c.topBy
+func (c *SrvReportCmd) renderTopTalkers(conns []connInfo) {
+	aggregate := func(key func(connInfo) string) []*connTalker {
+		talkers := map[string]*connTalker{}
+		for _, conn := range conns {
+			k := key(conn)
+			t, ok := talkers[k]
+			if !ok {
+				t = &connTalker{name: k}
+				talkers[k] = t
+			}
 
-	return nil
+			t.connections++
+			t.msgs += conn.InMsgs + conn.OutMsgs
+			t.bytes += conn.InBytes + conn.OutBytes
+			t.subs += int64(conn.NumSubs)
+			t.pending += int64(conn.Pending)
+		}
+
+		var res []*connTalker
+		for _, t := range talkers {
+			res = append(res, t)
+		}
+
+		sort.Slice(res, func(i, j int) bool {
+			return c.boolReverse(res[i].value(c.topBy) > res[j].value(c.topBy))
+		})
+
+		if c.topk > 0 && len(res) > c.topk {
+			res = res[:c.topk]
+		}
+
+		return res
+	}
+
+	render := func(title string, talkers []*connTalker) {
+		table := newTableWriter(title)
+		table.AddHeaders("Name", "Connections", "Messages", "Bytes", "Subs", "Pending")
+		for _, t := range talkers {
+			table.AddRow(t.name, humanize.Comma(int64(t.connections)), humanize.Comma(t.msgs), humanize.IBytes(uint64(t.bytes)), humanize.Comma(t.subs), humanize.IBytes(uint64(t.pending)))
+		}
+		fmt.Println()
+		fmt.Print(table.Render())
+	}
+
+	render(fmt.Sprintf("Top Accounts by %s", c.topBy), aggregate(func(conn connInfo) string { return conn.Account }))
+	render(fmt.Sprintf("Top Client IPs by %s", c.topBy), aggregate(func(conn connInfo) string { return conn.IP }))
 }
 
 func (c *SrvReportCmd) boolReverse(v bool) bool {
@@ -502,13 +894,19 @@ func (c *SrvReportCmd) sortConnections(conns []connInfo) {
 			return c.boolReverse(conns[i].Start.After(conns[j].Start))
 		case "cid":
 			return c.boolReverse(conns[i].Cid < conns[j].Cid)
+		case "msgs":
+			return c.boolReverse(conns[i].InMsgs+conns[i].OutMsgs < conns[j].InMsgs+conns[j].OutMsgs)
+		case "bytes":
+			return c.boolReverse(conns[i].InBytes+conns[i].OutBytes < conns[j].InBytes+conns[j].OutBytes)
+		case "pending":
+			return c.boolReverse(conns[i].Pending < conns[j].Pending)
 		default:
 			return c.boolReverse(len(conns[i].Subs) < len(conns[j].Subs))
 		}
 	})
 }
 
-func (c *SrvReportCmd) renderConnections(report []connInfo) {
+func (c *SrvReportCmd) renderConnections(report []connInfo) error {
 	c.sortConnections(report)
 
 	total := len(report)
@@ -518,7 +916,13 @@ func (c *SrvReportCmd) renderConnections(report []connInfo) {
 	}
 
 	table := newTableWriter(fmt.Sprintf("Top %d Connections out of %s by %s", limit, humanize.Comma(int64(total)), c.sort))
-	table.AddHeaders("CID", "Name", "Server", "Cluster", "IP", "Account", "Uptime", "In Msgs", "Out Msgs", "In Bytes", "Out Bytes", "Subs")
+	table.AddHeaders("CID", "Name", "Server", "Cluster", "IP", "Account", "Uptime", "In Msgs", "Out Msgs", "In Bytes", "Out Bytes", "Subs", "Lang", "Version", "RTT")
+	table.WideColumns("Lang", "Version", "RTT")
+	table.NarrowColumns("CID", "Name", "Account", "In Msgs", "Out Msgs", "Subs")
+	err := table.SelectColumns(c.columns...)
+	if err != nil {
+		return err
+	}
 
 	var oMsgs int64
 	var iMsgs int64
@@ -535,7 +939,7 @@ func (c *SrvReportCmd) renderConnections(report []connInfo) {
 
 	for i, info := range report {
 		name := info.Name
-		if len(info.Name) > 40 {
+		if len(info.Name) > 40 && !wideOutput() {
 			name = info.Name[:40] + " .."
 		}
 
@@ -557,7 +961,7 @@ func (c *SrvReportCmd) renderConnections(report []connInfo) {
 		srv.conns++
 
 		acc := info.Account
-		if len(info.Account) > 46 {
+		if len(info.Account) > 46 && !wideOutput() {
 			acc = info.Account[0:12] + " .."
 		}
 
@@ -567,12 +971,12 @@ func (c *SrvReportCmd) renderConnections(report []connInfo) {
 		}
 
 		if i < limit {
-			table.AddRow(cid, name, srvName, cluster, fmt.Sprintf("%s:%d", info.IP, info.Port), acc, info.Uptime, humanize.Comma(info.InMsgs), humanize.Comma(info.OutMsgs), humanize.IBytes(uint64(info.InBytes)), humanize.IBytes(uint64(info.OutBytes)), len(info.Subs))
+			table.AddRow(cid, name, srvName, cluster, fmt.Sprintf("%s:%d", info.IP, info.Port), acc, info.Uptime, humanize.Comma(info.InMsgs), humanize.Comma(info.OutMsgs), humanize.IBytes(uint64(info.InBytes)), humanize.IBytes(uint64(info.OutBytes)), len(info.Subs), info.Lang, info.Version, info.RTT)
 		}
 	}
 
 	if len(report) > 1 {
-		table.AddFooter("", fmt.Sprintf("Totals for %s connections", humanize.Comma(int64(total))), "", "", "", "", "", humanize.Comma(iMsgs), humanize.Comma(oMsgs), humanize.IBytes(uint64(iBytes)), humanize.IBytes(uint64(oBytes)), humanize.Comma(int64(subs)))
+		table.AddFooter("", fmt.Sprintf("Totals for %s connections", humanize.Comma(int64(total))), "", "", "", "", "", humanize.Comma(iMsgs), humanize.Comma(oMsgs), humanize.IBytes(uint64(iBytes)), humanize.IBytes(uint64(oBytes)), humanize.Comma(int64(subs)), "", "", "")
 	}
 
 	fmt.Print(table.Render())
@@ -591,6 +995,8 @@ func (c *SrvReportCmd) renderConnections(report []connInfo) {
 		}
 		fmt.Print(table.Render())
 	}
+
+	return nil
 }
 
 type connz struct {
@@ -784,3 +1190,1069 @@ func (c *SrvReportCmd) reqFilter() server.EventFilterOptions {
 		Tags:    c.tags,
 	}
 }
+
+type srvGatewayReport struct {
+	Server   server.ServerInfo `json:"server"`
+	Gateways *server.Gatewayz  `json:"gateways"`
+}
+
+func (c *SrvReportCmd) reportGateways(_ *fisk.ParseContext) error {
+	nc, _, err := prepareHelper("", natsOpts()...)
+	if err != nil {
+		return err
+	}
+
+	return c.watchReport(func() error {
+		return c.renderGateways(nc)
+	})
+}
+
+func (c *SrvReportCmd) renderGateways(nc *nats.Conn) error {
+	req := &server.GatewayzEventOptions{
+		GatewayzOptions:    server.GatewayzOptions{Accounts: true},
+		EventFilterOptions: c.reqFilter(),
+	}
+
+	res, err := doReq(req, "$SYS.REQ.SERVER.PING.GATEWAYZ", c.waitFor, nc)
+	if err != nil {
+		return err
+	}
+
+	var reports []*srvGatewayReport
+	for _, r := range res {
+		resp := struct {
+			Data   *server.Gatewayz  `json:"data"`
+			Server server.ServerInfo `json:"server"`
+			Error  *server.ApiError  `json:"error"`
+		}{}
+
+		err = json.Unmarshal(r, &resp)
+		if err != nil {
+			return err
+		}
+		if resp.Error != nil {
+			return fmt.Errorf("%s: %s", resp.Server.Name, resp.Error.Description)
+		}
+		if resp.Data == nil || resp.Data.Name == "" {
+			continue
+		}
+
+		reports = append(reports, &srvGatewayReport{Server: resp.Server, Gateways: resp.Data})
+	}
+
+	if len(reports) == 0 {
+		return fmt.Errorf("no gateway information received, ensure gateways are configured and the account used has system privileges")
+	}
+
+	sort.Slice(reports, func(i, j int) bool {
+		if reports[i].Gateways.Name != reports[j].Gateways.Name {
+			return reports[i].Gateways.Name < reports[j].Gateways.Name
+		}
+		return reports[i].Server.Name < reports[j].Server.Name
+	})
+
+	if c.json {
+		return printJSON(reports)
+	}
+
+	// cluster matrix counting outbound connections from servers in one cluster to another
+	var clusters []string
+	servers := map[string]int{}
+	outbound := map[string]map[string]int{}
+	for _, r := range reports {
+		local := r.Gateways.Name
+		if _, ok := outbound[local]; !ok {
+			clusters = append(clusters, local)
+			outbound[local] = map[string]int{}
+		}
+		servers[local]++
+
+		for name, gw := range r.Gateways.OutboundGateways {
+			if gw.Connection != nil {
+				outbound[local][name]++
+			}
+		}
+	}
+
+	var remotes []string
+	seen := map[string]bool{}
+	for _, r := range reports {
+		for name := range r.Gateways.OutboundGateways {
+			if !seen[name] {
+				seen[name] = true
+				remotes = append(remotes, name)
+			}
+		}
+	}
+	sort.Strings(remotes)
+
+	table := newTableWriter("Super Cluster Gateway Connectivity")
+	hdr := []any{"Cluster"}
+	for _, remote := range remotes {
+		hdr = append(hdr, remote)
+	}
+	table.AddHeaders(hdr...)
+	for _, local := range clusters {
+		row := []any{local}
+		for _, remote := range remotes {
+			if remote == local {
+				row = append(row, "")
+				continue
+			}
+
+			cnt := outbound[local][remote]
+			switch {
+			case cnt == servers[local]:
+				row = append(row, fmt.Sprintf("%d / %d", cnt, servers[local]))
+			default:
+				row = append(row, color.RedString("%d / %d", cnt, servers[local]))
+			}
+		}
+		table.AddRow(row...)
+	}
+	fmt.Print(table.Render())
+	fmt.Println()
+
+	table = newTableWriter("Gateway Connections")
+	table.AddHeaders("Server", "Cluster", "Remote", "Outbound", "RTT", "Pending", "Sent", "Received", "Inbound", "Interest Only")
+	for _, r := range reports {
+		var names []string
+		for name := range r.Gateways.OutboundGateways {
+			names = append(names, name)
+		}
+		for name := range r.Gateways.InboundGateways {
+			if _, ok := r.Gateways.OutboundGateways[name]; !ok {
+				names = append(names, name)
+			}
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			var (
+				status       = color.RedString("disconnected")
+				rtt          string
+				pending      int
+				sent         int64
+				received     int64
+				interestOnly int
+			)
+
+			out, ok := r.Gateways.OutboundGateways[name]
+			if ok && out.Connection != nil {
+				status = "connected"
+				rtt = out.Connection.RTT
+				pending = out.Connection.Pending
+				sent = out.Connection.OutBytes
+				received = out.Connection.InBytes
+			} else if !ok {
+				status = ""
+			}
+			if ok {
+				for _, acct := range out.Accounts {
+					if acct.InterestMode == server.InterestOnly.String() {
+						interestOnly++
+					}
+				}
+			}
+
+			inbound := r.Gateways.InboundGateways[name]
+			for _, in := range inbound {
+				if in.Connection != nil {
+					sent += in.Connection.OutBytes
+					received += in.Connection.InBytes
+				}
+			}
+
+			table.AddRow(
+				r.Server.Name,
+				r.Gateways.Name,
+				name,
+				status,
+				rtt,
+				humanize.IBytes(uint64(pending)),
+				humanize.IBytes(uint64(sent)),
+				humanize.IBytes(uint64(received)),
+				len(inbound),
+				interestOnly,
+			)
+		}
+	}
+	fmt.Print(table.Render())
+
+	return nil
+}
+
+type srvLeafReport struct {
+	Server string           `json:"server"`
+	Leaf   *server.LeafInfo `json:"leaf"`
+}
+
+func (c *SrvReportCmd) reportLeafs(_ *fisk.ParseContext) error {
+	nc, _, err := prepareHelper("", natsOpts()...)
+	if err != nil {
+		return err
+	}
+
+	return c.watchReport(func() error {
+		return c.renderLeafs(nc)
+	})
+}
+
+func (c *SrvReportCmd) renderLeafs(nc *nats.Conn) error {
+	req := &server.LeafzEventOptions{
+		LeafzOptions:       server.LeafzOptions{Account: c.account},
+		EventFilterOptions: c.reqFilter(),
+	}
+
+	res, err := doReq(req, "$SYS.REQ.SERVER.PING.LEAFZ", c.waitFor, nc)
+	if err != nil {
+		return err
+	}
+
+	var leafs []*srvLeafReport
+	for _, r := range res {
+		resp := struct {
+			Data   *server.Leafz     `json:"data"`
+			Server server.ServerInfo `json:"server"`
+			Error  *server.ApiError  `json:"error"`
+		}{}
+
+		err = json.Unmarshal(r, &resp)
+		if err != nil {
+			return err
+		}
+		if resp.Error != nil {
+			return fmt.Errorf("%s: %s", resp.Server.Name, resp.Error.Description)
+		}
+		if resp.Data == nil {
+			continue
+		}
+
+		for _, l := range resp.Data.Leafs {
+			leafs = append(leafs, &srvLeafReport{Server: resp.Server.Name, Leaf: l})
+		}
+	}
+
+	sort.Slice(leafs, func(i, j int) bool {
+		li, lj := leafs[i].Leaf, leafs[j].Leaf
+		switch c.sort {
+		case "name":
+			return li.Name < lj.Name
+		case "account":
+			return li.Account < lj.Account
+		case "subs":
+			return c.boolReverse(li.NumSubs < lj.NumSubs)
+		case "in-bytes":
+			return c.boolReverse(li.InBytes < lj.InBytes)
+		case "out-bytes":
+			return c.boolReverse(li.OutBytes < lj.OutBytes)
+		case "in-msgs":
+			return c.boolReverse(li.InMsgs < lj.InMsgs)
+		case "out-msgs":
+			return c.boolReverse(li.OutMsgs < lj.OutMsgs)
+		default:
+			if leafs[i].Server != leafs[j].Server {
+				return leafs[i].Server < leafs[j].Server
+			}
+			return li.Name < lj.Name
+		}
+	})
+
+	if c.json {
+		return printJSON(leafs)
+	}
+
+	if len(leafs) == 0 {
+		fmt.Println("No leafnode connections found")
+		return nil
+	}
+
+	var subs uint32
+	var inMsgs, outMsgs, inBytes, outBytes int64
+
+	table := newTableWriter(fmt.Sprintf("Leafnode Report for %d connections", len(leafs)))
+	table.AddHeaders("Server", "Name", "Account", "Address", "Spoke", "RTT", "Subs", "In Msgs", "Out Msgs", "In Bytes", "Out Bytes")
+	for _, l := range leafs {
+		subs += l.Leaf.NumSubs
+		inMsgs += l.Leaf.InMsgs
+		outMsgs += l.Leaf.OutMsgs
+		inBytes += l.Leaf.InBytes
+		outBytes += l.Leaf.OutBytes
+
+		table.AddRow(
+			l.Server,
+			l.Leaf.Name,
+			l.Leaf.Account,
+			fmt.Sprintf("%s:%d", l.Leaf.IP, l.Leaf.Port),
+			l.Leaf.IsSpoke,
+			l.Leaf.RTT,
+			humanize.Comma(int64(l.Leaf.NumSubs)),
+			humanize.Comma(l.Leaf.InMsgs),
+			humanize.Comma(l.Leaf.OutMsgs),
+			humanize.IBytes(uint64(l.Leaf.InBytes)),
+			humanize.IBytes(uint64(l.Leaf.OutBytes)),
+		)
+	}
+	table.AddFooter("", "", "", "", "", "", humanize.Comma(int64(subs)), humanize.Comma(inMsgs), humanize.Comma(outMsgs), humanize.IBytes(uint64(inBytes)), humanize.IBytes(uint64(outBytes)))
+	fmt.Print(table.Render())
+
+	return nil
+}
+
+func (c *SrvReportCmd) reportMQTT(_ *fisk.ParseContext) error {
+	nc, _, err := prepareHelper("", natsOpts()...)
+	if err != nil {
+		return err
+	}
+
+	connz, err := c.getConnz(0, nc)
+	if err != nil {
+		return err
+	}
+
+	var clients []connInfo
+	for _, conn := range connz.flatConnInfo() {
+		if conn.MQTTClient != "" {
+			clients = append(clients, conn)
+		}
+	}
+
+	sort.Slice(clients, func(i, j int) bool {
+		if clients[i].Account != clients[j].Account {
+			return clients[i].Account < clients[j].Account
+		}
+		return clients[i].MQTTClient < clients[j].MQTTClient
+	})
+
+	if c.json {
+		return printJSON(clients)
+	}
+
+	if len(clients) == 0 {
+		fmt.Println("No MQTT clients found")
+		return nil
+	}
+
+	table := newTableWriter(fmt.Sprintf("%d MQTT Clients", len(clients)))
+	table.AddHeaders("Client ID", "Account", "Server", "Address", "Uptime", "Subs", "In Msgs", "Out Msgs", "In Bytes", "Out Bytes")
+	for _, conn := range clients {
+		table.AddRow(
+			conn.MQTTClient,
+			conn.Account,
+			conn.Info.Name,
+			fmt.Sprintf("%s:%d", conn.IP, conn.Port),
+			conn.Uptime,
+			humanize.Comma(int64(conn.NumSubs)),
+			humanize.Comma(conn.InMsgs),
+			humanize.Comma(conn.OutMsgs),
+			humanize.IBytes(uint64(conn.InBytes)),
+			humanize.IBytes(uint64(conn.OutBytes)),
+		)
+	}
+	fmt.Print(table.Render())
+
+	return nil
+}
+
+type srvPlacement struct {
+	Server          string `json:"server"`
+	Cluster         string `json:"cluster"`
+	StreamLeaders   int    `json:"stream_leaders"`
+	ConsumerLeaders int    `json:"consumer_leaders"`
+	StreamReplicas  int    `json:"stream_replicas"`
+	ConsumerReplica int    `json:"consumer_replicas"`
+}
+
+type srvPlacementCluster struct {
+	Name                     string          `json:"name"`
+	Servers                  []*srvPlacement `json:"servers"`
+	StreamLeaderImbalance    float64         `json:"stream_leader_imbalance"`
+	ConsumerLeaderImbalance  float64         `json:"consumer_leader_imbalance"`
+	StreamReplicaImbalance   float64         `json:"stream_replica_imbalance"`
+	ConsumerReplicaImbalance float64         `json:"consumer_replica_imbalance"`
+}
+
+// placementImbalance is the coefficient of variation of the values in percent, 0 is perfectly balanced
+func placementImbalance(vals []int) float64 {
+	if len(vals) < 2 {
+		return 0
+	}
+
+	var total float64
+	for _, v := range vals {
+		total += float64(v)
+	}
+	mean := total / float64(len(vals))
+	if mean == 0 {
+		return 0
+	}
+
+	var variance float64
+	for _, v := range vals {
+		variance += math.Pow(float64(v)-mean, 2)
+	}
+	variance = variance / float64(len(vals))
+
+	return math.Sqrt(variance) / mean * 100
+}
+
+func (c *SrvReportCmd) reportPlacement(_ *fisk.ParseContext) error {
+	nc, _, err := prepareHelper("", natsOpts()...)
+	if err != nil {
+		return err
+	}
+
+	req := &server.JszEventOptions{
+		JSzOptions:         server.JSzOptions{Accounts: true, Streams: true, Consumer: true, Limit: 10000},
+		EventFilterOptions: c.reqFilter(),
+	}
+
+	res, err := doReq(req, "$SYS.REQ.SERVER.PING.JSZ", c.waitFor, nc)
+	if err != nil {
+		return err
+	}
+
+	clusters := map[string]*srvPlacementCluster{}
+	for _, r := range res {
+		resp := struct {
+			Data   server.JSInfo     `json:"data"`
+			Server server.ServerInfo `json:"server"`
+			Error  *server.ApiError  `json:"error"`
+		}{}
+
+		err = json.Unmarshal(r, &resp)
+		if err != nil {
+			return err
+		}
+		if resp.Error != nil {
+			return fmt.Errorf("%s: %s", resp.Server.Name, resp.Error.Description)
+		}
+
+		p := &srvPlacement{Server: resp.Server.Name, Cluster: resp.Server.Cluster}
+		isLeader := func(ci *server.ClusterInfo) bool {
+			return ci == nil || ci.Leader == "" && len(ci.Replicas) == 0 || ci.Leader == resp.Server.Name
+		}
+
+		for _, acct := range resp.Data.AccountDetails {
+			for _, s := range acct.Streams {
+				p.StreamReplicas++
+				if isLeader(s.Cluster) {
+					p.StreamLeaders++
+				}
+
+				for _, cons := range s.Consumer {
+					p.ConsumerReplica++
+					if isLeader(cons.Cluster) {
+						p.ConsumerLeaders++
+					}
+				}
+			}
+		}
+
+		cluster, ok := clusters[p.Cluster]
+		if !ok {
+			cluster = &srvPlacementCluster{Name: p.Cluster}
+			clusters[p.Cluster] = cluster
+		}
+		cluster.Servers = append(cluster.Servers, p)
+	}
+
+	if len(clusters) == 0 {
+		return fmt.Errorf("no results received, ensure the account used has system privileges and appropriate permissions")
+	}
+
+	var report []*srvPlacementCluster
+	for _, cluster := range clusters {
+		sort.Slice(cluster.Servers, func(i, j int) bool {
+			return cluster.Servers[i].Server < cluster.Servers[j].Server
+		})
+
+		var sl, cl, sr, cr []int
+		for _, s := range cluster.Servers {
+			sl = append(sl, s.StreamLeaders)
+			cl = append(cl, s.ConsumerLeaders)
+			sr = append(sr, s.StreamReplicas)
+			cr = append(cr, s.ConsumerReplica)
+		}
+		cluster.StreamLeaderImbalance = placementImbalance(sl)
+		cluster.ConsumerLeaderImbalance = placementImbalance(cl)
+		cluster.StreamReplicaImbalance = placementImbalance(sr)
+		cluster.ConsumerReplicaImbalance = placementImbalance(cr)
+
+		report = append(report, cluster)
+	}
+
+	sort.Slice(report, func(i, j int) bool {
+		return report[i].Name < report[j].Name
+	})
+
+	if c.json {
+		return printJSON(report)
+	}
+
+	pct := func(v float64) string {
+		s := fmt.Sprintf("%.0f%%", v)
+		if v > 25 {
+			return color.YellowString(s)
+		}
+		return s
+	}
+
+	for _, cluster := range report {
+		name := cluster.Name
+		if name == "" {
+			name = "unclustered"
+		}
+
+		table := newTableWriter(fmt.Sprintf("Asset placement for cluster %s", name))
+		table.AddHeaders("Server", "Stream Leaders", "Consumer Leaders", "Stream Replicas", "Consumer Replicas")
+		for _, s := range cluster.Servers {
+			table.AddRow(s.Server, humanize.Comma(int64(s.StreamLeaders)), humanize.Comma(int64(s.ConsumerLeaders)), humanize.Comma(int64(s.StreamReplicas)), humanize.Comma(int64(s.ConsumerReplica)))
+		}
+		table.AddFooter("Imbalance", pct(cluster.StreamLeaderImbalance), pct(cluster.ConsumerLeaderImbalance), pct(cluster.StreamReplicaImbalance), pct(cluster.ConsumerReplicaImbalance))
+		fmt.Print(table.Render())
+		fmt.Println()
+	}
+
+	return nil
+}
+
+type srvResourceSample struct {
+	Time          time.Time `json:"time"`
+	Server        string    `json:"server"`
+	Cluster       string    `json:"cluster,omitempty"`
+	CPU           float64   `json:"cpu"`
+	Memory        int64     `json:"memory"`
+	Connections   int       `json:"connections"`
+	SlowConsumers int64     `json:"slow_consumers"`
+	APITotal      uint64    `json:"api_total"`
+	APIRate       float64   `json:"api_rate"`
+}
+
+type srvResourceSeries struct {
+	Min float64 `json:"min"`
+	Avg float64 `json:"avg"`
+	Max float64 `json:"max"`
+}
+
+type srvResourceStats struct {
+	Server        string            `json:"server"`
+	Cluster       string            `json:"cluster,omitempty"`
+	Samples       int               `json:"samples"`
+	CPU           srvResourceSeries `json:"cpu"`
+	Memory        srvResourceSeries `json:"memory"`
+	Connections   srvResourceSeries `json:"connections"`
+	SlowConsumers int64             `json:"new_slow_consumers"`
+	APIRate       srvResourceSeries `json:"api_rate"`
+}
+
+func newSrvResourceSeries(vals []float64) srvResourceSeries {
+	if len(vals) == 0 {
+		return srvResourceSeries{}
+	}
+
+	res := srvResourceSeries{Min: vals[0], Max: vals[0]}
+	var total float64
+	for _, v := range vals {
+		res.Min = math.Min(res.Min, v)
+		res.Max = math.Max(res.Max, v)
+		total += v
+	}
+	res.Avg = total / float64(len(vals))
+
+	return res
+}
+
+func (s srvResourceSeries) render(format func(float64) string) string {
+	return fmt.Sprintf("%s / %s / %s", format(s.Min), format(s.Avg), format(s.Max))
+}
+
+func (c *SrvReportCmd) sampleResources(nc *nats.Conn) ([]*srvResourceSample, error) {
+	res, err := doReq(&server.VarzEventOptions{EventFilterOptions: c.reqFilter()}, "$SYS.REQ.SERVER.PING.VARZ", c.waitFor, nc)
+	if err != nil {
+		return nil, err
+	}
+
+	var samples []*srvResourceSample
+	for _, r := range res {
+		resp := struct {
+			Data   server.Varz       `json:"data"`
+			Server server.ServerInfo `json:"server"`
+			Error  *server.ApiError  `json:"error"`
+		}{}
+
+		err = json.Unmarshal(r, &resp)
+		if err != nil {
+			return nil, err
+		}
+		if resp.Error != nil {
+			return nil, fmt.Errorf("%s: %s", resp.Server.Name, resp.Error.Description)
+		}
+
+		sample := &srvResourceSample{
+			Time:          resp.Server.Time,
+			Server:        resp.Server.Name,
+			Cluster:       resp.Server.Cluster,
+			CPU:           resp.Data.CPU,
+			Memory:        resp.Data.Mem,
+			Connections:   resp.Data.Connections,
+			SlowConsumers: resp.Data.SlowConsumers,
+		}
+		if sample.Time.IsZero() {
+			sample.Time = time.Now().UTC()
+		}
+		if resp.Data.JetStream.Stats != nil {
+			sample.APITotal = resp.Data.JetStream.Stats.API.Total
+		}
+
+		samples = append(samples, sample)
+	}
+
+	sort.Slice(samples, func(i, j int) bool {
+		return samples[i].Server < samples[j].Server
+	})
+
+	return samples, nil
+}
+
+func (c *SrvReportCmd) reportResources(_ *fisk.ParseContext) error {
+	nc, _, err := prepareHelper("", natsOpts()...)
+	if err != nil {
+		return err
+	}
+
+	if c.sample <= 0 {
+		samples, err := c.sampleResources(nc)
+		if err != nil {
+			return err
+		}
+		if len(samples) == 0 {
+			return fmt.Errorf("no results received, ensure the account used has system privileges and appropriate permissions")
+		}
+
+		if c.json {
+			return printJSON(samples)
+		}
+
+		table := newTableWriter("Server Resource Usage")
+		table.AddHeaders("Server", "Cluster", "CPU %", "Memory", "Connections", "Slow Consumers", "JetStream API")
+		for _, s := range samples {
+			table.AddRow(s.Server, s.Cluster, fmt.Sprintf("%.1f", s.CPU), humanize.IBytes(uint64(s.Memory)), humanize.Comma(int64(s.Connections)), humanize.Comma(s.SlowConsumers), humanize.Comma(int64(s.APITotal)))
+		}
+		fmt.Print(table.Render())
+
+		return nil
+	}
+
+	if c.interval <= 0 {
+		return fmt.Errorf("sample interval has to be greater than 0")
+	}
+
+	var cw *csv.Writer
+	if c.csvFile != "" {
+		f, err := os.Create(c.csvFile)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		cw = csv.NewWriter(f)
+		cw.Write([]string{"time", "server", "cluster", "cpu", "memory", "connections", "slow_consumers", "api_total", "api_rate"})
+		cw.Flush()
+	}
+
+	if !c.json {
+		fmt.Printf("Sampling server resources every %v for %v, press ^C to report early\n", c.interval, c.sample)
+	}
+
+	series := map[string][]*srvResourceSample{}
+	last := map[string]*srvResourceSample{}
+	rounds := 0
+
+	timeout, cancel := context.WithTimeout(ctx, c.sample)
+	defer cancel()
+
+	ic := make(chan os.Signal, 1)
+	signal.Notify(ic, os.Interrupt)
+	defer signal.Stop(ic)
+	go func() {
+		select {
+		case <-ic:
+			cancel()
+		case <-timeout.Done():
+		}
+	}()
+
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+
+	for done := false; !done; {
+		samples, err := c.sampleResources(nc)
+		if err != nil {
+			return err
+		}
+
+		rounds++
+		for _, s := range samples {
+			if prev, ok := last[s.Server]; ok && s.Time.After(prev.Time) && s.APITotal >= prev.APITotal {
+				s.APIRate = float64(s.APITotal-prev.APITotal) / s.Time.Sub(prev.Time).Seconds()
+			}
+			last[s.Server] = s
+			series[s.Server] = append(series[s.Server], s)
+
+			if cw != nil {
+				cw.Write([]string{
+					s.Time.Format(time.RFC3339),
+					s.Server,
+					s.Cluster,
+					strconv.FormatFloat(s.CPU, 'f', 2, 64),
+					strconv.FormatInt(s.Memory, 10),
+					strconv.Itoa(s.Connections),
+					strconv.FormatInt(s.SlowConsumers, 10),
+					strconv.FormatUint(s.APITotal, 10),
+					strconv.FormatFloat(s.APIRate, 'f', 2, 64),
+				})
+			}
+		}
+
+		if cw != nil {
+			cw.Flush()
+			if cw.Error() != nil {
+				return cw.Error()
+			}
+		}
+
+		if !c.json {
+			fmt.Printf("\rCollected %d samples from %d servers", rounds, len(samples))
+		}
+
+		select {
+		case <-ticker.C:
+		case <-timeout.Done():
+			done = true
+		}
+	}
+
+	if !c.json {
+		fmt.Println()
+		fmt.Println()
+	}
+
+	if len(series) == 0 {
+		return fmt.Errorf("no results received, ensure the account used has system privileges and appropriate permissions")
+	}
+
+	var stats []*srvResourceStats
+	for name, samples := range series {
+		var cpu, mem, conns, rates []float64
+		for i, s := range samples {
+			cpu = append(cpu, s.CPU)
+			mem = append(mem, float64(s.Memory))
+			conns = append(conns, float64(s.Connections))
+			// the first sample has no earlier sample to calculate a rate from
+			if i > 0 {
+				rates = append(rates, s.APIRate)
+			}
+		}
+
+		first, last := samples[0], samples[len(samples)-1]
+		st := &srvResourceStats{
+			Server:      name,
+			Cluster:     first.Cluster,
+			Samples:     len(samples),
+			CPU:         newSrvResourceSeries(cpu),
+			Memory:      newSrvResourceSeries(mem),
+			Connections: newSrvResourceSeries(conns),
+			APIRate:     newSrvResourceSeries(rates),
+		}
+		if last.SlowConsumers > first.SlowConsumers {
+			st.SlowConsumers = last.SlowConsumers - first.SlowConsumers
+		}
+
+		stats = append(stats, st)
+	}
+
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Cluster != stats[j].Cluster {
+			return stats[i].Cluster < stats[j].Cluster
+		}
+		return stats[i].Server < stats[j].Server
+	})
+
+	if c.json {
+		return printJSON(stats)
+	}
+
+	pct := func(v float64) string { return fmt.Sprintf("%.1f", v) }
+	bytes := func(v float64) string { return humanize.IBytes(uint64(v)) }
+	count := func(v float64) string { return humanize.Comma(int64(math.Round(v))) }
+	rate := func(v float64) string { return fmt.Sprintf("%.1f", v) }
+
+	table := newTableWriter(fmt.Sprintf("Server Resource Usage over %d samples (min / avg / max)", rounds))
+	table.AddHeaders("Server", "Cluster", "Samples", "CPU %", "Memory", "Connections", "New Slow Consumers", "JetStream API/s")
+	for _, st := range stats {
+		slow := humanize.Comma(st.SlowConsumers)
+		if st.SlowConsumers > 0 {
+			slow = color.RedString(slow)
+		}
+
+		table.AddRow(st.Server, st.Cluster, st.Samples, st.CPU.render(pct), st.Memory.render(bytes), st.Connections.render(count), slow, st.APIRate.render(rate))
+	}
+	fmt.Print(table.Render())
+
+	if c.csvFile != "" {
+		fmt.Println()
+		fmt.Printf("Wrote all samples to %s\n", c.csvFile)
+	}
+
+	return nil
+}
+
+type srvSlowConsumerServer struct {
+	Server        string `json:"server"`
+	Cluster       string `json:"cluster,omitempty"`
+	SlowConsumers int64  `json:"slow_consumers"`
+	MaxPending    int64  `json:"max_pending"`
+}
+
+type srvSlowConsumerAccount struct {
+	Account       string `json:"account"`
+	SlowConsumers int64  `json:"slow_consumers"`
+}
+
+type srvSlowConsumerReport struct {
+	Servers      []*srvSlowConsumerServer  `json:"servers"`
+	Accounts     []*srvSlowConsumerAccount `json:"accounts"`
+	Disconnected []connInfo                `json:"disconnected"`
+	Pending      []connInfo                `json:"pending"`
+}
+
+func (c *SrvReportCmd) reportSlowConsumers(_ *fisk.ParseContext) error {
+	nc, _, err := prepareHelper("", natsOpts()...)
+	if err != nil {
+		return err
+	}
+
+	return c.watchReport(func() error {
+		return c.renderSlowConsumers(nc)
+	})
+}
+
+func (c *SrvReportCmd) gatherSlowConsumers(nc *nats.Conn) (*srvSlowConsumerReport, error) {
+	report := &srvSlowConsumerReport{}
+	maxPending := map[string]int64{}
+
+	res, err := doReq(&server.VarzEventOptions{EventFilterOptions: c.reqFilter()}, "$SYS.REQ.SERVER.PING.VARZ", c.waitFor, nc)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, r := range res {
+		resp := struct {
+			Data   server.Varz       `json:"data"`
+			Server server.ServerInfo `json:"server"`
+			Error  *server.ApiError  `json:"error"`
+		}{}
+
+		err = json.Unmarshal(r, &resp)
+		if err != nil {
+			return nil, err
+		}
+		if resp.Error != nil {
+			return nil, fmt.Errorf("%s: %s", resp.Server.Name, resp.Error.Description)
+		}
+
+		maxPending[resp.Server.ID] = resp.Data.MaxPending
+		report.Servers = append(report.Servers, &srvSlowConsumerServer{
+			Server:        resp.Server.Name,
+			Cluster:       resp.Server.Cluster,
+			SlowConsumers: resp.Data.SlowConsumers,
+			MaxPending:    resp.Data.MaxPending,
+		})
+	}
+
+	if len(report.Servers) == 0 {
+		return nil, fmt.Errorf("no results received, ensure the account used has system privileges and appropriate permissions")
+	}
+
+	sort.Slice(report.Servers, func(i, j int) bool {
+		if report.Servers[i].SlowConsumers != report.Servers[j].SlowConsumers {
+			return report.Servers[i].SlowConsumers > report.Servers[j].SlowConsumers
+		}
+		return report.Servers[i].Server < report.Servers[j].Server
+	})
+
+	statzReq := &server.AccountStatzEventOptions{EventFilterOptions: c.reqFilter()}
+	if c.account != "" {
+		statzReq.Accounts = []string{c.account}
+	}
+
+	res, err = doReq(statzReq, "$SYS.REQ.ACCOUNT.PING.STATZ", c.waitFor, nc)
+	if err != nil {
+		return nil, err
+	}
+
+	accounts := map[string]*srvSlowConsumerAccount{}
+	act := &actCmd{}
+	for _, r := range res {
+		sz, err := act.parseAccountStatResp(r)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, stat := range sz.Stats.Accounts {
+			if stat.SlowConsumers == 0 {
+				continue
+			}
+
+			acct, ok := accounts[stat.Account]
+			if !ok {
+				acct = &srvSlowConsumerAccount{Account: stat.Account}
+				accounts[stat.Account] = acct
+				report.Accounts = append(report.Accounts, acct)
+			}
+			acct.SlowConsumers += stat.SlowConsumers
+		}
+	}
+
+	sort.Slice(report.Accounts, func(i, j int) bool {
+		return report.Accounts[i].SlowConsumers > report.Accounts[j].SlowConsumers
+	})
+
+	// servers keep a list of recently closed connections that includes why they were closed
+	closedReq := &server.ConnzEventOptions{
+		ConnzOptions:       server.ConnzOptions{State: server.ConnClosed, Username: true, Account: c.account, Limit: 10000},
+		EventFilterOptions: c.reqFilter(),
+	}
+	res, err = doReq(closedReq, "$SYS.REQ.SERVER.PING.CONNZ", c.waitFor, nc)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, r := range res {
+		co, err := parseConnzResp(r)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, conn := range co.Connz.Conns {
+			if strings.HasPrefix(conn.Reason, "Slow Consumer") {
+				report.Disconnected = append(report.Disconnected, connInfo{conn, co.ServerInfo})
+			}
+		}
+	}
+
+	sort.Slice(report.Disconnected, func(i, j int) bool {
+		a, b := report.Disconnected[i].Stop, report.Disconnected[j].Stop
+		return a != nil && b != nil && a.After(*b)
+	})
+
+	openReq := &server.ConnzEventOptions{
+		ConnzOptions:       server.ConnzOptions{Sort: server.ByPending, Username: true, Account: c.account, Limit: 100},
+		EventFilterOptions: c.reqFilter(),
+	}
+	res, err = doReq(openReq, "$SYS.REQ.SERVER.PING.CONNZ", c.waitFor, nc)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, r := range res {
+		co, err := parseConnzResp(r)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, conn := range co.Connz.Conns {
+			if conn.Pending > 0 {
+				report.Pending = append(report.Pending, connInfo{conn, co.ServerInfo})
+			}
+		}
+	}
+
+	pendingPct := func(ci connInfo) float64 {
+		max := maxPending[ci.Info.ID]
+		if max <= 0 {
+			return 0
+		}
+		return float64(ci.Pending) / float64(max) * 100
+	}
+
+	sort.Slice(report.Pending, func(i, j int) bool {
+		return pendingPct(report.Pending[i]) > pendingPct(report.Pending[j])
+	})
+
+	return report, nil
+}
+
+func (c *SrvReportCmd) renderSlowConsumers(nc *nats.Conn) error {
+	report, err := c.gatherSlowConsumers(nc)
+	if err != nil {
+		return err
+	}
+
+	if c.json {
+		return printJSON(report)
+	}
+
+	maxPending := map[string]int64{}
+	var total int64
+
+	table := newTableWriter("Slow Consumers by Server")
+	table.AddHeaders("Server", "Cluster", "Slow Consumers", "Max Pending")
+	for _, s := range report.Servers {
+		total += s.SlowConsumers
+		maxPending[s.Server] = s.MaxPending
+
+		slow := humanize.Comma(s.SlowConsumers)
+		if s.SlowConsumers > 0 {
+			slow = color.RedString(slow)
+		}
+		table.AddRow(s.Server, s.Cluster, slow, humanize.IBytes(uint64(s.MaxPending)))
+	}
+	table.AddFooter("", "", humanize.Comma(total), "")
+	fmt.Print(table.Render())
+
+	if len(report.Accounts) > 0 {
+		fmt.Println()
+		table = newTableWriter("Slow Consumers by Account")
+		table.AddHeaders("Account", "Slow Consumers")
+		for _, a := range report.Accounts {
+			table.AddRow(a.Account, humanize.Comma(a.SlowConsumers))
+		}
+		fmt.Print(table.Render())
+	}
+
+	if len(report.Disconnected) > 0 {
+		fmt.Println()
+		table = newTableWriter("Recently Disconnected Slow Consumers")
+		table.AddHeaders("Server", "CID", "Account", "User", "Name", "Address", "Reason", "Disconnected")
+		for _, ci := range report.Disconnected {
+			stopped := ""
+			if ci.Stop != nil {
+				stopped = humanizeDuration(time.Since(*ci.Stop)) + " ago"
+			}
+			table.AddRow(ci.Info.Name, ci.Cid, ci.Account, ci.AuthorizedUser, ci.Name, fmt.Sprintf("%s:%d", ci.IP, ci.Port), strings.TrimSuffix(strings.TrimPrefix(ci.Reason, "Slow Consumer ("), ")"), stopped)
+		}
+		fmt.Print(table.Render())
+	}
+
+	if len(report.Pending) > 0 {
+		fmt.Println()
+		table = newTableWriter("Connections with Pending Data")
+		table.AddHeaders("Server", "CID", "Account", "User", "Name", "Address", "Pending", "Of Max Pending")
+		for _, ci := range report.Pending {
+			pct := ""
+			if max := maxPending[ci.Info.Name]; max > 0 {
+				p := float64(ci.Pending) / float64(max) * 100
+				pct = fmt.Sprintf("%.0f%%", p)
+				if p >= 50 {
+					pct = color.RedString(pct)
+				}
+			}
+			table.AddRow(ci.Info.Name, ci.Cid, ci.Account, ci.AuthorizedUser, ci.Name, fmt.Sprintf("%s:%d", ci.IP, ci.Port), humanize.IBytes(uint64(ci.Pending)), pct)
+		}
+		fmt.Print(table.Render())
+	}
+
+	return nil
+}
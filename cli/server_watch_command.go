@@ -0,0 +1,258 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/choria-io/fisk"
+	"github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats.go"
+)
+
+type SrvWatchCmd struct {
+	json    bool
+	missing time.Duration
+
+	servers map[string]*topologyServer
+	mu      sync.Mutex
+}
+
+type topologyServer struct {
+	info     server.ServerInfo
+	routes   []string
+	gateways []string
+	lastSeen time.Time
+	missing  bool
+}
+
+type topologyEvent struct {
+	Time    time.Time `json:"time"`
+	Event   string    `json:"event"`
+	Server  string    `json:"server"`
+	ID      string    `json:"id"`
+	Cluster string    `json:"cluster,omitempty"`
+	Detail  string    `json:"detail,omitempty"`
+}
+
+func configureServerWatchCommand(srv *fisk.CmdClause) {
+	c := &SrvWatchCmd{servers: map[string]*topologyServer{}}
+
+	watch := srv.Command("watch", "Watch server events as they happen")
+
+	topo := watch.Command("topology", "Watch servers joining and leaving and route and gateway changes").Alias("topo").Action(c.topologyAction)
+	topo.Flag("json", "Produce JSON output").Short('j').UnNegatableBoolVar(&c.json)
+	topo.Flag("missing", "Report servers as missing when no statistics were received for this long").Default("1m").DurationVar(&c.missing)
+}
+
+func (c *SrvWatchCmd) topologyAction(_ *fisk.ParseContext) error {
+	nc, _, err := prepareHelper("", natsOpts()...)
+	if err != nil {
+		return err
+	}
+
+	// seed the known servers so only changes get reported
+	res, err := doReq(nil, "$SYS.REQ.SERVER.PING", 0, nc)
+	if err != nil {
+		return err
+	}
+	for _, r := range res {
+		ssm := &server.ServerStatsMsg{}
+		err = json.Unmarshal(r, ssm)
+		if err != nil {
+			return err
+		}
+		c.servers[ssm.Server.ID] = c.newTopologyServer(ssm)
+	}
+
+	if !c.json {
+		fmt.Printf("Watching topology changes of %d known servers\n\n", len(c.servers))
+	}
+
+	handlers := map[string]func(*nats.Msg){
+		"$SYS.SERVER.*.STATSZ":   c.handleStatsz,
+		"$SYS.SERVER.*.SHUTDOWN": c.handleServerInfoEvent("shutdown"),
+		"$SYS.SERVER.*.LAMEDUCK": c.handleServerInfoEvent("lame-duck"),
+	}
+	for subj, handler := range handlers {
+		_, err = nc.Subscribe(subj, handler)
+		if err != nil {
+			return err
+		}
+	}
+
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			c.checkMissing()
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+func (c *SrvWatchCmd) newTopologyServer(ssm *server.ServerStatsMsg) *topologyServer {
+	ts := &topologyServer{info: ssm.Server, lastSeen: time.Now()}
+	for _, r := range ssm.Stats.Routes {
+		ts.routes = append(ts.routes, r.Name)
+	}
+	for _, g := range ssm.Stats.Gateways {
+		ts.gateways = append(ts.gateways, g.Name)
+	}
+	sort.Strings(ts.routes)
+	sort.Strings(ts.gateways)
+
+	return ts
+}
+
+func (c *SrvWatchCmd) handleStatsz(m *nats.Msg) {
+	ssm := &server.ServerStatsMsg{}
+	err := json.Unmarshal(m.Data, ssm)
+	if err != nil {
+		log.Printf("Could not decode statistics on %s: %s", m.Subject, err)
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	current := c.newTopologyServer(ssm)
+	previous, ok := c.servers[ssm.Server.ID]
+	c.servers[ssm.Server.ID] = current
+
+	switch {
+	case !ok:
+		c.report("join", ssm.Server, fmt.Sprintf("version %s", ssm.Server.Version))
+		return
+	case previous.missing:
+		c.report("returned", ssm.Server, "")
+	}
+
+	added, removed := diffStringSets(previous.routes, current.routes)
+	for _, r := range added {
+		c.report("route-up", ssm.Server, r)
+	}
+	for _, r := range removed {
+		c.report("route-down", ssm.Server, r)
+	}
+
+	added, removed = diffStringSets(previous.gateways, current.gateways)
+	for _, g := range added {
+		c.report("gateway-up", ssm.Server, g)
+	}
+	for _, g := range removed {
+		c.report("gateway-down", ssm.Server, g)
+	}
+}
+
+func (c *SrvWatchCmd) handleServerInfoEvent(event string) func(*nats.Msg) {
+	return func(m *nats.Msg) {
+		si := server.ServerInfo{}
+		// older servers send empty bodies, the id is in the subject
+		json.Unmarshal(m.Data, &si)
+		if si.ID == "" {
+			parts := strings.Split(m.Subject, ".")
+			if len(parts) > 2 {
+				si.ID = parts[2]
+			}
+		}
+
+		c.mu.Lock()
+		defer c.mu.Unlock()
+
+		known, ok := c.servers[si.ID]
+		if ok && si.Name == "" {
+			si = known.info
+		}
+
+		if event == "shutdown" {
+			delete(c.servers, si.ID)
+		}
+
+		c.report(event, si, "")
+	}
+}
+
+func (c *SrvWatchCmd) checkMissing() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, s := range c.servers {
+		if !s.missing && time.Since(s.lastSeen) > c.missing {
+			s.missing = true
+			c.report("missing", s.info, fmt.Sprintf("last seen %s ago", humanizeDuration(time.Since(s.lastSeen))))
+		}
+	}
+}
+
+func (c *SrvWatchCmd) report(event string, si server.ServerInfo, detail string) {
+	e := topologyEvent{
+		Time:    time.Now().UTC(),
+		Event:   event,
+		Server:  si.Name,
+		ID:      si.ID,
+		Cluster: si.Cluster,
+		Detail:  detail,
+	}
+
+	if c.json {
+		j, _ := json.Marshal(e)
+		fmt.Println(string(j))
+		return
+	}
+
+	name := e.Server
+	if name == "" {
+		name = e.ID
+	}
+	if e.Cluster != "" {
+		name = fmt.Sprintf("%s (%s)", name, e.Cluster)
+	}
+
+	if detail == "" {
+		fmt.Printf("[%s] %-12s %s\n", e.Time.Local().Format("15:04:05"), e.Event, name)
+	} else {
+		fmt.Printf("[%s] %-12s %s: %s\n", e.Time.Local().Format("15:04:05"), e.Event, name, detail)
+	}
+}
+
+// diffStringSets returns items in current but not previous, and items in previous but not current
+func diffStringSets(previous []string, current []string) (added []string, removed []string) {
+	prev := map[string]bool{}
+	for _, p := range previous {
+		prev[p] = true
+	}
+	cur := map[string]bool{}
+	for _, c := range current {
+		cur[c] = true
+		if !prev[c] {
+			added = append(added, c)
+		}
+	}
+	for _, p := range previous {
+		if !cur[p] {
+			removed = append(removed, p)
+		}
+	}
+
+	return added, removed
+}
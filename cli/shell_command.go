@@ -0,0 +1,441 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+
+	"github.com/choria-io/fisk"
+	"github.com/google/shlex"
+	"golang.org/x/term"
+)
+
+type shellCmd struct {
+	stream   string
+	consumer string
+	history  []string
+}
+
+// embeddedExit is raised when a command run inside the CLI process terminates, the exit code is the value
+type embeddedExit int
+
+var shellBuiltins = []string{"use", "history", "clear", "help", "exit", "quit"}
+
+func configureShellCommand(app commandHost) {
+	c := &shellCmd{}
+
+	help := `Interactive shell for running commands over a single connection
+
+Commands are entered without the leading 'nats', the connection is made
+once using the selected context and reused by all commands.
+
+Built-in commands:
+
+  use [STREAM [CONSUMER]]  selects a Stream and Consumer used when they are not given
+  history                  shows the commands entered in this session
+  clear                    clears the screen
+  help                     shows available commands
+  exit, quit               leaves the shell, also Ctrl-D
+
+Pressing tab completes commands, flags and the names of assets, up and
+down browse the history, Ctrl-C interrupts long running commands.
+`
+
+	shell := app.Command("shell", help).Action(c.shellAction)
+	addCheat("shell", shell)
+}
+
+func init() {
+	registerCommand("shell", 15, configureShellCommand)
+}
+
+// newEmbeddedApp creates an application with all commands that can be run repeatedly inside the current process
+func newEmbeddedApp(disable ...string) *fisk.Application {
+	app := fisk.New("nats", "NATS Utility")
+	app.UsageWriter(os.Stdout)
+	app.ErrorWriter(os.Stderr)
+	app.HelpFlag.Short('h')
+	app.Terminate(func(code int) { panic(embeddedExit(code)) })
+
+	commonConfigure(app, opts, disable...)
+
+	return app
+}
+
+// runEmbedded runs a command using app, commands that would terminate the process return an error instead
+func runEmbedded(app *fisk.Application, args []string) (err error) {
+	recorder := &errorRecorder{w: os.Stderr}
+	app.ErrorWriter(recorder)
+	fisk.CommandLine.ErrorWriter(recorder)
+	fisk.CommandLine.Terminate(func(code int) { panic(embeddedExit(code)) })
+
+	cmdCtx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+	ctx = cmdCtx
+
+	app.PreAction(func(pc *fisk.ParseContext) error {
+		startAudit(pc, args)
+		return checkContextGuard(pc)
+	})
+
+	defer func() {
+		if r := recover(); r != nil {
+			code, ok := r.(embeddedExit)
+			if !ok {
+				panic(r)
+			}
+
+			err = nil
+			if code != 0 {
+				msg := strings.TrimPrefix(strings.TrimSpace(recorder.lastMessage()), "nats: error: ")
+				if msg == "" {
+					msg = "command failed"
+				}
+				err = withExitCode(recorder.exitCode(), errors.New(msg))
+			}
+		}
+
+		stopPager()
+		finishAudit(err)
+
+		// commands that close the connection cause the next command to reconnect
+		if opts.Conn != nil && opts.Conn.IsClosed() {
+			opts.Conn, opts.Mgr, opts.JSc = nil, nil, nil
+		}
+	}()
+
+	_, err = app.Parse(args)
+	if err != nil {
+		if isUsageError(err) {
+			app.MustParseWithUsage(args)
+		}
+
+		app.Errorf("%v", err)
+	}
+
+	return err
+}
+
+func (c *shellCmd) shellAction(_ *fisk.ParseContext) error {
+	// connects once, all commands reuse the connection
+	_, _, err := prepareHelper("", natsOpts()...)
+	if err != nil {
+		return err
+	}
+
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		return c.readScript(os.Stdin)
+	}
+
+	return c.interactive()
+}
+
+// readScript runs commands read from a non interactive input
+func (c *shellCmd) readScript(r io.Reader) error {
+	failed := 0
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		done, err := c.execute(scanner.Text())
+		if err != nil {
+			failed++
+		}
+		if done {
+			break
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d commands failed", failed)
+	}
+
+	return scanner.Err()
+}
+
+func (c *shellCmd) interactive() error {
+	t := term.NewTerminal(struct {
+		io.Reader
+		io.Writer
+	}{os.Stdin, os.Stdout}, "")
+	t.AutoCompleteCallback = c.completer(t)
+
+	for {
+		t.SetPrompt(c.prompt())
+		if w, h, err := term.GetSize(int(os.Stdout.Fd())); err == nil {
+			t.SetSize(w, h)
+		}
+
+		state, err := term.MakeRaw(int(os.Stdin.Fd()))
+		if err != nil {
+			return err
+		}
+
+		line, err := t.ReadLine()
+		term.Restore(int(os.Stdin.Fd()), state)
+		if errors.Is(err, io.EOF) {
+			fmt.Println()
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		done, _ := c.execute(line)
+		if done {
+			return nil
+		}
+	}
+}
+
+func (c *shellCmd) prompt() string {
+	switch {
+	case c.stream != "" && c.consumer != "":
+		return fmt.Sprintf("nats %s > %s> ", c.stream, c.consumer)
+	case c.stream != "":
+		return fmt.Sprintf("nats %s> ", c.stream)
+	default:
+		return "nats> "
+	}
+}
+
+// execute runs a line of input, returns true when the shell should exit
+func (c *shellCmd) execute(line string) (bool, error) {
+	line = strings.TrimSpace(line)
+	if line == "" || strings.HasPrefix(line, "#") {
+		return false, nil
+	}
+
+	args, err := shlex.Split(line)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "nats: error: %v\n", err)
+		return false, err
+	}
+	if args[0] == "nats" {
+		args = args[1:]
+		if len(args) == 0 {
+			return false, nil
+		}
+	}
+
+	c.history = append(c.history, line)
+
+	switch args[0] {
+	case "exit", "quit":
+		return true, nil
+
+	case "clear":
+		clearScreen()
+		return false, nil
+
+	case "history":
+		for i, h := range c.history {
+			fmt.Printf("%4d  %s\n", i+1, h)
+		}
+		return false, nil
+
+	case "use":
+		return false, c.use(args[1:])
+
+	case "help":
+		args = append(args[1:], "--help")
+	}
+
+	app := newEmbeddedApp("shell")
+
+	return false, runEmbedded(app, c.withSelection(app, args))
+}
+
+func (c *shellCmd) use(args []string) error {
+	if len(args) > 2 {
+		err := fmt.Errorf("use accepts a Stream and Consumer")
+		fmt.Fprintf(os.Stderr, "nats: error: %v\n", err)
+		return err
+	}
+
+	c.stream, c.consumer = "", ""
+	if len(args) == 0 {
+		return nil
+	}
+
+	_, mgr, err := prepareHelper("", natsOpts()...)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "nats: error: %v\n", err)
+		return err
+	}
+
+	stream, err := mgr.LoadStream(args[0])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "nats: error: could not load Stream %s: %v\n", args[0], err)
+		return err
+	}
+	c.stream = stream.Name()
+
+	if len(args) == 2 {
+		cons, err := stream.LoadConsumer(args[1])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "nats: error: could not load Consumer %s: %v\n", args[1], err)
+			return err
+		}
+		c.consumer = cons.Name()
+	}
+
+	return nil
+}
+
+// withSelection adds the selected Stream and Consumer when the command accepts them but they were not given
+func (c *shellCmd) withSelection(app *fisk.Application, args []string) []string {
+	if c.stream == "" {
+		return args
+	}
+
+	pc, err := app.ParseContext(args)
+	if err != nil || pc.SelectedCommand == nil {
+		return args
+	}
+
+	given := map[string]string{}
+	for _, e := range pc.Elements {
+		if arg, ok := e.Clause.(*fisk.ArgClause); ok && e.Value != nil {
+			given[arg.Model().Name] = *e.Value
+		}
+	}
+
+	// names given to add commands are new assets
+	words := strings.Fields(pc.SelectedCommand.FullCommand())
+	adding := ""
+	if len(words) > 1 && words[len(words)-1] == "add" {
+		adding = words[0]
+	}
+
+	res := append([]string{}, args...)
+	stream := c.stream
+
+	for _, arg := range pc.SelectedCommand.Model().Args {
+		if arg.Name == adding {
+			break
+		}
+
+		value, ok := given[arg.Name]
+
+		switch {
+		case arg.Name == "stream" && ok:
+			stream = value
+		case arg.Name == "stream":
+			res = append(res, c.stream)
+		case arg.Name == "consumer" && !ok && c.consumer != "" && stream == c.stream:
+			res = append(res, c.consumer)
+		case !ok:
+			return res
+		}
+	}
+
+	return res
+}
+
+// completer completes input on tab using the same completions as the shell completion scripts
+func (c *shellCmd) completer(t *term.Terminal) func(string, int, rune) (string, int, bool) {
+	return func(line string, pos int, key rune) (string, int, bool) {
+		if key != '\t' {
+			return "", 0, false
+		}
+
+		prefix := line[:pos]
+		words := strings.Fields(prefix)
+		if len(words) == 0 || strings.HasSuffix(prefix, " ") {
+			words = append(words, "")
+		}
+		current := words[len(words)-1]
+
+		var options []string
+		for _, o := range c.completions(words) {
+			if strings.HasPrefix(o, current) {
+				options = append(options, o)
+			}
+		}
+
+		switch len(options) {
+		case 0:
+			return line, pos, true
+		case 1:
+			completed := prefix[:len(prefix)-len(current)] + options[0] + " "
+			return completed + line[pos:], len(completed), true
+		}
+
+		common := options[0]
+		for _, o := range options[1:] {
+			for !strings.HasPrefix(o, common) {
+				common = common[:len(common)-1]
+			}
+		}
+
+		if len(common) > len(current) {
+			completed := prefix[:len(prefix)-len(current)] + common
+			return completed + line[pos:], len(completed), true
+		}
+
+		t.Write([]byte(strings.Join(options, "  ") + "\n"))
+
+		return line, pos, true
+	}
+}
+
+func (c *shellCmd) completions(words []string) []string {
+	if len(words) == 1 {
+		return append(c.commandCompletions(words), shellBuiltins...)
+	}
+
+	if words[0] == "use" {
+		switch len(words) {
+		case 2:
+			return streamNamesHint()
+		case 3:
+			return consumerNamesHint(&words[1])()
+		}
+		return nil
+	}
+
+	return c.commandCompletions(words)
+}
+
+// commandCompletions captures the completions the application produces for shell completion scripts
+func (c *shellCmd) commandCompletions(words []string) []string {
+	app := newEmbeddedApp("shell")
+
+	// completes the position of a partial word rather than the word itself so abbreviations are not expanded
+	args := append([]string{"--completion-bash"}, words...)
+	if current := words[len(words)-1]; !strings.HasPrefix(current, "-") {
+		args[len(args)-1] = ""
+	}
+
+	out := captureStdout(func() {
+		defer func() {
+			if r := recover(); r != nil {
+				if _, ok := r.(embeddedExit); !ok {
+					panic(r)
+				}
+			}
+		}()
+
+		app.Parse(args)
+	})
+
+	return strings.Fields(out)
+}
@@ -0,0 +1,239 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"time"
+
+	"github.com/choria-io/fisk"
+	"github.com/nats-io/nats.go"
+)
+
+// bookmarkBucket is the Key-Value bucket holding stream bookmarks
+const bookmarkBucket = "nats-cli-bookmarks"
+
+var validBookmarkLabel = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+
+type streamBookmarkCmd struct {
+	stream   string
+	sequence uint64
+	label    string
+	note     string
+	force    bool
+	json     bool
+}
+
+// streamBookmark marks a message in a stream using a label
+type streamBookmark struct {
+	Stream   string    `json:"stream"`
+	Label    string    `json:"label"`
+	Sequence uint64    `json:"sequence"`
+	Subject  string    `json:"subject"`
+	Time     time.Time `json:"time"`
+	Note     string    `json:"note,omitempty"`
+	Created  time.Time `json:"created"`
+}
+
+func configureStreamBookmarkCommand(str *fisk.CmdClause) {
+	c := &streamBookmarkCmd{}
+
+	help := `Marks interesting positions in Streams
+
+Bookmarks label a message sequence in a Stream so it can be returned to
+later using nats stream view --from-bookmark. Bookmarks are stored in the
+nats-cli-bookmarks Key-Value bucket and shared with everyone using the
+same account.
+`
+
+	bookmark := str.Command("bookmark", help).Alias("bm")
+
+	add := mutating(bookmark.Command("add", "Bookmarks a message in a Stream").Action(c.addAction))
+	add.Arg("stream", "The Stream holding the message").Required().HintAction(streamNamesHint).StringVar(&c.stream)
+	add.Arg("sequence", "The sequence of the message to bookmark").Required().Uint64Var(&c.sequence)
+	add.Flag("label", "The label to bookmark the message as").Required().StringVar(&c.label)
+	add.Flag("note", "A note describing the bookmark").StringVar(&c.note)
+
+	ls := bookmark.Command("ls", "Lists bookmarks").Alias("list").Action(c.lsAction)
+	ls.Arg("stream", "Limits the list to a Stream").HintAction(streamNamesHint).StringVar(&c.stream)
+	addJSONOutputFlag(ls, &c.json)
+
+	rm := mutating(bookmark.Command("rm", "Removes a bookmark").Alias("delete").Alias("del").Action(c.rmAction))
+	rm.Arg("stream", "The Stream holding the bookmark").Required().HintAction(streamNamesHint).StringVar(&c.stream)
+	rm.Arg("label", "The bookmark to remove").Required().StringVar(&c.label)
+	rm.Flag("force", "Force removal without prompting").Short('f').UnNegatableBoolVar(&c.force)
+}
+
+func bookmarkKey(stream string, label string) string {
+	return fmt.Sprintf("%s.%s", stream, label)
+}
+
+// loadBookmark finds the bookmark label in stream
+func loadBookmark(stream string, label string) (*streamBookmark, error) {
+	kv, err := cliBucket(bookmarkBucket, "", false)
+	if err != nil {
+		return nil, fmt.Errorf("unknown bookmark %s: %w", label, err)
+	}
+
+	entry, err := kv.Get(bookmarkKey(stream, label))
+	if err != nil {
+		return nil, fmt.Errorf("unknown bookmark %s: %w", label, err)
+	}
+
+	bm := &streamBookmark{}
+	err = json.Unmarshal(entry.Value(), bm)
+	if err != nil {
+		return nil, fmt.Errorf("invalid bookmark %s: %w", label, err)
+	}
+
+	return bm, nil
+}
+
+func (c *streamBookmarkCmd) addAction(_ *fisk.ParseContext) error {
+	if !validBookmarkLabel.MatchString(c.label) {
+		return withExitCode(ExitValidation, fmt.Errorf("invalid label %q, labels may only contain letters, numbers, _ and -", c.label))
+	}
+
+	_, mgr, err := prepareHelper("", natsOpts()...)
+	if err != nil {
+		return err
+	}
+
+	str, err := mgr.LoadStream(c.stream)
+	if err != nil {
+		return fmt.Errorf("could not load Stream %s: %w", c.stream, err)
+	}
+
+	msg, err := str.ReadMessage(c.sequence)
+	if err != nil {
+		return fmt.Errorf("could not load message %d from Stream %s: %w", c.sequence, str.Name(), err)
+	}
+
+	bm := &streamBookmark{
+		Stream:   str.Name(),
+		Label:    c.label,
+		Sequence: msg.Sequence,
+		Subject:  msg.Subject,
+		Time:     msg.Time,
+		Note:     c.note,
+		Created:  time.Now().UTC(),
+	}
+
+	j, err := json.Marshal(bm)
+	if err != nil {
+		return err
+	}
+
+	kv, err := cliBucket(bookmarkBucket, "Stream bookmarks managed by nats stream bookmark", true)
+	if err != nil {
+		return fmt.Errorf("could not access the bookmarks bucket: %w", err)
+	}
+
+	_, err = kv.Create(bookmarkKey(bm.Stream, bm.Label), j)
+	if errors.Is(err, nats.ErrKeyExists) {
+		return withExitCode(ExitValidation, fmt.Errorf("bookmark %s already exists in Stream %s", bm.Label, bm.Stream))
+	}
+	if err != nil {
+		return fmt.Errorf("could not store bookmark: %w", err)
+	}
+
+	fmt.Printf("Bookmarked message %d on subject %s in Stream %s as %s\n", bm.Sequence, bm.Subject, bm.Stream, bm.Label)
+
+	return nil
+}
+
+func (c *streamBookmarkCmd) lsAction(_ *fisk.ParseContext) error {
+	bookmarks := []*streamBookmark{}
+
+	kv, err := cliBucket(bookmarkBucket, "", false)
+	switch {
+	case errors.Is(err, nats.ErrBucketNotFound):
+	case err != nil:
+		return err
+	default:
+		keys, err := kv.Keys()
+		if err != nil && !errors.Is(err, nats.ErrNoKeysFound) {
+			return err
+		}
+
+		for _, k := range keys {
+			entry, err := kv.Get(k)
+			if err != nil {
+				continue
+			}
+
+			bm := &streamBookmark{}
+			err = json.Unmarshal(entry.Value(), bm)
+			if err != nil {
+				continue
+			}
+
+			if c.stream == "" || bm.Stream == c.stream {
+				bookmarks = append(bookmarks, bm)
+			}
+		}
+	}
+
+	sort.Slice(bookmarks, func(i, j int) bool {
+		if bookmarks[i].Stream != bookmarks[j].Stream {
+			return bookmarks[i].Stream < bookmarks[j].Stream
+		}
+		return bookmarks[i].Sequence < bookmarks[j].Sequence
+	})
+
+	if c.json {
+		return printJSON(bookmarks)
+	}
+
+	if len(bookmarks) == 0 {
+		fmt.Println("No bookmarks found")
+		return nil
+	}
+
+	table := newTableWriter(fmt.Sprintf("%d Bookmarks", len(bookmarks)))
+	table.AddHeaders("Stream", "Label", "Sequence", "Subject", "Received", "Note")
+	for _, bm := range bookmarks {
+		table.AddRow(bm.Stream, bm.Label, bm.Sequence, bm.Subject, bm.Time.Local().Format(time.RFC3339), bm.Note)
+	}
+	fmt.Println(table.Render())
+
+	return nil
+}
+
+func (c *streamBookmarkCmd) rmAction(_ *fisk.ParseContext) error {
+	_, err := loadBookmark(c.stream, c.label)
+	if err != nil {
+		return err
+	}
+
+	if !c.force {
+		ok, err := askConfirmation(fmt.Sprintf("Really remove bookmark %s from Stream %s", c.label, c.stream), false)
+		fisk.FatalIfError(err, "could not obtain confirmation")
+
+		if !ok {
+			return nil
+		}
+	}
+
+	kv, err := cliBucket(bookmarkBucket, "", false)
+	if err != nil {
+		return err
+	}
+
+	return kv.Delete(bookmarkKey(c.stream, c.label))
+}
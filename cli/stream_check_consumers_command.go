@@ -0,0 +1,250 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/choria-io/fisk"
+	"github.com/fatih/color"
+	"github.com/nats-io/jsm.go/api"
+	"github.com/nats-io/nats-server/v2/server"
+)
+
+type streamCheckConsumersCmd struct {
+	stream string
+	json   bool
+}
+
+// Kinds of problems found by stream check-consumers
+const (
+	consumerCheckConflict = "conflict"
+	consumerCheckOverlap  = "overlap"
+	consumerCheckGap      = "gap"
+)
+
+// consumerCheckProblem is a conflict between consumer filters or a stream subject not covered by them
+type consumerCheckProblem struct {
+	Kind      string   `json:"kind"`
+	Subject   string   `json:"subject,omitempty"`
+	Consumers []string `json:"consumers,omitempty"`
+	Message   string   `json:"message"`
+}
+
+// consumerCheckReport is the result of checking the consumers of a stream
+type consumerCheckReport struct {
+	Stream    string                  `json:"stream"`
+	Retention string                  `json:"retention"`
+	Subjects  []string                `json:"subjects"`
+	Filters   map[string][]string     `json:"filters"`
+	Problems  []*consumerCheckProblem `json:"problems"`
+}
+
+func configureStreamCheckConsumersCommand(str *fisk.CmdClause) {
+	c := &streamCheckConsumersCmd{}
+
+	help := `Checks that Consumer filters fit Work Queue and Interest Streams
+
+Work Queue Streams require Consumers to have filters that do not overlap
+and messages not matching any Consumer filter are never removed. Interest
+Streams discard messages that do not match any Consumer filter and keep
+messages matching overlapping filters until every Consumer acknowledged
+them.
+
+This verifies the filters of all Consumers do not overlap and together
+cover all subjects of the Stream, exiting with code 5 when problems are
+found.
+`
+
+	check := str.Command("check-consumers", help).Action(c.checkAction)
+	check.Arg("stream", "The Stream to check").Required().HintAction(streamNamesHint).StringVar(&c.stream)
+	addJSONOutputFlag(check, &c.json)
+}
+
+// checkConsumerFilters finds overlapping filters and stream subjects not fully covered by any filter
+func checkConsumerFilters(subjects []string, filters map[string][]string, workQueue bool) []*consumerCheckProblem {
+	var problems []*consumerCheckProblem
+
+	var names []string
+	for name := range filters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for i, a := range names {
+		for _, b := range names[i+1:] {
+			var overlaps []string
+			for _, af := range filters[a] {
+				for _, bf := range filters[b] {
+					if server.SubjectsCollide(af, bf) {
+						overlaps = append(overlaps, fmt.Sprintf("%s and %s", af, bf))
+					}
+				}
+			}
+
+			if len(overlaps) == 0 {
+				continue
+			}
+
+			if workQueue {
+				problems = append(problems, &consumerCheckProblem{
+					Kind:      consumerCheckConflict,
+					Consumers: []string{a, b},
+					Message:   fmt.Sprintf("Filters %s overlap, the server rejects overlapping Work Queue Consumers", strings.Join(overlaps, ", ")),
+				})
+			} else {
+				problems = append(problems, &consumerCheckProblem{
+					Kind:      consumerCheckOverlap,
+					Consumers: []string{a, b},
+					Message:   fmt.Sprintf("Filters %s overlap, matching messages are kept until both Consumers acknowledge them", strings.Join(overlaps, ", ")),
+				})
+			}
+		}
+	}
+
+	outcome := "are discarded without being stored"
+	if workQueue {
+		outcome = "are stored but never consumed"
+	}
+
+	for _, subject := range subjects {
+		covered := false
+		var partial []string
+
+		for _, name := range names {
+			for _, filter := range filters[name] {
+				switch {
+				case subjectIsSubset(subject, filter):
+					covered = true
+				case server.SubjectsCollide(subject, filter):
+					partial = append(partial, filter)
+				}
+			}
+		}
+
+		switch {
+		case covered:
+		case len(partial) > 0:
+			problems = append(problems, &consumerCheckProblem{
+				Kind:    consumerCheckGap,
+				Subject: subject,
+				Message: fmt.Sprintf("Only partly covered by %s, messages on other subjects %s", strings.Join(partial, ", "), outcome),
+			})
+		default:
+			problems = append(problems, &consumerCheckProblem{
+				Kind:    consumerCheckGap,
+				Subject: subject,
+				Message: fmt.Sprintf("Not covered by any Consumer, messages %s", outcome),
+			})
+		}
+	}
+
+	return problems
+}
+
+func (c *streamCheckConsumersCmd) checkAction(_ *fisk.ParseContext) error {
+	_, mgr, err := prepareHelper("", natsOpts()...)
+	if err != nil {
+		return err
+	}
+
+	str, err := mgr.LoadStream(c.stream)
+	if err != nil {
+		return fmt.Errorf("could not load Stream %s: %w", c.stream, err)
+	}
+
+	cfg := str.Configuration()
+	if cfg.Retention != api.WorkQueuePolicy && cfg.Retention != api.InterestPolicy {
+		return withExitCode(ExitValidation, fmt.Errorf("stream %s uses %s retention, only Work Queue and Interest Streams can be checked", str.Name(), cfg.Retention))
+	}
+
+	consumers, _, err := loadConsumers(mgr, str.Name(), 1)
+	if err != nil {
+		return err
+	}
+
+	report := &consumerCheckReport{
+		Stream:    str.Name(),
+		Retention: cfg.Retention.String(),
+		Subjects:  cfg.Subjects,
+		Filters:   map[string][]string{},
+	}
+
+	for _, cons := range consumers {
+		report.Filters[cons.Name()] = consumerFilters(cons.Configuration())
+	}
+
+	report.Problems = checkConsumerFilters(cfg.Subjects, report.Filters, cfg.Retention == api.WorkQueuePolicy)
+	if report.Problems == nil {
+		report.Problems = []*consumerCheckProblem{}
+	}
+
+	if c.json {
+		err = printJSON(report)
+		if err != nil {
+			return err
+		}
+	} else {
+		c.render(report)
+	}
+
+	if len(report.Problems) > 0 {
+		return withExitCode(ExitThreshold, fmt.Errorf("%d problems found with the Consumers of Stream %s", len(report.Problems), report.Stream))
+	}
+
+	return nil
+}
+
+func (c *streamCheckConsumersCmd) render(report *consumerCheckReport) {
+	fmt.Printf("Checking %d Consumers of %s Stream %s with subjects %s\n\n", len(report.Filters), report.Retention, report.Stream, strings.Join(report.Subjects, ", "))
+
+	if len(report.Filters) > 0 {
+		var names []string
+		for name := range report.Filters {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		table := newTableWriter("Consumer Filters")
+		table.AddHeaders("Consumer", "Filters")
+		for _, name := range names {
+			table.AddRow(name, strings.Join(report.Filters[name], ", "))
+		}
+		fmt.Println(table.Render())
+	}
+
+	if len(report.Problems) == 0 {
+		fmt.Println("Consumer filters do not overlap and cover all subjects of the Stream")
+		return
+	}
+
+	table := newTableWriter("Problems")
+	table.AddHeaders("Kind", "Subject or Consumers", "Problem")
+	for _, p := range report.Problems {
+		kind := color.YellowString(strings.ToUpper(p.Kind))
+		if p.Kind != consumerCheckOverlap {
+			kind = color.RedString(strings.ToUpper(p.Kind))
+		}
+
+		target := p.Subject
+		if len(p.Consumers) > 0 {
+			target = strings.Join(p.Consumers, ", ")
+		}
+
+		table.AddRow(kind, target, p.Message)
+	}
+	fmt.Println(table.Render())
+}
@@ -0,0 +1,58 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"testing"
+)
+
+func TestCheckConsumerFilters(t *testing.T) {
+	problems := checkConsumerFilters([]string{"orders.>", "returns.*"}, map[string][]string{
+		"EU":  {"orders.eu.>"},
+		"ALL": {"orders.*.new"},
+		"RET": {"returns.*"},
+	}, true)
+
+	kinds := map[string]int{}
+	for _, p := range problems {
+		kinds[p.Kind]++
+	}
+
+	if kinds[consumerCheckConflict] != 1 || kinds[consumerCheckGap] != 1 || len(problems) != 2 {
+		t.Fatalf("unexpected problems: %+v", kinds)
+	}
+
+	for _, p := range problems {
+		switch p.Kind {
+		case consumerCheckConflict:
+			if len(p.Consumers) != 2 || p.Consumers[0] != "ALL" || p.Consumers[1] != "EU" {
+				t.Fatalf("unexpected conflict: %+v", p)
+			}
+		case consumerCheckGap:
+			if p.Subject != "orders.>" {
+				t.Fatalf("unexpected gap: %+v", p)
+			}
+		}
+	}
+
+	problems = checkConsumerFilters([]string{"orders.>"}, map[string][]string{"A": {"orders.eu.>"}, "B": {"orders.>"}}, false)
+	if len(problems) != 1 || problems[0].Kind != consumerCheckOverlap {
+		t.Fatalf("expected a single overlap: %+v", problems)
+	}
+
+	problems = checkConsumerFilters([]string{"orders.>"}, map[string][]string{}, false)
+	if len(problems) != 1 || problems[0].Kind != consumerCheckGap {
+		t.Fatalf("expected a gap: %+v", problems)
+	}
+}
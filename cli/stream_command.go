@@ -40,6 +40,7 @@ import (
 	"github.com/nats-io/jsm.go"
 	"github.com/nats-io/jsm.go/api"
 	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nkeys"
 	"gopkg.in/yaml.v3"
 )
 
@@ -47,6 +48,8 @@ type streamCmd struct {
 	stream           string
 	force            bool
 	json             bool
+	pushGateway      string
+	pushJob          string
 	msgID            int64
 	retentionPolicyS string
 	inputFile        string
@@ -76,6 +79,8 @@ type streamCmd struct {
 	reportRaw             bool
 	reportLimitCluster    string
 	reportLeaderDistrib   bool
+	reportColumns         []string
+	reportParallel        int
 	discardPolicy         string
 	validateOnly          bool
 	backupDirectory       string
@@ -93,6 +98,7 @@ type streamCmd struct {
 	mirror                string
 	interactive           bool
 	purgeKeep             uint64
+	purgeAt               string
 	purgeSubject          string
 	purgeSequence         uint64
 	description           string
@@ -136,7 +142,16 @@ type streamCmd struct {
 	vwPageSize   int
 	vwRaw        bool
 	vwTranslate  string
+	vwAnonymize  string
+	vwBookmark   string
+	vwFollow     bool
+	vwTemp       bool
 	vwSubject    string
+	vwShowAge    bool
+	vwShowSize   bool
+	vwDecode     string
+	vwDecryptKey string
+	vwDecrypter  nkeys.KeyPair
 
 	dryRun         bool
 	selectedStream *jsm.Stream
@@ -145,19 +160,26 @@ type streamCmd struct {
 }
 
 type streamStat struct {
-	Name      string
-	Consumers int
-	Msgs      int64
-	Bytes     uint64
-	Storage   string
-	Template  string
-	Cluster   *api.ClusterInfo
-	LostBytes uint64
-	LostMsgs  int
-	Deleted   int
-	Mirror    *api.StreamSourceInfo
-	Sources   []*api.StreamSourceInfo
-	Placement *api.Placement
+	Name      string                  `json:"name"`
+	Consumers int                     `json:"consumers"`
+	Msgs      int64                   `json:"messages"`
+	Bytes     uint64                  `json:"bytes"`
+	Storage   string                  `json:"storage"`
+	Template  string                  `json:"template,omitempty"`
+	Cluster   *api.ClusterInfo        `json:"cluster,omitempty"`
+	LostBytes uint64                  `json:"lost_bytes,omitempty"`
+	LostMsgs  int                     `json:"lost_messages,omitempty"`
+	Deleted   int                     `json:"deleted"`
+	Mirror    *api.StreamSourceInfo   `json:"mirror,omitempty"`
+	Sources   []*api.StreamSourceInfo `json:"sources,omitempty"`
+	Placement *api.Placement          `json:"placement,omitempty"`
+	Subjects  []string                `json:"subjects,omitempty"`
+}
+
+// streamReport is the structured form of the stream report
+type streamReport struct {
+	Streams      []streamStat `json:"streams"`
+	Inaccessible []string     `json:"inaccessible,omitempty"`
 }
 
 func configureStreamCommand(app commandHost) {
@@ -211,7 +233,7 @@ func configureStreamCommand(app commandHost) {
 	str.Flag("all", "When listing or selecting streams show all streams including system ones").Short('a').UnNegatableBoolVar(&c.showAll)
 	addCheat("stream", str)
 
-	strAdd := str.Command("add", "Create a new Stream").Alias("create").Alias("new").Action(c.addAction)
+	strAdd := mutating(str.Command("add", "Create a new Stream").Alias("create").Alias("new").Action(c.addAction))
 	strAdd.Arg("stream", "Stream name").StringVar(&c.stream)
 	strAdd.Flag("config", "JSON file to read configuration from").ExistingFileVar(&c.inputFile)
 	strAdd.Flag("validate", "Only validates the configuration against the official Schema").UnNegatableBoolVar(&c.validateOnly)
@@ -222,9 +244,11 @@ func configureStreamCommand(app commandHost) {
 	strLs := str.Command("ls", "List all known Streams").Alias("list").Alias("l").Action(c.lsAction)
 	strLs.Flag("subject", "Limit the list to streams with matching subjects").StringVar(&c.filterSubject)
 	strLs.Flag("names", "Show just the stream names").Short('n').UnNegatableBoolVar(&c.listNames)
-	strLs.Flag("json", "Produce JSON output").Short('j').UnNegatableBoolVar(&c.json)
+	addJSONOutputFlag(strLs, &c.json)
+	pagedCommand(strLs)
 
 	strReport := str.Command("report", "Reports on Stream statistics").Action(c.reportAction)
+	pagedCommand(strReport)
 	strReport.Flag("subject", "Limit the report to streams with matching subjects").StringVar(&c.filterSubject)
 	strReport.Flag("cluster", "Limit report to streams within a specific cluster").StringVar(&c.reportLimitCluster)
 	strReport.Flag("consumers", "Sort by number of Consumers").Short('o').UnNegatableBoolVar(&c.reportSortConsumers)
@@ -234,6 +258,10 @@ func configureStreamCommand(app commandHost) {
 	strReport.Flag("raw", "Show un-formatted numbers").Short('r').UnNegatableBoolVar(&c.reportRaw)
 	strReport.Flag("dot", "Produce a GraphViz graph of replication topology").StringVar(&c.outFile)
 	strReport.Flag("leaders", "Show details about RAFT leaders").Short('l').UnNegatableBoolVar(&c.reportLeaderDistrib)
+	strReport.Flag("columns", "Show only specific columns, comma separated").PlaceHolder("COLUMNS").StringsVar(&c.reportColumns)
+	addJSONOutputFlag(strReport, &c.json)
+	addParallelFlag(strReport, &c.reportParallel)
+	addPushGatewayFlags(strReport, &c.pushGateway, &c.pushJob)
 
 	strFind := str.Command("find", "Finds streams matching certain criteria").Alias("query").Action(c.findAction)
 	strFind.Flag("server-name", "Display streams present on a regular expression matched server").StringVar(&c.fServer)
@@ -248,96 +276,123 @@ func configureStreamCommand(app commandHost) {
 	strFind.Flag("mirrored", "Display that mirrors data from other streams").IsSetByUser(&c.fMirroredSet).UnNegatableBoolVar(&c.fMirrored)
 	strFind.Flag("names", "Show just the stream names").Short('n').UnNegatableBoolVar(&c.listNames)
 	strFind.Flag("invert", "Invert the check - before becomes after, with becomes without").BoolVar(&c.fInvert)
+	addJSONOutputFlag(strFind, &c.json)
 
 	strInfo := str.Command("info", "Stream information").Alias("nfo").Alias("i").Action(c.infoAction)
-	strInfo.Arg("stream", "Stream to retrieve information for").StringVar(&c.stream)
-	strInfo.Flag("json", "Produce JSON output").Short('j').UnNegatableBoolVar(&c.json)
+	strInfo.Arg("stream", "Stream to retrieve information for").HintAction(streamNamesHint).StringVar(&c.stream)
+	addJSONOutputFlag(strInfo, &c.json)
 	strInfo.Flag("state", "Shows only the stream state").UnNegatableBoolVar(&c.showStateOnly)
 	strInfo.Flag("no-select", "Do not select streams from a list").Default("false").UnNegatableBoolVar(&c.force)
 
 	strState := str.Command("state", "Stream state").Action(c.stateAction)
-	strState.Arg("stream", "Stream to retrieve state information for").StringVar(&c.stream)
-	strState.Flag("json", "Produce JSON output").Short('j').UnNegatableBoolVar(&c.json)
+	strState.Arg("stream", "Stream to retrieve state information for").HintAction(streamNamesHint).StringVar(&c.stream)
+	addJSONOutputFlag(strState, &c.json)
 
 	strSubs := str.Command("subjects", "Query subjects held in a stream").Alias("subj").Action(c.subjectsAction)
-	strSubs.Arg("stream", "Stream name").StringVar(&c.stream)
+	strSubs.Arg("stream", "Stream name").HintAction(streamNamesHint).StringVar(&c.stream)
 	strSubs.Arg("filter", "Limit the subjects to those matching a filter").Default(">").StringVar(&c.filterSubject)
 	strSubs.Flag("json", "Produce JSON output").Short('j').UnNegatableBoolVar(&c.json)
 	strSubs.Flag("sort", "Adjusts the sorting order (name, messages)").Default("messages").EnumVar(&c.reportSort, "name", "subjects", "messages", "count")
 	strSubs.Flag("reverse", "Reverse sort servers").Short('R').UnNegatableBoolVar(&c.reportSortReverse)
 	strSubs.Flag("names", "SList only subject names").BoolVar(&c.listNames)
 
-	strEdit := str.Command("edit", "Edits an existing stream").Alias("update").Action(c.editAction)
-	strEdit.Arg("stream", "Stream to retrieve edit").StringVar(&c.stream)
+	strEdit := mutating(str.Command("edit", "Edits an existing stream").Alias("update").Action(c.editAction))
+	strEdit.Arg("stream", "Stream to retrieve edit").HintAction(streamNamesHint).StringVar(&c.stream)
 	strEdit.Flag("config", "JSON file to read configuration from").ExistingFileVar(&c.inputFile)
 	strEdit.Flag("force", "Force edit without prompting").Short('f').UnNegatableBoolVar(&c.force)
 	strEdit.Flag("interactive", "Edit the configuring using your editor").Short('i').BoolVar(&c.interactive)
 	strEdit.Flag("dry-run", "Only shows differences, do not edit the stream").UnNegatableBoolVar(&c.dryRun)
 	addCreateFlags(strEdit, true)
 
-	strRm := str.Command("rm", "Removes a Stream").Alias("delete").Alias("del").Action(c.rmAction)
-	strRm.Arg("stream", "Stream name").StringVar(&c.stream)
+	strDiff := str.Command("diff", "Shows differences between a Stream and a configuration file").Action(c.diffAction)
+	strDiff.Arg("stream", "Stream to compare").HintAction(streamNamesHint).Required().StringVar(&c.stream)
+	strDiff.Arg("file", "JSON file to read configuration from").Required().ExistingFileVar(&c.inputFile)
+
+	strRm := mutating(str.Command("rm", "Removes a Stream").Alias("delete").Alias("del").Action(c.rmAction))
+	strRm.Arg("stream", "Stream name").HintAction(streamNamesHint).StringVar(&c.stream)
 	strRm.Flag("force", "Force removal without prompting").Short('f').UnNegatableBoolVar(&c.force)
 
-	strPurge := str.Command("purge", "Purge a Stream without deleting it").Action(c.purgeAction)
-	strPurge.Arg("stream", "Stream name").StringVar(&c.stream)
+	strPurge := mutating(str.Command("purge", "Purge a Stream without deleting it").Action(c.purgeAction))
+	strPurge.Arg("stream", "Stream name").HintAction(streamNamesHint).StringVar(&c.stream)
 	strPurge.Flag("json", "Produce JSON output").Short('j').UnNegatableBoolVar(&c.json)
 	strPurge.Flag("force", "Force removal without prompting").Short('f').UnNegatableBoolVar(&c.force)
 	strPurge.Flag("subject", "Limits the purge to a specific subject").PlaceHolder("SUBJECT").StringVar(&c.purgeSubject)
 	strPurge.Flag("seq", "Purge up to but not including a specific message sequence").PlaceHolder("SEQUENCE").Uint64Var(&c.purgeSequence)
 	strPurge.Flag("keep", "Keeps a certain number of messages after the purge").PlaceHolder("MESSAGES").Uint64Var(&c.purgeKeep)
+	strPurge.Flag("at", "Schedules the purge for a RFC3339 time or after a duration, executed by nats schedule run").PlaceHolder("TIME").StringVar(&c.purgeAt)
 
-	strCopy := str.Command("copy", "Creates a new Stream based on the configuration of another, does not copy data").Alias("cp").Action(c.cpAction)
+	strCopy := mutating(str.Command("copy", "Creates a new Stream based on the configuration of another, does not copy data").Alias("cp").Action(c.cpAction))
 	strCopy.Arg("source", "Source Stream to copy").Required().StringVar(&c.stream)
 	strCopy.Arg("destination", "New Stream to create").Required().StringVar(&c.destination)
 	addCreateFlags(strCopy, false)
 
-	strRmMsg := str.Command("rmm", "Securely removes an individual message from a Stream").Action(c.rmMsgAction)
-	strRmMsg.Arg("stream", "Stream name").StringVar(&c.stream)
+	strRmMsg := mutating(str.Command("rmm", "Securely removes an individual message from a Stream").Action(c.rmMsgAction))
+	strRmMsg.Arg("stream", "Stream name").HintAction(streamNamesHint).StringVar(&c.stream)
 	strRmMsg.Arg("id", "Message Sequence to remove").Int64Var(&c.msgID)
 	strRmMsg.Flag("force", "Force removal without prompting").Short('f').UnNegatableBoolVar(&c.force)
 
 	strView := str.Command("view", "View messages in a stream").Action(c.viewAction)
-	strView.Arg("stream", "Stream name").StringVar(&c.stream)
+	pagedCommand(strView)
+	strView.Arg("stream", "Stream name").HintAction(streamNamesHint).StringVar(&c.stream)
 	strView.Arg("size", "Page size").Default("10").IntVar(&c.vwPageSize)
 	strView.Flag("id", "Start at a specific message Sequence").IntVar(&c.vwStartId)
 	strView.Flag("since", "Delivers messages received since a duration like 1d3h5m2s").DurationVar(&c.vwStartDelta)
 	strView.Flag("raw", "Show the raw data received").UnNegatableBoolVar(&c.vwRaw)
 	strView.Flag("translate", "Translate the message data by running it through the given command before output").StringVar(&c.vwTranslate)
 	strView.Flag("subject", "Filter the stream using a subject").StringVar(&c.vwSubject)
+	strView.Flag("follow", "Keeps showing new messages as they are stored after showing the last page").Short('F').UnNegatableBoolVar(&c.vwFollow)
+	strView.Flag("temp", "Use an ephemeral R1 memory Consumer that is removed on exit or when inactive").UnNegatableBoolVar(&c.vwTemp)
+	strView.Flag("from-bookmark", "Start at a message bookmarked using nats stream bookmark").PlaceHolder("LABEL").StringVar(&c.vwBookmark)
+	strView.Flag("anonymize", "Hashes or redacts JSON fields and headers using rules in a YAML file").PlaceHolder("FILE").ExistingFileVar(&c.vwAnonymize)
+	strView.Flag("show-age", "Shows how long ago messages were stored").UnNegatableBoolVar(&c.vwShowAge)
+	strView.Flag("show-size", "Shows the size of messages").UnNegatableBoolVar(&c.vwShowSize)
+	strView.Flag("decode", "Decodes message bodies for display (json, proto, hex)").EnumVar(&c.vwDecode, msgDecoders...)
+	strView.Flag("decrypt-key", "Decrypts encrypted payloads using a curve NKey seed, given directly or in a file, defaults to the key of the context").PlaceHolder("KEY").StringVar(&c.vwDecryptKey)
 
 	strGet := str.Command("get", "Retrieves a specific message from a Stream").Action(c.getAction)
-	strGet.Arg("stream", "Stream name").StringVar(&c.stream)
+	strGet.Arg("stream", "Stream name").HintAction(streamNamesHint).StringVar(&c.stream)
 	strGet.Arg("id", "Message Sequence to retrieve").Int64Var(&c.msgID)
 	strGet.Flag("last-for", "Retrieves the message for a specific subject").Short('S').PlaceHolder("SUBJECT").StringVar(&c.filterSubject)
 	strGet.Flag("json", "Produce JSON output").Short('j').UnNegatableBoolVar(&c.json)
 	strGet.Flag("translate", "Translate the message data by running it through the given command before output").StringVar(&c.vwTranslate)
+	strGet.Flag("show-age", "Shows how long ago the message was stored").UnNegatableBoolVar(&c.vwShowAge)
+	strGet.Flag("show-size", "Shows the size of the message").UnNegatableBoolVar(&c.vwShowSize)
+	strGet.Flag("decode", "Decodes the message body for display (json, proto, hex)").EnumVar(&c.vwDecode, msgDecoders...)
 
 	strBackup := str.Command("backup", "Creates a backup of a Stream over the NATS network").Alias("snapshot").Action(c.backupAction)
-	strBackup.Arg("stream", "Stream to backup").Required().StringVar(&c.stream)
+	strBackup.Arg("stream", "Stream to backup").HintAction(streamNamesHint).Required().StringVar(&c.stream)
 	strBackup.Arg("target", "Directory to create the backup in").Required().StringVar(&c.backupDirectory)
 	strBackup.Flag("progress", "Enables or disables progress reporting using a progress bar").Default("true").BoolVar(&c.showProgress)
 	strBackup.Flag("check", "Checks the Stream for health prior to backup").UnNegatableBoolVar(&c.healthCheck)
 	strBackup.Flag("consumers", "Enable or disable consumer backups").Default("true").BoolVar(&c.snapShotConsumers)
 
-	strRestore := str.Command("restore", "Restore a Stream over the NATS network").Action(c.restoreAction)
+	strRestore := mutating(str.Command("restore", "Restore a Stream over the NATS network").Action(c.restoreAction))
 	strRestore.Arg("file", "The directory holding the backup to restore").Required().ExistingDirVar(&c.backupDirectory)
 	strRestore.Flag("progress", "Enables or disables progress reporting using a progress bar").Default("true").BoolVar(&c.showProgress)
 	strRestore.Flag("config", "Load a different configuration when restoring the stream").ExistingFileVar(&c.inputFile)
 	strRestore.Flag("cluster", "Place the stream in a specific cluster").StringVar(&c.placementCluster)
 	strRestore.Flag("tag", "Place the stream on servers that has specific tags (pass multiple times)").StringsVar(&c.placementTags)
 
-	strSeal := str.Command("seal", "Seals a stream preventing further updates").Action(c.sealAction)
-	strSeal.Arg("stream", "The name of the Stream to seal").Required().StringVar(&c.stream)
+	strSeal := mutating(str.Command("seal", "Seals a stream preventing further updates").Action(c.sealAction))
+	strSeal.Arg("stream", "The name of the Stream to seal").HintAction(streamNamesHint).Required().StringVar(&c.stream)
 	strSeal.Flag("force", "Force sealing without prompting").Short('f').UnNegatableBoolVar(&c.force)
 
 	strCluster := str.Command("cluster", "Manages a clustered Stream").Alias("c")
-	strClusterDown := strCluster.Command("step-down", "Force a new leader election by standing down the current leader").Alias("stepdown").Alias("sd").Alias("elect").Alias("down").Alias("d").Action(c.leaderStandDown)
-	strClusterDown.Arg("stream", "Stream to act on").StringVar(&c.stream)
+	strClusterDown := mutating(strCluster.Command("step-down", "Force a new leader election by standing down the current leader").Alias("stepdown").Alias("sd").Alias("elect").Alias("down").Alias("d").Action(c.leaderStandDown))
+	strClusterDown.Arg("stream", "Stream to act on").HintAction(streamNamesHint).StringVar(&c.stream)
 
-	strClusterRemovePeer := strCluster.Command("peer-remove", "Removes a peer from the Stream cluster").Alias("pr").Action(c.removePeer)
-	strClusterRemovePeer.Arg("stream", "The stream to act on").StringVar(&c.stream)
+	strClusterRemovePeer := mutating(strCluster.Command("peer-remove", "Removes a peer from the Stream cluster").Alias("pr").Action(c.removePeer))
+	strClusterRemovePeer.Arg("stream", "The stream to act on").HintAction(streamNamesHint).StringVar(&c.stream)
 	strClusterRemovePeer.Arg("peer", "The name of the peer to remove").StringVar(&c.peerName)
+
+	configureStreamPlanCommand(str)
+	configureStreamDedupeCommand(str)
+	configureStreamRateCommand(str)
+	configureStreamBookmarkCommand(str)
+	configureStreamCheckConsumersCommand(str)
+	configureStreamMetaCommand(str)
+	configureStreamTailCommand(str)
+	configureStreamReportSourcesCommand(str)
 }
 
 func init() {
@@ -353,8 +408,7 @@ func (c *streamCmd) subjectsAction(_ *fisk.ParseContext) (err error) {
 	}
 
 	if c.json {
-		printJSON(subs)
-		return nil
+		return printJSON(subs)
 	}
 
 	if asked {
@@ -631,6 +685,21 @@ func (c *streamCmd) viewAction(_ *fisk.ParseContext) error {
 		c.vwPageSize = 25
 	}
 
+	var anon *anonymizer
+	if c.vwAnonymize != "" {
+		var err error
+		anon, err = loadAnonymizer(c.vwAnonymize)
+		if err != nil {
+			return withExitCode(ExitValidation, err)
+		}
+	}
+
+	var err error
+	c.vwDecrypter, err = loadPayloadDecryptKey(c.vwDecryptKey)
+	if err != nil {
+		return withExitCode(ExitValidation, fmt.Errorf("invalid decryption key: %w", err))
+	}
+
 	c.connectAndAskStream()
 
 	str, err := c.loadStream(c.stream)
@@ -642,22 +711,39 @@ func (c *streamCmd) viewAction(_ *fisk.ParseContext) error {
 		return fmt.Errorf("work queue stream contents can not be viewed")
 	}
 
-	pops := []jsm.PagerOption{
-		jsm.PagerSize(c.vwPageSize),
+	if c.vwBookmark != "" {
+		bm, err := loadBookmark(str.Name(), c.vwBookmark)
+		if err != nil {
+			return withExitCode(ExitNotFound, err)
+		}
+		c.vwStartId = int(bm.Sequence)
 	}
 
-	switch {
-	case c.vwStartDelta > 0:
-		pops = append(pops, jsm.PagerStartDelta(c.vwStartDelta))
-	case c.vwStartId > 0:
-		pops = append(pops, jsm.PagerStartId(c.vwStartId))
+	if c.vwFollow {
+		return c.followStream(str, anon)
 	}
 
-	if c.vwSubject != "" {
-		pops = append(pops, jsm.PagerFilterSubject(c.vwSubject))
-	}
+	var pgr msgPager
+	if c.vwTemp {
+		pgr, err = newTempPager(c.nc, c.mgr, str.Name(), c.vwPageSize, c.vwStartId, c.vwStartDelta, c.vwSubject)
+	} else {
+		pops := []jsm.PagerOption{
+			jsm.PagerSize(c.vwPageSize),
+		}
+
+		switch {
+		case c.vwStartDelta > 0:
+			pops = append(pops, jsm.PagerStartDelta(c.vwStartDelta))
+		case c.vwStartId > 0:
+			pops = append(pops, jsm.PagerStartId(c.vwStartId))
+		}
+
+		if c.vwSubject != "" {
+			pops = append(pops, jsm.PagerFilterSubject(c.vwSubject))
+		}
 
-	pgr, err := str.PageContents(pops...)
+		pgr, err = str.PageContents(pops...)
+	}
 	if err != nil {
 		return err
 	}
@@ -676,6 +762,9 @@ func (c *streamCmd) viewAction(_ *fisk.ParseContext) error {
 		}
 	}()
 
+	// when paging the pager handles scrolling so we keep fetching pages until the user closes it
+	startPager()
+
 	for {
 		msg, last, err := pgr.NextMsg(ctx)
 		if err != nil && last {
@@ -686,31 +775,19 @@ func (c *streamCmd) viewAction(_ *fisk.ParseContext) error {
 			return err
 		}
 
-		switch {
-		case c.vwRaw:
-			fmt.Println(string(msg.Data))
-		default:
-			meta, err := jsm.ParseJSMsgMetadata(msg)
-			if err == nil {
-				fmt.Printf("[%d] Subject: %s Received: %s\n", meta.StreamSequence(), msg.Subject, meta.TimeStamp().Format(time.RFC3339))
-			} else {
-				fmt.Printf("Subject: %s Reply: %s\n", msg.Subject, msg.Reply)
-			}
+		err = c.renderViewMsg(msg, anon)
+		if err != nil {
+			return err
+		}
 
-			if len(msg.Header) > 0 {
-				fmt.Println()
-				for k, vs := range msg.Header {
-					for _, v := range vs {
-						fmt.Printf("  %s: %s\n", k, v)
-					}
+		if last {
+			if pagerActive() {
+				if pagerClosed() {
+					return nil
 				}
+				continue
 			}
 
-			fmt.Println()
-			outPutMSGBody(msg.Data, c.vwTranslate, msg.Subject, meta.Stream())
-		}
-
-		if last {
 			next := false
 			askOne(&survey.Confirm{Message: "Next Page?", Default: true}, &next)
 			if !next {
@@ -720,6 +797,111 @@ func (c *streamCmd) viewAction(_ *fisk.ParseContext) error {
 	}
 }
 
+// followStream shows messages from the selected start, or the last page, and keeps showing new messages until interrupted
+func (c *streamCmd) followStream(str *jsm.Stream, anon *anonymizer) error {
+	js, err := c.nc.JetStream()
+	if err != nil {
+		return err
+	}
+
+	sopts := []nats.SubOpt{nats.BindStream(str.Name()), nats.OrderedConsumer()}
+
+	switch {
+	case c.vwStartDelta > 0:
+		sopts = append(sopts, nats.StartTime(time.Now().Add(-c.vwStartDelta)))
+	case c.vwStartId > 0:
+		sopts = append(sopts, nats.StartSequence(uint64(c.vwStartId)))
+	default:
+		state, err := str.State()
+		if err != nil {
+			return err
+		}
+
+		if state.Msgs == 0 {
+			sopts = append(sopts, nats.DeliverNew())
+		} else {
+			start := state.FirstSeq
+			if state.LastSeq >= uint64(c.vwPageSize) && state.LastSeq-uint64(c.vwPageSize)+1 > start {
+				start = state.LastSeq - uint64(c.vwPageSize) + 1
+			}
+			sopts = append(sopts, nats.StartSequence(start))
+		}
+	}
+
+	sub, err := js.SubscribeSync(c.vwSubject, sopts...)
+	if err != nil {
+		return err
+	}
+	defer sub.Unsubscribe()
+
+	ctx, cancel := signal.NotifyContext(ctx, syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT)
+	defer cancel()
+
+	for {
+		msg, err := sub.NextMsgWithContext(ctx)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		err = c.renderViewMsg(msg, anon)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (c *streamCmd) renderViewMsg(msg *nats.Msg, anon *anonymizer) error {
+	// payloads are compressed before being encrypted when published
+	err := decryptPayload(msg, c.vwDecrypter)
+	if err == nil {
+		err = decompressPayload(msg)
+	}
+	if err != nil {
+		log.Printf("Message on %s: %s", msg.Subject, err)
+	}
+
+	if anon != nil {
+		err = anon.apply(msg)
+		if err != nil {
+			return fmt.Errorf("could not anonymize message on %s: %w", msg.Subject, err)
+		}
+	}
+
+	switch {
+	case c.vwRaw:
+		fmt.Println(string(msg.Data))
+	default:
+		var stream string
+		meta, err := jsm.ParseJSMsgMetadata(msg)
+		if err == nil {
+			stream = meta.Stream()
+			fmt.Printf("[%d] Subject: %s Received: %s\n", meta.StreamSequence(), msg.Subject, meta.TimeStamp().Format(time.RFC3339))
+			if c.vwShowAge || c.vwShowSize {
+				fmt.Printf("  %s\n", msgAnnotations(meta.TimeStamp(), len(msg.Data), msgHeaderSize(msg.Header), c.vwShowAge, c.vwShowSize))
+			}
+		} else {
+			fmt.Printf("Subject: %s Reply: %s\n", msg.Subject, msg.Reply)
+		}
+
+		if len(msg.Header) > 0 {
+			fmt.Println()
+			for k, vs := range msg.Header {
+				for _, v := range vs {
+					fmt.Printf("  %s: %s\n", k, v)
+				}
+			}
+		}
+
+		fmt.Println()
+		outPutDecodedMSGBody(msg.Data, c.vwTranslate, c.vwDecode, msg.Subject, stream)
+	}
+
+	return nil
+}
+
 func (c *streamCmd) sealAction(_ *fisk.ParseContext) error {
 	c.connectAndAskStream()
 
@@ -976,13 +1158,18 @@ func (c *streamCmd) reportAction(_ *fisk.ParseContext) error {
 	dg := dot.NewGraph(dot.Directed)
 	dg.Label("Stream Replication Structure")
 
-	missing, err := mgr.EachStream(filter, func(stream *jsm.Stream) {
+	streams, missing, err := loadStreams(mgr, filter, c.reportParallel)
+	if err != nil {
+		return err
+	}
+
+	for _, stream := range streams {
 		info, err := stream.LatestInformation()
 		fisk.FatalIfError(err, "could not get stream info for %s", stream.Name())
 
 		if info.Cluster != nil {
 			if c.reportLimitCluster != "" && info.Cluster.Name != c.reportLimitCluster {
-				return
+				continue
 			}
 
 			if info.Cluster.Leader != "" {
@@ -1011,6 +1198,7 @@ func (c *streamCmd) reportAction(_ *fisk.ParseContext) error {
 			Mirror:    info.Mirror,
 			Sources:   info.Sources,
 			Placement: info.Config.Placement,
+			Subjects:  info.Config.Subjects,
 		}
 		if info.State.Lost != nil {
 			s.LostBytes = info.State.Lost.Bytes
@@ -1049,15 +1237,10 @@ func (c *streamCmd) reportAction(_ *fisk.ParseContext) error {
 		}
 
 		stats = append(stats, s)
-	})
-	if err != nil {
-		return err
 	}
 
-	if len(stats) == 0 {
-		if !c.json {
-			fmt.Println("No Streams defined")
-		}
+	if len(stats) == 0 && !c.json {
+		fmt.Println("No Streams defined")
 		return nil
 	}
 
@@ -1073,7 +1256,22 @@ func (c *streamCmd) reportAction(_ *fisk.ParseContext) error {
 		sort.Slice(stats, func(i, j int) bool { return stats[i].Bytes < stats[j].Bytes })
 	}
 
-	c.renderStreams(stats)
+	if c.pushGateway != "" {
+		err = c.pushStreamMetrics(stats)
+		if err != nil {
+			return err
+		}
+	}
+
+	if c.json {
+		sort.Strings(missing)
+		return printJSON(streamReport{Streams: stats, Inaccessible: missing})
+	}
+
+	err = c.renderStreams(stats)
+	if err != nil {
+		return err
+	}
 
 	if showReplication {
 		c.renderReplication(stats)
@@ -1092,6 +1290,32 @@ func (c *streamCmd) reportAction(_ *fisk.ParseContext) error {
 	return nil
 }
 
+func (c *streamCmd) pushStreamMetrics(stats []streamStat) error {
+	msgs := newPushGauge("stream", "messages", "Number of messages stored in the stream", "stream")
+	size := newPushGauge("stream", "bytes", "Number of bytes stored in the stream", "stream")
+	consumers := newPushGauge("stream", "consumers", "Number of consumers on the stream", "stream")
+	lag := newPushGauge("stream", "source_lag", "Number of messages the stream is behind its mirror or source", "stream", "source", "kind")
+	active := newPushGauge("stream", "source_active_seconds", "Time since the mirror or source was last active", "stream", "source", "kind")
+
+	for _, s := range stats {
+		msgs.WithLabelValues(s.Name).Set(float64(s.Msgs))
+		size.WithLabelValues(s.Name).Set(float64(s.Bytes))
+		consumers.WithLabelValues(s.Name).Set(float64(s.Consumers))
+
+		if s.Mirror != nil {
+			lag.WithLabelValues(s.Name, s.Mirror.Name, "mirror").Set(float64(s.Mirror.Lag))
+			active.WithLabelValues(s.Name, s.Mirror.Name, "mirror").Set(s.Mirror.Active.Seconds())
+		}
+
+		for _, source := range s.Sources {
+			lag.WithLabelValues(s.Name, source.Name, "source").Set(float64(source.Lag))
+			active.WithLabelValues(s.Name, source.Name, "source").Set(source.Active.Seconds())
+		}
+	}
+
+	return pushMetrics(c.pushGateway, c.pushJob, msgs, size, consumers, lag, active)
+}
+
 func (c *streamCmd) renderReplication(stats []streamStat) {
 	table := newTableWriter("Replication Report")
 	table.AddHeaders("Stream", "Kind", "API Prefix", "Source Stream", "Active", "Lag", "Error")
@@ -1141,9 +1365,15 @@ func (c *streamCmd) renderReplication(stats []streamStat) {
 	fmt.Println(table.Render())
 }
 
-func (c *streamCmd) renderStreams(stats []streamStat) {
+func (c *streamCmd) renderStreams(stats []streamStat) error {
 	table := newTableWriter("Stream Report")
-	table.AddHeaders("Stream", "Storage", "Placement", "Consumers", "Messages", "Bytes", "Lost", "Deleted", "Replicas")
+	table.AddHeaders("Stream", "Storage", "Placement", "Consumers", "Messages", "Bytes", "Lost", "Deleted", "Replicas", "Subjects")
+	table.WideColumns("Subjects")
+	table.NarrowColumns("Stream", "Consumers", "Messages", "Bytes")
+	err := table.SelectColumns(c.reportColumns...)
+	if err != nil {
+		return err
+	}
 
 	for _, s := range stats {
 		lost := "0"
@@ -1161,16 +1391,18 @@ func (c *streamCmd) renderStreams(stats []streamStat) {
 			if s.LostMsgs > 0 {
 				lost = fmt.Sprintf("%d (%d)", s.LostMsgs, s.LostBytes)
 			}
-			table.AddRow(s.Name, s.Storage, placement, s.Consumers, s.Msgs, s.Bytes, lost, s.Deleted, renderCluster(s.Cluster))
+			table.AddRow(s.Name, s.Storage, placement, s.Consumers, s.Msgs, s.Bytes, lost, s.Deleted, renderCluster(s.Cluster), strings.Join(s.Subjects, ", "))
 		} else {
 			if s.LostMsgs > 0 {
 				lost = fmt.Sprintf("%s (%s)", humanize.Comma(int64(s.LostMsgs)), humanize.IBytes(s.LostBytes))
 			}
-			table.AddRow(s.Name, s.Storage, placement, s.Consumers, humanize.Comma(s.Msgs), humanize.IBytes(s.Bytes), lost, s.Deleted, renderCluster(s.Cluster))
+			table.AddRow(s.Name, s.Storage, placement, s.Consumers, humanize.Comma(s.Msgs), humanize.IBytes(s.Bytes), lost, s.Deleted, renderCluster(s.Cluster), strings.Join(s.Subjects, ", "))
 		}
 	}
 
 	fmt.Println(table.Render())
+
+	return nil
 }
 
 func (c *streamCmd) loadConfigFile(file string) (*api.StreamConfig, error) {
@@ -1470,6 +1702,7 @@ func (c *streamCmd) editAction(pc *fisk.ParseContext) error {
 	if c.dryRun {
 		os.Exit(1)
 	}
+	auditConfigChange(diff)
 
 	if !c.force {
 		ok, err := askConfirmation(fmt.Sprintf("Really edit Stream %s", c.stream), false)
@@ -1492,6 +1725,63 @@ func (c *streamCmd) editAction(pc *fisk.ParseContext) error {
 	return nil
 }
 
+func (c *streamCmd) diffAction(_ *fisk.ParseContext) error {
+	cfg, err := c.loadConfigFile(c.inputFile)
+	if err != nil {
+		return withExitCode(ExitValidation, fmt.Errorf("could not load %s: %w", c.inputFile, err))
+	}
+	cfg.Name = c.stream
+	normalizeStreamConfig(cfg)
+
+	c.connectAndAskStream()
+
+	str, err := c.loadStream(c.stream)
+	if err != nil {
+		return fmt.Errorf("could not load Stream %s: %w", c.stream, err)
+	}
+
+	diff := configDiff(str.Configuration(), *cfg)
+	if diff == "" {
+		fmt.Printf("Stream %s matches %s\n", c.stream, c.inputFile)
+		return nil
+	}
+
+	fmt.Printf("Differences (-live +%s):\n%s", c.inputFile, diff)
+
+	return withExitCode(ExitThreshold, fmt.Errorf("stream %s differs from %s", c.stream, c.inputFile))
+}
+
+// normalizeStreamConfig sets unset limits in cfg to the defaults the server would apply so that configuration
+// files that omit them can be compared to live Streams
+func normalizeStreamConfig(cfg *api.StreamConfig) {
+	for _, v := range []*int64{&cfg.MaxMsgs, &cfg.MaxBytes, &cfg.MaxMsgsPer} {
+		if *v == 0 {
+			*v = -1
+		}
+	}
+
+	if cfg.MaxConsumers == 0 {
+		cfg.MaxConsumers = -1
+	}
+	if cfg.MaxMsgSize == 0 {
+		cfg.MaxMsgSize = -1
+	}
+	if cfg.Replicas == 0 {
+		cfg.Replicas = 1
+	}
+
+	if cfg.Duplicates == 0 && cfg.Mirror == nil {
+		cfg.Duplicates = 2 * time.Minute
+		if cfg.MaxAge != 0 && cfg.MaxAge < cfg.Duplicates {
+			cfg.Duplicates = cfg.MaxAge
+		}
+	}
+
+	if len(cfg.Subjects) == 0 && cfg.Mirror == nil && len(cfg.Sources) == 0 {
+		cfg.Subjects = []string{cfg.Name}
+	}
+}
+
 func (c *streamCmd) cpAction(pc *fisk.ParseContext) error {
 	if c.stream == c.destination {
 		fisk.Fatalf("source and destination Stream names cannot be the same")
@@ -1894,6 +2184,26 @@ func (c *streamCmd) retentionPolicyFromString() api.RetentionPolicy {
 	}
 }
 
+// checkServerFeatures clears settings the connected server does not support, warning that they will be ignored
+func (c *streamCmd) checkServerFeatures(cfg *api.StreamConfig) {
+	version := serverVersion(c.nc)
+
+	if len(cfg.Metadata) > 0 && !serverSupports(version, featureMetadata) {
+		warnUnsupportedFeature(version, featureMetadata)
+		cfg.Metadata = nil
+	}
+
+	if cfg.Compression != api.NoCompression && !serverSupports(version, featureCompression) {
+		warnUnsupportedFeature(version, featureCompression)
+		cfg.Compression = api.NoCompression
+	}
+
+	if cfg.DiscardNewPer && !serverSupports(version, featureDiscardNewPerSubj) {
+		warnUnsupportedFeature(version, featureDiscardNewPerSubj)
+		cfg.DiscardNewPer = false
+	}
+}
+
 func (c *streamCmd) prepareConfig(_ *fisk.ParseContext, requireSize bool) api.StreamConfig {
 	var err error
 
@@ -2170,6 +2480,8 @@ func (c *streamCmd) prepareConfig(_ *fisk.ParseContext, requireSize bool) api.St
 		cfg.Metadata = c.metadata
 	}
 
+	c.checkServerFeatures(&cfg)
+
 	if c.placementCluster != "" || len(c.placementTags) > 0 {
 		cfg.Placement = &api.Placement{
 			Cluster: c.placementCluster,
@@ -2393,8 +2705,9 @@ func (c *streamCmd) validateCfg(cfg *api.StreamConfig) (bool, []byte, []string,
 }
 
 func (c *streamCmd) addAction(pc *fisk.ParseContext) (err error) {
-	_, mgr, err := prepareHelper("", natsOpts()...)
+	c.nc, c.mgr, err = prepareHelper("", natsOpts()...)
 	fisk.FatalIfError(err, "could not create Stream")
+	mgr := c.mgr
 
 	requireSize, _ := mgr.IsStreamMaxBytesRequired()
 
@@ -2427,8 +2740,12 @@ func (c *streamCmd) addAction(pc *fisk.ParseContext) (err error) {
 		return os.WriteFile(c.outFile, j, 0644)
 	}
 
+	auditConfigChange(cmp.Diff(api.StreamConfig{}, cfg))
+
 	str, err := mgr.NewStreamFromDefault(c.stream, cfg)
-	fisk.FatalIfError(err, "could not create Stream")
+	if err != nil {
+		return fmt.Errorf("could not create Stream: %w", err)
+	}
 
 	fmt.Printf("Stream %s was created\n\n", c.stream)
 
@@ -2475,10 +2792,27 @@ func (c *streamCmd) rmAction(_ *fisk.ParseContext) (err error) {
 }
 
 func (c *streamCmd) purgeAction(_ *fisk.ParseContext) (err error) {
+	if c.purgeSequence > 0 && c.purgeKeep > 0 {
+		return fmt.Errorf("sequence and keep cannot be combined when purghing")
+	}
+
+	var at time.Time
+	if c.purgeAt != "" {
+		at, err = parseScheduleTime(c.purgeAt, time.Now())
+		if err != nil {
+			return withExitCode(ExitValidation, err)
+		}
+	}
+
 	c.connectAndAskStream()
 
 	if !c.force {
-		ok, err := askConfirmation(fmt.Sprintf("Really purge Stream %s", c.stream), false)
+		prompt := fmt.Sprintf("Really purge Stream %s", c.stream)
+		if !at.IsZero() {
+			prompt = fmt.Sprintf("Really schedule a purge of Stream %s at %s", c.stream, at.Local().Format(time.RFC1123))
+		}
+
+		ok, err := askConfirmation(prompt, false)
 		fisk.FatalIfError(err, "could not obtain confirmation")
 
 		if !ok {
@@ -2489,11 +2823,19 @@ func (c *streamCmd) purgeAction(_ *fisk.ParseContext) (err error) {
 	stream, err := c.loadStream(c.stream)
 	fisk.FatalIfError(err, "could not purge Stream")
 
+	if !at.IsZero() {
+		return scheduleJob(&scheduledJob{
+			Action:   scheduleStreamPurge,
+			At:       at.UTC(),
+			Stream:   stream.Name(),
+			Subject:  c.purgeSubject,
+			Sequence: c.purgeSequence,
+			Keep:     c.purgeKeep,
+		})
+	}
+
 	var req *api.JSApiStreamPurgeRequest
 	if c.purgeKeep > 0 || c.purgeSubject != "" || c.purgeSequence > 0 {
-		if c.purgeSequence > 0 && c.purgeKeep > 0 {
-			return fmt.Errorf("sequence and keep cannot be combined when purghing")
-		}
 
 		req = &api.JSApiStreamPurgeRequest{
 			Sequence: c.purgeSequence,
@@ -2699,11 +3041,14 @@ func (c *streamCmd) getAction(_ *fisk.ParseContext) (err error) {
 	fisk.FatalIfError(err, "could not retrieve %s#%d", c.stream, c.msgID)
 
 	if c.json {
-		printJSON(item)
-		return nil
+		return printJSON(item)
 	}
 
-	fmt.Printf("Item: %s#%d received %v on Subject %s\n\n", c.stream, item.Sequence, item.Time, item.Subject)
+	fmt.Printf("Item: %s#%d received %v on Subject %s\n", c.stream, item.Sequence, item.Time, item.Subject)
+	if c.vwShowAge || c.vwShowSize {
+		fmt.Println(msgAnnotations(item.Time, len(item.Data), len(item.Header), c.vwShowAge, c.vwShowSize))
+	}
+	fmt.Println()
 
 	if len(item.Header) > 0 {
 		fmt.Println("Headers:")
@@ -2717,7 +3062,7 @@ func (c *streamCmd) getAction(_ *fisk.ParseContext) (err error) {
 		}
 		fmt.Println()
 	}
-	outPutMSGBody(item.Data, c.vwTranslate, item.Subject, c.stream)
+	outPutDecodedMSGBody(item.Data, c.vwTranslate, c.vwDecode, item.Subject, c.stream)
 	return nil
 }
 
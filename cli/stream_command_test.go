@@ -0,0 +1,49 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"testing"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nkeys"
+)
+
+func TestStreamRenderViewMsgWithoutMetadata(t *testing.T) {
+	c := &streamCmd{vwDecode: "json", vwShowAge: true}
+	msg := nats.NewMsg("orders.new")
+	msg.Data = []byte(`{"id":1}`)
+
+	assertNoError(t, c.renderViewMsg(msg, nil))
+}
+
+func TestStreamRenderViewMsgDecryptsBeforeDecompressing(t *testing.T) {
+	kp, err := nkeys.CreateCurveKeys()
+	checkErr(t, err, "key creation failed")
+	pub, _ := kp.PublicKey()
+
+	enc, err := newPayloadEncrypter(pub)
+	checkErr(t, err, "encrypter creation failed")
+
+	msg := nats.NewMsg("orders.new")
+	msg.Data = []byte("compressed and encrypted")
+	checkErr(t, compressPayload(msg, "zstd"), "compress failed")
+	checkErr(t, enc.encrypt(msg), "encrypt failed")
+
+	c := &streamCmd{vwRaw: true, vwDecrypter: kp}
+	assertNoError(t, c.renderViewMsg(msg, nil))
+	if string(msg.Data) != "compressed and encrypted" {
+		t.Fatalf("expected the payload to be decrypted and decompressed, got %q", msg.Data)
+	}
+}
@@ -0,0 +1,266 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/choria-io/fisk"
+	"github.com/dustin/go-humanize"
+	"github.com/nats-io/nats.go"
+)
+
+type streamDedupeCmd struct {
+	stream  string
+	window  time.Duration
+	subject string
+	top     int
+	json    bool
+}
+
+// dedupeReport summarizes the use of message IDs in a range of a stream
+type dedupeReport struct {
+	Stream      string           `json:"stream"`
+	Duplicates  time.Duration    `json:"duplicate_window"`
+	Start       time.Time        `json:"start"`
+	Window      time.Duration    `json:"window"`
+	Messages    uint64           `json:"messages"`
+	WithID      uint64           `json:"with_id"`
+	DuplicateID uint64           `json:"duplicate_ids"`
+	MaxGap      time.Duration    `json:"max_gap"`
+	Subjects    []*dedupeSubject `json:"subjects"`
+}
+
+// dedupeSubject is the message ID usage for a single subject
+type dedupeSubject struct {
+	Subject     string        `json:"subject"`
+	Messages    uint64        `json:"messages"`
+	WithID      uint64        `json:"with_id"`
+	DuplicateID uint64        `json:"duplicate_ids"`
+	MaxGap      time.Duration `json:"max_gap"`
+}
+
+func (s *dedupeSubject) duplicateRate() float64 {
+	if s.WithID == 0 {
+		return 0
+	}
+
+	return float64(s.DuplicateID) / float64(s.WithID) * 100
+}
+
+func configureStreamDedupeCommand(str *fisk.CmdClause) {
+	c := &streamDedupeCmd{}
+
+	help := `Reports on the use of message IDs for deduplication
+
+Scans the Nats-Msg-Id headers of messages stored within the duplicate
+window of the Stream, or a longer window, and reports per subject how
+many messages carry IDs and how many IDs were stored more than once.
+
+The server discards duplicates received within the duplicate window so
+stored duplicates were published again after the window passed, the
+largest gap between them is the window needed to catch them. Subjects
+with few IDs point to publishers that do not set message IDs at all.
+`
+
+	dedupe := str.Command("dedupe-report", help).Alias("dedupe").Action(c.reportAction)
+	dedupe.Arg("stream", "The Stream to analyze").Required().HintAction(streamNamesHint).StringVar(&c.stream)
+	dedupe.Flag("window", "Analyze messages received in this window, defaults to the duplicate window of the Stream").PlaceHolder("DURATION").DurationVar(&c.window)
+	dedupe.Flag("subject", "Limits the analysis to messages matching a subject").PlaceHolder("SUBJECT").StringVar(&c.subject)
+	dedupe.Flag("top", "Show only the top subjects by duplicates").Default("20").IntVar(&c.top)
+	addJSONOutputFlag(dedupe, &c.json)
+}
+
+func (c *streamDedupeCmd) reportAction(_ *fisk.ParseContext) error {
+	nc, mgr, err := prepareHelper("", natsOpts()...)
+	if err != nil {
+		return err
+	}
+
+	str, err := mgr.LoadStream(c.stream)
+	if err != nil {
+		return fmt.Errorf("could not load Stream %s: %w", c.stream, err)
+	}
+
+	report := &dedupeReport{
+		Stream:     str.Name(),
+		Duplicates: str.DuplicateWindow(),
+		Window:     c.window,
+	}
+	if report.Window <= 0 {
+		report.Window = report.Duplicates
+	}
+	if report.Window <= 0 {
+		return withExitCode(ExitValidation, fmt.Errorf("stream %s has no duplicate window, pass --window", c.stream))
+	}
+	report.Start = time.Now().Add(-report.Window)
+
+	if !c.json {
+		fmt.Printf("Analyzing message IDs in Stream %s received since %s\n\n", report.Stream, report.Start.Format(time.RFC1123))
+	}
+
+	err = c.scan(nc, report)
+	if err != nil {
+		return err
+	}
+
+	sort.Slice(report.Subjects, func(i, j int) bool {
+		a, b := report.Subjects[i], report.Subjects[j]
+		if a.DuplicateID != b.DuplicateID {
+			return a.DuplicateID > b.DuplicateID
+		}
+		return a.Subject < b.Subject
+	})
+
+	if c.json {
+		return printJSON(report)
+	}
+
+	c.render(report)
+
+	return nil
+}
+
+// scan reads the headers of all messages since the start of the report
+func (c *streamDedupeCmd) scan(nc *nats.Conn, report *dedupeReport) error {
+	js, err := nc.JetStream()
+	if err != nil {
+		return err
+	}
+
+	sub, err := js.SubscribeSync(c.subject, nats.BindStream(report.Stream), nats.OrderedConsumer(), nats.StartTime(report.Start), nats.HeadersOnly())
+	if err != nil {
+		return fmt.Errorf("could not read Stream %s: %w", report.Stream, err)
+	}
+	defer sub.Unsubscribe()
+
+	nfo, err := sub.ConsumerInfo()
+	if err != nil {
+		return err
+	}
+
+	// messages might have been pushed to the subscription already
+	if nfo.NumPending == 0 && nfo.Delivered.Consumer == 0 {
+		return nil
+	}
+
+	seen := map[string]time.Time{}
+	subjects := map[string]*dedupeSubject{}
+
+	for {
+		msg, err := sub.NextMsg(opts.Timeout)
+		if err != nil {
+			return fmt.Errorf("could not read Stream %s: %w", report.Stream, err)
+		}
+
+		meta, err := msg.Metadata()
+		if err != nil {
+			return err
+		}
+
+		subj, ok := subjects[msg.Subject]
+		if !ok {
+			subj = &dedupeSubject{Subject: msg.Subject}
+			subjects[msg.Subject] = subj
+			report.Subjects = append(report.Subjects, subj)
+		}
+
+		report.Messages++
+		subj.Messages++
+
+		id := msg.Header.Get(nats.MsgIdHdr)
+		if id != "" {
+			report.WithID++
+			subj.WithID++
+
+			if prev, ok := seen[id]; ok {
+				gap := meta.Timestamp.Sub(prev)
+				report.DuplicateID++
+				subj.DuplicateID++
+				if gap > report.MaxGap {
+					report.MaxGap = gap
+				}
+				if gap > subj.MaxGap {
+					subj.MaxGap = gap
+				}
+			}
+
+			seen[id] = meta.Timestamp
+		}
+
+		if meta.NumPending == 0 {
+			break
+		}
+	}
+
+	return nil
+}
+
+func (c *streamDedupeCmd) render(report *dedupeReport) {
+	if report.Messages == 0 {
+		fmt.Println("No messages found")
+		return
+	}
+
+	pct := func(v uint64, total uint64) string {
+		if total == 0 {
+			return "0%"
+		}
+		return fmt.Sprintf("%.1f%%", float64(v)/float64(total)*100)
+	}
+
+	gap := func(d time.Duration) string {
+		if d == 0 {
+			return ""
+		}
+		return humanizeDuration(d)
+	}
+
+	subjects := report.Subjects
+	if c.top > 0 && len(subjects) > c.top {
+		subjects = subjects[:c.top]
+	}
+
+	table := newTableWriter(fmt.Sprintf("Message ID usage for %d subjects", len(report.Subjects)))
+	table.AddHeaders("Subject", "Messages", "With ID", "Duplicate IDs", "Duplicate Rate", "Largest Gap")
+	for _, s := range subjects {
+		table.AddRow(s.Subject, humanize.Comma(int64(s.Messages)), pct(s.WithID, s.Messages), humanize.Comma(int64(s.DuplicateID)), fmt.Sprintf("%.1f%%", s.duplicateRate()), gap(s.MaxGap))
+	}
+	fmt.Println(table.Render())
+
+	fmt.Println("Summary:")
+	fmt.Println()
+	fmt.Printf("      Duplicate Window: %s\n", humanizeDuration(report.Duplicates))
+	fmt.Printf("       Analyzed Window: %s\n", humanizeDuration(report.Window))
+	fmt.Printf("              Messages: %s\n", humanize.Comma(int64(report.Messages)))
+	fmt.Printf("      With Message IDs: %s (%s)\n", humanize.Comma(int64(report.WithID)), pct(report.WithID, report.Messages))
+	fmt.Printf("  Stored Duplicate IDs: %s (%s)\n", humanize.Comma(int64(report.DuplicateID)), pct(report.DuplicateID, report.WithID))
+	if report.MaxGap > 0 {
+		fmt.Printf("   Largest Gap Between: %s\n", humanizeDuration(report.MaxGap))
+	}
+	fmt.Println()
+
+	switch {
+	case report.WithID == 0:
+		fmt.Println("No messages carry a message ID, deduplication is not used by publishers")
+	case report.MaxGap > report.Duplicates:
+		fmt.Printf("Duplicates were published up to %s apart, a duplicate window of at least %s would have discarded them\n", humanizeDuration(report.MaxGap), humanizeDuration(report.MaxGap.Round(time.Second)+time.Second))
+	case report.WithID < report.Messages:
+		fmt.Printf("%s messages do not carry a message ID and cannot be deduplicated\n", humanize.Comma(int64(report.Messages-report.WithID)))
+	default:
+		fmt.Println("No duplicates were stored")
+	}
+}
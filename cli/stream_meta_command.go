@@ -0,0 +1,207 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/choria-io/fisk"
+	"github.com/google/go-cmp/cmp"
+)
+
+type streamMetaCmd struct {
+	stream string
+	items  []string
+	force  bool
+	json   bool
+}
+
+func configureStreamMetaCommand(str *fisk.CmdClause) {
+	c := &streamMetaCmd{}
+
+	help := `Manages the metadata of a Stream
+
+Metadata keys are changed individually leaving other keys in place,
+unlike nats stream edit --metadata that replaces all metadata. Keys
+starting with _nats are managed by the server and cannot be changed.
+`
+
+	meta := str.Command("meta", help).Alias("metadata")
+
+	ls := meta.Command("ls", "Shows the metadata of a Stream").Alias("list").Action(c.lsAction)
+	ls.Arg("stream", "The Stream to show").Required().HintAction(streamNamesHint).StringVar(&c.stream)
+	addJSONOutputFlag(ls, &c.json)
+
+	set := mutating(meta.Command("set", "Sets metadata keys of a Stream").Action(c.setAction))
+	set.Arg("stream", "The Stream to update").Required().HintAction(streamNamesHint).StringVar(&c.stream)
+	set.Arg("metadata", "Metadata to set as key=value").Required().StringsVar(&c.items)
+	set.Flag("force", "Update without prompting").Short('f').UnNegatableBoolVar(&c.force)
+
+	del := mutating(meta.Command("del", "Removes metadata keys from a Stream").Alias("rm").Action(c.delAction))
+	del.Arg("stream", "The Stream to update").Required().HintAction(streamNamesHint).StringVar(&c.stream)
+	del.Arg("key", "Metadata keys to remove").Required().StringsVar(&c.items)
+	del.Flag("force", "Update without prompting").Short('f').UnNegatableBoolVar(&c.force)
+}
+
+// parseMetadataItems parses key=value pairs, when values is false only keys are expected
+func parseMetadataItems(items []string, values bool) (map[string]string, error) {
+	res := map[string]string{}
+
+	for _, item := range items {
+		k, v := item, ""
+		if values {
+			var ok bool
+			k, v, ok = strings.Cut(item, "=")
+			if !ok {
+				return nil, fmt.Errorf("invalid metadata %q, expected key=value", item)
+			}
+		}
+
+		k = strings.TrimSpace(k)
+		if k == "" {
+			return nil, fmt.Errorf("invalid metadata %q, keys cannot be empty", item)
+		}
+		if strings.HasPrefix(k, "_nats") {
+			return nil, fmt.Errorf("metadata key %s is managed by the server", k)
+		}
+
+		res[k] = v
+	}
+
+	return res, nil
+}
+
+func (c *streamMetaCmd) lsAction(_ *fisk.ParseContext) error {
+	_, mgr, err := prepareHelper("", natsOpts()...)
+	if err != nil {
+		return err
+	}
+
+	str, err := mgr.LoadStream(c.stream)
+	if err != nil {
+		return fmt.Errorf("could not load Stream %s: %w", c.stream, err)
+	}
+
+	meta := str.Configuration().Metadata
+	if meta == nil {
+		meta = map[string]string{}
+	}
+
+	if c.json {
+		return printJSON(meta)
+	}
+
+	if len(meta) == 0 {
+		fmt.Printf("Stream %s has no metadata\n", str.Name())
+		return nil
+	}
+
+	var keys []string
+	for k := range meta {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	table := newTableWriter(fmt.Sprintf("Metadata for Stream %s", str.Name()))
+	table.AddHeaders("Key", "Value")
+	for _, k := range keys {
+		table.AddRow(k, meta[k])
+	}
+	fmt.Println(table.Render())
+
+	return nil
+}
+
+func (c *streamMetaCmd) setAction(_ *fisk.ParseContext) error {
+	items, err := parseMetadataItems(c.items, true)
+	if err != nil {
+		return withExitCode(ExitValidation, err)
+	}
+
+	return c.update(func(meta map[string]string) {
+		for k, v := range items {
+			meta[k] = v
+		}
+	})
+}
+
+func (c *streamMetaCmd) delAction(_ *fisk.ParseContext) error {
+	items, err := parseMetadataItems(c.items, false)
+	if err != nil {
+		return withExitCode(ExitValidation, err)
+	}
+
+	return c.update(func(meta map[string]string) {
+		for k := range items {
+			delete(meta, k)
+		}
+	})
+}
+
+// update applies changes to a copy of the metadata and updates the stream when it changed
+func (c *streamMetaCmd) update(change func(map[string]string)) error {
+	_, mgr, err := prepareHelper("", natsOpts()...)
+	if err != nil {
+		return err
+	}
+
+	str, err := mgr.LoadStream(c.stream)
+	if err != nil {
+		return fmt.Errorf("could not load Stream %s: %w", c.stream, err)
+	}
+
+	cfg := str.Configuration()
+	current := map[string]string{}
+	meta := map[string]string{}
+	for k, v := range cfg.Metadata {
+		current[k] = v
+		meta[k] = v
+	}
+
+	change(meta)
+
+	diff := cmp.Diff(current, meta)
+	if diff == "" {
+		fmt.Println("No difference in metadata")
+		return nil
+	}
+
+	fmt.Printf("Differences (-old +new):\n%s", diff)
+	auditConfigChange(diff)
+
+	if !c.force {
+		ok, err := askConfirmation(fmt.Sprintf("Really update the metadata of Stream %s", str.Name()), false)
+		fisk.FatalIfError(err, "could not obtain confirmation")
+
+		if !ok {
+			return nil
+		}
+	}
+
+	cfg.Metadata = meta
+	if len(meta) == 0 {
+		cfg.Metadata = nil
+	}
+
+	err = str.UpdateConfiguration(cfg)
+	if err != nil {
+		return fmt.Errorf("could not update Stream %s: %w", str.Name(), err)
+	}
+
+	fmt.Printf("Stream %s metadata was updated\n", str.Name())
+
+	return nil
+}
@@ -0,0 +1,46 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseMetadataItems(t *testing.T) {
+	items, err := parseMetadataItems([]string{"owner=ops", "url=http://x?a=b", "empty="}, true)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expect := map[string]string{"owner": "ops", "url": "http://x?a=b", "empty": ""}
+	if !reflect.DeepEqual(items, expect) {
+		t.Fatalf("unexpected items: %v", items)
+	}
+
+	items, err = parseMetadataItems([]string{"owner", "team"}, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(items) != 2 {
+		t.Fatalf("unexpected items: %v", items)
+	}
+
+	for _, bad := range []string{"owner", "=ops", "_nats.level=1"} {
+		_, err = parseMetadataItems([]string{bad}, true)
+		if err == nil {
+			t.Fatalf("expected %q to fail", bad)
+		}
+	}
+}
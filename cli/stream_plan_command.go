@@ -0,0 +1,373 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/choria-io/fisk"
+	"github.com/dustin/go-humanize"
+	"github.com/nats-io/jsm.go/api"
+)
+
+type streamPlanCmd struct {
+	stream      string
+	rate        string
+	size        string
+	retention   string
+	replicas    int
+	maxBytes    string
+	maxMsgs     int64
+	sample      time.Duration
+	json        bool
+	rateSet     bool
+	sizeSet     bool
+	retSet      bool
+	replicasSet bool
+	maxBytesSet bool
+	maxMsgsSet  bool
+}
+
+// streamPlan is a capacity projection for a stream with a steady ingest rate
+type streamPlan struct {
+	Stream       string            `json:"stream,omitempty"`
+	Rate         float64           `json:"rate"`
+	Size         float64           `json:"message_size"`
+	MaxAge       time.Duration     `json:"max_age"`
+	MaxBytes     int64             `json:"max_bytes"`
+	MaxMsgs      int64             `json:"max_msgs"`
+	Replicas     int               `json:"replicas"`
+	Discard      api.DiscardPolicy `json:"discard"`
+	CurrentMsgs  uint64            `json:"current_messages"`
+	CurrentBytes uint64            `json:"current_bytes"`
+
+	SteadyMsgs      float64        `json:"steady_messages"`
+	SteadyBytes     float64        `json:"steady_bytes"`
+	Unbounded       bool           `json:"unbounded"`
+	EffectiveAge    time.Duration  `json:"effective_age,omitempty"`
+	MaxBytesReached *time.Duration `json:"max_bytes_reached,omitempty"`
+	MaxMsgsReached  *time.Duration `json:"max_msgs_reached,omitempty"`
+	Projection      []planPoint    `json:"projection"`
+}
+
+type planPoint struct {
+	Period  time.Duration `json:"period"`
+	Msgs    float64       `json:"messages"`
+	Bytes   float64       `json:"bytes"`
+	Cluster float64       `json:"cluster_bytes"`
+}
+
+var rateParser = regexp.MustCompile(`^([\d.]+)\s*([kKmMgG]?)(?:/(s|m|h|d))?$`)
+
+// parseRate parses rates like 5k/s, 300/m or 1M/h into messages per second, the period defaults to seconds
+func parseRate(rate string) (float64, error) {
+	matches := rateParser.FindStringSubmatch(strings.TrimSpace(rate))
+	if matches == nil {
+		return 0, fmt.Errorf("invalid rate %q, expected a rate like 5k/s", rate)
+	}
+
+	n, err := strconv.ParseFloat(matches[1], 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid rate %q: %w", rate, err)
+	}
+
+	switch strings.ToUpper(matches[2]) {
+	case "K":
+		n *= 1000
+	case "M":
+		n *= 1000 * 1000
+	case "G":
+		n *= 1000 * 1000 * 1000
+	}
+
+	switch matches[3] {
+	case "m":
+		n /= 60
+	case "h":
+		n /= 3600
+	case "d":
+		n /= 86400
+	}
+
+	return n, nil
+}
+
+func configureStreamPlanCommand(str *fisk.CmdClause) {
+	c := &streamPlanCmd{}
+
+	help := `Projects storage growth and capacity requirements for a Stream
+
+Without a Stream the ingest rate and message size have to be given,
+with a Stream its ingest is sampled and its configured limits are used,
+any limit given on the command line overrides the Stream configuration.
+`
+
+	plan := str.Command("plan", help).Action(c.planAction)
+	plan.Arg("stream", "Samples the ingest and limits of a Stream").HintAction(streamNamesHint).StringVar(&c.stream)
+	plan.Flag("rate", "Messages received per period like 5k/s, 300/m or 1M/h").IsSetByUser(&c.rateSet).StringVar(&c.rate)
+	plan.Flag("size", "Average message size like 2KB").IsSetByUser(&c.sizeSet).StringVar(&c.size)
+	plan.Flag("retention", "Maximum age of messages like 7d").IsSetByUser(&c.retSet).PlaceHolder("DURATION").StringVar(&c.retention)
+	plan.Flag("replicas", "Number of replicas").Default("1").IsSetByUser(&c.replicasSet).IntVar(&c.replicas)
+	plan.Flag("max-bytes", "Maximum size of the Stream like 1TB").IsSetByUser(&c.maxBytesSet).StringVar(&c.maxBytes)
+	plan.Flag("max-msgs", "Maximum number of messages in the Stream").Default("-1").IsSetByUser(&c.maxMsgsSet).Int64Var(&c.maxMsgs)
+	plan.Flag("sample", "How long to sample the ingest of a Stream").Default("10s").DurationVar(&c.sample)
+	addJSONOutputFlag(plan, &c.json)
+}
+
+func (c *streamPlanCmd) planAction(_ *fisk.ParseContext) error {
+	plan := &streamPlan{Stream: c.stream, MaxBytes: -1, MaxMsgs: -1, Replicas: 1}
+
+	if c.stream != "" {
+		err := c.sampleStream(plan)
+		if err != nil {
+			return err
+		}
+	} else if !c.rateSet || !c.sizeSet {
+		return withExitCode(ExitValidation, fmt.Errorf("--rate and --size are required when not sampling a Stream"))
+	}
+
+	var err error
+	if c.rateSet {
+		plan.Rate, err = parseRate(c.rate)
+		if err != nil {
+			return withExitCode(ExitValidation, err)
+		}
+	}
+	if c.sizeSet {
+		size, err := parseStringAsBytes(c.size)
+		if err != nil || size <= 0 {
+			return withExitCode(ExitValidation, fmt.Errorf("invalid message size %q", c.size))
+		}
+		plan.Size = float64(size)
+	}
+	if c.retSet {
+		plan.MaxAge, err = parseDurationString(c.retention)
+		if err != nil {
+			return withExitCode(ExitValidation, fmt.Errorf("invalid retention: %w", err))
+		}
+	}
+	if c.replicasSet {
+		plan.Replicas = c.replicas
+	}
+	if c.maxBytesSet {
+		plan.MaxBytes, err = parseStringAsBytes(c.maxBytes)
+		if err != nil {
+			return withExitCode(ExitValidation, err)
+		}
+	}
+	if c.maxMsgsSet {
+		plan.MaxMsgs = c.maxMsgs
+	}
+
+	plan.calculate()
+
+	if c.json {
+		return printJSON(plan)
+	}
+
+	c.render(plan)
+
+	return nil
+}
+
+// sampleStream measures the ingest rate and average message size of a stream and loads its limits
+func (c *streamPlanCmd) sampleStream(plan *streamPlan) error {
+	_, mgr, err := prepareHelper("", natsOpts()...)
+	if err != nil {
+		return err
+	}
+
+	str, err := mgr.LoadStream(c.stream)
+	if err != nil {
+		return fmt.Errorf("could not load Stream %s: %w", c.stream, err)
+	}
+
+	start, err := str.Information()
+	if err != nil {
+		return err
+	}
+
+	if !c.json && !c.rateSet {
+		fmt.Printf("Sampling ingest of Stream %s for %v\n\n", c.stream, c.sample)
+	}
+
+	nfo := start
+	if !c.rateSet {
+		started := time.Now()
+		time.Sleep(c.sample)
+
+		nfo, err = str.Information()
+		if err != nil {
+			return err
+		}
+
+		plan.Rate = float64(nfo.State.LastSeq-start.State.LastSeq) / time.Since(started).Seconds()
+	}
+
+	cfg := nfo.Config
+	plan.MaxAge = cfg.MaxAge
+	plan.MaxBytes = cfg.MaxBytes
+	plan.MaxMsgs = cfg.MaxMsgs
+	plan.Replicas = cfg.Replicas
+	plan.Discard = cfg.Discard
+	plan.CurrentMsgs = nfo.State.Msgs
+	plan.CurrentBytes = nfo.State.Bytes
+
+	if nfo.State.Msgs > 0 {
+		plan.Size = float64(nfo.State.Bytes) / float64(nfo.State.Msgs)
+	}
+
+	if plan.Size == 0 && !c.sizeSet {
+		return withExitCode(ExitValidation, fmt.Errorf("stream %s is empty, pass --size to give the average message size", c.stream))
+	}
+
+	return nil
+}
+
+// at projects the messages and bytes stored after d
+func (p *streamPlan) at(d time.Duration) (float64, float64) {
+	msgs := float64(p.CurrentMsgs) + p.Rate*d.Seconds()
+	if p.MaxAge > 0 {
+		msgs = math.Min(msgs, p.Rate*p.MaxAge.Seconds())
+	}
+	if p.MaxMsgs > 0 {
+		msgs = math.Min(msgs, float64(p.MaxMsgs))
+	}
+	if p.MaxBytes > 0 {
+		msgs = math.Min(msgs, float64(p.MaxBytes)/p.Size)
+	}
+
+	return msgs, msgs * p.Size
+}
+
+func (p *streamPlan) calculate() {
+	byteRate := p.Rate * p.Size
+
+	switch {
+	case p.Rate == 0:
+		p.SteadyMsgs, p.SteadyBytes = float64(p.CurrentMsgs), float64(p.CurrentBytes)
+	case p.MaxAge == 0 && p.MaxMsgs <= 0 && p.MaxBytes <= 0:
+		p.Unbounded = true
+	default:
+		p.SteadyMsgs, p.SteadyBytes = p.at(100 * 365 * 24 * time.Hour)
+	}
+
+	// time until a limit is reached when it is lower than what the max age would hold
+	reached := func(limit float64, current float64, rate float64) *time.Duration {
+		if rate <= 0 || limit <= 0 {
+			return nil
+		}
+		if p.MaxAge > 0 && rate*p.MaxAge.Seconds() <= limit {
+			return nil
+		}
+
+		d := time.Duration(math.Max(0, (limit-current)/rate) * float64(time.Second))
+		return &d
+	}
+
+	p.MaxBytesReached = reached(float64(p.MaxBytes), float64(p.CurrentBytes), byteRate)
+	p.MaxMsgsReached = reached(float64(p.MaxMsgs), float64(p.CurrentMsgs), p.Rate)
+
+	if p.Rate > 0 && !p.Unbounded && (p.MaxBytesReached != nil || p.MaxMsgsReached != nil) {
+		p.EffectiveAge = time.Duration(p.SteadyMsgs / p.Rate * float64(time.Second))
+	}
+
+	for _, d := range []time.Duration{time.Hour, 24 * time.Hour, 7 * 24 * time.Hour, 30 * 24 * time.Hour, 90 * 24 * time.Hour, 365 * 24 * time.Hour} {
+		msgs, bytes := p.at(d)
+		p.Projection = append(p.Projection, planPoint{Period: d, Msgs: msgs, Bytes: bytes, Cluster: bytes * float64(p.Replicas)})
+	}
+}
+
+func (c *streamPlanCmd) render(p *streamPlan) {
+	limit := func(v int64, bytes bool) string {
+		switch {
+		case v <= 0:
+			return "unlimited"
+		case bytes:
+			return humanize.IBytes(uint64(v))
+		default:
+			return humanize.Comma(v)
+		}
+	}
+
+	if p.Stream != "" {
+		fmt.Printf("Capacity plan for Stream %s\n\n", p.Stream)
+	} else {
+		fmt.Println("Capacity plan")
+		fmt.Println()
+	}
+
+	fmt.Println("Ingest:")
+	fmt.Println()
+	fmt.Printf("          Messages: %s/s, %s/day\n", humanize.CommafWithDigits(p.Rate, 2), humanize.Comma(int64(p.Rate*86400)))
+	fmt.Printf("      Message Size: %s\n", humanize.IBytes(uint64(p.Size)))
+	fmt.Printf("         Data Rate: %s/s, %s/day\n", humanize.IBytes(uint64(p.Rate*p.Size)), humanize.IBytes(uint64(p.Rate*p.Size*86400)))
+	fmt.Println()
+	fmt.Println("Limits:")
+	fmt.Println()
+	if p.MaxAge > 0 {
+		fmt.Printf("           Max Age: %s\n", humanizeDuration(p.MaxAge))
+	} else {
+		fmt.Printf("           Max Age: unlimited\n")
+	}
+	fmt.Printf("         Max Bytes: %s\n", limit(p.MaxBytes, true))
+	fmt.Printf("      Max Messages: %s\n", limit(p.MaxMsgs, false))
+	fmt.Printf("          Replicas: %d\n", p.Replicas)
+	fmt.Println()
+
+	fmt.Println("Steady State:")
+	fmt.Println()
+	if p.Unbounded {
+		fmt.Println("    Storage grows without limit, set a retention or size limit")
+	} else {
+		fmt.Printf("          Messages: %s\n", humanize.Comma(int64(p.SteadyMsgs)))
+		fmt.Printf("  Per Server Store: %s\n", humanize.IBytes(uint64(p.SteadyBytes)))
+		fmt.Printf("     Cluster Store: %s\n", humanize.IBytes(uint64(p.SteadyBytes*float64(p.Replicas))))
+	}
+	if p.MaxBytesReached != nil {
+		fmt.Printf("   Max Bytes After: %s\n", humanizeDuration(*p.MaxBytesReached))
+	}
+	if p.MaxMsgsReached != nil {
+		fmt.Printf("Max Messages After: %s\n", humanizeDuration(*p.MaxMsgsReached))
+	}
+	if p.EffectiveAge > 0 {
+		if p.Discard == api.DiscardNew {
+			fmt.Println()
+			fmt.Println("    New messages will be rejected once limits are reached")
+		} else {
+			fmt.Printf("     Effective Age: %s, older messages are discarded by limits\n", humanizeDuration(p.EffectiveAge))
+		}
+	}
+	fmt.Println()
+
+	table := newTableWriter("Projected Storage")
+	table.AddHeaders("After", "Messages", "Per Server", "Cluster")
+	for _, point := range p.Projection {
+		period := "1 hour"
+		switch days := int(point.Period.Hours() / 24); {
+		case days == 1:
+			period = "1 day"
+		case days > 1:
+			period = fmt.Sprintf("%d days", days)
+		}
+
+		table.AddRow(period, humanize.Comma(int64(point.Msgs)), humanize.IBytes(uint64(point.Bytes)), humanize.IBytes(uint64(point.Cluster)))
+	}
+	fmt.Println(table.Render())
+}
@@ -0,0 +1,221 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/choria-io/fisk"
+	"github.com/dustin/go-humanize"
+	"github.com/nats-io/nats.go"
+)
+
+type streamRateCmd struct {
+	stream    string
+	duration  time.Duration
+	subject   string
+	bySubject bool
+	top       int
+	json      bool
+}
+
+// streamRate is the ingest rate of a stream measured over a sample period
+type streamRate struct {
+	Stream      string               `json:"stream"`
+	Duration    time.Duration        `json:"duration"`
+	Messages    uint64               `json:"messages"`
+	Bytes       uint64               `json:"bytes"`
+	MsgsPerSec  float64              `json:"msgs_per_second"`
+	BytesPerSec float64              `json:"bytes_per_second"`
+	Subjects    []*streamSubjectRate `json:"subjects,omitempty"`
+}
+
+// streamSubjectRate is the ingest rate of a single subject
+type streamSubjectRate struct {
+	Subject     string  `json:"subject"`
+	Messages    uint64  `json:"messages"`
+	Bytes       uint64  `json:"bytes"`
+	MsgsPerSec  float64 `json:"msgs_per_second"`
+	BytesPerSec float64 `json:"bytes_per_second"`
+}
+
+func configureStreamRateCommand(str *fisk.CmdClause) {
+	c := &streamRateCmd{}
+
+	help := `Measures the rate messages are stored in a Stream
+
+Stream information only shows cumulative totals, this samples the Stream
+for a period and reports messages and bytes per second. The message rate
+is taken from the sequence numbers assigned by the Stream while sizes and
+subjects are taken from the headers of new messages as they are stored.
+
+Press ^C to end sampling early.
+`
+
+	rate := str.Command("rate", help).Action(c.rateAction)
+	rate.Arg("stream", "The Stream to sample").Required().HintAction(streamNamesHint).StringVar(&c.stream)
+	rate.Flag("duration", "How long to sample the Stream for").Default("30s").DurationVar(&c.duration)
+	rate.Flag("subject", "Limits sampling to messages matching a subject").PlaceHolder("SUBJECT").StringVar(&c.subject)
+	rate.Flag("by-subject", "Shows the rate of the busiest subjects").UnNegatableBoolVar(&c.bySubject)
+	rate.Flag("top", "Number of subjects to show with --by-subject").Default("10").IntVar(&c.top)
+	addJSONOutputFlag(rate, &c.json)
+}
+
+func (c *streamRateCmd) rateAction(_ *fisk.ParseContext) error {
+	if c.duration <= 0 {
+		return withExitCode(ExitValidation, fmt.Errorf("duration has to be positive"))
+	}
+
+	nc, mgr, err := prepareHelper("", natsOpts()...)
+	if err != nil {
+		return err
+	}
+
+	str, err := mgr.LoadStream(c.stream)
+	if err != nil {
+		return fmt.Errorf("could not load Stream %s: %w", c.stream, err)
+	}
+
+	js, err := nc.JetStream()
+	if err != nil {
+		return err
+	}
+
+	var mu sync.Mutex
+	report := &streamRate{Stream: str.Name()}
+	subjects := map[string]*streamSubjectRate{}
+
+	sub, err := js.Subscribe(c.subject, func(m *nats.Msg) {
+		size, _ := strconv.ParseUint(m.Header.Get(nats.MsgSize), 10, 64)
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		report.Bytes += size
+		ss, ok := subjects[m.Subject]
+		if !ok {
+			ss = &streamSubjectRate{Subject: m.Subject}
+			subjects[m.Subject] = ss
+		}
+		ss.Messages++
+		ss.Bytes += size
+	}, nats.BindStream(str.Name()), nats.OrderedConsumer(), nats.DeliverNew(), nats.HeadersOnly())
+	if err != nil {
+		return fmt.Errorf("could not read Stream %s: %w", str.Name(), err)
+	}
+	defer sub.Unsubscribe()
+
+	before, err := str.State()
+	if err != nil {
+		return err
+	}
+	start := time.Now()
+
+	if !c.json {
+		fmt.Printf("Sampling Stream %s for %s\n", str.Name(), humanizeDuration(c.duration))
+	}
+
+	timeout, cancel := context.WithTimeout(ctx, c.duration)
+	defer cancel()
+
+	ic := make(chan os.Signal, 1)
+	signal.Notify(ic, os.Interrupt)
+	defer signal.Stop(ic)
+
+	select {
+	case <-ic:
+	case <-timeout.Done():
+	}
+
+	after, err := str.State()
+	if err != nil {
+		return err
+	}
+	elapsed := time.Since(start)
+
+	sub.Unsubscribe()
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	report.Duration = elapsed
+	seconds := elapsed.Seconds()
+
+	// sequences count every stored message even when limits remove them before they are delivered
+	if c.subject == "" && after.LastSeq >= before.LastSeq {
+		report.Messages = after.LastSeq - before.LastSeq
+	} else {
+		for _, ss := range subjects {
+			report.Messages += ss.Messages
+		}
+	}
+	report.MsgsPerSec = float64(report.Messages) / seconds
+	report.BytesPerSec = float64(report.Bytes) / seconds
+
+	if c.bySubject {
+		report.Subjects = []*streamSubjectRate{}
+		for _, ss := range subjects {
+			ss.MsgsPerSec = float64(ss.Messages) / seconds
+			ss.BytesPerSec = float64(ss.Bytes) / seconds
+			report.Subjects = append(report.Subjects, ss)
+		}
+
+		sort.Slice(report.Subjects, func(i, j int) bool {
+			a, b := report.Subjects[i], report.Subjects[j]
+			if a.Messages != b.Messages {
+				return a.Messages > b.Messages
+			}
+			return a.Subject < b.Subject
+		})
+
+		if c.top > 0 && len(report.Subjects) > c.top {
+			report.Subjects = report.Subjects[:c.top]
+		}
+	}
+
+	if c.json {
+		return printJSON(report)
+	}
+
+	c.render(report, len(subjects))
+
+	return nil
+}
+
+func (c *streamRateCmd) render(report *streamRate, subjects int) {
+	fmt.Println()
+
+	if c.bySubject && len(report.Subjects) > 0 {
+		table := newTableWriter(fmt.Sprintf("Ingest rate for %d of %d subjects", len(report.Subjects), subjects))
+		table.AddHeaders("Subject", "Messages", "Msgs/sec", "Bytes", "Bytes/sec")
+		for _, s := range report.Subjects {
+			table.AddRow(s.Subject, humanize.Comma(int64(s.Messages)), fmt.Sprintf("%.1f", s.MsgsPerSec), humanize.IBytes(s.Bytes), humanize.IBytes(uint64(s.BytesPerSec)))
+		}
+		fmt.Println(table.Render())
+	}
+
+	fmt.Printf("Ingest rate for Stream %s over %s:\n", report.Stream, humanizeDuration(report.Duration.Round(time.Millisecond)))
+	fmt.Println()
+	fmt.Printf("    Messages: %s\n", humanize.Comma(int64(report.Messages)))
+	fmt.Printf("    Msgs/sec: %.1f\n", report.MsgsPerSec)
+	fmt.Printf("       Bytes: %s\n", humanize.IBytes(report.Bytes))
+	fmt.Printf("   Bytes/sec: %s\n", humanize.IBytes(uint64(report.BytesPerSec)))
+}
@@ -0,0 +1,302 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/choria-io/fisk"
+	"github.com/dustin/go-humanize"
+	"github.com/nats-io/nats.go"
+)
+
+type streamSourcesCmd struct {
+	stream   string
+	duration time.Duration
+	headers  []string
+	account  string
+	top      int
+	json     bool
+}
+
+// streamSourcesReport attributes messages stored in a stream during a sample period to their publishers
+type streamSourcesReport struct {
+	Stream      string                          `json:"stream"`
+	Duration    time.Duration                   `json:"duration"`
+	Messages    uint64                          `json:"messages"`
+	Subjects    []*streamSourceCount            `json:"subjects"`
+	Headers     []*streamSourceCount            `json:"headers"`
+	Values      map[string][]*streamSourceCount `json:"header_values,omitempty"`
+	Connections []*streamSourceConnection       `json:"connections,omitempty"`
+}
+
+type streamSourceCount struct {
+	Name     string `json:"name"`
+	Messages uint64 `json:"messages"`
+}
+
+// streamSourceConnection is a client connection in the stream account that published during the sample period
+type streamSourceConnection struct {
+	Server   string `json:"server"`
+	CID      uint64 `json:"cid"`
+	Name     string `json:"name,omitempty"`
+	IP       string `json:"ip"`
+	Account  string `json:"account,omitempty"`
+	User     string `json:"user,omitempty"`
+	Messages int64  `json:"messages"`
+}
+
+func configureStreamReportSourcesCommand(str *fisk.CmdClause) {
+	c := &streamSourcesCmd{}
+
+	help := `Reports on the publishers of messages stored in a Stream
+
+Samples new messages stored in a Stream and reports the subjects and
+headers seen, optionally reporting the values of specific headers that
+identify publishers, like client names or application IDs.
+
+With system access and --account the connections in the account that
+published messages during the sample period are listed, these include
+publishes to any subject so should be correlated with the subjects seen.
+
+Press ^C to end sampling early.
+`
+
+	sources := str.Command("report-sources", help).Alias("report-publishers").Action(c.sourcesAction)
+	sources.Arg("stream", "The Stream to sample").Required().HintAction(streamNamesHint).StringVar(&c.stream)
+	sources.Flag("duration", "How long to sample the Stream for").Default("1m").DurationVar(&c.duration)
+	sources.Flag("header", "Reports the values of a header identifying publishers (pass multiple times)").PlaceHolder("HEADER").StringsVar(&c.headers)
+	sources.Flag("account", "Lists publishing connections in this account, requires system access").StringVar(&c.account)
+	sources.Flag("top", "Number of entries to show in each section").Default("10").IntVar(&c.top)
+	addJSONOutputFlag(sources, &c.json)
+}
+
+func (c *streamSourcesCmd) snapshotConnections(nc *nats.Conn) (map[string]connInfo, error) {
+	finder := &SrvConnectionCmd{account: c.account}
+	conns, err := finder.findConnections(nc)
+	if err != nil {
+		return nil, err
+	}
+
+	res := map[string]connInfo{}
+	for _, conn := range conns {
+		res[fmt.Sprintf("%s:%d", conn.Info.ID, conn.Cid)] = conn
+	}
+
+	return res, nil
+}
+
+func sortedSourceCounts(counts map[string]uint64, top int) []*streamSourceCount {
+	var res []*streamSourceCount
+	for k, v := range counts {
+		res = append(res, &streamSourceCount{Name: k, Messages: v})
+	}
+
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Messages == res[j].Messages {
+			return res[i].Name < res[j].Name
+		}
+		return res[i].Messages > res[j].Messages
+	})
+
+	if top > 0 && len(res) > top {
+		res = res[:top]
+	}
+
+	return res
+}
+
+func (c *streamSourcesCmd) sourcesAction(_ *fisk.ParseContext) error {
+	if c.duration <= 0 {
+		return withExitCode(ExitValidation, fmt.Errorf("duration has to be positive"))
+	}
+
+	nc, mgr, err := prepareHelper("", natsOpts()...)
+	if err != nil {
+		return err
+	}
+
+	str, err := mgr.LoadStream(c.stream)
+	if err != nil {
+		return fmt.Errorf("could not load Stream %s: %w", c.stream, err)
+	}
+
+	js, err := nc.JetStream()
+	if err != nil {
+		return err
+	}
+
+	var before map[string]connInfo
+	if c.account != "" {
+		before, err = c.snapshotConnections(nc)
+		if err != nil {
+			return fmt.Errorf("could not list connections: %w", err)
+		}
+	}
+
+	var mu sync.Mutex
+	report := &streamSourcesReport{Stream: str.Name(), Values: map[string][]*streamSourceCount{}}
+	subjects := map[string]uint64{}
+	headers := map[string]uint64{}
+	values := map[string]map[string]uint64{}
+	for _, h := range c.headers {
+		values[h] = map[string]uint64{}
+	}
+
+	sub, err := js.Subscribe("", func(m *nats.Msg) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		report.Messages++
+		subjects[m.Subject]++
+
+		for h := range m.Header {
+			if h == nats.MsgSize {
+				continue
+			}
+			headers[h]++
+		}
+
+		for _, h := range c.headers {
+			v := m.Header.Get(h)
+			if v == "" {
+				v = "(not set)"
+			}
+			values[h][v]++
+		}
+	}, nats.BindStream(str.Name()), nats.OrderedConsumer(), nats.DeliverNew(), nats.HeadersOnly())
+	if err != nil {
+		return fmt.Errorf("could not read Stream %s: %w", str.Name(), err)
+	}
+
+	if !c.json {
+		fmt.Printf("Sampling publishers to Stream %s for %s, press ^C to end early\n\n", str.Name(), humanizeDuration(c.duration))
+	}
+
+	start := time.Now()
+	sctx, cancel := context.WithTimeout(ctx, c.duration)
+	defer cancel()
+
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(sigs)
+
+	select {
+	case <-sctx.Done():
+	case <-sigs:
+	}
+
+	sub.Unsubscribe()
+	report.Duration = time.Since(start)
+
+	if c.account != "" {
+		after, err := c.snapshotConnections(nc)
+		if err != nil {
+			return fmt.Errorf("could not list connections: %w", err)
+		}
+
+		for id, conn := range after {
+			delta := conn.InMsgs
+			if prev, ok := before[id]; ok {
+				delta -= prev.InMsgs
+			}
+			if delta <= 0 {
+				continue
+			}
+
+			report.Connections = append(report.Connections, &streamSourceConnection{
+				Server:   conn.Info.Name,
+				CID:      conn.Cid,
+				Name:     conn.Name,
+				IP:       conn.IP,
+				Account:  conn.Account,
+				User:     conn.AuthorizedUser,
+				Messages: delta,
+			})
+		}
+
+		sort.Slice(report.Connections, func(i, j int) bool {
+			return report.Connections[i].Messages > report.Connections[j].Messages
+		})
+		if c.top > 0 && len(report.Connections) > c.top {
+			report.Connections = report.Connections[:c.top]
+		}
+	}
+
+	mu.Lock()
+	report.Subjects = sortedSourceCounts(subjects, c.top)
+	report.Headers = sortedSourceCounts(headers, c.top)
+	for h, vals := range values {
+		report.Values[h] = sortedSourceCounts(vals, c.top)
+	}
+	mu.Unlock()
+
+	if c.json {
+		return printJSON(report)
+	}
+
+	return c.renderReport(report)
+}
+
+func (c *streamSourcesCmd) renderReport(report *streamSourcesReport) error {
+	fmt.Printf("Sampled %s messages stored in %s over %s\n\n", humanize.Comma(int64(report.Messages)), report.Stream, humanizeDuration(report.Duration))
+
+	if report.Messages == 0 && len(report.Connections) == 0 {
+		fmt.Println("No messages were stored during the sample period")
+		return nil
+	}
+
+	renderCounts := func(title string, header string, counts []*streamSourceCount) {
+		if len(counts) == 0 {
+			return
+		}
+
+		table := newTableWriter(title)
+		table.AddHeaders(header, "Messages", "Share")
+		for _, cnt := range counts {
+			table.AddRow(cnt.Name, humanize.Comma(int64(cnt.Messages)), fmt.Sprintf("%.1f%%", float64(cnt.Messages)/float64(report.Messages)*100))
+		}
+		fmt.Println(table.Render())
+		fmt.Println()
+	}
+
+	renderCounts("Busiest Subjects", "Subject", report.Subjects)
+	renderCounts("Headers Present", "Header", report.Headers)
+	for _, h := range c.headers {
+		renderCounts(fmt.Sprintf("Values for header %s", h), "Value", report.Values[h])
+	}
+
+	if c.account != "" {
+		if len(report.Connections) == 0 {
+			fmt.Printf("No connections in account %s published messages during the sample period\n", c.account)
+			return nil
+		}
+
+		table := newTableWriter(fmt.Sprintf("Publishing connections in account %s", c.account))
+		table.AddHeaders("Server", "CID", "Name", "IP", "User", "Messages Published")
+		for _, conn := range report.Connections {
+			table.AddRow(conn.Server, conn.CID, conn.Name, conn.IP, conn.User, humanize.Comma(conn.Messages))
+		}
+		fmt.Println(table.Render())
+	}
+
+	return nil
+}
@@ -0,0 +1,194 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"fmt"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/choria-io/fisk"
+	"github.com/nats-io/jsm.go/api"
+	"github.com/nats-io/nats.go"
+)
+
+type streamTailCmd struct {
+	stream    string
+	filter    string
+	last      uint64
+	raw       bool
+	translate string
+}
+
+func configureStreamTailCommand(str *fisk.CmdClause) {
+	c := &streamTailCmd{}
+
+	tail := str.Command("tail", "Shows the last messages in a Stream and follows new messages as they are stored").Action(c.tailAction)
+	tail.Arg("stream", "The Stream to tail").Required().HintAction(streamNamesHint).StringVar(&c.stream)
+	tail.Flag("filter", "Only show messages matching a subject").PlaceHolder("SUBJECT").StringVar(&c.filter)
+	tail.Flag("last", "Number of existing messages to show before following new ones").Short('n').Default("10").Uint64Var(&c.last)
+	tail.Flag("raw", "Show only the message bodies").UnNegatableBoolVar(&c.raw)
+	tail.Flag("translate", "Translate the message data by running it through the given command before output").StringVar(&c.translate)
+}
+
+// startSequence finds the sequence of the first of the last messages to show, 0 when only new messages are shown.
+// pending counts the messages matching the filter stored at or after a sequence, it is used to search for the
+// sequence when filtering or when messages were deleted from the Stream
+func (c *streamTailCmd) startSequence(state api.StreamState, pending func(seq uint64) (uint64, error)) (uint64, error) {
+	if c.last == 0 || state.Msgs == 0 {
+		return 0, nil
+	}
+
+	if c.filter == "" && state.NumDeleted == 0 {
+		if state.Msgs <= c.last {
+			return state.FirstSeq, nil
+		}
+
+		return state.LastSeq - c.last + 1, nil
+	}
+
+	total, err := pending(state.FirstSeq)
+	if err != nil {
+		return 0, err
+	}
+
+	switch {
+	case total == 0:
+		return 0, nil
+	case total <= c.last:
+		return state.FirstSeq, nil
+	}
+
+	// the highest sequence with at least last matching messages stored at or after it
+	lo, hi := state.FirstSeq, state.LastSeq
+	for lo < hi {
+		mid := lo + (hi-lo+1)/2
+
+		n, err := pending(mid)
+		if err != nil {
+			return 0, err
+		}
+
+		if n >= c.last {
+			lo = mid
+		} else {
+			hi = mid - 1
+		}
+	}
+
+	return lo, nil
+}
+
+// pendingFrom counts the messages matching the filter stored at or after seq using the pending count of a short-lived consumer
+func (c *streamTailCmd) pendingFrom(js nats.JetStreamContext, stream string, seq uint64) (uint64, error) {
+	nfo, err := js.AddConsumer(stream, &nats.ConsumerConfig{
+		DeliverPolicy:     nats.DeliverByStartSequencePolicy,
+		OptStartSeq:       seq,
+		FilterSubject:     c.filter,
+		AckPolicy:         nats.AckNonePolicy,
+		InactiveThreshold: time.Minute,
+		MemoryStorage:     true,
+		Replicas:          1,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	err = js.DeleteConsumer(stream, nfo.Name)
+	if err != nil {
+		return 0, err
+	}
+
+	return nfo.NumPending, nil
+}
+
+func (c *streamTailCmd) tailAction(_ *fisk.ParseContext) error {
+	nc, mgr, err := prepareHelper("", natsOpts()...)
+	if err != nil {
+		return err
+	}
+
+	str, err := mgr.LoadStream(c.stream)
+	if err != nil {
+		return fmt.Errorf("could not load Stream %s: %w", c.stream, err)
+	}
+
+	if str.Retention() == api.WorkQueuePolicy {
+		return fmt.Errorf("work queue stream contents can not be viewed")
+	}
+
+	js, err := nc.JetStream()
+	if err != nil {
+		return err
+	}
+
+	state, err := str.State()
+	if err != nil {
+		return err
+	}
+
+	start, err := c.startSequence(state, func(seq uint64) (uint64, error) {
+		return c.pendingFrom(js, str.Name(), seq)
+	})
+	if err != nil {
+		return fmt.Errorf("could not determine start sequence: %w", err)
+	}
+
+	sopts := []nats.SubOpt{nats.BindStream(str.Name()), nats.OrderedConsumer()}
+	if start > 0 {
+		sopts = append(sopts, nats.StartSequence(start))
+	} else {
+		sopts = append(sopts, nats.DeliverNew())
+	}
+
+	sub, err := js.SubscribeSync(c.filter, sopts...)
+	if err != nil {
+		return fmt.Errorf("could not read Stream %s: %w", str.Name(), err)
+	}
+	defer sub.Unsubscribe()
+
+	if !c.raw {
+		if c.filter != "" {
+			fmt.Printf("Tailing Stream %s for subject %s, press ^C to end\n\n", str.Name(), c.filter)
+		} else {
+			fmt.Printf("Tailing Stream %s, press ^C to end\n\n", str.Name())
+		}
+	}
+
+	ctx, cancel := signal.NotifyContext(ctx, syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT)
+	defer cancel()
+
+	// messages are shown like nats sub shows JetStream messages
+	view := &subCmd{raw: c.raw, translate: c.translate, jetStream: true}
+	var ctr uint
+
+	for {
+		msg, err := sub.NextMsgWithContext(ctx)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		err = decompressPayload(msg)
+		if err != nil && !c.raw {
+			log.Printf("Message on %s: %s", msg.Subject, err)
+		}
+
+		ctr++
+		printMsg(view, msg, nil, ctr)
+	}
+}
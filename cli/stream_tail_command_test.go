@@ -0,0 +1,76 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"testing"
+
+	"github.com/nats-io/jsm.go/api"
+)
+
+func TestStreamTailStartSequence(t *testing.T) {
+	// matching messages are stored at these sequences in a stream holding 1 to 1000
+	matching := []uint64{5, 17, 300, 301, 650, 999}
+	probes := 0
+	pending := func(seq uint64) (uint64, error) {
+		probes++
+		var n uint64
+		for _, m := range matching {
+			if m >= seq {
+				n++
+			}
+		}
+		return n, nil
+	}
+
+	state := api.StreamState{Msgs: 1000, FirstSeq: 1, LastSeq: 1000}
+
+	for last, expect := range map[uint64]uint64{0: 0, 1: 999, 3: 301, 4: 300, 6: 1, 10: 1} {
+		c := &streamTailCmd{filter: "orders.eu.>", last: last}
+		start, err := c.startSequence(state, pending)
+		assertNoError(t, err)
+		if start != expect {
+			t.Fatalf("expected the last %d to start at %d got %d", last, expect, start)
+		}
+	}
+
+	if probes > 50 {
+		t.Fatalf("expected the start to be searched, %d probes made", probes)
+	}
+
+	c := &streamTailCmd{filter: "orders.us.>", last: 10}
+	start, err := c.startSequence(state, func(uint64) (uint64, error) { return 0, nil })
+	assertNoError(t, err)
+	if start != 0 {
+		t.Fatalf("expected only new messages without matches, got %d", start)
+	}
+
+	c = &streamTailCmd{last: 10}
+	start, err = c.startSequence(state, func(uint64) (uint64, error) {
+		t.Fatalf("unexpected pending count for unfiltered streams without deletes")
+		return 0, nil
+	})
+	assertNoError(t, err)
+	if start != 991 {
+		t.Fatalf("expected unfiltered tails to start at 991 got %d", start)
+	}
+
+	state.NumDeleted = 994
+	state.Msgs = 6
+	start, err = c.startSequence(state, pending)
+	assertNoError(t, err)
+	if start != 1 {
+		t.Fatalf("expected streams with deletes to be searched, got %d", start)
+	}
+}
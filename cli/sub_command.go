@@ -31,6 +31,7 @@ import (
 	"github.com/nats-io/jsm.go"
 	"github.com/nats-io/nats-server/v2/server"
 	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nkeys"
 )
 
 type subCmd struct {
@@ -57,6 +58,72 @@ type subCmd struct {
 	jetStream             bool
 	ignoreSubjects        []string
 	wait                  time.Duration
+	resumeFile            string
+	decryptKey            string
+	decrypter             nkeys.KeyPair
+}
+
+// resumePosition is the last processed stream position persisted by --resume-file
+type resumePosition struct {
+	Stream   string    `json:"stream"`
+	Sequence uint64    `json:"sequence"`
+	Time     time.Time `json:"time"`
+}
+
+func loadResumePosition(file string) (*resumePosition, error) {
+	pos := &resumePosition{}
+
+	jb, err := os.ReadFile(file)
+	if errors.Is(err, os.ErrNotExist) {
+		return pos, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	err = json.Unmarshal(jb, pos)
+	if err != nil {
+		return nil, fmt.Errorf("invalid resume file %s: %w", file, err)
+	}
+
+	return pos, nil
+}
+
+// save writes the position via a temporary file so a crash never leaves a truncated file behind
+func (p *resumePosition) save(file string) error {
+	jb, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+
+	tf, err := os.CreateTemp(filepath.Dir(file), filepath.Base(file)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tf.Name())
+
+	_, err = tf.Write(jb)
+	tf.Close()
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tf.Name(), file)
+}
+
+func (p *resumePosition) update(info *jsm.MsgInfo, file string) {
+	if info == nil {
+		return
+	}
+
+	p.Stream = info.Stream()
+	p.Sequence = info.StreamSequence()
+	p.Time = info.TimeStamp()
+
+	err := p.save(file)
+	if err != nil {
+		log.Printf("Could not save resume position to %s: %s", file, err)
+	}
 }
 
 func configureSubCommand(app commandHost) {
@@ -86,6 +153,8 @@ func configureSubCommand(app commandHost) {
 	act.Flag("wait", "Max time to wait before unsubscribing.").DurationVar(&c.wait)
 	act.Flag("report-subjects", "Subscribes to a subject pattern and builds a de-duplicated report of active subjects receiving data").UnNegatableBoolVar(&c.reportSubjects)
 	act.Flag("report-top", "Number of subjects to show when doing 'report-subjects'. Default is 10.").Default("10").IntVar(&c.reportSubjectsCount)
+	act.Flag("resume-file", "Records the last processed Stream sequence in a file and resumes from it on restart (requires JetStream)").PlaceHolder("FILE").StringVar(&c.resumeFile)
+	act.Flag("decrypt-key", "Decrypts encrypted payloads using a curve NKey seed, given directly or in a file, defaults to the key of the context").PlaceHolder("KEY").StringVar(&c.decryptKey)
 }
 
 func init() {
@@ -171,7 +240,7 @@ func (c *subCmd) subscribe(p *fisk.ParseContext) error {
 		return fmt.Errorf("generating inboxes is not compatible with dumping to stdout using null terminated strings")
 	}
 
-	c.jetStream = c.sseq > 0 || len(c.durable) > 0 || c.deliverAll || c.deliverNew || c.deliverLast || c.deliverSince != "" || c.deliverLastPerSubject || c.stream != ""
+	c.jetStream = c.sseq > 0 || len(c.durable) > 0 || c.deliverAll || c.deliverNew || c.deliverLast || c.deliverSince != "" || c.deliverLastPerSubject || c.stream != "" || c.resumeFile != ""
 
 	if c.inbox && c.jetStream {
 		return fmt.Errorf("generating inboxes is not compatible with JetStream subscriptions")
@@ -183,6 +252,22 @@ func (c *subCmd) subscribe(p *fisk.ParseContext) error {
 	if c.reportSubjects && c.reportSubjectsCount == 0 {
 		return fmt.Errorf("subject count must be at least one")
 	}
+	if c.resumeFile != "" && c.durable != "" {
+		return fmt.Errorf("resume files are not compatible with durable consumers")
+	}
+
+	c.decrypter, err = loadPayloadDecryptKey(c.decryptKey)
+	if err != nil {
+		return withExitCode(ExitValidation, fmt.Errorf("invalid decryption key: %w", err))
+	}
+
+	var resume *resumePosition
+	if c.resumeFile != "" {
+		resume, err = loadResumePosition(c.resumeFile)
+		if err != nil {
+			return err
+		}
+	}
 
 	var (
 		sub            *nats.Subscription
@@ -246,6 +331,14 @@ func (c *subCmd) subscribe(p *fisk.ParseContext) error {
 			}
 		}
 
+		err := decryptPayload(m, c.decrypter)
+		if err == nil {
+			err = decompressPayload(m)
+		}
+		if err != nil && !dump && !c.raw {
+			log.Printf("Message on %s: %s", m.Subject, err)
+		}
+
 		ctr++
 		if c.reportSubjects {
 			subjMu.Lock()
@@ -263,6 +356,10 @@ func (c *subCmd) subscribe(p *fisk.ParseContext) error {
 			}
 		}
 
+		if resume != nil {
+			resume.update(info, c.resumeFile)
+		}
+
 		if ctr == c.limit {
 			sub.Unsubscribe()
 			// if no reply matching, or if didn't yet get all replies
@@ -351,7 +448,13 @@ func (c *subCmd) subscribe(p *fisk.ParseContext) error {
 		opts := []nats.SubOpt{
 			nats.EnableFlowControl(),
 			nats.IdleHeartbeat(5 * time.Second),
-			nats.AckNone(),
+		}
+
+		// ordered consumers manage their own acks and recreate themselves on gaps
+		if resume != nil {
+			opts = append(opts, nats.OrderedConsumer())
+		} else {
+			opts = append(opts, nats.AckNone())
 		}
 
 		if c.headersOnly {
@@ -386,6 +489,13 @@ func (c *subCmd) subscribe(p *fisk.ParseContext) error {
 		}
 
 		switch {
+		case resume != nil && resume.Sequence > 0:
+			if c.stream != "" && resume.Stream != "" && resume.Stream != c.stream {
+				return fmt.Errorf("resume file %s records a position in Stream %s", c.resumeFile, resume.Stream)
+			}
+
+			log.Printf("Resuming JetStream Stream holding messages with subject %s after sequence %d processed at %s %s", subMsg, resume.Sequence, resume.Time.Format(time.RFC3339), ignoredSubjInfo)
+			opts = append(opts, nats.StartSequence(resume.Sequence+1))
 		case c.sseq > 0:
 			log.Printf("Subscribing to JetStream Stream holding messages with subject %s starting with sequence %d %s", subMsg, c.sseq, ignoredSubjInfo)
 			opts = append(opts, nats.StartSequence(c.sseq))
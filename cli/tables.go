@@ -15,14 +15,23 @@ package cli
 
 import (
 	"fmt"
+	"os"
 	"sort"
+	"strings"
 
 	"github.com/jedib0t/go-pretty/v6/table"
 	"github.com/jedib0t/go-pretty/v6/text"
+	terminal "golang.org/x/term"
 )
 
 type tbl struct {
-	writer table.Writer
+	writer   table.Writer
+	title    string
+	headers  []string
+	rows     [][]string
+	wide     []string
+	narrow   []string
+	selected []string
 }
 
 var styles = map[string]table.Style{
@@ -62,9 +71,123 @@ func ValidStyles() []string {
 }
 
 func (t *tbl) AddHeaders(items ...any) {
+	for _, i := range items {
+		t.headers = append(t.headers, fmt.Sprint(i))
+	}
+
 	t.writer.AppendHeader(items)
 }
 
+// WideColumns marks columns that are only shown when wide output is selected using --output-format wide
+func (t *tbl) WideColumns(names ...string) {
+	t.wide = append(t.wide, names...)
+}
+
+// NarrowColumns sets the columns that are shown when narrow output is selected using --output-format narrow
+func (t *tbl) NarrowColumns(names ...string) {
+	t.narrow = append(t.narrow, names...)
+}
+
+// SelectColumns limits the table to specific columns, matched case insensitively ignoring spaces and dashes
+// and may hold comma separated lists. Selected columns are shown regardless of wide or narrow output.
+func (t *tbl) SelectColumns(columns ...string) error {
+	for _, col := range columns {
+		for _, c := range strings.Split(col, ",") {
+			if strings.TrimSpace(c) == "" {
+				continue
+			}
+
+			found := false
+			for _, h := range t.headers {
+				if columnMatch(h, c) {
+					t.selected = append(t.selected, h)
+					found = true
+					break
+				}
+			}
+
+			if !found {
+				var valid []string
+				for _, h := range t.headers {
+					valid = append(valid, columnName(h))
+				}
+
+				return fmt.Errorf("unknown column %q, valid columns are %s", c, strings.Join(valid, ", "))
+			}
+		}
+	}
+
+	return nil
+}
+
+func columnName(header string) string {
+	return strings.ReplaceAll(strings.ToLower(strings.TrimSpace(header)), " ", "-")
+}
+
+func columnMatch(header string, name string) bool {
+	norm := func(s string) string {
+		return strings.NewReplacer(" ", "", "-", "", "_", "").Replace(strings.ToLower(strings.TrimSpace(s)))
+	}
+
+	return norm(header) == norm(name)
+}
+
+func (t *tbl) visibleColumns() []string {
+	if len(t.selected) > 0 {
+		return t.selected
+	}
+
+	if opts.OutputFormat == "narrow" && len(t.narrow) > 0 {
+		return t.narrow
+	}
+
+	if opts.OutputFormat == "wide" {
+		return t.headers
+	}
+
+	var visible []string
+	for _, h := range t.headers {
+		wide := false
+		for _, w := range t.wide {
+			if columnMatch(h, w) {
+				wide = true
+				break
+			}
+		}
+
+		if !wide {
+			visible = append(visible, h)
+		}
+	}
+
+	return visible
+}
+
+func (t *tbl) hideColumns() {
+	if len(t.selected) == 0 && len(t.wide) == 0 && len(t.narrow) == 0 {
+		return
+	}
+
+	visible := t.visibleColumns()
+
+	var configs []table.ColumnConfig
+	for i, h := range t.headers {
+		show := false
+		for _, v := range visible {
+			if columnMatch(h, v) {
+				show = true
+				break
+			}
+		}
+
+		if !show {
+			configs = append(configs, table.ColumnConfig{Number: i + 1, Hidden: true})
+		}
+	}
+
+	t.writer.SetColumnConfigs(configs)
+}
+
 func (t *tbl) AddFooter(items ...any) {
 	t.writer.AppendFooter(items)
 }
@@ -74,9 +197,34 @@ func (t *tbl) AddSeparator() {
 }
 
 func (t *tbl) AddRow(items ...any) {
+	row := make([]string, len(items))
+	for i, item := range items {
+		row[i] = fmt.Sprint(item)
+	}
+	t.rows = append(t.rows, row)
+
 	t.writer.AppendRow(items)
 }
 
 func (t *tbl) Render() string {
+	if eachContextChild() {
+		return t.export()
+	}
+
+	t.hideColumns()
+	startPager()
+
+	if opts.OutputFormat == "narrow" {
+		w, _, err := terminal.GetSize(int(os.Stdout.Fd()))
+		if err == nil && w > 0 {
+			t.writer.SetAllowedRowLength(w)
+		}
+	}
+
 	return fmt.Sprintln(t.writer.Render())
 }
+
+// wideOutput determines if wide output was selected using --output-format wide
+func wideOutput() bool {
+	return opts.OutputFormat == "wide"
+}
@@ -0,0 +1,145 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/nats-io/jsm.go"
+	"github.com/nats-io/jsm.go/api"
+	"github.com/nats-io/nats.go"
+)
+
+// tempConsumerInactiveThreshold bounds how long a temporary consumer survives should the CLI be killed without cleaning up
+const tempConsumerInactiveThreshold = 5 * time.Minute
+
+// msgPager is implemented by jsm.StreamPager and tempPager
+type msgPager interface {
+	NextMsg(ctx context.Context) (*nats.Msg, bool, error)
+	Close() error
+}
+
+// newTempConsumer creates an ephemeral R1 memory consumer, callers should defer deleteTempConsumer and handle
+// interrupts by cancelling their context so the deferred delete runs. Consumers left behind when the CLI is killed
+// are removed by the server once inactive for tempConsumerInactiveThreshold
+func newTempConsumer(mgr *jsm.Manager, stream string, cfg api.ConsumerConfig) (*jsm.Consumer, error) {
+	cfg.Durable = ""
+	cfg.Name = ""
+	cfg.Replicas = 1
+	cfg.MemoryStorage = true
+	cfg.InactiveThreshold = tempConsumerInactiveThreshold
+	if cfg.Description == "" {
+		cfg.Description = "Temporary Consumer created by the nats CLI"
+	}
+
+	return mgr.NewConsumerFromDefault(stream, cfg)
+}
+
+// deleteTempConsumer removes a consumer created using newTempConsumer
+func deleteTempConsumer(cons *jsm.Consumer) error {
+	return cons.Delete()
+}
+
+// tempPager pages through a stream like jsm.StreamPager using a temporary consumer
+type tempPager struct {
+	nc       *nats.Conn
+	cons     *jsm.Consumer
+	sub      *nats.Subscription
+	q        chan *nats.Msg
+	pageSize int
+	seen     int
+	timeout  time.Duration
+}
+
+func newTempPager(nc *nats.Conn, mgr *jsm.Manager, stream string, pageSize int, startSeq int, startDelta time.Duration, filter string) (*tempPager, error) {
+	cfg := api.ConsumerConfig{
+		Description:   "Stream view by the nats CLI",
+		AckPolicy:     api.AckExplicit,
+		AckWait:       time.Minute,
+		DeliverPolicy: api.DeliverAll,
+		FilterSubject: filter,
+	}
+
+	switch {
+	case startDelta > 0:
+		t := time.Now().UTC().Add(-startDelta)
+		cfg.DeliverPolicy = api.DeliverByStartTime
+		cfg.OptStartTime = &t
+	case startSeq > 0:
+		cfg.DeliverPolicy = api.DeliverByStartSequence
+		cfg.OptStartSeq = uint64(startSeq)
+	}
+
+	p := &tempPager{nc: nc, pageSize: pageSize, seen: -1, timeout: 5 * time.Second}
+	p.q = make(chan *nats.Msg, pageSize)
+
+	var err error
+	p.sub, err = nc.ChanSubscribe(nc.NewRespInbox(), p.q)
+	if err != nil {
+		return nil, err
+	}
+
+	p.cons, err = newTempConsumer(mgr, stream, cfg)
+	if err != nil {
+		p.sub.Unsubscribe()
+		return nil, fmt.Errorf("could not create temporary Consumer: %w", err)
+	}
+
+	return p, nil
+}
+
+// NextMsg behaves like jsm.StreamPager.NextMsg
+func (p *tempPager) NextMsg(ctx context.Context) (*nats.Msg, bool, error) {
+	if p.seen == p.pageSize || p.seen == -1 {
+		p.seen = 0
+
+		rj, err := json.Marshal(api.JSApiConsumerGetNextRequest{Batch: p.pageSize, NoWait: true})
+		if err != nil {
+			return nil, false, err
+		}
+
+		err = p.nc.PublishRequest(p.cons.NextSubject(), p.sub.Subject, rj)
+		if err != nil {
+			return nil, false, err
+		}
+	}
+
+	timeout, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+
+	select {
+	case msg := <-p.q:
+		p.seen++
+
+		status := msg.Header.Get("Status")
+		if status == "404" || status == "408" {
+			return nil, true, fmt.Errorf("last message reached")
+		}
+
+		msg.Ack()
+
+		return msg, p.seen == p.pageSize, nil
+
+	case <-timeout.Done():
+		return nil, true, fmt.Errorf("timeout waiting for new messages")
+	}
+}
+
+func (p *tempPager) Close() error {
+	p.sub.Unsubscribe()
+	return deleteTempConsumer(p.cons)
+}
@@ -0,0 +1,582 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/choria-io/fisk"
+	"github.com/dustin/go-humanize"
+	"github.com/nats-io/jsm.go"
+	"github.com/nats-io/jsm.go/api"
+	"github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats.go"
+	"golang.org/x/term"
+)
+
+type topCmd struct {
+	interval  time.Duration
+	startView string
+
+	nc       *nats.Conn
+	mgr      *jsm.Manager
+	view     int
+	selected int
+	detail   bool
+	status   string
+
+	rows       []topRow
+	lastSeqs   map[string]uint64
+	lastSample time.Time
+	rates      map[string]float64
+}
+
+// topRow is a row in a dashboard view, key identifies the item for drill down
+type topRow struct {
+	key   string
+	cells []any
+}
+
+// Views shown by nats top
+const (
+	topServers = iota
+	topAccounts
+	topStreams
+	topConsumers
+)
+
+var topViewNames = []string{"Servers", "Accounts", "Streams", "Consumers"}
+
+func configureTopCommand(app commandHost) {
+	c := &topCmd{lastSeqs: map[string]uint64{}, rates: map[string]float64{}}
+
+	help := `Interactive dashboard of servers, accounts, streams and consumers
+
+Keys:
+
+  1-4, tab    switch between Servers, Accounts, Streams and Consumers
+  up, down    select a row, also k and j
+  enter       show information about the selected Stream or Consumer
+  esc         return from information
+  q           quit
+
+The Servers and Accounts views require a system account.
+`
+
+	top := app.Command("top", help).Action(c.topAction)
+	top.Flag("interval", "How often to refresh").Default("2s").DurationVar(&c.interval)
+	top.Flag("view", "The view to start in").Default("streams").EnumVar(&c.startView, "servers", "accounts", "streams", "consumers")
+	addCheat("top", top)
+}
+
+func init() {
+	registerCommand("top", 18, configureTopCommand)
+}
+
+func (c *topCmd) topAction(_ *fisk.ParseContext) error {
+	if !term.IsTerminal(int(os.Stdin.Fd())) || !term.IsTerminal(int(os.Stdout.Fd())) {
+		return fmt.Errorf("nats top requires an interactive terminal")
+	}
+
+	for i, v := range topViewNames {
+		if strings.EqualFold(v, c.startView) {
+			c.view = i
+		}
+	}
+
+	var err error
+	c.nc, c.mgr, err = prepareHelper("", natsOpts()...)
+	if err != nil {
+		return err
+	}
+
+	state, err := term.MakeRaw(int(os.Stdin.Fd()))
+	if err != nil {
+		return err
+	}
+	defer term.Restore(int(os.Stdin.Fd()), state)
+
+	// alternate screen and hidden cursor, restored on exit
+	fmt.Print("\033[?1049h\033[?25l")
+	defer fmt.Print("\033[?25h\033[?1049l")
+
+	keys := make(chan string, 10)
+	go func() {
+		buf := make([]byte, 16)
+		for {
+			n, err := os.Stdin.Read(buf)
+			if err != nil {
+				close(keys)
+				return
+			}
+			keys <- string(buf[:n])
+		}
+	}()
+
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+
+	c.refresh()
+	for {
+		c.draw()
+
+		select {
+		case key, ok := <-keys:
+			if !ok || !c.handleKey(key) {
+				return nil
+			}
+			// only refresh data when the view changed, moving the selection only redraws
+			if key != "j" && key != "k" && key != "\033[A" && key != "\033[B" {
+				c.refresh()
+			}
+
+		case <-ticker.C:
+			c.refresh()
+
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// handleKey updates the dashboard state for a key press, returns false when the dashboard should exit
+func (c *topCmd) handleKey(key string) bool {
+	switch key {
+	case "q", "Q", "\x03":
+		return false
+	case "1", "2", "3", "4":
+		c.view = int(key[0] - '1')
+		c.selected = 0
+		c.detail = false
+	case "\t":
+		c.view = (c.view + 1) % len(topViewNames)
+		c.selected = 0
+		c.detail = false
+	case "k", "\033[A":
+		if c.selected > 0 {
+			c.selected--
+		}
+	case "j", "\033[B":
+		if c.selected < len(c.rows)-1 {
+			c.selected++
+		}
+	case "\r", "\n":
+		if c.view == topStreams || c.view == topConsumers {
+			c.detail = len(c.rows) > 0
+		}
+	case "\033":
+		c.detail = false
+	}
+
+	return true
+}
+
+func (c *topCmd) refresh() {
+	c.status = ""
+
+	var err error
+	switch c.view {
+	case topServers:
+		c.rows, err = c.serverRows()
+	case topAccounts:
+		c.rows, err = c.accountRows()
+	case topStreams:
+		c.rows, err = c.streamRows()
+	case topConsumers:
+		c.rows, err = c.consumerRows()
+	}
+	if err != nil {
+		c.status = err.Error()
+	}
+
+	if c.selected >= len(c.rows) {
+		c.selected = len(c.rows) - 1
+	}
+	if c.selected < 0 {
+		c.selected = 0
+	}
+}
+
+// rate calculates the per second rate of a counter since the previous refresh
+func (c *topCmd) rate(key string, value uint64, elapsed float64) float64 {
+	prev, ok := c.lastSeqs[key]
+	c.lastSeqs[key] = value
+	if !ok || elapsed <= 0 || value < prev {
+		return c.rates[key]
+	}
+
+	c.rates[key] = float64(value-prev) / elapsed
+	return c.rates[key]
+}
+
+func (c *topCmd) elapsed() float64 {
+	now := time.Now()
+	elapsed := now.Sub(c.lastSample).Seconds()
+	c.lastSample = now
+
+	// ignore refreshes caused by key presses to avoid noisy rates
+	if elapsed < c.interval.Seconds()/2 {
+		return 0
+	}
+
+	return elapsed
+}
+
+func (c *topCmd) serverRows() ([]topRow, error) {
+	var stats []*server.ServerStatsMsg
+
+	err := doReqAsync(nil, "$SYS.REQ.SERVER.PING", 0, c.nc, func(data []byte) {
+		ssm := &server.ServerStatsMsg{}
+		if json.Unmarshal(data, ssm) == nil {
+			stats = append(stats, ssm)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(stats) == 0 {
+		return nil, fmt.Errorf("no servers responded, the Servers view requires a system account")
+	}
+
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Server.Name < stats[j].Server.Name })
+
+	elapsed := c.elapsed()
+	var rows []topRow
+	for _, s := range stats {
+		in := c.rate("in:"+s.Server.ID, uint64(s.Stats.Received.Msgs), elapsed)
+		out := c.rate("out:"+s.Server.ID, uint64(s.Stats.Sent.Msgs), elapsed)
+
+		rows = append(rows, topRow{key: s.Server.Name, cells: []any{
+			s.Server.Name,
+			s.Server.Cluster,
+			s.Server.Version,
+			humanize.Comma(int64(s.Stats.Connections)),
+			humanize.Comma(int64(s.Stats.NumSubs)),
+			fmt.Sprintf("%.1f%%", s.Stats.CPU),
+			humanize.IBytes(uint64(s.Stats.Mem)),
+			humanize.CommafWithDigits(in, 1),
+			humanize.CommafWithDigits(out, 1),
+			humanize.Comma(s.Stats.SlowConsumers),
+		}})
+	}
+
+	return rows, nil
+}
+
+func (c *topCmd) accountRows() ([]topRow, error) {
+	type account struct {
+		name    string
+		memory  uint64
+		store   uint64
+		streams int
+		api     uint64
+		errors  uint64
+	}
+
+	accounts := map[string]*account{}
+	req := &server.JszEventOptions{JSzOptions: server.JSzOptions{Accounts: true, Streams: true, Limit: 10000}}
+
+	err := doReqAsync(req, "$SYS.REQ.SERVER.PING.JSZ", 0, c.nc, func(data []byte) {
+		var res struct {
+			Data server.JSInfo `json:"data"`
+		}
+		if json.Unmarshal(data, &res) != nil {
+			return
+		}
+
+		for _, d := range res.Data.AccountDetails {
+			acct, ok := accounts[d.Name]
+			if !ok {
+				acct = &account{name: d.Name}
+				accounts[d.Name] = acct
+			}
+
+			acct.memory += d.Memory
+			acct.store += d.Store
+			acct.api += d.API.Total
+			acct.errors += d.API.Errors
+			for _, s := range d.Streams {
+				if s.Cluster == nil || s.Cluster.Leader == "" || s.Cluster.Leader == res.Data.ID || len(s.Cluster.Replicas) == 0 {
+					acct.streams++
+				}
+			}
+		}
+	})
+	if err != nil {
+		return c.currentAccountRows(err)
+	}
+	if len(accounts) == 0 {
+		return c.currentAccountRows(fmt.Errorf("no servers responded"))
+	}
+
+	var list []*account
+	for _, a := range accounts {
+		list = append(list, a)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].store+list[i].memory > list[j].store+list[j].memory })
+
+	var rows []topRow
+	for _, a := range list {
+		rows = append(rows, topRow{key: a.name, cells: []any{a.name, humanize.IBytes(a.memory), humanize.IBytes(a.store), a.streams, humanize.Comma(int64(a.api)), humanize.Comma(int64(a.errors))}})
+	}
+
+	return rows, nil
+}
+
+// currentAccountRows shows the account of the current connection when system account access is not available
+func (c *topCmd) currentAccountRows(sysErr error) ([]topRow, error) {
+	nfo, err := c.mgr.JetStreamAccountInfo()
+	if err != nil {
+		return nil, err
+	}
+
+	c.status = fmt.Sprintf("showing the current account only: %v", sysErr)
+
+	return []topRow{{key: "current", cells: []any{"current", humanize.IBytes(nfo.Memory), humanize.IBytes(nfo.Store), nfo.Streams, humanize.Comma(int64(nfo.API.Total)), humanize.Comma(int64(nfo.API.Errors))}}}, nil
+}
+
+func (c *topCmd) streamRows() ([]topRow, error) {
+	streams, _, err := c.mgr.Streams(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	type entry struct {
+		name string
+		rate float64
+		nfo  *api.StreamInfo
+	}
+
+	elapsed := c.elapsed()
+	var list []*entry
+	for _, s := range streams {
+		nfo, err := s.LatestInformation()
+		if err != nil {
+			continue
+		}
+
+		list = append(list, &entry{name: s.Name(), rate: c.rate("stream:"+s.Name(), nfo.State.LastSeq, elapsed), nfo: nfo})
+	}
+
+	sort.SliceStable(list, func(i, j int) bool {
+		if list[i].rate == list[j].rate {
+			return list[i].name < list[j].name
+		}
+		return list[i].rate > list[j].rate
+	})
+
+	var rows []topRow
+	for _, e := range list {
+		rows = append(rows, topRow{key: e.name, cells: []any{e.name, humanize.CommafWithDigits(e.rate, 1), humanize.Comma(int64(e.nfo.State.Msgs)), humanize.IBytes(e.nfo.State.Bytes), e.nfo.State.Consumers, e.nfo.Config.Replicas}})
+	}
+
+	return rows, nil
+}
+
+func (c *topCmd) consumerRows() ([]topRow, error) {
+	names, err := c.mgr.StreamNames(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	type entry struct {
+		stream  string
+		name    string
+		pending uint64
+		ack     int
+		redeliv int
+		waiting int
+		lag     uint64
+	}
+
+	var list []*entry
+	for _, stream := range names {
+		consumers, _, err := c.mgr.Consumers(stream)
+		if err != nil {
+			continue
+		}
+
+		for _, cons := range consumers {
+			state, err := cons.LatestState()
+			if err != nil {
+				continue
+			}
+
+			list = append(list, &entry{
+				stream:  stream,
+				name:    cons.Name(),
+				pending: state.NumPending,
+				ack:     state.NumAckPending,
+				redeliv: state.NumRedelivered,
+				waiting: state.NumWaiting,
+				lag:     state.NumPending + uint64(state.NumAckPending),
+			})
+		}
+	}
+
+	sort.SliceStable(list, func(i, j int) bool {
+		if list[i].lag == list[j].lag {
+			return list[i].stream+list[i].name < list[j].stream+list[j].name
+		}
+		return list[i].lag > list[j].lag
+	})
+
+	var rows []topRow
+	for _, e := range list {
+		rows = append(rows, topRow{key: e.stream + " " + e.name, cells: []any{e.stream, e.name, humanize.Comma(int64(e.pending)), humanize.Comma(int64(e.ack)), humanize.Comma(int64(e.redeliv)), humanize.Comma(int64(e.waiting))}})
+	}
+
+	return rows, nil
+}
+
+func (c *topCmd) draw() {
+	width, height, err := term.GetSize(int(os.Stdout.Fd()))
+	if err != nil || width < 20 || height < 10 {
+		width, height = 80, 24
+	}
+
+	buf := bytes.NewBuffer([]byte{})
+
+	var tabs []string
+	for i, v := range topViewNames {
+		if i == c.view {
+			tabs = append(tabs, fmt.Sprintf("[%d %s]", i+1, v))
+		} else {
+			tabs = append(tabs, fmt.Sprintf(" %d %s ", i+1, v))
+		}
+	}
+	fmt.Fprintf(buf, "nats top - %s - %s\n", c.nc.ConnectedUrlRedacted(), time.Now().Format("15:04:05"))
+	fmt.Fprintf(buf, "%s   q quit, enter info, esc back\n\n", strings.Join(tabs, " "))
+
+	if c.detail && c.selected < len(c.rows) {
+		buf.WriteString(c.detailView(c.rows[c.selected].key))
+	} else {
+		buf.WriteString(c.renderRows(height - 6))
+	}
+
+	if c.status != "" {
+		fmt.Fprintf(buf, "\n%s\n", c.status)
+	}
+
+	// truncate to the screen and use raw mode line endings
+	lines := strings.Split(strings.TrimRight(buf.String(), "\n"), "\n")
+	if len(lines) > height-1 {
+		lines = lines[:height-1]
+	}
+	for i, l := range lines {
+		if len([]rune(l)) > width {
+			lines[i] = string([]rune(l)[:width])
+		}
+	}
+
+	fmt.Print("\033[H\033[2J" + strings.Join(lines, "\r\n"))
+}
+
+func (c *topCmd) renderRows(max int) string {
+	headers := map[int][]any{
+		topServers:   {"", "Server", "Cluster", "Version", "Connections", "Subscriptions", "CPU", "Memory", "Msgs In/s", "Msgs Out/s", "Slow Consumers"},
+		topAccounts:  {"", "Account", "Memory", "Storage", "Streams", "API Requests", "API Errors"},
+		topStreams:   {"", "Stream", "Msgs/s", "Messages", "Size", "Consumers", "Replicas"},
+		topConsumers: {"", "Stream", "Consumer", "Pending", "Ack Pending", "Redelivered", "Waiting Pulls"},
+	}
+
+	table := newTableWriter(topViewNames[c.view])
+	table.AddHeaders(headers[c.view]...)
+
+	// scroll so the selected row is visible
+	start := 0
+	if max < 1 {
+		max = 1
+	}
+	if c.selected >= max {
+		start = c.selected - max + 1
+	}
+
+	for i := start; i < len(c.rows) && i < start+max; i++ {
+		marker := ""
+		if i == c.selected {
+			marker = ">"
+		}
+		table.AddRow(append([]any{marker}, c.rows[i].cells...)...)
+	}
+
+	return table.Render()
+}
+
+// detailView renders the standard information output for the selected stream or consumer
+func (c *topCmd) detailView(key string) string {
+	switch c.view {
+	case topStreams:
+		str, err := c.mgr.LoadStream(key)
+		if err != nil {
+			return err.Error()
+		}
+
+		nfo, err := str.LatestInformation()
+		if err != nil {
+			return err.Error()
+		}
+
+		return captureStdout(func() { (&streamCmd{stream: key}).showStreamInfo(nfo) })
+
+	case topConsumers:
+		parts := strings.SplitN(key, " ", 2)
+		cons, err := c.mgr.LoadConsumer(parts[0], parts[1])
+		if err != nil {
+			return err.Error()
+		}
+
+		state, err := cons.LatestState()
+		if err != nil {
+			return err.Error()
+		}
+
+		return captureStdout(func() { (&consumerCmd{}).showInfo(cons.Configuration(), state) })
+	}
+
+	return ""
+}
+
+// captureStdout returns the output written to stdout by cb
+func captureStdout(cb func()) string {
+	rdr, wrtr, err := os.Pipe()
+	if err != nil {
+		return err.Error()
+	}
+
+	orig := os.Stdout
+	os.Stdout = wrtr
+
+	out := make(chan string)
+	go func() {
+		b, _ := io.ReadAll(rdr)
+		out <- string(b)
+	}()
+
+	cb()
+
+	os.Stdout = orig
+	wrtr.Close()
+	res := <-out
+	rdr.Close()
+
+	return res
+}
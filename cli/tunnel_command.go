@@ -0,0 +1,452 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/choria-io/fisk"
+	"github.com/dustin/go-humanize"
+	"github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nuid"
+)
+
+// Tunnels are opened by a request to the tunnel subject, after that every connection uses subjects
+// below SUBJECT.ID, data sent by the listener is published to SUBJECT.ID.l and data sent by the
+// connector to SUBJECT.ID.c. Receivers acknowledge the bytes they wrote on DATA_SUBJECT.ack which
+// limits the unacknowledged data in flight to the window size
+const (
+	tunnelSeqHeader     = "Tunnel-Seq"
+	tunnelControlHeader = "Tunnel-Control"
+	tunnelAckHeader     = "Tunnel-Ack"
+	tunnelClose         = "close"
+)
+
+type tunnelCmd struct {
+	subject    string
+	listen     string
+	target     string
+	queue      string
+	window     int
+	ackTimeout time.Duration
+}
+
+// tunnelOpenRequest is sent by the listener to open a tunnel for a new connection
+type tunnelOpenRequest struct {
+	ID     string `json:"id"`
+	Source string `json:"source"`
+}
+
+// tunnelOpenResponse is the response to a tunnelOpenRequest
+type tunnelOpenResponse struct {
+	Error string `json:"error,omitempty"`
+}
+
+func configureTunnelCommand(app commandHost) {
+	c := &tunnelCmd{}
+
+	help := `Tunnels TCP connections over NATS
+
+A listener accepts TCP connections and forwards them over NATS to a
+connector that connects to the target and relays the data, allowing
+services to be reached across leafnode and network boundaries.
+
+The byte streams are sent in chunks with a window limiting the
+unacknowledged data in flight.
+`
+
+	tunnel := app.Command("tunnel", help)
+	addCheat("tunnel", tunnel)
+
+	listen := publishing(tunnel.Command("listen", "Accepts TCP connections and forwards them to a connector").Action(c.listenAction))
+	listen.Arg("address", "The address to listen on like :5432").Required().StringVar(&c.listen)
+	listen.Flag("subject", "The subject the connector listens on").Required().StringVar(&c.subject)
+	listen.Flag("window", "Maximum unacknowledged bytes in flight per connection").Default("1048576").IntVar(&c.window)
+	listen.Flag("ack-timeout", "Closes connections when the other side does not acknowledge data for this long").Default("30s").DurationVar(&c.ackTimeout)
+
+	connect := publishing(tunnel.Command("connect", "Connects tunneled connections to a target").Action(c.connectAction))
+	connect.Flag("subject", "The subject to listen on for tunneled connections").Required().StringVar(&c.subject)
+	connect.Flag("target", "The address to connect tunneled connections to like localhost:5432").Required().StringVar(&c.target)
+	connect.Flag("queue", "Queue group to join, connectors in the same group share connections").Default("nats-tunnel").StringVar(&c.queue)
+	connect.Flag("window", "Maximum unacknowledged bytes in flight per connection").Default("1048576").IntVar(&c.window)
+	connect.Flag("ack-timeout", "Closes connections when the other side does not acknowledge data for this long").Default("30s").DurationVar(&c.ackTimeout)
+}
+
+func init() {
+	registerCommand("tunnel", 18, configureTunnelCommand)
+}
+
+// tunnelStream relays data between a TCP connection and a pair of subjects
+type tunnelStream struct {
+	nc         *nats.Conn
+	conn       net.Conn
+	id         string
+	out        string
+	in         string
+	window     int
+	ackTimeout time.Duration
+
+	mu       sync.Mutex
+	inflight int
+	acked    chan struct{}
+	done     chan struct{}
+	once     sync.Once
+	expect   uint64
+	sent     uint64
+	received uint64
+	subs     []*nats.Subscription
+}
+
+func newTunnelStream(nc *nats.Conn, conn net.Conn, id string, out string, in string, window int, ackTimeout time.Duration) *tunnelStream {
+	return &tunnelStream{
+		nc:         nc,
+		conn:       conn,
+		id:         id,
+		out:        out,
+		in:         in,
+		window:     window,
+		ackTimeout: ackTimeout,
+		acked:      make(chan struct{}, 1),
+		done:       make(chan struct{}),
+		expect:     1,
+	}
+}
+
+// subscribe sets up the subscriptions for incoming data and acknowledgements, must be called before the other side sends data
+func (s *tunnelStream) subscribe() error {
+	sub, err := s.nc.Subscribe(s.in, s.handleData)
+	if err != nil {
+		return err
+	}
+	sub.SetPendingLimits(-1, -1)
+	s.subs = append(s.subs, sub)
+
+	sub, err = s.nc.Subscribe(s.out+".ack", s.handleAck)
+	if err != nil {
+		s.close()
+		return err
+	}
+	s.subs = append(s.subs, sub)
+
+	return nil
+}
+
+func (s *tunnelStream) close() {
+	s.once.Do(func() {
+		for _, sub := range s.subs {
+			sub.Unsubscribe()
+		}
+		s.conn.Close()
+		close(s.done)
+	})
+}
+
+// closeRemote tells the other side the connection closed and closes the local side
+func (s *tunnelStream) closeRemote() {
+	msg := nats.NewMsg(s.out)
+	msg.Header.Set(tunnelControlHeader, tunnelClose)
+	s.nc.PublishMsg(msg)
+	s.close()
+}
+
+func (s *tunnelStream) handleData(m *nats.Msg) {
+	if m.Header.Get(tunnelControlHeader) == tunnelClose {
+		s.close()
+		return
+	}
+
+	seq, err := strconv.ParseUint(m.Header.Get(tunnelSeqHeader), 10, 64)
+	if err != nil || seq != s.expect {
+		log.Printf("Tunnel %s: received chunk %s while expecting %d, closing", s.id, m.Header.Get(tunnelSeqHeader), s.expect)
+		s.closeRemote()
+		return
+	}
+	s.expect++
+
+	_, err = s.conn.Write(m.Data)
+	if err != nil {
+		s.closeRemote()
+		return
+	}
+
+	s.mu.Lock()
+	s.received += uint64(len(m.Data))
+	s.mu.Unlock()
+
+	ack := nats.NewMsg(s.in + ".ack")
+	ack.Header.Set(tunnelAckHeader, strconv.Itoa(len(m.Data)))
+	s.nc.PublishMsg(ack)
+}
+
+func (s *tunnelStream) handleAck(m *nats.Msg) {
+	n, err := strconv.Atoi(m.Header.Get(tunnelAckHeader))
+	if err != nil {
+		return
+	}
+
+	s.mu.Lock()
+	s.inflight -= n
+	s.mu.Unlock()
+
+	select {
+	case s.acked <- struct{}{}:
+	default:
+	}
+}
+
+// waitForWindow blocks until n more bytes can be sent
+func (s *tunnelStream) waitForWindow(n int) error {
+	timer := time.NewTimer(s.ackTimeout)
+	defer timer.Stop()
+
+	for {
+		s.mu.Lock()
+		inflight := s.inflight
+		s.mu.Unlock()
+
+		if inflight == 0 || inflight+n <= s.window {
+			return nil
+		}
+
+		select {
+		case <-s.acked:
+		case <-timer.C:
+			return fmt.Errorf("no acknowledgement received in %v", s.ackTimeout)
+		case <-s.done:
+			return io.EOF
+		}
+	}
+}
+
+// run relays data read from the connection until either side closes
+func (s *tunnelStream) run() {
+	chunk := int(s.nc.MaxPayload()) - 1024
+	if chunk > 64*1024 {
+		chunk = 64 * 1024
+	}
+	buf := make([]byte, chunk)
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			s.closeRemote()
+		case <-s.done:
+		}
+	}()
+
+	var seq uint64
+	for {
+		n, err := s.conn.Read(buf)
+		if n > 0 {
+			werr := s.waitForWindow(n)
+			if werr != nil {
+				if !errors.Is(werr, io.EOF) {
+					log.Printf("Tunnel %s: %v, closing", s.id, werr)
+				}
+				s.closeRemote()
+				return
+			}
+
+			seq++
+			msg := nats.NewMsg(s.out)
+			msg.Data = append([]byte{}, buf[:n]...)
+			msg.Header.Set(tunnelSeqHeader, strconv.FormatUint(seq, 10))
+
+			s.mu.Lock()
+			s.inflight += n
+			s.sent += uint64(n)
+			s.mu.Unlock()
+
+			perr := s.nc.PublishMsg(msg)
+			if perr != nil {
+				log.Printf("Tunnel %s: could not send data: %v, closing", s.id, perr)
+				s.closeRemote()
+				return
+			}
+		}
+
+		if err != nil {
+			select {
+			case <-s.done:
+			default:
+				s.closeRemote()
+			}
+			return
+		}
+	}
+}
+
+func (s *tunnelStream) stats() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return fmt.Sprintf("sent %s received %s", humanize.IBytes(s.sent), humanize.IBytes(s.received))
+}
+
+func (c *tunnelCmd) validate() error {
+	if !server.IsValidLiteralSubject(c.subject) {
+		return withExitCode(ExitValidation, fmt.Errorf("invalid subject %q", c.subject))
+	}
+	if c.window < 1024 {
+		return withExitCode(ExitValidation, fmt.Errorf("window must be at least 1024 bytes"))
+	}
+
+	return nil
+}
+
+func (c *tunnelCmd) listenAction(_ *fisk.ParseContext) error {
+	err := c.validate()
+	if err != nil {
+		return err
+	}
+
+	nc, err := newNatsConn("", natsOpts()...)
+	if err != nil {
+		return err
+	}
+	defer nc.Close()
+
+	listener, err := net.Listen("tcp", c.listen)
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		<-ctx.Done()
+		listener.Close()
+	}()
+
+	log.Printf("Tunneling connections to %s over %s", listener.Addr(), c.subject)
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+
+		go c.handleListenerConn(nc, conn)
+	}
+}
+
+func (c *tunnelCmd) handleListenerConn(nc *nats.Conn, conn net.Conn) {
+	id := nuid.Next()
+	stream := newTunnelStream(nc, conn, id, fmt.Sprintf("%s.%s.l", c.subject, id), fmt.Sprintf("%s.%s.c", c.subject, id), c.window, c.ackTimeout)
+
+	err := stream.subscribe()
+	if err != nil {
+		log.Printf("Tunnel %s: could not subscribe: %v", id, err)
+		conn.Close()
+		return
+	}
+
+	req, _ := json.Marshal(tunnelOpenRequest{ID: id, Source: conn.RemoteAddr().String()})
+	res, err := nc.Request(c.subject, req, opts.Timeout)
+	if err == nil {
+		var open tunnelOpenResponse
+		err = json.Unmarshal(res.Data, &open)
+		if err == nil && open.Error != "" {
+			err = errors.New(open.Error)
+		}
+	}
+	if err != nil {
+		log.Printf("Tunnel %s: could not open tunnel for %s: %v", id, conn.RemoteAddr(), err)
+		stream.close()
+		return
+	}
+
+	log.Printf("Tunnel %s: opened for %s", id, conn.RemoteAddr())
+	stream.run()
+	<-stream.done
+	log.Printf("Tunnel %s: closed, %s", id, stream.stats())
+}
+
+func (c *tunnelCmd) connectAction(_ *fisk.ParseContext) error {
+	err := c.validate()
+	if err != nil {
+		return err
+	}
+
+	nc, err := newNatsConn("", natsOpts()...)
+	if err != nil {
+		return err
+	}
+	defer nc.Close()
+
+	sub, err := nc.QueueSubscribe(c.subject, c.queue, func(m *nats.Msg) {
+		var req tunnelOpenRequest
+		err := json.Unmarshal(m.Data, &req)
+		if err != nil || req.ID == "" {
+			c.respondOpen(m, fmt.Errorf("invalid tunnel request"))
+			return
+		}
+
+		go c.handleConnectorConn(nc, m, req)
+	})
+	if err != nil {
+		return err
+	}
+	defer sub.Unsubscribe()
+
+	log.Printf("Connecting tunneled connections on %s to %s", c.subject, c.target)
+
+	<-ctx.Done()
+
+	return nil
+}
+
+func (c *tunnelCmd) respondOpen(m *nats.Msg, err error) {
+	res := tunnelOpenResponse{}
+	if err != nil {
+		res.Error = err.Error()
+	}
+
+	j, _ := json.Marshal(res)
+	m.Respond(j)
+}
+
+func (c *tunnelCmd) handleConnectorConn(nc *nats.Conn, m *nats.Msg, req tunnelOpenRequest) {
+	conn, err := net.DialTimeout("tcp", c.target, opts.Timeout)
+	if err != nil {
+		log.Printf("Tunnel %s: could not connect to %s: %v", req.ID, c.target, err)
+		c.respondOpen(m, fmt.Errorf("could not connect to target: %w", err))
+		return
+	}
+
+	stream := newTunnelStream(nc, conn, req.ID, fmt.Sprintf("%s.%s.c", c.subject, req.ID), fmt.Sprintf("%s.%s.l", c.subject, req.ID), c.window, c.ackTimeout)
+	err = stream.subscribe()
+	if err != nil {
+		log.Printf("Tunnel %s: could not subscribe: %v", req.ID, err)
+		c.respondOpen(m, err)
+		conn.Close()
+		return
+	}
+
+	c.respondOpen(m, nil)
+
+	log.Printf("Tunnel %s: connected %s to %s", req.ID, req.Source, c.target)
+	stream.run()
+	<-stream.done
+	log.Printf("Tunnel %s: closed, %s", req.ID, stream.stats())
+}
@@ -0,0 +1,388 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/choria-io/fisk"
+	"github.com/fatih/color"
+	"github.com/nats-io/jsm.go"
+	"github.com/nats-io/jsm.go/api"
+	"github.com/nats-io/nats-server/v2/server"
+)
+
+type upgradeCheckCmd struct {
+	target string
+	all    bool
+	json   bool
+
+	current  string
+	findings []*upgradeFinding
+}
+
+// Severities of upgrade findings
+const (
+	upgradeRequired = "required"
+	upgradeWarning  = "warning"
+	upgradeInfo     = "info"
+)
+
+var upgradeSeverities = map[string]int{upgradeRequired: 0, upgradeWarning: 1, upgradeInfo: 2}
+
+// upgradeFinding is a configuration that is deprecated or behaves differently since a server version
+type upgradeFinding struct {
+	Severity string `json:"severity"`
+	Since    string `json:"since,omitempty"`
+	Stream   string `json:"stream"`
+	Consumer string `json:"consumer,omitempty"`
+	Problem  string `json:"problem"`
+	Change   string `json:"change"`
+}
+
+// upgradeReport is the result of nats upgrade-check
+type upgradeReport struct {
+	Current  string            `json:"current_version"`
+	Target   string            `json:"target_version,omitempty"`
+	Findings []*upgradeFinding `json:"findings"`
+}
+
+func configureUpgradeCheckCommand(app commandHost) {
+	c := &upgradeCheckCmd{}
+
+	help := `Finds configurations to change before upgrading the NATS Server
+
+Inspects all Streams and Consumers for settings that are deprecated,
+rejected or that behave differently in server versions newer than the
+connected server, listing the changes to make before upgrading.
+
+By default every newer version is considered, use --target to check
+for a specific version and --all to also list findings that already
+apply to the connected server.
+`
+
+	upgrade := app.Command("upgrade-check", help).Action(c.checkAction)
+	upgrade.Flag("target", "The server version to upgrade to").PlaceHolder("VERSION").StringVar(&c.target)
+	upgrade.Flag("all", "Include findings that already apply to the connected server").UnNegatableBoolVar(&c.all)
+	addJSONOutputFlag(upgrade, &c.json)
+	addCheat("upgrade-check", upgrade)
+}
+
+func init() {
+	registerCommand("upgrade-check", 19, configureUpgradeCheckCommand)
+}
+
+// normalizeVersion completes versions like 2.10 to 2.10.0
+func normalizeVersion(version string) string {
+	version = strings.TrimPrefix(strings.TrimSpace(version), "v")
+
+	for strings.Count(version, ".") < 2 {
+		version += ".0"
+	}
+
+	return version
+}
+
+// applies determines if a finding since a version is relevant to the upgrade
+func (c *upgradeCheckCmd) applies(since string) bool {
+	if since == "" {
+		return true
+	}
+
+	major, minor, patch, err := versionComponents(since)
+	if err != nil {
+		return true
+	}
+
+	if c.target != "" && !serverMinVersion(c.target, major, minor, patch) {
+		return false
+	}
+
+	return c.all || !serverMinVersion(c.current, major, minor, patch)
+}
+
+func (c *upgradeCheckCmd) add(since string, severity string, stream string, consumer string, problem string, change string) {
+	if !c.applies(since) {
+		return
+	}
+
+	c.findings = append(c.findings, &upgradeFinding{
+		Severity: severity,
+		Since:    since,
+		Stream:   stream,
+		Consumer: consumer,
+		Problem:  problem,
+		Change:   change,
+	})
+}
+
+func (c *upgradeCheckCmd) checkAction(_ *fisk.ParseContext) error {
+	if c.target != "" {
+		c.target = normalizeVersion(c.target)
+		_, _, _, err := versionComponents(c.target)
+		if err != nil {
+			return withExitCode(ExitValidation, fmt.Errorf("invalid target version %q", c.target))
+		}
+	}
+
+	nc, mgr, err := prepareHelper("", natsOpts()...)
+	if err != nil {
+		return err
+	}
+
+	c.current = nc.ConnectedServerVersion()
+	if c.target != "" && !c.all {
+		major, minor, patch, _ := versionComponents(c.target)
+		if serverMinVersion(c.current, major, minor, patch) {
+			return withExitCode(ExitValidation, fmt.Errorf("connected server %s is not older than %s", c.current, c.target))
+		}
+	}
+
+	streams, missing, err := loadStreams(mgr, nil, 1)
+	if err != nil {
+		return err
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("could not obtain stream information for %d streams", len(missing))
+	}
+
+	for _, s := range streams {
+		consumers, _, err := loadConsumers(mgr, s.Name(), 1)
+		if err != nil {
+			return err
+		}
+
+		c.checkStream(s, consumers)
+	}
+
+	sort.SliceStable(c.findings, func(i, j int) bool {
+		return upgradeSeverities[c.findings[i].Severity] < upgradeSeverities[c.findings[j].Severity]
+	})
+
+	if c.json {
+		err = printJSON(&upgradeReport{Current: c.current, Target: c.target, Findings: c.findings})
+		if err != nil {
+			return err
+		}
+	} else {
+		c.render()
+	}
+
+	required := 0
+	for _, f := range c.findings {
+		if f.Severity == upgradeRequired {
+			required++
+		}
+	}
+
+	if required > 0 {
+		return withExitCode(ExitThreshold, fmt.Errorf("%d required changes found", required))
+	}
+
+	return nil
+}
+
+func (c *upgradeCheckCmd) checkStream(s *jsm.Stream, consumers []*jsm.Consumer) {
+	cfg := s.Configuration()
+	name := cfg.Name
+
+	if cfg.Template != "" {
+		c.add("2.10.0", upgradeWarning, name, "", fmt.Sprintf("Created by the Stream Template %s, templates are deprecated", cfg.Template), "Create and manage the Stream directly")
+	}
+
+	if cfg.Mirror != nil && len(cfg.Subjects) > 0 {
+		c.add("2.2.0", upgradeRequired, name, "", "Mirrors cannot listen on subjects", "Remove the subjects from the Stream configuration")
+	}
+
+	if jsm.IsKVBucketStream(name) && !cfg.AllowDirect {
+		c.add("2.9.0", upgradeInfo, name, "", "Key-Value bucket does not use direct gets", fmt.Sprintf("nats stream edit %s --allow-direct", name))
+	}
+
+	if cfg.Retention == api.InterestPolicy {
+		c.checkInterest(cfg, consumers)
+	}
+
+	if cfg.Retention == api.WorkQueuePolicy {
+		c.checkWorkQueue(cfg, consumers)
+	}
+
+	for _, cons := range consumers {
+		c.checkConsumer(name, cons.Configuration())
+	}
+}
+
+// consumerFilters returns the filters of a consumer, consumers without filters match all subjects
+func consumerFilters(cfg api.ConsumerConfig) []string {
+	filters := cfg.FilterSubjects
+	if cfg.FilterSubject != "" {
+		filters = append([]string{cfg.FilterSubject}, filters...)
+	}
+	if len(filters) == 0 {
+		filters = []string{">"}
+	}
+
+	return filters
+}
+
+func (c *upgradeCheckCmd) checkInterest(cfg api.StreamConfig, consumers []*jsm.Consumer) {
+	if len(consumers) == 0 {
+		c.add("2.10.0", upgradeWarning, cfg.Name, "", "Interest Stream without Consumers, newer servers discard messages without interest when they are received", "Create the Consumers before publishing or use limits retention")
+		return
+	}
+
+	for _, subject := range cfg.Subjects {
+		covered := false
+		for _, cons := range consumers {
+			for _, filter := range consumerFilters(cons.Configuration()) {
+				if subjectIsSubset(subject, filter) {
+					covered = true
+				}
+			}
+		}
+
+		if !covered {
+			c.add("2.10.0", upgradeWarning, cfg.Name, "", fmt.Sprintf("No Consumer filter covers all of %s, messages without interest are discarded when they are received", subject), "Add a Consumer for the subject or remove the subject from the Stream")
+		}
+	}
+
+	for _, cons := range consumers {
+		ccfg := cons.Configuration()
+		if ccfg.AckPolicy == api.AckNone {
+			c.add("", upgradeInfo, cfg.Name, cons.Name(), "Consumers without acknowledgements on Interest Streams remove messages as soon as they are delivered", "Use explicit acknowledgements")
+		}
+	}
+}
+
+func (c *upgradeCheckCmd) checkWorkQueue(cfg api.StreamConfig, consumers []*jsm.Consumer) {
+	for i, a := range consumers {
+		acfg := a.Configuration()
+
+		if acfg.AckPolicy != api.AckExplicit {
+			c.add("2.2.0", upgradeRequired, cfg.Name, a.Name(), "Work Queue Consumers have to use explicit acknowledgements", "Recreate the Consumer with --ack explicit")
+		}
+
+		for _, b := range consumers[i+1:] {
+			overlap := false
+			for _, af := range consumerFilters(acfg) {
+				for _, bf := range consumerFilters(b.Configuration()) {
+					if server.SubjectsCollide(af, bf) {
+						overlap = true
+					}
+				}
+			}
+
+			if overlap {
+				c.add("2.9.0", upgradeRequired, cfg.Name, a.Name(), fmt.Sprintf("Filter overlaps with Consumer %s, Work Queue Consumers need unique filters", b.Name()), "Recreate the Consumers with filters that do not overlap")
+			}
+		}
+	}
+}
+
+func (c *upgradeCheckCmd) checkConsumer(stream string, cfg api.ConsumerConfig) {
+	name := cfg.Name
+	if name == "" {
+		name = cfg.Durable
+	}
+
+	if cfg.Durable != "" && cfg.InactiveThreshold > 0 {
+		c.add("2.9.0", upgradeWarning, stream, name, fmt.Sprintf("Durable Consumer sets an inactive threshold, newer servers remove durable Consumers after being inactive for %s", humanizeDuration(cfg.InactiveThreshold)), "Remove the inactive threshold to keep the Consumer")
+	}
+
+	if cfg.DeliverSubject == "" {
+		var invalid []string
+		if cfg.RateLimit > 0 {
+			invalid = append(invalid, "rate limit")
+		}
+		if cfg.FlowControl {
+			invalid = append(invalid, "flow control")
+		}
+		if cfg.Heartbeat > 0 {
+			invalid = append(invalid, "idle heartbeats")
+		}
+		if cfg.DeliverGroup != "" {
+			invalid = append(invalid, "deliver group")
+		}
+
+		if len(invalid) > 0 {
+			c.add("2.9.0", upgradeRequired, stream, name, fmt.Sprintf("Pull Consumer sets Push Consumer options: %s", strings.Join(invalid, ", ")), "Recreate the Consumer without these options")
+		}
+
+		return
+	}
+
+	var invalid []string
+	if cfg.MaxWaiting > 0 {
+		invalid = append(invalid, "max waiting")
+	}
+	if cfg.MaxRequestBatch > 0 {
+		invalid = append(invalid, "max request batch")
+	}
+	if cfg.MaxRequestExpires > 0 {
+		invalid = append(invalid, "max request expires")
+	}
+	if len(invalid) > 0 {
+		c.add("2.9.0", upgradeRequired, stream, name, fmt.Sprintf("Push Consumer sets Pull Consumer options: %s", strings.Join(invalid, ", ")), "Recreate the Consumer without these options")
+	}
+
+	if cfg.Heartbeat == 0 || !cfg.FlowControl {
+		c.add("", upgradeInfo, stream, name, "Push Consumer without heartbeats and flow control cannot detect stalls", "Recreate as a Pull Consumer, newer client APIs favor Pull Consumers")
+	}
+}
+
+func (c *upgradeCheckCmd) render() {
+	target := c.target
+	if target == "" {
+		target = "the latest release"
+	}
+
+	if len(c.findings) == 0 {
+		fmt.Printf("No changes needed to upgrade from %s to %s\n", c.current, target)
+		return
+	}
+
+	table := newTableWriter(fmt.Sprintf("Upgrade from %s to %s", c.current, target))
+	table.AddHeaders("Severity", "Since", "Asset", "Problem", "Change")
+
+	counts := map[string]int{}
+	for _, f := range c.findings {
+		counts[f.Severity]++
+
+		asset := f.Stream
+		if f.Consumer != "" {
+			asset = fmt.Sprintf("%s > %s", f.Stream, f.Consumer)
+		}
+
+		severity := f.Severity
+		switch f.Severity {
+		case upgradeRequired:
+			severity = color.RedString("REQUIRED")
+		case upgradeWarning:
+			severity = color.YellowString("WARNING")
+		case upgradeInfo:
+			severity = color.CyanString("INFO")
+		}
+
+		since := f.Since
+		if since == "" {
+			since = "any"
+		}
+
+		table.AddRow(severity, since, asset, f.Problem, f.Change)
+	}
+
+	fmt.Println(table.Render())
+	fmt.Printf("%d required changes, %d warnings, %d informational\n", counts[upgradeRequired], counts[upgradeWarning], counts[upgradeInfo])
+}
@@ -28,6 +28,7 @@ import (
 	"os"
 	"os/exec"
 	"regexp"
+	"runtime"
 	"sort"
 	"strconv"
 	"strings"
@@ -73,6 +74,9 @@ func selectConsumer(mgr *jsm.Manager, stream string, consumer string, force bool
 	}
 
 	if !isTerminal() {
+		if consumer != "" {
+			return "", nil, fmt.Errorf("consumer %q > %q not found", stream, consumer)
+		}
 		return "", nil, fmt.Errorf("cannot pick a Consumer without a terminal and no Consumer name supplied")
 	}
 
@@ -130,6 +134,9 @@ func selectStream(mgr *jsm.Manager, stream string, force bool, all bool) (string
 	}
 
 	if !isTerminal() {
+		if stream != "" {
+			return "", nil, fmt.Errorf("stream %q not found", stream)
+		}
 		return "", nil, fmt.Errorf("cannot pick a Stream without a terminal and no Stream name supplied")
 	}
 
@@ -161,28 +168,12 @@ func askOne(p survey.Prompt, response any, opts ...survey.AskOpt) error {
 		return fmt.Errorf("cannot prompt for user input without a terminal")
 	}
 
-	return survey.AskOne(p, response, append(surveyColors(), opts...)...)
-}
-
-func toJSON(d any) (string, error) {
-	j, err := json.MarshalIndent(d, "", "  ")
-	if err != nil {
-		return "", err
-	}
+	// prompts cannot be shown while paging so wait for the user to close the pager first
+	stopPager()
 
-	return string(j), nil
+	return survey.AskOne(p, response, append(surveyColors(), opts...)...)
 }
 
-func printJSON(d any) error {
-	j, err := json.MarshalIndent(d, "", "  ")
-	if err != nil {
-		return err
-	}
-
-	fmt.Println(string(j))
-
-	return nil
-}
 func parseDurationString(dstr string) (dur time.Duration, err error) {
 	dstr = strings.TrimSpace(dstr)
 	if len(dstr) == 0 {
@@ -228,6 +219,16 @@ func selectPageSize(count int) int {
 	return ps
 }
 
+// clearScreen clears the terminal and moves the cursor home, used by commands that redraw their output
+func clearScreen() {
+	if runtime.GOOS == "windows" {
+		return
+	}
+
+	fmt.Print("\033[2J")
+	fmt.Print("\033[H")
+}
+
 func isTerminal() bool {
 	return terminal.IsTerminal(int(os.Stdin.Fd()))
 }
@@ -338,7 +339,7 @@ func natsOpts() []nats.Option {
 		return []nats.Option{}
 	}
 
-	copts, err := opts.Config.NATSOptions()
+	copts, err := contextNATSOptions(opts.Config)
 	fisk.FatalIfError(err, "configuration error")
 
 	connectionName := strings.TrimSpace(opts.ConnectionName)
@@ -449,6 +450,21 @@ func prepareJSHelper() (*nats.Conn, nats.JetStreamContext, error) {
 	return opts.Conn, opts.JSc, nil
 }
 
+// cliBucket loads a Key-Value bucket used to store state shared between users of the CLI, optionally creating it
+func cliBucket(bucket string, description string, create bool) (nats.KeyValue, error) {
+	_, js, err := prepareJSHelper()
+	if err != nil {
+		return nil, err
+	}
+
+	kv, err := js.KeyValue(bucket)
+	if errors.Is(err, nats.ErrBucketNotFound) && create {
+		kv, err = js.CreateKeyValue(&nats.KeyValueConfig{Bucket: bucket, Description: description})
+	}
+
+	return kv, err
+}
+
 func prepareHelper(servers string, copts ...nats.Option) (*nats.Conn, *jsm.Manager, error) {
 	mu.Lock()
 	defer mu.Unlock()
@@ -516,6 +532,49 @@ func prepareHelperUnlocked(servers string, copts ...nats.Option) (*nats.Conn, *j
 	return opts.Conn, opts.Mgr, err
 }
 
+// prepareContextHelper connects to the servers of a named context, unlike prepareHelper the connection is not
+// shared and should be closed by the caller
+func prepareContextHelper(name string) (*nats.Conn, *jsm.Manager, nats.JetStreamContext, error) {
+	cfg, err := loadResolvedContext(name)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("could not load context %s: %w", name, err)
+	}
+
+	copts, err := contextNATSOptions(cfg)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	copts = append(copts, nats.Name("NATS CLI Version "+Version), nats.MaxReconnects(-1))
+	nc, err := nats.Connect(cfg.ServerURL(), copts...)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("could not connect using context %s: %w", name, err)
+	}
+
+	jsopts := []jsm.Option{
+		jsm.WithAPIPrefix(cfg.JSAPIPrefix()),
+		jsm.WithEventPrefix(cfg.JSEventPrefix()),
+		jsm.WithDomain(cfg.JSDomain()),
+	}
+	if opts.Timeout != 0 {
+		jsopts = append(jsopts, jsm.WithTimeout(opts.Timeout))
+	}
+
+	mgr, err := jsm.New(nc, jsopts...)
+	if err != nil {
+		nc.Close()
+		return nil, nil, nil, err
+	}
+
+	js, err := nc.JetStream(nats.Domain(cfg.JSDomain()), nats.APIPrefix(cfg.JSAPIPrefix()), nats.MaxWait(opts.Timeout))
+	if err != nil {
+		nc.Close()
+		return nil, nil, nil, err
+	}
+
+	return nc, mgr, js, nil
+}
+
 func humanizeDuration(d time.Duration) string {
 	if d < time.Millisecond {
 		return d.Round(time.Microsecond).String()
@@ -648,10 +707,7 @@ func pubReplyBodyTemplate(body string, request string, ctr int) ([]byte, error)
 		"TimeStamp": func() string { return now.Format(time.RFC3339) },
 		"Time":      func() string { return now.Format(time.Kitchen) },
 		"ID":        func() string { return nuid.Next() },
-	}
-
-	if request != "" {
-		funcMap["Request"] = func() string { return request }
+		"Request":   func() string { return request },
 	}
 
 	templ, err := template.New("body").Funcs(funcMap).Parse(body)
@@ -777,10 +833,13 @@ func loadContext() error {
 
 	exist, _ := fileAccessible(opts.CfgCtx)
 
-	if exist && strings.HasSuffix(opts.CfgCtx, ".json") {
-		opts.Config, err = natscontext.NewFromFile(opts.CfgCtx, ctxOpts...)
-	} else {
-		opts.Config, err = natscontext.New(opts.CfgCtx, !SkipContexts, ctxOpts...)
+	switch {
+	case exist && strings.HasSuffix(opts.CfgCtx, ".json"):
+		opts.Config, err = loadResolvedContextFile(opts.CfgCtx, ctxOpts...)
+	case SkipContexts:
+		opts.Config, err = natscontext.New(opts.CfgCtx, false, ctxOpts...)
+	default:
+		opts.Config, err = loadResolvedContext(opts.CfgCtx, ctxOpts...)
 	}
 
 	return err
@@ -1081,11 +1140,12 @@ func compactStrings(source []string) []string {
 func newTableWriter(title string) *tbl {
 	tbl := &tbl{
 		writer: table.NewWriter(),
+		title:  title,
 	}
 
 	tbl.writer.SetStyle(styles["rounded"])
 
-	if isatty.IsTerminal(os.Stdout.Fd()) {
+	if isatty.IsTerminal(os.Stdout.Fd()) || pagerActive() {
 		if opts.Config != nil {
 			style, ok := styles[opts.Config.ColorScheme()]
 			if ok {
@@ -1402,3 +1462,112 @@ func filterDataThroughCmd(data []byte, filter, subject, stream string) ([]byte,
 	// maybe we want to do something on error?
 	return runner.CombinedOutput()
 }
+
+// addParallelFlag adds the flag setting how many API requests bulk operations make concurrently
+func addParallelFlag(cmd *fisk.CmdClause, workers *int) {
+	cmd.Flag("parallel", "Number of concurrent API requests to make").Default("1").PlaceHolder("N").IntVar(workers)
+}
+
+// parallelDo calls cb for every index below count using up to workers concurrent calls
+func parallelDo(workers int, count int, cb func(i int)) {
+	if workers < 1 {
+		workers = 1
+	}
+	if workers > count {
+		workers = count
+	}
+
+	work := make(chan int, count)
+	for i := 0; i < count; i++ {
+		work <- i
+	}
+	close(work)
+
+	wg := sync.WaitGroup{}
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range work {
+				cb(i)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// loadStreams loads all Streams matching filter, using up to workers concurrent requests when workers is above 1
+func loadStreams(mgr *jsm.Manager, filter *jsm.StreamNamesFilter, workers int) (streams []*jsm.Stream, missing []string, err error) {
+	if workers <= 1 {
+		err = apiCall(func() (err error) {
+			streams, missing, err = mgr.Streams(filter)
+			return err
+		})
+		return streams, missing, err
+	}
+
+	var names []string
+	err = apiCall(func() (err error) {
+		names, err = mgr.StreamNames(filter)
+		return err
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	loaded := make([]*jsm.Stream, len(names))
+	parallelDo(workers, len(names), func(i int) {
+		apiCall(func() (err error) {
+			loaded[i], err = mgr.LoadStream(names[i])
+			return err
+		})
+	})
+
+	for i, s := range loaded {
+		if s == nil {
+			missing = append(missing, names[i])
+			continue
+		}
+		streams = append(streams, s)
+	}
+
+	return streams, missing, nil
+}
+
+// loadConsumers loads all Consumers of stream, using up to workers concurrent requests when workers is above 1
+func loadConsumers(mgr *jsm.Manager, stream string, workers int) (consumers []*jsm.Consumer, missing []string, err error) {
+	if workers <= 1 {
+		err = apiCall(func() (err error) {
+			consumers, missing, err = mgr.Consumers(stream)
+			return err
+		})
+		return consumers, missing, err
+	}
+
+	var names []string
+	err = apiCall(func() (err error) {
+		names, err = mgr.ConsumerNames(stream)
+		return err
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	loaded := make([]*jsm.Consumer, len(names))
+	parallelDo(workers, len(names), func(i int) {
+		apiCall(func() (err error) {
+			loaded[i], err = mgr.LoadConsumer(stream, names[i])
+			return err
+		})
+	})
+
+	for i, c := range loaded {
+		if c == nil {
+			missing = append(missing, names[i])
+			continue
+		}
+		consumers = append(consumers, c)
+	}
+
+	return consumers, missing, nil
+}
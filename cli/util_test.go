@@ -16,7 +16,9 @@ package cli
 import (
 	"errors"
 	"sort"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/nats-io/jsm.go/api"
@@ -244,3 +246,37 @@ func TestHostnameCompactor(t *testing.T) {
 		t.Fatalf("Recevied %#v", result)
 	}
 }
+
+func TestParallelDo(t *testing.T) {
+	for _, workers := range []int{0, 1, 4, 200} {
+		var active, peak int32
+		seen := make([]int32, 100)
+
+		parallelDo(workers, len(seen), func(i int) {
+			n := atomic.AddInt32(&active, 1)
+			for {
+				p := atomic.LoadInt32(&peak)
+				if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+					break
+				}
+			}
+			atomic.AddInt32(&seen[i], 1)
+			time.Sleep(time.Millisecond)
+			atomic.AddInt32(&active, -1)
+		})
+
+		for i, s := range seen {
+			if s != 1 {
+				t.Fatalf("item %d was handled %d times with %d workers", i, s, workers)
+			}
+		}
+
+		limit := int32(workers)
+		if limit < 1 {
+			limit = 1
+		}
+		if peak > limit {
+			t.Fatalf("%d concurrent calls exceeded %d workers", peak, workers)
+		}
+	}
+}
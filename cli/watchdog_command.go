@@ -0,0 +1,448 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/choria-io/fisk"
+	"github.com/ghodss/yaml"
+	"github.com/kballard/go-shellquote"
+	"github.com/nats-io/jsm.go"
+	"github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats.go"
+)
+
+type watchdogCmd struct {
+	rulesFile string
+	interval  time.Duration
+	once      bool
+
+	nc  *nats.Conn
+	mgr *jsm.Manager
+}
+
+// Checks supported by watchdog rules
+const (
+	watchdogConsumerPending  = "consumer_pending"
+	watchdogStreamLeaderless = "stream_leaderless"
+	watchdogServerDown       = "server_down"
+	watchdogMirrorLag        = "mirror_lag"
+)
+
+// watchdogRules is the rules file for the watchdog
+type watchdogRules struct {
+	Interval string          `json:"interval"`
+	Rules    []*watchdogRule `json:"rules"`
+}
+
+// watchdogRule is a condition that fires actions once it held for a period
+type watchdogRule struct {
+	Name      string            `json:"name"`
+	Check     string            `json:"check"`
+	Stream    string            `json:"stream,omitempty"`
+	Consumer  string            `json:"consumer,omitempty"`
+	Servers   []string          `json:"servers,omitempty"`
+	Expect    int               `json:"expect,omitempty"`
+	Threshold uint64            `json:"threshold,omitempty"`
+	For       string            `json:"for,omitempty"`
+	Actions   []*watchdogAction `json:"actions"`
+
+	forDuration time.Duration
+	since       time.Time
+	firing      bool
+}
+
+// watchdogAction is an action taken when a rule fires or resolves, only one of the fields should be set
+type watchdogAction struct {
+	Exec    string `json:"exec,omitempty"`
+	Publish string `json:"publish,omitempty"`
+	Webhook string `json:"webhook,omitempty"`
+}
+
+// watchdogEvent is sent to actions when a rule changes state
+type watchdogEvent struct {
+	Rule    string    `json:"rule"`
+	Check   string    `json:"check"`
+	State   string    `json:"state"`
+	Message string    `json:"message"`
+	Since   time.Time `json:"since"`
+	Time    time.Time `json:"time"`
+}
+
+func configureWatchdogCommand(app commandHost) {
+	c := &watchdogCmd{}
+
+	help := `Evaluates alerting rules and runs actions when they fire
+
+Rules are read from a YAML file and evaluated on an interval, each rule
+fires once its condition held for the period given in "for" and
+resolves when it no longer holds, actions are run on both occasions:
+
+  interval: 30s
+  rules:
+    - name: orders backlog
+      check: consumer_pending
+      stream: ORDERS
+      consumer: NEW
+      threshold: 1000
+      for: 5m
+      actions:
+        - exec: /usr/local/bin/page-oncall
+        - publish: alerts.orders
+        - webhook: https://hooks.example.net/nats
+
+Supported checks are consumer_pending, stream_leaderless, mirror_lag
+with a threshold and server_down which requires a system account and
+either a list of servers or the number of servers to expect.
+
+Commands receive the event as JSON on standard input and in the
+NATS_WATCHDOG_RULE, NATS_WATCHDOG_STATE and NATS_WATCHDOG_MESSAGE
+environment variables.
+`
+
+	watchdog := publishing(app.Command("watchdog", help).Action(c.watchdogAction))
+	watchdog.Flag("rules", "The rules file to evaluate").Required().PlaceHolder("FILE").ExistingFileVar(&c.rulesFile)
+	watchdog.Flag("interval", "How often to evaluate rules, overrides the rules file").PlaceHolder("DURATION").DurationVar(&c.interval)
+	watchdog.Flag("once", "Evaluates rules once and exits, failing when any rule fails").UnNegatableBoolVar(&c.once)
+	addCheat("watchdog", watchdog)
+}
+
+func init() {
+	registerCommand("watchdog", 18, configureWatchdogCommand)
+}
+
+func loadWatchdogRules(file string) (*watchdogRules, error) {
+	body, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	j, err := yaml.YAMLToJSON(body)
+	if err != nil {
+		return nil, err
+	}
+
+	rules := &watchdogRules{}
+	err = json.Unmarshal(j, rules)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(rules.Rules) == 0 {
+		return nil, fmt.Errorf("no rules defined")
+	}
+
+	for i, rule := range rules.Rules {
+		if rule.Name == "" {
+			rule.Name = fmt.Sprintf("rule %d", i+1)
+		}
+
+		switch rule.Check {
+		case watchdogConsumerPending:
+			if rule.Stream == "" || rule.Consumer == "" {
+				return nil, fmt.Errorf("%s: %s requires a stream and consumer", rule.Name, rule.Check)
+			}
+		case watchdogStreamLeaderless, watchdogMirrorLag:
+			if rule.Stream == "" {
+				return nil, fmt.Errorf("%s: %s requires a stream", rule.Name, rule.Check)
+			}
+		case watchdogServerDown:
+			if len(rule.Servers) == 0 && rule.Expect == 0 {
+				return nil, fmt.Errorf("%s: %s requires servers or expect", rule.Name, rule.Check)
+			}
+		default:
+			return nil, fmt.Errorf("%s: unknown check %q", rule.Name, rule.Check)
+		}
+
+		if rule.For != "" {
+			rule.forDuration, err = fisk.ParseDuration(rule.For)
+			if err != nil {
+				return nil, fmt.Errorf("%s: invalid for: %w", rule.Name, err)
+			}
+		}
+
+		for _, action := range rule.Actions {
+			if action.Exec == "" && action.Publish == "" && action.Webhook == "" {
+				return nil, fmt.Errorf("%s: actions require exec, publish or webhook", rule.Name)
+			}
+		}
+	}
+
+	return rules, nil
+}
+
+func (c *watchdogCmd) watchdogAction(_ *fisk.ParseContext) error {
+	rules, err := loadWatchdogRules(c.rulesFile)
+	if err != nil {
+		return withExitCode(ExitValidation, fmt.Errorf("invalid rules in %s: %w", c.rulesFile, err))
+	}
+
+	if c.interval == 0 {
+		c.interval = 30 * time.Second
+		if rules.Interval != "" {
+			c.interval, err = fisk.ParseDuration(rules.Interval)
+			if err != nil {
+				return withExitCode(ExitValidation, fmt.Errorf("invalid interval: %w", err))
+			}
+		}
+	}
+
+	c.nc, c.mgr, err = prepareHelper("", natsOpts()...)
+	if err != nil {
+		return err
+	}
+
+	if c.once {
+		failed := 0
+		for _, rule := range rules.Rules {
+			ok, msg := c.evaluate(rule)
+			if !ok {
+				failed++
+				fmt.Printf("FAIL %s: %s\n", rule.Name, msg)
+			} else {
+				fmt.Printf("OK   %s: %s\n", rule.Name, msg)
+			}
+		}
+
+		if failed > 0 {
+			return withExitCode(ExitThreshold, fmt.Errorf("%d of %d rules failed", failed, len(rules.Rules)))
+		}
+
+		return nil
+	}
+
+	log.Printf("Evaluating %d rules every %v", len(rules.Rules), c.interval)
+
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+
+	for {
+		c.evaluateRules(rules.Rules)
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+func (c *watchdogCmd) evaluateRules(rules []*watchdogRule) {
+	now := time.Now()
+
+	for _, rule := range rules {
+		ok, msg := c.evaluate(rule)
+
+		switch {
+		case !ok && rule.since.IsZero():
+			rule.since = now
+			fallthrough
+
+		case !ok && !rule.firing:
+			if now.Sub(rule.since) < rule.forDuration {
+				continue
+			}
+
+			rule.firing = true
+			log.Printf("FIRING %s: %s", rule.Name, msg)
+			c.runActions(rule, &watchdogEvent{Rule: rule.Name, Check: rule.Check, State: "firing", Message: msg, Since: rule.since, Time: now})
+
+		case ok && !rule.since.IsZero():
+			if rule.firing {
+				log.Printf("RESOLVED %s: %s", rule.Name, msg)
+				c.runActions(rule, &watchdogEvent{Rule: rule.Name, Check: rule.Check, State: "resolved", Message: msg, Since: rule.since, Time: now})
+			}
+
+			rule.since = time.Time{}
+			rule.firing = false
+		}
+	}
+}
+
+// evaluate checks the condition of a rule, returning false when the rule fails
+func (c *watchdogCmd) evaluate(rule *watchdogRule) (bool, string) {
+	switch rule.Check {
+	case watchdogConsumerPending:
+		cons, err := c.mgr.LoadConsumer(rule.Stream, rule.Consumer)
+		if err != nil {
+			return false, fmt.Sprintf("could not load consumer %s > %s: %v", rule.Stream, rule.Consumer, err)
+		}
+
+		state, err := cons.LatestState()
+		if err != nil {
+			return false, fmt.Sprintf("could not load consumer %s > %s state: %v", rule.Stream, rule.Consumer, err)
+		}
+
+		return state.NumPending <= rule.Threshold, fmt.Sprintf("%d messages pending on %s > %s", state.NumPending, rule.Stream, rule.Consumer)
+
+	case watchdogStreamLeaderless, watchdogMirrorLag:
+		str, err := c.mgr.LoadStream(rule.Stream)
+		if err != nil {
+			return false, fmt.Sprintf("could not load stream %s: %v", rule.Stream, err)
+		}
+
+		nfo, err := str.LatestInformation()
+		if err != nil {
+			return false, fmt.Sprintf("could not load stream %s information: %v", rule.Stream, err)
+		}
+
+		if rule.Check == watchdogStreamLeaderless {
+			if nfo.Cluster == nil || nfo.Cluster.Leader == "" {
+				return false, fmt.Sprintf("stream %s has no leader", rule.Stream)
+			}
+
+			return true, fmt.Sprintf("stream %s leader is %s", rule.Stream, nfo.Cluster.Leader)
+		}
+
+		if nfo.Mirror == nil {
+			return false, fmt.Sprintf("stream %s is not a mirror", rule.Stream)
+		}
+
+		return nfo.Mirror.Lag <= rule.Threshold, fmt.Sprintf("mirror %s is %d messages behind", rule.Stream, nfo.Mirror.Lag)
+
+	case watchdogServerDown:
+		return c.checkServers(rule)
+	}
+
+	return false, fmt.Sprintf("unknown check %q", rule.Check)
+}
+
+func (c *watchdogCmd) checkServers(rule *watchdogRule) (bool, string) {
+	var (
+		mu    sync.Mutex
+		found = map[string]bool{}
+	)
+
+	expect := rule.Expect
+	if len(rule.Servers) > 0 {
+		expect = len(rule.Servers)
+	}
+
+	err := doReqAsync(nil, "$SYS.REQ.SERVER.PING", expect, c.nc, func(data []byte) {
+		ssm := &server.ServerStatsMsg{}
+		if json.Unmarshal(data, ssm) != nil {
+			return
+		}
+
+		mu.Lock()
+		found[ssm.Server.Name] = true
+		mu.Unlock()
+	})
+	if err != nil {
+		return false, fmt.Sprintf("could not ping servers: %v", err)
+	}
+
+	if len(rule.Servers) > 0 {
+		var missing []string
+		for _, s := range rule.Servers {
+			if !found[s] {
+				missing = append(missing, s)
+			}
+		}
+		sort.Strings(missing)
+
+		if len(missing) > 0 {
+			return false, fmt.Sprintf("servers %s did not respond", strings.Join(missing, ", "))
+		}
+
+		return true, fmt.Sprintf("all %d servers responded", len(rule.Servers))
+	}
+
+	return len(found) >= rule.Expect, fmt.Sprintf("%d of %d servers responded", len(found), rule.Expect)
+}
+
+func (c *watchdogCmd) runActions(rule *watchdogRule, event *watchdogEvent) {
+	body, err := json.Marshal(event)
+	if err != nil {
+		log.Printf("Could not encode event for %s: %v", rule.Name, err)
+		return
+	}
+
+	for _, action := range rule.Actions {
+		switch {
+		case action.Exec != "":
+			err = c.execAction(action.Exec, event, body)
+		case action.Publish != "":
+			err = c.nc.Publish(action.Publish, body)
+		case action.Webhook != "":
+			err = c.webhookAction(action.Webhook, body)
+		}
+
+		if err != nil {
+			log.Printf("Action for %s failed: %v", rule.Name, err)
+		}
+	}
+}
+
+func (c *watchdogCmd) execAction(command string, event *watchdogEvent, body []byte) error {
+	parts, err := shellquote.Split(command)
+	if err != nil {
+		return err
+	}
+	if len(parts) == 0 {
+		return fmt.Errorf("empty command")
+	}
+
+	cctx, cancel := context.WithTimeout(ctx, time.Minute)
+	defer cancel()
+
+	cmd := exec.CommandContext(cctx, parts[0], parts[1:]...)
+	cmd.Env = append(os.Environ(),
+		fmt.Sprintf("NATS_WATCHDOG_RULE=%s", event.Rule),
+		fmt.Sprintf("NATS_WATCHDOG_STATE=%s", event.State),
+		fmt.Sprintf("NATS_WATCHDOG_MESSAGE=%s", event.Message),
+	)
+	cmd.Stdin = bytes.NewReader(body)
+
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%s failed: %v: %s", parts[0], err, strings.TrimSpace(string(out)))
+	}
+
+	return nil
+}
+
+func (c *watchdogCmd) webhookAction(url string, body []byte) error {
+	cctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(cctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook %s returned %s", url, resp.Status)
+	}
+
+	return nil
+}
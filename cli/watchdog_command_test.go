@@ -0,0 +1,56 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLoadWatchdogRules(t *testing.T) {
+	dir := t.TempDir()
+
+	write := func(body string) string {
+		t.Helper()
+		f := filepath.Join(dir, "rules.yaml")
+		err := os.WriteFile(f, []byte(body), 0600)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return f
+	}
+
+	rules, err := loadWatchdogRules(write("rules:\n  - check: consumer_pending\n    stream: ORDERS\n    consumer: NEW\n    threshold: 10\n    for: 5m\n    actions:\n      - publish: alerts\n"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if rules.Rules[0].Name != "rule 1" || rules.Rules[0].forDuration != 5*time.Minute || rules.Rules[0].Threshold != 10 {
+		t.Fatalf("unexpected rule: %+v", rules.Rules[0])
+	}
+
+	for _, body := range []string{
+		"rules: []\n",
+		"rules:\n  - check: unknown\n",
+		"rules:\n  - check: consumer_pending\n    stream: ORDERS\n",
+		"rules:\n  - check: server_down\n",
+		"rules:\n  - check: mirror_lag\n    stream: M\n    actions:\n      - {}\n",
+	} {
+		_, err = loadWatchdogRules(write(body))
+		if err == nil {
+			t.Fatalf("expected an error for %q", body)
+		}
+	}
+}
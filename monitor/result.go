@@ -22,6 +22,7 @@ import (
 	"strings"
 
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/push"
 	"github.com/prometheus/common/expfmt"
 )
 
@@ -46,6 +47,8 @@ type Result struct {
 	RenderFormat RenderFormat `json:"-"`
 	NameSpace    string       `json:"-"`
 	OutFile      string       `json:"-"`
+	PushGateway  string       `json:"-"`
+	PushJob      string       `json:"-"`
 }
 
 func (r *Result) Pd(pd ...*PerfDataItem) {
@@ -149,7 +152,7 @@ func (r *Result) renderHuman() string {
 	return buf.String()
 }
 
-func (r *Result) renderPrometheus() string {
+func (r *Result) prometheusRegistry() *prometheus.Registry {
 	if r.Check == "" {
 		r.Check = r.Name
 	}
@@ -181,9 +184,13 @@ func (r *Result) renderPrometheus() string {
 
 	status.WithLabelValues(sname, string(r.Status)).Set(float64(r.nagiosCode()))
 
+	return registry
+}
+
+func (r *Result) renderPrometheus() string {
 	var buf bytes.Buffer
 
-	mfs, err := prometheus.DefaultGatherer.Gather()
+	mfs, err := r.prometheusRegistry().Gather()
 	if err != nil {
 		panic(err)
 	}
@@ -228,7 +235,7 @@ func (r *Result) renderNagios() string {
 	return fmt.Sprintf("%s %s | %s", r.Status, strings.Join(res, " "), r.PerfData)
 }
 
-func (r *Result) String() string {
+func (r *Result) updateStatus() {
 	if r.Status == "" {
 		r.Status = UnknownStatus
 	}
@@ -244,6 +251,22 @@ func (r *Result) String() string {
 	default:
 		r.Status = OKStatus
 	}
+}
+
+// pushPrometheus pushes the Prometheus metrics for the result to the Push Gateway
+func (r *Result) pushPrometheus() error {
+	r.updateStatus()
+
+	job := r.PushJob
+	if job == "" {
+		job = "nats-cli"
+	}
+
+	return push.New(r.PushGateway, job).Gatherer(r.prometheusRegistry()).Add()
+}
+
+func (r *Result) String() string {
+	r.updateStatus()
 
 	switch r.RenderFormat {
 	case JSONFormat:
@@ -258,6 +281,13 @@ func (r *Result) String() string {
 }
 
 func (r *Result) GenericExit() {
+	if r.PushGateway != "" {
+		err := r.pushPrometheus()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "push to %s failed: %s\n", r.PushGateway, err)
+		}
+	}
+
 	if r.OutFile != "" {
 		f, err := os.CreateTemp(filepath.Dir(r.OutFile), "")
 		if err != nil {
@@ -55,20 +55,33 @@ See 'nats cheat' for a quick cheatsheet of commands`
 	ncli.Flag("tlskey", "TLS private key").Envar("NATS_KEY").PlaceHolder("FILE").ExistingFileVar(&opts.TlsKey)
 	ncli.Flag("tlsca", "TLS certificate authority chain").Envar("NATS_CA").PlaceHolder("FILE").ExistingFileVar(&opts.TlsCA)
 	ncli.Flag("timeout", "Time to wait on responses from NATS").Default("5s").Envar("NATS_TIMEOUT").PlaceHolder("DURATION").DurationVar(&opts.Timeout)
-	ncli.Flag("socks-proxy", "SOCKS5 proxy for connecting to NATS server").Envar("NATS_SOCKS_PROXY").PlaceHolder("PROXY").StringVar(&opts.SocksProxy)
+	ncli.Flag("proxy", "SOCKS5 or HTTP proxy for connecting to NATS server, eg. socks5://host:1080").Envar("NATS_PROXY").PlaceHolder("PROXY").StringVar(&opts.SocksProxy)
+	ncli.Flag("socks-proxy", "SOCKS5 proxy for connecting to NATS server").Envar("NATS_SOCKS_PROXY").PlaceHolder("PROXY").Hidden().StringVar(&opts.SocksProxy)
 	ncli.Flag("js-api-prefix", "Subject prefix for access to JetStream API").PlaceHolder("PREFIX").StringVar(&opts.JsApiPrefix)
 	ncli.Flag("js-event-prefix", "Subject prefix for access to JetStream Advisories").PlaceHolder("PREFIX").StringVar(&opts.JsEventPrefix)
 	ncli.Flag("js-domain", "JetStream domain to access").PlaceHolder("DOMAIN").StringVar(&opts.JsDomain)
 	ncli.Flag("inbox-prefix", "Custom inbox prefix to use for inboxes").PlaceHolder("PREFIX").StringVar(&opts.InboxPrefix)
 	ncli.Flag("domain", "JetStream domain to access").PlaceHolder("DOMAIN").Hidden().StringVar(&opts.JsDomain)
+	ncli.Flag("output-format", "Renders info, list and report output as text, wide or narrow tables, json or yaml").Default("text").Envar("NATS_OUTPUT_FORMAT").PlaceHolder("FORMAT").EnumVar(&opts.OutputFormat, "text", "wide", "narrow", "json", "yaml")
+	ncli.Flag("filter-json", "Filters JSON output using a jq style expression").PlaceHolder("FILTER").StringVar(&opts.JSONFilter)
+	ncli.Flag("template", "Renders info and report output using a Go template").PlaceHolder("TEMPLATE").StringVar(&opts.OutputTemplate)
 	ncli.Flag("colors", "Sets a color scheme to use").PlaceHolder("SCHEME").Envar("NATS_COLOR").EnumVar(&opts.ColorScheme, cli.ValidStyles()...)
 	ncli.Flag("context", "Configuration context").Envar("NATS_CONTEXT").PlaceHolder("NAME").StringVar(&opts.CfgCtx)
 	ncli.Flag("trace", "Trace API interactions").UnNegatableBoolVar(&opts.Trace)
+	ncli.Flag("quiet", "Suppress output other than structured output and errors").UnNegatableBoolVar(&opts.Quiet)
+	ncli.Flag("no-pager", "Do not send long output through a pager").Envar("NATS_NO_PAGER").UnNegatableBoolVar(&opts.NoPager)
+	ncli.Flag("otel-endpoint", "Sends traces of the command and its API calls to an OpenTelemetry OTLP HTTP endpoint").Envar("NATS_OTEL_ENDPOINT").PlaceHolder("URL").StringVar(&opts.OtelEndpoint)
+	ncli.Flag("audit-file", "Records changes made by mutating commands to a file").Envar("NATS_AUDIT_FILE").PlaceHolder("FILE").StringVar(&opts.AuditFile)
+	ncli.Flag("api-rate", "Limits the rate of JetStream API requests made by reports and bulk operations, like 50/s").Envar("NATS_API_RATE").PlaceHolder("RATE").StringVar(&opts.APIRate)
+	ncli.Flag("retries", "Number of times JetStream API requests made by reports and bulk operations are retried on transient failures").Default("0").Envar("NATS_RETRIES").PlaceHolder("N").IntVar(&opts.Retries)
+	ncli.Flag("retry-backoff", "Time to wait before retrying a failed JetStream API request, doubling for every retry").Default("500ms").PlaceHolder("DURATION").DurationVar(&opts.RetryBackoff)
+	ncli.Flag("audit-subject", "Publishes changes made by mutating commands to a subject").Envar("NATS_AUDIT_SUBJECT").PlaceHolder("SUBJECT").StringVar(&opts.AuditSubject)
+	ncli.Flag("each-context", "Runs a read-only command against all contexts matching a pattern and merges the output, publishing needs confirmation").PlaceHolder("PATTERN").StringVar(&opts.EachContext)
 	ncli.Flag("no-context", "Disable the selected context").UnNegatableBoolVar(&cli.SkipContexts)
 
 	log.SetFlags(log.Ltime)
 
-	ncli.MustParseWithUsage(os.Args[1:])
+	cli.Run(ncli, os.Args[1:])
 }
 
 func getVersion() string {